@@ -0,0 +1,444 @@
+package binlog
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/binary"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+)
+
+// Capability flags of handshake
+const (
+	clientLongPassword   = 0x00000001
+	clientLongFlag       = 0x00000004
+	clientProtocol41     = 0x00000200
+	clientTransactions   = 0x00002000
+	clientSecureConn     = 0x00008000
+	clientPluginAuth     = 0x00080000
+	clientCapabilityBase = clientLongPassword | clientLongFlag | clientProtocol41 | clientTransactions | clientSecureConn | clientPluginAuth
+)
+
+// Commands
+const (
+	comQuery      = 0x03
+	comBinlogDump = 0x12
+)
+
+// First byte of response packet
+const (
+	packetOk       = 0x00
+	packetAuthMore = 0x01
+	packetEof      = 0xfe
+	packetErr      = 0xff
+)
+
+// Auth plugins
+const (
+	nativePassword = "mysql_native_password"
+	cachingSha2    = "caching_sha2_password"
+)
+
+// Error code of MySQL when binlog of the position can not be read
+// ex: the binlog file was purged
+const ErrCodeMasterFatalReadingBinlog = 1236
+
+// Config of connection to MySQL as replica
+// User needs REPLICATION SLAVE and REPLICATION CLIENT privileges
+type Config struct {
+	Addr     string
+	User     string
+	Password string
+
+	// Server id of replica. It must be unique among replicas of the MySQL server
+	ServerId uint32
+
+	// MySQL sends heartbeat event in this period while no event is written
+	// Read fails when no event arrived in 3 times of the period. If 0, heartbeat is not used
+	HeartbeatPeriod time.Duration
+
+	DialTimeout time.Duration
+}
+
+// Error packet of MySQL
+type MySQLError struct {
+	Code    uint16
+	Message string
+}
+
+func (e MySQLError) Error() string {
+	return fmt.Sprintf("MySQL error %d: %s", e.Code, e.Message)
+}
+
+// Connection to MySQL that reads binlog events
+type Conn struct {
+	packets  *packetConn
+	config   Config
+	checksum bool
+}
+
+// Connect and authenticate
+func Dial(config Config) (*Conn, error) {
+	netConn, err := net.DialTimeout("tcp", config.Addr, config.DialTimeout)
+	if err != nil {
+		return nil, err
+	}
+
+	conn := &Conn{packets: newPacketConn(netConn), config: config}
+	conn.packets.timeout = config.DialTimeout
+	if err := conn.handshake(); err != nil {
+		netConn.Close()
+		return nil, err
+	}
+	conn.packets.timeout = 0
+	return conn, nil
+}
+
+// Close connection
+// ReadEvent that is waiting for event returns error
+func (c *Conn) Close() error {
+	return c.packets.conn.Close()
+}
+
+// Run query that does not return rows
+func (c *Conn) Exec(query string) error {
+	if err := c.packets.writeCommand(comQuery, []byte(query)); err != nil {
+		return err
+	}
+	packet, err := c.packets.readPacket()
+	if err != nil {
+		return err
+	}
+	switch packet[0] {
+	case packetOk:
+		return nil
+	case packetErr:
+		return parseError(packet)
+	default:
+		return errors.New(fmt.Sprintf("Query returned rows. query = %s", query))
+	}
+}
+
+// Run query that returns one row of one column, and get the value
+// It returns error if the value is NULL
+func (c *Conn) QueryValue(query string) (string, error) {
+	if err := c.packets.writeCommand(comQuery, []byte(query)); err != nil {
+		return "", err
+	}
+	packet, err := c.packets.readPacket()
+	if err != nil {
+		return "", err
+	}
+	switch packet[0] {
+	case packetErr:
+		return "", parseError(packet)
+	case packetOk:
+		return "", errors.New(fmt.Sprintf("Query returned no rows. query = %s", query))
+	}
+	if columns, _, n := readLengthEncodedInt(packet); n == 0 || columns != 1 {
+		return "", errors.New(fmt.Sprintf("Query did not return one column. query = %s", query))
+	}
+
+	// Column definition and EOF, because CLIENT_DEPRECATE_EOF is not set
+	for {
+		packet, err := c.packets.readPacket()
+		if err != nil {
+			return "", err
+		}
+		if packet[0] == packetEof && len(packet) < 9 {
+			break
+		}
+	}
+
+	var value []byte
+	for {
+		packet, err := c.packets.readPacket()
+		if err != nil {
+			return "", err
+		}
+		switch {
+		case packet[0] == packetErr:
+			return "", parseError(packet)
+		case packet[0] == packetEof && len(packet) < 9:
+			if value == nil {
+				return "", errors.New(fmt.Sprintf("Query returned no value. query = %s", query))
+			}
+			return string(value), nil
+		case value == nil:
+			// Value of NULL is nil
+			value, _ = readLengthEncodedString(packet)
+		}
+	}
+}
+
+// Start binlog dump from position
+// Events are read by ReadEvent after that
+func (c *Conn) StartDump(position Position) error {
+	// Receive events with checksum of the server, and remove it in Parser
+	// Fake rotate event before format description has checksum if the server has
+	checksum, err := c.QueryValue("SELECT @@global.binlog_checksum")
+	if err != nil {
+		return err
+	}
+	c.checksum = !strings.EqualFold(checksum, "NONE")
+	if err := c.Exec("SET @master_binlog_checksum = @@global.binlog_checksum"); err != nil {
+		return err
+	}
+	if c.config.HeartbeatPeriod > 0 {
+		if err := c.Exec(fmt.Sprintf("SET @master_heartbeat_period = %d", c.config.HeartbeatPeriod.Nanoseconds())); err != nil {
+			return err
+		}
+		c.packets.timeout = 3 * c.config.HeartbeatPeriod
+	}
+
+	data := make([]byte, 10, 10+len(position.Name))
+	binary.LittleEndian.PutUint32(data[0:], position.Pos)
+	binary.LittleEndian.PutUint16(data[4:], 0)
+	binary.LittleEndian.PutUint32(data[6:], c.config.ServerId)
+	data = append(data, position.Name...)
+	return c.packets.writeCommand(comBinlogDump, data)
+}
+
+// Events of dump have checksum. It is known after StartDump
+func (c *Conn) Checksum() bool {
+	return c.checksum
+}
+
+// Read raw event
+// It returns MySQLError if MySQL can not send the binlog, and io.EOF if MySQL ended the dump
+func (c *Conn) ReadEvent() ([]byte, error) {
+	packet, err := c.packets.readPacket()
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case len(packet) == 0:
+		return nil, errors.New("Empty binlog packet")
+	case packet[0] == packetOk:
+		return packet[1:], nil
+	case packet[0] == packetErr:
+		return nil, parseError(packet)
+	case packet[0] == packetEof && len(packet) < 9:
+		return nil, io.EOF
+	default:
+		return nil, errors.New(fmt.Sprintf("Unknown binlog packet. header = %x", packet[0]))
+	}
+}
+
+// Read initial handshake, and send handshake response
+func (c *Conn) handshake() error {
+	packet, err := c.packets.readPacket()
+	if err != nil {
+		return err
+	}
+	if packet[0] == packetErr {
+		return parseError(packet)
+	}
+	if packet[0] != 10 {
+		return errors.New(fmt.Sprintf("Not supported protocol version %d", packet[0]))
+	}
+
+	// protocol version, server version, connection id
+	_, n := readNullTerminatedString(packet[1:])
+	pos := 1 + n + 4
+	if len(packet) < pos+8+1+2 {
+		return errors.New("Invalid handshake packet")
+	}
+	scramble := append([]byte{}, packet[pos:pos+8]...)
+	pos += 8 + 1
+	capabilities := uint32(binary.LittleEndian.Uint16(packet[pos:]))
+	pos += 2
+
+	plugin := nativePassword
+	if len(packet) > pos {
+		// charset, status flags, upper capabilities, auth data length, reserved
+		capabilities |= uint32(binary.LittleEndian.Uint16(packet[pos+3:])) << 16
+		authDataLength := int(packet[pos+5])
+		pos += 1 + 2 + 2 + 1 + 10
+
+		if capabilities&clientSecureConn != 0 {
+			length := authDataLength - 8
+			if length < 13 {
+				length = 13
+			}
+			if len(packet) < pos+length {
+				return errors.New("Invalid handshake packet")
+			}
+			// The last byte is null terminator
+			scramble = append(scramble, packet[pos:pos+length-1]...)
+			pos += length
+		}
+		if capabilities&clientPluginAuth != 0 && len(packet) > pos {
+			plugin, _ = readNullTerminatedString(packet[pos:])
+		}
+	}
+	if capabilities&clientProtocol41 == 0 {
+		return errors.New("MySQL server does not support protocol 41")
+	}
+
+	authResponse, err := scramblePassword(plugin, scramble, c.config.Password)
+	if err != nil {
+		return err
+	}
+
+	response := make([]byte, 32)
+	binary.LittleEndian.PutUint32(response[0:], clientCapabilityBase)
+	binary.LittleEndian.PutUint32(response[4:], maxPacketSize)
+	// utf8_general_ci
+	response[8] = 33
+	response = append(response, c.config.User...)
+	response = append(response, 0)
+	response = append(response, byte(len(authResponse)))
+	response = append(response, authResponse...)
+	response = append(response, plugin...)
+	response = append(response, 0)
+	if err := c.packets.writePacket(response); err != nil {
+		return err
+	}
+	return c.authResult(plugin, scramble)
+}
+
+// Read result of authentication until OK
+func (c *Conn) authResult(plugin string, scramble []byte) error {
+	for {
+		packet, err := c.packets.readPacket()
+		if err != nil {
+			return err
+		}
+		switch packet[0] {
+		case packetOk:
+			return nil
+
+		case packetErr:
+			return parseError(packet)
+
+		case packetEof:
+			// Auth switch request
+			name, n := readNullTerminatedString(packet[1:])
+			plugin = name
+			scramble = bytes.TrimRight(packet[1+n:], "\x00")
+			authResponse, err := scramblePassword(plugin, scramble, c.config.Password)
+			if err != nil {
+				return err
+			}
+			if err := c.packets.writePacket(authResponse); err != nil {
+				return err
+			}
+
+		case packetAuthMore:
+			if plugin != cachingSha2 || len(packet) < 2 {
+				return errors.New(fmt.Sprintf("Unknown auth packet. plugin = %s", plugin))
+			}
+			switch packet[1] {
+			case 3:
+				// Fast auth succeeded. OK packet follows
+			case 4:
+				// Full auth. Password is encrypted with public key of server, because connection is not TLS
+				if err := c.packets.writePacket([]byte{2}); err != nil {
+					return err
+				}
+				keyPacket, err := c.packets.readPacket()
+				if err != nil {
+					return err
+				}
+				encrypted, err := encryptPassword(keyPacket[1:], scramble, c.config.Password)
+				if err != nil {
+					return err
+				}
+				if err := c.packets.writePacket(encrypted); err != nil {
+					return err
+				}
+			default:
+				return errors.New(fmt.Sprintf("Unknown caching_sha2_password packet %d", packet[1]))
+			}
+
+		default:
+			return errors.New(fmt.Sprintf("Unknown auth packet. header = %x", packet[0]))
+		}
+	}
+}
+
+// Auth response of plugin
+func scramblePassword(plugin string, scramble []byte, password string) ([]byte, error) {
+	if password == "" {
+		return []byte{}, nil
+	}
+	if len(scramble) < 20 {
+		return nil, errors.New("Invalid scramble of MySQL server")
+	}
+	switch plugin {
+	case nativePassword:
+		// SHA1(password) XOR SHA1(scramble + SHA1(SHA1(password)))
+		stage1 := sha1.Sum([]byte(password))
+		stage2 := sha1.Sum(stage1[:])
+		hash := sha1.New()
+		hash.Write(scramble[:20])
+		hash.Write(stage2[:])
+		return xorBytes(stage1[:], hash.Sum(nil)), nil
+
+	case cachingSha2:
+		// SHA256(password) XOR SHA256(SHA256(SHA256(password)) + scramble)
+		stage1 := sha256.Sum256([]byte(password))
+		stage2 := sha256.Sum256(stage1[:])
+		hash := sha256.New()
+		hash.Write(stage2[:])
+		hash.Write(scramble[:20])
+		return xorBytes(stage1[:], hash.Sum(nil)), nil
+
+	default:
+		return nil, errors.New(fmt.Sprintf("Not supported auth plugin %s", plugin))
+	}
+}
+
+// Encrypt password with RSA public key of server for caching_sha2_password full auth
+func encryptPassword(publicKeyPem []byte, scramble []byte, password string) ([]byte, error) {
+	block, _ := pem.Decode(publicKeyPem)
+	if block == nil {
+		return nil, errors.New("Invalid public key of MySQL server")
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	publicKey, ok := key.(*rsa.PublicKey)
+	if !ok {
+		return nil, errors.New("Public key of MySQL server is not RSA")
+	}
+
+	plain := append([]byte(password), 0)
+	for i := range plain {
+		plain[i] ^= scramble[i%len(scramble)]
+	}
+	return rsa.EncryptOAEP(sha1.New(), rand.Reader, publicKey, plain, nil)
+}
+
+func xorBytes(a []byte, b []byte) []byte {
+	result := make([]byte, len(a))
+	for i := range a {
+		result[i] = a[i] ^ b[i]
+	}
+	return result
+}
+
+// Parse error packet
+// 0xff, error code(2), sql state marker and sql state(6), message
+func parseError(packet []byte) error {
+	if len(packet) < 3 {
+		return MySQLError{Message: "Unknown error"}
+	}
+	code := binary.LittleEndian.Uint16(packet[1:])
+	message := packet[3:]
+	if len(message) >= 6 && message[0] == '#' {
+		message = message[6:]
+	}
+	return MySQLError{Code: code, Message: strings.TrimSpace(string(message))}
+}
@@ -0,0 +1,208 @@
+package binlog
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Test dial with native password, start dump and read events
+func TestDial(t *testing.T) {
+	events := roleTransaction(true)
+	addr, queries := serveBinlog(t, func(server *packetConn) {
+		for _, event := range events {
+			server.writePacket(append([]byte{packetOk}, event...))
+		}
+		server.writePacket([]byte{packetEof, 0, 0, 0, 0})
+	})
+
+	conn, err := Dial(Config{Addr: addr, User: "replica", Password: "password", ServerId: 100, DialTimeout: time.Second})
+	if err != nil {
+		t.Errorf("Incorrect TestDial test. err = %v", err)
+		t.FailNow()
+	}
+	defer conn.Close()
+
+	if err := conn.StartDump(Position{Name: "mysql-bin.000001", Pos: 4}); err != nil {
+		t.Errorf("Incorrect TestDial test. err = %v", err)
+		t.FailNow()
+	}
+	for i, expected := range events {
+		event, err := conn.ReadEvent()
+		if err != nil || !bytes.Equal(event, expected) {
+			t.Errorf("Incorrect TestDial test. index = %d, err = %v", i, err)
+			t.FailNow()
+		}
+	}
+	if _, err := conn.ReadEvent(); err != io.EOF {
+		t.Errorf("Incorrect TestDial test. err = %v", err)
+		t.FailNow()
+	}
+
+	if !conn.Checksum() {
+		t.Errorf("Incorrect TestDial test. checksum = %v", conn.Checksum())
+		t.FailNow()
+	}
+	if query := <-queries; query != "SELECT @@global.binlog_checksum" {
+		t.Errorf("Incorrect TestDial test. query = %s", query)
+		t.FailNow()
+	}
+	if query := <-queries; query != "SET @master_binlog_checksum = @@global.binlog_checksum" {
+		t.Errorf("Incorrect TestDial test. query = %s", query)
+		t.FailNow()
+	}
+	if dump := <-queries; dump != "\x04\x00\x00\x00\x00\x00\x64\x00\x00\x00mysql-bin.000001" {
+		t.Errorf("Incorrect TestDial test. dump = %q", dump)
+		t.FailNow()
+	}
+}
+
+// Test read event of purged binlog
+func TestReadEvent_Error(t *testing.T) {
+	addr, _ := serveBinlog(t, func(server *packetConn) {
+		server.writePacket(append([]byte{packetErr, 0xd4, 0x04, '#', 'H', 'Y', '0', '0', '0'}, "Could not find first log file name in binary log index file"...))
+	})
+
+	conn, err := Dial(Config{Addr: addr, User: "replica", Password: "password", DialTimeout: time.Second})
+	if err != nil {
+		t.Errorf("Incorrect TestReadEvent_Error test. err = %v", err)
+		t.FailNow()
+	}
+	defer conn.Close()
+
+	conn.StartDump(Position{Name: "mysql-bin.000001", Pos: 4})
+	_, err = conn.ReadEvent()
+	if mysqlErr, ok := err.(MySQLError); !ok || mysqlErr.Code != ErrCodeMasterFatalReadingBinlog {
+		t.Errorf("Incorrect TestReadEvent_Error test. err = %v", err)
+		t.FailNow()
+	}
+}
+
+// Test dial with wrong password
+func TestDial_AccessDenied(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("Can not listen. %v", err)
+	}
+	defer listener.Close()
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		server := newPacketConn(conn)
+		server.writePacket(handshakePacket())
+		server.readPacket()
+		server.writePacket(append([]byte{packetErr, 0x15, 0x04, '#', '2', '8', '0', '0', '0'}, "Access denied"...))
+	}()
+
+	if _, err := Dial(Config{Addr: listener.Addr().String(), User: "replica", Password: "wrong", DialTimeout: time.Second}); err == nil {
+		t.Errorf("Incorrect TestDial_AccessDenied test")
+		t.FailNow()
+	}
+}
+
+// Test scramble of mysql_native_password
+func TestScramblePassword(t *testing.T) {
+	scramble := []byte("abcdefghijklmnopqrst")
+	response, err := scramblePassword(nativePassword, scramble, "password")
+	if err != nil || len(response) != 20 {
+		t.Errorf("Incorrect TestScramblePassword test. err = %v", err)
+		t.FailNow()
+	}
+
+	if response, err := scramblePassword(cachingSha2, scramble, "password"); err != nil || len(response) != 32 {
+		t.Errorf("Incorrect TestScramblePassword test. err = %v", err)
+		t.FailNow()
+	}
+	if response, err := scramblePassword(nativePassword, scramble, ""); err != nil || len(response) != 0 {
+		t.Errorf("Incorrect TestScramblePassword test. err = %v", err)
+		t.FailNow()
+	}
+	if _, err := scramblePassword("sha256_password", scramble, "password"); err == nil {
+		t.Errorf("Incorrect TestScramblePassword test")
+		t.FailNow()
+	}
+}
+
+// Test length encoded integer
+func TestLengthEncodedInt(t *testing.T) {
+	for _, value := range []uint64{0, 250, 251, 1 << 16, 1 << 24, 1 << 40} {
+		actual, isNull, n := readLengthEncodedInt(appendLengthEncodedInt(nil, value))
+		if actual != value || isNull || n == 0 {
+			t.Errorf("Incorrect TestLengthEncodedInt test. value = %d, actual = %d", value, actual)
+			t.FailNow()
+		}
+	}
+}
+
+// Fake MySQL server that accepts auth and queries, and calls dump after COM_BINLOG_DUMP
+// Queries and the data of dump command are sent to the channel
+func serveBinlog(t *testing.T, dump func(server *packetConn)) (string, chan string) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("Can not listen. %v", err)
+	}
+	queries := make(chan string, 10)
+	go func() {
+		defer listener.Close()
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		server := newPacketConn(conn)
+		server.writePacket(handshakePacket())
+		if _, err := server.readPacket(); err != nil {
+			return
+		}
+		server.writePacket([]byte{packetOk, 0, 0, 2, 0, 0, 0})
+
+		for {
+			server.sequence = 0
+			packet, err := server.readPacket()
+			if err != nil {
+				return
+			}
+			switch packet[0] {
+			case comQuery:
+				queries <- string(packet[1:])
+				if strings.HasPrefix(string(packet[1:]), "SELECT") {
+					writeValue(server, "CRC32")
+				} else {
+					server.writePacket([]byte{packetOk, 0, 0, 2, 0, 0, 0})
+				}
+			case comBinlogDump:
+				queries <- string(packet[1:])
+				dump(server)
+			}
+		}
+	}()
+	return listener.Addr().String(), queries
+}
+
+// Write result set of one row of one column
+func writeValue(server *packetConn, value string) {
+	server.writePacket([]byte{1})
+	server.writePacket(append([]byte{3}, "def"...))
+	server.writePacket([]byte{packetEof, 0, 0, 2, 0})
+	server.writePacket(append(appendLengthEncodedInt(nil, uint64(len(value))), value...))
+	server.writePacket([]byte{packetEof, 0, 0, 2, 0})
+}
+
+// Handshake v10 of MySQL 8.0 with mysql_native_password
+func handshakePacket() []byte {
+	packet := []byte{10}
+	packet = append(packet, "8.0.19\x00"...)
+	packet = append(packet, 1, 0, 0, 0)
+	packet = append(packet, "abcdefgh"...)
+	packet = append(packet, 0, 0xff, 0xf7, 33, 2, 0, 0xff, 0xc3, 21)
+	packet = append(packet, make([]byte, 10)...)
+	packet = append(packet, "ijklmnopqrst\x00"...)
+	return append(packet, nativePassword+"\x00"...)
+}
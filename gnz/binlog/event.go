@@ -0,0 +1,417 @@
+package binlog
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"strconv"
+	"strings"
+)
+
+// Event types
+const (
+	QueryEvent             = 2
+	RotateEvent            = 4
+	FormatDescriptionEvent = 15
+	XidEvent               = 16
+	TableMapEvent          = 19
+	WriteRowsEventV1       = 23
+	UpdateRowsEventV1      = 24
+	DeleteRowsEventV1      = 25
+	HeartbeatEvent         = 27
+	WriteRowsEventV2       = 30
+	UpdateRowsEventV2      = 31
+	DeleteRowsEventV2      = 32
+)
+
+// Size of event header
+const EventHeaderSize = 19
+
+// Optional metadata type of table map that has column names
+const columnNameMetadata = 4
+
+// Position of binlog
+type Position struct {
+	Name string `json:"name"`
+	Pos  uint32 `json:"pos"`
+}
+
+func (p Position) String() string {
+	return fmt.Sprintf("%s:%d", p.Name, p.Pos)
+}
+
+// Header of event
+type EventHeader struct {
+	Timestamp uint32
+	Type      byte
+	ServerId  uint32
+	EventSize uint32
+
+	// End position of the event in the binlog file
+	LogPos uint32
+	Flags  uint16
+}
+
+// Parsed event
+// Body is *Rotate, *TableMap, *Rows, *Xid or nil for events that are not used
+type Event struct {
+	Header EventHeader
+	Body   interface{}
+}
+
+// Rotate to next binlog file
+type Rotate struct {
+	Next Position
+}
+
+// Commit of transaction
+type Xid struct {
+	Xid uint64
+}
+
+// Table of rows events that follow
+type TableMap struct {
+	TableId     uint64
+	Schema      string
+	Table       string
+	ColumnTypes []byte
+	ColumnMeta  []uint16
+
+	// Names of columns. It is empty unless binlog_row_metadata is FULL
+	ColumnNames []string
+}
+
+// Kind of row change
+const (
+	RowsInsert = "insert"
+	RowsUpdate = "update"
+	RowsDelete = "delete"
+)
+
+// Row change of one table
+type Rows struct {
+	Kind  string
+	Table *TableMap
+
+	// Values of rows by column index. Column that is not in the image is nil
+	// For update, each element is a pair of before and after image
+	Rows [][]interface{}
+}
+
+// Parser of events of one binlog stream
+// It keeps the format description and table maps of the stream
+type Parser struct {
+	checksum bool
+	format   bool
+	tables   map[uint64]*TableMap
+
+	// Schema of rows to parse. Rows of other schema are not parsed
+	Schema string
+
+	// Tables of rows to parse. If empty, all tables of schema are parsed
+	Tables map[string]bool
+
+	// Events before format description have checksum, that is fake rotate event of binlog dump
+	// Events after it have checksum by its algorithm
+	DumpChecksum bool
+}
+
+// Constructor
+func NewParser(schema string, tables []string) *Parser {
+	parser := &Parser{tables: make(map[uint64]*TableMap), Schema: schema, Tables: make(map[string]bool)}
+	for _, table := range tables {
+		parser.Tables[table] = true
+	}
+	return parser
+}
+
+// Parse raw event
+func (p *Parser) Parse(data []byte) (Event, error) {
+	if len(data) < EventHeaderSize {
+		return Event{}, errors.New(fmt.Sprintf("Event is too short. length = %d", len(data)))
+	}
+	header := EventHeader{
+		Timestamp: binary.LittleEndian.Uint32(data[0:]),
+		Type:      data[4],
+		ServerId:  binary.LittleEndian.Uint32(data[5:]),
+		EventSize: binary.LittleEndian.Uint32(data[9:]),
+		LogPos:    binary.LittleEndian.Uint32(data[13:]),
+		Flags:     binary.LittleEndian.Uint16(data[17:]),
+	}
+	event := Event{Header: header}
+
+	if header.Type == FormatDescriptionEvent {
+		checksum, err := parseChecksum(data[EventHeaderSize:])
+		if err != nil {
+			return event, err
+		}
+		p.checksum = checksum
+		p.format = true
+	}
+
+	body := data[EventHeaderSize:]
+	if p.checksum || (!p.format && p.DumpChecksum) {
+		if len(body) < 4 {
+			return event, errors.New("Event is too short for checksum")
+		}
+		if !hasChecksum(data) {
+			return event, errors.New(fmt.Sprintf("Invalid event checksum. type = %d, log_pos = %d", header.Type, header.LogPos))
+		}
+		body = body[:len(body)-4]
+	}
+
+	var err error
+	switch header.Type {
+	case RotateEvent:
+		event.Body, err = parseRotate(body)
+	case XidEvent:
+		if len(body) < 8 {
+			return event, errors.New("Invalid xid event")
+		}
+		event.Body = &Xid{Xid: binary.LittleEndian.Uint64(body)}
+	case TableMapEvent:
+		var table *TableMap
+		table, err = parseTableMap(body)
+		if err == nil {
+			p.tables[table.TableId] = table
+			event.Body = table
+		}
+	case WriteRowsEventV1, WriteRowsEventV2, UpdateRowsEventV1, UpdateRowsEventV2, DeleteRowsEventV1, DeleteRowsEventV2:
+		var rows *Rows
+		rows, err = p.parseRows(header.Type, body)
+		if rows != nil {
+			event.Body = rows
+		}
+	}
+	return event, err
+}
+
+// Last 4 bytes of event are CRC32 of the rest
+func hasChecksum(data []byte) bool {
+	return len(data) >= EventHeaderSize+4 && crc32.ChecksumIEEE(data[:len(data)-4]) == binary.LittleEndian.Uint32(data[len(data)-4:])
+}
+
+// Events after format description have checksum if its algorithm is CRC32
+// Algorithm is the byte before checksum of format description, from MySQL 5.6.1
+func parseChecksum(body []byte) (bool, error) {
+	if len(body) < 2+50 {
+		return false, errors.New("Invalid format description event")
+	}
+	version := strings.TrimRight(string(body[2:52]), "\x00")
+	if !versionAtLeast(version, 5, 6, 1) {
+		return false, nil
+	}
+	if len(body) < 5 {
+		return false, errors.New("Invalid format description event")
+	}
+	return body[len(body)-5] == 1, nil
+}
+
+// Server version is the version or later
+func versionAtLeast(version string, major int, minor int, patch int) bool {
+	parts := strings.SplitN(strings.SplitN(version, "-", 2)[0], ".", 3)
+	expected := []int{major, minor, patch}
+	for i, part := range parts {
+		value, err := strconv.Atoi(part)
+		if err != nil {
+			return false
+		}
+		if value != expected[i] {
+			return value > expected[i]
+		}
+	}
+	return len(parts) == 3
+}
+
+func parseRotate(body []byte) (*Rotate, error) {
+	if len(body) < 8 {
+		return nil, errors.New("Invalid rotate event")
+	}
+	return &Rotate{Next: Position{Name: string(body[8:]), Pos: uint32(binary.LittleEndian.Uint64(body))}}, nil
+}
+
+// table id(6), flags(2), schema, table, column count, column types, metadata, null bitmap, optional metadata
+func parseTableMap(body []byte) (*TableMap, error) {
+	invalid := errors.New("Invalid table map event")
+	if len(body) < 8+1 {
+		return nil, invalid
+	}
+	table := &TableMap{TableId: readTableId(body)}
+	pos := 8
+
+	schemaLength := int(body[pos])
+	if len(body) < pos+1+schemaLength+1+1 {
+		return nil, invalid
+	}
+	table.Schema = string(body[pos+1 : pos+1+schemaLength])
+	pos += 1 + schemaLength + 1
+
+	tableLength := int(body[pos])
+	if len(body) < pos+1+tableLength+1 {
+		return nil, invalid
+	}
+	table.Table = string(body[pos+1 : pos+1+tableLength])
+	pos += 1 + tableLength + 1
+
+	columnCount, _, n := readLengthEncodedInt(body[pos:])
+	pos += n
+	if n == 0 || len(body) < pos+int(columnCount) {
+		return nil, invalid
+	}
+	table.ColumnTypes = append([]byte{}, body[pos:pos+int(columnCount)]...)
+	pos += int(columnCount)
+
+	metadata, n := readLengthEncodedString(body[pos:])
+	if n == 0 {
+		return nil, invalid
+	}
+	pos += n
+	columnMeta, err := parseColumnMeta(table.ColumnTypes, metadata)
+	if err != nil {
+		return nil, err
+	}
+	table.ColumnMeta = columnMeta
+
+	// Null bitmap
+	pos += (int(columnCount) + 7) / 8
+	for pos < len(body) {
+		metadataType := body[pos]
+		value, n := readLengthEncodedString(body[pos+1:])
+		if n == 0 {
+			return nil, invalid
+		}
+		pos += 1 + n
+		if metadataType == columnNameMetadata {
+			for len(value) > 0 {
+				name, n := readLengthEncodedString(value)
+				if n == 0 {
+					return nil, invalid
+				}
+				table.ColumnNames = append(table.ColumnNames, string(name))
+				value = value[n:]
+			}
+		}
+	}
+	return table, nil
+}
+
+// table id(6), flags(2), extra data for v2, column count, columns present bitmap(s), rows
+func (p *Parser) parseRows(eventType byte, body []byte) (*Rows, error) {
+	invalid := errors.New("Invalid rows event")
+	if len(body) < 8 {
+		return nil, invalid
+	}
+	table, ok := p.tables[readTableId(body)]
+	if !ok {
+		return nil, errors.New(fmt.Sprintf("Not found table map of rows event. table_id = %d", readTableId(body)))
+	}
+	if table.Schema != p.Schema || len(p.Tables) != 0 && !p.Tables[table.Table] {
+		return nil, nil
+	}
+
+	rows := &Rows{Table: table}
+	switch eventType {
+	case WriteRowsEventV1, WriteRowsEventV2:
+		rows.Kind = RowsInsert
+	case UpdateRowsEventV1, UpdateRowsEventV2:
+		rows.Kind = RowsUpdate
+	default:
+		rows.Kind = RowsDelete
+	}
+
+	pos := 8
+	if eventType >= WriteRowsEventV2 {
+		if len(body) < pos+2 {
+			return nil, invalid
+		}
+		// Length of extra data includes its length bytes
+		pos += int(binary.LittleEndian.Uint16(body[pos:]))
+	}
+
+	columnCount, _, n := readLengthEncodedInt(body[pos:])
+	pos += n
+	if n == 0 || int(columnCount) != len(table.ColumnTypes) {
+		return nil, errors.New(fmt.Sprintf("Column count of rows event does not match table map. table = %s", table.Table))
+	}
+	bitmapSize := (int(columnCount) + 7) / 8
+	if len(body) < pos+bitmapSize {
+		return nil, invalid
+	}
+	present := body[pos : pos+bitmapSize]
+	pos += bitmapSize
+	presentAfter := present
+	if rows.Kind == RowsUpdate {
+		if len(body) < pos+bitmapSize {
+			return nil, invalid
+		}
+		presentAfter = body[pos : pos+bitmapSize]
+		pos += bitmapSize
+	}
+
+	for pos < len(body) {
+		row, n, err := parseRow(table, present, body[pos:])
+		if err != nil {
+			return nil, err
+		}
+		pos += n
+		rows.Rows = append(rows.Rows, row)
+
+		if rows.Kind == RowsUpdate {
+			after, n, err := parseRow(table, presentAfter, body[pos:])
+			if err != nil {
+				return nil, err
+			}
+			pos += n
+			rows.Rows = append(rows.Rows, after)
+		}
+	}
+	return rows, nil
+}
+
+// Null bitmap of present columns, and values of present and not null columns
+func parseRow(table *TableMap, present []byte, data []byte) ([]interface{}, int, error) {
+	columnCount := len(table.ColumnTypes)
+	presentCount := 0
+	for i := 0; i < columnCount; i++ {
+		if isBitSet(present, i) {
+			presentCount++
+		}
+	}
+
+	nullSize := (presentCount + 7) / 8
+	if len(data) < nullSize {
+		return nil, 0, errors.New("Invalid row of rows event")
+	}
+	nulls := data[:nullSize]
+	pos := nullSize
+
+	row := make([]interface{}, columnCount)
+	nullIndex := 0
+	for i := 0; i < columnCount; i++ {
+		if !isBitSet(present, i) {
+			continue
+		}
+		isNull := isBitSet(nulls, nullIndex)
+		nullIndex++
+		if isNull {
+			continue
+		}
+
+		value, n, err := decodeValue(table.ColumnTypes[i], table.ColumnMeta[i], data[pos:])
+		if err != nil {
+			return nil, 0, errors.New(fmt.Sprintf("Failed to decode column %d of %s. %s", i, table.Table, err.Error()))
+		}
+		row[i] = value
+		pos += n
+	}
+	return row, pos, nil
+}
+
+func readTableId(body []byte) uint64 {
+	return uint64(body[0]) | uint64(body[1])<<8 | uint64(body[2])<<16 | uint64(body[3])<<24 | uint64(body[4])<<32 | uint64(body[5])<<40
+}
+
+func isBitSet(bitmap []byte, i int) bool {
+	return bitmap[i/8]&(1<<uint(i%8)) != 0
+}
@@ -0,0 +1,244 @@
+package binlog
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"testing"
+)
+
+// Column types and metadata of `roles` table
+var (
+	roleColumnTypes = []byte{TypeLong, TypeVarchar, TypeVarchar, TypeVarchar, TypeDatetime2, TypeDatetime2}
+	roleColumnMeta  = []byte{96, 0, 128, 1, 128, 1, 0, 0}
+	roleColumnNames = []string{"id", "internal_id", "uuid", "name", "created_at", "updated_at"}
+)
+
+// Test parse events of transaction with checksum
+func TestParse(t *testing.T) {
+	parser := NewParser("grant_n_z", []string{"roles"})
+	parser.DumpChecksum = true
+	for i, data := range roleTransaction(true) {
+		event, err := parser.Parse(data)
+		if err != nil {
+			t.Errorf("Incorrect TestParse test. index = %d, err = %v", i, err)
+			t.FailNow()
+		}
+
+		switch body := event.Body.(type) {
+		case *TableMap:
+			if body.Table != "roles" || len(body.ColumnNames) != 6 || body.ColumnNames[2] != "uuid" {
+				t.Errorf("Incorrect TestParse test. table = %v", body)
+				t.FailNow()
+			}
+		case *Rows:
+			if body.Kind != RowsUpdate || len(body.Rows) != 2 || body.Rows[0][3] != "user" || body.Rows[1][3] != "admin" {
+				t.Errorf("Incorrect TestParse test. rows = %v", body.Rows)
+				t.FailNow()
+			}
+			if body.Rows[1][0] != int64(1) || body.Rows[1][4] != "2020-04-01 12:30:15" {
+				t.Errorf("Incorrect TestParse test. row = %v", body.Rows[1])
+				t.FailNow()
+			}
+		case *Xid:
+			if body.Xid != 100 {
+				t.Errorf("Incorrect TestParse test. xid = %d", body.Xid)
+				t.FailNow()
+			}
+		}
+	}
+}
+
+// Test parse events without checksum
+func TestParse_NoChecksum(t *testing.T) {
+	parser := NewParser("grant_n_z", nil)
+	var rows *Rows
+	for _, data := range roleTransaction(false) {
+		event, err := parser.Parse(data)
+		if err != nil {
+			t.Errorf("Incorrect TestParse_NoChecksum test. err = %v", err)
+			t.FailNow()
+		}
+		if body, ok := event.Body.(*Rows); ok {
+			rows = body
+		}
+	}
+
+	if rows == nil || rows.Rows[1][2] != "role-uuid" {
+		t.Errorf("Incorrect TestParse_NoChecksum test. rows = %v", rows)
+		t.FailNow()
+	}
+}
+
+// Test parse rows of other table
+func TestParse_OtherTable(t *testing.T) {
+	parser := NewParser("grant_n_z", []string{"policies"})
+	parser.DumpChecksum = true
+	for _, data := range roleTransaction(true) {
+		event, err := parser.Parse(data)
+		if err != nil {
+			t.Errorf("Incorrect TestParse_OtherTable test. err = %v", err)
+			t.FailNow()
+		}
+		if event.Header.Type == UpdateRowsEventV2 && event.Body != nil {
+			t.Errorf("Incorrect TestParse_OtherTable test. body = %v", event.Body)
+			t.FailNow()
+		}
+	}
+}
+
+// Test parse event of invalid checksum
+func TestParse_InvalidChecksum(t *testing.T) {
+	parser := NewParser("grant_n_z", nil)
+	events := roleTransaction(true)
+	if _, err := parser.Parse(events[1]); err != nil {
+		t.Errorf("Incorrect TestParse_InvalidChecksum test. err = %v", err)
+		t.FailNow()
+	}
+
+	events[2][len(events[2])-1] ^= 0xff
+	if _, err := parser.Parse(events[2]); err == nil {
+		t.Errorf("Incorrect TestParse_InvalidChecksum test")
+		t.FailNow()
+	}
+}
+
+// Test parse rotate event
+func TestParse_Rotate(t *testing.T) {
+	// Fake rotate event of binlog dump has checksum before format description
+	parser := NewParser("grant_n_z", nil)
+	parser.DumpChecksum = true
+	event, err := parser.Parse(roleTransaction(true)[0])
+	if err != nil || event.Body.(*Rotate).Next != (Position{Name: "mysql-bin.000002", Pos: 4}) {
+		t.Errorf("Incorrect TestParse_Rotate test. event = %v, err = %v", event, err)
+		t.FailNow()
+	}
+
+	// Checksum of fake rotate event is verified, and is not guessed from its bytes
+	rotate := roleTransaction(true)[0]
+	rotate[len(rotate)-1] ^= 0xff
+	if _, err := parser.Parse(rotate); err == nil {
+		t.Errorf("Incorrect TestParse_Rotate test")
+		t.FailNow()
+	}
+
+	event, err = NewParser("grant_n_z", nil).Parse(roleTransaction(false)[0])
+	if err != nil || event.Body.(*Rotate).Next.Name != "mysql-bin.000002" {
+		t.Errorf("Incorrect TestParse_Rotate test. event = %v, err = %v", event, err)
+		t.FailNow()
+	}
+}
+
+// Test parse rows without table map
+func TestParse_NotFoundTableMap(t *testing.T) {
+	parser := NewParser("grant_n_z", nil)
+	events := roleTransaction(true)
+	parser.Parse(events[1])
+	if _, err := parser.Parse(events[4]); err == nil {
+		t.Errorf("Incorrect TestParse_NotFoundTableMap test")
+		t.FailNow()
+	}
+}
+
+// Test server version
+func TestVersionAtLeast(t *testing.T) {
+	if !versionAtLeast("8.0.19-log", 5, 6, 1) || !versionAtLeast("5.6.1", 5, 6, 1) || versionAtLeast("5.5.62", 5, 6, 1) {
+		t.Errorf("Incorrect TestVersionAtLeast test")
+		t.FailNow()
+	}
+}
+
+// Rotate, format description, query, table map, update rows and xid of `roles` table
+// Events are encoded in the same layout as MySQL 8.0 writes with binlog_row_metadata = FULL
+func roleTransaction(checksum bool) [][]byte {
+	rotate := make([]byte, 8)
+	binary.LittleEndian.PutUint64(rotate, 4)
+	rotate = append(rotate, "mysql-bin.000002"...)
+
+	tableMap := []byte{70, 0, 0, 0, 0, 0, 1, 0}
+	tableMap = append(tableMap, byte(len("grant_n_z")))
+	tableMap = append(tableMap, "grant_n_z\x00"...)
+	tableMap = append(tableMap, byte(len("roles")))
+	tableMap = append(tableMap, "roles\x00"...)
+	tableMap = append(tableMap, byte(len(roleColumnTypes)))
+	tableMap = append(tableMap, roleColumnTypes...)
+	tableMap = append(tableMap, byte(len(roleColumnMeta)))
+	tableMap = append(tableMap, roleColumnMeta...)
+	tableMap = append(tableMap, 0)
+	var names []byte
+	for _, name := range roleColumnNames {
+		names = append(names, byte(len(name)))
+		names = append(names, name...)
+	}
+	tableMap = append(tableMap, columnNameMetadata, byte(len(names)))
+	tableMap = append(tableMap, names...)
+
+	rows := []byte{70, 0, 0, 0, 0, 0, 1, 0, 2, 0, 6, 0x3f, 0x3f}
+	rows = append(rows, roleRow("user")...)
+	rows = append(rows, roleRow("admin")...)
+
+	xid := make([]byte, 8)
+	binary.LittleEndian.PutUint64(xid, 100)
+
+	return [][]byte{
+		encodeEvent(RotateEvent, 0, rotate, checksum),
+		encodeEvent(FormatDescriptionEvent, 124, formatDescription(checksum), checksum),
+		encodeEvent(QueryEvent, 200, []byte("BEGIN"), checksum),
+		encodeEvent(TableMapEvent, 300, tableMap, checksum),
+		encodeEvent(UpdateRowsEventV2, 400, rows, checksum),
+		encodeEvent(XidEvent, 431, xid, checksum),
+	}
+}
+
+func roleRow(name string) []byte {
+	// Length of varchar(32) is 1 byte, and varchar(128) is 2 bytes
+	row := []byte{0, 1, 0, 0, 0, byte(len("internal"))}
+	row = append(row, "internal"...)
+	for _, value := range []string{"role-uuid", name} {
+		row = append(row, byte(len(value)), 0)
+		row = append(row, value...)
+	}
+	datetime := encodeDatetime2(2020, 4, 1, 12, 30, 15)
+	row = append(row, datetime...)
+	return append(row, datetime...)
+}
+
+func formatDescription(checksum bool) []byte {
+	body := []byte{4, 0}
+	version := make([]byte, 50)
+	copy(version, "8.0.19")
+	body = append(body, version...)
+	body = append(body, 0, 0, 0, 0, EventHeaderSize)
+	body = append(body, bytes.Repeat([]byte{0}, 40)...)
+	if checksum {
+		return append(body, 1)
+	}
+	// Checksum field exists even if checksum is off
+	return append(body, 0, 0, 0, 0, 0)
+}
+
+func encodeEvent(eventType byte, logPos uint32, body []byte, checksum bool) []byte {
+	size := EventHeaderSize + len(body)
+	if checksum {
+		size += 4
+	}
+	data := make([]byte, EventHeaderSize, size)
+	binary.LittleEndian.PutUint32(data[0:], 1585744215)
+	data[4] = eventType
+	binary.LittleEndian.PutUint32(data[5:], 1)
+	binary.LittleEndian.PutUint32(data[9:], uint32(size))
+	binary.LittleEndian.PutUint32(data[13:], logPos)
+	data = append(data, body...)
+	if checksum {
+		sum := make([]byte, 4)
+		binary.LittleEndian.PutUint32(sum, crc32.ChecksumIEEE(data))
+		data = append(data, sum...)
+	}
+	return data
+}
+
+func encodeDatetime2(year int64, month int64, day int64, hour int64, minute int64, second int64) []byte {
+	value := ((year*13+month)<<5|day)<<17 | hour<<12 | minute<<6 | second
+	value += 0x8000000000
+	return []byte{byte(value >> 32), byte(value >> 24), byte(value >> 16), byte(value >> 8), byte(value)}
+}
@@ -0,0 +1,138 @@
+package binlog
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// Test dump and parse binlog of MySQL of TEST_MYSQL_DSN, such as `root:password@tcp(127.0.0.1:3306)/grant_n_z`
+// MySQL needs binlog_format = ROW and binlog_row_image = FULL, and the user needs REPLICATION SLAVE and REPLICATION CLIENT
+func TestMySQL(t *testing.T) {
+	dsn := os.Getenv("TEST_MYSQL_DSN")
+	if dsn == "" {
+		t.Skip("TEST_MYSQL_DSN is not set")
+	}
+	config, err := mysql.ParseDSN(dsn)
+	if err != nil {
+		t.Errorf("Incorrect TestMySQL test. err = %v", err)
+		t.FailNow()
+	}
+	db, err := sql.Open("mysql", dsn)
+	if err != nil {
+		t.Errorf("Incorrect TestMySQL test. err = %v", err)
+		t.FailNow()
+	}
+	defer db.Close()
+
+	queries := []string{
+		"CREATE TABLE IF NOT EXISTS binlog_test (id int NOT NULL PRIMARY KEY, uuid varchar(128) NOT NULL, name varchar(128) NOT NULL, created_at datetime NOT NULL, deleted_at datetime NULL)",
+		"DELETE FROM binlog_test",
+	}
+	for _, query := range queries {
+		if _, err := db.Exec(query); err != nil {
+			t.Errorf("Incorrect TestMySQL test. query = %s, err = %v", query, err)
+			t.FailNow()
+		}
+	}
+	position := masterStatus(t, db)
+
+	queries = []string{
+		"INSERT INTO binlog_test (id, uuid, name, created_at) VALUES (1, 'role-uuid', 'user', '2020-04-01 12:30:15')",
+		"UPDATE binlog_test SET name = 'ロール' WHERE id = 1",
+		"DELETE FROM binlog_test WHERE id = 1",
+	}
+	for _, query := range queries {
+		if _, err := db.Exec(query); err != nil {
+			t.Errorf("Incorrect TestMySQL test. query = %s, err = %v", query, err)
+			t.FailNow()
+		}
+	}
+
+	conn, err := Dial(Config{
+		Addr:            config.Addr,
+		User:            config.User,
+		Password:        config.Passwd,
+		ServerId:        uint32(3000000000 + time.Now().UnixNano()%1000000),
+		HeartbeatPeriod: time.Second,
+		DialTimeout:     10 * time.Second,
+	})
+	if err != nil {
+		t.Errorf("Incorrect TestMySQL test. err = %v", err)
+		t.FailNow()
+	}
+	defer conn.Close()
+	if err := conn.StartDump(position); err != nil {
+		t.Errorf("Incorrect TestMySQL test. err = %v", err)
+		t.FailNow()
+	}
+
+	parser := NewParser(config.DBName, []string{"binlog_test"})
+	parser.DumpChecksum = conn.Checksum()
+	var changes []*Rows
+	deadline := time.Now().Add(10 * time.Second)
+	for len(changes) < len(queries) && time.Now().Before(deadline) {
+		data, err := conn.ReadEvent()
+		if err != nil {
+			t.Errorf("Incorrect TestMySQL test. err = %v", err)
+			t.FailNow()
+		}
+		event, err := parser.Parse(data)
+		if err != nil {
+			t.Errorf("Incorrect TestMySQL test. type = %d, err = %v", event.Header.Type, err)
+			t.FailNow()
+		}
+		if rows, ok := event.Body.(*Rows); ok {
+			changes = append(changes, rows)
+		}
+	}
+
+	if len(changes) != len(queries) {
+		t.Errorf("Incorrect TestMySQL test. changes = %d", len(changes))
+		t.FailNow()
+	}
+	inserted := fmt.Sprint(changes[0].Rows[0])
+	if changes[0].Kind != RowsInsert || inserted != "[1 role-uuid user 2020-04-01 12:30:15 <nil>]" {
+		t.Errorf("Incorrect TestMySQL test. insert = %s", inserted)
+		t.FailNow()
+	}
+	if changes[1].Kind != RowsUpdate || changes[1].Rows[0][2] != "user" || changes[1].Rows[1][2] != "ロール" {
+		t.Errorf("Incorrect TestMySQL test. update = %v", changes[1].Rows)
+		t.FailNow()
+	}
+	if changes[2].Kind != RowsDelete || changes[2].Rows[0][0] != int64(1) {
+		t.Errorf("Incorrect TestMySQL test. delete = %v", changes[2].Rows)
+		t.FailNow()
+	}
+}
+
+// Current binlog position of MySQL
+func masterStatus(t *testing.T, db *sql.DB) Position {
+	rows, err := db.Query("SHOW MASTER STATUS")
+	if err != nil {
+		t.Errorf("Incorrect TestMySQL test. err = %v", err)
+		t.FailNow()
+	}
+	defer rows.Close()
+	columns, _ := rows.Columns()
+	if !rows.Next() {
+		t.Errorf("Incorrect TestMySQL test. Binlog is not enabled")
+		t.FailNow()
+	}
+	values := make([]interface{}, len(columns))
+	var position Position
+	values[0] = &position.Name
+	values[1] = &position.Pos
+	for i := 2; i < len(values); i++ {
+		values[i] = new(sql.RawBytes)
+	}
+	if err := rows.Scan(values...); err != nil {
+		t.Errorf("Incorrect TestMySQL test. err = %v", err)
+		t.FailNow()
+	}
+	return position
+}
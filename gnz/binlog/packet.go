@@ -0,0 +1,155 @@
+package binlog
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"time"
+)
+
+// Max payload of one MySQL packet. Larger payload is split to several packets
+const maxPacketSize = 1<<24 - 1
+
+// Max size of event. It is max_allowed_packet of MySQL
+const maxEventSize = 1 << 30
+
+// Packet connection of MySQL client/server protocol
+type packetConn struct {
+	conn     net.Conn
+	reader   *bufio.Reader
+	sequence uint8
+	timeout  time.Duration
+}
+
+func newPacketConn(conn net.Conn) *packetConn {
+	return &packetConn{conn: conn, reader: bufio.NewReaderSize(conn, 64*1024)}
+}
+
+// Read payload of packet
+// If timeout is set, read fails when no packet arrived in the timeout
+func (p *packetConn) readPacket() ([]byte, error) {
+	var payload []byte
+	for {
+		if p.timeout > 0 {
+			p.conn.SetReadDeadline(time.Now().Add(p.timeout))
+		}
+		header := make([]byte, 4)
+		if _, err := io.ReadFull(p.reader, header); err != nil {
+			return nil, err
+		}
+		length := int(uint32(header[0]) | uint32(header[1])<<8 | uint32(header[2])<<16)
+		if header[3] != p.sequence {
+			return nil, errors.New(fmt.Sprintf("Invalid packet sequence. expected = %d, actual = %d", p.sequence, header[3]))
+		}
+		p.sequence++
+
+		data := make([]byte, length)
+		if _, err := io.ReadFull(p.reader, data); err != nil {
+			return nil, err
+		}
+		payload = append(payload, data...)
+		if length < maxPacketSize {
+			return payload, nil
+		}
+	}
+}
+
+// Write payload as packets
+func (p *packetConn) writePacket(payload []byte) error {
+	for {
+		length := len(payload)
+		if length > maxPacketSize {
+			length = maxPacketSize
+		}
+		packet := make([]byte, 4+length)
+		packet[0] = byte(length)
+		packet[1] = byte(length >> 8)
+		packet[2] = byte(length >> 16)
+		packet[3] = p.sequence
+		copy(packet[4:], payload[:length])
+		p.sequence++
+
+		if _, err := p.conn.Write(packet); err != nil {
+			return err
+		}
+		payload = payload[length:]
+		if length < maxPacketSize {
+			return nil
+		}
+	}
+}
+
+// Write command packet. Command starts new sequence
+func (p *packetConn) writeCommand(command byte, data []byte) error {
+	p.sequence = 0
+	return p.writePacket(append([]byte{command}, data...))
+}
+
+// Read length encoded integer
+// It returns the value, null or not, and read bytes
+func readLengthEncodedInt(data []byte) (uint64, bool, int) {
+	if len(data) == 0 {
+		return 0, false, 0
+	}
+	switch data[0] {
+	case 0xfb:
+		return 0, true, 1
+	case 0xfc:
+		if len(data) < 3 {
+			return 0, false, 0
+		}
+		return uint64(binary.LittleEndian.Uint16(data[1:])), false, 3
+	case 0xfd:
+		if len(data) < 4 {
+			return 0, false, 0
+		}
+		return uint64(data[1]) | uint64(data[2])<<8 | uint64(data[3])<<16, false, 4
+	case 0xfe:
+		if len(data) < 9 {
+			return 0, false, 0
+		}
+		return binary.LittleEndian.Uint64(data[1:]), false, 9
+	default:
+		return uint64(data[0]), false, 1
+	}
+}
+
+// Read length encoded string
+// It returns the value and read bytes. Read bytes is 0 if data is short
+func readLengthEncodedString(data []byte) ([]byte, int) {
+	length, isNull, n := readLengthEncodedInt(data)
+	if isNull || n == 0 || len(data) < n+int(length) {
+		return nil, n
+	}
+	return data[n : n+int(length)], n + int(length)
+}
+
+// Read null terminated string
+func readNullTerminatedString(data []byte) (string, int) {
+	for i, b := range data {
+		if b == 0 {
+			return string(data[:i]), i + 1
+		}
+	}
+	return string(data), len(data)
+}
+
+// Encode length encoded integer
+func appendLengthEncodedInt(data []byte, value uint64) []byte {
+	switch {
+	case value < 251:
+		return append(data, byte(value))
+	case value < 1<<16:
+		return append(data, 0xfc, byte(value), byte(value>>8))
+	case value < 1<<24:
+		return append(data, 0xfd, byte(value), byte(value>>8), byte(value>>16))
+	default:
+		buf := make([]byte, 9)
+		buf[0] = 0xfe
+		binary.LittleEndian.PutUint64(buf[1:], value)
+		return append(data, buf...)
+	}
+}
@@ -0,0 +1,251 @@
+package binlog
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+// Column types
+const (
+	TypeTiny       = 1
+	TypeShort      = 2
+	TypeLong       = 3
+	TypeFloat      = 4
+	TypeDouble     = 5
+	TypeTimestamp  = 7
+	TypeLongLong   = 8
+	TypeInt24      = 9
+	TypeDate       = 10
+	TypeTime       = 11
+	TypeDatetime   = 12
+	TypeYear       = 13
+	TypeVarchar    = 15
+	TypeTimestamp2 = 17
+	TypeDatetime2  = 18
+	TypeTime2      = 19
+	TypeJson       = 245
+	TypeEnum       = 247
+	TypeSet        = 248
+	TypeTinyBlob   = 249
+	TypeMediumBlob = 250
+	TypeLongBlob   = 251
+	TypeBlob       = 252
+	TypeVarString  = 253
+	TypeString     = 254
+	TypeGeometry   = 255
+)
+
+// Read metadata of each column from table map
+func parseColumnMeta(columnTypes []byte, metadata []byte) ([]uint16, error) {
+	columnMeta := make([]uint16, len(columnTypes))
+	pos := 0
+	for i, columnType := range columnTypes {
+		size := 0
+		switch columnType {
+		case TypeFloat, TypeDouble, TypeBlob, TypeJson, TypeGeometry, TypeTimestamp2, TypeDatetime2, TypeTime2:
+			size = 1
+		case TypeVarchar, TypeVarString:
+			size = 2
+		case TypeString, TypeEnum, TypeSet:
+			size = 2
+		}
+		if len(metadata) < pos+size {
+			return nil, errors.New("Invalid column metadata of table map")
+		}
+
+		switch {
+		case size == 1:
+			columnMeta[i] = uint16(metadata[pos])
+		case columnType == TypeString || columnType == TypeEnum || columnType == TypeSet:
+			// Real type and length in big endian
+			columnMeta[i] = uint16(metadata[pos])<<8 | uint16(metadata[pos+1])
+		case size == 2:
+			columnMeta[i] = binary.LittleEndian.Uint16(metadata[pos:])
+		}
+		pos += size
+	}
+	return columnMeta, nil
+}
+
+// Decode value of column
+// It returns the value and read bytes. Integer is int64, string and blob are string, and date and time are string of MySQL format
+func decodeValue(columnType byte, meta uint16, data []byte) (interface{}, int, error) {
+	size, err := valueSize(columnType, meta, data)
+	if err != nil {
+		return nil, 0, err
+	}
+	if len(data) < size {
+		return nil, 0, errors.New(fmt.Sprintf("Value is too short. type = %d", columnType))
+	}
+
+	switch columnType {
+	case TypeTiny:
+		return int64(int8(data[0])), size, nil
+	case TypeShort:
+		return int64(int16(binary.LittleEndian.Uint16(data))), size, nil
+	case TypeInt24:
+		value := int32(uint32(data[0]) | uint32(data[1])<<8 | uint32(data[2])<<16)
+		if value&0x800000 != 0 {
+			value -= 1 << 24
+		}
+		return int64(value), size, nil
+	case TypeLong:
+		return int64(int32(binary.LittleEndian.Uint32(data))), size, nil
+	case TypeLongLong:
+		return int64(binary.LittleEndian.Uint64(data)), size, nil
+	case TypeFloat:
+		return float64(math.Float32frombits(binary.LittleEndian.Uint32(data))), size, nil
+	case TypeDouble:
+		return math.Float64frombits(binary.LittleEndian.Uint64(data)), size, nil
+	case TypeYear:
+		if data[0] == 0 {
+			return int64(0), size, nil
+		}
+		return int64(data[0]) + 1900, size, nil
+	case TypeDate:
+		value := uint32(data[0]) | uint32(data[1])<<8 | uint32(data[2])<<16
+		return fmt.Sprintf("%04d-%02d-%02d", value>>9, value>>5&15, value&31), size, nil
+	case TypeTime:
+		value := uint32(data[0]) | uint32(data[1])<<8 | uint32(data[2])<<16
+		return fmt.Sprintf("%02d:%02d:%02d", value/10000, value/100%100, value%100), size, nil
+	case TypeTimestamp:
+		return formatTime(time.Unix(int64(binary.LittleEndian.Uint32(data)), 0), 0), size, nil
+	case TypeDatetime:
+		value := binary.LittleEndian.Uint64(data)
+		date, clock := value/1000000, value%1000000
+		return fmt.Sprintf("%04d-%02d-%02d %02d:%02d:%02d", date/10000, date/100%100, date%100, clock/10000, clock/100%100, clock%100), size, nil
+	case TypeTimestamp2:
+		seconds := int64(binary.BigEndian.Uint32(data))
+		micros := readFraction(meta, data[4:])
+		return formatTime(time.Unix(seconds, micros*1000), int(meta)), size, nil
+	case TypeDatetime2:
+		return decodeDatetime2(meta, data), size, nil
+	case TypeTime2:
+		value := int64(uint64(data[0])<<16|uint64(data[1])<<8|uint64(data[2])) - 0x800000
+		sign := ""
+		if value < 0 {
+			sign, value = "-", -value
+		}
+		return fmt.Sprintf("%s%02d:%02d:%02d", sign, value>>12&0x3ff, value>>6&0x3f, value&0x3f), size, nil
+	case TypeVarchar, TypeVarString, TypeString:
+		prefix := size - stringLength(columnType, meta, data)
+		return string(data[prefix:size]), size, nil
+	case TypeBlob, TypeJson, TypeGeometry:
+		return string(data[meta:size]), size, nil
+	default:
+		return nil, 0, errors.New(fmt.Sprintf("Not supported column type %d", columnType))
+	}
+}
+
+// Bytes of value including length prefix
+func valueSize(columnType byte, meta uint16, data []byte) (int, error) {
+	switch columnType {
+	case TypeTiny, TypeYear:
+		return 1, nil
+	case TypeShort:
+		return 2, nil
+	case TypeInt24, TypeDate, TypeTime:
+		return 3, nil
+	case TypeLong, TypeFloat, TypeTimestamp:
+		return 4, nil
+	case TypeLongLong, TypeDouble, TypeDatetime:
+		return 8, nil
+	case TypeTimestamp2:
+		return 4 + (int(meta)+1)/2, nil
+	case TypeDatetime2:
+		return 5 + (int(meta)+1)/2, nil
+	case TypeTime2:
+		return 3 + (int(meta)+1)/2, nil
+	case TypeVarchar, TypeVarString, TypeString:
+		if columnType == TypeString && isEnumOrSet(meta) {
+			return 0, errors.New("Not supported column type enum or set")
+		}
+		prefix := 1
+		if maxStringLength(columnType, meta) > 255 {
+			prefix = 2
+		}
+		if len(data) < prefix {
+			return 0, errors.New("String is too short")
+		}
+		return prefix + stringLength(columnType, meta, data), nil
+	case TypeBlob, TypeJson, TypeGeometry:
+		if meta < 1 || meta > 4 || len(data) < int(meta) {
+			return 0, errors.New("Invalid blob")
+		}
+		length := 0
+		for i := 0; i < int(meta); i++ {
+			length |= int(data[i]) << uint(8*i)
+		}
+		return int(meta) + length, nil
+	default:
+		return 0, errors.New(fmt.Sprintf("Not supported column type %d", columnType))
+	}
+}
+
+// Length of string value without its prefix
+func stringLength(columnType byte, meta uint16, data []byte) int {
+	if maxStringLength(columnType, meta) > 255 {
+		return int(binary.LittleEndian.Uint16(data))
+	}
+	return int(data[0])
+}
+
+// Max bytes of string column
+// Metadata of char is real type and length, and the upper bits of length are in real type
+func maxStringLength(columnType byte, meta uint16) int {
+	if columnType != TypeString {
+		return int(meta)
+	}
+	realType, length := byte(meta>>8), int(meta&0xff)
+	if realType&0x30 != 0x30 {
+		length |= int((realType&0x30)^0x30) << 4
+	}
+	return length
+}
+
+func isEnumOrSet(meta uint16) bool {
+	realType := byte(meta >> 8)
+	if realType&0x30 != 0x30 {
+		realType |= 0x30
+	}
+	return realType == TypeEnum || realType == TypeSet
+}
+
+// Fractional seconds of fsp in microseconds
+func readFraction(fsp uint16, data []byte) int64 {
+	switch fsp {
+	case 1, 2:
+		return int64(data[0]) * 10000
+	case 3, 4:
+		return int64(binary.BigEndian.Uint16(data)) * 100
+	case 5, 6:
+		return int64(uint32(data[0])<<16 | uint32(data[1])<<8 | uint32(data[2]))
+	default:
+		return 0
+	}
+}
+
+// Datetime2 is 5 bytes big endian with 0x8000000000 offset, and fraction
+// year*13+month(17 bits), day(5), hour(5), minute(6), second(6)
+func decodeDatetime2(fsp uint16, data []byte) string {
+	value := int64(uint64(data[0])<<32|uint64(data[1])<<24|uint64(data[2])<<16|uint64(data[3])<<8|uint64(data[4])) - 0x8000000000
+	ymd, hms := value>>17, value&0x1ffff
+	ym := ymd >> 5
+	result := fmt.Sprintf("%04d-%02d-%02d %02d:%02d:%02d", ym/13, ym%13, ymd&31, hms>>12, hms>>6&63, hms&63)
+	if fsp > 0 {
+		result += fmt.Sprintf(".%06d", readFraction(fsp, data[5:]))[:fsp+1]
+	}
+	return result
+}
+
+// Time in UTC of MySQL format
+func formatTime(t time.Time, fsp int) string {
+	result := t.UTC().Format("2006-01-02 15:04:05")
+	if fsp > 0 {
+		result += fmt.Sprintf(".%06d", t.Nanosecond()/1000)[:fsp+1]
+	}
+	return result
+}
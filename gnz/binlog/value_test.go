@@ -0,0 +1,97 @@
+package binlog
+
+import (
+	"testing"
+)
+
+// Test decode integer values
+func TestDecodeValue_Integer(t *testing.T) {
+	cases := []struct {
+		columnType byte
+		data       []byte
+		expected   int64
+	}{
+		{TypeTiny, []byte{0xff}, -1},
+		{TypeShort, []byte{0x01, 0x02}, 513},
+		{TypeInt24, []byte{0xff, 0xff, 0xff}, -1},
+		{TypeLong, []byte{0x2a, 0, 0, 0}, 42},
+		{TypeLongLong, []byte{0xfe, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}, -2},
+	}
+	for _, c := range cases {
+		value, n, err := decodeValue(c.columnType, 0, c.data)
+		if err != nil || value != c.expected || n != len(c.data) {
+			t.Errorf("Incorrect TestDecodeValue_Integer test. type = %d, value = %v", c.columnType, value)
+			t.FailNow()
+		}
+	}
+}
+
+// Test decode date and time values
+func TestDecodeValue_Time(t *testing.T) {
+	value, n, err := decodeValue(TypeDatetime2, 0, encodeDatetime2(2020, 4, 1, 12, 30, 15))
+	if err != nil || value != "2020-04-01 12:30:15" || n != 5 {
+		t.Errorf("Incorrect TestDecodeValue_Time test. value = %v", value)
+		t.FailNow()
+	}
+
+	value, n, err = decodeValue(TypeDatetime2, 3, append(encodeDatetime2(2020, 4, 1, 12, 30, 15), 0x00, 0x7b))
+	if err != nil || value != "2020-04-01 12:30:15.012" || n != 7 {
+		t.Errorf("Incorrect TestDecodeValue_Time test. value = %v", value)
+		t.FailNow()
+	}
+
+	value, _, err = decodeValue(TypeTimestamp2, 0, []byte{0x5e, 0x84, 0x89, 0x57})
+	if err != nil || value != "2020-04-01 12:30:15" {
+		t.Errorf("Incorrect TestDecodeValue_Time test. value = %v", value)
+		t.FailNow()
+	}
+}
+
+// Test decode string values
+func TestDecodeValue_String(t *testing.T) {
+	value, n, err := decodeValue(TypeVarchar, 384, []byte{5, 0, 'a', 'd', 'm', 'i', 'n'})
+	if err != nil || value != "admin" || n != 7 {
+		t.Errorf("Incorrect TestDecodeValue_String test. value = %v", value)
+		t.FailNow()
+	}
+
+	// char(32) of utf8 is 96 bytes
+	value, n, err = decodeValue(TypeString, uint16(TypeString)<<8|96, []byte{5, 'a', 'd', 'm', 'i', 'n'})
+	if err != nil || value != "admin" || n != 6 {
+		t.Errorf("Incorrect TestDecodeValue_String test. value = %v", value)
+		t.FailNow()
+	}
+
+	value, n, err = decodeValue(TypeBlob, 2, []byte{2, 0, '{', '}'})
+	if err != nil || value != "{}" || n != 4 {
+		t.Errorf("Incorrect TestDecodeValue_String test. value = %v", value)
+		t.FailNow()
+	}
+
+	if _, _, err := decodeValue(TypeVarchar, 384, []byte{5, 0, 'a'}); err == nil {
+		t.Errorf("Incorrect TestDecodeValue_String test")
+		t.FailNow()
+	}
+}
+
+// Test decode value of not supported type
+func TestDecodeValue_NotSupported(t *testing.T) {
+	if _, _, err := decodeValue(246, 0x0a02, []byte{0x80, 0, 0, 0, 1}); err == nil {
+		t.Errorf("Incorrect TestDecodeValue_NotSupported test")
+		t.FailNow()
+	}
+}
+
+// Test parse column metadata
+func TestParseColumnMeta(t *testing.T) {
+	meta, err := parseColumnMeta([]byte{TypeLong, TypeVarchar, TypeString, TypeDatetime2, TypeBlob}, []byte{128, 1, TypeString, 96, 3, 2})
+	if err != nil || meta[0] != 0 || meta[1] != 384 || meta[2] != uint16(TypeString)<<8|96 || meta[3] != 3 || meta[4] != 2 {
+		t.Errorf("Incorrect TestParseColumnMeta test. meta = %v", meta)
+		t.FailNow()
+	}
+
+	if _, err := parseColumnMeta([]byte{TypeVarchar}, []byte{128}); err == nil {
+		t.Errorf("Incorrect TestParseColumnMeta test")
+		t.FailNow()
+	}
+}
@@ -0,0 +1,30 @@
+package cache
+
+// Key and value of batch write
+// Key does not have namespace, and value is struct of `gnz/cache/structure`
+type KeyValue struct {
+	Key   string
+	Value interface{}
+}
+
+// Key and encoded value of batch write
+type rawKeyValue struct {
+	key   string
+	value []byte
+}
+
+// Batch that failed to write
+// Values are written again by passing them to SetBatch
+type FailedBatch struct {
+	Values []KeyValue
+	Err    error
+}
+
+// Values of all failed batches
+func RetryValues(failed []FailedBatch) []KeyValue {
+	var values []KeyValue
+	for _, batch := range failed {
+		values = append(values, batch.Values...)
+	}
+	return values
+}
@@ -0,0 +1,150 @@
+package cache
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sync"
+)
+
+// Compact cache value
+// byte 0: compactMagic, byte 1: flags, then uvarint of schema version, then msgpack of data
+// If flags has compactFlagDeflate, msgpack is compressed by deflate
+// compactMagic is never the first byte of json, so that both encodings are read from the same keys
+const (
+	compactMagic       = 0xc1
+	compactFlagDeflate = 0x01
+
+	// Data smaller than this is not compressed, because deflate does not make it smaller
+	compactDeflateThreshold = 128
+)
+
+var (
+	flateWriterPool = sync.Pool{New: func() interface{} {
+		writer, _ := flate.NewWriter(nil, flate.DefaultCompression)
+		return writer
+	}}
+	flateReaderPool sync.Pool
+)
+
+// Value is compact cache value
+func isCompactValue(value []byte) bool {
+	return len(value) > 0 && value[0] == compactMagic
+}
+
+// Convert struct to compact cache value of SchemaVersion
+func encodeCompactValue(structData interface{}) ([]byte, error) {
+	data, err := marshalMsgpack(structData)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	buf.WriteByte(compactMagic)
+	if len(data) < compactDeflateThreshold {
+		buf.WriteByte(0)
+		writeUvarint(&buf, SchemaVersion)
+		buf.Write(data)
+		return buf.Bytes(), nil
+	}
+
+	buf.WriteByte(compactFlagDeflate)
+	writeUvarint(&buf, SchemaVersion)
+	writer := flateWriterPool.Get().(*flate.Writer)
+	defer flateWriterPool.Put(writer)
+	writer.Reset(&buf)
+	if _, err := writer.Write(data); err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Convert compact cache value to struct
+// Value of older version is migrated as json
+func decodeCompactValue(value []byte, structData interface{}) error {
+	version, data, err := readCompactValue(value)
+	if err != nil {
+		return err
+	}
+	if version > SchemaVersion {
+		return errors.New(fmt.Sprintf("Not supported cache value version. version = %d", version))
+	}
+	if version == SchemaVersion {
+		return unmarshalMsgpack(data, structData)
+	}
+
+	jsonData, err := msgpackToJson(data)
+	if err != nil {
+		return err
+	}
+	migrated, err := migrateData(version, jsonData)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(migrated, structData)
+}
+
+// Versioned cache json of compact cache value
+// Data is not migrated, so the json has the version of value
+func compactToJsonValue(value []byte) ([]byte, error) {
+	version, data, err := readCompactValue(value)
+	if err != nil {
+		return nil, err
+	}
+	jsonData, err := msgpackToJson(data)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(versionedValue{Version: version, Data: jsonData})
+}
+
+// Schema version and msgpack of compact cache value
+func readCompactValue(value []byte) (int, []byte, error) {
+	if !isCompactValue(value) || len(value) < 2 {
+		return 0, nil, errors.New("Invalid compact cache value")
+	}
+	flags := value[1]
+	version, n := binary.Uvarint(value[2:])
+	if n <= 0 {
+		return 0, nil, errors.New("Invalid compact cache value version")
+	}
+	data := value[2+n:]
+	if flags&compactFlagDeflate == 0 {
+		return int(version), data, nil
+	}
+
+	reader, ok := flateReaderPool.Get().(io.ReadCloser)
+	if ok {
+		reader.(flate.Resetter).Reset(bytes.NewReader(data), nil)
+	} else {
+		reader = flate.NewReader(bytes.NewReader(data))
+	}
+	defer flateReaderPool.Put(reader)
+	inflated, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return 0, nil, errors.New(fmt.Sprintf("Invalid compact cache value. %s", err.Error()))
+	}
+	return int(version), inflated, nil
+}
+
+func msgpackToJson(data []byte) (json.RawMessage, error) {
+	var generic interface{}
+	if err := unmarshalMsgpack(data, &generic); err != nil {
+		return nil, err
+	}
+	return json.Marshal(generic)
+}
+
+func writeUvarint(buf *bytes.Buffer, value uint64) {
+	var varint [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(varint[:], value)
+	buf.Write(varint[:n])
+}
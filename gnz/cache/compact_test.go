@@ -0,0 +1,159 @@
+package cache
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/tomoyane/grant-n-z/gnz/cache/structure"
+)
+
+// Policies of a user in many groups
+func testUserPolicies(size int) []structure.UserPolicy {
+	services := []string{uuid.New().String(), uuid.New().String()}
+	policies := make([]structure.UserPolicy, size)
+	for i := range policies {
+		policies[i] = structure.UserPolicy{
+			ServiceUuid:    services[i%len(services)],
+			GroupUuid:      uuid.New().String(),
+			RoleName:       []string{"admin", "developer", "viewer"}[i%3],
+			PermissionName: []string{"read", "write"}[i%2],
+		}
+	}
+	return policies
+}
+
+// Test encode and decode compact value
+func TestCompactValue(t *testing.T) {
+	for _, size := range []int{1, 50} {
+		policies := testUserPolicies(size)
+		value, err := encodeValueWith(ValueEncodingCompact, policies)
+		if err != nil || !isCompactValue(value) {
+			t.Errorf("Incorrect TestCompactValue test. err = %v", err)
+			t.FailNow()
+		}
+		if value[1]&compactFlagDeflate == 0 {
+			t.Errorf("Incorrect TestCompactValue test. size = %d, flags = %d", size, value[1])
+			t.FailNow()
+		}
+
+		var decoded []structure.UserPolicy
+		if err := decodeValue(value, &decoded); err != nil || len(decoded) != size || decoded[size-1] != policies[size-1] {
+			t.Errorf("Incorrect TestCompactValue test. size = %d, err = %v", size, err)
+			t.FailNow()
+		}
+
+		// Keys of json data are sorted, so that data is compared as json
+		jsonValue, _ := encodeValue(policies)
+		data, err := DecodeData(value)
+		jsonData, _ := DecodeData(jsonValue)
+		var compactGeneric, jsonGeneric interface{}
+		json.Unmarshal(data, &compactGeneric)
+		json.Unmarshal(jsonData, &jsonGeneric)
+		if err != nil || !reflect.DeepEqual(compactGeneric, jsonGeneric) {
+			t.Errorf("Incorrect TestCompactValue test. data = %s", string(data))
+			t.FailNow()
+		}
+	}
+
+	// Small value is not compressed
+	value, err := encodeValueWith(ValueEncodingCompact, structure.Role{Name: "admin", Uuid: "uuid"})
+	var role structure.Role
+	if err != nil || value[1]&compactFlagDeflate != 0 || decodeValue(value, &role) != nil || role.Name != "admin" {
+		t.Errorf("Incorrect TestCompactValue test. role = %v", role)
+		t.FailNow()
+	}
+}
+
+// Test compact value of older and newer version
+func TestCompactValue_Version(t *testing.T) {
+	data, _ := marshalMsgpack(structure.Role{Name: "admin", Uuid: "uuid"})
+
+	var role structure.Role
+	older := append([]byte{compactMagic, 0, 0}, data...)
+	if err := decodeValue(older, &role); err != nil || role.Name != "admin" {
+		t.Errorf("Incorrect TestCompactValue_Version test. role = %v", role)
+		t.FailNow()
+	}
+
+	newer := append([]byte{compactMagic, 0, SchemaVersion + 1}, data...)
+	if err := decodeValue(newer, &role); err == nil {
+		t.Errorf("Incorrect TestCompactValue_Version test")
+		t.FailNow()
+	}
+
+	if err := decodeValue([]byte{compactMagic, compactFlagDeflate, SchemaVersion, 0xff}, &role); err == nil {
+		t.Errorf("Incorrect TestCompactValue_Version test")
+		t.FailNow()
+	}
+}
+
+// Test compact value to versioned json
+func TestCompactToJsonValue(t *testing.T) {
+	value, _ := encodeCompactValue(structure.Role{Name: "admin", Uuid: "uuid"})
+	converted, err := compactToJsonValue(value)
+	expected, _ := encodeValue(structure.Role{Name: "admin", Uuid: "uuid"})
+	if err != nil || !bytes.Equal(converted, expected) {
+		t.Errorf("Incorrect TestCompactToJsonValue test. value = %s", string(converted))
+		t.FailNow()
+	}
+}
+
+// Test import value of snapshot is encoded by encoding of client
+func TestImportValue(t *testing.T) {
+	value := json.RawMessage(`{"version":1,"data":{"name":"admin","uuid":"uuid"}}`)
+
+	imported, err := SnapshotImpl{Etcd: EtcdClientImpl{}}.importValue(value)
+	if err != nil || !bytes.Equal(imported, value) {
+		t.Errorf("Incorrect TestImportValue test. value = %s", string(imported))
+		t.FailNow()
+	}
+
+	imported, err = SnapshotImpl{Etcd: EtcdClientImpl{Encoding: ValueEncodingCompact}}.importValue(value)
+	var role structure.Role
+	if err != nil || !isCompactValue(imported) || decodeValue(imported, &role) != nil || role.Name != "admin" {
+		t.Errorf("Incorrect TestImportValue test. role = %v", role)
+		t.FailNow()
+	}
+}
+
+// Size and time to encode user_policy of policies in each encoding
+func BenchmarkEncodeValue(b *testing.B) {
+	for _, size := range []int{10, 100, 1000} {
+		policies := testUserPolicies(size)
+		for _, encoding := range []string{ValueEncodingJson, ValueEncodingCompact} {
+			b.Run(fmt.Sprintf("%s/policies=%d", encoding, size), func(b *testing.B) {
+				var value []byte
+				b.ReportAllocs()
+				for i := 0; i < b.N; i++ {
+					value, _ = encodeValueWith(encoding, policies)
+				}
+				b.ReportMetric(float64(len(value)), "value-bytes")
+			})
+		}
+	}
+}
+
+// Size and time to decode user_policy of policies in each encoding
+func BenchmarkDecodeValue(b *testing.B) {
+	for _, size := range []int{10, 100, 1000} {
+		policies := testUserPolicies(size)
+		for _, encoding := range []string{ValueEncodingJson, ValueEncodingCompact} {
+			value, _ := encodeValueWith(encoding, policies)
+			b.Run(fmt.Sprintf("%s/policies=%d", encoding, size), func(b *testing.B) {
+				b.ReportAllocs()
+				for i := 0; i < b.N; i++ {
+					var decoded []structure.UserPolicy
+					if err := decodeValue(value, &decoded); err != nil {
+						b.Fatal(err)
+					}
+				}
+				b.ReportMetric(float64(len(value)), "value-bytes")
+			})
+		}
+	}
+}
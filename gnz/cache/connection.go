@@ -37,6 +37,8 @@ func InitEtcd() {
 
 // Close etcd
 func Close() {
+	closeLocalCache()
+	closeMembership()
 	if connection != nil {
 		connection.Close()
 		log.Logger.Info("Closed etcd connection")
@@ -5,74 +5,143 @@ import (
 	"errors"
 	"fmt"
 
-	"encoding/json"
+	"strings"
+	"time"
 
 	"go.etcd.io/etcd/clientv3"
 
 	"github.com/tomoyane/grant-n-z/gnz/cache/structure"
+	"github.com/tomoyane/grant-n-z/gnz/common"
 	"github.com/tomoyane/grant-n-z/gnz/log"
 )
 
-const retryCnt = 5
+const (
+	retryCnt         = 5
+	pingTimeout      = 1 * time.Second
+	defaultBatchSize = 100
+	rangePageSize    = 1000
+)
+
+// Cache key prefix
+const (
+	UserPolicyKeyPrefix  = "user_policy="
+	UserServiceKeyPrefix = "user_service="
+	UserGroupKeyPrefix   = "user_group="
+	PermissionKeyPrefix  = "permission="
+	RoleKeyPrefix        = "role="
+	ServiceKeyPrefix     = "service="
+)
+
+// Key prefix of checkpoint of gnzcacher. It is not cache data, so it is not in KeyPrefixes
+const CheckpointKeyPrefix = "checkpoint="
+
+// All cache key prefixes
+var KeyPrefixes = []string{
+	UserPolicyKeyPrefix,
+	UserServiceKeyPrefix,
+	UserGroupKeyPrefix,
+	PermissionKeyPrefix,
+	RoleKeyPrefix,
+	ServiceKeyPrefix,
+}
 
 var eInstance EtcdClient
 
+// Keys have namespace of config before them, and values are versioned json of SchemaVersion
+// ex: key: {namespace}/permission={uuid}, value: {"version":1,"data":{"name":"{name}"}}
+// Keys in arguments and results do not have namespace
 type EtcdClient interface {
 	// Set permission with expires
 	// key: permission={uuid}
 	// value: {"name":"{name}"}
-	SetPermission(permissionUuid string, permission structure.Permission)
+	SetPermission(ctx context.Context, permissionUuid string, permission structure.Permission)
 
 	// Set role with expires
 	// key: role={uuid}
 	// value: {"name":"{name}"}
-	SetRole(roleUuid string, role structure.Role)
+	SetRole(ctx context.Context, roleUuid string, role structure.Role)
 
 	// Set service with expires
 	// key: service={uuid}
 	// value: {"name":"{name}"}
-	SetService(serviceUuid string, service structure.Service)
+	SetService(ctx context.Context, serviceUuid string, service structure.Service)
 
 	// Set policy with expires
 	// key: user_policy={user_uuid}
 	// value: [{"service_uuid":"{uuid}","group_uuid":"{uuid}","role_name":"{name}","permission_name":"{name}"}]
-	SetUserPolicy(userUuid string, policy []structure.UserPolicy)
+	SetUserPolicy(ctx context.Context, userUuid string, policy []structure.UserPolicy)
 
 	// Set user_service with expires
 	// key: user_service={user_uuid}
 	// value: [{"service_name":"{name}","service_uuid":"{uuid}"}]
-	SetUserService(userUuid string, userServices []structure.UserService)
+	SetUserService(ctx context.Context, userUuid string, userServices []structure.UserService)
 
 	// Set user_group with expires
 	// key: user_group={user_uuid}
 	// value: [{"group_name":"{name}","group_uuid":"{uuid}"}]
-	SetUserGroup(userUuid string, userGroups []structure.UserGroup)
+	SetUserGroup(ctx context.Context, userUuid string, userGroups []structure.UserGroup)
 
 	// Get policy by user uuid
-	GetUserPolicy(userUuid string) []structure.UserPolicy
+	GetUserPolicy(ctx context.Context, userUuid string) []structure.UserPolicy
 
 	// Get permission by uuid
-	GetPermission(permissionUuid string) *structure.Permission
+	GetPermission(ctx context.Context, permissionUuid string) *structure.Permission
 
 	// Get role by uuid
-	GetRole(roleUuid string) *structure.Role
+	GetRole(ctx context.Context, roleUuid string) *structure.Role
 
 	// Get service by uuid
-	GetService(serviceUuid string) *structure.Service
+	GetService(ctx context.Context, serviceUuid string) *structure.Service
 
 	// Get user_service by user uuid
-	GetUserService(userUuid string) []structure.UserService
+	GetUserService(ctx context.Context, userUuid string) []structure.UserService
 
 	// Get user_group by user uuid
-	GetUserGroup(userUuid string) []structure.UserGroup
+	GetUserGroup(ctx context.Context, userUuid string) []structure.UserGroup
 
 	// Delete policy by user uuid
-	DeleteUserPolicy(userUuid string)
+	DeleteUserPolicy(ctx context.Context, userUuid string)
+
+	// Get all keys that start with prefix
+	// ex: prefix is `user_policy=`, keys are [user_policy={user_uuid}, ...]
+	GetKeys(ctx context.Context, prefix string) ([]string, error)
+
+	// Get all keys and values that start with prefix
+	// Values are versioned json as it is in etcd
+	GetValues(ctx context.Context, prefix string) (map[string][]byte, error)
+
+	// Delete keys
+	DeleteKeys(ctx context.Context, keys []string)
+
+	// Set values with etcd transaction of BatchSize keys
+	// Each batch is written atomically. It returns the batches that failed, so that the caller can retry them
+	SetBatch(ctx context.Context, values []KeyValue) []FailedBatch
+
+	// Get checkpoint by name
+	// key: checkpoint={name}
+	// It returns false if the checkpoint does not exist
+	GetCheckpoint(ctx context.Context, name string, checkpoint interface{}) (bool, error)
+
+	// Set checkpoint by name without expires
+	SetCheckpoint(ctx context.Context, name string, checkpoint interface{}) error
+
+	// Check etcd is reachable
+	Ping(ctx context.Context) error
 }
 
 type EtcdClientImpl struct {
 	Connection *clientv3.Client
-	Ctx        context.Context
+	LocalCache *LocalCache
+	Namespace  string
+
+	// Timeout of each etcd call. If 0, it waits until ctx of the caller is done
+	Timeout time.Duration
+
+	// Keys of one transaction of SetBatch. If 0, defaultBatchSize is used
+	BatchSize int
+
+	// Encoding of values that are written. If empty, values are json
+	Encoding string
 }
 
 func GetEtcdClientInstance() EtcdClient {
@@ -88,115 +157,250 @@ func NewEtcdClient() EtcdClient {
 	log.Logger.Info("New `EtcdClient` instance")
 	return EtcdClientImpl{
 		Connection: connection,
-		Ctx:        context.Background(),
+		LocalCache: localCache,
+		Namespace:  keyNamespace(),
+		Timeout:    time.Duration(common.Etcd.Timeout) * time.Millisecond,
+		BatchSize:  common.Etcd.BatchSize,
+		Encoding:   common.Etcd.ValueEncoding,
 	}
 }
 
-func (e EtcdClientImpl) SetUserPolicy(userUuid string, policy []structure.UserPolicy) {
-	policyJson, _ := json.Marshal(policy)
-	e.set([]string{fmt.Sprintf("user_policy=%s", userUuid)}, policyJson)
+func (e EtcdClientImpl) SetUserPolicy(ctx context.Context, userUuid string, policy []structure.UserPolicy) {
+	policyJson, _ := e.encode(policy)
+	e.set(ctx, []string{UserPolicyKeyPrefix + userUuid}, policyJson)
 }
 
-func (e EtcdClientImpl) SetPermission(permissionUuid string, permission structure.Permission) {
-	permissionJson, _ := json.Marshal(permission)
-	e.set([]string{fmt.Sprintf("permission=%s", permissionUuid)}, permissionJson)
+func (e EtcdClientImpl) SetPermission(ctx context.Context, permissionUuid string, permission structure.Permission) {
+	permissionJson, _ := e.encode(permission)
+	e.set(ctx, []string{PermissionKeyPrefix + permissionUuid}, permissionJson)
 }
 
-func (e EtcdClientImpl) SetRole(roleUuid string, role structure.Role) {
-	roleJson, _ := json.Marshal(role)
-	e.set([]string{fmt.Sprintf("role=%s", roleUuid)}, roleJson)
+func (e EtcdClientImpl) SetRole(ctx context.Context, roleUuid string, role structure.Role) {
+	roleJson, _ := e.encode(role)
+	e.set(ctx, []string{RoleKeyPrefix + roleUuid}, roleJson)
 }
 
-func (e EtcdClientImpl) SetService(serviceUuid string, service structure.Service) {
-	serviceJson, _ := json.Marshal(service)
-	e.set([]string{fmt.Sprintf("service=%s", serviceUuid)}, serviceJson)
+func (e EtcdClientImpl) SetService(ctx context.Context, serviceUuid string, service structure.Service) {
+	serviceJson, _ := e.encode(service)
+	e.set(ctx, []string{ServiceKeyPrefix + serviceUuid}, serviceJson)
 }
 
-func (e EtcdClientImpl) SetUserService(userUuid string, userServices []structure.UserService) {
-	userServiceJson, _ := json.Marshal(userServices)
-	e.set([]string{fmt.Sprintf("user_service=%s", userUuid)}, userServiceJson)
+func (e EtcdClientImpl) SetUserService(ctx context.Context, userUuid string, userServices []structure.UserService) {
+	userServiceJson, _ := e.encode(userServices)
+	e.set(ctx, []string{UserServiceKeyPrefix + userUuid}, userServiceJson)
 }
 
-func (e EtcdClientImpl) SetUserGroup(userUuid string, userGroups []structure.UserGroup) {
-	userGroupJson, _ := json.Marshal(userGroups)
-	e.set([]string{fmt.Sprintf("user_group=%s", userUuid)}, userGroupJson)
+func (e EtcdClientImpl) SetUserGroup(ctx context.Context, userUuid string, userGroups []structure.UserGroup) {
+	userGroupJson, _ := e.encode(userGroups)
+	e.set(ctx, []string{UserGroupKeyPrefix + userUuid}, userGroupJson)
 }
 
-func (e EtcdClientImpl) GetUserPolicy(userUuid string) []structure.UserPolicy {
+func (e EtcdClientImpl) GetUserPolicy(ctx context.Context, userUuid string) []structure.UserPolicy {
 	var policy []structure.UserPolicy
-	err := e.get(fmt.Sprintf("user_policy=%s", userUuid), &policy)
+	err := e.get(ctx, UserPolicyKeyPrefix+userUuid, &policy)
 	if err != nil {
 		return nil
 	}
 	return policy
 }
 
-func (e EtcdClientImpl) GetPermission(permissionUuid string) *structure.Permission {
+func (e EtcdClientImpl) GetPermission(ctx context.Context, permissionUuid string) *structure.Permission {
 	var permission structure.Permission
-	err := e.get(fmt.Sprintf("permission=%s", permissionUuid), &permission)
+	err := e.get(ctx, PermissionKeyPrefix+permissionUuid, &permission)
 	if err != nil {
 		return nil
 	}
 	return &permission
 }
 
-func (e EtcdClientImpl) GetRole(roleUuid string) *structure.Role {
+func (e EtcdClientImpl) GetRole(ctx context.Context, roleUuid string) *structure.Role {
 	var role structure.Role
-	err := e.get(fmt.Sprintf("role=%s", roleUuid), &role)
+	err := e.get(ctx, RoleKeyPrefix+roleUuid, &role)
 	if err != nil {
 		return nil
 	}
 	return &role
 }
 
-func (e EtcdClientImpl) GetService(serviceUuid string) *structure.Service {
+func (e EtcdClientImpl) GetService(ctx context.Context, serviceUuid string) *structure.Service {
 	var service structure.Service
-	err := e.get(fmt.Sprintf("service=%s", serviceUuid), &service)
+	err := e.get(ctx, ServiceKeyPrefix+serviceUuid, &service)
 	if err != nil {
 		return nil
 	}
 	return &service
 }
 
-func (e EtcdClientImpl) GetUserService(userUuid string) []structure.UserService {
+func (e EtcdClientImpl) GetUserService(ctx context.Context, userUuid string) []structure.UserService {
 	var userServices []structure.UserService
-	err := e.get(fmt.Sprintf("user_service=%s", userUuid), &userServices)
+	err := e.get(ctx, UserServiceKeyPrefix+userUuid, &userServices)
 	if err != nil {
 		return nil
 	}
 	return userServices
 }
 
-func (e EtcdClientImpl) GetUserGroup(userUuid string) []structure.UserGroup {
+func (e EtcdClientImpl) GetUserGroup(ctx context.Context, userUuid string) []structure.UserGroup {
 	var userGroups []structure.UserGroup
-	err := e.get(fmt.Sprintf("user_group=%s", userUuid), &userGroups)
+	err := e.get(ctx, UserGroupKeyPrefix+userUuid, &userGroups)
 	if err != nil {
 		return nil
 	}
 	return userGroups
 }
 
-func (e EtcdClientImpl) DeleteUserPolicy(userUuid string) {
-	e.delete([]string{fmt.Sprintf("user_policy=%s", userUuid)})
+func (e EtcdClientImpl) DeleteUserPolicy(ctx context.Context, userUuid string) {
+	e.delete(ctx, []string{UserPolicyKeyPrefix + userUuid})
+}
+
+func (e EtcdClientImpl) GetKeys(ctx context.Context, prefix string) ([]string, error) {
+	if e.Connection == nil {
+		return nil, errors.New("Not connected etcd")
+	}
+	ctx, cancel := e.withTimeout(ctx)
+	defer cancel()
+	response, err := e.Connection.Get(ctx, e.Namespace+prefix, clientv3.WithPrefix(), clientv3.WithKeysOnly())
+	if err != nil {
+		log.Logger.Error(fmt.Sprintf("Failed to get keys. prefix = %s. err = %s", prefix, err.Error()))
+		return nil, err
+	}
+
+	keys := make([]string, 0, len(response.Kvs))
+	for _, kv := range response.Kvs {
+		keys = append(keys, strings.TrimPrefix(string(kv.Key), e.Namespace))
+	}
+	return keys, nil
+}
+
+func (e EtcdClientImpl) GetValues(ctx context.Context, prefix string) (map[string][]byte, error) {
+	values := make(map[string][]byte)
+	err := e.rangePrefix(ctx, prefix, 0, func(key string, value []byte) error {
+		values[key] = value
+		return nil
+	})
+	if err != nil {
+		log.Logger.Error(fmt.Sprintf("Failed to get values. prefix = %s. err = %s", prefix, err.Error()))
+		return nil, err
+	}
+	return values, nil
+}
+
+func (e EtcdClientImpl) DeleteKeys(ctx context.Context, keys []string) {
+	e.delete(ctx, keys)
+}
+
+func (e EtcdClientImpl) SetBatch(ctx context.Context, values []KeyValue) []FailedBatch {
+	batchSize := e.BatchSize
+	if batchSize <= 0 {
+		batchSize = defaultBatchSize
+	}
+
+	var failed []FailedBatch
+	for start := 0; start < len(values); start += batchSize {
+		end := start + batchSize
+		if end > len(values) {
+			end = len(values)
+		}
+		batch := values[start:end]
+		if err := e.commitBatch(ctx, batch); err != nil {
+			log.Logger.Error(fmt.Sprintf("Failed to put batch. keys = %d. err = %s", len(batch), err.Error()))
+			failed = append(failed, FailedBatch{Values: batch, Err: err})
+		}
+	}
+	return failed
+}
+
+func (e EtcdClientImpl) GetCheckpoint(ctx context.Context, name string, checkpoint interface{}) (bool, error) {
+	if e.Connection == nil {
+		return false, errors.New("Not connected etcd")
+	}
+	key := CheckpointKeyPrefix + name
+	getCtx, cancel := e.withTimeout(ctx)
+	defer cancel()
+	response, err := e.Connection.Get(getCtx, e.Namespace+key)
+	if err != nil {
+		log.Logger.Error(fmt.Sprintf("Failed to get checkpoint. key = %s. err = %s", key, err.Error()))
+		return false, err
+	}
+	if len(response.Kvs) == 0 {
+		return false, nil
+	}
+	if err := e.decode(key, response.Kvs[0].Value, checkpoint); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (e EtcdClientImpl) SetCheckpoint(ctx context.Context, name string, checkpoint interface{}) error {
+	json, err := e.encode(checkpoint)
+	if err != nil {
+		return err
+	}
+	return e.putRaw(ctx, []rawKeyValue{{key: CheckpointKeyPrefix + name, value: json}})
+}
+
+func (e EtcdClientImpl) Ping(ctx context.Context) error {
+	if e.Connection == nil {
+		return errors.New("Not connected etcd")
+	}
+	ctx, cancel := context.WithTimeout(ctx, pingTimeout)
+	defer cancel()
+	_, err := e.Connection.Get(ctx, "ping", clientv3.WithCountOnly())
+	return err
 }
 
 // Get cache shared method
-func (e EtcdClientImpl) get(key string, structData interface{}) error {
+// If local cache is used, it reads local cache before etcd
+func (e EtcdClientImpl) get(ctx context.Context, key string, structData interface{}) error {
+	if e.LocalCache != nil {
+		if value, ok := e.LocalCache.Get(key); ok {
+			return e.decode(key, value, structData)
+		}
+	}
+
+	value, err := e.getValue(ctx, key)
+	if e.LocalCache != nil {
+		e.LocalCache.EndLoad(key, value)
+	}
+	if err != nil {
+		return err
+	}
+	return e.decode(key, value, structData)
+}
+
+// Get value from etcd
+func (e EtcdClientImpl) getValue(ctx context.Context, key string) ([]byte, error) {
 	if e.Connection == nil {
 		detail := "Not connected etcd"
 		log.Logger.Info(detail)
-		return errors.New(detail)
+		return nil, errors.New(detail)
 	}
-	response, err := e.Connection.Get(e.Ctx, key)
+	ctx, cancel := e.withTimeout(ctx)
+	defer cancel()
+	response, err := e.Connection.Get(ctx, e.Namespace+key)
 	if err != nil || len(response.Kvs) == 0 {
 		detail := fmt.Sprintf("Cache data is not existence. key = %v" + key)
 		log.Logger.Info(detail)
-		return errors.New(detail)
+		return nil, errors.New(detail)
 	}
-	kvs := response.Kvs[0]
-	err = json.Unmarshal(kvs.Value, &structData)
+	return response.Kvs[0].Value, nil
+}
+
+// Local cache is used instead of etcd
+func (e EtcdClientImpl) standalone() bool {
+	return e.Connection == nil && e.LocalCache != nil && e.LocalCache.Standalone()
+}
+
+// Convert struct to cache value of Encoding
+func (e EtcdClientImpl) encode(structData interface{}) ([]byte, error) {
+	return encodeValueWith(e.Encoding, structData)
+}
+
+// Convert cache value to struct
+func (e EtcdClientImpl) decode(key string, value []byte, structData interface{}) error {
+	err := decodeValue(value, structData)
 	if err != nil {
-		detail := fmt.Sprintf("Failed to convert json to struct for cache. %v", err.Error())
+		detail := fmt.Sprintf("Failed to convert json to struct for cache. key = %s. %v", key, err.Error())
 		log.Logger.Info(detail)
 		return errors.New(detail)
 	}
@@ -204,26 +408,99 @@ func (e EtcdClientImpl) get(key string, structData interface{}) error {
 }
 
 // Set cache shared method
-func (e EtcdClientImpl) set(keys []string, json []byte) {
+func (e EtcdClientImpl) set(ctx context.Context, keys []string, json []byte) {
 	if e.Connection == nil {
+		if e.standalone() {
+			for _, key := range keys {
+				e.LocalCache.Set(key, json)
+			}
+		}
 		return
 	}
 	for _, key := range keys {
-		_, err := e.Connection.Put(e.Ctx, key, string(json))
+		putCtx, cancel := e.withTimeout(ctx)
+		_, err := e.Connection.Put(putCtx, e.Namespace+key, string(json))
+		cancel()
 		if err != nil {
 			log.Logger.Error(fmt.Sprintf("Failed to put data. key = %v. err = %s", key, err.Error()))
 		}
+		if e.LocalCache != nil {
+			e.LocalCache.Remove(key)
+		}
+	}
+}
+
+// Put one batch in one transaction
+func (e EtcdClientImpl) commitBatch(ctx context.Context, batch []KeyValue) error {
+	values := make([]rawKeyValue, 0, len(batch))
+	for _, kv := range batch {
+		json, err := e.encode(kv.Value)
+		if err != nil {
+			log.Logger.Error(fmt.Sprintf("Failed to convert struct to json for cache. key = %s. err = %s", kv.Key, err.Error()))
+			continue
+		}
+		values = append(values, rawKeyValue{key: kv.Key, value: json})
+	}
+	return e.putRaw(ctx, values)
+}
+
+// Put encoded values in one transaction
+// If ctx has shard owner, they are put only while the owner has not changed
+func (e EtcdClientImpl) putRaw(ctx context.Context, values []rawKeyValue) error {
+	if e.Connection == nil {
+		if !e.standalone() {
+			return errors.New("Not connected etcd")
+		}
+		for _, kv := range values {
+			e.LocalCache.Set(kv.key, kv.value)
+		}
+		return nil
+	}
+
+	ops := make([]clientv3.Op, 0, len(values))
+	for _, kv := range values {
+		ops = append(ops, clientv3.OpPut(e.Namespace+kv.key, string(kv.value)))
+	}
+
+	txnCtx, cancel := e.withTimeout(ctx)
+	defer cancel()
+	txn := e.Connection.Txn(txnCtx)
+	if owner, ok := shardOwnerOf(ctx); ok {
+		txn = txn.If(owner.compare(e.Namespace))
+	}
+	response, err := txn.Then(ops...).Commit()
+	if err != nil {
+		return err
+	}
+	if !response.Succeeded {
+		return ErrShardNotOwned
+	}
+
+	if e.LocalCache != nil {
+		for _, kv := range values {
+			e.LocalCache.Remove(kv.key)
+		}
 	}
+	return nil
 }
 
 // Delete cache shared method
-func (e EtcdClientImpl) delete(keys []string) {
+func (e EtcdClientImpl) delete(ctx context.Context, keys []string) {
 	if e.Connection == nil {
+		if e.standalone() {
+			for _, key := range keys {
+				e.LocalCache.Remove(key)
+			}
+		}
 		return
 	}
 	for _, key := range keys {
-		for i := 0; i < retryCnt; i++ {
-			_, err := e.Connection.Delete(e.Ctx, key)
+		for i := 0; i < retryCnt && ctx.Err() == nil; i++ {
+			err := e.deleteKey(ctx, key)
+			if err == ErrShardNotOwned {
+				log.Logger.Error(fmt.Sprintf("Stop deleting data. key = %v. err = %s", key, err.Error()))
+				return
+			}
 			if err != nil {
 				fmt.Println(err)
 				log.Logger.Error(fmt.Sprintf("Failed to delete data. key = %v. err = %s", key, err.Error()))
@@ -231,5 +508,72 @@ func (e EtcdClientImpl) delete(keys []string) {
 				break
 			}
 		}
+		if e.LocalCache != nil {
+			e.LocalCache.Remove(key)
+		}
+	}
+}
+
+// Delete one key
+// If ctx has shard owner, the key is deleted only while the owner has not changed
+func (e EtcdClientImpl) deleteKey(ctx context.Context, key string) error {
+	deleteCtx, cancel := e.withTimeout(ctx)
+	defer cancel()
+	owner, ok := shardOwnerOf(ctx)
+	if !ok {
+		_, err := e.Connection.Delete(deleteCtx, e.Namespace+key)
+		return err
+	}
+
+	response, err := e.Connection.Txn(deleteCtx).If(owner.compare(e.Namespace)).Then(clientv3.OpDelete(e.Namespace + key)).Commit()
+	if err != nil {
+		return err
+	}
+	if !response.Succeeded {
+		return ErrShardNotOwned
+	}
+	return nil
+}
+
+// Read keys and values of prefix page by page
+// If revision is 0, it reads at revision of the first page, so all pages are consistent
+func (e EtcdClientImpl) rangePrefix(ctx context.Context, prefix string, revision int64, fn func(key string, value []byte) error) error {
+	if e.Connection == nil {
+		return errors.New("Not connected etcd")
+	}
+
+	key := e.Namespace + prefix
+	end := clientv3.GetPrefixRangeEnd(key)
+	for {
+		opts := []clientv3.OpOption{clientv3.WithRange(end), clientv3.WithLimit(rangePageSize)}
+		if revision != 0 {
+			opts = append(opts, clientv3.WithRev(revision))
+		}
+		getCtx, cancel := e.withTimeout(ctx)
+		response, err := e.Connection.Get(getCtx, key, opts...)
+		cancel()
+		if err != nil {
+			return err
+		}
+
+		revision = response.Header.Revision
+		for _, kv := range response.Kvs {
+			if err := fn(strings.TrimPrefix(string(kv.Key), e.Namespace), kv.Value); err != nil {
+				return err
+			}
+		}
+
+		if !response.More || len(response.Kvs) == 0 {
+			return nil
+		}
+		key = string(response.Kvs[len(response.Kvs)-1].Key) + "\x00"
+	}
+}
+
+// Context of one etcd call
+func (e EtcdClientImpl) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if e.Timeout <= 0 {
+		return context.WithCancel(ctx)
 	}
+	return context.WithTimeout(ctx, e.Timeout)
 }
@@ -20,10 +20,9 @@ func init() {
 
 // Setup not connected etdc pattern
 func setUpNotConnected() {
-	c, _ := context.WithTimeout(context.Background(), 100*time.Millisecond)
 	etcdClient = EtcdClientImpl{
 		Connection: nil,
-		Ctx:        c,
+		Timeout:    100 * time.Millisecond,
 	}
 }
 
@@ -34,10 +33,9 @@ func setUpStubConnected() {
 	})
 
 	connection = stubConnection
-	c, _ := context.WithTimeout(context.Background(), 100*time.Millisecond)
 	etcdClient = EtcdClientImpl{
 		Connection: connection,
-		Ctx:        c,
+		Timeout:    100 * time.Millisecond,
 	}
 }
 
@@ -50,81 +48,81 @@ func TestGetEtcdClientInstance(t *testing.T) {
 // SetUserPolicy failed test
 func TestSetPolicy_NotConnected(t *testing.T) {
 	setUpNotConnected()
-	etcdClient.SetUserPolicy(uuid.New().String(), []structure.UserPolicy{{RoleName: "test"}})
+	etcdClient.SetUserPolicy(context.Background(), uuid.New().String(), []structure.UserPolicy{{RoleName: "test"}})
 }
 
 // SetPermission failed test
 func TestSetPermission_NotConnected(t *testing.T) {
 	setUpNotConnected()
-	etcdClient.SetPermission(uuid.New().String(), structure.Permission{Name: "test"})
+	etcdClient.SetPermission(context.Background(), uuid.New().String(), structure.Permission{Name: "test"})
 }
 
 // SetRole failed test
 func TestSetRole_NotConnected(t *testing.T) {
 	setUpNotConnected()
-	etcdClient.SetRole(uuid.New().String(), structure.Role{Name: "test"})
+	etcdClient.SetRole(context.Background(), uuid.New().String(), structure.Role{Name: "test"})
 }
 
 // SetService failed test
 func TestSetService_NotConnected(t *testing.T) {
 	setUpNotConnected()
-	etcdClient.SetService(uuid.New().String(), structure.Service{Name: "test"})
+	etcdClient.SetService(context.Background(), uuid.New().String(), structure.Service{Name: "test"})
 }
 
 // SetUserService failed test
 func TestSetUserService_NotConnected(t *testing.T) {
 	setUpNotConnected()
-	etcdClient.SetUserService(uuid.New().String(), []structure.UserService{{ServiceName: "test"}})
+	etcdClient.SetUserService(context.Background(), uuid.New().String(), []structure.UserService{{ServiceName: "test"}})
 }
 
 // SetUserService failed test
 func TestSetUserGroup_NotConnected(t *testing.T) {
 	setUpNotConnected()
-	etcdClient.SetUserGroup(uuid.New().String(), []structure.UserGroup{{GroupName: "test"}})
+	etcdClient.SetUserGroup(context.Background(), uuid.New().String(), []structure.UserGroup{{GroupName: "test"}})
 }
 
 // This is connected pattern for PUT
 // SetUserPolicy failed test
 func TestSetPolicy_FailedPut(t *testing.T) {
 	setUpStubConnected()
-	etcdClient.SetUserPolicy(uuid.New().String(), []structure.UserPolicy{{RoleName: "test"}})
+	etcdClient.SetUserPolicy(context.Background(), uuid.New().String(), []structure.UserPolicy{{RoleName: "test"}})
 }
 
 // SetPermission failed test
 func TestSetPermission_FailedPut(t *testing.T) {
 	setUpStubConnected()
-	etcdClient.SetPermission(uuid.New().String(), structure.Permission{Name: "test"})
+	etcdClient.SetPermission(context.Background(), uuid.New().String(), structure.Permission{Name: "test"})
 }
 
 // SetRole failed test
 func TestSetRole_FailedPut(t *testing.T) {
 	setUpStubConnected()
-	etcdClient.SetRole(uuid.New().String(), structure.Role{Name: "test"})
+	etcdClient.SetRole(context.Background(), uuid.New().String(), structure.Role{Name: "test"})
 }
 
 // SetService failed test
 func TestSetService_FailedPut(t *testing.T) {
 	setUpStubConnected()
-	etcdClient.SetService(uuid.New().String(), structure.Service{Name: "test"})
+	etcdClient.SetService(context.Background(), uuid.New().String(), structure.Service{Name: "test"})
 }
 
 // SetUserService failed test
 func TestSetUserService_FailedPut(t *testing.T) {
 	setUpStubConnected()
-	etcdClient.SetUserService(uuid.New().String(), []structure.UserService{{ServiceName: "test"}})
+	etcdClient.SetUserService(context.Background(), uuid.New().String(), []structure.UserService{{ServiceName: "test"}})
 }
 
 // SetUserGroup failed test
 func TestSetUserGroup_FailedPut(t *testing.T) {
 	setUpStubConnected()
-	etcdClient.SetUserGroup(uuid.New().String(), []structure.UserGroup{{GroupName: "test"}})
+	etcdClient.SetUserGroup(context.Background(), uuid.New().String(), []structure.UserGroup{{GroupName: "test"}})
 }
 
 // This is not connected pattern for GET
 // GetUserPolicy nil test
 func TestGetPolicy_NotConnected(t *testing.T) {
 	setUpNotConnected()
-	policy := etcdClient.GetUserPolicy("policy")
+	policy := etcdClient.GetUserPolicy(context.Background(), "policy")
 	if policy != nil {
 		t.Errorf("Incorrect TestGetPolicy_Nil test")
 		t.FailNow()
@@ -134,7 +132,7 @@ func TestGetPolicy_NotConnected(t *testing.T) {
 // GetPermission nil test
 func TestGetPermission_NotConnected(t *testing.T) {
 	setUpNotConnected()
-	permission := etcdClient.GetPermission("permission")
+	permission := etcdClient.GetPermission(context.Background(), "permission")
 	if permission != nil {
 		t.Errorf("Incorrect TestGetPermission_Nil test")
 		t.FailNow()
@@ -144,7 +142,7 @@ func TestGetPermission_NotConnected(t *testing.T) {
 // GetRole nil test
 func TestGetRole_NotConnected(t *testing.T) {
 	setUpNotConnected()
-	role := etcdClient.GetRole("role")
+	role := etcdClient.GetRole(context.Background(), "role")
 	if role != nil {
 		t.Errorf("Incorrect TestGetRole_Nil test")
 		t.FailNow()
@@ -154,7 +152,7 @@ func TestGetRole_NotConnected(t *testing.T) {
 // GetService nil test
 func TestGetService_NotConnected(t *testing.T) {
 	setUpNotConnected()
-	service := etcdClient.GetService("service")
+	service := etcdClient.GetService(context.Background(), "service")
 	if service != nil {
 		t.Errorf("Incorrect TestGetService_Nil test")
 		t.FailNow()
@@ -164,7 +162,7 @@ func TestGetService_NotConnected(t *testing.T) {
 // GetUserService nil test
 func TestGetUserService_NotConnected(t *testing.T) {
 	setUpNotConnected()
-	userService := etcdClient.GetUserService(uuid.New().String())
+	userService := etcdClient.GetUserService(context.Background(), uuid.New().String())
 	if userService != nil {
 		t.Errorf("Incorrect TestGetUserService_Nil test")
 	}
@@ -173,7 +171,7 @@ func TestGetUserService_NotConnected(t *testing.T) {
 // GetUserGroup nil test
 func TestGetUserGroup_NotConnected(t *testing.T) {
 	setUpNotConnected()
-	userGroup := etcdClient.GetUserGroup(uuid.New().String())
+	userGroup := etcdClient.GetUserGroup(context.Background(), uuid.New().String())
 	if userGroup != nil {
 		t.Errorf("Incorrect TestGetUserGroup_NotConnected test")
 	}
@@ -183,7 +181,7 @@ func TestGetUserGroup_NotConnected(t *testing.T) {
 // GetUserPolicy nil test
 func TestGetPolicy_Nil(t *testing.T) {
 	setUpStubConnected()
-	policy := etcdClient.GetUserPolicy("policy")
+	policy := etcdClient.GetUserPolicy(context.Background(), "policy")
 	if policy != nil {
 		t.Errorf("Incorrect TestGetPolicy_Nil test")
 	}
@@ -192,7 +190,7 @@ func TestGetPolicy_Nil(t *testing.T) {
 // GetPermission nil test
 func TestGetPermission_Nil(t *testing.T) {
 	setUpStubConnected()
-	permission := etcdClient.GetPermission("permission")
+	permission := etcdClient.GetPermission(context.Background(), "permission")
 	if permission != nil {
 		t.Errorf("Incorrect TestGetPermission_Nil test")
 		t.FailNow()
@@ -202,7 +200,7 @@ func TestGetPermission_Nil(t *testing.T) {
 // GetRole nil test
 func TestGetRole_Nil(t *testing.T) {
 	setUpStubConnected()
-	role := etcdClient.GetRole("role")
+	role := etcdClient.GetRole(context.Background(), "role")
 	if role != nil {
 		t.Errorf("Incorrect TestGetRole_Nil test")
 		t.FailNow()
@@ -212,7 +210,7 @@ func TestGetRole_Nil(t *testing.T) {
 // GetService nil test
 func TestGetService_Nil(t *testing.T) {
 	setUpStubConnected()
-	service := etcdClient.GetService("service")
+	service := etcdClient.GetService(context.Background(), "service")
 	if service != nil {
 		t.Errorf("Incorrect TestGetService_Nil test")
 		t.FailNow()
@@ -222,7 +220,7 @@ func TestGetService_Nil(t *testing.T) {
 // GetUserService nil test
 func TestGetUserService_Nil(t *testing.T) {
 	setUpStubConnected()
-	userService := etcdClient.GetUserService(uuid.New().String())
+	userService := etcdClient.GetUserService(context.Background(), uuid.New().String())
 	if userService != nil {
 		t.Errorf("Incorrect TestGetUserService_Nil test")
 		t.FailNow()
@@ -232,9 +230,118 @@ func TestGetUserService_Nil(t *testing.T) {
 // GetUserGroup nil test
 func TestGetUserGroup_Nil(t *testing.T) {
 	setUpStubConnected()
-	userGroup := etcdClient.GetUserGroup(uuid.New().String())
+	userGroup := etcdClient.GetUserGroup(context.Background(), uuid.New().String())
 	if userGroup != nil {
 		t.Errorf("Incorrect TestGetUserGroup_Nil test")
 		t.FailNow()
 	}
 }
+
+// GetKeys not connected test
+func TestGetKeys_NotConnected(t *testing.T) {
+	setUpNotConnected()
+	keys, err := etcdClient.GetKeys(context.Background(), UserPolicyKeyPrefix)
+	if err == nil || keys != nil {
+		t.Errorf("Incorrect TestGetKeys_NotConnected test")
+		t.FailNow()
+	}
+}
+
+// GetKeys failed test
+func TestGetKeys_Error(t *testing.T) {
+	setUpStubConnected()
+	_, err := etcdClient.GetKeys(context.Background(), UserPolicyKeyPrefix)
+	if err == nil {
+		t.Errorf("Incorrect TestGetKeys_Error test")
+		t.FailNow()
+	}
+}
+
+// DeleteKeys not connected test
+func TestDeleteKeys_NotConnected(t *testing.T) {
+	setUpNotConnected()
+	etcdClient.DeleteKeys(context.Background(), []string{UserPolicyKeyPrefix + uuid.New().String()})
+}
+
+// Ping not connected test
+func TestPing_NotConnected(t *testing.T) {
+	setUpNotConnected()
+	if err := etcdClient.Ping(context.Background()); err == nil {
+		t.Errorf("Incorrect TestPing_NotConnected test")
+		t.FailNow()
+	}
+}
+
+// Ping failed test
+func TestPing_Error(t *testing.T) {
+	setUpStubConnected()
+	if err := etcdClient.Ping(context.Background()); err == nil {
+		t.Errorf("Incorrect TestPing_Error test")
+		t.FailNow()
+	}
+}
+
+// GetValues not connected test
+func TestGetValues_NotConnected(t *testing.T) {
+	setUpNotConnected()
+	values, err := etcdClient.GetValues(context.Background(), UserPolicyKeyPrefix)
+	if err == nil || values != nil {
+		t.Errorf("Incorrect TestGetValues_NotConnected test")
+		t.FailNow()
+	}
+}
+
+// Checkpoint not connected test
+func TestCheckpoint_NotConnected(t *testing.T) {
+	setUpNotConnected()
+	var position map[string]interface{}
+	found, err := etcdClient.GetCheckpoint(context.Background(), "binlog", &position)
+	if err == nil || found {
+		t.Errorf("Incorrect TestCheckpoint_NotConnected test")
+		t.FailNow()
+	}
+
+	if err := etcdClient.SetCheckpoint(context.Background(), "binlog", map[string]interface{}{"pos": 4}); err == nil {
+		t.Errorf("Incorrect TestCheckpoint_NotConnected test")
+		t.FailNow()
+	}
+}
+
+// SetBatch not connected test
+func TestSetBatch_NotConnected(t *testing.T) {
+	setUpNotConnected()
+	values := []KeyValue{{Key: RoleKeyPrefix + "a", Value: structure.Role{Name: "test"}}}
+	failed := etcdClient.SetBatch(context.Background(), values)
+	if len(failed) != 1 || len(failed[0].Values) != 1 || failed[0].Err == nil {
+		t.Errorf("Incorrect TestSetBatch_NotConnected test")
+		t.FailNow()
+	}
+}
+
+// SetBatch failed test
+func TestSetBatch_Error(t *testing.T) {
+	setUpStubConnected()
+	values := []KeyValue{
+		{Key: RoleKeyPrefix + "a", Value: structure.Role{Name: "a"}},
+		{Key: RoleKeyPrefix + "b", Value: structure.Role{Name: "b"}},
+		{Key: RoleKeyPrefix + "c", Value: structure.Role{Name: "c"}},
+	}
+	failed := EtcdClientImpl{Connection: connection, Timeout: 100 * time.Millisecond, BatchSize: 2}.SetBatch(context.Background(), values)
+	if len(failed) != 2 || len(RetryValues(failed)) != 3 {
+		t.Errorf("Incorrect TestSetBatch_Error test")
+		t.FailNow()
+	}
+}
+
+// GetKeys cancelled test
+func TestGetKeys_Cancelled(t *testing.T) {
+	setUpStubConnected()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	start := time.Now()
+	if _, err := (EtcdClientImpl{Connection: connection}).GetKeys(ctx, UserPolicyKeyPrefix); err == nil || time.Since(start) > time.Second {
+		t.Errorf("Incorrect TestGetKeys_Cancelled test")
+		t.FailNow()
+	}
+}
@@ -0,0 +1,350 @@
+package cache
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"go.etcd.io/etcd/clientv3"
+
+	"github.com/tomoyane/grant-n-z/gnz/log"
+)
+
+const (
+	// Wait time before watching again after watch failed
+	watchRetryInterval = 1 * time.Second
+
+	// Interval of logging local cache metrics
+	metricsReportInterval = 1 * time.Minute
+)
+
+// Global local cache. It is nil unless InitLocalCache is called
+var localCache *LocalCache
+
+// Watched cache key prefixes
+var watchPrefixes = []string{
+	UserPolicyKeyPrefix,
+	UserServiceKeyPrefix,
+	UserGroupKeyPrefix,
+	PermissionKeyPrefix,
+	RoleKeyPrefix,
+	ServiceKeyPrefix,
+}
+
+// Bounded LRU of etcd values in process
+// It is kept fresh by watching cache key prefixes. While the watch of a prefix is not running,
+// keys of the prefix are not cached, because changes could be missed
+// Standalone local cache is used without etcd, and keeps values that this process sets
+type LocalCache struct {
+	mutex    sync.Mutex
+	capacity int
+	entries  map[string]*list.Element
+	order    *list.List
+	watching map[string]bool
+	loads    map[string]*load
+	metrics  LocalCacheMetrics
+	cancel   context.CancelFunc
+
+	// This process is the only writer, so that values are set to local cache instead of etcd
+	standalone bool
+
+	// Key namespace in etcd. Keys in local cache do not have it
+	namespace string
+}
+
+// Local cache counts
+type LocalCacheMetrics struct {
+	Size      int    `json:"size"`
+	Capacity  int    `json:"capacity"`
+	Hits      uint64 `json:"hits"`
+	Misses    uint64 `json:"misses"`
+	Evictions uint64 `json:"evictions"`
+}
+
+type entry struct {
+	key   string
+	value []byte
+}
+
+// Key that is being loaded from etcd after miss
+// If the key changed while loading, the loaded value may be stale, so it is not cached
+type load struct {
+	count   int
+	changed bool
+}
+
+// Initialize local cache for EtcdClient, and start watching etcd
+// If size is 0, local cache is not used. If etcd is not connected, standalone local cache is used
+func InitLocalCache(size int) {
+	if size <= 0 {
+		log.Logger.Info("Not use local cache")
+		return
+	}
+	if connection == nil {
+		localCache = NewStandaloneLocalCache(size)
+		log.Logger.Info(fmt.Sprintf("Use standalone local cache without etcd. size = %d", size))
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	localCache = NewLocalCache(size)
+	localCache.cancel = cancel
+	localCache.namespace = keyNamespace()
+	for _, prefix := range watchPrefixes {
+		go localCache.Watch(ctx, connection, prefix)
+	}
+	log.Logger.Info(fmt.Sprintf("Use local cache. size = %d", size))
+}
+
+// Get metrics of local cache
+// If local cache is not used, it returns zero
+func GetLocalCacheMetrics() LocalCacheMetrics {
+	if localCache == nil {
+		return LocalCacheMetrics{}
+	}
+	return localCache.GetMetrics()
+}
+
+// Log metrics of local cache periodically
+func ReportLocalCacheMetrics() {
+	if localCache == nil {
+		return
+	}
+	for {
+		time.Sleep(metricsReportInterval)
+		metrics := localCache.GetMetrics()
+		log.Logger.Info(fmt.Sprintf("Local cache metrics. size = %d, hits = %d, misses = %d, evictions = %d",
+			metrics.Size, metrics.Hits, metrics.Misses, metrics.Evictions))
+	}
+}
+
+// Stop watching etcd
+func closeLocalCache() {
+	if localCache != nil && localCache.cancel != nil {
+		localCache.cancel()
+	}
+}
+
+// Constructor
+func NewLocalCache(capacity int) *LocalCache {
+	return &LocalCache{
+		capacity: capacity,
+		entries:  make(map[string]*list.Element),
+		order:    list.New(),
+		watching: make(map[string]bool),
+		loads:    make(map[string]*load),
+		metrics:  LocalCacheMetrics{Capacity: capacity},
+	}
+}
+
+// Constructor of standalone local cache
+// There are no changes by other processes, so that all prefixes are cached without watching
+func NewStandaloneLocalCache(capacity int) *LocalCache {
+	c := NewLocalCache(capacity)
+	c.standalone = true
+	for _, prefix := range watchPrefixes {
+		c.watching[prefix] = true
+	}
+	return c
+}
+
+// Local cache is used without etcd
+func (c *LocalCache) Standalone() bool {
+	return c.standalone
+}
+
+// Get value of key
+// On miss, the caller must call EndLoad after reading etcd
+func (c *LocalCache) Get(key string) ([]byte, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if element, ok := c.entries[key]; ok {
+		c.order.MoveToFront(element)
+		c.metrics.Hits++
+		return element.Value.(*entry).value, true
+	}
+
+	c.metrics.Misses++
+	l, ok := c.loads[key]
+	if !ok {
+		l = &load{}
+		c.loads[key] = l
+	}
+	l.count++
+	return nil, false
+}
+
+// End load of key that was started by Get
+// value is nil if key was not found in etcd
+func (c *LocalCache) EndLoad(key string, value []byte) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	l, ok := c.loads[key]
+	if !ok {
+		return
+	}
+	l.count--
+	if l.count <= 0 {
+		delete(c.loads, key)
+	}
+
+	if value == nil || l.changed || !c.watching[keyPrefix(key)] {
+		return
+	}
+	c.put(key, value)
+}
+
+// Set value of key to standalone local cache
+func (c *LocalCache) Set(key string, value []byte) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.markChanged(key)
+	c.put(key, value)
+}
+
+// Apply put of key in etcd
+// Only the key that is already cached is updated, so that the cache keeps hot keys
+func (c *LocalCache) Update(key string, value []byte) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.markChanged(key)
+	if element, ok := c.entries[key]; ok {
+		element.Value.(*entry).value = value
+	}
+}
+
+// Remove key
+func (c *LocalCache) Remove(key string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.markChanged(key)
+	if element, ok := c.entries[key]; ok {
+		c.order.Remove(element)
+		delete(c.entries, key)
+	}
+}
+
+// Remove all keys of prefix
+func (c *LocalCache) RemovePrefix(prefix string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	for key, l := range c.loads {
+		if strings.HasPrefix(key, prefix) {
+			l.changed = true
+		}
+	}
+	for key, element := range c.entries {
+		if strings.HasPrefix(key, prefix) {
+			c.order.Remove(element)
+			delete(c.entries, key)
+		}
+	}
+}
+
+// Get hit, miss and size counts
+func (c *LocalCache) GetMetrics() LocalCacheMetrics {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	metrics := c.metrics
+	metrics.Size = c.order.Len()
+	return metrics
+}
+
+// Watch prefix and apply changes until ctx is done
+// When watch fails, keys of the prefix are removed and it watches again
+func (c *LocalCache) Watch(ctx context.Context, client *clientv3.Client, prefix string) {
+	for ctx.Err() == nil {
+		watchChan := client.Watch(clientv3.WithRequireLeader(ctx), c.namespace+prefix, clientv3.WithPrefix(), clientv3.WithCreatedNotify())
+		for response := range watchChan {
+			if !c.HandleWatchResponse(prefix, response) {
+				break
+			}
+		}
+
+		c.setWatching(prefix, false)
+		select {
+		case <-ctx.Done():
+		case <-time.After(watchRetryInterval):
+			log.Logger.Info(fmt.Sprintf("Watch etcd again. prefix = %s", prefix))
+		}
+	}
+}
+
+// Apply watch response to cache
+// Returns false if watch failed
+func (c *LocalCache) HandleWatchResponse(prefix string, response clientv3.WatchResponse) bool {
+	if err := response.Err(); err != nil {
+		log.Logger.Warn(fmt.Sprintf("Failed to watch etcd. prefix = %s. err = %s", prefix, err.Error()))
+		c.setWatching(prefix, false)
+		return false
+	}
+
+	if response.Created {
+		c.setWatching(prefix, true)
+	}
+
+	for _, event := range response.Events {
+		key := strings.TrimPrefix(string(event.Kv.Key), c.namespace)
+		switch event.Type {
+		case clientv3.EventTypePut:
+			c.Update(key, event.Kv.Value)
+		case clientv3.EventTypeDelete:
+			c.Remove(key)
+		}
+	}
+	return true
+}
+
+// Start or stop caching keys of prefix
+// Keys of the prefix are removed, because changes could be missed while not watching
+func (c *LocalCache) setWatching(prefix string, watching bool) {
+	c.RemovePrefix(prefix)
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.watching[prefix] = watching
+}
+
+// Put key as most recently used, and evict least recently used key if over capacity
+func (c *LocalCache) put(key string, value []byte) {
+	if element, ok := c.entries[key]; ok {
+		element.Value.(*entry).value = value
+		c.order.MoveToFront(element)
+		return
+	}
+
+	c.entries[key] = c.order.PushFront(&entry{key: key, value: value})
+	if c.order.Len() > c.capacity {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*entry).key)
+		c.metrics.Evictions++
+	}
+}
+
+// Mark loading key as changed
+func (c *LocalCache) markChanged(key string) {
+	if l, ok := c.loads[key]; ok {
+		l.changed = true
+	}
+}
+
+// Cache key prefix of key
+func keyPrefix(key string) string {
+	for _, prefix := range watchPrefixes {
+		if strings.HasPrefix(key, prefix) {
+			return prefix
+		}
+	}
+	return ""
+}
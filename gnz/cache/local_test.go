@@ -0,0 +1,223 @@
+package cache
+
+import (
+	"context"
+	"testing"
+
+	"github.com/coreos/etcd/mvcc/mvccpb"
+	"go.etcd.io/etcd/clientv3"
+
+	"github.com/tomoyane/grant-n-z/gnz/cache/structure"
+)
+
+// Local cache that is watching all prefixes
+func newWatchingLocalCache(capacity int) *LocalCache {
+	c := NewLocalCache(capacity)
+	for _, prefix := range watchPrefixes {
+		c.HandleWatchResponse(prefix, clientv3.WatchResponse{Created: true})
+	}
+	return c
+}
+
+// Load key to local cache as EtcdClient does on miss
+func loadLocalCache(c *LocalCache, key string, value string) {
+	if _, ok := c.Get(key); !ok {
+		c.EndLoad(key, []byte(value))
+	}
+}
+
+// Test get hit and miss
+func TestLocalCacheGet(t *testing.T) {
+	c := newWatchingLocalCache(10)
+	key := RoleKeyPrefix + "uuid"
+
+	if _, ok := c.Get(key); ok {
+		t.Errorf("Incorrect TestLocalCacheGet test. Empty cache hit")
+		t.FailNow()
+	}
+	c.EndLoad(key, []byte("value"))
+
+	value, ok := c.Get(key)
+	if !ok || string(value) != "value" {
+		t.Errorf("Incorrect TestLocalCacheGet test. value = %s", string(value))
+		t.FailNow()
+	}
+
+	metrics := c.GetMetrics()
+	if metrics.Hits != 1 || metrics.Misses != 1 || metrics.Size != 1 || metrics.Capacity != 10 {
+		t.Errorf("Incorrect TestLocalCacheGet test. metrics = %v", metrics)
+		t.FailNow()
+	}
+}
+
+// Test least recently used key is evicted
+func TestLocalCacheEviction(t *testing.T) {
+	c := newWatchingLocalCache(2)
+	loadLocalCache(c, RoleKeyPrefix+"a", "a")
+	loadLocalCache(c, RoleKeyPrefix+"b", "b")
+	c.Get(RoleKeyPrefix + "a")
+	loadLocalCache(c, RoleKeyPrefix+"c", "c")
+
+	if _, ok := c.Get(RoleKeyPrefix + "b"); ok {
+		t.Errorf("Incorrect TestLocalCacheEviction test. Least recently used key was not evicted")
+		t.FailNow()
+	}
+	c.EndLoad(RoleKeyPrefix+"b", nil)
+
+	if _, ok := c.Get(RoleKeyPrefix + "a"); !ok {
+		t.Errorf("Incorrect TestLocalCacheEviction test. Recently used key was evicted")
+		t.FailNow()
+	}
+
+	if metrics := c.GetMetrics(); metrics.Size != 2 || metrics.Evictions != 1 {
+		t.Errorf("Incorrect TestLocalCacheEviction test. metrics = %v", metrics)
+		t.FailNow()
+	}
+}
+
+// Test value loaded while the key changed is not cached
+func TestLocalCacheEndLoad_Changed(t *testing.T) {
+	c := newWatchingLocalCache(10)
+	key := UserPolicyKeyPrefix + "uuid"
+
+	c.Get(key)
+	c.HandleWatchResponse(UserPolicyKeyPrefix, clientv3.WatchResponse{Events: []*clientv3.Event{
+		{Type: clientv3.EventTypePut, Kv: &mvccpb.KeyValue{Key: []byte(key), Value: []byte("new")}},
+	}})
+	c.EndLoad(key, []byte("old"))
+
+	if _, ok := c.Get(key); ok {
+		t.Errorf("Incorrect TestLocalCacheEndLoad_Changed test. Stale value was cached")
+		t.FailNow()
+	}
+}
+
+// Test keys are not cached while the prefix is not watched
+func TestLocalCacheEndLoad_NotWatching(t *testing.T) {
+	c := NewLocalCache(10)
+	loadLocalCache(c, RoleKeyPrefix+"uuid", "value")
+
+	if _, ok := c.Get(RoleKeyPrefix + "uuid"); ok {
+		t.Errorf("Incorrect TestLocalCacheEndLoad_NotWatching test. Key was cached without watch")
+		t.FailNow()
+	}
+}
+
+// Test watch events update and remove cached keys
+func TestLocalCacheHandleWatchResponse(t *testing.T) {
+	c := newWatchingLocalCache(10)
+	loadLocalCache(c, RoleKeyPrefix+"a", "a")
+	loadLocalCache(c, RoleKeyPrefix+"b", "b")
+
+	ok := c.HandleWatchResponse(RoleKeyPrefix, clientv3.WatchResponse{Events: []*clientv3.Event{
+		{Type: clientv3.EventTypePut, Kv: &mvccpb.KeyValue{Key: []byte(RoleKeyPrefix + "a"), Value: []byte("a2")}},
+		{Type: clientv3.EventTypePut, Kv: &mvccpb.KeyValue{Key: []byte(RoleKeyPrefix + "c"), Value: []byte("c")}},
+		{Type: clientv3.EventTypeDelete, Kv: &mvccpb.KeyValue{Key: []byte(RoleKeyPrefix + "b")}},
+	}})
+	if !ok {
+		t.Errorf("Incorrect TestLocalCacheHandleWatchResponse test. Watch failed")
+		t.FailNow()
+	}
+
+	if value, ok := c.Get(RoleKeyPrefix + "a"); !ok || string(value) != "a2" {
+		t.Errorf("Incorrect TestLocalCacheHandleWatchResponse test. a = %s", string(value))
+		t.FailNow()
+	}
+	if metrics := c.GetMetrics(); metrics.Size != 1 {
+		t.Errorf("Incorrect TestLocalCacheHandleWatchResponse test. metrics = %v", metrics)
+		t.FailNow()
+	}
+}
+
+// Test watch failure removes keys of the prefix and stops caching it
+func TestLocalCacheHandleWatchResponse_Error(t *testing.T) {
+	c := newWatchingLocalCache(10)
+	loadLocalCache(c, RoleKeyPrefix+"a", "a")
+	loadLocalCache(c, ServiceKeyPrefix+"a", "a")
+
+	if c.HandleWatchResponse(RoleKeyPrefix, clientv3.WatchResponse{CompactRevision: 1}) {
+		t.Errorf("Incorrect TestLocalCacheHandleWatchResponse_Error test. Compacted watch did not fail")
+		t.FailNow()
+	}
+	if c.HandleWatchResponse(ServiceKeyPrefix, clientv3.WatchResponse{Canceled: true}) {
+		t.Errorf("Incorrect TestLocalCacheHandleWatchResponse_Error test. Canceled watch did not fail")
+		t.FailNow()
+	}
+	if _, ok := c.Get(RoleKeyPrefix + "a"); ok {
+		t.Errorf("Incorrect TestLocalCacheHandleWatchResponse_Error test. Key was not removed")
+		t.FailNow()
+	}
+	if metrics := c.GetMetrics(); metrics.Size != 0 {
+		t.Errorf("Incorrect TestLocalCacheHandleWatchResponse_Error test. metrics = %v", metrics)
+		t.FailNow()
+	}
+}
+
+// Test EtcdClient reads local cache before etcd
+func TestGetUserPolicy_LocalCache(t *testing.T) {
+	c := newWatchingLocalCache(10)
+	loadLocalCache(c, UserPolicyKeyPrefix+"uuid", `[{"service_uuid":"service","role_name":"admin"}]`)
+	etcdClient = EtcdClientImpl{Connection: nil, LocalCache: c}
+
+	policies := etcdClient.GetUserPolicy(context.Background(), "uuid")
+	if len(policies) != 1 || policies[0] != (structure.UserPolicy{ServiceUuid: "service", RoleName: "admin"}) {
+		t.Errorf("Incorrect TestGetUserPolicy_LocalCache test. policies = %v", policies)
+		t.FailNow()
+	}
+
+	if etcdClient.GetUserPolicy(context.Background(), "other") != nil {
+		t.Errorf("Incorrect TestGetUserPolicy_LocalCache test. Not cached user has policies")
+		t.FailNow()
+	}
+}
+
+// Test local cache metrics without local cache
+func TestGetLocalCacheMetrics(t *testing.T) {
+	if metrics := GetLocalCacheMetrics(); metrics != (LocalCacheMetrics{}) {
+		t.Errorf("Incorrect TestGetLocalCacheMetrics test. metrics = %v", metrics)
+		t.FailNow()
+	}
+}
+
+// Test watch events of namespaced keys
+func TestLocalCacheHandleWatchResponse_Namespace(t *testing.T) {
+	c := newWatchingLocalCache(10)
+	c.namespace = "staging/"
+	loadLocalCache(c, RoleKeyPrefix+"a", "a")
+
+	c.HandleWatchResponse(RoleKeyPrefix, clientv3.WatchResponse{Events: []*clientv3.Event{
+		{Type: clientv3.EventTypeDelete, Kv: &mvccpb.KeyValue{Key: []byte("staging/" + RoleKeyPrefix + "a")}},
+	}})
+	if _, ok := c.Get(RoleKeyPrefix + "a"); ok {
+		t.Errorf("Incorrect TestLocalCacheHandleWatchResponse_Namespace test. Key was not removed")
+		t.FailNow()
+	}
+}
+
+// Test EtcdClient sets and deletes standalone local cache without etcd
+func TestStandaloneLocalCache(t *testing.T) {
+	c := NewStandaloneLocalCache(10)
+	etcdClient = EtcdClientImpl{Connection: nil, LocalCache: c}
+
+	etcdClient.SetUserPolicy(context.Background(), "uuid", []structure.UserPolicy{{ServiceUuid: "service", RoleName: "admin"}})
+	policies := etcdClient.GetUserPolicy(context.Background(), "uuid")
+	if len(policies) != 1 || policies[0].RoleName != "admin" {
+		t.Errorf("Incorrect TestStandaloneLocalCache test. policies = %v", policies)
+		t.FailNow()
+	}
+
+	etcdClient.DeleteUserPolicy(context.Background(), "uuid")
+	if etcdClient.GetUserPolicy(context.Background(), "uuid") != nil {
+		t.Errorf("Incorrect TestStandaloneLocalCache test. Deleted user has policies")
+		t.FailNow()
+	}
+
+	// Local cache that is not standalone is not written without etcd
+	c = newWatchingLocalCache(10)
+	etcdClient = EtcdClientImpl{Connection: nil, LocalCache: c}
+	etcdClient.SetUserPolicy(context.Background(), "uuid", []structure.UserPolicy{{RoleName: "admin"}})
+	if metrics := c.GetMetrics(); metrics.Size != 0 {
+		t.Errorf("Incorrect TestStandaloneLocalCache test. metrics = %v", metrics)
+		t.FailNow()
+	}
+}
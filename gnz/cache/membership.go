@@ -0,0 +1,369 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"go.etcd.io/etcd/clientv3"
+
+	"github.com/tomoyane/grant-n-z/gnz/common"
+	"github.com/tomoyane/grant-n-z/gnz/log"
+)
+
+// Key prefixes of gnzcacher replicas. They are not cache data, so they are not in KeyPrefixes
+// key: member={member_id}, value: {member_id}
+// key: shard={shard}, value: {member_id} of the owner
+// key: leader={name}, value: {member_id} of the leader
+const (
+	MemberKeyPrefix = "member="
+	ShardKeyPrefix  = "shard="
+	LeaderKeyPrefix = "leader="
+)
+
+const (
+	// Member and shard keys are deleted when the replica did not keep alive in this ttl
+	memberTtlSeconds = 10
+	leaveTimeout     = 1 * time.Second
+)
+
+// Shards of user uuid space
+// A user belongs to the shard of the first hex character of its uuid
+var Shards = []string{"0", "1", "2", "3", "4", "5", "6", "7", "8", "9", "a", "b", "c", "d", "e", "f"}
+
+// Error of writes with the context of Membership.Guard or Membership.Lead after the shard or the leader moved to another replica
+var ErrShardNotOwned = errors.New("Shard is not owned by this replica")
+
+var mInstance Membership
+
+// Membership of gnzcacher replicas that split shards between them
+// Member and shard keys are put with the lease of the replica, so they are deleted when the replica stopped
+type Membership interface {
+	// Join members and keep alive
+	// It does nothing if already joined. If the lease expired, it joins again
+	Join(ctx context.Context) error
+
+	// Leave members and release all shards
+	Leave(ctx context.Context) error
+
+	// Id of this replica
+	Id() string
+
+	// Ids of all members in sorted order
+	Members(ctx context.Context) ([]string, error)
+
+	// Claim shard for this replica
+	// It returns false if another replica owns the shard
+	Claim(ctx context.Context, shard string) (bool, error)
+
+	// Release shard if this replica owns it
+	Release(ctx context.Context, shard string) error
+
+	// Context whose cache writes succeed only while this replica owns shard
+	// Writes fail with ErrShardNotOwned after the shard moved to another replica
+	Guard(ctx context.Context, shard string) context.Context
+
+	// Campaign for leader of name, such as the binlog follower
+	// It returns false if another replica is the leader. The leader is kept until its lease expires or it leaves
+	Campaign(ctx context.Context, name string) (bool, error)
+
+	// Context whose cache writes succeed only while this replica is the leader of name
+	// Writes fail with ErrShardNotOwned after another replica became the leader
+	Lead(ctx context.Context, name string) context.Context
+}
+
+type MembershipImpl struct {
+	Connection *clientv3.Client
+	Namespace  string
+	MemberId   string
+
+	// Timeout of each etcd call. If 0, it waits until ctx of the caller is done
+	Timeout time.Duration
+
+	// Ttl of lease. If 0, memberTtlSeconds is used
+	TtlSeconds int64
+
+	lease *memberLease
+}
+
+// Lease of this replica shared between copies of MembershipImpl
+type memberLease struct {
+	mutex  sync.Mutex
+	id     clientv3.LeaseID
+	cancel context.CancelFunc
+}
+
+// Owner of shard that writes of the context require
+type shardOwner struct {
+	key      string
+	memberId string
+}
+
+type shardOwnerKey struct{}
+
+func GetMembershipInstance() Membership {
+	if mInstance == nil {
+		mInstance = NewMembership()
+	}
+	return mInstance
+}
+
+// Constructor
+// Member id is host name with random suffix, so that restarted replica is a new member
+func NewMembership() Membership {
+	log.Logger.Info("New `Membership` instance")
+	hostname, err := os.Hostname()
+	if err != nil || hostname == "" {
+		hostname = "gnzcacher"
+	}
+	return NewMembershipWithId(connection, fmt.Sprintf("%s-%s", hostname, uuid.New().String()[:8]))
+}
+
+// Constructor with member id
+func NewMembershipWithId(conn *clientv3.Client, memberId string) MembershipImpl {
+	return MembershipImpl{
+		Connection: conn,
+		Namespace:  keyNamespace(),
+		MemberId:   memberId,
+		Timeout:    time.Duration(common.Etcd.Timeout) * time.Millisecond,
+		TtlSeconds: memberTtlSeconds,
+		lease:      &memberLease{},
+	}
+}
+
+func (m MembershipImpl) Join(ctx context.Context) error {
+	if m.Connection == nil {
+		return errors.New("Not connected etcd")
+	}
+	m.lease.mutex.Lock()
+	defer m.lease.mutex.Unlock()
+	if m.lease.id != 0 {
+		return nil
+	}
+
+	ttl := m.TtlSeconds
+	if ttl <= 0 {
+		ttl = memberTtlSeconds
+	}
+	joinCtx, cancel := m.withTimeout(ctx)
+	defer cancel()
+	grant, err := m.Connection.Grant(joinCtx, ttl)
+	if err != nil {
+		return err
+	}
+	if _, err := m.Connection.Put(joinCtx, m.Namespace+MemberKeyPrefix+m.MemberId, m.MemberId, clientv3.WithLease(grant.ID)); err != nil {
+		return err
+	}
+
+	keepAliveCtx, keepAliveCancel := context.WithCancel(context.Background())
+	responses, err := m.Connection.KeepAlive(keepAliveCtx, grant.ID)
+	if err != nil {
+		keepAliveCancel()
+		return err
+	}
+	m.lease.id = grant.ID
+	m.lease.cancel = keepAliveCancel
+	go m.keepAlive(grant.ID, responses)
+
+	log.Logger.Info(fmt.Sprintf("Joined gnzcacher members. member_id = %s", m.MemberId))
+	return nil
+}
+
+func (m MembershipImpl) Leave(ctx context.Context) error {
+	if m.Connection == nil {
+		return errors.New("Not connected etcd")
+	}
+	m.lease.mutex.Lock()
+	id := m.lease.id
+	if id != 0 {
+		m.lease.id = 0
+		m.lease.cancel()
+	}
+	m.lease.mutex.Unlock()
+	if id == 0 {
+		return nil
+	}
+
+	// Revoking lease deletes member key and all shard keys of this replica
+	leaveCtx, cancel := m.withTimeout(ctx)
+	defer cancel()
+	if _, err := m.Connection.Revoke(leaveCtx, id); err != nil {
+		return err
+	}
+	log.Logger.Info(fmt.Sprintf("Left gnzcacher members. member_id = %s", m.MemberId))
+	return nil
+}
+
+func (m MembershipImpl) Id() string {
+	return m.MemberId
+}
+
+func (m MembershipImpl) Members(ctx context.Context) ([]string, error) {
+	if m.Connection == nil {
+		return nil, errors.New("Not connected etcd")
+	}
+	getCtx, cancel := m.withTimeout(ctx)
+	defer cancel()
+	response, err := m.Connection.Get(getCtx, m.Namespace+MemberKeyPrefix, clientv3.WithPrefix(), clientv3.WithKeysOnly())
+	if err != nil {
+		return nil, err
+	}
+
+	members := make([]string, 0, len(response.Kvs))
+	for _, kv := range response.Kvs {
+		members = append(members, strings.TrimPrefix(string(kv.Key), m.Namespace+MemberKeyPrefix))
+	}
+	sort.Strings(members)
+	return members, nil
+}
+
+func (m MembershipImpl) Claim(ctx context.Context, shard string) (bool, error) {
+	return m.claim(ctx, ShardKeyPrefix+shard)
+}
+
+// Put key of this replica with its lease, if no other replica has put it
+func (m MembershipImpl) claim(ctx context.Context, key string) (bool, error) {
+	leaseId, err := m.leaseId()
+	if err != nil {
+		return false, err
+	}
+
+	key = m.Namespace + key
+	txnCtx, cancel := m.withTimeout(ctx)
+	defer cancel()
+	response, err := m.Connection.Txn(txnCtx).
+		If(clientv3.Compare(clientv3.CreateRevision(key), "=", 0)).
+		Then(clientv3.OpPut(key, m.MemberId, clientv3.WithLease(leaseId))).
+		Else(clientv3.OpGet(key)).
+		Commit()
+	if err != nil {
+		return false, err
+	}
+	if response.Succeeded {
+		return true, nil
+	}
+
+	// Key of the lease that expired on client side is not owned until etcd deletes it
+	kvs := response.Responses[0].GetResponseRange().Kvs
+	return len(kvs) != 0 && string(kvs[0].Value) == m.MemberId && kvs[0].Lease == int64(leaseId), nil
+}
+
+func (m MembershipImpl) Release(ctx context.Context, shard string) error {
+	if m.Connection == nil {
+		return errors.New("Not connected etcd")
+	}
+	key := m.Namespace + ShardKeyPrefix + shard
+	txnCtx, cancel := m.withTimeout(ctx)
+	defer cancel()
+	_, err := m.Connection.Txn(txnCtx).
+		If(clientv3.Compare(clientv3.Value(key), "=", m.MemberId)).
+		Then(clientv3.OpDelete(key)).
+		Commit()
+	return err
+}
+
+func (m MembershipImpl) Guard(ctx context.Context, shard string) context.Context {
+	return context.WithValue(ctx, shardOwnerKey{}, shardOwner{key: ShardKeyPrefix + shard, memberId: m.MemberId})
+}
+
+func (m MembershipImpl) Campaign(ctx context.Context, name string) (bool, error) {
+	return m.claim(ctx, LeaderKeyPrefix+name)
+}
+
+func (m MembershipImpl) Lead(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, shardOwnerKey{}, shardOwner{key: LeaderKeyPrefix + name, memberId: m.MemberId})
+}
+
+// Drain keep alive responses
+// The channel is closed when the lease expired or Leave was called. After expired, Join grants a new lease
+func (m MembershipImpl) keepAlive(id clientv3.LeaseID, responses <-chan *clientv3.LeaseKeepAliveResponse) {
+	for range responses {
+	}
+
+	m.lease.mutex.Lock()
+	defer m.lease.mutex.Unlock()
+	if m.lease.id == id {
+		log.Logger.Warn(fmt.Sprintf("Lease of gnzcacher member expired. member_id = %s", m.MemberId))
+		m.lease.id = 0
+		m.lease.cancel()
+	}
+}
+
+// Lease of this replica. It returns error if not joined
+func (m MembershipImpl) leaseId() (clientv3.LeaseID, error) {
+	if m.Connection == nil {
+		return 0, errors.New("Not connected etcd")
+	}
+	m.lease.mutex.Lock()
+	defer m.lease.mutex.Unlock()
+	if m.lease.id == 0 {
+		return 0, errors.New(fmt.Sprintf("Not joined gnzcacher members. member_id = %s", m.MemberId))
+	}
+	return m.lease.id, nil
+}
+
+func (m MembershipImpl) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if m.Timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, m.Timeout)
+}
+
+// Shard of user uuid
+func ShardOf(userUuid string) string {
+	if userUuid == "" {
+		return ""
+	}
+	return strings.ToLower(userUuid[:1])
+}
+
+// Shards assigned to member
+// Shards are assigned in round robin of sorted members, so that the numbers of shards differ at most one
+// It returns nil if member is not in members
+func AssignShards(members []string, member string) []string {
+	sorted := make([]string, len(members))
+	copy(sorted, members)
+	sort.Strings(sorted)
+
+	index := sort.SearchStrings(sorted, member)
+	if index == len(sorted) || sorted[index] != member {
+		return nil
+	}
+
+	var shards []string
+	for i, shard := range Shards {
+		if i%len(sorted) == index {
+			shards = append(shards, shard)
+		}
+	}
+	return shards
+}
+
+// Compare of etcd transaction that the owner of shard has not changed
+func (o shardOwner) compare(namespace string) clientv3.Cmp {
+	return clientv3.Compare(clientv3.Value(namespace+o.key), "=", o.memberId)
+}
+
+func shardOwnerOf(ctx context.Context) (shardOwner, bool) {
+	owner, ok := ctx.Value(shardOwnerKey{}).(shardOwner)
+	return owner, ok
+}
+
+// Leave members on close of etcd
+func closeMembership() {
+	if mInstance == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), leaveTimeout)
+	defer cancel()
+	if err := mInstance.Leave(ctx); err != nil {
+		log.Logger.Warn(fmt.Sprintf("Failed to leave gnzcacher members. err = %s", err.Error()))
+	}
+	mInstance = nil
+}
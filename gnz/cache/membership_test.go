@@ -0,0 +1,112 @@
+package cache
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+// Test shard of user uuid
+func TestShardOf(t *testing.T) {
+	if ShardOf("A1b2") != "a" || ShardOf("0abc") != "0" || ShardOf("") != "" {
+		t.Errorf("Incorrect TestShardOf test")
+		t.FailNow()
+	}
+}
+
+// Test shards are split between members without overlap
+func TestAssignShards(t *testing.T) {
+	members := []string{"c", "a", "b"}
+	assigned := make(map[string]string)
+	for _, member := range members {
+		shards := AssignShards(members, member)
+		if len(shards) < 5 || len(shards) > 6 {
+			t.Errorf("Incorrect TestAssignShards test. member = %s, shards = %v", member, shards)
+			t.FailNow()
+		}
+		for _, shard := range shards {
+			if owner, ok := assigned[shard]; ok {
+				t.Errorf("Incorrect TestAssignShards test. shard %s is assigned to %s and %s", shard, owner, member)
+				t.FailNow()
+			}
+			assigned[shard] = member
+		}
+	}
+	if len(assigned) != len(Shards) || assigned["0"] != "a" {
+		t.Errorf("Incorrect TestAssignShards test. assigned = %v", assigned)
+		t.FailNow()
+	}
+
+	if shards := AssignShards(members, "d"); shards != nil {
+		t.Errorf("Incorrect TestAssignShards test. shards = %v", shards)
+		t.FailNow()
+	}
+}
+
+// Test guard context has the owner of shard
+func TestGuard(t *testing.T) {
+	membership := NewMembershipWithId(nil, "member-a")
+	ctx := membership.Guard(context.Background(), "a")
+
+	owner, ok := shardOwnerOf(ctx)
+	if !ok || owner.key != ShardKeyPrefix+"a" || owner.memberId != "member-a" {
+		t.Errorf("Incorrect TestGuard test. owner = %v", owner)
+		t.FailNow()
+	}
+	if _, ok := shardOwnerOf(context.Background()); ok {
+		t.Errorf("Incorrect TestGuard test")
+		t.FailNow()
+	}
+}
+
+// Test lead context has the leader of name
+func TestLead(t *testing.T) {
+	membership := NewMembershipWithId(nil, "member-a")
+	ctx := membership.Lead(context.Background(), "binlog")
+
+	owner, ok := shardOwnerOf(ctx)
+	if !ok || owner.key != LeaderKeyPrefix+"binlog" || owner.memberId != "member-a" {
+		t.Errorf("Incorrect TestLead test. owner = %v", owner)
+		t.FailNow()
+	}
+}
+
+// Test membership is not connected
+func TestMembership_NotConnected(t *testing.T) {
+	membership := NewMembershipWithId(nil, "member-a")
+	ctx := context.Background()
+
+	if err := membership.Join(ctx); err == nil {
+		t.Errorf("Incorrect TestMembership_NotConnected test. join")
+		t.FailNow()
+	}
+	if _, err := membership.Members(ctx); err == nil {
+		t.Errorf("Incorrect TestMembership_NotConnected test. members")
+		t.FailNow()
+	}
+	if _, err := membership.Claim(ctx, "0"); err == nil {
+		t.Errorf("Incorrect TestMembership_NotConnected test. claim")
+		t.FailNow()
+	}
+	if _, err := membership.Campaign(ctx, "binlog"); err == nil {
+		t.Errorf("Incorrect TestMembership_NotConnected test. campaign")
+		t.FailNow()
+	}
+	if err := membership.Release(ctx, "0"); err == nil {
+		t.Errorf("Incorrect TestMembership_NotConnected test. release")
+		t.FailNow()
+	}
+	if membership.Id() != "member-a" {
+		t.Errorf("Incorrect TestMembership_NotConnected test. id = %s", membership.Id())
+		t.FailNow()
+	}
+}
+
+// Test member id of constructor
+func TestNewMembership(t *testing.T) {
+	membership := NewMembership()
+	if !strings.Contains(membership.Id(), "-") {
+		t.Errorf("Incorrect TestNewMembership test. id = %s", membership.Id())
+		t.FailNow()
+	}
+}
@@ -0,0 +1,96 @@
+package cache
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// MessagePack codec of cache values
+// Structs are maps by json name of fields, so that the data is the same as json of the struct
+const msgpackStructTag = "json"
+
+// Convert value to msgpack
+// Json is written as msgpack of its data
+func marshalMsgpack(value interface{}) ([]byte, error) {
+	if raw, ok := value.(json.RawMessage); ok {
+		data, err := jsonToGeneric(raw)
+		if err != nil {
+			return nil, err
+		}
+		value = data
+	}
+
+	var buf bytes.Buffer
+	encoder := msgpack.NewEncoder(&buf)
+	encoder.SetCustomStructTag(msgpackStructTag)
+	if err := encoder.Encode(value); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Convert msgpack to value
+// value needs to be pointer. Pointer of json.RawMessage is json of the data
+func unmarshalMsgpack(data []byte, value interface{}) error {
+	if raw, ok := value.(*json.RawMessage); ok {
+		jsonData, err := msgpackToJson(data)
+		if err != nil {
+			return err
+		}
+		*raw = jsonData
+		return nil
+	}
+
+	reader := bytes.NewReader(data)
+	decoder := msgpack.NewDecoder(reader)
+	decoder.SetCustomStructTag(msgpackStructTag)
+	decoder.UseLooseInterfaceDecoding(true)
+	if err := decoder.Decode(value); err != nil {
+		return err
+	}
+	if reader.Len() != 0 {
+		return errors.New(fmt.Sprintf("Invalid msgpack. %d bytes after value", reader.Len()))
+	}
+	return nil
+}
+
+// Read json as the data of json.Unmarshal to interface{}
+// Integers are int64 or uint64 instead of float64, so that they are not rounded
+func jsonToGeneric(raw json.RawMessage) (interface{}, error) {
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	var data interface{}
+	if err := decoder.Decode(&data); err != nil {
+		return nil, err
+	}
+	return genericNumbers(data), nil
+}
+
+// Convert json.Number in data to number of msgpack
+func genericNumbers(data interface{}) interface{} {
+	switch v := data.(type) {
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i
+		}
+		if u, err := strconv.ParseUint(v.String(), 10, 64); err == nil {
+			return u
+		}
+		f, _ := v.Float64()
+		return f
+	case []interface{}:
+		for i := range v {
+			v[i] = genericNumbers(v[i])
+		}
+	case map[string]interface{}:
+		for key := range v {
+			v[key] = genericNumbers(v[key])
+		}
+	}
+	return data
+}
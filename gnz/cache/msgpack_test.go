@@ -0,0 +1,108 @@
+package cache
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/tomoyane/grant-n-z/gnz/cache/structure"
+)
+
+// Test struct of msgpack with tags and types that are not in structure
+type msgpackTestData struct {
+	Name      string            `json:"name"`
+	Count     int               `json:"count"`
+	Negative  int64             `json:"negative"`
+	Position  uint32            `json:"position"`
+	Ratio     float64           `json:"ratio"`
+	Enabled   bool              `json:"enabled"`
+	Omitted   string            `json:"omitted,omitempty"`
+	Skipped   string            `json:"-"`
+	Labels    map[string]string `json:"labels"`
+	Empty     []string          `json:"empty"`
+	CreatedAt time.Time         `json:"created_at"`
+	Role      *structure.Role   `json:"role"`
+}
+
+// Test msgpack of struct
+func TestMsgpack_Struct(t *testing.T) {
+	createdAt := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	source := msgpackTestData{
+		Name:      "test",
+		Count:     300,
+		Negative:  -70000,
+		Position:  4294967295,
+		Ratio:     0.5,
+		Enabled:   true,
+		Skipped:   "skipped",
+		Labels:    map[string]string{"a": "b"},
+		CreatedAt: createdAt,
+		Role:      &structure.Role{Name: "admin", Uuid: "uuid"},
+	}
+	data, err := marshalMsgpack(source)
+	if err != nil {
+		t.Errorf("Incorrect TestMsgpack_Struct test. err = %v", err)
+		t.FailNow()
+	}
+
+	var target msgpackTestData
+	if err := unmarshalMsgpack(data, &target); err != nil {
+		t.Errorf("Incorrect TestMsgpack_Struct test. err = %v", err)
+		t.FailNow()
+	}
+	source.Skipped = ""
+	if target.Name != source.Name || target.Count != source.Count || target.Negative != source.Negative || target.Position != source.Position ||
+		target.Ratio != source.Ratio || !target.Enabled || target.Skipped != "" || target.Labels["a"] != "b" || target.Empty != nil ||
+		!target.CreatedAt.Equal(createdAt) || target.Role == nil || target.Role.Name != "admin" {
+		t.Errorf("Incorrect TestMsgpack_Struct test. target = %v", target)
+		t.FailNow()
+	}
+}
+
+// Test msgpack is read as the same json as the struct
+func TestMsgpack_Json(t *testing.T) {
+	policies := []structure.UserPolicy{{ServiceUuid: "service", RoleName: "admin"}}
+	data, err := marshalMsgpack(policies)
+	if err != nil {
+		t.Errorf("Incorrect TestMsgpack_Json test. err = %v", err)
+		t.FailNow()
+	}
+
+	var raw json.RawMessage
+	if err := unmarshalMsgpack(data, &raw); err != nil {
+		t.Errorf("Incorrect TestMsgpack_Json test. err = %v", err)
+		t.FailNow()
+	}
+	var decoded []structure.UserPolicy
+	if err := json.Unmarshal(raw, &decoded); err != nil || len(decoded) != 1 || decoded[0] != policies[0] {
+		t.Errorf("Incorrect TestMsgpack_Json test. json = %s", string(raw))
+		t.FailNow()
+	}
+
+	// Json is written as msgpack of its data
+	rawData, err := marshalMsgpack(json.RawMessage(`{"count":1,"name":"test","ratio":1.5}`))
+	var target msgpackTestData
+	if err != nil || unmarshalMsgpack(rawData, &target) != nil || target.Count != 1 || target.Name != "test" || target.Ratio != 1.5 {
+		t.Errorf("Incorrect TestMsgpack_Json test. target = %v", target)
+		t.FailNow()
+	}
+}
+
+// Test invalid msgpack
+func TestMsgpack_Invalid(t *testing.T) {
+	var policies []structure.UserPolicy
+	invalids := [][]byte{
+		{},
+		{0x91},
+		{0x91, 0x81, 0xa1},
+		{0xdd, 0xff, 0xff, 0xff, 0xff},
+		{0x90, 0x90},
+		{0xc1},
+	}
+	for _, data := range invalids {
+		if err := unmarshalMsgpack(data, &policies); err == nil {
+			t.Errorf("Incorrect TestMsgpack_Invalid test. data = %v", data)
+			t.FailNow()
+		}
+	}
+}
@@ -0,0 +1,329 @@
+package cache
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"go.etcd.io/etcd/clientv3"
+
+	"github.com/tomoyane/grant-n-z/gnz/log"
+)
+
+// Format of snapshot file
+// Snapshot file is gzip of json lines. First line is SnapshotHeader, and each next line is SnapshotEntry
+const (
+	SnapshotFormat        = "grant-n-z-cache-snapshot"
+	SnapshotFormatVersion = 1
+)
+
+// Export and import all cache keys of the namespace
+type Snapshot interface {
+	// Write all cache keys to snapshot file
+	// Keys are read at one etcd revision, so the snapshot is consistent
+	Export(ctx context.Context, w io.Writer) (SnapshotResult, error)
+
+	// Put all keys of snapshot file
+	// The whole file is validated before put. Keys that are not in the snapshot are not deleted
+	Import(ctx context.Context, r io.Reader) (SnapshotResult, error)
+}
+
+// First line of snapshot file
+type SnapshotHeader struct {
+	Format        string    `json:"format"`
+	Version       int       `json:"version"`
+	SchemaVersion int       `json:"schema_version"`
+	Namespace     string    `json:"namespace"`
+	Revision      int64     `json:"revision"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// Cache key and value of snapshot file
+// Key does not have namespace, and value is versioned json of etcd as it is
+type SnapshotEntry struct {
+	Key   string          `json:"key"`
+	Value json.RawMessage `json:"value"`
+}
+
+// Result of export and import
+type SnapshotResult struct {
+	Header SnapshotHeader `json:"header"`
+	Keys   int            `json:"keys"`
+}
+
+// Difference of two snapshots
+type SnapshotDiff struct {
+	// Keys that only target has
+	Added []string `json:"added"`
+
+	// Keys that only source has
+	Removed []string `json:"removed"`
+
+	// Keys that have different data
+	Changed []string `json:"changed"`
+}
+
+// Snapshot file can not be imported
+type InvalidSnapshotError struct {
+	Detail string
+}
+
+func (e InvalidSnapshotError) Error() string {
+	return e.Detail
+}
+
+type SnapshotImpl struct {
+	Etcd EtcdClientImpl
+}
+
+// Constructor
+// Need to initial InitEtcd method
+func NewSnapshot() Snapshot {
+	log.Logger.Info("New `Snapshot` instance")
+	return SnapshotImpl{Etcd: NewEtcdClient().(EtcdClientImpl)}
+}
+
+func (s SnapshotImpl) Export(ctx context.Context, w io.Writer) (SnapshotResult, error) {
+	result := SnapshotResult{}
+	if s.Etcd.Connection == nil {
+		return result, errors.New("Not connected etcd")
+	}
+
+	// Revision of the first read is used for all reads
+	getCtx, cancel := s.Etcd.withTimeout(ctx)
+	response, err := s.Etcd.Connection.Get(getCtx, "snapshot", clientv3.WithCountOnly())
+	cancel()
+	if err != nil {
+		return result, err
+	}
+
+	result.Header = SnapshotHeader{
+		Format:        SnapshotFormat,
+		Version:       SnapshotFormatVersion,
+		SchemaVersion: SchemaVersion,
+		Namespace:     s.Etcd.Namespace,
+		Revision:      response.Header.Revision,
+		CreatedAt:     time.Now().UTC(),
+	}
+	writer, err := newSnapshotWriter(w, result.Header)
+	if err != nil {
+		return result, err
+	}
+	for _, prefix := range KeyPrefixes {
+		keys, err := s.exportPrefix(ctx, writer, prefix, result.Header.Revision)
+		result.Keys += keys
+		if err != nil {
+			return result, err
+		}
+	}
+	return result, writer.close()
+}
+
+func (s SnapshotImpl) Import(ctx context.Context, r io.Reader) (SnapshotResult, error) {
+	header, entries, err := ReadSnapshot(r)
+	result := SnapshotResult{Header: header}
+	if err != nil {
+		return result, err
+	}
+	if s.Etcd.Connection == nil {
+		return result, errors.New("Not connected etcd")
+	}
+
+	batchSize := s.Etcd.BatchSize
+	if batchSize <= 0 {
+		batchSize = defaultBatchSize
+	}
+	for start := 0; start < len(entries); start += batchSize {
+		end := start + batchSize
+		if end > len(entries) {
+			end = len(entries)
+		}
+		values := make([]rawKeyValue, 0, end-start)
+		for _, entry := range entries[start:end] {
+			value, err := s.importValue(entry.Value)
+			if err != nil {
+				return result, err
+			}
+			values = append(values, rawKeyValue{key: entry.Key, value: value})
+		}
+		if err := s.Etcd.putRaw(ctx, values); err != nil {
+			return result, err
+		}
+		result.Keys += len(values)
+	}
+	return result, nil
+}
+
+// Write keys of prefix at revision
+func (s SnapshotImpl) exportPrefix(ctx context.Context, writer *snapshotWriter, prefix string, revision int64) (int, error) {
+	keys := 0
+	err := s.Etcd.rangePrefix(ctx, prefix, revision, func(key string, value []byte) error {
+		if isCompactValue(value) {
+			converted, err := compactToJsonValue(value)
+			if err != nil {
+				log.Logger.Warn(fmt.Sprintf("Skip invalid compact cache value. key = %s. err = %s", key, err.Error()))
+				return nil
+			}
+			value = converted
+		}
+		if !json.Valid(value) {
+			log.Logger.Warn(fmt.Sprintf("Skip not json cache value. key = %s", key))
+			return nil
+		}
+		if err := writer.write(SnapshotEntry{Key: key, Value: value}); err != nil {
+			return err
+		}
+		keys++
+		return nil
+	})
+	return keys, err
+}
+
+// Value of snapshot to put
+// Snapshot has json values, so they are converted when values are written in compact encoding
+func (s SnapshotImpl) importValue(value json.RawMessage) ([]byte, error) {
+	if s.Etcd.Encoding != ValueEncodingCompact {
+		return value, nil
+	}
+	var data json.RawMessage
+	if err := decodeValue(value, &data); err != nil {
+		return nil, err
+	}
+	return encodeCompactValue(data)
+}
+
+// Read and validate snapshot file
+// It returns InvalidSnapshotError if the format is unknown, a key is not cache key, or a value can not be read by this binary
+func ReadSnapshot(r io.Reader) (SnapshotHeader, []SnapshotEntry, error) {
+	var header SnapshotHeader
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return header, nil, InvalidSnapshotError{Detail: fmt.Sprintf("Snapshot is not gzip. %s", err.Error())}
+	}
+	defer gz.Close()
+
+	decoder := json.NewDecoder(gz)
+	if err := decoder.Decode(&header); err != nil {
+		return header, nil, InvalidSnapshotError{Detail: fmt.Sprintf("Failed to read snapshot header. %s", err.Error())}
+	}
+	if header.Format != SnapshotFormat || header.Version != SnapshotFormatVersion {
+		return header, nil, InvalidSnapshotError{Detail: fmt.Sprintf("Not supported snapshot. format = %s, version = %d", header.Format, header.Version)}
+	}
+	if header.SchemaVersion > SchemaVersion {
+		return header, nil, InvalidSnapshotError{Detail: fmt.Sprintf("Not supported cache value version. schema_version = %d", header.SchemaVersion)}
+	}
+
+	var entries []SnapshotEntry
+	for {
+		var entry SnapshotEntry
+		if err := decoder.Decode(&entry); err == io.EOF {
+			break
+		} else if err != nil {
+			return header, nil, InvalidSnapshotError{Detail: fmt.Sprintf("Failed to read snapshot entry. %s", err.Error())}
+		}
+		if !hasKeyPrefix(entry.Key) {
+			return header, nil, InvalidSnapshotError{Detail: fmt.Sprintf("Not cache key in snapshot. key = %s", entry.Key)}
+		}
+		var data json.RawMessage
+		if err := decodeValue(entry.Value, &data); err != nil {
+			return header, nil, InvalidSnapshotError{Detail: fmt.Sprintf("Invalid cache value in snapshot. key = %s. %s", entry.Key, err.Error())}
+		}
+		entries = append(entries, entry)
+	}
+	return header, entries, nil
+}
+
+// Compare data of two snapshot files
+// Values are compared after migration to SchemaVersion, so a value of older version that has same data is not changed
+func DiffSnapshot(source io.Reader, target io.Reader) (SnapshotDiff, error) {
+	diff := SnapshotDiff{Added: []string{}, Removed: []string{}, Changed: []string{}}
+	sourceData, err := readSnapshotData(source)
+	if err != nil {
+		return diff, err
+	}
+	targetData, err := readSnapshotData(target)
+	if err != nil {
+		return diff, err
+	}
+
+	for key, sourceValue := range sourceData {
+		targetValue, ok := targetData[key]
+		if !ok {
+			diff.Removed = append(diff.Removed, key)
+		} else if !bytes.Equal(sourceValue, targetValue) {
+			diff.Changed = append(diff.Changed, key)
+		}
+	}
+	for key := range targetData {
+		if _, ok := sourceData[key]; !ok {
+			diff.Added = append(diff.Added, key)
+		}
+	}
+
+	sort.Strings(diff.Added)
+	sort.Strings(diff.Removed)
+	sort.Strings(diff.Changed)
+	return diff, nil
+}
+
+// Read compact data of each key
+func readSnapshotData(r io.Reader) (map[string][]byte, error) {
+	_, entries, err := ReadSnapshot(r)
+	if err != nil {
+		return nil, err
+	}
+
+	data := make(map[string][]byte, len(entries))
+	for _, entry := range entries {
+		var value json.RawMessage
+		decodeValue(entry.Value, &value)
+		var compact bytes.Buffer
+		if err := json.Compact(&compact, value); err != nil {
+			return nil, err
+		}
+		data[entry.Key] = compact.Bytes()
+	}
+	return data, nil
+}
+
+// Key has one of cache key prefixes
+func hasKeyPrefix(key string) bool {
+	for _, prefix := range KeyPrefixes {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// Writer of snapshot file
+type snapshotWriter struct {
+	gz      *gzip.Writer
+	encoder *json.Encoder
+}
+
+// Write header, and return writer of entries
+func newSnapshotWriter(w io.Writer, header SnapshotHeader) (*snapshotWriter, error) {
+	gz := gzip.NewWriter(w)
+	writer := &snapshotWriter{gz: gz, encoder: json.NewEncoder(gz)}
+	if err := writer.encoder.Encode(header); err != nil {
+		return nil, err
+	}
+	return writer, nil
+}
+
+func (sw *snapshotWriter) write(entry SnapshotEntry) error {
+	return sw.encoder.Encode(entry)
+}
+
+// Flush gzip. Snapshot file without close is truncated
+func (sw *snapshotWriter) close() error {
+	return sw.gz.Close()
+}
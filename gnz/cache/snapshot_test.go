@@ -0,0 +1,115 @@
+package cache
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"testing"
+)
+
+// Create snapshot file of entries
+func newTestSnapshot(header SnapshotHeader, entries ...SnapshotEntry) *bytes.Buffer {
+	var buf bytes.Buffer
+	writer, _ := newSnapshotWriter(&buf, header)
+	for _, entry := range entries {
+		writer.write(entry)
+	}
+	writer.close()
+	return &buf
+}
+
+// Header of this binary
+func testSnapshotHeader() SnapshotHeader {
+	return SnapshotHeader{Format: SnapshotFormat, Version: SnapshotFormatVersion, SchemaVersion: SchemaVersion}
+}
+
+// ReadSnapshot test
+func TestReadSnapshot(t *testing.T) {
+	snapshot := newTestSnapshot(testSnapshotHeader(),
+		SnapshotEntry{Key: RoleKeyPrefix + "a", Value: json.RawMessage(`{"version":1,"data":{"name":"a"}}`)},
+		SnapshotEntry{Key: RoleKeyPrefix + "b", Value: json.RawMessage(`{"name":"b"}`)},
+	)
+
+	header, entries, err := ReadSnapshot(snapshot)
+	if err != nil || header.Format != SnapshotFormat || len(entries) != 2 || entries[1].Key != RoleKeyPrefix+"b" {
+		t.Errorf("Incorrect TestReadSnapshot test. err = %v", err)
+		t.FailNow()
+	}
+}
+
+// ReadSnapshot invalid test
+func TestReadSnapshot_Invalid(t *testing.T) {
+	newerHeader := testSnapshotHeader()
+	newerHeader.SchemaVersion = SchemaVersion + 1
+	unknownHeader := testSnapshotHeader()
+	unknownHeader.Format = "unknown"
+
+	var truncated bytes.Buffer
+	gz := gzip.NewWriter(&truncated)
+	json.NewEncoder(gz).Encode(testSnapshotHeader())
+	gz.Flush()
+
+	snapshots := map[string]*bytes.Buffer{
+		"not gzip":           bytes.NewBufferString("snapshot"),
+		"unknown format":     newTestSnapshot(unknownHeader),
+		"newer schema":       newTestSnapshot(newerHeader),
+		"not cache key":      newTestSnapshot(testSnapshotHeader(), SnapshotEntry{Key: "unknown=a", Value: json.RawMessage(`{}`)}),
+		"newer value":        newTestSnapshot(testSnapshotHeader(), SnapshotEntry{Key: RoleKeyPrefix + "a", Value: json.RawMessage(`{"version":100,"data":{}}`)}),
+		"truncated snapshot": &truncated,
+	}
+	for name, snapshot := range snapshots {
+		if _, _, err := ReadSnapshot(snapshot); err == nil {
+			t.Errorf("Incorrect TestReadSnapshot_Invalid test. %s", name)
+		} else if _, ok := err.(InvalidSnapshotError); !ok {
+			t.Errorf("Incorrect TestReadSnapshot_Invalid test. %s", name)
+		}
+	}
+}
+
+// DiffSnapshot test
+func TestDiffSnapshot(t *testing.T) {
+	source := newTestSnapshot(testSnapshotHeader(),
+		SnapshotEntry{Key: RoleKeyPrefix + "a", Value: json.RawMessage(`{"name":"a"}`)},
+		SnapshotEntry{Key: RoleKeyPrefix + "b", Value: json.RawMessage(`{"version":1,"data":{"name":"b"}}`)},
+		SnapshotEntry{Key: RoleKeyPrefix + "c", Value: json.RawMessage(`{"version":1,"data":{"name":"c"}}`)},
+	)
+	target := newTestSnapshot(testSnapshotHeader(),
+		SnapshotEntry{Key: RoleKeyPrefix + "a", Value: json.RawMessage(`{"version":1,"data":{"name": "a"}}`)},
+		SnapshotEntry{Key: RoleKeyPrefix + "b", Value: json.RawMessage(`{"version":1,"data":{"name":"x"}}`)},
+		SnapshotEntry{Key: RoleKeyPrefix + "d", Value: json.RawMessage(`{"version":1,"data":{"name":"d"}}`)},
+	)
+
+	diff, err := DiffSnapshot(source, target)
+	if err != nil || len(diff.Added) != 1 || diff.Added[0] != RoleKeyPrefix+"d" ||
+		len(diff.Removed) != 1 || diff.Removed[0] != RoleKeyPrefix+"c" ||
+		len(diff.Changed) != 1 || diff.Changed[0] != RoleKeyPrefix+"b" {
+		t.Errorf("Incorrect TestDiffSnapshot test. diff = %v, err = %v", diff, err)
+		t.FailNow()
+	}
+}
+
+// Export not connected test
+func TestExport_NotConnected(t *testing.T) {
+	var buf bytes.Buffer
+	if _, err := (SnapshotImpl{}).Export(context.Background(), &buf); err == nil {
+		t.Errorf("Incorrect TestExport_NotConnected test")
+		t.FailNow()
+	}
+}
+
+// Import test
+func TestImport(t *testing.T) {
+	snapshot := newTestSnapshot(testSnapshotHeader(), SnapshotEntry{Key: RoleKeyPrefix + "a", Value: json.RawMessage(`{"name":"a"}`)})
+	if result, err := (SnapshotImpl{}).Import(context.Background(), snapshot); err == nil || result.Keys != 0 {
+		t.Errorf("Incorrect TestImport test. not connected")
+		t.FailNow()
+	}
+
+	setUpStubConnected()
+	snapshot = newTestSnapshot(testSnapshotHeader(), SnapshotEntry{Key: RoleKeyPrefix + "a", Value: json.RawMessage(`{"name":"a"}`)})
+	if result, err := (SnapshotImpl{Etcd: etcdClient.(EtcdClientImpl)}).Import(context.Background(), snapshot); err == nil || result.Keys != 0 {
+		t.Errorf("Incorrect TestImport test. put failed")
+		t.FailNow()
+	}
+}
@@ -0,0 +1,112 @@
+package cache
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/tomoyane/grant-n-z/gnz/common"
+)
+
+// Schema version of cache value that this binary writes
+// Increment it when a struct in `gnz/cache/structure` changes incompatibly, and add migration of the previous version
+const SchemaVersion = 1
+
+// Migrations of cache value data from version (key) to version + 1
+// Version 0 is the value that was written before cache values had version
+var valueMigrations = map[int]func(data json.RawMessage) (json.RawMessage, error){
+	0: func(data json.RawMessage) (json.RawMessage, error) { return data, nil },
+}
+
+// Encoding of cache values in etcd.value-encoding
+// Values of both encodings are always readable, and the encoding is only for writes
+const (
+	ValueEncodingJson    = "json"
+	ValueEncodingCompact = "compact"
+)
+
+// Versioned cache value in etcd
+// ex: {"version":1,"data":[{"service_uuid":"{uuid}","group_uuid":"{uuid}","role_name":"{name}","permission_name":"{name}"}]}
+type versionedValue struct {
+	Version int             `json:"version"`
+	Data    json.RawMessage `json:"data"`
+}
+
+// Cache key namespace of config
+// Two GrantNZ environments that share etcd cluster need different namespace
+func keyNamespace() string {
+	namespace := common.Etcd.Namespace
+	if namespace == "" || strings.HasSuffix(namespace, "/") {
+		return namespace
+	}
+	return namespace + "/"
+}
+
+// Convert struct to versioned cache json
+func encodeValue(structData interface{}) ([]byte, error) {
+	data, err := json.Marshal(structData)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(versionedValue{Version: SchemaVersion, Data: data})
+}
+
+// Convert struct to cache value of encoding
+// Unknown encoding is json
+func encodeValueWith(encoding string, structData interface{}) ([]byte, error) {
+	if encoding == ValueEncodingCompact {
+		return encodeCompactValue(structData)
+	}
+	return encodeValue(structData)
+}
+
+// Convert versioned cache json or compact cache value to struct
+// Value of older version is migrated to SchemaVersion. Value of newer version is error,
+// because this binary can not know its schema, so the caller reads it as cache miss
+func decodeValue(value []byte, structData interface{}) error {
+	if isCompactValue(value) {
+		return decodeCompactValue(value, structData)
+	}
+
+	var versioned versionedValue
+	if err := json.Unmarshal(value, &versioned); err != nil || versioned.Data == nil {
+		// Value that was written without version
+		versioned = versionedValue{Version: 0, Data: value}
+	}
+
+	if versioned.Version > SchemaVersion {
+		return errors.New(fmt.Sprintf("Not supported cache value version. version = %d", versioned.Version))
+	}
+
+	data, err := migrateData(versioned.Version, versioned.Data)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, structData)
+}
+
+// Migrate json data of version to SchemaVersion
+func migrateData(version int, data json.RawMessage) (json.RawMessage, error) {
+	for ; version < SchemaVersion; version++ {
+		migrate, ok := valueMigrations[version]
+		if !ok {
+			return nil, errors.New(fmt.Sprintf("Not found cache value migration. version = %d", version))
+		}
+		migrated, err := migrate(data)
+		if err != nil {
+			return nil, err
+		}
+		data = migrated
+	}
+	return data, nil
+}
+
+// Json data of cache value that is migrated to SchemaVersion
+func DecodeData(value []byte) (json.RawMessage, error) {
+	var data json.RawMessage
+	if err := decodeValue(value, &data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
@@ -0,0 +1,69 @@
+package cache
+
+import (
+	"testing"
+
+	"github.com/tomoyane/grant-n-z/gnz/cache/structure"
+	"github.com/tomoyane/grant-n-z/gnz/common"
+)
+
+// Test encode and decode value
+func TestEncodeValue(t *testing.T) {
+	value, err := encodeValue([]structure.UserPolicy{{ServiceUuid: "service", RoleName: "admin"}})
+	if err != nil || string(value) != `{"version":1,"data":[{"service_uuid":"service","group_uuid":"","role_name":"admin","permission_name":""}]}` {
+		t.Errorf("Incorrect TestEncodeValue test. value = %s", string(value))
+		t.FailNow()
+	}
+
+	var policies []structure.UserPolicy
+	if err := decodeValue(value, &policies); err != nil || len(policies) != 1 || policies[0].RoleName != "admin" {
+		t.Errorf("Incorrect TestEncodeValue test. policies = %v", policies)
+		t.FailNow()
+	}
+}
+
+// Test decode value that was written without version
+func TestDecodeValue_Unversioned(t *testing.T) {
+	var policies []structure.UserPolicy
+	if err := decodeValue([]byte(`[{"role_name":"admin"}]`), &policies); err != nil || len(policies) != 1 || policies[0].RoleName != "admin" {
+		t.Errorf("Incorrect TestDecodeValue_Unversioned test. policies = %v", policies)
+		t.FailNow()
+	}
+
+	var role structure.Role
+	if err := decodeValue([]byte(`{"name":"admin","uuid":"uuid"}`), &role); err != nil || role.Name != "admin" {
+		t.Errorf("Incorrect TestDecodeValue_Unversioned test. role = %v", role)
+		t.FailNow()
+	}
+}
+
+// Test decode value of newer version
+func TestDecodeValue_NewerVersion(t *testing.T) {
+	var role structure.Role
+	if err := decodeValue([]byte(`{"version":2,"data":{"name":"admin"}}`), &role); err == nil {
+		t.Errorf("Incorrect TestDecodeValue_NewerVersion test")
+		t.FailNow()
+	}
+}
+
+// Test key namespace
+func TestKeyNamespace(t *testing.T) {
+	defer func() { common.Etcd.Namespace = "" }()
+
+	if namespace := keyNamespace(); namespace != "" {
+		t.Errorf("Incorrect TestKeyNamespace test. namespace = %s", namespace)
+		t.FailNow()
+	}
+
+	common.Etcd.Namespace = "staging"
+	if namespace := keyNamespace(); namespace != "staging/" {
+		t.Errorf("Incorrect TestKeyNamespace test. namespace = %s", namespace)
+		t.FailNow()
+	}
+
+	common.Etcd.Namespace = "staging/"
+	if namespace := keyNamespace(); namespace != "staging/" {
+		t.Errorf("Incorrect TestKeyNamespace test. namespace = %s", namespace)
+		t.FailNow()
+	}
+}
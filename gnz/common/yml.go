@@ -28,8 +28,20 @@ type AppConfig struct {
 
 // About app data in grant_n_z_cacher.yaml
 type CacherConfig struct {
-	TimeMillisStr string `yaml:"time-millis"`
-	TimeMillis    int
+	TimeMillisStr     string `yaml:"time-millis"`
+	JitterMillisStr   string `yaml:"jitter-millis"`
+	TimeoutMillisStr  string `yaml:"timeout-millis"`
+	PruneMode         string `yaml:"prune-mode"`
+	Port              string `yaml:"port"`
+	ResyncToken       string `yaml:"resync-token"`
+	Mode              string `yaml:"mode"`
+	BinlogServerIdStr string `yaml:"binlog-server-id"`
+	MaxBodyBytesStr   string `yaml:"max-body-bytes"`
+	TimeMillis        int
+	JitterMillis      int
+	TimeoutMillis     int
+	BinlogServerId    int
+	MaxBodyBytes      int64
 }
 
 // About server data in grant_n_z_server.yaml
@@ -39,10 +51,12 @@ type ServerConfig struct {
 	ValidatePublicKeyPath  string `yaml:"validate-token-public-key-path"`
 	TokenExpireHourStr     string `yaml:"token-expire-hour"`
 	SignAlgorithm          string `yaml:"sign-algorithm"`
+	LocalCacheSizeStr      string `yaml:"local-cache-size"`
 	SignedInPrivateKey     *rsa.PrivateKey
 	ValidatePublicKey      *rsa.PublicKey
 	SigningMethod          jwt.SigningMethod
 	TokenExpireHour        int
+	LocalCacheSize         int
 }
 
 // About db data in grant_n_z_{component}.yaml
@@ -55,12 +69,42 @@ type DbConfig struct {
 	Name              string `yaml:"name"`
 	MaxOpenConnection string `yaml:"max-open-connection"`
 	MaxIdleConnection string `yaml:"max-idle-connection"`
+	QueryTimeoutStr   string `yaml:"query-timeout-millis"`
+	QueryTimeout      int
+	SslMode           string `yaml:"ssl-mode"`
+	SslRootCert       string `yaml:"ssl-root-cert"`
+	SslCert           string `yaml:"ssl-cert"`
+	SslKey            string `yaml:"ssl-key"`
+
+	// Retry of connection at start with exponential backoff, and max lifetime of each connection
+	ConnectRetryMaxStr string `yaml:"connect-retry-max"`
+	ConnectRetryMax    int
+	ConnMaxLifetimeStr string `yaml:"conn-max-lifetime-millis"`
+	ConnMaxLifetime    int
+
+	// Comma separated hosts of read replicas. host or host:port, that uses port of primary if it is omitted
+	ReplicaHostsStr         string `yaml:"replica-hosts"`
+	ReplicaHosts            []string
+	ReplicaCheckIntervalStr string `yaml:"replica-check-interval-millis"`
+	ReplicaCheckInterval    int
+
+	// Days that soft deleted rows are kept before purge. If 0 or less, they are not purged
+	SoftDeleteRetentionDaysStr string `yaml:"soft-delete-retention-days"`
+	SoftDeleteRetentionDays    int
 }
 
 // About etcd data in grant_n_z_{component}.yaml
 type EtcdConfig struct {
-	Host string `yaml:"host"`
-	Port string `yaml:"port"`
+	Host         string `yaml:"host"`
+	Port         string `yaml:"port"`
+	Namespace    string `yaml:"namespace"`
+	TimeoutStr   string `yaml:"timeout-millis"`
+	Timeout      int
+	BatchSizeStr string `yaml:"batch-size"`
+	BatchSize    int
+
+	// Encoding of cache values that are written. `json` or `compact`
+	ValueEncoding string `yaml:"value-encoding"`
 }
 
 // Getter AppConfig
@@ -78,13 +122,71 @@ func (yml YmlConfig) GetAppConfig() AppConfig {
 // Getter CacherConfig
 func (yml YmlConfig) GetCacherConfig() CacherConfig {
 	timMillisStr := yml.Cacher.TimeMillisStr
+	jitterMillisStr := yml.Cacher.JitterMillisStr
+	timeoutMillisStr := yml.Cacher.TimeoutMillisStr
+	pruneMode := yml.Cacher.PruneMode
+	port := yml.Cacher.Port
+	resyncToken := yml.Cacher.ResyncToken
+	mode := yml.Cacher.Mode
+	binlogServerIdStr := yml.Cacher.BinlogServerIdStr
+	maxBodyBytesStr := yml.Cacher.MaxBodyBytesStr
 
 	if strings.Contains(timMillisStr, "$") {
 		timMillisStr = os.Getenv(yml.Cacher.TimeMillisStr[1:])
 	}
 
+	if strings.Contains(jitterMillisStr, "$") {
+		jitterMillisStr = os.Getenv(yml.Cacher.JitterMillisStr[1:])
+	}
+
+	if strings.Contains(timeoutMillisStr, "$") {
+		timeoutMillisStr = os.Getenv(yml.Cacher.TimeoutMillisStr[1:])
+	}
+
+	if strings.Contains(pruneMode, "$") {
+		pruneMode = os.Getenv(yml.Cacher.PruneMode[1:])
+	}
+
+	if strings.Contains(port, "$") {
+		port = os.Getenv(yml.Cacher.Port[1:])
+	}
+
+	if strings.Contains(resyncToken, "$") {
+		resyncToken = os.Getenv(yml.Cacher.ResyncToken[1:])
+	}
+
+	if strings.Contains(mode, "$") {
+		mode = os.Getenv(yml.Cacher.Mode[1:])
+	}
+	if mode == "" {
+		mode = "poll"
+	}
+
+	if strings.Contains(binlogServerIdStr, "$") {
+		binlogServerIdStr = os.Getenv(yml.Cacher.BinlogServerIdStr[1:])
+	}
+
+	if strings.Contains(maxBodyBytesStr, "$") {
+		maxBodyBytesStr = os.Getenv(yml.Cacher.MaxBodyBytesStr[1:])
+	}
+	if maxBodyBytesStr == "" {
+		maxBodyBytesStr = "67108864"
+	}
+
 	yml.Cacher.TimeMillisStr = timMillisStr
+	yml.Cacher.JitterMillisStr = jitterMillisStr
+	yml.Cacher.TimeoutMillisStr = timeoutMillisStr
+	yml.Cacher.PruneMode = pruneMode
+	yml.Cacher.Port = port
+	yml.Cacher.ResyncToken = resyncToken
+	yml.Cacher.Mode = mode
+	yml.Cacher.BinlogServerIdStr = binlogServerIdStr
+	yml.Cacher.MaxBodyBytesStr = maxBodyBytesStr
 	yml.Cacher.TimeMillis, _ = strconv.Atoi(timMillisStr)
+	yml.Cacher.JitterMillis, _ = strconv.Atoi(jitterMillisStr)
+	yml.Cacher.TimeoutMillis, _ = strconv.Atoi(timeoutMillisStr)
+	yml.Cacher.BinlogServerId, _ = strconv.Atoi(binlogServerIdStr)
+	yml.Cacher.MaxBodyBytes, _ = strconv.ParseInt(maxBodyBytesStr, 10, 64)
 	return yml.Cacher
 }
 
@@ -95,6 +197,7 @@ func (yml YmlConfig) GetServerConfig() ServerConfig {
 	publicKeyStr := yml.Server.SignedInPrivateKeyPath
 	tokenExpireHourStr := yml.Server.TokenExpireHourStr
 	signAlgorithm := yml.Server.SignAlgorithm
+	localCacheSizeStr := yml.Server.LocalCacheSizeStr
 
 	if strings.Contains(port, "$") {
 		port = os.Getenv(yml.Server.Port[1:])
@@ -119,13 +222,22 @@ func (yml YmlConfig) GetServerConfig() ServerConfig {
 		signAlgorithm = os.Getenv(yml.Server.SignAlgorithm[1:])
 	}
 
+	if strings.Contains(localCacheSizeStr, "$") {
+		localCacheSizeStr = os.Getenv(yml.Server.LocalCacheSizeStr[1:])
+	}
+	if localCacheSizeStr == "" {
+		localCacheSizeStr = "10000"
+	}
+
 	yml.Server.Port = port
 	yml.Server.SignedInPrivateKeyPath = privateKeyStr
 	yml.Server.ValidatePublicKeyPath = publicKeyStr
 	yml.Server.TokenExpireHourStr = tokenExpireHourStr
+	yml.Server.LocalCacheSizeStr = localCacheSizeStr
 
 	// Generate server config data
 	yml.Server.TokenExpireHour, _ = strconv.Atoi(tokenExpireHourStr)
+	yml.Server.LocalCacheSize, _ = strconv.Atoi(localCacheSizeStr)
 
 	yml.Server.SignAlgorithm = signAlgorithm
 	switch yml.Server.SignAlgorithm {
@@ -172,6 +284,10 @@ func (yml YmlConfig) GetEtcdConfig() EtcdConfig {
 	}
 	host := yml.Etcd.Host
 	port := yml.Etcd.Port
+	namespace := yml.Etcd.Namespace
+	timeoutStr := yml.Etcd.TimeoutStr
+	batchSizeStr := yml.Etcd.BatchSizeStr
+	valueEncoding := yml.Etcd.ValueEncoding
 
 	if strings.Contains(host, "$") {
 		host = os.Getenv(yml.Etcd.Host[1:])
@@ -181,8 +297,41 @@ func (yml YmlConfig) GetEtcdConfig() EtcdConfig {
 		port = os.Getenv(yml.Etcd.Port[1:])
 	}
 
+	if strings.Contains(namespace, "$") {
+		namespace = os.Getenv(yml.Etcd.Namespace[1:])
+	}
+
+	if strings.Contains(timeoutStr, "$") {
+		timeoutStr = os.Getenv(yml.Etcd.TimeoutStr[1:])
+	}
+	if timeoutStr == "" {
+		timeoutStr = "1000"
+	}
+
+	// Max operations of one etcd transaction is 128 by default
+	if strings.Contains(batchSizeStr, "$") {
+		batchSizeStr = os.Getenv(yml.Etcd.BatchSizeStr[1:])
+	}
+	if batchSizeStr == "" {
+		batchSizeStr = "100"
+	}
+
+	// Values of both encodings are readable, so readers need to be updated before writers use `compact`
+	if strings.Contains(valueEncoding, "$") {
+		valueEncoding = os.Getenv(yml.Etcd.ValueEncoding[1:])
+	}
+	if valueEncoding == "" {
+		valueEncoding = "json"
+	}
+
 	yml.Etcd.Host = host
 	yml.Etcd.Port = port
+	yml.Etcd.Namespace = namespace
+	yml.Etcd.TimeoutStr = timeoutStr
+	yml.Etcd.Timeout, _ = strconv.Atoi(timeoutStr)
+	yml.Etcd.BatchSizeStr = batchSizeStr
+	yml.Etcd.BatchSize, _ = strconv.Atoi(batchSizeStr)
+	yml.Etcd.ValueEncoding = valueEncoding
 	return yml.Etcd
 }
 
@@ -196,6 +345,16 @@ func (yml YmlConfig) GetDbConfig() DbConfig {
 	name := yml.Db.Name
 	maxOpenConnection := yml.Db.MaxOpenConnection
 	maxIdleConnection := yml.Db.MaxIdleConnection
+	queryTimeoutStr := yml.Db.QueryTimeoutStr
+	connectRetryMaxStr := yml.Db.ConnectRetryMaxStr
+	connMaxLifetimeStr := yml.Db.ConnMaxLifetimeStr
+	sslMode := yml.Db.SslMode
+	sslRootCert := yml.Db.SslRootCert
+	sslCert := yml.Db.SslCert
+	sslKey := yml.Db.SslKey
+	replicaHostsStr := yml.Db.ReplicaHostsStr
+	replicaCheckIntervalStr := yml.Db.ReplicaCheckIntervalStr
+	softDeleteRetentionDaysStr := yml.Db.SoftDeleteRetentionDaysStr
 
 	if strings.Contains(engine, "$") {
 		engine = os.Getenv(yml.Db.Engine[1:])
@@ -235,6 +394,70 @@ func (yml YmlConfig) GetDbConfig() DbConfig {
 		}
 	}
 
+	if strings.Contains(queryTimeoutStr, "$") {
+		queryTimeoutStr = os.Getenv(yml.Db.QueryTimeoutStr[1:])
+	}
+	if queryTimeoutStr == "" {
+		queryTimeoutStr = "5000"
+	}
+
+	if strings.Contains(connectRetryMaxStr, "$") {
+		connectRetryMaxStr = os.Getenv(yml.Db.ConnectRetryMaxStr[1:])
+	}
+	if connectRetryMaxStr == "" {
+		connectRetryMaxStr = "10"
+	}
+
+	if strings.Contains(connMaxLifetimeStr, "$") {
+		connMaxLifetimeStr = os.Getenv(yml.Db.ConnMaxLifetimeStr[1:])
+	}
+	if connMaxLifetimeStr == "" {
+		connMaxLifetimeStr = "300000"
+	}
+
+	if strings.Contains(sslMode, "$") {
+		sslMode = os.Getenv(yml.Db.SslMode[1:])
+	}
+	if sslMode == "" {
+		sslMode = "disable"
+	}
+
+	if strings.Contains(sslRootCert, "$") {
+		sslRootCert = os.Getenv(yml.Db.SslRootCert[1:])
+	}
+
+	if strings.Contains(sslCert, "$") {
+		sslCert = os.Getenv(yml.Db.SslCert[1:])
+	}
+
+	if strings.Contains(sslKey, "$") {
+		sslKey = os.Getenv(yml.Db.SslKey[1:])
+	}
+
+	if strings.Contains(replicaHostsStr, "$") {
+		replicaHostsStr = os.Getenv(yml.Db.ReplicaHostsStr[1:])
+	}
+	var replicaHosts []string
+	for _, host := range strings.Split(replicaHostsStr, ",") {
+		if strings.TrimSpace(host) != "" {
+			replicaHosts = append(replicaHosts, strings.TrimSpace(host))
+		}
+	}
+
+	if strings.Contains(replicaCheckIntervalStr, "$") {
+		replicaCheckIntervalStr = os.Getenv(yml.Db.ReplicaCheckIntervalStr[1:])
+	}
+	if replicaCheckIntervalStr == "" {
+		replicaCheckIntervalStr = "5000"
+	}
+
+	if strings.Contains(softDeleteRetentionDaysStr, "$") {
+		softDeleteRetentionDaysStr = os.Getenv(yml.Db.SoftDeleteRetentionDaysStr[1:])
+	}
+	if softDeleteRetentionDaysStr == "" {
+		softDeleteRetentionDaysStr = "30"
+	}
+
 	yml.Db.Engine = engine
 	yml.Db.User = user
 	yml.Db.Password = password
@@ -243,5 +466,21 @@ func (yml YmlConfig) GetDbConfig() DbConfig {
 	yml.Db.Name = name
 	yml.Db.MaxOpenConnection = maxOpenConnection
 	yml.Db.MaxIdleConnection = maxIdleConnection
+	yml.Db.QueryTimeoutStr = queryTimeoutStr
+	yml.Db.QueryTimeout, _ = strconv.Atoi(queryTimeoutStr)
+	yml.Db.ConnectRetryMaxStr = connectRetryMaxStr
+	yml.Db.ConnectRetryMax, _ = strconv.Atoi(connectRetryMaxStr)
+	yml.Db.ConnMaxLifetimeStr = connMaxLifetimeStr
+	yml.Db.ConnMaxLifetime, _ = strconv.Atoi(connMaxLifetimeStr)
+	yml.Db.SslMode = sslMode
+	yml.Db.SslRootCert = sslRootCert
+	yml.Db.SslCert = sslCert
+	yml.Db.SslKey = sslKey
+	yml.Db.ReplicaHostsStr = replicaHostsStr
+	yml.Db.ReplicaHosts = replicaHosts
+	yml.Db.ReplicaCheckIntervalStr = replicaCheckIntervalStr
+	yml.Db.ReplicaCheckInterval, _ = strconv.Atoi(replicaCheckIntervalStr)
+	yml.Db.SoftDeleteRetentionDaysStr = softDeleteRetentionDaysStr
+	yml.Db.SoftDeleteRetentionDays, _ = strconv.Atoi(softDeleteRetentionDaysStr)
 	return yml.Db
 }
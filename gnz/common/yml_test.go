@@ -22,16 +22,86 @@ func TestGetAppConfig(t *testing.T) {
 
 // GetCacherConfig test
 func TestGetCacherConfig(t *testing.T) {
-	cacherConfig := CacherConfig{TimeMillisStr: "$CACHER_TIME_MILLIS"}
+	cacherConfig := CacherConfig{
+		TimeMillisStr:     "$CACHER_TIME_MILLIS",
+		JitterMillisStr:   "$CACHER_JITTER_MILLIS",
+		TimeoutMillisStr:  "$CACHER_TIMEOUT_MILLIS",
+		PruneMode:         "$CACHER_PRUNE_MODE",
+		Port:              "$CACHER_PORT",
+		ResyncToken:       "$CACHER_RESYNC_TOKEN",
+		Mode:              "$CACHER_MODE",
+		BinlogServerIdStr: "$CACHER_BINLOG_SERVER_ID",
+		MaxBodyBytesStr:   "$CACHER_MAX_BODY_BYTES",
+	}
 	ymlConfig := YmlConfig{Cacher: cacherConfig}
 
 	// Test data
 	os.Setenv("CACHER_TIME_MILLIS", "100")
+	os.Setenv("CACHER_JITTER_MILLIS", "10")
+	os.Setenv("CACHER_TIMEOUT_MILLIS", "50")
+	os.Setenv("CACHER_PRUNE_MODE", "dry-run")
+	os.Setenv("CACHER_PORT", "8081")
+	os.Setenv("CACHER_RESYNC_TOKEN", "token")
+	os.Setenv("CACHER_MODE", "binlog")
+	os.Setenv("CACHER_BINLOG_SERVER_ID", "1001")
+	os.Setenv("CACHER_MAX_BODY_BYTES", "1024")
 
 	if !strings.EqualFold(ymlConfig.GetCacherConfig().TimeMillisStr, "100") {
 		t.Errorf("Incorrect CacherConfig test. time-millis = %s", ymlConfig.GetCacherConfig().TimeMillisStr)
 		t.FailNow()
 	}
+
+	if ymlConfig.GetCacherConfig().JitterMillis != 10 {
+		t.Errorf("Incorrect CacherConfig test. jitter-millis = %d", ymlConfig.GetCacherConfig().JitterMillis)
+		t.FailNow()
+	}
+
+	if ymlConfig.GetCacherConfig().TimeoutMillis != 50 {
+		t.Errorf("Incorrect CacherConfig test. timeout-millis = %d", ymlConfig.GetCacherConfig().TimeoutMillis)
+		t.FailNow()
+	}
+
+	if !strings.EqualFold(ymlConfig.GetCacherConfig().PruneMode, "dry-run") {
+		t.Errorf("Incorrect CacherConfig test. prune-mode = %s", ymlConfig.GetCacherConfig().PruneMode)
+		t.FailNow()
+	}
+
+	if !strings.EqualFold(ymlConfig.GetCacherConfig().Port, "8081") {
+		t.Errorf("Incorrect CacherConfig test. port = %s", ymlConfig.GetCacherConfig().Port)
+		t.FailNow()
+	}
+
+	if ymlConfig.GetCacherConfig().ResyncToken != "token" {
+		t.Errorf("Incorrect CacherConfig test. resync-token = %s", ymlConfig.GetCacherConfig().ResyncToken)
+		t.FailNow()
+	}
+
+	if ymlConfig.GetCacherConfig().Mode != "binlog" {
+		t.Errorf("Incorrect CacherConfig test. mode = %s", ymlConfig.GetCacherConfig().Mode)
+		t.FailNow()
+	}
+
+	if ymlConfig.GetCacherConfig().BinlogServerId != 1001 {
+		t.Errorf("Incorrect CacherConfig test. binlog-server-id = %d", ymlConfig.GetCacherConfig().BinlogServerId)
+		t.FailNow()
+	}
+
+	if ymlConfig.GetCacherConfig().MaxBodyBytes != 1024 {
+		t.Errorf("Incorrect CacherConfig test. max-body-bytes = %d", ymlConfig.GetCacherConfig().MaxBodyBytes)
+		t.FailNow()
+	}
+
+	os.Setenv("CACHER_MAX_BODY_BYTES", "")
+	if ymlConfig.GetCacherConfig().MaxBodyBytes != 67108864 {
+		t.Errorf("Incorrect CacherConfig test. max-body-bytes = %d", ymlConfig.GetCacherConfig().MaxBodyBytes)
+		t.FailNow()
+	}
+
+	os.Setenv("CACHER_MODE", "")
+	if ymlConfig.GetCacherConfig().Mode != "poll" {
+		t.Errorf("Incorrect CacherConfig test. mode = %s", ymlConfig.GetCacherConfig().Mode)
+		t.FailNow()
+	}
 }
 
 // GetServerConfig test
@@ -42,6 +112,7 @@ func TestGetServerConfig(t *testing.T) {
 		ValidatePublicKeyPath:  "$SERVER_PUBLIC_KEY_PATH",
 		TokenExpireHourStr:     "$SERVER_TOKEN_EXPIRE_HOUR",
 		SignAlgorithm:          "$SERVER_SIGN_ALGORITHM",
+		LocalCacheSizeStr:      "$SERVER_LOCAL_CACHE_SIZE",
 	}
 	ymlConfig := YmlConfig{Server: serverConfig}
 
@@ -51,6 +122,7 @@ func TestGetServerConfig(t *testing.T) {
 	os.Setenv("SERVER_PUBLIC_KEY_PATH", "./test-public.key")
 	os.Setenv("SERVER_TOKEN_EXPIRE_HOUR", "100")
 	os.Setenv("SERVER_SIGN_ALGORITHM", "rsa256")
+	os.Setenv("SERVER_LOCAL_CACHE_SIZE", "500")
 
 	if !strings.EqualFold(ymlConfig.GetServerConfig().Port, "8080") {
 		t.Errorf("Incorrect GetServerConfig test. port = %s", ymlConfig.GetServerConfig().Port)
@@ -76,16 +148,30 @@ func TestGetServerConfig(t *testing.T) {
 		t.Errorf("Incorrect GetServerConfig test. rsa-algorithm = %s", ymlConfig.GetServerConfig().SignAlgorithm)
 		t.FailNow()
 	}
+
+	if ymlConfig.GetServerConfig().LocalCacheSize != 500 {
+		t.Errorf("Incorrect GetServerConfig test. local-cache-size = %d", ymlConfig.GetServerConfig().LocalCacheSize)
+		t.FailNow()
+	}
+
+	os.Setenv("SERVER_LOCAL_CACHE_SIZE", "")
+	if ymlConfig.GetServerConfig().LocalCacheSize != 10000 {
+		t.Errorf("Incorrect GetServerConfig test. default local-cache-size = %d", ymlConfig.GetServerConfig().LocalCacheSize)
+		t.FailNow()
+	}
 }
 
 // GetEtcdConfig test
 func TestGetEtcdConfig(t *testing.T) {
-	etcdConfig := EtcdConfig{Host: "$ETCD_HOST", Port: "$ETCD_PORT"}
+	etcdConfig := EtcdConfig{Host: "$ETCD_HOST", Port: "$ETCD_PORT", Namespace: "$ETCD_NAMESPACE", TimeoutStr: "$ETCD_TIMEOUT_MILLIS", BatchSizeStr: "$ETCD_BATCH_SIZE", ValueEncoding: "$ETCD_VALUE_ENCODING"}
 	ymlConfig := YmlConfig{Etcd: etcdConfig}
 
 	// Test data
 	os.Setenv("ETCD_HOST", "localhost")
 	os.Setenv("ETCD_PORT", "2380")
+	os.Setenv("ETCD_NAMESPACE", "staging")
+	os.Setenv("ETCD_BATCH_SIZE", "50")
+	os.Setenv("ETCD_VALUE_ENCODING", "compact")
 
 	if !strings.EqualFold(ymlConfig.GetEtcdConfig().Host, "localhost") {
 		t.Errorf("Incorrect GetEtcdConfig test. host = %s", ymlConfig.GetEtcdConfig().Host)
@@ -96,6 +182,26 @@ func TestGetEtcdConfig(t *testing.T) {
 		t.Errorf("Incorrect GetEtcdConfig test. port = %s", ymlConfig.GetEtcdConfig().Port)
 		t.FailNow()
 	}
+
+	if !strings.EqualFold(ymlConfig.GetEtcdConfig().Namespace, "staging") {
+		t.Errorf("Incorrect GetEtcdConfig test. namespace = %s", ymlConfig.GetEtcdConfig().Namespace)
+		t.FailNow()
+	}
+
+	if ymlConfig.GetEtcdConfig().Timeout != 1000 {
+		t.Errorf("Incorrect GetEtcdConfig test. default timeout-millis = %d", ymlConfig.GetEtcdConfig().Timeout)
+		t.FailNow()
+	}
+
+	if ymlConfig.GetEtcdConfig().BatchSize != 50 {
+		t.Errorf("Incorrect GetEtcdConfig test. batch-size = %d", ymlConfig.GetEtcdConfig().BatchSize)
+		t.FailNow()
+	}
+
+	if ymlConfig.GetEtcdConfig().ValueEncoding != "compact" {
+		t.Errorf("Incorrect GetEtcdConfig test. value-encoding = %s", ymlConfig.GetEtcdConfig().ValueEncoding)
+		t.FailNow()
+	}
 }
 
 // GetDbConfig test
@@ -109,6 +215,10 @@ func TestGetDbConfig(t *testing.T) {
 		Name:              "$DB_NAME",
 		MaxOpenConnection: "$DB_MAX_OPEN_CONNECTION",
 		MaxIdleConnection: "$DB_MAX_IDLE_CONNECTION",
+		QueryTimeoutStr:   "$DB_QUERY_TIMEOUT_MILLIS",
+		SslMode:           "$DB_SSL_MODE",
+		SslRootCert:       "$DB_SSL_ROOT_CERT",
+		ReplicaHostsStr:   "$DB_REPLICA_HOSTS",
 	}
 	ymlConfig := YmlConfig{Db: dbConfig}
 
@@ -121,6 +231,10 @@ func TestGetDbConfig(t *testing.T) {
 	os.Setenv("DB_NAME", "grant_n_z")
 	os.Setenv("DB_MAX_OPEN_CONNECTION", "15")
 	os.Setenv("DB_MAX_IDLE_CONNECTION", "15")
+	os.Setenv("DB_QUERY_TIMEOUT_MILLIS", "3000")
+	os.Setenv("DB_SSL_MODE", "verify-full")
+	os.Setenv("DB_SSL_ROOT_CERT", "/etc/ssl/root.crt")
+	os.Setenv("DB_REPLICA_HOSTS", "replica1, replica2:3307,")
 
 	if !strings.EqualFold(ymlConfig.GetDbConfig().Engine, "mysql") {
 		t.Errorf("Incorrect TestGetDbConfig test. engine = %s", ymlConfig.GetDbConfig().Engine)
@@ -161,4 +275,42 @@ func TestGetDbConfig(t *testing.T) {
 		t.Errorf("Incorrect TestGetDbConfig test. max-idle-connection = %s", ymlConfig.GetDbConfig().MaxIdleConnection)
 		t.FailNow()
 	}
+
+	if ymlConfig.GetDbConfig().QueryTimeout != 3000 {
+		t.Errorf("Incorrect TestGetDbConfig test. query-timeout-millis = %d", ymlConfig.GetDbConfig().QueryTimeout)
+		t.FailNow()
+	}
+
+	if ymlConfig.GetDbConfig().SslMode != "verify-full" || ymlConfig.GetDbConfig().SslRootCert != "/etc/ssl/root.crt" {
+		t.Errorf("Incorrect TestGetDbConfig test. ssl-mode = %s", ymlConfig.GetDbConfig().SslMode)
+		t.FailNow()
+	}
+
+	replicaHosts := ymlConfig.GetDbConfig().ReplicaHosts
+	if len(replicaHosts) != 2 || replicaHosts[0] != "replica1" || replicaHosts[1] != "replica2:3307" {
+		t.Errorf("Incorrect TestGetDbConfig test. replica-hosts = %v", replicaHosts)
+		t.FailNow()
+	}
+
+	if ymlConfig.GetDbConfig().ConnectRetryMax != 10 || ymlConfig.GetDbConfig().ConnMaxLifetime != 300000 {
+		t.Errorf("Incorrect TestGetDbConfig test. connect-retry-max = %d", ymlConfig.GetDbConfig().ConnectRetryMax)
+		t.FailNow()
+	}
+
+	if ymlConfig.GetDbConfig().ReplicaCheckInterval != 5000 {
+		t.Errorf("Incorrect TestGetDbConfig test. replica-check-interval-millis = %d", ymlConfig.GetDbConfig().ReplicaCheckInterval)
+		t.FailNow()
+	}
+
+	if ymlConfig.GetDbConfig().SoftDeleteRetentionDays != 30 {
+		t.Errorf("Incorrect TestGetDbConfig test. soft-delete-retention-days = %d", ymlConfig.GetDbConfig().SoftDeleteRetentionDays)
+		t.FailNow()
+	}
+
+	os.Setenv("DB_SSL_MODE", "")
+	os.Setenv("DB_REPLICA_HOSTS", "")
+	if ymlConfig.GetDbConfig().SslMode != "disable" || ymlConfig.GetDbConfig().ReplicaHosts != nil {
+		t.Errorf("Incorrect TestGetDbConfig test. ssl-mode = %s", ymlConfig.GetDbConfig().SslMode)
+		t.FailNow()
+	}
 }
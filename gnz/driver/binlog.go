@@ -0,0 +1,220 @@
+package driver
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/jinzhu/gorm"
+
+	"github.com/tomoyane/grant-n-z/gnz/binlog"
+	"github.com/tomoyane/grant-n-z/gnz/entity"
+	"github.com/tomoyane/grant-n-z/gnz/log"
+)
+
+var brInstance BinlogRepository
+
+// Repository for change data capture of binlog
+// It reads binlog status of MySQL, and users whose cache depends on changed rows
+type BinlogRepository interface {
+	// Find current binlog position of MySQL
+	// It needs REPLICATION CLIENT privilege
+	FindMasterStatus(ctx context.Context) (binlog.Position, error)
+
+	// Find binlog file names that MySQL has not purged
+	FindBinaryLogs(ctx context.Context) ([]string, error)
+
+	// Find column names of table in ordinal order
+	FindColumnNames(ctx context.Context, schema string, table string) ([]string, error)
+
+	// Find user uuids of user_groups by user_group uuids
+	FindUserUuidsByUserGroupUuids(ctx context.Context, userGroupUuids []string) ([]string, error)
+
+	// Find user uuids of user_groups by group uuids
+	FindUserUuidsByGroupUuids(ctx context.Context, groupUuids []string) ([]string, error)
+
+	// Find user uuids of user_groups that have policies of role uuids
+	FindUserUuidsByRoleUuids(ctx context.Context, roleUuids []string) ([]string, error)
+
+	// Find user uuids of user_groups that have policies of permission uuids
+	FindUserUuidsByPermissionUuids(ctx context.Context, permissionUuids []string) ([]string, error)
+
+	// Find user uuids of user_services by service uuids
+	FindUserUuidsByServiceUuids(ctx context.Context, serviceUuids []string) ([]string, error)
+}
+
+type BinlogRepositoryImpl struct {
+	Connection *gorm.DB
+}
+
+func GetBinlogRepositoryInstance() BinlogRepository {
+	if brInstance == nil {
+		brInstance = NewBinlogRepository()
+	}
+	return brInstance
+}
+
+func NewBinlogRepository() BinlogRepository {
+	log.Logger.Info("New `BinlogRepository` instance")
+	return BinlogRepositoryImpl{Connection: connection}
+}
+
+func (bri BinlogRepositoryImpl) FindMasterStatus(ctx context.Context) (binlog.Position, error) {
+	var position binlog.Position
+	found := false
+	err := bri.queryRows(ctx, "SHOW MASTER STATUS", func(values []sql.RawBytes) error {
+		var pos uint64
+		if _, err := fmt.Sscan(string(values[1]), &pos); err != nil {
+			return err
+		}
+		position = binlog.Position{Name: string(values[0]), Pos: uint32(pos)}
+		found = true
+		return nil
+	})
+	if err != nil {
+		return position, err
+	}
+	if !found {
+		return position, errors.New("Binlog is not enabled")
+	}
+	return position, nil
+}
+
+func (bri BinlogRepositoryImpl) FindBinaryLogs(ctx context.Context) ([]string, error) {
+	var names []string
+	err := bri.queryRows(ctx, "SHOW BINARY LOGS", func(values []sql.RawBytes) error {
+		names = append(names, string(values[0]))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return names, nil
+}
+
+func (bri BinlogRepositoryImpl) FindColumnNames(ctx context.Context, schema string, table string) ([]string, error) {
+	db, cancel := withContext(ctx, bri.Connection)
+	defer cancel()
+
+	var names []string
+	if err := db.Table("information_schema.columns").
+		Where("table_schema = ? AND table_name = ?", schema, table).
+		Order("ordinal_position").
+		Pluck("column_name", &names).Error; err != nil {
+
+		return nil, err
+	}
+
+	return names, nil
+}
+
+func (bri BinlogRepositoryImpl) FindUserUuidsByUserGroupUuids(ctx context.Context, userGroupUuids []string) ([]string, error) {
+	db, cancel := withContext(ctx, bri.Connection)
+	defer cancel()
+
+	var userUuids []string
+	if err := db.Table(entity.UserGroupTable.String()).
+		Where(fmt.Sprintf("%s IN (?)", entity.UserGroupUuid.String()), userGroupUuids).
+		Pluck("DISTINCT "+entity.UserGroupUserUuid.String(), &userUuids).Error; err != nil {
+
+		return nil, err
+	}
+
+	return userUuids, nil
+}
+
+func (bri BinlogRepositoryImpl) FindUserUuidsByGroupUuids(ctx context.Context, groupUuids []string) ([]string, error) {
+	db, cancel := withContext(ctx, bri.Connection)
+	defer cancel()
+
+	var userUuids []string
+	if err := db.Table(entity.UserGroupTable.String()).
+		Where(fmt.Sprintf("%s IN (?)", entity.UserGroupGroupUuid.String()), groupUuids).
+		Pluck("DISTINCT "+entity.UserGroupUserUuid.String(), &userUuids).Error; err != nil {
+
+		return nil, err
+	}
+
+	return userUuids, nil
+}
+
+func (bri BinlogRepositoryImpl) FindUserUuidsByRoleUuids(ctx context.Context, roleUuids []string) ([]string, error) {
+	return bri.findUserUuidsOfPolicies(ctx, entity.PolicyRoleUuid.String(), roleUuids)
+}
+
+func (bri BinlogRepositoryImpl) FindUserUuidsByPermissionUuids(ctx context.Context, permissionUuids []string) ([]string, error) {
+	return bri.findUserUuidsOfPolicies(ctx, entity.PolicyPermissionUuid.String(), permissionUuids)
+}
+
+func (bri BinlogRepositoryImpl) FindUserUuidsByServiceUuids(ctx context.Context, serviceUuids []string) ([]string, error) {
+	db, cancel := withContext(ctx, bri.Connection)
+	defer cancel()
+
+	var userUuids []string
+	if err := db.Table(entity.UserServiceTable.String()).
+		Where(fmt.Sprintf("%s IN (?)", entity.UserServiceServiceUuid.String()), serviceUuids).
+		Pluck("DISTINCT "+entity.UserServiceUserUuid.String(), &userUuids).Error; err != nil {
+
+		return nil, err
+	}
+
+	return userUuids, nil
+}
+
+// Join user_groups and policies, and find user uuids of policies that column is in values
+func (bri BinlogRepositoryImpl) findUserUuidsOfPolicies(ctx context.Context, column string, values []string) ([]string, error) {
+	db, cancel := withContext(ctx, bri.Connection)
+	defer cancel()
+
+	var userUuids []string
+	if err := db.Table(entity.UserGroupTable.String()).
+		Joins(fmt.Sprintf("INNER JOIN %s ON %s.%s = %s.%s",
+			entity.PolicyTable.String(),
+			entity.PolicyTable.String(),
+			entity.PolicyUserGroupUuid.String(),
+			entity.UserGroupTable.String(),
+			entity.UserGroupUuid.String())).
+		Where(fmt.Sprintf("%s.%s IN (?)", entity.PolicyTable.String(), column), values).
+		Pluck(fmt.Sprintf("DISTINCT %s.%s", entity.UserGroupTable.String(), entity.UserGroupUserUuid.String()), &userUuids).Error; err != nil {
+
+		return nil, err
+	}
+
+	return userUuids, nil
+}
+
+// Run statement that gorm can not scan, such as SHOW
+// Columns of the result differ between MySQL versions, so that each row is read as raw bytes
+func (bri BinlogRepositoryImpl) queryRows(ctx context.Context, query string, fn func(values []sql.RawBytes) error) error {
+	db, cancel := withContext(ctx, bri.Connection)
+	defer cancel()
+
+	rows, err := db.Raw(query).Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+	if len(columns) < 2 {
+		return errors.New(fmt.Sprintf("Unexpected columns of %s. columns = %v", query, columns))
+	}
+	values := make([]sql.RawBytes, len(columns))
+	dest := make([]interface{}, len(columns))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+	for rows.Next() {
+		if err := rows.Scan(dest...); err != nil {
+			return err
+		}
+		if err := fn(values); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
@@ -0,0 +1,72 @@
+package driver
+
+import (
+	"context"
+	"testing"
+
+	"github.com/tomoyane/grant-n-z/gnz/log"
+)
+
+var binlogRepository BinlogRepository
+
+// Setup test precondition
+func init() {
+	log.InitLogger("info")
+
+	connection = openTestConnection()
+	binlogRepository = GetBinlogRepositoryInstance()
+}
+
+// FindMasterStatus InternalServerError test
+func TestFindMasterStatus_Error(t *testing.T) {
+	_, err := binlogRepository.FindMasterStatus(context.Background())
+	if err == nil {
+		t.Errorf("Incorrect TestFindMasterStatus_Error test")
+		t.FailNow()
+	}
+}
+
+// FindBinaryLogs InternalServerError test
+func TestFindBinaryLogs_Error(t *testing.T) {
+	_, err := binlogRepository.FindBinaryLogs(context.Background())
+	if err == nil {
+		t.Errorf("Incorrect TestFindBinaryLogs_Error test")
+		t.FailNow()
+	}
+}
+
+// FindColumnNames InternalServerError test
+func TestFindColumnNames_Error(t *testing.T) {
+	_, err := binlogRepository.FindColumnNames(context.Background(), "grant_n_z", "roles")
+	if err == nil {
+		t.Errorf("Incorrect TestFindColumnNames_Error test")
+		t.FailNow()
+	}
+}
+
+// FindUserUuidsByUserGroupUuids InternalServerError test
+func TestFindUserUuidsByUserGroupUuids_Error(t *testing.T) {
+	_, err := binlogRepository.FindUserUuidsByUserGroupUuids(context.Background(), []string{"uuid"})
+	if err == nil {
+		t.Errorf("Incorrect TestFindUserUuidsByUserGroupUuids_Error test")
+		t.FailNow()
+	}
+}
+
+// FindUserUuidsByRoleUuids InternalServerError test
+func TestFindUserUuidsByRoleUuids_Error(t *testing.T) {
+	_, err := binlogRepository.FindUserUuidsByRoleUuids(context.Background(), []string{"uuid"})
+	if err == nil {
+		t.Errorf("Incorrect TestFindUserUuidsByRoleUuids_Error test")
+		t.FailNow()
+	}
+}
+
+// FindUserUuidsByServiceUuids InternalServerError test
+func TestFindUserUuidsByServiceUuids_Error(t *testing.T) {
+	_, err := binlogRepository.FindUserUuidsByServiceUuids(context.Background(), []string{"uuid"})
+	if err == nil {
+		t.Errorf("Incorrect TestFindUserUuidsByServiceUuids_Error test")
+		t.FailNow()
+	}
+}
@@ -0,0 +1,158 @@
+package driver
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/jinzhu/gorm"
+
+	"github.com/tomoyane/grant-n-z/gnz/log"
+)
+
+// State of circuit breaker
+const (
+	// Calls are sent to rdbms
+	BreakerClosed = "closed"
+
+	// Calls fail fast with ErrUnavailable without rdbms
+	BreakerOpen = "open"
+
+	// One trial call is sent to rdbms, and its result closes or opens the breaker again
+	BreakerHalfOpen = "half-open"
+)
+
+const (
+	// Consecutive connection failures that open the breaker
+	breakerFailureThreshold = 5
+
+	// Time from open to half-open
+	breakerOpenTimeout = 5 * time.Second
+)
+
+// Circuit breaker of the global connection
+var breaker = newCircuitBreaker(breakerFailureThreshold, breakerOpenTimeout)
+
+// Circuit breaker of rdbms connection
+// Only connection failures count, and errors of query such as constraint violation mean rdbms is reachable
+type circuitBreaker struct {
+	mutex       sync.Mutex
+	state       string
+	failures    int
+	threshold   int
+	openTimeout time.Duration
+	openedAt    time.Time
+	trial       bool
+	now         func() time.Time
+}
+
+func newCircuitBreaker(threshold int, openTimeout time.Duration) *circuitBreaker {
+	return &circuitBreaker{
+		state:       BreakerClosed,
+		threshold:   threshold,
+		openTimeout: openTimeout,
+		now:         time.Now,
+	}
+}
+
+// State of circuit breaker of the global connection
+func BreakerState() string {
+	return breaker.currentState()
+}
+
+// Rdbms is not known to be down, so that callers should not fail fast
+func Available() bool {
+	return breaker.currentState() != BreakerOpen
+}
+
+// Circuit breaker of the global connection, that is primary
+// Replicas are checked by health check instead, and other connections such as test do not have it
+func breakerOf(db *gorm.DB) *circuitBreaker {
+	if db != nil && db == connection {
+		return breaker
+	}
+	return nil
+}
+
+// Call is allowed to be sent to rdbms
+// In half-open, only one trial call is allowed until its result is recorded
+func (b *circuitBreaker) allow() bool {
+	if b == nil {
+		return true
+	}
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	switch b.state {
+	case BreakerOpen:
+		if b.now().Sub(b.openedAt) < b.openTimeout {
+			return false
+		}
+		b.state = BreakerHalfOpen
+		b.trial = true
+		return true
+	case BreakerHalfOpen:
+		if b.trial {
+			return false
+		}
+		b.trial = true
+		return true
+	default:
+		return true
+	}
+}
+
+// Record result of call to rdbms
+// Cancel and timeout of the caller do not tell whether rdbms is reachable, so they are not counted
+func (b *circuitBreaker) record(err error) {
+	if b == nil {
+		return
+	}
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.trial = false
+	switch {
+	case err == context.Canceled || err == context.DeadlineExceeded:
+	case isConnectionError(err):
+		b.failures++
+		if b.state == BreakerHalfOpen || (b.state == BreakerClosed && b.failures >= b.threshold) {
+			if b.state == BreakerClosed {
+				log.Logger.Warn(fmt.Sprintf("Open circuit breaker of rdbms. Calls fail fast for %s. err = %s", b.openTimeout, err.Error()))
+			}
+			b.state = BreakerOpen
+			b.openedAt = b.now()
+		}
+	default:
+		if b.state != BreakerClosed {
+			log.Logger.Info("Close circuit breaker of rdbms")
+		}
+		b.state = BreakerClosed
+		b.failures = 0
+	}
+}
+
+func (b *circuitBreaker) currentState() string {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if b.state == BreakerOpen && b.now().Sub(b.openedAt) >= b.openTimeout {
+		return BreakerHalfOpen
+	}
+	return b.state
+}
+
+// Error is failure of connection to rdbms, not of query
+func isConnectionError(err error) bool {
+	return matchError(err, func(err error) bool {
+		if err == driver.ErrBadConn || err == mysql.ErrInvalidConn {
+			return true
+		}
+		_, ok := err.(net.Error)
+		return ok
+	})
+}
@@ -0,0 +1,114 @@
+package driver
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"net"
+	"testing"
+	"time"
+)
+
+// Circuit breaker whose clock is moved by test
+func newTestCircuitBreaker(now *time.Time) *circuitBreaker {
+	b := newCircuitBreaker(2, time.Second)
+	b.now = func() time.Time { return *now }
+	return b
+}
+
+// Test breaker opens after consecutive connection failures
+func TestCircuitBreaker_Open(t *testing.T) {
+	now := time.Now()
+	b := newTestCircuitBreaker(&now)
+
+	b.record(driver.ErrBadConn)
+	b.record(errors.New("Error 1062: Duplicate entry"))
+	b.record(driver.ErrBadConn)
+	if b.currentState() != BreakerClosed || !b.allow() {
+		t.Errorf("Incorrect TestCircuitBreaker_Open test. state = %s", b.currentState())
+		t.FailNow()
+	}
+
+	b.record(&net.OpError{Op: "dial", Err: errors.New("connection refused")})
+	if b.currentState() != BreakerOpen || b.allow() {
+		t.Errorf("Incorrect TestCircuitBreaker_Open test. state = %s", b.currentState())
+		t.FailNow()
+	}
+}
+
+// Test breaker allows one trial after open timeout
+func TestCircuitBreaker_HalfOpen(t *testing.T) {
+	now := time.Now()
+	b := newTestCircuitBreaker(&now)
+	b.record(driver.ErrBadConn)
+	b.record(driver.ErrBadConn)
+
+	now = now.Add(time.Second)
+	if b.currentState() != BreakerHalfOpen || !b.allow() || b.allow() {
+		t.Errorf("Incorrect TestCircuitBreaker_HalfOpen test. state = %s", b.currentState())
+		t.FailNow()
+	}
+
+	// Failure of trial opens again
+	b.record(driver.ErrBadConn)
+	if b.currentState() != BreakerOpen {
+		t.Errorf("Incorrect TestCircuitBreaker_HalfOpen test. state = %s", b.currentState())
+		t.FailNow()
+	}
+
+	// Cancel of trial does not close, and allows next trial
+	now = now.Add(time.Second)
+	b.allow()
+	b.record(context.Canceled)
+	if b.currentState() != BreakerHalfOpen || !b.allow() {
+		t.Errorf("Incorrect TestCircuitBreaker_HalfOpen test. state = %s", b.currentState())
+		t.FailNow()
+	}
+
+	// Success of trial closes
+	b.record(nil)
+	if b.currentState() != BreakerClosed || !b.allow() {
+		t.Errorf("Incorrect TestCircuitBreaker_HalfOpen test. state = %s", b.currentState())
+		t.FailNow()
+	}
+}
+
+// Test call of the global connection fails fast while breaker is open
+func TestCircuitBreaker_FailFast(t *testing.T) {
+	db := setUpContextConnection(t)
+	defer db.Close()
+
+	now := time.Now()
+	globalConnection, globalBreaker := connection, breaker
+	connection, breaker = db, newTestCircuitBreaker(&now)
+	defer func() { connection, breaker = globalConnection, globalBreaker }()
+
+	breaker.record(driver.ErrBadConn)
+	breaker.record(driver.ErrBadConn)
+	if Available() {
+		t.Errorf("Incorrect TestCircuitBreaker_FailFast test")
+		t.FailNow()
+	}
+	if _, err := (RoleRepositoryImpl{Connection: db}).FindAll(context.Background()); err != ErrUnavailable {
+		t.Errorf("Incorrect TestCircuitBreaker_FailFast test. err = %v", err)
+		t.FailNow()
+	}
+
+	now = now.Add(time.Second)
+	if _, err := (RoleRepositoryImpl{Connection: db}).FindAll(context.Background()); err != nil || BreakerState() != BreakerClosed {
+		t.Errorf("Incorrect TestCircuitBreaker_FailFast test. err = %v", err)
+		t.FailNow()
+	}
+}
+
+// Test backoff of connection retry
+func TestConnectBackoff(t *testing.T) {
+	if connectBackoff(0) != connectInitialBackoff || connectBackoff(1) != 2*connectInitialBackoff {
+		t.Errorf("Incorrect TestConnectBackoff test. backoff = %s", connectBackoff(1))
+		t.FailNow()
+	}
+	if connectBackoff(100) != connectMaxBackoff {
+		t.Errorf("Incorrect TestConnectBackoff test. backoff = %s", connectBackoff(100))
+		t.FailNow()
+	}
+}
@@ -1,6 +1,9 @@
 package driver
 
 import (
+	"context"
+	"database/sql/driver"
+	"errors"
 	"fmt"
 	"strconv"
 	"strings"
@@ -14,6 +17,18 @@ import (
 // Global DataBase Client
 var connection *gorm.DB
 
+const (
+	// Backoff of connection retry at start, that doubles on each retry up to max
+	connectInitialBackoff = 500 * time.Millisecond
+	connectMaxBackoff     = 30 * time.Second
+
+	// Interval of ping, that updates circuit breaker when no api call is sent to rdbms
+	pingInterval = 10 * time.Second
+
+	// Interval of purge of soft deleted rows that passed retention period
+	purgeInterval = time.Hour
+)
+
 type Database struct {
 	DbConfig common.DbConfig
 	AppConfig common.AppConfig
@@ -27,64 +42,196 @@ func NewDatabase() Database {
 	}
 }
 
+// Set connection pool of dialect
+func setConnectionPool(db *gorm.DB, dialect string, config common.DbConfig) {
+	openConnection, _ := strconv.Atoi(config.MaxOpenConnection)
+	idleConnection, _ := strconv.Atoi(config.MaxIdleConnection)
+	connMaxLifetime := time.Duration(config.ConnMaxLifetime) * time.Millisecond
+	if dialect == EngineSqlite {
+		// Each connection of :memory: has its own database, and sqlite has one writer
+		// The connection is never recycled, because the database of :memory: is dropped with it
+		openConnection = 1
+		idleConnection = 1
+		connMaxLifetime = 0
+	}
+	db.DB().SetMaxOpenConns(openConnection)
+	db.DB().SetMaxIdleConns(idleConnection)
+	db.DB().SetConnMaxLifetime(connMaxLifetime)
+}
+
 // Initialize database driver for GrantNZ server
 func (r Database) Connect() {
-	if !strings.EqualFold(r.DbConfig.Engine, "mysql") {
-		panic("Current status, only support mysql.")
+	dialect, dbSource, err := BuildDataSource(r.DbConfig)
+	if err != nil {
+		panic(err.Error())
 	}
 
-	dbSource := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?charset=utf8&parseTime=True",
-		r.DbConfig.User,
-		r.DbConfig.Password,
-		r.DbConfig.Hosts,
-		r.DbConfig.Port,
-		r.DbConfig.Name,
-	)
-
-	db, err := gorm.Open("mysql", dbSource)
+	db, err := r.open(dialect, dbSource)
 	if err != nil {
 		log.Logger.Warn(err.Error())
 		r.Close()
-		panic("Cannot connect MySQL")
+		panic(fmt.Sprintf("Cannot connect %s", dialect))
 	}
 
 	if strings.EqualFold(r.AppConfig.LogLevel, "DEBUG") || strings.EqualFold(r.AppConfig.LogLevel, "debug") {
-		db.LogMode(true)
+		logMode = true
 	} else {
-		db.LogMode(false)
+		logMode = false
 	}
+	db.LogMode(logMode)
+	queryTimeout = time.Duration(r.DbConfig.QueryTimeout) * time.Millisecond
 
-	openConnection, _ := strconv.Atoi(r.DbConfig.MaxOpenConnection)
-	idleConnection, _ := strconv.Atoi(r.DbConfig.MaxIdleConnection)
-	db.DB().SetMaxOpenConns(openConnection)
-	db.DB().SetMaxIdleConns(idleConnection)
+	setConnectionPool(db, dialect, r.DbConfig)
 
-	log.Logger.Info(fmt.Sprintf("Connected MySQL. Open connection = %d. Max open connection = %d.",
+	if dialect == EngineSqlite {
+		// Schema of sqlite is created at start, because it is for development and embedded deployments
+		if _, err := (MigratorImpl{Connection: db, Migrations: Migrations}).Up(context.Background()); err != nil {
+			log.Logger.Warn(err.Error())
+			db.Close()
+			panic("Cannot migrate sqlite schema")
+		}
+	}
+
+	log.Logger.Info(fmt.Sprintf("Connected %s. Open connection = %d. Max open connection = %d.",
+		dialect,
 		db.DB().Stats().OpenConnections,
 		db.DB().Stats().MaxOpenConnections),
 	)
 	connection = db
+
+	if len(r.DbConfig.ReplicaHosts) == 0 {
+		return
+	}
+	if dialect == EngineSqlite {
+		log.Logger.Warn("Replicas of sqlite are not supported. db.replica-hosts is ignored")
+		return
+	}
+	openConnection, _ := strconv.Atoi(r.DbConfig.MaxOpenConnection)
+	idleConnection, _ := strconv.Atoi(r.DbConfig.MaxIdleConnection)
+	rs, err := openReplicas(r.DbConfig, db, openConnection, idleConnection)
+	if err != nil {
+		log.Logger.Warn(err.Error())
+		r.Close()
+		panic("Cannot connect replicas")
+	}
+	replicas = rs
+	log.Logger.Info(fmt.Sprintf("Connected replicas. hosts = %s", strings.Join(r.DbConfig.ReplicaHosts, ",")))
 }
 
-// Ping RDBMS
+// Open rdbms, and retry with exponential backoff up to db.connect-retry-max times
+// Rdbms may not be ready yet while rolling restart or start of all components
+func (r Database) open(dialect string, dbSource string) (*gorm.DB, error) {
+	for retry := 0; ; retry++ {
+		db, err := gorm.Open(dialect, dbSource)
+		if err == nil {
+			return db, nil
+		}
+		if retry >= r.DbConfig.ConnectRetryMax {
+			return nil, err
+		}
+
+		backoff := connectBackoff(retry)
+		log.Logger.Warn(fmt.Sprintf("Failed to connect %s. Retry after %s. retry = %d/%d. err = %s",
+			dialect, backoff, retry+1, r.DbConfig.ConnectRetryMax, err.Error()))
+		time.Sleep(backoff)
+	}
+}
+
+// Refuse to run against schema that is not the same as migrations of this binary
+func (r Database) CheckSchema() {
+	if err := NewMigrator().Check(context.Background()); err != nil {
+		log.Logger.Error(err.Error())
+		r.Close()
+		panic("Unsupported schema version. " + err.Error())
+	}
+}
+
+// Ping RDBMS in interval, so that circuit breaker is closed when rdbms is recovered without api call
 func (r Database) PingRdbms() {
 	for {
-		time.Sleep(1 * time.Minute)
-		err := connection.DB().Ping()
-		if err != nil {
+		time.Sleep(pingInterval)
+		ctx, cancel := context.WithTimeout(context.Background(), pingInterval)
+		if err := r.Ping(ctx); err != nil {
 			log.Logger.Warn("Failed to rdbms ping.", err.Error())
-		} else {
-			log.Logger.Info("Ping rdbms.")
 		}
+		cancel()
+	}
+}
+
+// Check health of replicas in interval of db.replica-check-interval-millis
+// Reads of unhealthy replica fail over to other replica or primary until it is healthy again
+func (r Database) CheckReplicas() {
+	if replicas == nil {
+		return
+	}
+	interval := time.Duration(r.DbConfig.ReplicaCheckInterval) * time.Millisecond
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+	for {
+		time.Sleep(interval)
+		replicas.check(context.Background(), interval)
 	}
 }
 
+// Purge soft deleted rows in interval, that were deleted db.soft-delete-retention-days ago
+// If retention days is 0 or less, soft deleted rows are kept
+func (r Database) PurgeSoftDeleted() {
+	if r.DbConfig.SoftDeleteRetentionDays <= 0 {
+		log.Logger.Info("Purge of soft deleted rows is disabled")
+		return
+	}
+	retention := time.Duration(r.DbConfig.SoftDeleteRetentionDays) * 24 * time.Hour
+	for {
+		time.Sleep(purgeInterval)
+		purged, err := NewSoftDeleteRepository().Purge(context.Background(), time.Now().Add(-retention))
+		if err != nil {
+			log.Logger.Warn("Failed to purge soft deleted rows.", err.Error())
+			continue
+		}
+		if purged != 0 {
+			log.Logger.Info(fmt.Sprintf("Purged soft deleted rows. rows = %d", purged))
+		}
+	}
+}
+
+// Ping RDBMS once, and record the result to circuit breaker
+func (r Database) Ping(ctx context.Context) error {
+	if connection == nil {
+		return errors.New("Not connected rdbms")
+	}
+	err := connection.DB().PingContext(ctx)
+	if err == context.DeadlineExceeded {
+		// Rdbms that does not respond to ping is down, though timeout of query is not counted
+		breaker.record(driver.ErrBadConn)
+	} else {
+		breaker.record(err)
+	}
+	return err
+}
+
 // Close RDBMS
 func (r Database) Close() {
+	if replicas != nil {
+		replicas.close()
+		replicas = nil
+	}
 	if connection != nil {
 		connection.Close()
-		log.Logger.Info("Closed MySQL connection")
+		log.Logger.Info("Closed rdbms connection")
 	} else {
-		log.Logger.Info("Already closed MySQL connection")
+		log.Logger.Info("Already closed rdbms connection")
+	}
+}
+
+// Backoff of retry, that is initial backoff * 2^retry up to max backoff
+func connectBackoff(retry int) time.Duration {
+	backoff := connectInitialBackoff
+	for i := 0; i < retry && backoff < connectMaxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > connectMaxBackoff {
+		return connectMaxBackoff
 	}
+	return backoff
 }
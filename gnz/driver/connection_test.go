@@ -0,0 +1,23 @@
+package driver
+
+import (
+	"os"
+
+	"github.com/jinzhu/gorm"
+)
+
+// Connection of repository tests
+// Repository tests run on PostgreSQL when $TEST_POSTGRES_DSN is set, e.g. "host=localhost user=postgres dbname=grant_n_z_test sslmode=disable"
+// The database of tests must not have tables, the same as sqlite3 of default
+func openTestConnection() *gorm.DB {
+	if dsn := os.Getenv("TEST_POSTGRES_DSN"); dsn != "" {
+		db, err := gorm.Open(EnginePostgres, dsn)
+		if err != nil {
+			panic(err.Error())
+		}
+		return db
+	}
+
+	db, _ := gorm.Open("sqlite3", "/tmp/test_grant_nz.db")
+	return db
+}
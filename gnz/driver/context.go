@@ -0,0 +1,104 @@
+package driver
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+var (
+	// Timeout of each repository call. If 0, it waits until ctx of the caller is done
+	queryTimeout time.Duration
+
+	// Log sql of each repository call
+	logMode bool
+)
+
+// Connection that runs every sql with ctx
+// gorm v1 does not take context, so repository opens gorm on this connection for each call
+type contextConnection struct {
+	db      *sql.DB
+	ctx     context.Context
+	breaker *circuitBreaker
+}
+
+func (c contextConnection) Exec(query string, args ...interface{}) (sql.Result, error) {
+	if !c.breaker.allow() {
+		return nil, ErrUnavailable
+	}
+	result, err := c.db.ExecContext(c.ctx, query, args...)
+	c.breaker.record(err)
+	return result, err
+}
+
+func (c contextConnection) Prepare(query string) (*sql.Stmt, error) {
+	if !c.breaker.allow() {
+		return nil, ErrUnavailable
+	}
+	stmt, err := c.db.PrepareContext(c.ctx, query)
+	c.breaker.record(err)
+	return stmt, err
+}
+
+func (c contextConnection) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	if !c.breaker.allow() {
+		return nil, ErrUnavailable
+	}
+	rows, err := c.db.QueryContext(c.ctx, query, args...)
+	c.breaker.record(err)
+	return rows, err
+}
+
+// Error of sql.Row is returned by Scan, so circuit breaker does not know the result
+func (c contextConnection) QueryRow(query string, args ...interface{}) *sql.Row {
+	return c.db.QueryRowContext(c.ctx, query, args...)
+}
+
+func (c contextConnection) Begin() (*sql.Tx, error) {
+	return c.BeginTx(c.ctx, nil)
+}
+
+// gorm begins transaction with background context, so ctx of the connection is used instead
+func (c contextConnection) BeginTx(_ context.Context, opts *sql.TxOptions) (*sql.Tx, error) {
+	if !c.breaker.allow() {
+		return nil, ErrUnavailable
+	}
+	tx, err := c.db.BeginTx(c.ctx, opts)
+	c.breaker.record(err)
+	return tx, err
+}
+
+// Get gorm connection of primary that is cancelled when ctx is done or query timeout passed
+// The caller must call cancel after the repository call
+func withContext(ctx context.Context, connection *gorm.DB) (*gorm.DB, context.CancelFunc) {
+	markWritten(ctx)
+	return openContext(ctx, connection)
+}
+
+func openContext(ctx context.Context, connection *gorm.DB) (*gorm.DB, context.CancelFunc) {
+	var cancel context.CancelFunc
+	if queryTimeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, queryTimeout)
+	} else {
+		ctx, cancel = context.WithCancel(ctx)
+	}
+
+	return withCancel(ctx, connection), cancel
+}
+
+// Get gorm connection that is cancelled only when ctx is done
+// It is for the call that takes longer than query timeout, such as migration
+func withCancel(ctx context.Context, connection *gorm.DB) *gorm.DB {
+	sqlDb, ok := connection.CommonDB().(*sql.DB)
+	if !ok {
+		return connection
+	}
+	db, err := gorm.Open(connection.Dialect().GetName(), contextConnection{db: sqlDb, ctx: ctx, breaker: breakerOf(connection)})
+	if err != nil {
+		return connection
+	}
+	db.LogMode(logMode)
+	return db
+}
@@ -0,0 +1,61 @@
+package driver
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/tomoyane/grant-n-z/gnz/entity"
+)
+
+// Setup seeded in memory connection
+func setUpContextConnection(t *testing.T) *gorm.DB {
+	db, err := gorm.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Errorf("Incorrect context test. err = %s", err.Error())
+		t.FailNow()
+	}
+	db.DB().SetMaxOpenConns(1)
+	db.AutoMigrate(&entity.Role{})
+	db.Create(&entity.Role{Name: "admin"})
+	return db
+}
+
+// Test repository call with context
+func TestWithContext(t *testing.T) {
+	db := setUpContextConnection(t)
+	defer db.Close()
+
+	roles, err := RoleRepositoryImpl{Connection: db}.FindAll(context.Background())
+	if err != nil || len(roles) != 1 {
+		t.Errorf("Incorrect TestWithContext test. roles = %v, err = %v", roles, err)
+		t.FailNow()
+	}
+}
+
+// Test repository call with cancelled context
+func TestWithContext_Cancelled(t *testing.T) {
+	db := setUpContextConnection(t)
+	defer db.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := (RoleRepositoryImpl{Connection: db}).FindAll(ctx); err == nil {
+		t.Errorf("Incorrect TestWithContext_Cancelled test")
+		t.FailNow()
+	}
+}
+
+// Test repository call over query timeout
+func TestWithContext_Timeout(t *testing.T) {
+	db := setUpContextConnection(t)
+	defer db.Close()
+
+	queryTimeout = time.Nanosecond
+	defer func() { queryTimeout = 0 }()
+	if _, err := (RoleRepositoryImpl{Connection: db}).FindAll(context.Background()); err == nil {
+		t.Errorf("Incorrect TestWithContext_Timeout test")
+		t.FailNow()
+	}
+}
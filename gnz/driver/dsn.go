@@ -0,0 +1,199 @@
+package driver
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/go-sql-driver/mysql"
+	_ "github.com/jinzhu/gorm/dialects/sqlite"
+	_ "github.com/lib/pq"
+
+	"github.com/tomoyane/grant-n-z/gnz/common"
+)
+
+// Supported db.engine
+const (
+	EngineMysql    = "mysql"
+	EnginePostgres = "postgres"
+	EngineSqlite   = "sqlite3"
+)
+
+// Supported db.ssl-mode, that is the same as sslmode of PostgreSQL
+const (
+	SslModeDisable    = "disable"
+	SslModeRequire    = "require"
+	SslModeVerifyCa   = "verify-ca"
+	SslModeVerifyFull = "verify-full"
+)
+
+// Name of tls config that is registered to mysql driver
+const mysqlTlsConfigName = "grant_n_z"
+
+// Build gorm dialect and data source name of db config
+func BuildDataSource(config common.DbConfig) (string, string, error) {
+	switch strings.ToLower(config.Engine) {
+	case EngineMysql:
+		dsn, err := buildMysqlDataSource(config)
+		return EngineMysql, dsn, err
+	case EnginePostgres, "postgresql":
+		dsn, err := buildPostgresDataSource(config)
+		return EnginePostgres, dsn, err
+	case EngineSqlite, "sqlite":
+		dsn, err := buildSqliteDataSource(config)
+		return EngineSqlite, dsn, err
+	default:
+		return "", "", errors.New(fmt.Sprintf("Not supported db engine. engine = %s", config.Engine))
+	}
+}
+
+// Data source name of go-sql-driver/mysql
+func buildMysqlDataSource(config common.DbConfig) (string, error) {
+	params := url.Values{}
+	params.Set("charset", "utf8")
+	params.Set("parseTime", "True")
+
+	switch sslMode(config) {
+	case SslModeDisable:
+	case SslModeRequire:
+		params.Set("tls", "skip-verify")
+	case SslModeVerifyCa, SslModeVerifyFull:
+		tlsConfig, err := buildTlsConfig(config)
+		if err != nil {
+			return "", err
+		}
+		if err := mysql.RegisterTLSConfig(mysqlTlsConfigName, tlsConfig); err != nil {
+			return "", err
+		}
+		params.Set("tls", mysqlTlsConfigName)
+	default:
+		return "", errors.New(fmt.Sprintf("Not supported db ssl mode. ssl-mode = %s", config.SslMode))
+	}
+
+	return fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?%s",
+		config.User,
+		config.Password,
+		config.Hosts,
+		config.Port,
+		config.Name,
+		params.Encode(),
+	), nil
+}
+
+// Data source name of lib/pq, that is key=value format
+func buildPostgresDataSource(config common.DbConfig) (string, error) {
+	mode := sslMode(config)
+	switch mode {
+	case SslModeDisable, SslModeRequire, SslModeVerifyCa, SslModeVerifyFull:
+	default:
+		return "", errors.New(fmt.Sprintf("Not supported db ssl mode. ssl-mode = %s", config.SslMode))
+	}
+
+	values := [][]string{
+		{"host", config.Hosts},
+		{"port", config.Port},
+		{"user", config.User},
+		{"password", config.Password},
+		{"dbname", config.Name},
+		{"sslmode", mode},
+		{"sslrootcert", config.SslRootCert},
+		{"sslcert", config.SslCert},
+		{"sslkey", config.SslKey},
+	}
+	var params []string
+	for _, value := range values {
+		if value[1] == "" {
+			continue
+		}
+		params = append(params, fmt.Sprintf("%s=%s", value[0], quotePostgresValue(value[1])))
+	}
+	return strings.Join(params, " "), nil
+}
+
+// Data source name of mattn/go-sqlite3
+// db.name is file path or ":memory:", and foreign keys are enabled the same as other engines
+func buildSqliteDataSource(config common.DbConfig) (string, error) {
+	if config.Name == "" {
+		return "", errors.New("Not found db name of sqlite. name is file path or :memory:")
+	}
+
+	params := url.Values{}
+	params.Set("_foreign_keys", "1")
+	if config.QueryTimeout > 0 {
+		params.Set("_busy_timeout", strconv.Itoa(config.QueryTimeout))
+	}
+	return fmt.Sprintf("file:%s?%s", config.Name, params.Encode()), nil
+}
+
+// Tls config of verify-ca and verify-full for mysql
+// verify-ca verifies the certificate chain, but does not verify the host name
+func buildTlsConfig(config common.DbConfig) (*tls.Config, error) {
+	tlsConfig := &tls.Config{ServerName: config.Hosts}
+	if config.SslRootCert != "" {
+		pem, err := ioutil.ReadFile(config.SslRootCert)
+		if err != nil {
+			return nil, err
+		}
+		rootCAs := x509.NewCertPool()
+		if !rootCAs.AppendCertsFromPEM(pem) {
+			return nil, errors.New(fmt.Sprintf("Failed to read db ssl root cert. ssl-root-cert = %s", config.SslRootCert))
+		}
+		tlsConfig.RootCAs = rootCAs
+	}
+
+	if config.SslCert != "" || config.SslKey != "" {
+		certificate, err := tls.LoadX509KeyPair(config.SslCert, config.SslKey)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.Certificates = []tls.Certificate{certificate}
+	}
+
+	if sslMode(config) == SslModeVerifyCa {
+		rootCAs := tlsConfig.RootCAs
+		tlsConfig.InsecureSkipVerify = true
+		tlsConfig.VerifyPeerCertificate = func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+			return verifyCertificateChain(rawCerts, rootCAs)
+		}
+	}
+	return tlsConfig, nil
+}
+
+func verifyCertificateChain(rawCerts [][]byte, rootCAs *x509.CertPool) error {
+	if len(rawCerts) == 0 {
+		return errors.New("Not found db server certificate")
+	}
+	options := x509.VerifyOptions{Roots: rootCAs, Intermediates: x509.NewCertPool()}
+	var leaf *x509.Certificate
+	for i, rawCert := range rawCerts {
+		certificate, err := x509.ParseCertificate(rawCert)
+		if err != nil {
+			return err
+		}
+		if i == 0 {
+			leaf = certificate
+		} else {
+			options.Intermediates.AddCert(certificate)
+		}
+	}
+	_, err := leaf.Verify(options)
+	return err
+}
+
+func sslMode(config common.DbConfig) string {
+	if config.SslMode == "" {
+		return SslModeDisable
+	}
+	return strings.ToLower(config.SslMode)
+}
+
+func quotePostgresValue(value string) string {
+	value = strings.Replace(value, `\`, `\\`, -1)
+	value = strings.Replace(value, `'`, `\'`, -1)
+	return "'" + value + "'"
+}
@@ -0,0 +1,95 @@
+package driver
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/tomoyane/grant-n-z/gnz/common"
+)
+
+func testDbConfig(engine string, sslMode string) common.DbConfig {
+	return common.DbConfig{
+		Engine:   engine,
+		Hosts:    "localhost",
+		User:     "root",
+		Password: "it's secret",
+		Port:     "3306",
+		Name:     "grant_n_z",
+		SslMode:  sslMode,
+	}
+}
+
+// BuildDataSource of mysql test
+func TestBuildDataSource_Mysql(t *testing.T) {
+	dialect, dsn, err := BuildDataSource(testDbConfig("MySQL", ""))
+	if err != nil || dialect != EngineMysql || dsn != "root:it's secret@tcp(localhost:3306)/grant_n_z?charset=utf8&parseTime=True" {
+		t.Errorf("Incorrect TestBuildDataSource_Mysql test. dialect = %s, dsn = %s", dialect, dsn)
+		t.FailNow()
+	}
+
+	_, dsn, err = BuildDataSource(testDbConfig(EngineMysql, SslModeRequire))
+	if err != nil || !strings.HasSuffix(dsn, "&tls=skip-verify") {
+		t.Errorf("Incorrect TestBuildDataSource_Mysql test. dsn = %s", dsn)
+		t.FailNow()
+	}
+
+	_, dsn, err = BuildDataSource(testDbConfig(EngineMysql, SslModeVerifyFull))
+	if err != nil || !strings.HasSuffix(dsn, "&tls="+mysqlTlsConfigName) {
+		t.Errorf("Incorrect TestBuildDataSource_Mysql test. dsn = %s", dsn)
+		t.FailNow()
+	}
+}
+
+// BuildDataSource of postgres test
+func TestBuildDataSource_Postgres(t *testing.T) {
+	config := testDbConfig("postgresql", SslModeVerifyCa)
+	config.Port = "5432"
+	config.SslRootCert = "/etc/ssl/root.crt"
+	dialect, dsn, err := BuildDataSource(config)
+	expected := `host='localhost' port='5432' user='root' password='it\'s secret' dbname='grant_n_z' sslmode='verify-ca' sslrootcert='/etc/ssl/root.crt'`
+	if err != nil || dialect != EnginePostgres || dsn != expected {
+		t.Errorf("Incorrect TestBuildDataSource_Postgres test. dialect = %s, dsn = %s", dialect, dsn)
+		t.FailNow()
+	}
+
+	_, dsn, err = BuildDataSource(testDbConfig(EnginePostgres, ""))
+	if err != nil || !strings.Contains(dsn, "sslmode='disable'") {
+		t.Errorf("Incorrect TestBuildDataSource_Postgres test. dsn = %s", dsn)
+		t.FailNow()
+	}
+}
+
+// BuildDataSource of sqlite test
+func TestBuildDataSource_Sqlite(t *testing.T) {
+	config := common.DbConfig{Engine: "sqlite", Name: ":memory:", QueryTimeout: 3000}
+	dialect, dsn, err := BuildDataSource(config)
+	if err != nil || dialect != EngineSqlite || dsn != "file::memory:?_busy_timeout=3000&_foreign_keys=1" {
+		t.Errorf("Incorrect TestBuildDataSource_Sqlite test. dialect = %s, dsn = %s", dialect, dsn)
+		t.FailNow()
+	}
+}
+
+// BuildDataSource of invalid config test
+func TestBuildDataSource_Error(t *testing.T) {
+	configs := []common.DbConfig{
+		testDbConfig("oracle", ""),
+		testDbConfig(EngineMysql, "prefer"),
+		testDbConfig(EnginePostgres, "prefer"),
+		{Engine: EngineSqlite},
+	}
+	notFoundCert := testDbConfig(EngineMysql, SslModeVerifyCa)
+	notFoundCert.SslRootCert = "/not/found/root.crt"
+	configs = append(configs, notFoundCert)
+
+	for _, config := range configs {
+		if _, _, err := BuildDataSource(config); err == nil {
+			t.Errorf("Incorrect TestBuildDataSource_Error test. config = %v", config)
+			t.FailNow()
+		}
+	}
+
+	if err := verifyCertificateChain(nil, nil); err == nil {
+		t.Errorf("Incorrect TestBuildDataSource_Error test")
+		t.FailNow()
+	}
+}
@@ -0,0 +1,136 @@
+package driver
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/jinzhu/gorm"
+	"github.com/lib/pq"
+	"github.com/mattn/go-sqlite3"
+)
+
+// Errors of repository that are independent of db engine
+// Repository returns them instead of errors of gorm and db driver, and other errors as they are
+var (
+	// Record of the condition does not exist
+	ErrNotFound = errors.New("Not found data")
+
+	// Record violates unique constraint
+	ErrDuplicate = errors.New("Already exists data")
+
+	// Record refers to data that does not exist, or is referred to by other data
+	ErrForeignKey = errors.New("Not found relational data")
+
+	// Transaction failed by other transaction, such as deadlock and serialization failure
+	ErrConflict = errors.New("Conflicted with other transaction")
+
+	// Rdbms is not reachable, or circuit breaker is open
+	ErrUnavailable = errors.New("Database is unavailable")
+
+	// Record was updated by other request after the version that the caller read
+	ErrStaleVersion = errors.New("Data was updated by other request")
+
+	// Record refers to data of other service than the tenant of context
+	ErrTenant = errors.New("Data belongs to other service")
+)
+
+// Error number and code of constraint violation in each engine
+const (
+	mysqlErrDupEntry            = 1062
+	mysqlErrRowIsReferenced     = 1451
+	mysqlErrNoReferencedRow     = 1452
+	mysqlErrLockDeadlock        = 1213
+	postgresUniqueViolation     = "23505"
+	postgresForeignKeyViolation = "23503"
+	postgresSerializationFailed = "40001"
+	postgresDeadlockDetected    = "40P01"
+)
+
+// Translate error of gorm and db driver to error of repository
+func translateError(err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case gorm.IsRecordNotFoundError(err):
+		return ErrNotFound
+	case IsUniqueViolation(err):
+		return ErrDuplicate
+	case IsForeignKeyViolation(err):
+		return ErrForeignKey
+	case isConflict(err):
+		return ErrConflict
+	case isConnectionError(err) || matchError(err, func(err error) bool { return err == ErrUnavailable }):
+		return ErrUnavailable
+	default:
+		return err
+	}
+}
+
+// Error is violation of unique constraint
+func IsUniqueViolation(err error) bool {
+	return matchError(err, func(err error) bool {
+		switch driverErr := err.(type) {
+		case *mysql.MySQLError:
+			return driverErr.Number == mysqlErrDupEntry
+		case *pq.Error:
+			return driverErr.Code == postgresUniqueViolation
+		case sqlite3.Error:
+			return driverErr.ExtendedCode == sqlite3.ErrConstraintUnique || driverErr.ExtendedCode == sqlite3.ErrConstraintPrimaryKey
+		}
+		return false
+	})
+}
+
+// Error is violation of foreign key constraint
+func IsForeignKeyViolation(err error) bool {
+	return matchError(err, func(err error) bool {
+		switch driverErr := err.(type) {
+		case *mysql.MySQLError:
+			return driverErr.Number == mysqlErrNoReferencedRow || driverErr.Number == mysqlErrRowIsReferenced
+		case *pq.Error:
+			return driverErr.Code == postgresForeignKeyViolation
+		case sqlite3.Error:
+			return driverErr.ExtendedCode == sqlite3.ErrConstraintForeignKey
+		}
+		return false
+	})
+}
+
+// Error is failure of transaction by other transaction
+func isConflict(err error) bool {
+	return matchError(err, func(err error) bool {
+		switch driverErr := err.(type) {
+		case *mysql.MySQLError:
+			return driverErr.Number == mysqlErrLockDeadlock
+		case *pq.Error:
+			return driverErr.Code == postgresSerializationFailed || driverErr.Code == postgresDeadlockDetected
+		case sqlite3.Error:
+			return driverErr.Code == sqlite3.ErrBusy || driverErr.Code == sqlite3.ErrLocked
+		}
+		return false
+	})
+}
+
+// Error of update by version that changed no row whose column is value
+// If the row exists, it has other version than the caller read
+func versionError(db *gorm.DB, row interface{}, column string, value string) error {
+	err := db.Where(fmt.Sprintf("%s = ?", column), value).First(row).Error
+	if err == nil {
+		return ErrStaleVersion
+	}
+	return translateError(err)
+}
+
+// Gorm returns gorm.Errors when some errors occurred in one operation
+func matchError(err error, match func(err error) bool) bool {
+	if gormErrs, ok := err.(gorm.Errors); ok {
+		for _, gormErr := range gormErrs {
+			if matchError(gormErr, match) {
+				return true
+			}
+		}
+		return false
+	}
+	return err != nil && match(err)
+}
@@ -0,0 +1,124 @@
+package driver
+
+import (
+	"database/sql/driver"
+	"errors"
+	"testing"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/jinzhu/gorm"
+	"github.com/lib/pq"
+	"github.com/mattn/go-sqlite3"
+)
+
+// IsUniqueViolation test
+func TestIsUniqueViolation(t *testing.T) {
+	violations := []error{
+		&mysql.MySQLError{Number: 1062, Message: "Duplicate entry"},
+		&pq.Error{Code: "23505"},
+		sqlite3.Error{Code: sqlite3.ErrConstraint, ExtendedCode: sqlite3.ErrConstraintUnique},
+		gorm.Errors{errors.New("failed"), &pq.Error{Code: "23505"}},
+	}
+	for _, err := range violations {
+		if !IsUniqueViolation(err) || IsForeignKeyViolation(err) {
+			t.Errorf("Incorrect TestIsUniqueViolation test. err = %v", err)
+			t.FailNow()
+		}
+	}
+
+	others := []error{nil, errors.New("Error 1062"), &mysql.MySQLError{Number: 1452}, &pq.Error{Code: "23503"}}
+	for _, err := range others {
+		if IsUniqueViolation(err) {
+			t.Errorf("Incorrect TestIsUniqueViolation test. err = %v", err)
+			t.FailNow()
+		}
+	}
+}
+
+// IsForeignKeyViolation test
+func TestIsForeignKeyViolation(t *testing.T) {
+	violations := []error{
+		&mysql.MySQLError{Number: 1452},
+		&mysql.MySQLError{Number: 1451},
+		&pq.Error{Code: "23503"},
+		sqlite3.Error{Code: sqlite3.ErrConstraint, ExtendedCode: sqlite3.ErrConstraintForeignKey},
+		gorm.Errors{&mysql.MySQLError{Number: 1452}},
+	}
+	for _, err := range violations {
+		if !IsForeignKeyViolation(err) || IsUniqueViolation(err) {
+			t.Errorf("Incorrect TestIsForeignKeyViolation test. err = %v", err)
+			t.FailNow()
+		}
+	}
+
+	if IsForeignKeyViolation(nil) || IsForeignKeyViolation(errors.New("Error 1452")) {
+		t.Errorf("Incorrect TestIsForeignKeyViolation test")
+		t.FailNow()
+	}
+}
+
+// translateError test
+func TestTranslateError(t *testing.T) {
+	other := errors.New("failed")
+	cases := []struct {
+		err      error
+		expected error
+	}{
+		{nil, nil},
+		{gorm.ErrRecordNotFound, ErrNotFound},
+		{gorm.Errors{gorm.ErrRecordNotFound}, ErrNotFound},
+		{&mysql.MySQLError{Number: 1062}, ErrDuplicate},
+		{sqlite3.Error{Code: sqlite3.ErrConstraint, ExtendedCode: sqlite3.ErrConstraintUnique}, ErrDuplicate},
+		{&pq.Error{Code: "23503"}, ErrForeignKey},
+		{&mysql.MySQLError{Number: 1213}, ErrConflict},
+		{&pq.Error{Code: "40001"}, ErrConflict},
+		{&pq.Error{Code: "40P01"}, ErrConflict},
+		{sqlite3.Error{Code: sqlite3.ErrBusy}, ErrConflict},
+		{driver.ErrBadConn, ErrUnavailable},
+		{gorm.Errors{ErrUnavailable}, ErrUnavailable},
+		{other, other},
+	}
+	for _, c := range cases {
+		if translateError(c.err) != c.expected {
+			t.Errorf("Incorrect TestTranslateError test. err = %v", c.err)
+			t.FailNow()
+		}
+	}
+}
+
+// Errors of PostgreSQL are translated test
+func TestConstraintViolation_Postgres(t *testing.T) {
+	db := openTestConnection()
+	defer db.Close()
+	if db.Dialect().GetName() != EnginePostgres {
+		t.Skip("TEST_POSTGRES_DSN is not set")
+	}
+
+	statements := []string{
+		"CREATE TEMPORARY TABLE test_parents (uuid varchar(128) NOT NULL, UNIQUE (uuid))",
+		"CREATE TEMPORARY TABLE test_children (parent_uuid varchar(128) NOT NULL REFERENCES test_parents (uuid))",
+		"INSERT INTO test_parents (uuid) VALUES ('parent')",
+	}
+	tx := db.Begin()
+	defer tx.Rollback()
+	for _, statement := range statements {
+		if err := tx.Exec(statement).Error; err != nil {
+			t.Errorf("Incorrect TestConstraintViolation_Postgres test. err = %v", err)
+			t.FailNow()
+		}
+	}
+
+	if err := tx.Exec("SAVEPOINT violation").Error; err != nil {
+		t.Errorf("Incorrect TestConstraintViolation_Postgres test. err = %v", err)
+		t.FailNow()
+	}
+	if err := tx.Exec("INSERT INTO test_parents (uuid) VALUES ('parent')").Error; !IsUniqueViolation(err) {
+		t.Errorf("Incorrect TestConstraintViolation_Postgres test. err = %v", err)
+		t.FailNow()
+	}
+	tx.Exec("ROLLBACK TO SAVEPOINT violation")
+	if err := tx.Exec("INSERT INTO test_children (parent_uuid) VALUES ('none')").Error; !IsForeignKeyViolation(err) {
+		t.Errorf("Incorrect TestConstraintViolation_Postgres test. err = %v", err)
+		t.FailNow()
+	}
+}
@@ -1,7 +1,10 @@
 package driver
 
 import (
+	"context"
 	"fmt"
+	"time"
+
 	"github.com/jinzhu/gorm"
 
 	"github.com/tomoyane/grant-n-z/gnz/entity"
@@ -13,33 +16,36 @@ var grInstance GroupRepository
 
 type GroupRepository interface {
 	// Get groups all data
-	FindAll() ([]*entity.Group, error)
+	FindAll(ctx context.Context) ([]*entity.Group, error)
 
 	// Get group by uuid
-	FindByUuid(uuid string) (*entity.Group, error)
+	FindByUuid(ctx context.Context, uuid string) (*entity.Group, error)
 
 	// Get group from groups.name
-	FindByName(name string) (*entity.Group, error)
+	FindByName(ctx context.Context, name string) (*entity.Group, error)
 
 	// Get all groups by user uuid
 	// Join user_groups and groups
-	FindByUserUuid(userUuid string) ([]*entity.Group, error)
+	// Keyset pagination, filters and sort of page. Next cursor is empty if it is the last page
+	FindByUserUuid(ctx context.Context, userUuid string, page Page) ([]*entity.Group, string, error)
 
 	// Get all groups by service uuid
 	// Join service_groups and groups
-	FindByServiceUuid(serviceUuid string) ([]*entity.Group, error)
+	// Keyset pagination, filters and sort of page. Next cursor is empty if it is the last page
+	FindByServiceUuid(ctx context.Context, serviceUuid string, page Page) ([]*entity.Group, string, error)
 
 	// Get all groups with user_groups with policy that has user
 	// Join user_groups and groups and polices
-	FindGroupWithUserWithPolicyGroupsByUserUuid(userUuid string) ([]*model.GroupWithUserGroupWithPolicy, error)
+	FindGroupWithUserWithPolicyGroupsByUserUuid(ctx context.Context, userUuid string) ([]*model.GroupWithUserGroupWithPolicy, error)
 
 	// Get user_groups with policies by user id and group id
 	// Join user_groups and groups and polices
-	FindGroupWithPolicyByUserUuidAndGroupUuid(userUuid string, groupUuid string) (*model.GroupWithUserGroupWithPolicy, error)
+	FindGroupWithPolicyByUserUuidAndGroupUuid(ctx context.Context, userUuid string, groupUuid string) (*model.GroupWithUserGroupWithPolicy, error)
 
 	// Generate groups, user_groups, service_groups
 	// Transaction mode
 	SaveWithRelationalData(
+		ctx context.Context,
 		group entity.Group,
 		serviceGroup entity.ServiceGroup,
 		userGroup entity.UserGroup,
@@ -68,37 +74,60 @@ func NewGroupRepository() GroupRepository {
 	return GroupRepositoryImpl{Connection: connection}
 }
 
-func (gr GroupRepositoryImpl) FindAll() ([]*entity.Group, error) {
+func (gr GroupRepositoryImpl) FindAll(ctx context.Context) ([]*entity.Group, error) {
+	db, cancel := withReadContext(ctx, gr.Connection)
+	defer cancel()
+
 	var groups []*entity.Group
-	if err := gr.Connection.Find(&groups).Error; err != nil {
-		return nil, err
+	if err := db.Find(&groups).Error; err != nil {
+		return nil, translateError(err)
 	}
 
 	return groups, nil
 }
 
-func (gr GroupRepositoryImpl) FindByUuid(uuid string) (*entity.Group, error) {
+func (gr GroupRepositoryImpl) FindByUuid(ctx context.Context, uuid string) (*entity.Group, error) {
+	db, cancel := withReadContext(ctx, gr.Connection)
+	defer cancel()
+
 	var group entity.Group
-	if err := gr.Connection.Where("uuid = ?", uuid).Find(&group).Error; err != nil {
-		return nil, err
+	db = groupTenant.scope(ctx, db, entity.GroupTable.String()+"."+entity.GroupUuid.String())
+	if err := db.Where("uuid = ?", uuid).Find(&group).Error; err != nil {
+		return nil, translateError(err)
 	}
 
 	return &group, nil
 }
 
-func (gr GroupRepositoryImpl) FindByName(name string) (*entity.Group, error) {
+func (gr GroupRepositoryImpl) FindByName(ctx context.Context, name string) (*entity.Group, error) {
+	db, cancel := withReadContext(ctx, gr.Connection)
+	defer cancel()
+
 	var group *entity.Group
-	if err := gr.Connection.Where("name = ?", name).Find(&group).Error; err != nil {
-		return nil, err
+	if err := db.Where("name = ?", name).Find(&group).Error; err != nil {
+		return nil, translateError(err)
 	}
 
 	return group, nil
 }
 
-func (gr GroupRepositoryImpl) FindByUserUuid(userUuid string) ([]*entity.Group, error) {
+func (gr GroupRepositoryImpl) FindByUserUuid(ctx context.Context, userUuid string, page Page) ([]*entity.Group, string, error) {
+	db, cancel := withReadContext(ctx, gr.Connection)
+	defer cancel()
+
+	db = groupTenant.scope(ctx, db, entity.GroupTable.String()+"."+entity.GroupUuid.String())
+	query, err := page.apply(db, entity.GroupTable.String(), entity.GroupName.String())
+	if err != nil {
+		return nil, "", err
+	}
+
 	var groups []*entity.Group
 
 	target := entity.GroupTable.String() + "." +
+		entity.GroupId.String() + "," +
+		entity.GroupTable.String() + "." +
+		entity.GroupInternalId.String() + "," +
+		entity.GroupTable.String() + "." +
 		entity.GroupUuid.String() + "," +
 		entity.GroupTable.String() + "." +
 		entity.GroupName.String() + "," +
@@ -107,7 +136,7 @@ func (gr GroupRepositoryImpl) FindByUserUuid(userUuid string) ([]*entity.Group,
 		entity.GroupTable.String() + "." +
 		entity.GroupUpdatedAt.String()
 
-	if err := gr.Connection.Table(entity.UserGroupTable.String()).
+	if err := query.Table(entity.UserGroupTable.String()).
 		Select(target).
 		Joins(fmt.Sprintf("LEFT JOIN %s ON %s.%s = %s.%s",
 			entity.GroupTable.String(),
@@ -115,18 +144,31 @@ func (gr GroupRepositoryImpl) FindByUserUuid(userUuid string) ([]*entity.Group,
 			entity.UserGroupGroupUuid.String(),
 			entity.GroupTable.String(),
 			entity.GroupUuid.String())).
+		Where(notDeleted(entity.UserGroupTable.String(), entity.GroupTable.String())).
 		Where(fmt.Sprintf("%s.%s = ?",
 			entity.UserGroupTable.String(),
 			entity.UserGroupUserUuid.String()), userUuid).
 		Scan(&groups).Error; err != nil {
 
-		return nil, err
+		return nil, "", translateError(err)
 	}
 
-	return groups, nil
+	size, next := page.nextCursor(len(groups), func(i int) (int, time.Time, string) {
+		return groups[i].Id, groups[i].CreatedAt, groups[i].Name
+	})
+	return groups[:size], next, nil
 }
 
-func (gr GroupRepositoryImpl) FindByServiceUuid(serviceUuid string) ([]*entity.Group, error) {
+func (gr GroupRepositoryImpl) FindByServiceUuid(ctx context.Context, serviceUuid string, page Page) ([]*entity.Group, string, error) {
+	db, cancel := withReadContext(ctx, gr.Connection)
+	defer cancel()
+
+	db = groupTenant.scope(ctx, db, entity.GroupTable.String()+"."+entity.GroupUuid.String())
+	query, err := page.apply(db, entity.GroupTable.String(), entity.GroupName.String())
+	if err != nil {
+		return nil, "", err
+	}
+
 	var groups []*entity.Group
 
 	target := entity.GroupTable.String() + "." +
@@ -142,7 +184,7 @@ func (gr GroupRepositoryImpl) FindByServiceUuid(serviceUuid string) ([]*entity.G
 		entity.GroupTable.String() + "." +
 		entity.GroupUpdatedAt.String()
 
-	if err := gr.Connection.Table(entity.ServiceGroupTable.String()).
+	if err := query.Table(entity.ServiceGroupTable.String()).
 		Select(target).
 		Joins(fmt.Sprintf("LEFT JOIN %s ON %s.%s = %s.%s",
 			entity.GroupTable.String(),
@@ -150,21 +192,29 @@ func (gr GroupRepositoryImpl) FindByServiceUuid(serviceUuid string) ([]*entity.G
 			entity.GroupUuid.String(),
 			entity.ServiceGroupTable.String(),
 			entity.ServiceGroupGroupUuid.String())).
+		Where(notDeleted(entity.ServiceGroupTable.String(), entity.GroupTable.String())).
 		Where(fmt.Sprintf("%s.%s = ?",
 			entity.ServiceGroupTable.String(),
 			entity.ServiceGroupServiceUuid.String()), serviceUuid).
 		Scan(&groups).Error; err != nil {
 
-		return nil, err
+		return nil, "", translateError(err)
 	}
 
-	return groups, nil
+	size, next := page.nextCursor(len(groups), func(i int) (int, time.Time, string) {
+		return groups[i].Id, groups[i].CreatedAt, groups[i].Name
+	})
+	return groups[:size], next, nil
 }
 
-func (gr GroupRepositoryImpl) FindGroupWithUserWithPolicyGroupsByUserUuid(userUuid string) ([]*model.GroupWithUserGroupWithPolicy, error) {
+func (gr GroupRepositoryImpl) FindGroupWithUserWithPolicyGroupsByUserUuid(ctx context.Context, userUuid string) ([]*model.GroupWithUserGroupWithPolicy, error) {
+	db, cancel := withReadContext(ctx, gr.Connection)
+	defer cancel()
+
 	var groupWithUserGroupWithPolicies []*model.GroupWithUserGroupWithPolicy
 
-	if err := gr.Connection.Table(entity.UserGroupTable.String()).
+	db = groupTenant.scope(ctx, db, entity.UserGroupTable.String()+"."+entity.UserGroupGroupUuid.String())
+	if err := db.Table(entity.UserGroupTable.String()).
 		Select("*").
 		Joins(fmt.Sprintf("LEFT JOIN %s ON %s.%s = %s.%s",
 			entity.GroupTable.String(),
@@ -172,27 +222,33 @@ func (gr GroupRepositoryImpl) FindGroupWithUserWithPolicyGroupsByUserUuid(userUu
 			entity.UserGroupGroupUuid.String(),
 			entity.GroupTable.String(),
 			entity.GroupUuid.String())).
-		Joins(fmt.Sprintf("LEFT JOIN %s ON %s.%s = %s.%s",
+		Joins(fmt.Sprintf("LEFT JOIN %s ON %s.%s = %s.%s AND %s",
 			entity.PolicyTable.String(),
 			entity.UserGroupTable.String(),
 			entity.UserGroupUuid.String(),
 			entity.PolicyTable.String(),
-			entity.PolicyUserGroupUuid.String())).
+			entity.PolicyUserGroupUuid.String(),
+			notDeleted(entity.PolicyTable.String()))).
+		Where(notDeleted(entity.UserGroupTable.String(), entity.GroupTable.String())).
 		Where(fmt.Sprintf("%s.%s = ?",
 			entity.UserGroupTable.String(),
 			entity.UserGroupUserUuid.String()), userUuid).
 		Scan(&groupWithUserGroupWithPolicies).Error; err != nil {
 
-		return nil, err
+		return nil, translateError(err)
 	}
 
 	return groupWithUserGroupWithPolicies, nil
 }
 
-func (gr GroupRepositoryImpl) FindGroupWithPolicyByUserUuidAndGroupUuid(userUuid string, groupUuid string) (*model.GroupWithUserGroupWithPolicy, error) {
+func (gr GroupRepositoryImpl) FindGroupWithPolicyByUserUuidAndGroupUuid(ctx context.Context, userUuid string, groupUuid string) (*model.GroupWithUserGroupWithPolicy, error) {
+	db, cancel := withReadContext(ctx, gr.Connection)
+	defer cancel()
+
 	var groupWithUserGroupWithPolicy model.GroupWithUserGroupWithPolicy
 
-	if err := gr.Connection.Table(entity.UserGroupTable.String()).
+	db = groupTenant.scope(ctx, db, entity.UserGroupTable.String()+"."+entity.UserGroupGroupUuid.String())
+	if err := db.Table(entity.UserGroupTable.String()).
 		Select("*").
 		Joins(fmt.Sprintf("LEFT JOIN %s ON %s.%s = %s.%s",
 			entity.GroupTable.String(),
@@ -200,12 +256,14 @@ func (gr GroupRepositoryImpl) FindGroupWithPolicyByUserUuidAndGroupUuid(userUuid
 			entity.UserGroupGroupUuid.String(),
 			entity.GroupTable.String(),
 			entity.GroupUuid.String())).
-		Joins(fmt.Sprintf("LEFT JOIN %s ON %s.%s = %s.%s",
+		Joins(fmt.Sprintf("LEFT JOIN %s ON %s.%s = %s.%s AND %s",
 			entity.PolicyTable.String(),
 			entity.UserGroupTable.String(),
 			entity.UserGroupUuid.String(),
 			entity.PolicyTable.String(),
-			entity.PolicyUserGroupUuid.String())).
+			entity.PolicyUserGroupUuid.String(),
+			notDeleted(entity.PolicyTable.String()))).
+		Where(notDeleted(entity.UserGroupTable.String(), entity.GroupTable.String())).
 		Where(fmt.Sprintf("%s.%s = ?",
 			entity.UserGroupTable.String(),
 			entity.UserGroupUserUuid.String()), userUuid).
@@ -214,7 +272,7 @@ func (gr GroupRepositoryImpl) FindGroupWithPolicyByUserUuidAndGroupUuid(userUuid
 			entity.UserGroupGroupUuid.String()), groupUuid).
 		Scan(&groupWithUserGroupWithPolicy).Error; err != nil {
 
-		return nil, err
+		return nil, translateError(err)
 	}
 
 	return &groupWithUserGroupWithPolicy, nil
@@ -222,6 +280,7 @@ func (gr GroupRepositoryImpl) FindGroupWithPolicyByUserUuidAndGroupUuid(userUuid
 }
 
 func (gr GroupRepositoryImpl) SaveWithRelationalData(
+	ctx context.Context,
 	group entity.Group,
 	serviceGroup entity.ServiceGroup,
 	userGroup entity.UserGroup,
@@ -229,50 +288,65 @@ func (gr GroupRepositoryImpl) SaveWithRelationalData(
 	groupRole entity.GroupRole,
 	policy entity.Policy) (*entity.Group, error) {
 
-	tx := gr.Connection.Begin()
+	db, cancel := withContext(ctx, gr.Connection)
+	defer cancel()
+
+	if err := checkTenantService(ctx, serviceGroup.ServiceUuid.String()); err != nil {
+		return nil, err
+	}
+	if err := checkTenantService(ctx, policy.ServiceUuid.String()); err != nil {
+		return nil, err
+	}
+	if err := userTenant.check(ctx, db, entity.UserTable.String(), entity.UserUuid.String(), userGroup.UserUuid.String()); err != nil {
+		return nil, err
+	}
+
+	tx := db.Begin()
 
 	// Save groups
 	if err := tx.Create(&group).Error; err != nil {
 		tx.Rollback()
-		return nil, err
+		return nil, translateError(err)
 	}
 
 	// Save service_groups
 	serviceGroup.GroupUuid = group.Uuid
 	if err := tx.Create(&serviceGroup).Error; err != nil {
 		tx.Rollback()
-		return nil, err
+		return nil, translateError(err)
 	}
 
 	// Save user_groups
 	userGroup.GroupUuid = group.Uuid
 	if err := tx.Create(&userGroup).Error; err != nil {
 		tx.Rollback()
-		return nil, err
+		return nil, translateError(err)
 	}
 
 	// Save group_roles
 	groupRole.GroupUuid = group.Uuid
 	if err := tx.Create(&groupRole).Error; err != nil {
 		tx.Rollback()
-		return nil, err
+		return nil, translateError(err)
 	}
 
 	// Save group_permissions
 	groupPermission.GroupUuid = group.Uuid
 	if err := tx.Create(&groupPermission).Error; err != nil {
 		tx.Rollback()
-		return nil, err
+		return nil, translateError(err)
 	}
 
 	// Save policies
 	policy.UserGroupUuid = userGroup.Uuid
 	if err := tx.Create(&policy).Error; err != nil {
 		tx.Rollback()
-		return nil, err
+		return nil, translateError(err)
 	}
 
-	tx.Commit()
+	if err := tx.Commit().Error; err != nil {
+		return nil, translateError(err)
+	}
 
 	return &group, nil
 }
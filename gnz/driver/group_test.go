@@ -1,11 +1,10 @@
 package driver
 
 import (
+	"context"
 	"testing"
 
 	"github.com/google/uuid"
-	"github.com/jinzhu/gorm"
-	_ "github.com/jinzhu/gorm/dialects/sqlite"
 	"github.com/tomoyane/grant-n-z/gnz/entity"
 	"github.com/tomoyane/grant-n-z/gnz/log"
 )
@@ -16,14 +15,13 @@ var groupRepository GroupRepository
 func init() {
 	log.InitLogger("info")
 
-	stubConnection, _ := gorm.Open("sqlite3", "/tmp/test_grant_nz.db")
-	connection = stubConnection
+	connection = openTestConnection()
 	groupRepository = GetGroupRepositoryInstance()
 }
 
 // FindAll InternalServerError test
 func TestGroupFindAll_Error(t *testing.T) {
-	_, err := groupRepository.FindAll()
+	_, err := groupRepository.FindAll(context.Background())
 	if err == nil {
 		t.Errorf("Incorrect TestGroupFindAll_Error test")
 		t.FailNow()
@@ -32,7 +30,7 @@ func TestGroupFindAll_Error(t *testing.T) {
 
 // FindByUuid InternalServerError test
 func TestGroupFindById_Error(t *testing.T) {
-	_, err := groupRepository.FindByUuid(uuid.New().String())
+	_, err := groupRepository.FindByUuid(context.Background(), uuid.New().String())
 	if err == nil {
 		t.Errorf("Incorrect TestGroupFindById_Error test")
 		t.FailNow()
@@ -41,7 +39,7 @@ func TestGroupFindById_Error(t *testing.T) {
 
 // FindByName InternalServerError test
 func TestGroupFindByName_Error(t *testing.T) {
-	_, err := groupRepository.FindByName("name")
+	_, err := groupRepository.FindByName(context.Background(), "name")
 	if err == nil {
 		t.Errorf("Incorrect TestGroupFindByName_Error test")
 		t.FailNow()
@@ -50,7 +48,7 @@ func TestGroupFindByName_Error(t *testing.T) {
 
 // FindByUserUuid InternalServerError test
 func TestGroupFindGroupsByUserId_Error(t *testing.T) {
-	_, err := groupRepository.FindByUserUuid("uuid")
+	_, _, err := groupRepository.FindByUserUuid(context.Background(), "uuid", Page{})
 	if err == nil {
 		t.Errorf("Incorrect TestGroupFindGroupsByUserId_Error test")
 		t.FailNow()
@@ -59,7 +57,7 @@ func TestGroupFindGroupsByUserId_Error(t *testing.T) {
 
 // FindByServiceUuid InternalServerError test
 func TestFindByServiceUuid_Error(t *testing.T) {
-	_, err := groupRepository.FindByServiceUuid("uuid")
+	_, _, err := groupRepository.FindByServiceUuid(context.Background(), "uuid", Page{})
 	if err == nil {
 		t.Errorf("Incorrect TestFindByServiceUuid_Error test")
 		t.FailNow()
@@ -68,7 +66,7 @@ func TestFindByServiceUuid_Error(t *testing.T) {
 
 // FindGroupWithUserWithPolicyGroupsByUserUuid InternalServerError test
 func TestGroupFindGroupWithUserWithPolicyGroupsByUserId_Error(t *testing.T) {
-	_, err := groupRepository.FindGroupWithUserWithPolicyGroupsByUserUuid("uuid")
+	_, err := groupRepository.FindGroupWithUserWithPolicyGroupsByUserUuid(context.Background(), "uuid")
 	if err == nil {
 		t.Errorf("Incorrect TestGroupFindGroupWithUserWithPolicyGroupsByUserId_Error test")
 		t.FailNow()
@@ -77,7 +75,7 @@ func TestGroupFindGroupWithUserWithPolicyGroupsByUserId_Error(t *testing.T) {
 
 // FindGroupWithUserWithPolicyGroupsByUserUuid InternalServerError test
 func TestGroupFindGroupWithPolicyByUserIdAndGroupId_Error(t *testing.T) {
-	_, err := groupRepository.FindGroupWithPolicyByUserUuidAndGroupUuid("uuid", "uuid")
+	_, err := groupRepository.FindGroupWithPolicyByUserUuidAndGroupUuid(context.Background(), "uuid", "uuid")
 	if err == nil {
 		t.Errorf("Incorrect TestGroupFindGroupWithPolicyByUserIdAndGroupId_Error test")
 		t.FailNow()
@@ -87,6 +85,7 @@ func TestGroupFindGroupWithPolicyByUserIdAndGroupId_Error(t *testing.T) {
 // SaveWithRelationalData InternalServerError test
 func TestGroupSaveWithRelationalData_Error(t *testing.T) {
 	_, err := groupRepository.SaveWithRelationalData(
+		context.Background(),
 		entity.Group{},
 		entity.ServiceGroup{},
 		entity.UserGroup{},
@@ -0,0 +1,404 @@
+package driver
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jinzhu/gorm"
+
+	"github.com/tomoyane/grant-n-z/gnz/log"
+)
+
+// Table of applied migrations
+const schemaMigrationsTable = "schema_migrations"
+
+// The same ddl works in all engines
+const schemaMigrationsDdl = `
+CREATE TABLE IF NOT EXISTS schema_migrations (
+  version int NOT NULL,
+  name varchar(128) NOT NULL,
+  checksum varchar(64) NOT NULL,
+  applied_at timestamp NOT NULL,
+  PRIMARY KEY (version)
+)`
+
+// State of migration in status
+const (
+	// Migration is applied with the same checksum
+	MigrationApplied = "applied"
+
+	// Migration is not applied yet
+	MigrationPending = "pending"
+
+	// Migration was applied, but sql of it was changed after that
+	MigrationModified = "modified"
+
+	// Migration was applied by newer binary, and this binary does not know it
+	MigrationUnknown = "unknown"
+)
+
+// Ordered schema migrations of all engines
+// Applied migration must not be changed, because its checksum is verified. Add new version instead
+var Migrations = []Migration{
+	{
+		Version: 1,
+		Name:    "create_tables",
+		Up: map[string]string{
+			EngineMysql:    mysqlV1Up,
+			EnginePostgres: postgresV1Up,
+			EngineSqlite:   sqliteV1Up,
+		},
+		Down: map[string]string{
+			EngineMysql:    v1Down,
+			EnginePostgres: v1Down,
+			EngineSqlite:   v1Down,
+		},
+	},
+	{
+		Version: 2,
+		Name:    "soft_delete",
+		Up: map[string]string{
+			EngineMysql:    mysqlV2Up,
+			EnginePostgres: postgresV2Up,
+			EngineSqlite:   sqliteV2Up,
+		},
+		// Sqlite of go-sqlite3 does not support DROP COLUMN, and its database is created at start
+		Down: map[string]string{
+			EngineMysql:    v2Down,
+			EnginePostgres: v2Down,
+		},
+	},
+	{
+		Version: 3,
+		Name:    "row_version",
+		Up: map[string]string{
+			EngineMysql:    mysqlV3Up,
+			EnginePostgres: postgresV3Up,
+			EngineSqlite:   sqliteV3Up,
+		},
+		Down: map[string]string{
+			EngineMysql:    mysqlV3Down,
+			EnginePostgres: postgresV3Down,
+		},
+	},
+}
+
+const v1Down = `
+DROP TABLE IF EXISTS policies;
+DROP TABLE IF EXISTS operator_policies;
+DROP TABLE IF EXISTS group_permissions;
+DROP TABLE IF EXISTS group_roles;
+DROP TABLE IF EXISTS service_permissions;
+DROP TABLE IF EXISTS service_roles;
+DROP TABLE IF EXISTS service_groups;
+DROP TABLE IF EXISTS user_groups;
+DROP TABLE IF EXISTS user_services;
+DROP TABLE IF EXISTS roles;
+DROP TABLE IF EXISTS groups;
+DROP TABLE IF EXISTS permissions;
+DROP TABLE IF EXISTS users;
+DROP TABLE IF EXISTS services;
+`
+
+const v2Down = `
+ALTER TABLE policies DROP COLUMN deleted_at;
+ALTER TABLE operator_policies DROP COLUMN deleted_at;
+ALTER TABLE group_permissions DROP COLUMN deleted_at;
+ALTER TABLE group_roles DROP COLUMN deleted_at;
+ALTER TABLE service_permissions DROP COLUMN deleted_at;
+ALTER TABLE service_roles DROP COLUMN deleted_at;
+ALTER TABLE service_groups DROP COLUMN deleted_at;
+ALTER TABLE user_groups DROP COLUMN deleted_at;
+ALTER TABLE user_services DROP COLUMN deleted_at;
+ALTER TABLE roles DROP COLUMN deleted_at;
+ALTER TABLE groups DROP COLUMN deleted_at;
+ALTER TABLE permissions DROP COLUMN deleted_at;
+ALTER TABLE users DROP COLUMN deleted_at;
+ALTER TABLE services DROP COLUMN deleted_at;
+`
+
+// Table of the first migration
+// Database that has it without schema_migrations was created by schema/database.sql, that is version 1
+const baselineTable = "users"
+
+var mInstance Migrator
+
+// Schema migration of one version
+// Up and Down have sql statements of each engine, that are separated by semicolon
+type Migration struct {
+	Version int
+	Name    string
+	Up      map[string]string
+	Down    map[string]string
+}
+
+// Status of one migration
+type MigrationStatus struct {
+	Version   int        `json:"version"`
+	Name      string     `json:"name"`
+	State     string     `json:"state"`
+	Checksum  string     `json:"checksum"`
+	AppliedAt *time.Time `json:"applied_at,omitempty"`
+}
+
+// Row of schema_migrations
+type schemaMigration struct {
+	Version   int
+	Name      string
+	Checksum  string
+	AppliedAt time.Time
+}
+
+type Migrator interface {
+	// Apply all pending migrations in order
+	// It fails without applying anything if modified or unknown migration was applied
+	Up(ctx context.Context) ([]MigrationStatus, error)
+
+	// Revert applied migrations from the latest
+	Down(ctx context.Context, steps int) ([]MigrationStatus, error)
+
+	// Get status of embedded and applied migrations in order of version
+	Status(ctx context.Context) ([]MigrationStatus, error)
+
+	// Check all embedded migrations are applied and no other migrations are applied
+	Check(ctx context.Context) error
+}
+
+type MigratorImpl struct {
+	Connection *gorm.DB
+	Migrations []Migration
+}
+
+func GetMigratorInstance() Migrator {
+	if mInstance == nil {
+		mInstance = NewMigrator()
+	}
+	return mInstance
+}
+
+// Constructor
+func NewMigrator() Migrator {
+	log.Logger.Info("New `Migrator` instance")
+	return MigratorImpl{
+		Connection: connection,
+		Migrations: Migrations,
+	}
+}
+
+func (m MigratorImpl) Up(ctx context.Context) ([]MigrationStatus, error) {
+	db := withCancel(ctx, m.Connection)
+	if err := m.prepare(db); err != nil {
+		return nil, err
+	}
+	statuses, err := m.status(db)
+	if err != nil {
+		return nil, err
+	}
+	if err := verifyStatuses(statuses); err != nil {
+		return statuses, err
+	}
+
+	for _, migration := range m.Migrations {
+		if stateOf(statuses, migration.Version) != MigrationPending {
+			continue
+		}
+		log.Logger.Info(fmt.Sprintf("Apply migration. version = %d, name = %s", migration.Version, migration.Name))
+		err := m.transaction(db, migration.Up, func(tx *gorm.DB) error {
+			return tx.Exec("INSERT INTO schema_migrations (version, name, checksum, applied_at) VALUES (?, ?, ?, ?)",
+				migration.Version, migration.Name, m.checksum(migration), time.Now().UTC()).Error
+		})
+		if err != nil {
+			return statuses, errors.New(fmt.Sprintf("Failed to apply migration. version = %d. %s", migration.Version, err.Error()))
+		}
+	}
+	return m.status(db)
+}
+
+func (m MigratorImpl) Down(ctx context.Context, steps int) ([]MigrationStatus, error) {
+	db := withCancel(ctx, m.Connection)
+	if err := m.prepare(db); err != nil {
+		return nil, err
+	}
+	statuses, err := m.status(db)
+	if err != nil {
+		return nil, err
+	}
+	if err := verifyStatuses(statuses); err != nil {
+		return statuses, err
+	}
+
+	for i := len(m.Migrations) - 1; i >= 0 && steps > 0; i-- {
+		migration := m.Migrations[i]
+		if stateOf(statuses, migration.Version) != MigrationApplied {
+			continue
+		}
+		log.Logger.Info(fmt.Sprintf("Revert migration. version = %d, name = %s", migration.Version, migration.Name))
+		err := m.transaction(db, migration.Down, func(tx *gorm.DB) error {
+			return tx.Exec("DELETE FROM schema_migrations WHERE version = ?", migration.Version).Error
+		})
+		if err != nil {
+			return statuses, errors.New(fmt.Sprintf("Failed to revert migration. version = %d. %s", migration.Version, err.Error()))
+		}
+		steps--
+	}
+	return m.status(db)
+}
+
+func (m MigratorImpl) Status(ctx context.Context) ([]MigrationStatus, error) {
+	return m.status(withCancel(ctx, m.Connection))
+}
+
+func (m MigratorImpl) Check(ctx context.Context) error {
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		return err
+	}
+	if err := verifyStatuses(statuses); err != nil {
+		return err
+	}
+	for _, status := range statuses {
+		if status.State == MigrationPending {
+			return errors.New(fmt.Sprintf("Schema is older than this binary. Run migrate up. pending version = %d", status.Version))
+		}
+	}
+	return nil
+}
+
+// Create schema_migrations
+// If tables of version 1 exist without it, version 1 is recorded as applied
+func (m MigratorImpl) prepare(db *gorm.DB) error {
+	if db.HasTable(schemaMigrationsTable) {
+		return nil
+	}
+	baseline := db.HasTable(baselineTable)
+	if err := db.Exec(schemaMigrationsDdl).Error; err != nil {
+		return err
+	}
+	if !baseline || len(m.Migrations) == 0 {
+		return nil
+	}
+
+	migration := m.Migrations[0]
+	log.Logger.Info(fmt.Sprintf("Record existing schema as migration. version = %d, name = %s", migration.Version, migration.Name))
+	return db.Exec("INSERT INTO schema_migrations (version, name, checksum, applied_at) VALUES (?, ?, ?, ?)",
+		migration.Version, migration.Name, m.checksum(migration), time.Now().UTC()).Error
+}
+
+// Status of migrations
+// Without schema_migrations, all migrations are pending
+func (m MigratorImpl) status(db *gorm.DB) ([]MigrationStatus, error) {
+	var applied []schemaMigration
+	if db.HasTable(schemaMigrationsTable) {
+		if err := db.Raw("SELECT version, name, checksum, applied_at FROM schema_migrations ORDER BY version").Scan(&applied).Error; err != nil {
+			return nil, err
+		}
+	}
+	appliedMap := make(map[int]schemaMigration)
+	for _, row := range applied {
+		appliedMap[row.Version] = row
+	}
+
+	var statuses []MigrationStatus
+	known := make(map[int]bool)
+	for _, migration := range m.Migrations {
+		known[migration.Version] = true
+		status := MigrationStatus{
+			Version:  migration.Version,
+			Name:     migration.Name,
+			State:    MigrationPending,
+			Checksum: m.checksum(migration),
+		}
+		if row, ok := appliedMap[migration.Version]; ok {
+			appliedAt := row.AppliedAt
+			status.AppliedAt = &appliedAt
+			status.State = MigrationApplied
+			if row.Checksum != status.Checksum {
+				status.State = MigrationModified
+			}
+		}
+		statuses = append(statuses, status)
+	}
+
+	for _, row := range applied {
+		if known[row.Version] {
+			continue
+		}
+		appliedAt := row.AppliedAt
+		statuses = append(statuses, MigrationStatus{
+			Version:   row.Version,
+			Name:      row.Name,
+			State:     MigrationUnknown,
+			Checksum:  row.Checksum,
+			AppliedAt: &appliedAt,
+		})
+	}
+	return statuses, nil
+}
+
+// Run sql of migration and record in one transaction
+// DDL of mysql is committed implicitly, so failed migration of mysql may be applied partially
+func (m MigratorImpl) transaction(db *gorm.DB, sqls map[string]string, record func(tx *gorm.DB) error) error {
+	sql, ok := sqls[db.Dialect().GetName()]
+	if !ok {
+		return errors.New(fmt.Sprintf("Not found migration of db engine. engine = %s", db.Dialect().GetName()))
+	}
+
+	tx := db.Begin()
+	if tx.Error != nil {
+		return tx.Error
+	}
+	for _, statement := range splitStatements(sql) {
+		if err := tx.Exec(statement).Error; err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	if err := record(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit().Error
+}
+
+// Checksum of up sql of the engine of connection
+func (m MigratorImpl) checksum(migration Migration) string {
+	sum := sha256.Sum256([]byte(strings.TrimSpace(migration.Up[m.Connection.Dialect().GetName()])))
+	return hex.EncodeToString(sum[:])
+}
+
+// Error if modified or unknown migration was applied
+func verifyStatuses(statuses []MigrationStatus) error {
+	for _, status := range statuses {
+		switch status.State {
+		case MigrationModified:
+			return errors.New(fmt.Sprintf("Applied migration was modified. version = %d, name = %s", status.Version, status.Name))
+		case MigrationUnknown:
+			return errors.New(fmt.Sprintf("Unknown schema version. Schema is newer than this binary. version = %d, name = %s", status.Version, status.Name))
+		}
+	}
+	return nil
+}
+
+func stateOf(statuses []MigrationStatus, version int) string {
+	for _, status := range statuses {
+		if status.Version == version {
+			return status.State
+		}
+	}
+	return ""
+}
+
+func splitStatements(sql string) []string {
+	var statements []string
+	for _, statement := range strings.Split(sql, ";") {
+		if strings.TrimSpace(statement) != "" {
+			statements = append(statements, statement)
+		}
+	}
+	return statements
+}
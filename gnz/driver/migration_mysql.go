@@ -0,0 +1,293 @@
+package driver
+
+// Version 1 of mysql, that is the same as schema/database.sql
+const mysqlV1Up = `
+CREATE TABLE services (
+  id int(11) NOT NULL AUTO_INCREMENT,
+  internal_id varchar(32) NOT NULL,
+  uuid varchar(128) NOT NULL,
+  name varchar(128) NOT NULL,
+  secret varchar(128) NOT NULL,
+  created_at datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (id),
+  UNIQUE (name),
+  UNIQUE (uuid)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+
+CREATE TABLE users (
+  id int(11) NOT NULL AUTO_INCREMENT,
+  internal_id varchar(32) NOT NULL,
+  uuid varchar(128) NOT NULL,
+  username varchar(128) NOT NULL,
+  email varchar(128) NOT NULL,
+  password varchar(128) NOT NULL,
+  created_at datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (id),
+  UNIQUE (email),
+  UNIQUE (uuid)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+
+CREATE TABLE permissions (
+  id int(11) NOT NULL AUTO_INCREMENT,
+  internal_id varchar(32) NOT NULL,
+  uuid varchar(128) NOT NULL,
+  name varchar(128) NOT NULL,
+  created_at datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (id),
+  UNIQUE (name),
+  UNIQUE (uuid)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+
+CREATE TABLE groups (
+  id int(11) NOT NULL AUTO_INCREMENT,
+  internal_id varchar(32) NOT NULL,
+  uuid varchar(128) NOT NULL,
+  name varchar(128) NOT NULL,
+  created_at datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (id),
+  UNIQUE (uuid)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+
+CREATE TABLE roles (
+  id int(11) NOT NULL AUTO_INCREMENT,
+  internal_id varchar(32) NOT NULL,
+  uuid varchar(128) NOT NULL,
+  name varchar(128) NOT NULL,
+  created_at datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (id),
+  UNIQUE (name),
+  UNIQUE (uuid)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+
+CREATE TABLE user_services (
+  id int(11) NOT NULL AUTO_INCREMENT,
+  internal_id varchar(32) NOT NULL,
+  user_uuid varchar(128) NOT NULL,
+  service_uuid varchar(128) NOT NULL,
+  created_at datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (id),
+  INDEX (user_uuid),
+  INDEX (service_uuid),
+  CONSTRAINT fk_user_services_user_uuid
+  FOREIGN KEY (user_uuid)
+  REFERENCES users (uuid)
+  ON DELETE RESTRICT ON UPDATE RESTRICT,
+  CONSTRAINT fk_user_services_service_uuid
+  FOREIGN KEY (service_uuid)
+  REFERENCES services (uuid)
+  ON DELETE RESTRICT ON UPDATE RESTRICT
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+
+CREATE TABLE user_groups (
+  id int(11) NOT NULL AUTO_INCREMENT,
+  internal_id varchar(32) NOT NULL,
+  uuid varchar(128) NOT NULL,
+  user_uuid varchar(128) NOT NULL,
+  group_uuid varchar(128) NOT NULL,
+  created_at datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (id),
+  UNIQUE (uuid),
+  INDEX (user_uuid),
+  INDEX (group_uuid),
+  CONSTRAINT fk_user_groups_user_uuid
+  FOREIGN KEY (user_uuid)
+  REFERENCES users (uuid)
+  ON DELETE RESTRICT ON UPDATE RESTRICT,
+  CONSTRAINT fk_user_groups_group_uuid
+  FOREIGN KEY (group_uuid)
+  REFERENCES groups (uuid)
+  ON DELETE RESTRICT ON UPDATE RESTRICT
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+
+CREATE TABLE service_groups (
+  id int(11) NOT NULL AUTO_INCREMENT,
+  internal_id varchar(32) NOT NULL,
+  group_uuid varchar(128) NOT NULL,
+  service_uuid varchar(128) NOT NULL,
+  created_at datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (id),
+  INDEX (group_uuid),
+  INDEX (service_uuid),
+  CONSTRAINT fk_service_groups_group_uuid
+  FOREIGN KEY (group_uuid)
+  REFERENCES groups (uuid)
+  ON DELETE RESTRICT ON UPDATE RESTRICT,
+  CONSTRAINT fk_service_groups_serviceuuid
+  FOREIGN KEY (service_uuid)
+  REFERENCES services (uuid)
+  ON DELETE RESTRICT ON UPDATE RESTRICT
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+
+CREATE TABLE service_roles (
+  id int(11) NOT NULL AUTO_INCREMENT,
+  internal_id varchar(32) NOT NULL,
+  role_uuid varchar(128) NOT NULL,
+  service_uuid varchar(128) NOT NULL,
+  created_at datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (id),
+  INDEX (role_uuid),
+  INDEX (service_uuid),
+  CONSTRAINT fk_service_roles_role_uuid
+  FOREIGN KEY (role_uuid)
+  REFERENCES roles (uuid)
+  ON DELETE RESTRICT ON UPDATE RESTRICT,
+  CONSTRAINT fk_service_roles_service_uuid
+  FOREIGN KEY (service_uuid)
+  REFERENCES services (uuid)
+  ON DELETE RESTRICT ON UPDATE RESTRICT
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+
+CREATE TABLE service_permissions (
+  id int(11) NOT NULL AUTO_INCREMENT,
+  internal_id varchar(32) NOT NULL,
+  permission_uuid varchar(128) NOT NULL,
+  service_uuid varchar(128) NOT NULL,
+  created_at datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (id),
+  INDEX (permission_uuid),
+  INDEX (service_uuid),
+  CONSTRAINT fk_service_permissions_permission_uuid
+  FOREIGN KEY (permission_uuid)
+  REFERENCES permissions (uuid)
+  ON DELETE RESTRICT ON UPDATE RESTRICT,
+  CONSTRAINT fk_service_permissions_service_uuid
+  FOREIGN KEY (service_uuid)
+  REFERENCES services (uuid)
+  ON DELETE RESTRICT ON UPDATE RESTRICT
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+
+CREATE TABLE group_roles (
+  id int(11) NOT NULL AUTO_INCREMENT,
+  internal_id varchar(32) NOT NULL,
+  role_uuid varchar(128) NOT NULL,
+  group_uuid varchar(128) NOT NULL,
+  created_at datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (id),
+  INDEX (role_uuid),
+  INDEX (group_uuid),
+  CONSTRAINT fk_group_roles_role_uuid
+  FOREIGN KEY (role_uuid)
+  REFERENCES roles (uuid)
+  ON DELETE RESTRICT ON UPDATE RESTRICT,
+  CONSTRAINT fk_group_roles_group_uuid
+  FOREIGN KEY (group_uuid)
+  REFERENCES groups (uuid)
+  ON DELETE RESTRICT ON UPDATE RESTRICT
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+
+CREATE TABLE group_permissions (
+  id int(11) NOT NULL AUTO_INCREMENT,
+  internal_id varchar(32) NOT NULL,
+  permission_uuid varchar(128) NOT NULL,
+  group_uuid varchar(128) NOT NULL,
+  created_at datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (id),
+  INDEX (permission_uuid),
+  INDEX (group_uuid),
+  CONSTRAINT fk_group_permissions_permission_uuid
+  FOREIGN KEY (permission_uuid)
+  REFERENCES permissions (uuid)
+  ON DELETE RESTRICT ON UPDATE RESTRICT,
+  CONSTRAINT fk_group_permissions_group_uuid
+  FOREIGN KEY (group_uuid)
+  REFERENCES groups (uuid)
+  ON DELETE RESTRICT ON UPDATE RESTRICT
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+
+CREATE TABLE operator_policies (
+  id int(11) NOT NULL AUTO_INCREMENT,
+  internal_id varchar(32) NOT NULL,
+  role_uuid varchar(128) NOT NULL,
+  user_uuid varchar(128) NOT NULL,
+  created_at datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (id),
+  INDEX (user_uuid),
+  INDEX (role_uuid),
+  CONSTRAINT fk_operator_policies_role_uuid
+  FOREIGN KEY (role_uuid)
+  REFERENCES roles (uuid)
+  ON DELETE RESTRICT ON UPDATE RESTRICT,
+  CONSTRAINT fk_operator_policies_user_uuid
+  FOREIGN KEY (user_uuid)
+  REFERENCES users (uuid)
+  ON DELETE RESTRICT ON UPDATE RESTRICT
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+
+CREATE TABLE policies (
+  id int(11) NOT NULL AUTO_INCREMENT,
+  internal_id varchar(32) NOT NULL,
+  name varchar(128) NOT NULL,
+  role_uuid varchar(128) NOT NULL,
+  permission_uuid varchar(128) NOT NULL,
+  service_uuid varchar(128) NOT NULL,
+  user_group_uuid varchar(128) NOT NULL,
+  created_at datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (id),
+  INDEX (role_uuid),
+  INDEX (permission_uuid),
+  INDEX (service_uuid),
+  INDEX (user_group_uuid),
+  CONSTRAINT fk_policies_role_uuid
+  FOREIGN KEY (role_uuid)
+  REFERENCES roles (uuid)
+  ON DELETE RESTRICT ON UPDATE RESTRICT,
+  CONSTRAINT fk_policies_permission_uuid
+  FOREIGN KEY (permission_uuid)
+  REFERENCES permissions (uuid)
+  ON DELETE RESTRICT ON UPDATE RESTRICT,
+  CONSTRAINT fk_policies_service_uuid
+  FOREIGN KEY (service_uuid)
+  REFERENCES services (uuid)
+  ON DELETE RESTRICT ON UPDATE RESTRICT,
+  CONSTRAINT fk_policies_user_group_uuid
+  FOREIGN KEY (user_group_uuid)
+  REFERENCES user_groups (uuid)
+  ON DELETE RESTRICT ON UPDATE RESTRICT
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+`
+
+// Version 2 of mysql, that adds deleted_at of soft delete to all tables
+const mysqlV2Up = `
+ALTER TABLE services ADD COLUMN deleted_at datetime NULL;
+ALTER TABLE users ADD COLUMN deleted_at datetime NULL;
+ALTER TABLE permissions ADD COLUMN deleted_at datetime NULL;
+ALTER TABLE groups ADD COLUMN deleted_at datetime NULL;
+ALTER TABLE roles ADD COLUMN deleted_at datetime NULL;
+ALTER TABLE user_services ADD COLUMN deleted_at datetime NULL;
+ALTER TABLE user_groups ADD COLUMN deleted_at datetime NULL;
+ALTER TABLE service_groups ADD COLUMN deleted_at datetime NULL;
+ALTER TABLE service_roles ADD COLUMN deleted_at datetime NULL;
+ALTER TABLE service_permissions ADD COLUMN deleted_at datetime NULL;
+ALTER TABLE group_roles ADD COLUMN deleted_at datetime NULL;
+ALTER TABLE group_permissions ADD COLUMN deleted_at datetime NULL;
+ALTER TABLE operator_policies ADD COLUMN deleted_at datetime NULL;
+ALTER TABLE policies ADD COLUMN deleted_at datetime NULL;
+`
+
+// Version 3 of mysql, that adds version of optimistic concurrency control to tables that are updated
+// Policy is unique by user group, so that concurrent create of it is rejected
+const mysqlV3Up = `
+ALTER TABLE users ADD COLUMN version int NOT NULL DEFAULT 1;
+ALTER TABLE policies ADD COLUMN version int NOT NULL DEFAULT 1;
+CREATE UNIQUE INDEX uq_policies_user_group_uuid ON policies (user_group_uuid);
+`
+
+const mysqlV3Down = `
+DROP INDEX uq_policies_user_group_uuid ON policies;
+ALTER TABLE policies DROP COLUMN version;
+ALTER TABLE users DROP COLUMN version;
+`
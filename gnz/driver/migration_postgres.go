@@ -0,0 +1,293 @@
+package driver
+
+// Version 1 of postgres, that is the same as schema/postgresql.sql
+const postgresV1Up = `
+CREATE TABLE services (
+  id SERIAL NOT NULL,
+  internal_id varchar(32) NOT NULL,
+  uuid varchar(128) NOT NULL,
+  name varchar(128) NOT NULL,
+  secret varchar(128) NOT NULL,
+  created_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (id),
+  UNIQUE (name),
+  UNIQUE (uuid)
+);
+
+CREATE TABLE users (
+  id SERIAL NOT NULL,
+  internal_id varchar(32) NOT NULL,
+  uuid varchar(128) NOT NULL,
+  username varchar(128) NOT NULL,
+  email varchar(128) NOT NULL,
+  password varchar(128) NOT NULL,
+  created_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (id),
+  UNIQUE (email),
+  UNIQUE (uuid)
+);
+
+CREATE TABLE permissions (
+  id SERIAL NOT NULL,
+  internal_id varchar(32) NOT NULL,
+  uuid varchar(128) NOT NULL,
+  name varchar(128) NOT NULL,
+  created_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (id),
+  UNIQUE (name),
+  UNIQUE (uuid)
+);
+
+CREATE TABLE groups (
+  id SERIAL NOT NULL,
+  internal_id varchar(32) NOT NULL,
+  uuid varchar(128) NOT NULL,
+  name varchar(128) NOT NULL,
+  created_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (id),
+  UNIQUE (uuid)
+);
+
+CREATE TABLE roles (
+  id SERIAL NOT NULL,
+  internal_id varchar(32) NOT NULL,
+  uuid varchar(128) NOT NULL,
+  name varchar(128) NOT NULL,
+  created_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (id),
+  UNIQUE (name),
+  UNIQUE (uuid)
+);
+
+CREATE TABLE user_services (
+  id SERIAL NOT NULL,
+  internal_id varchar(32) NOT NULL,
+  user_uuid varchar(128) NOT NULL,
+  service_uuid varchar(128) NOT NULL,
+  created_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (id),
+  CONSTRAINT fk_user_services_user_uuid
+  FOREIGN KEY (user_uuid)
+  REFERENCES users (uuid)
+  ON DELETE RESTRICT ON UPDATE RESTRICT,
+  CONSTRAINT fk_user_services_service_uuid
+  FOREIGN KEY (service_uuid)
+  REFERENCES services (uuid)
+  ON DELETE RESTRICT ON UPDATE RESTRICT
+);
+CREATE INDEX idx_user_services_user_uuid ON user_services (user_uuid);
+CREATE INDEX idx_user_services_service_uuid ON user_services (service_uuid);
+
+CREATE TABLE user_groups (
+  id SERIAL NOT NULL,
+  internal_id varchar(32) NOT NULL,
+  uuid varchar(128) NOT NULL,
+  user_uuid varchar(128) NOT NULL,
+  group_uuid varchar(128) NOT NULL,
+  created_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (id),
+  UNIQUE (uuid),
+  CONSTRAINT fk_user_groups_user_uuid
+  FOREIGN KEY (user_uuid)
+  REFERENCES users (uuid)
+  ON DELETE RESTRICT ON UPDATE RESTRICT,
+  CONSTRAINT fk_user_groups_group_uuid
+  FOREIGN KEY (group_uuid)
+  REFERENCES groups (uuid)
+  ON DELETE RESTRICT ON UPDATE RESTRICT
+);
+CREATE INDEX idx_user_groups_user_uuid ON user_groups (user_uuid);
+CREATE INDEX idx_user_groups_group_uuid ON user_groups (group_uuid);
+
+CREATE TABLE service_groups (
+  id SERIAL NOT NULL,
+  internal_id varchar(32) NOT NULL,
+  group_uuid varchar(128) NOT NULL,
+  service_uuid varchar(128) NOT NULL,
+  created_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (id),
+  CONSTRAINT fk_service_groups_group_uuid
+  FOREIGN KEY (group_uuid)
+  REFERENCES groups (uuid)
+  ON DELETE RESTRICT ON UPDATE RESTRICT,
+  CONSTRAINT fk_service_groups_serviceuuid
+  FOREIGN KEY (service_uuid)
+  REFERENCES services (uuid)
+  ON DELETE RESTRICT ON UPDATE RESTRICT
+);
+CREATE INDEX idx_service_groups_group_uuid ON service_groups (group_uuid);
+CREATE INDEX idx_service_groups_service_uuid ON service_groups (service_uuid);
+
+CREATE TABLE service_roles (
+  id SERIAL NOT NULL,
+  internal_id varchar(32) NOT NULL,
+  role_uuid varchar(128) NOT NULL,
+  service_uuid varchar(128) NOT NULL,
+  created_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (id),
+  CONSTRAINT fk_service_roles_role_uuid
+  FOREIGN KEY (role_uuid)
+  REFERENCES roles (uuid)
+  ON DELETE RESTRICT ON UPDATE RESTRICT,
+  CONSTRAINT fk_service_roles_service_uuid
+  FOREIGN KEY (service_uuid)
+  REFERENCES services (uuid)
+  ON DELETE RESTRICT ON UPDATE RESTRICT
+);
+CREATE INDEX idx_service_roles_role_uuid ON service_roles (role_uuid);
+CREATE INDEX idx_service_roles_service_uuid ON service_roles (service_uuid);
+
+CREATE TABLE service_permissions (
+  id SERIAL NOT NULL,
+  internal_id varchar(32) NOT NULL,
+  permission_uuid varchar(128) NOT NULL,
+  service_uuid varchar(128) NOT NULL,
+  created_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (id),
+  CONSTRAINT fk_service_permissions_permission_uuid
+  FOREIGN KEY (permission_uuid)
+  REFERENCES permissions (uuid)
+  ON DELETE RESTRICT ON UPDATE RESTRICT,
+  CONSTRAINT fk_service_permissions_service_uuid
+  FOREIGN KEY (service_uuid)
+  REFERENCES services (uuid)
+  ON DELETE RESTRICT ON UPDATE RESTRICT
+);
+CREATE INDEX idx_service_permissions_permission_uuid ON service_permissions (permission_uuid);
+CREATE INDEX idx_service_permissions_service_uuid ON service_permissions (service_uuid);
+
+CREATE TABLE group_roles (
+  id SERIAL NOT NULL,
+  internal_id varchar(32) NOT NULL,
+  role_uuid varchar(128) NOT NULL,
+  group_uuid varchar(128) NOT NULL,
+  created_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (id),
+  CONSTRAINT fk_group_roles_role_uuid
+  FOREIGN KEY (role_uuid)
+  REFERENCES roles (uuid)
+  ON DELETE RESTRICT ON UPDATE RESTRICT,
+  CONSTRAINT fk_group_roles_group_uuid
+  FOREIGN KEY (group_uuid)
+  REFERENCES groups (uuid)
+  ON DELETE RESTRICT ON UPDATE RESTRICT
+);
+CREATE INDEX idx_group_roles_role_uuid ON group_roles (role_uuid);
+CREATE INDEX idx_group_roles_group_uuid ON group_roles (group_uuid);
+
+CREATE TABLE group_permissions (
+  id SERIAL NOT NULL,
+  internal_id varchar(32) NOT NULL,
+  permission_uuid varchar(128) NOT NULL,
+  group_uuid varchar(128) NOT NULL,
+  created_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (id),
+  CONSTRAINT fk_group_permissions_permission_uuid
+  FOREIGN KEY (permission_uuid)
+  REFERENCES permissions (uuid)
+  ON DELETE RESTRICT ON UPDATE RESTRICT,
+  CONSTRAINT fk_group_permissions_group_uuid
+  FOREIGN KEY (group_uuid)
+  REFERENCES groups (uuid)
+  ON DELETE RESTRICT ON UPDATE RESTRICT
+);
+CREATE INDEX idx_group_permissions_permission_uuid ON group_permissions (permission_uuid);
+CREATE INDEX idx_group_permissions_group_uuid ON group_permissions (group_uuid);
+
+CREATE TABLE operator_policies (
+  id SERIAL NOT NULL,
+  internal_id varchar(32) NOT NULL,
+  role_uuid varchar(128) NOT NULL,
+  user_uuid varchar(128) NOT NULL,
+  created_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (id),
+  CONSTRAINT fk_operator_policies_role_uuid
+  FOREIGN KEY (role_uuid)
+  REFERENCES roles (uuid)
+  ON DELETE RESTRICT ON UPDATE RESTRICT,
+  CONSTRAINT fk_operator_policies_user_uuid
+  FOREIGN KEY (user_uuid)
+  REFERENCES users (uuid)
+  ON DELETE RESTRICT ON UPDATE RESTRICT
+);
+CREATE INDEX idx_operator_policies_user_uuid ON operator_policies (user_uuid);
+CREATE INDEX idx_operator_policies_role_uuid ON operator_policies (role_uuid);
+
+CREATE TABLE policies (
+  id SERIAL NOT NULL,
+  internal_id varchar(32) NOT NULL,
+  name varchar(128) NOT NULL,
+  role_uuid varchar(128) NOT NULL,
+  permission_uuid varchar(128) NOT NULL,
+  service_uuid varchar(128) NOT NULL,
+  user_group_uuid varchar(128) NOT NULL,
+  created_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (id),
+  CONSTRAINT fk_policies_role_uuid
+  FOREIGN KEY (role_uuid)
+  REFERENCES roles (uuid)
+  ON DELETE RESTRICT ON UPDATE RESTRICT,
+  CONSTRAINT fk_policies_permission_uuid
+  FOREIGN KEY (permission_uuid)
+  REFERENCES permissions (uuid)
+  ON DELETE RESTRICT ON UPDATE RESTRICT,
+  CONSTRAINT fk_policies_service_uuid
+  FOREIGN KEY (service_uuid)
+  REFERENCES services (uuid)
+  ON DELETE RESTRICT ON UPDATE RESTRICT,
+  CONSTRAINT fk_policies_user_group_uuid
+  FOREIGN KEY (user_group_uuid)
+  REFERENCES user_groups (uuid)
+  ON DELETE RESTRICT ON UPDATE RESTRICT
+);
+CREATE INDEX idx_policies_role_uuid ON policies (role_uuid);
+CREATE INDEX idx_policies_permission_uuid ON policies (permission_uuid);
+CREATE INDEX idx_policies_service_uuid ON policies (service_uuid);
+CREATE INDEX idx_policies_user_group_uuid ON policies (user_group_uuid);
+`
+
+// Version 2 of postgres, that adds deleted_at of soft delete to all tables
+const postgresV2Up = `
+ALTER TABLE services ADD COLUMN deleted_at timestamp NULL;
+ALTER TABLE users ADD COLUMN deleted_at timestamp NULL;
+ALTER TABLE permissions ADD COLUMN deleted_at timestamp NULL;
+ALTER TABLE groups ADD COLUMN deleted_at timestamp NULL;
+ALTER TABLE roles ADD COLUMN deleted_at timestamp NULL;
+ALTER TABLE user_services ADD COLUMN deleted_at timestamp NULL;
+ALTER TABLE user_groups ADD COLUMN deleted_at timestamp NULL;
+ALTER TABLE service_groups ADD COLUMN deleted_at timestamp NULL;
+ALTER TABLE service_roles ADD COLUMN deleted_at timestamp NULL;
+ALTER TABLE service_permissions ADD COLUMN deleted_at timestamp NULL;
+ALTER TABLE group_roles ADD COLUMN deleted_at timestamp NULL;
+ALTER TABLE group_permissions ADD COLUMN deleted_at timestamp NULL;
+ALTER TABLE operator_policies ADD COLUMN deleted_at timestamp NULL;
+ALTER TABLE policies ADD COLUMN deleted_at timestamp NULL;
+`
+
+// Version 3 of postgres, that adds version of optimistic concurrency control to tables that are updated
+// Policy is unique by user group, so that concurrent create of it is rejected
+const postgresV3Up = `
+ALTER TABLE users ADD COLUMN version integer NOT NULL DEFAULT 1;
+ALTER TABLE policies ADD COLUMN version integer NOT NULL DEFAULT 1;
+CREATE UNIQUE INDEX uq_policies_user_group_uuid ON policies (user_group_uuid);
+`
+
+const postgresV3Down = `
+DROP INDEX uq_policies_user_group_uuid;
+ALTER TABLE policies DROP COLUMN version;
+ALTER TABLE users DROP COLUMN version;
+`
@@ -0,0 +1,140 @@
+package driver
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+// Migrations with version 2 that creates one table
+func testMigrations(v2Up string) []Migration {
+	return append(Migrations[:1:1], Migration{
+		Version: 2,
+		Name:    "create_tests",
+		Up:      map[string]string{EngineSqlite: v2Up},
+		Down:    map[string]string{EngineSqlite: "DROP TABLE tests"},
+	})
+}
+
+// Apply migrations in order test
+func TestMigratorUp(t *testing.T) {
+	db := openSqliteConnection(t)
+	defer db.Close()
+	migrator := MigratorImpl{Connection: db, Migrations: testMigrations("CREATE TABLE tests (id int);")}
+
+	if err := migrator.Check(context.Background()); err == nil || !strings.Contains(err.Error(), "pending version = 1") {
+		t.Errorf("Incorrect TestMigratorUp test. err = %v", err)
+		t.FailNow()
+	}
+
+	for i := 0; i < 2; i++ {
+		statuses, err := migrator.Up(context.Background())
+		if err != nil || len(statuses) != 2 || statuses[0].State != MigrationApplied || statuses[1].State != MigrationApplied || statuses[1].AppliedAt == nil {
+			t.Errorf("Incorrect TestMigratorUp test. statuses = %v, err = %v", statuses, err)
+			t.FailNow()
+		}
+	}
+	if !db.HasTable("policies") || !db.HasTable("tests") || migrator.Check(context.Background()) != nil {
+		t.Errorf("Incorrect TestMigratorUp test")
+		t.FailNow()
+	}
+}
+
+// Revert migrations from the latest test
+func TestMigratorDown(t *testing.T) {
+	db := openSqliteConnection(t)
+	defer db.Close()
+	migrator := MigratorImpl{Connection: db, Migrations: testMigrations("CREATE TABLE tests (id int);")}
+	migrator.Up(context.Background())
+
+	statuses, err := migrator.Down(context.Background(), 1)
+	if err != nil || statuses[0].State != MigrationApplied || statuses[1].State != MigrationPending || db.HasTable("tests") {
+		t.Errorf("Incorrect TestMigratorDown test. statuses = %v, err = %v", statuses, err)
+		t.FailNow()
+	}
+
+	statuses, err = migrator.Down(context.Background(), 5)
+	if err != nil || statuses[0].State != MigrationPending || db.HasTable("users") {
+		t.Errorf("Incorrect TestMigratorDown test. statuses = %v, err = %v", statuses, err)
+		t.FailNow()
+	}
+}
+
+// Existing schema without schema_migrations is version 1 test
+func TestMigratorUp_Baseline(t *testing.T) {
+	db := openSqliteConnection(t)
+	defer db.Close()
+	for _, statement := range splitStatements(sqliteV1Up) {
+		db.Exec(statement)
+	}
+
+	statuses, err := (MigratorImpl{Connection: db, Migrations: Migrations}).Up(context.Background())
+	if err != nil || len(statuses) != len(Migrations) || statuses[0].State != MigrationApplied || statuses[1].State != MigrationApplied {
+		t.Errorf("Incorrect TestMigratorUp_Baseline test. statuses = %v, err = %v", statuses, err)
+		t.FailNow()
+	}
+}
+
+// Failed migration is rolled back test
+func TestMigratorUp_Error(t *testing.T) {
+	db := openSqliteConnection(t)
+	defer db.Close()
+	migrator := MigratorImpl{Connection: db, Migrations: testMigrations("CREATE TABLE tests (id int); CREATE TABLE invalid (")}
+
+	statuses, err := migrator.Up(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "version = 2") || db.HasTable("tests") {
+		t.Errorf("Incorrect TestMigratorUp_Error test. err = %v", err)
+		t.FailNow()
+	}
+
+	statuses, _ = migrator.Status(context.Background())
+	if statuses[0].State != MigrationApplied || statuses[1].State != MigrationPending {
+		t.Errorf("Incorrect TestMigratorUp_Error test. statuses = %v", statuses)
+		t.FailNow()
+	}
+}
+
+// Modified and unknown migrations are refused test
+func TestMigratorCheck_Error(t *testing.T) {
+	db := openSqliteConnection(t)
+	defer db.Close()
+	MigratorImpl{Connection: db, Migrations: testMigrations("CREATE TABLE tests (id int);")}.Up(context.Background())
+
+	modified := MigratorImpl{Connection: db, Migrations: testMigrations("CREATE TABLE tests (id bigint);")}
+	if err := modified.Check(context.Background()); err == nil || !strings.Contains(err.Error(), "modified") {
+		t.Errorf("Incorrect TestMigratorCheck_Error test. err = %v", err)
+		t.FailNow()
+	}
+	if _, err := modified.Down(context.Background(), 1); err == nil || !db.HasTable("tests") {
+		t.Errorf("Incorrect TestMigratorCheck_Error test. err = %v", err)
+		t.FailNow()
+	}
+
+	older := MigratorImpl{Connection: db, Migrations: Migrations[:1]}
+	statuses, err := older.Up(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "Unknown schema version") || statuses[1].State != MigrationUnknown || statuses[1].Version != 2 {
+		t.Errorf("Incorrect TestMigratorCheck_Error test. statuses = %v, err = %v", statuses, err)
+		t.FailNow()
+	}
+	if err := older.Check(context.Background()); err == nil {
+		t.Errorf("Incorrect TestMigratorCheck_Error test")
+		t.FailNow()
+	}
+}
+
+// Embedded migrations have sql of all engines test
+// Down of sqlite may be missing, because sqlite cannot drop column
+func TestMigrations(t *testing.T) {
+	for i, migration := range Migrations {
+		if migration.Version != i+1 {
+			t.Errorf("Incorrect TestMigrations test. version = %d", migration.Version)
+			t.FailNow()
+		}
+		for _, engine := range []string{EngineMysql, EnginePostgres, EngineSqlite} {
+			if migration.Up[engine] == "" || (migration.Down[engine] == "" && engine != EngineSqlite) {
+				t.Errorf("Incorrect TestMigrations test. version = %d, engine = %s", migration.Version, engine)
+				t.FailNow()
+			}
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package driver
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/jinzhu/gorm"
@@ -12,15 +13,15 @@ import (
 var oprInstance OperatorPolicyRepository
 
 type OperatorPolicyRepository interface {
-	FindAll() ([]*entity.OperatorPolicy, error)
+	FindAll(ctx context.Context) ([]*entity.OperatorPolicy, error)
 
-	FindByUserUuid(userUuid string) ([]*entity.OperatorPolicy, error)
+	FindByUserUuid(ctx context.Context, userUuid string) ([]*entity.OperatorPolicy, error)
 
-	FindByUserUuidAndRoleUuid(userUuid string, roleUuid string) (*entity.OperatorPolicy, error)
+	FindByUserUuidAndRoleUuid(ctx context.Context, userUuid string, roleUuid string) (*entity.OperatorPolicy, error)
 
-	FindRoleNameByUserUuid(userUuid string) ([]string, error)
+	FindRoleNameByUserUuid(ctx context.Context, userUuid string) ([]string, error)
 
-	Save(role entity.OperatorPolicy) (*entity.OperatorPolicy, error)
+	Save(ctx context.Context, role entity.OperatorPolicy) (*entity.OperatorPolicy, error)
 }
 
 type OperatorPolicyRepositoryImpl struct {
@@ -39,35 +40,47 @@ func NewOperatorPolicyRepository() OperatorPolicyRepository {
 	return OperatorPolicyRepositoryImpl{Connection: connection}
 }
 
-func (opr OperatorPolicyRepositoryImpl) FindAll() ([]*entity.OperatorPolicy, error) {
+func (opr OperatorPolicyRepositoryImpl) FindAll(ctx context.Context) ([]*entity.OperatorPolicy, error) {
+	db, cancel := withReadContext(ctx, opr.Connection)
+	defer cancel()
+
 	var entities []*entity.OperatorPolicy
-	if err := opr.Connection.Find(&entities).Error; err != nil {
-		return nil, err
+	if err := db.Find(&entities).Error; err != nil {
+		return nil, translateError(err)
 	}
 
 	return entities, nil
 }
 
-func (opr OperatorPolicyRepositoryImpl) FindByUserUuid(userUuid string) ([]*entity.OperatorPolicy, error) {
+func (opr OperatorPolicyRepositoryImpl) FindByUserUuid(ctx context.Context, userUuid string) ([]*entity.OperatorPolicy, error) {
+	db, cancel := withReadContext(ctx, opr.Connection)
+	defer cancel()
+
 	var entities []*entity.OperatorPolicy
-	if err := opr.Connection.Where("user_uuid = ?", userUuid).Find(&entities).Error; err != nil {
-		return nil, err
+	if err := db.Where("user_uuid = ?", userUuid).Find(&entities).Error; err != nil {
+		return nil, translateError(err)
 	}
 
 	return entities, nil
 }
 
-func (opr OperatorPolicyRepositoryImpl) FindByUserUuidAndRoleUuid(userUuid string, roleUuid string) (*entity.OperatorPolicy, error) {
+func (opr OperatorPolicyRepositoryImpl) FindByUserUuidAndRoleUuid(ctx context.Context, userUuid string, roleUuid string) (*entity.OperatorPolicy, error) {
+	db, cancel := withReadContext(ctx, opr.Connection)
+	defer cancel()
+
 	var operatorMemberRole entity.OperatorPolicy
-	if err := opr.Connection.Where("user_uuid = ? AND role_uuid = ?", userUuid, roleUuid).Find(&operatorMemberRole).Error; err != nil {
-		return nil, err
+	if err := db.Where("user_uuid = ? AND role_uuid = ?", userUuid, roleUuid).Find(&operatorMemberRole).Error; err != nil {
+		return nil, translateError(err)
 	}
 
 	return &operatorMemberRole, nil
 }
 
-func (opr OperatorPolicyRepositoryImpl) FindRoleNameByUserUuid(userUuid string) ([]string, error) {
-	query := opr.Connection.Table(entity.OperatorPolicyTable.String()).
+func (opr OperatorPolicyRepositoryImpl) FindRoleNameByUserUuid(ctx context.Context, userUuid string) ([]string, error) {
+	db, cancel := withReadContext(ctx, opr.Connection)
+	defer cancel()
+
+	query := db.Table(entity.OperatorPolicyTable.String()).
 		Select("name").
 		Joins(fmt.Sprintf("LEFT JOIN %s ON %s.%s = %s.%s",
 			entity.RoleTable.String(),
@@ -75,13 +88,14 @@ func (opr OperatorPolicyRepositoryImpl) FindRoleNameByUserUuid(userUuid string)
 			entity.OperatorPolicyRoleUuid.String(),
 			entity.RoleTable.String(),
 			entity.RoleUuid.String())).
+		Where(notDeleted(entity.OperatorPolicyTable.String(), entity.RoleTable.String())).
 		Where(fmt.Sprintf("%s.%s = ?",
 			entity.OperatorPolicyTable.String(),
 			entity.OperatorPolicyUserUuid.String()), userUuid)
 
 	rows, err := query.Rows()
 	if err != nil {
-		return nil, err
+		return nil, translateError(err)
 	}
 
 	var result struct {
@@ -92,7 +106,7 @@ func (opr OperatorPolicyRepositoryImpl) FindRoleNameByUserUuid(userUuid string)
 	for rows.Next() {
 		err := query.ScanRows(rows, &result)
 		if err != nil {
-			return nil, err
+			return nil, translateError(err)
 		}
 		if result.name != nil {
 			names = append(names, *result.name)
@@ -102,9 +116,12 @@ func (opr OperatorPolicyRepositoryImpl) FindRoleNameByUserUuid(userUuid string)
 	return names, nil
 }
 
-func (opr OperatorPolicyRepositoryImpl) Save(entity entity.OperatorPolicy) (*entity.OperatorPolicy, error) {
-	if err := opr.Connection.Create(&entity).Error; err != nil {
-		return nil, err
+func (opr OperatorPolicyRepositoryImpl) Save(ctx context.Context, entity entity.OperatorPolicy) (*entity.OperatorPolicy, error) {
+	db, cancel := withContext(ctx, opr.Connection)
+	defer cancel()
+
+	if err := db.Create(&entity).Error; err != nil {
+		return nil, translateError(err)
 	}
 
 	return &entity, nil
@@ -1,9 +1,9 @@
 package driver
 
 import (
+	"context"
 	"testing"
 
-	"github.com/jinzhu/gorm"
 	"github.com/tomoyane/grant-n-z/gnz/entity"
 	"github.com/tomoyane/grant-n-z/gnz/log"
 )
@@ -14,14 +14,13 @@ var operatorPolicyRepository OperatorPolicyRepository
 func init() {
 	log.InitLogger("info")
 
-	stubConnection, _ := gorm.Open("sqlite3", "/tmp/test_grant_nz.db")
-	connection = stubConnection
+	connection = openTestConnection()
 	operatorPolicyRepository = GetOperatorPolicyRepositoryInstance()
 }
 
 // FindAll InternalServerError test
 func TestOperatorPolicyFindAll_Error(t *testing.T) {
-	_, err := operatorPolicyRepository.FindAll()
+	_, err := operatorPolicyRepository.FindAll(context.Background())
 	if err == nil {
 		t.Errorf("Incorrect TestOperatorPolicyFindAll_Error test")
 		t.FailNow()
@@ -30,7 +29,7 @@ func TestOperatorPolicyFindAll_Error(t *testing.T) {
 
 // FindByUserUuid InternalServerError test
 func TestOperatorPolicyFindByUserId_Error(t *testing.T) {
-	_, err := operatorPolicyRepository.FindByUserUuid("uuid")
+	_, err := operatorPolicyRepository.FindByUserUuid(context.Background(), "uuid")
 	if err == nil {
 		t.Errorf("Incorrect TestOperatorPolicyFindByUserId_Error test")
 		t.FailNow()
@@ -39,7 +38,7 @@ func TestOperatorPolicyFindByUserId_Error(t *testing.T) {
 
 // FindByUserUuidAndRoleUuid InternalServerError test
 func TestOperatorPolicyFindByUserIdAndRoleId_Error(t *testing.T) {
-	_, err := operatorPolicyRepository.FindByUserUuidAndRoleUuid("uuid", "uuid")
+	_, err := operatorPolicyRepository.FindByUserUuidAndRoleUuid(context.Background(), "uuid", "uuid")
 	if err == nil {
 		t.Errorf("Incorrect TestOperatorPolicyFindByUserIdAndRoleId_Error test")
 		t.FailNow()
@@ -48,7 +47,7 @@ func TestOperatorPolicyFindByUserIdAndRoleId_Error(t *testing.T) {
 
 // FindRoleNameByUserUuid InternalServerError test
 func TestOperatorPolicyFindRoleNameByUserId_Error(t *testing.T) {
-	_, err := operatorPolicyRepository.FindRoleNameByUserUuid("uuid")
+	_, err := operatorPolicyRepository.FindRoleNameByUserUuid(context.Background(), "uuid")
 	if err == nil {
 		t.Errorf("Incorrect TestOperatorPolicyFindRoleNameByUserId_Error test")
 		t.FailNow()
@@ -57,7 +56,7 @@ func TestOperatorPolicyFindRoleNameByUserId_Error(t *testing.T) {
 
 // Save InternalServerError test
 func TestOperatorPolicySave_Error(t *testing.T) {
-	_, err := operatorPolicyRepository.Save(entity.OperatorPolicy{})
+	_, err := operatorPolicyRepository.Save(context.Background(), entity.OperatorPolicy{})
 	if err == nil {
 		t.Errorf("Incorrect TestOperatorPolicySave_Error test")
 		t.FailNow()
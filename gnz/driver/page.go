@@ -0,0 +1,178 @@
+package driver
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// Sort of list query
+// Rows are ordered by the column and id, so that rows of the same value are not skipped by cursor
+const (
+	SortCreatedAt     = "created_at"
+	SortCreatedAtDesc = "-created_at"
+	SortName          = "name"
+	SortNameDesc      = "-name"
+)
+
+const (
+	// Limit of list query that does not have limit
+	DefaultPageLimit = 100
+
+	// Max limit of list query
+	MaxPageLimit = 1000
+)
+
+// Escape character of LIKE, that is the same in all db engines
+const likeEscape = "!"
+
+// Keyset pagination, filters and sort of list query
+type Page struct {
+	// Max rows of the page. If 0, DefaultPageLimit
+	Limit int
+
+	// Next cursor of the previous page. If empty, the first page
+	Cursor string
+
+	// Prefix of name, that is username of users
+	NamePrefix string
+
+	// Email of users. Other tables do not have it
+	Email string
+
+	// Rows that were created at or after it
+	CreatedFrom *time.Time
+
+	// Rows that were created before it
+	CreatedTo *time.Time
+
+	// One of sort consts. If empty, SortCreatedAt
+	Sort string
+}
+
+// Sort value and id of the last row of page, that is encoded to cursor
+type pageCursor struct {
+	Sort  string `json:"s"`
+	Value string `json:"v"`
+	Id    int    `json:"i"`
+}
+
+// Validate limit, sort and cursor
+// Cursor is valid only with the same sort as the page that returned it
+func (p Page) Validate() error {
+	if p.Limit < 0 || p.Limit > MaxPageLimit {
+		return errors.New(fmt.Sprintf("Limit must be 1 to %d.", MaxPageLimit))
+	}
+
+	switch p.Sort {
+	case "", SortCreatedAt, SortCreatedAtDesc, SortName, SortNameDesc:
+	default:
+		return errors.New(fmt.Sprintf("Not support sort. sort = %s", p.Sort))
+	}
+
+	if p.Cursor != "" {
+		c, err := decodeCursor(p.Cursor)
+		if err != nil || c.Sort != p.sort() {
+			return errors.New("Invalid cursor.")
+		}
+		if _, err := c.value(); err != nil {
+			return errors.New("Invalid cursor.")
+		}
+	}
+	return nil
+}
+
+func (p Page) limit() int {
+	if p.Limit == 0 {
+		return DefaultPageLimit
+	}
+	return p.Limit
+}
+
+func (p Page) sort() string {
+	if p.Sort == "" {
+		return SortCreatedAt
+	}
+	return p.Sort
+}
+
+// Apply filters, sort and cursor of page to query of table
+// Limit is one more than the page, so that nextCursor knows whether the next page exists
+func (p Page) apply(db *gorm.DB, table string, nameColumn string) (*gorm.DB, error) {
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+
+	if p.NamePrefix != "" {
+		db = db.Where(fmt.Sprintf("%s.%s LIKE ? ESCAPE '%s'", table, nameColumn, likeEscape), likePrefix(p.NamePrefix))
+	}
+	if p.CreatedFrom != nil {
+		db = db.Where(fmt.Sprintf("%s.created_at >= ?", table), *p.CreatedFrom)
+	}
+	if p.CreatedTo != nil {
+		db = db.Where(fmt.Sprintf("%s.created_at < ?", table), *p.CreatedTo)
+	}
+
+	column := fmt.Sprintf("%s.created_at", table)
+	if strings.TrimPrefix(p.sort(), "-") == SortName {
+		column = fmt.Sprintf("%s.%s", table, nameColumn)
+	}
+	operator, order := ">", "ASC"
+	if strings.HasPrefix(p.sort(), "-") {
+		operator, order = "<", "DESC"
+	}
+
+	if p.Cursor != "" {
+		c, _ := decodeCursor(p.Cursor)
+		value, _ := c.value()
+		db = db.Where(fmt.Sprintf("(%s %s ? OR (%s = ? AND %s.id %s ?))", column, operator, column, table, operator), value, value, c.Id)
+	}
+
+	return db.Order(fmt.Sprintf("%s %s", column, order)).Order(fmt.Sprintf("%s.id %s", table, order)).Limit(p.limit() + 1), nil
+}
+
+// Rows of page, and next cursor after the last row
+// If rows do not have the extra row of apply, the page is the last and next cursor is empty
+// Row of index is id, created_at and name of the row
+func (p Page) nextCursor(size int, row func(index int) (int, time.Time, string)) (int, string) {
+	if size <= p.limit() {
+		return size, ""
+	}
+
+	id, createdAt, name := row(p.limit() - 1)
+	c := pageCursor{Sort: p.sort(), Value: createdAt.Format(time.RFC3339Nano), Id: id}
+	if strings.TrimPrefix(p.sort(), "-") == SortName {
+		c.Value = name
+	}
+	value, _ := json.Marshal(c)
+	return p.limit(), base64.RawURLEncoding.EncodeToString(value)
+}
+
+// Sort value of cursor, that is time of created_at or string of name
+func (c pageCursor) value() (interface{}, error) {
+	if strings.TrimPrefix(c.Sort, "-") == SortName {
+		return c.Value, nil
+	}
+	return time.Parse(time.RFC3339Nano, c.Value)
+}
+
+func decodeCursor(cursor string) (pageCursor, error) {
+	var c pageCursor
+	value, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return c, err
+	}
+	err = json.Unmarshal(value, &c)
+	return c, err
+}
+
+// Pattern of LIKE that matches prefix, whose wildcards are escaped
+func likePrefix(prefix string) string {
+	replacer := strings.NewReplacer(likeEscape, likeEscape+likeEscape, "%", likeEscape+"%", "_", likeEscape+"_")
+	return replacer.Replace(prefix) + "%"
+}
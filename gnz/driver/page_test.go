@@ -0,0 +1,115 @@
+package driver
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/tomoyane/grant-n-z/gnz/entity"
+)
+
+// Validate page test
+func TestPageValidate(t *testing.T) {
+	_, cursor := Page{Limit: 1}.nextCursor(2, func(index int) (int, time.Time, string) {
+		return 1, time.Now(), "name"
+	})
+	cases := []struct {
+		page  Page
+		valid bool
+	}{
+		{Page{}, true},
+		{Page{Limit: MaxPageLimit, Sort: SortNameDesc}, true},
+		{Page{Limit: -1}, false},
+		{Page{Limit: MaxPageLimit + 1}, false},
+		{Page{Sort: "email"}, false},
+		{Page{Limit: 1, Cursor: cursor}, true},
+		{Page{Cursor: cursor, Sort: SortName}, false},
+		{Page{Cursor: "invalid"}, false},
+	}
+
+	for _, c := range cases {
+		if err := c.page.Validate(); (err == nil) != c.valid {
+			t.Errorf("Incorrect TestPageValidate test. page = %v, err = %v", c.page, err)
+			t.FailNow()
+		}
+	}
+}
+
+// Pattern of LIKE test
+func TestLikePrefix(t *testing.T) {
+	if pattern := likePrefix("a_b%c!"); pattern != "a!_b!%c!!%" {
+		t.Errorf("Incorrect TestLikePrefix test. pattern = %s", pattern)
+		t.FailNow()
+	}
+}
+
+// Keyset pagination of users in group test
+func TestPage_FindByGroupUuid(t *testing.T) {
+	db := openSqliteConnection(t)
+	defer db.Close()
+	if _, err := (MigratorImpl{Connection: db, Migrations: Migrations}).Up(context.Background()); err != nil {
+		t.Errorf("Incorrect TestPage_FindByGroupUuid test. err = %v", err)
+		t.FailNow()
+	}
+
+	group := entity.Group{InternalId: "internal", Uuid: uuid.New(), Name: "group"}
+	db.Create(&group)
+	createdAt := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i, name := range []string{"carol", "alice", "bob", "al_ex", "dave"} {
+		user := entity.User{InternalId: "internal", Uuid: uuid.New(), Username: name, Email: name + "@gmail.com", Password: "password",
+			CreatedAt: createdAt.Add(time.Duration(i%2) * time.Hour)}
+		db.Create(&user)
+		db.Create(&entity.UserGroup{InternalId: "internal", Uuid: uuid.New(), UserUuid: user.Uuid, GroupUuid: group.Uuid})
+	}
+	repository := UserRepositoryImpl{Connection: db}
+
+	// created_at of carol, bob and dave is the same, so that they are ordered by id
+	names := func(page Page) string {
+		var result []string
+		for {
+			users, next, err := repository.FindByGroupUuid(context.Background(), group.Uuid.String(), page)
+			if err != nil {
+				return err.Error()
+			}
+			for _, user := range users {
+				result = append(result, user.Username)
+			}
+			if next == "" {
+				return strings.Join(result, ",")
+			}
+			page.Cursor = next
+		}
+	}
+
+	cases := []struct {
+		page     Page
+		expected string
+	}{
+		{Page{Limit: 2}, "carol,bob,dave,alice,al_ex"},
+		{Page{Limit: 2, Sort: SortCreatedAtDesc}, "al_ex,alice,dave,bob,carol"},
+		{Page{Limit: 2, Sort: SortName}, "al_ex,alice,bob,carol,dave"},
+		{Page{Limit: 1, Sort: SortNameDesc}, "dave,carol,bob,alice,al_ex"},
+		{Page{NamePrefix: "al_"}, "al_ex"},
+		{Page{Email: "bob@gmail.com"}, "bob"},
+		{Page{CreatedFrom: &createdAt, CreatedTo: timeOf(createdAt.Add(time.Minute))}, "carol,bob,dave"},
+	}
+
+	for _, c := range cases {
+		if actual := names(c.page); actual != c.expected {
+			t.Errorf("Incorrect TestPage_FindByGroupUuid test. page = %v, users = %s", c.page, actual)
+			t.FailNow()
+		}
+	}
+
+	if _, _, err := repository.FindByGroupUuid(context.Background(), group.Uuid.String(), Page{Sort: "email"}); err == nil {
+		t.Errorf("Incorrect TestPage_FindByGroupUuid test")
+		t.FailNow()
+	}
+}
+
+func timeOf(t time.Time) *time.Time {
+	return &t
+}
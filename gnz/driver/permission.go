@@ -1,7 +1,10 @@
 package driver
 
 import (
+	"context"
 	"fmt"
+	"time"
+
 	"github.com/google/uuid"
 	"github.com/jinzhu/gorm"
 
@@ -13,32 +16,33 @@ var prInstance PermissionRepository
 
 type PermissionRepository interface {
 	// Find all permission
-	FindAll() ([]*entity.Permission, error)
+	FindAll(ctx context.Context) ([]*entity.Permission, error)
 
 	// Find permission for offset and limit
-	FindOffSetAndLimit(offsetCnt int, limitCnt int) ([]*entity.Permission, error)
+	FindOffSetAndLimit(ctx context.Context, offsetCnt int, limitCnt int) ([]*entity.Permission, error)
 
 	// Find permission by uuid
-	FindByUuid(uuid string) (*entity.Permission, error)
+	FindByUuid(ctx context.Context, uuid string) (*entity.Permission, error)
 
 	// Find permission by name
-	FindByName(name string) (*entity.Permission, error)
+	FindByName(ctx context.Context, name string) (*entity.Permission, error)
 
 	// Find permission by name array
-	FindByNames(names []string) ([]entity.Permission, error)
+	FindByNames(ctx context.Context, names []string) ([]entity.Permission, error)
 
 	// Find permissions by group uuid
 	// Join group_permission and permission
-	FindByGroupUuid(groupUuid string) ([]*entity.Permission, error)
+	// Keyset pagination, filters and sort of page. Next cursor is empty if it is the last page
+	FindByGroupUuid(ctx context.Context, groupUuid string, page Page) ([]*entity.Permission, string, error)
 
 	// Find permission name by uuid
-	FindNameByUuid(uuid string) *string
+	FindNameByUuid(ctx context.Context, uuid string) *string
 
 	// Save permission
-	Save(permission entity.Permission) (*entity.Permission, error)
+	Save(ctx context.Context, permission entity.Permission) (*entity.Permission, error)
 
 	// Save permission with relational data
-	SaveWithRelationalData(groupUuid string, permission entity.Permission) (*entity.Permission, error)
+	SaveWithRelationalData(ctx context.Context, groupUuid string, permission entity.Permission) (*entity.Permission, error)
 }
 
 type PermissionRepositoryImpl struct {
@@ -57,96 +61,134 @@ func NewPermissionRepository() PermissionRepository {
 	return PermissionRepositoryImpl{Connection: connection}
 }
 
-func (pri PermissionRepositoryImpl) FindAll() ([]*entity.Permission, error) {
+func (pri PermissionRepositoryImpl) FindAll(ctx context.Context) ([]*entity.Permission, error) {
+	db, cancel := withReadContext(ctx, pri.Connection)
+	defer cancel()
+
 	var permissions []*entity.Permission
-	if err := pri.Connection.Find(&permissions).Error; err != nil {
-		return nil, err
+	if err := db.Find(&permissions).Error; err != nil {
+		return nil, translateError(err)
 	}
 
 	return permissions, nil
 }
 
-func (pri PermissionRepositoryImpl) FindOffSetAndLimit(offsetCnt int, limitCnt int) ([]*entity.Permission, error) {
+func (pri PermissionRepositoryImpl) FindOffSetAndLimit(ctx context.Context, offsetCnt int, limitCnt int) ([]*entity.Permission, error) {
+	db, cancel := withReadContext(ctx, pri.Connection)
+	defer cancel()
+
 	var permissions []*entity.Permission
-	if err := pri.Connection.Limit(limitCnt).Offset(offsetCnt).Find(&permissions).Error; err != nil {
-		return nil, err
+	if err := db.Limit(limitCnt).Offset(offsetCnt).Find(&permissions).Error; err != nil {
+		return nil, translateError(err)
 	}
 
 	return permissions, nil
 }
 
-func (pri PermissionRepositoryImpl) FindByUuid(uuid string) (*entity.Permission, error) {
+func (pri PermissionRepositoryImpl) FindByUuid(ctx context.Context, uuid string) (*entity.Permission, error) {
+	db, cancel := withReadContext(ctx, pri.Connection)
+	defer cancel()
+
 	var permission entity.Permission
-	if err := pri.Connection.Where("uuid = ?", uuid).Find(&permission).Error; err != nil {
-		return nil, err
+	if err := db.Where("uuid = ?", uuid).Find(&permission).Error; err != nil {
+		return nil, translateError(err)
 	}
 
 	return &permission, nil
 }
 
-func (pri PermissionRepositoryImpl) FindByName(name string) (*entity.Permission, error) {
+func (pri PermissionRepositoryImpl) FindByName(ctx context.Context, name string) (*entity.Permission, error) {
+	db, cancel := withReadContext(ctx, pri.Connection)
+	defer cancel()
+
 	var permission entity.Permission
-	if err := pri.Connection.Where("name = ?", name).Find(&permission).Error; err != nil {
-		return nil, err
+	if err := db.Where("name = ?", name).Find(&permission).Error; err != nil {
+		return nil, translateError(err)
 	}
 
 	return &permission, nil
 }
 
-func (pri PermissionRepositoryImpl) FindByNames(names []string) ([]entity.Permission, error) {
+func (pri PermissionRepositoryImpl) FindByNames(ctx context.Context, names []string) ([]entity.Permission, error) {
+	db, cancel := withReadContext(ctx, pri.Connection)
+	defer cancel()
+
 	var permissions []entity.Permission
-	if err := pri.Connection.Where("name IN (?)", names).Find(&permissions).Error; err != nil {
-		return nil, err
+	if err := db.Where("name IN (?)", names).Find(&permissions).Error; err != nil {
+		return nil, translateError(err)
 	}
 
 	return permissions, nil
 }
 
-func (pri PermissionRepositoryImpl) FindByGroupUuid(groupUuid string) ([]*entity.Permission, error) {
+func (pri PermissionRepositoryImpl) FindByGroupUuid(ctx context.Context, groupUuid string, page Page) ([]*entity.Permission, string, error) {
+	db, cancel := withReadContext(ctx, pri.Connection)
+	defer cancel()
+
+	db = groupTenant.scope(ctx, db, entity.GroupPermissionTable.String()+"."+entity.GroupPermissionGroupUuid.String())
+	query, err := page.apply(db, entity.PermissionTable.String(), entity.PermissionName.String())
+	if err != nil {
+		return nil, "", err
+	}
+
 	var permissions []*entity.Permission
 
-	if err := pri.Connection.Table(entity.GroupPermissionTable.String()).
-		Select("*").
+	if err := query.Table(entity.GroupPermissionTable.String()).
+		Select(entity.PermissionTable.String()+".*").
 		Joins(fmt.Sprintf("LEFT JOIN %s ON %s.%s = %s.%s",
 			entity.PermissionTable.String(),
 			entity.GroupPermissionTable.String(),
 			entity.GroupPermissionPermissionUuid.String(),
 			entity.PermissionTable.String(),
 			entity.PermissionUuid.String())).
+		Where(notDeleted(entity.GroupPermissionTable.String(), entity.PermissionTable.String())).
 		Where(fmt.Sprintf("%s.%s = ?",
 			entity.GroupPermissionTable.String(),
 			entity.GroupPermissionGroupUuid.String()), groupUuid).
 		Scan(&permissions).Error; err != nil {
 
-		return nil, err
+		return nil, "", translateError(err)
 	}
 
-	return permissions, nil
+	size, next := page.nextCursor(len(permissions), func(i int) (int, time.Time, string) {
+		return permissions[i].Id, permissions[i].CreatedAt, permissions[i].Name
+	})
+	return permissions[:size], next, nil
 }
 
-func (pri PermissionRepositoryImpl) FindNameByUuid(uuid string) *string {
-	permission, err := pri.FindByUuid(uuid)
+func (pri PermissionRepositoryImpl) FindNameByUuid(ctx context.Context, uuid string) *string {
+	permission, err := pri.FindByUuid(ctx, uuid)
 	if err != nil {
 		return nil
 	}
 	return &permission.Name
 }
 
-func (pri PermissionRepositoryImpl) Save(permission entity.Permission) (*entity.Permission, error) {
-	if err := pri.Connection.Create(&permission).Error; err != nil {
-		return nil, err
+func (pri PermissionRepositoryImpl) Save(ctx context.Context, permission entity.Permission) (*entity.Permission, error) {
+	db, cancel := withContext(ctx, pri.Connection)
+	defer cancel()
+
+	if err := db.Create(&permission).Error; err != nil {
+		return nil, translateError(err)
 	}
 
 	return &permission, nil
 }
 
-func (pri PermissionRepositoryImpl) SaveWithRelationalData(gUuid string, permission entity.Permission) (*entity.Permission, error) {
-	tx := pri.Connection.Begin()
+func (pri PermissionRepositoryImpl) SaveWithRelationalData(ctx context.Context, gUuid string, permission entity.Permission) (*entity.Permission, error) {
+	db, cancel := withContext(ctx, pri.Connection)
+	defer cancel()
+
+	if err := groupTenant.check(ctx, db, entity.GroupTable.String(), entity.GroupUuid.String(), gUuid); err != nil {
+		return nil, err
+	}
+
+	tx := db.Begin()
 
 	// Save permission
 	if err := tx.Create(&permission).Error; err != nil {
 		tx.Rollback()
-		return nil, err
+		return nil, translateError(err)
 	}
 
 	// Save group_permissions
@@ -157,10 +199,12 @@ func (pri PermissionRepositoryImpl) SaveWithRelationalData(gUuid string, permiss
 	}
 	if err := tx.Create(&groupPermission).Error; err != nil {
 		tx.Rollback()
-		return nil, err
+		return nil, translateError(err)
 	}
 
-	tx.Commit()
+	if err := tx.Commit().Error; err != nil {
+		return nil, translateError(err)
+	}
 
 	return &permission, nil
 }
@@ -1,9 +1,9 @@
 package driver
 
 import (
+	"context"
 	"testing"
 
-	"github.com/jinzhu/gorm"
 	"github.com/tomoyane/grant-n-z/gnz/entity"
 	"github.com/tomoyane/grant-n-z/gnz/log"
 )
@@ -14,14 +14,13 @@ var permissionRepository PermissionRepository
 func init() {
 	log.InitLogger("info")
 
-	stubConnection, _ := gorm.Open("sqlite3", "/tmp/test_grant_nz.db")
-	connection = stubConnection
+	connection = openTestConnection()
 	permissionRepository = GetPermissionRepositoryInstance()
 }
 
 // FindAll InternalServerError test
 func TestPermissionFindAll_Error(t *testing.T) {
-	_, err := permissionRepository.FindAll()
+	_, err := permissionRepository.FindAll(context.Background())
 	if err == nil {
 		t.Errorf("Incorrect TestPermissionFindAll_Error test")
 		t.FailNow()
@@ -30,7 +29,7 @@ func TestPermissionFindAll_Error(t *testing.T) {
 
 // FindOffSetAndLimit InternalServerError test
 func TestPermissionFindOffSetAndLimit_Error(t *testing.T) {
-	_, err := permissionRepository.FindOffSetAndLimit(1, 1)
+	_, err := permissionRepository.FindOffSetAndLimit(context.Background(), 1, 1)
 	if err == nil {
 		t.Errorf("Incorrect TestPermissionFindOffSetAndLimit_Error test")
 		t.FailNow()
@@ -39,7 +38,7 @@ func TestPermissionFindOffSetAndLimit_Error(t *testing.T) {
 
 // FindByUuid InternalServerError test
 func TestPermissionFindById_Error(t *testing.T) {
-	_, err := permissionRepository.FindByUuid("uuid")
+	_, err := permissionRepository.FindByUuid(context.Background(), "uuid")
 	if err == nil {
 		t.Errorf("Incorrect TestPermissionFindById_Error test")
 		t.FailNow()
@@ -48,7 +47,7 @@ func TestPermissionFindById_Error(t *testing.T) {
 
 // FindByName InternalServerError test
 func TestPermissionFindByName_Error(t *testing.T) {
-	_, err := permissionRepository.FindByName("test")
+	_, err := permissionRepository.FindByName(context.Background(), "test")
 	if err == nil {
 		t.Errorf("Incorrect TestPermissionFindByName_Error test")
 		t.FailNow()
@@ -57,7 +56,7 @@ func TestPermissionFindByName_Error(t *testing.T) {
 
 // FindByNames InternalServerError test
 func TestPermissionFindByNames_Error(t *testing.T) {
-	_, err := permissionRepository.FindByNames([]string{"test"})
+	_, err := permissionRepository.FindByNames(context.Background(), []string{"test"})
 	if err == nil {
 		t.Errorf("Incorrect TestPermissionFindByNames_Error test")
 		t.FailNow()
@@ -66,7 +65,7 @@ func TestPermissionFindByNames_Error(t *testing.T) {
 
 // FindByGroupUuid InternalServerError test
 func TestPermissionFindByGroupId_Error(t *testing.T) {
-	_, err := permissionRepository.FindByGroupUuid("uuid")
+	_, _, err := permissionRepository.FindByGroupUuid(context.Background(), "uuid", Page{})
 	if err == nil {
 		t.Errorf("Incorrect TestPermissionFindByGroupId_Error test")
 		t.FailNow()
@@ -75,7 +74,7 @@ func TestPermissionFindByGroupId_Error(t *testing.T) {
 
 // FindNameByUuid name is nil test
 func TestPermissionFindNameById_Nil(t *testing.T) {
-	name := permissionRepository.FindNameByUuid("uuid")
+	name := permissionRepository.FindNameByUuid(context.Background(), "uuid")
 	if name != nil {
 		t.Errorf("Incorrect TestPermissionFindNameById_Nil test")
 		t.FailNow()
@@ -84,7 +83,7 @@ func TestPermissionFindNameById_Nil(t *testing.T) {
 
 // Save InternalServerError test
 func TestPermissionSave_Error(t *testing.T) {
-	_, err := permissionRepository.Save(entity.Permission{})
+	_, err := permissionRepository.Save(context.Background(), entity.Permission{})
 	if err == nil {
 		t.Errorf("Incorrect TestPermissionSave_Error test")
 		t.FailNow()
@@ -93,7 +92,7 @@ func TestPermissionSave_Error(t *testing.T) {
 
 // SaveWithRelationalData InternalServerError test
 func TestPermissionSaveWithRelationalData_Error(t *testing.T) {
-	_, err := permissionRepository.SaveWithRelationalData("uuid", entity.Permission{})
+	_, err := permissionRepository.SaveWithRelationalData(context.Background(), "uuid", entity.Permission{})
 	if err == nil {
 		t.Errorf("Incorrect TestPermissionSaveWithRelationalData_Error test")
 		t.FailNow()
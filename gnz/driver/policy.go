@@ -1,6 +1,7 @@
 package driver
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/jinzhu/gorm"
@@ -14,25 +15,28 @@ var plrInstance PolicyRepository
 
 type PolicyRepository interface {
 	// Find all policy
-	FindAll() ([]*entity.Policy, error)
+	FindAll(ctx context.Context) ([]*entity.Policy, error)
 
 	// Find policy for offset and limit
-	FindOffSetAndLimit(offsetCnt int, limitCnt int) ([]*entity.Policy, error)
+	FindOffSetAndLimit(ctx context.Context, offsetCnt int, limitCnt int) ([]*entity.Policy, error)
 
 	// Find policy by role uuid
-	FindByRoleUuid(roleUuid string) ([]*entity.Policy, error)
+	FindByRoleUuid(ctx context.Context, roleUuid string) ([]*entity.Policy, error)
 
 	// Find by uuid
-	FindByUuid(uuid string) (entity.Policy, error)
+	FindByUuid(ctx context.Context, uuid string) (entity.Policy, error)
 
 	// Find policy data by user uuid and group uuid
-	FindPolicyOfUserGroupByUserUuidAndGroupUuid(userUuid string, groupUuid string) (model.UserPolicyOnGroupResponse, error)
+	FindPolicyOfUserGroupByUserUuidAndGroupUuid(ctx context.Context, userUuid string, groupUuid string) (model.UserPolicyOnGroupResponse, error)
 
-	// Find policy data by user uuid and group uuid
-	FindPolicyOfUserServiceByUserUuidAndServiceUuid(userUuid string) ([]model.UserPolicyOnServiceResponse, error)
+	// Find policy data of all user_groups of users in userUuids, ordered by user uuid
+	// Join user_groups and policies and roles and permissions
+	FindPolicyOfUserGroupByUserUuids(ctx context.Context, userUuids []string) ([]model.UserPolicyOnUserGroup, error)
 
-	// Update
-	Update(policy entity.Policy) (*entity.Policy, error)
+	// Update policy of user_group whose version is the version of policy, and increment version
+	// If the version is 0, create policy of user_group only if it does not exist
+	// If other request updated or created it after the caller read, ErrStaleVersion
+	Update(ctx context.Context, policy entity.Policy) (*entity.Policy, error)
 }
 
 type PolicyRepositoryImpl struct {
@@ -51,43 +55,58 @@ func NewPolicyRepository() PolicyRepository {
 	return PolicyRepositoryImpl{Connection: connection}
 }
 
-func (pri PolicyRepositoryImpl) FindAll() ([]*entity.Policy, error) {
+func (pri PolicyRepositoryImpl) FindAll(ctx context.Context) ([]*entity.Policy, error) {
+	db, cancel := withReadContext(ctx, pri.Connection)
+	defer cancel()
+
 	var policies []*entity.Policy
-	if err := pri.Connection.Find(&policies).Error; err != nil {
-		return nil, err
+	if err := db.Find(&policies).Error; err != nil {
+		return nil, translateError(err)
 	}
 
 	return policies, nil
 }
 
-func (pri PolicyRepositoryImpl) FindOffSetAndLimit(offsetCnt int, limitCnt int) ([]*entity.Policy, error) {
+func (pri PolicyRepositoryImpl) FindOffSetAndLimit(ctx context.Context, offsetCnt int, limitCnt int) ([]*entity.Policy, error) {
+	db, cancel := withReadContext(ctx, pri.Connection)
+	defer cancel()
+
 	var policies []*entity.Policy
-	if err := pri.Connection.Limit(limitCnt).Offset(offsetCnt).Find(&policies).Error; err != nil {
-		return nil, err
+	if err := db.Limit(limitCnt).Offset(offsetCnt).Find(&policies).Error; err != nil {
+		return nil, translateError(err)
 	}
 
 	return policies, nil
 }
 
-func (pri PolicyRepositoryImpl) FindByRoleUuid(roleUuid string) ([]*entity.Policy, error) {
+func (pri PolicyRepositoryImpl) FindByRoleUuid(ctx context.Context, roleUuid string) ([]*entity.Policy, error) {
+	db, cancel := withReadContext(ctx, pri.Connection)
+	defer cancel()
+
 	var policies []*entity.Policy
-	if err := pri.Connection.Where("role_uuid = ?", roleUuid).Find(&policies).Error; err != nil {
-		return nil, err
+	if err := db.Where("role_uuid = ?", roleUuid).Find(&policies).Error; err != nil {
+		return nil, translateError(err)
 	}
 
 	return policies, nil
 }
 
-func (pri PolicyRepositoryImpl) FindByUuid(uuid string) (entity.Policy, error) {
+func (pri PolicyRepositoryImpl) FindByUuid(ctx context.Context, uuid string) (entity.Policy, error) {
+	db, cancel := withReadContext(ctx, pri.Connection)
+	defer cancel()
+
 	var policy entity.Policy
-	if err := pri.Connection.Where("uuid = ?", uuid).Find(&policy).Error; err != nil {
-		return entity.Policy{}, err
+	if err := db.Where("uuid = ?", uuid).Find(&policy).Error; err != nil {
+		return entity.Policy{}, translateError(err)
 	}
 
 	return policy, nil
 }
 
-func (pri PolicyRepositoryImpl) FindPolicyOfUserGroupByUserUuidAndGroupUuid(userUuid string, groupUuid string) (model.UserPolicyOnGroupResponse, error) {
+func (pri PolicyRepositoryImpl) FindPolicyOfUserGroupByUserUuidAndGroupUuid(ctx context.Context, userUuid string, groupUuid string) (model.UserPolicyOnGroupResponse, error) {
+	db, cancel := withReadContext(ctx, pri.Connection)
+	defer cancel()
+
 	var policy model.UserPolicyOnGroupResponse
 
 	target := entity.UserTable.String() + "." +
@@ -96,6 +115,8 @@ func (pri PolicyRepositoryImpl) FindPolicyOfUserGroupByUserUuidAndGroupUuid(user
 		entity.UserEmail.String() + "," +
 		entity.PolicyTable.String() + "." +
 		entity.PolicyName.String() + " AS policy_name," +
+		"COALESCE(" + entity.PolicyTable.String() + "." +
+		entity.PolicyVersion.String() + ", 0) AS policy_version," +
 		entity.RoleTable.String() + "." +
 		entity.RoleName.String() + " AS role_name," +
 		entity.PermissionTable.String() + "." +
@@ -103,38 +124,45 @@ func (pri PolicyRepositoryImpl) FindPolicyOfUserGroupByUserUuidAndGroupUuid(user
 		entity.ServiceTable.String() + "." +
 		entity.ServiceName.String() + " AS service_name"
 
-	if err := pri.Connection.Table(entity.UserGroupTable.String()).
+	db = groupTenant.scope(ctx, db, entity.UserGroupTable.String()+"."+entity.UserGroupGroupUuid.String())
+	if err := db.Table(entity.UserGroupTable.String()).
 		Select(target).
-		Joins(fmt.Sprintf("LEFT JOIN %s ON %s.%s = %s.%s",
+		Joins(fmt.Sprintf("LEFT JOIN %s ON %s.%s = %s.%s AND %s",
 			entity.PolicyTable.String(),
 			entity.UserGroupTable.String(),
 			entity.UserGroupUuid.String(),
 			entity.PolicyTable.String(),
-			entity.PolicyUserGroupUuid.String())).
-		Joins(fmt.Sprintf("LEFT JOIN %s ON %s.%s = %s.%s",
+			entity.PolicyUserGroupUuid.String(),
+			notDeleted(entity.PolicyTable.String()))).
+		Joins(fmt.Sprintf("LEFT JOIN %s ON %s.%s = %s.%s AND %s",
 			entity.RoleTable.String(),
 			entity.RoleTable.String(),
 			entity.RoleUuid.String(),
 			entity.PolicyTable.String(),
-			entity.PolicyRoleUuid.String())).
-		Joins(fmt.Sprintf("LEFT JOIN %s ON %s.%s = %s.%s",
+			entity.PolicyRoleUuid.String(),
+			notDeleted(entity.RoleTable.String()))).
+		Joins(fmt.Sprintf("LEFT JOIN %s ON %s.%s = %s.%s AND %s",
 			entity.PermissionTable.String(),
 			entity.PermissionTable.String(),
 			entity.PermissionUuid.String(),
 			entity.PolicyTable.String(),
-			entity.PolicyPermissionUuid.String())).
-		Joins(fmt.Sprintf("LEFT JOIN %s ON %s.%s = %s.%s",
+			entity.PolicyPermissionUuid.String(),
+			notDeleted(entity.PermissionTable.String()))).
+		Joins(fmt.Sprintf("LEFT JOIN %s ON %s.%s = %s.%s AND %s",
 			entity.UserTable.String(),
 			entity.UserGroupTable.String(),
 			entity.UserGroupUserUuid.String(),
 			entity.UserTable.String(),
-			entity.UserUuid.String())).
-		Joins(fmt.Sprintf("LEFT JOIN %s ON %s.%s = %s.%s",
-			entity.PolicyTable.String(),
+			entity.UserUuid.String(),
+			notDeleted(entity.UserTable.String()))).
+		Joins(fmt.Sprintf("LEFT JOIN %s ON %s.%s = %s.%s AND %s",
+			entity.ServiceTable.String(),
 			entity.PolicyTable.String(),
 			entity.PolicyServiceUuid.String(),
 			entity.ServiceTable.String(),
-			entity.ServiceUuid.String())).
+			entity.ServiceUuid.String(),
+			notDeleted(entity.ServiceTable.String()))).
+		Where(notDeleted(entity.UserGroupTable.String())).
 		Where(fmt.Sprintf("%s.%s = ?",
 			entity.UserGroupTable.String(),
 			entity.UserGroupUserUuid.String()), userUuid).
@@ -143,87 +171,135 @@ func (pri PolicyRepositoryImpl) FindPolicyOfUserGroupByUserUuidAndGroupUuid(user
 			entity.UserGroupGroupUuid.String()), groupUuid).
 		Scan(&policy).Error; err != nil {
 
-		return model.UserPolicyOnGroupResponse{}, err
+		return model.UserPolicyOnGroupResponse{}, translateError(err)
 	}
 
 	return policy, nil
 }
 
-func (pri PolicyRepositoryImpl) FindPolicyOfUserServiceByUserUuidAndServiceUuid(userUuid string) ([]model.UserPolicyOnServiceResponse, error) {
-	var policy []model.UserPolicyOnServiceResponse
+func (pri PolicyRepositoryImpl) FindPolicyOfUserGroupByUserUuids(ctx context.Context, userUuids []string) ([]model.UserPolicyOnUserGroup, error) {
+	db, cancel := withReadContext(ctx, pri.Connection)
+	defer cancel()
 
-	target := entity.UserTable.String() + "." +
-		entity.UserUsername.String() + "," +
-		entity.UserTable.String() + "." +
-		entity.UserEmail.String() + "," +
+	var policies []model.UserPolicyOnUserGroup
+
+	target := entity.UserGroupTable.String() + "." +
+		entity.UserGroupUserUuid.String() + " AS user_uuid," +
 		entity.PolicyTable.String() + "." +
-		entity.PolicyName.String() + " AS policy_name," +
+		entity.PolicyServiceUuid.String() + " AS service_uuid," +
+		entity.UserGroupTable.String() + "." +
+		entity.UserGroupGroupUuid.String() + " AS group_uuid," +
 		entity.RoleTable.String() + "." +
 		entity.RoleName.String() + " AS role_name," +
-		entity.RoleTable.String() + "." +
-		entity.RoleUuid.String() + " AS role_uuid," +
 		entity.PermissionTable.String() + "." +
-		entity.PermissionName.String() + " AS permission_name," +
-		entity.PermissionTable.String() + "." +
-		entity.PermissionUuid.String() + " AS permission_uuid," +
-		entity.GroupTable.String() + "." +
-		entity.GroupName.String() + " AS group_name," +
-		entity.GroupTable.String() + "." +
-		entity.GroupUuid.String() + " AS group_uuid"
+		entity.PermissionName.String() + " AS permission_name"
 
-	if err := pri.Connection.Table(entity.UserTable.String()).
+	if err := db.Table(entity.UserGroupTable.String()).
 		Select(target).
-		Joins(fmt.Sprintf("LEFT JOIN %s ON %s.%s = %s.%s",
-			entity.UserServiceTable.String(),
-			entity.UserServiceTable.String(),
-			entity.UserServiceUserUuid.String(),
-			entity.UserTable.String(),
-			entity.UserUuid.String())).
-		Joins(fmt.Sprintf("LEFT JOIN %s ON %s.%s = %s.%s",
+		Joins(fmt.Sprintf("INNER JOIN %s ON %s.%s = %s.%s AND %s",
 			entity.PolicyTable.String(),
 			entity.PolicyTable.String(),
-			entity.PolicyServiceUuid.String(),
-			entity.UserServiceTable.String(),
-			entity.UserServiceServiceUuid.String())).
-		Joins(fmt.Sprintf("LEFT JOIN %s ON %s.%s = %s.%s",
-			entity.PermissionTable.String(),
-			entity.PermissionTable.String(),
-			entity.PermissionUuid.String(),
-			entity.PolicyTable.String(),
-			entity.PolicyPermissionUuid.String())).
-		Joins(fmt.Sprintf("LEFT JOIN %s ON %s.%s = %s.%s",
+			entity.PolicyUserGroupUuid.String(),
+			entity.UserGroupTable.String(),
+			entity.UserGroupUuid.String(),
+			notDeleted(entity.PolicyTable.String()))).
+		Joins(fmt.Sprintf("INNER JOIN %s ON %s.%s = %s.%s AND %s",
 			entity.RoleTable.String(),
 			entity.RoleTable.String(),
 			entity.RoleUuid.String(),
 			entity.PolicyTable.String(),
-			entity.PolicyRoleUuid.String())).
-		Joins(fmt.Sprintf("LEFT JOIN %s ON %s.%s = %s.%s",
+			entity.PolicyRoleUuid.String(),
+			notDeleted(entity.RoleTable.String()))).
+		Joins(fmt.Sprintf("INNER JOIN %s ON %s.%s = %s.%s AND %s",
+			entity.PermissionTable.String(),
+			entity.PermissionTable.String(),
+			entity.PermissionUuid.String(),
+			entity.PolicyTable.String(),
+			entity.PolicyPermissionUuid.String(),
+			notDeleted(entity.PermissionTable.String()))).
+		Where(notDeleted(entity.UserGroupTable.String())).
+		Where(fmt.Sprintf("%s.%s IN (?)",
 			entity.UserGroupTable.String(),
+			entity.UserGroupUserUuid.String()), userUuids).
+		Order(fmt.Sprintf("%s.%s, %s.%s",
 			entity.UserGroupTable.String(),
-			entity.UserGroupUuid.String(),
+			entity.UserGroupUserUuid.String(),
 			entity.PolicyTable.String(),
-			entity.PolicyUserGroupUuid.String())).
-		Joins(fmt.Sprintf("LEFT JOIN %s ON %s.%s = %s.%s",
-			entity.GroupTable.String(),
-			entity.GroupTable.String(),
-			entity.GroupUuid.String(),
-			entity.UserGroupTable.String(),
-			entity.UserGroupGroupUuid.String())).
-		Where(fmt.Sprintf("%s.%s = ?",
-			entity.UserTable.String(),
-			entity.UserUuid.String()), userUuid).
-		Scan(&policy).Error; err != nil {
+			entity.PolicyId.String())).
+		Scan(&policies).Error; err != nil {
 
-		return []model.UserPolicyOnServiceResponse{}, err
+		return nil, translateError(err)
 	}
 
-	return policy, nil
+	return policies, nil
 }
 
-func (pri PolicyRepositoryImpl) Update(policy entity.Policy) (*entity.Policy, error) {
-	if err := pri.Connection.Where("user_group_uuid = ?", policy.UserGroupUuid).Assign(policy).FirstOrCreate(&policy).Error; err != nil {
+func (pri PolicyRepositoryImpl) Update(ctx context.Context, policy entity.Policy) (*entity.Policy, error) {
+	db, cancel := withContext(ctx, pri.Connection)
+	defer cancel()
+
+	userGroupUuid := policy.UserGroupUuid.String()
+	if err := pri.checkTenant(ctx, db, policy); err != nil {
 		return nil, err
 	}
 
-	return &policy, nil
+	if policy.Version == 0 {
+		// Policy exists, or finding it failed
+		err := versionError(db, &entity.Policy{}, entity.PolicyUserGroupUuid.String(), userGroupUuid)
+		if err != ErrNotFound {
+			return nil, err
+		}
+
+		// Policy that was soft deleted with its role, permission or service is replaced, because user_group_uuid is unique
+		query := fmt.Sprintf("DELETE FROM %s WHERE %s = ? AND %s IS NOT NULL", entity.PolicyTable.String(), entity.PolicyUserGroupUuid.String(), deletedAtColumn)
+		if err := db.Exec(query, userGroupUuid).Error; err != nil {
+			return nil, translateError(err)
+		}
+
+		// Policy of the same user group was created by concurrent request
+		if err := translateError(db.Create(&policy).Error); err == ErrDuplicate {
+			return nil, ErrStaleVersion
+		} else if err != nil {
+			return nil, err
+		}
+		return &policy, nil
+	}
+
+	result := db.Model(&entity.Policy{}).
+		Where(fmt.Sprintf("%s = ? AND %s = ?", entity.PolicyUserGroupUuid.String(), entity.PolicyVersion.String()), userGroupUuid, policy.Version).
+		Updates(map[string]interface{}{
+			entity.PolicyInternalId.String():     policy.InternalId,
+			entity.PolicyName.String():           policy.Name,
+			entity.PolicyRoleUuid.String():       policy.RoleUuid.String(),
+			entity.PolicyPermissionUuid.String(): policy.PermissionUuid.String(),
+			entity.PolicyServiceUuid.String():    policy.ServiceUuid.String(),
+			entity.PolicyVersion.String():        gorm.Expr(entity.PolicyVersion.String() + " + 1"),
+		})
+	if result.Error != nil {
+		return nil, translateError(result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return nil, versionError(db, &entity.Policy{}, entity.PolicyUserGroupUuid.String(), userGroupUuid)
+	}
+
+	var updatedPolicy entity.Policy
+	if err := db.Where("user_group_uuid = ?", userGroupUuid).First(&updatedPolicy).Error; err != nil {
+		return nil, translateError(err)
+	}
+
+	return &updatedPolicy, nil
+}
+
+// Check that service, group of user_groups, role and permission of policy belong to tenant of ctx
+func (pri PolicyRepositoryImpl) checkTenant(ctx context.Context, db *gorm.DB, policy entity.Policy) error {
+	if err := checkTenantService(ctx, policy.ServiceUuid.String()); err != nil {
+		return err
+	}
+	if err := groupTenant.check(ctx, db, entity.UserGroupTable.String(), entity.UserGroupGroupUuid.String(), policy.UserGroupUuid.String()); err != nil {
+		return err
+	}
+	if err := roleTenant.check(ctx, db, entity.RoleTable.String(), entity.RoleUuid.String(), policy.RoleUuid.String()); err != nil {
+		return err
+	}
+	return permissionTenant.check(ctx, db, entity.PermissionTable.String(), entity.PermissionUuid.String(), policy.PermissionUuid.String())
 }
@@ -1,9 +1,9 @@
 package driver
 
 import (
+	"context"
 	"testing"
 
-	"github.com/jinzhu/gorm"
 	"github.com/tomoyane/grant-n-z/gnz/entity"
 	"github.com/tomoyane/grant-n-z/gnz/log"
 )
@@ -14,14 +14,13 @@ var policyRepository PolicyRepository
 func init() {
 	log.InitLogger("info")
 
-	stubConnection, _ := gorm.Open("sqlite3", "/tmp/test_grant_nz.db")
-	connection = stubConnection
+	connection = openTestConnection()
 	policyRepository = GetPolicyRepositoryInstance()
 }
 
 // FindAll InternalServerError test
 func TestPolicyFindAll_Error(t *testing.T) {
-	_, err := policyRepository.FindAll()
+	_, err := policyRepository.FindAll(context.Background())
 	if err == nil {
 		t.Errorf("Incorrect TestPolicyFindAll_Error test")
 		t.FailNow()
@@ -30,7 +29,7 @@ func TestPolicyFindAll_Error(t *testing.T) {
 
 // FindOffSetAndLimit InternalServerError test
 func TestPolicyFindOffSetAndLimit_Error(t *testing.T) {
-	_, err := policyRepository.FindOffSetAndLimit(1, 1)
+	_, err := policyRepository.FindOffSetAndLimit(context.Background(), 1, 1)
 	if err == nil {
 		t.Errorf("Incorrect TestPolicyFindOffSetAndLimit_Error test")
 		t.FailNow()
@@ -39,7 +38,7 @@ func TestPolicyFindOffSetAndLimit_Error(t *testing.T) {
 
 // FindByRoleUuid InternalServerError test
 func TestPolicyFindByRoleId_Error(t *testing.T) {
-	_, err := policyRepository.FindByRoleUuid("uuid")
+	_, err := policyRepository.FindByRoleUuid(context.Background(), "uuid")
 	if err == nil {
 		t.Errorf("Incorrect TestPolicyFindByRoleId_Error test")
 		t.FailNow()
@@ -48,7 +47,7 @@ func TestPolicyFindByRoleId_Error(t *testing.T) {
 
 // FindByUuid InternalServerError test
 func TestPolicyFindById_Error(t *testing.T) {
-	_, err := policyRepository.FindByUuid("uuid")
+	_, err := policyRepository.FindByUuid(context.Background(), "uuid")
 	if err == nil {
 		t.Errorf("Incorrect TestPolicyFindById_Error test")
 		t.FailNow()
@@ -57,25 +56,25 @@ func TestPolicyFindById_Error(t *testing.T) {
 
 // TestFindPolicyOfUserGroupByUserUuidAndGroupUuid InternalServerError test
 func TestFindPolicyOfUserGroupByUserUuidAndGroupUuid_Error(t *testing.T) {
-	_, err := policyRepository.FindPolicyOfUserGroupByUserUuidAndGroupUuid("uuid", "uuid")
+	_, err := policyRepository.FindPolicyOfUserGroupByUserUuidAndGroupUuid(context.Background(), "uuid", "uuid")
 	if err == nil {
 		t.Errorf("Incorrect TestFindPolicyOfUserGroupByUserUuidAndGroupUuid_Error test")
 		t.FailNow()
 	}
 }
 
-// FindPolicyOfUserServiceByUserUuidAndServiceUuid InternalServerError test
-func TestFindPolicyOfUserServiceByUserUuidAndGroupUuid_Error(t *testing.T) {
-	_, err := policyRepository.FindPolicyOfUserServiceByUserUuidAndServiceUuid("uuid")
+// FindPolicyOfUserGroupByUserUuids InternalServerError test
+func TestFindPolicyOfUserGroupByUserUuids_Error(t *testing.T) {
+	_, err := policyRepository.FindPolicyOfUserGroupByUserUuids(context.Background(), []string{"uuid"})
 	if err == nil {
-		t.Errorf("Incorrect FindPolicyOfUserServiceByUserUuidAndServiceUuid test")
+		t.Errorf("Incorrect TestFindPolicyOfUserGroupByUserUuids_Error test")
 		t.FailNow()
 	}
 }
 
 // Update InternalServerError test
 func TestPolicyUpdate_Error(t *testing.T) {
-	_, err := policyRepository.Update(entity.Policy{})
+	_, err := policyRepository.Update(context.Background(), entity.Policy{})
 	if err == nil {
 		t.Errorf("Incorrect TestPolicyUpdate_Error test")
 		t.FailNow()
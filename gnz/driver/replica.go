@@ -0,0 +1,163 @@
+package driver
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net"
+	"sync/atomic"
+	"time"
+
+	"github.com/jinzhu/gorm"
+
+	"github.com/tomoyane/grant-n-z/gnz/common"
+	"github.com/tomoyane/grant-n-z/gnz/log"
+)
+
+// Read replicas of the global connection
+// Find methods of repositories read from them, and other methods use primary
+var replicas *replicaSet
+
+type primaryKey struct{}
+
+type readYourWritesKey struct{}
+
+// Read replica of rdbms
+type replica struct {
+	host    string
+	db      *gorm.DB
+	healthy int32
+}
+
+// Replicas of primary that reads are routed to by round robin
+type replicaSet struct {
+	primary  *gorm.DB
+	replicas []*replica
+	next     uint32
+}
+
+// Writes of the context after WithReadYourWrites
+type writeMarker struct {
+	written int32
+}
+
+// Get context whose reads are always routed to primary
+// It is for reads that must not be stale, such as reloading cache of changed rows
+func WithPrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, primaryKey{}, true)
+}
+
+// Get context whose reads are routed to primary after the first write of the context
+// Replicas may not have written data yet, so the caller reads own writes from primary
+func WithReadYourWrites(ctx context.Context) context.Context {
+	return context.WithValue(ctx, readYourWritesKey{}, &writeMarker{})
+}
+
+// Get gorm connection for read of Find methods, that is cancelled the same as withContext
+func withReadContext(ctx context.Context, connection *gorm.DB) (*gorm.DB, context.CancelFunc) {
+	return openContext(ctx, readConnection(ctx, connection))
+}
+
+// Healthy replica of connection, or connection itself
+// Repository that has other connection than the global connection, such as test, always uses its connection
+func readConnection(ctx context.Context, connection *gorm.DB) *gorm.DB {
+	if replicas == nil || replicas.primary != connection || ReadsPrimary(ctx) {
+		return connection
+	}
+	if db := replicas.pick(); db != nil {
+		return db
+	}
+	return connection
+}
+
+// Reads of ctx are routed to primary by WithPrimary, or by WithReadYourWrites after the first write
+func ReadsPrimary(ctx context.Context) bool {
+	if primary, ok := ctx.Value(primaryKey{}).(bool); ok && primary {
+		return true
+	}
+	if marker, ok := ctx.Value(readYourWritesKey{}).(*writeMarker); ok {
+		return atomic.LoadInt32(&marker.written) == 1
+	}
+	return false
+}
+
+// Record write of ctx for WithReadYourWrites
+func markWritten(ctx context.Context) {
+	if marker, ok := ctx.Value(readYourWritesKey{}).(*writeMarker); ok {
+		atomic.StoreInt32(&marker.written, 1)
+	}
+}
+
+// Open replicas of db.replica-hosts
+// Replica that cannot be connected at start is unhealthy until health check succeeds
+func openReplicas(config common.DbConfig, primary *gorm.DB, openConnection int, idleConnection int) (*replicaSet, error) {
+	rs := &replicaSet{primary: primary}
+	for _, host := range config.ReplicaHosts {
+		replicaConfig := config
+		replicaConfig.Hosts = host
+		if h, port, err := net.SplitHostPort(host); err == nil {
+			replicaConfig.Hosts = h
+			replicaConfig.Port = port
+		}
+
+		dialect, dbSource, err := BuildDataSource(replicaConfig)
+		if err != nil {
+			rs.close()
+			return nil, err
+		}
+		sqlDb, err := sql.Open(dialect, dbSource)
+		if err != nil {
+			rs.close()
+			return nil, err
+		}
+
+		// gorm does not close *sql.DB that failed to ping
+		db, err := gorm.Open(dialect, sqlDb)
+		r := &replica{host: host, db: db, healthy: 1}
+		if err != nil {
+			log.Logger.Warn(fmt.Sprintf("Failed to connect replica. host = %s. err = %s", host, err.Error()))
+			r.healthy = 0
+		}
+		db.LogMode(logMode)
+		db.DB().SetMaxOpenConns(openConnection)
+		db.DB().SetMaxIdleConns(idleConnection)
+		db.DB().SetConnMaxLifetime(time.Duration(config.ConnMaxLifetime) * time.Millisecond)
+		rs.replicas = append(rs.replicas, r)
+	}
+	return rs, nil
+}
+
+// Healthy replica by round robin. If all replicas are unhealthy, nil
+func (rs *replicaSet) pick() *gorm.DB {
+	size := uint32(len(rs.replicas))
+	for i := uint32(0); i < size; i++ {
+		r := rs.replicas[(atomic.AddUint32(&rs.next, 1)-1)%size]
+		if atomic.LoadInt32(&r.healthy) == 1 {
+			return r.db
+		}
+	}
+	return nil
+}
+
+// Ping all replicas, and update health of them
+func (rs *replicaSet) check(ctx context.Context, timeout time.Duration) {
+	for _, r := range rs.replicas {
+		pingCtx, cancel := context.WithTimeout(ctx, timeout)
+		err := r.db.DB().PingContext(pingCtx)
+		cancel()
+
+		if err != nil {
+			if atomic.SwapInt32(&r.healthy, 0) == 1 {
+				log.Logger.Warn(fmt.Sprintf("Replica is unhealthy. Reads fail over to other replica or primary. host = %s. err = %s", r.host, err.Error()))
+			}
+		} else if atomic.SwapInt32(&r.healthy, 1) == 0 {
+			log.Logger.Info(fmt.Sprintf("Replica is healthy. host = %s", r.host))
+		}
+	}
+}
+
+func (rs *replicaSet) close() {
+	for _, r := range rs.replicas {
+		r.db.Close()
+	}
+}
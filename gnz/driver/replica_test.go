@@ -0,0 +1,142 @@
+package driver
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jinzhu/gorm"
+
+	"github.com/tomoyane/grant-n-z/gnz/common"
+	"github.com/tomoyane/grant-n-z/gnz/entity"
+)
+
+// Setup primary and replica that have different roles
+func setUpReplicaSet(t *testing.T) (*gorm.DB, *gorm.DB) {
+	primary := setUpContextConnection(t)
+	replicaDb := setUpContextConnection(t)
+	replicaDb.Create(&entity.Role{Name: "replica"})
+
+	replicas = &replicaSet{primary: primary, replicas: []*replica{{host: "replica", db: replicaDb, healthy: 1}}}
+	return primary, replicaDb
+}
+
+func tearDownReplicaSet(primary *gorm.DB, replicaDb *gorm.DB) {
+	replicas = nil
+	primary.Close()
+	replicaDb.Close()
+}
+
+// Test Find method reads replica
+func TestReadConnection_Replica(t *testing.T) {
+	primary, replicaDb := setUpReplicaSet(t)
+	defer tearDownReplicaSet(primary, replicaDb)
+
+	roles, err := RoleRepositoryImpl{Connection: primary}.FindAll(context.Background())
+	if err != nil || len(roles) != 2 {
+		t.Errorf("Incorrect TestReadConnection_Replica test. roles = %v, err = %v", roles, err)
+		t.FailNow()
+	}
+
+	// Other connection than primary of replicas does not use replicas
+	other := setUpContextConnection(t)
+	defer other.Close()
+	if readConnection(context.Background(), other) != other {
+		t.Errorf("Incorrect TestReadConnection_Replica test")
+		t.FailNow()
+	}
+}
+
+// Test Find method reads primary when all replicas are unhealthy
+func TestReadConnection_Failover(t *testing.T) {
+	primary, replicaDb := setUpReplicaSet(t)
+	defer tearDownReplicaSet(primary, replicaDb)
+
+	replicas.replicas[0].healthy = 0
+	roles, err := RoleRepositoryImpl{Connection: primary}.FindAll(context.Background())
+	if err != nil || len(roles) != 1 {
+		t.Errorf("Incorrect TestReadConnection_Failover test. roles = %v, err = %v", roles, err)
+		t.FailNow()
+	}
+}
+
+// Test Find method reads primary with WithPrimary
+func TestReadConnection_WithPrimary(t *testing.T) {
+	primary, replicaDb := setUpReplicaSet(t)
+	defer tearDownReplicaSet(primary, replicaDb)
+
+	if readConnection(WithPrimary(context.Background()), primary) != primary {
+		t.Errorf("Incorrect TestReadConnection_WithPrimary test")
+		t.FailNow()
+	}
+}
+
+// Test Find method reads primary after write with WithReadYourWrites
+func TestReadConnection_ReadYourWrites(t *testing.T) {
+	primary, replicaDb := setUpReplicaSet(t)
+	defer tearDownReplicaSet(primary, replicaDb)
+
+	ctx := WithReadYourWrites(context.Background())
+	if readConnection(ctx, primary) != replicaDb {
+		t.Errorf("Incorrect TestReadConnection_ReadYourWrites test")
+		t.FailNow()
+	}
+
+	repository := RoleRepositoryImpl{Connection: primary}
+	if _, err := repository.Save(ctx, entity.Role{Name: "written"}); err != nil {
+		t.Errorf("Incorrect TestReadConnection_ReadYourWrites test. err = %v", err)
+		t.FailNow()
+	}
+	if _, err := repository.FindByName(ctx, "written"); err != nil {
+		t.Errorf("Incorrect TestReadConnection_ReadYourWrites test. err = %v", err)
+		t.FailNow()
+	}
+}
+
+// Test health check of replicas
+func TestReplicaSet_Check(t *testing.T) {
+	primary, replicaDb := setUpReplicaSet(t)
+	defer tearDownReplicaSet(primary, replicaDb)
+
+	replicas.replicas[0].healthy = 0
+	replicas.check(context.Background(), time.Second)
+	if replicas.pick() != replicaDb {
+		t.Errorf("Incorrect TestReplicaSet_Check test")
+		t.FailNow()
+	}
+
+	replicaDb.Close()
+	replicas.check(context.Background(), time.Second)
+	if replicas.pick() != nil {
+		t.Errorf("Incorrect TestReplicaSet_Check test")
+		t.FailNow()
+	}
+}
+
+// Test round robin of healthy replicas
+func TestReplicaSet_Pick(t *testing.T) {
+	first := &replica{host: "first", db: &gorm.DB{}, healthy: 1}
+	second := &replica{host: "second", db: &gorm.DB{}, healthy: 1}
+	rs := &replicaSet{replicas: []*replica{first, second}}
+	if rs.pick() != first.db || rs.pick() != second.db || rs.pick() != first.db {
+		t.Errorf("Incorrect TestReplicaSet_Pick test")
+		t.FailNow()
+	}
+
+	first.healthy = 0
+	if rs.pick() != second.db || rs.pick() != second.db {
+		t.Errorf("Incorrect TestReplicaSet_Pick test")
+		t.FailNow()
+	}
+}
+
+// Test replica that cannot be connected at start is unhealthy
+func TestOpenReplicas_Unhealthy(t *testing.T) {
+	config := common.DbConfig{Engine: "mysql", Hosts: "localhost", Port: "3306", ReplicaHosts: []string{"127.0.0.1:1"}}
+	rs, err := openReplicas(config, nil, 1, 1)
+	if err != nil || len(rs.replicas) != 1 || rs.pick() != nil {
+		t.Errorf("Incorrect TestOpenReplicas_Unhealthy test. err = %v", err)
+		t.FailNow()
+	}
+	rs.close()
+}
@@ -1,7 +1,10 @@
 package driver
 
 import (
+	"context"
 	"fmt"
+	"time"
+
 	"github.com/google/uuid"
 	"github.com/jinzhu/gorm"
 
@@ -13,32 +16,33 @@ var rrInstance RoleRepository
 
 type RoleRepository interface {
 	// Find all roles
-	FindAll() ([]*entity.Role, error)
+	FindAll(ctx context.Context) ([]*entity.Role, error)
 
 	// Find role for offset and limit
-	FindOffSetAndLimit(offset int, limit int) ([]*entity.Role, error)
+	FindOffSetAndLimit(ctx context.Context, offset int, limit int) ([]*entity.Role, error)
 
 	// Find role by uuid
-	FindByUuid(uuid string) (*entity.Role, error)
+	FindByUuid(ctx context.Context, uuid string) (*entity.Role, error)
 
 	// FInd role by role name
-	FindByName(name string) (*entity.Role, error)
+	FindByName(ctx context.Context, name string) (*entity.Role, error)
 
 	// Find roles by role name array
-	FindByNames(name []string) ([]entity.Role, error)
+	FindByNames(ctx context.Context, name []string) ([]entity.Role, error)
 
 	// Find roles by group uuid
 	// Join group_roles and roles
-	FindByGroupUuid(groupUuid string) ([]*entity.Role, error)
+	// Keyset pagination, filters and sort of page. Next cursor is empty if it is the last page
+	FindByGroupUuid(ctx context.Context, groupUuid string, page Page) ([]*entity.Role, string, error)
 
 	// Find role name by uuid
-	FindNameByUuid(uuid string) *string
+	FindNameByUuid(ctx context.Context, uuid string) *string
 
 	// Save role
-	Save(role entity.Role) (*entity.Role, error)
+	Save(ctx context.Context, role entity.Role) (*entity.Role, error)
 
 	// Save role with relational data
-	SaveWithRelationalData(groupUuid string, role entity.Role) (*entity.Role, error)
+	SaveWithRelationalData(ctx context.Context, groupUuid string, role entity.Role) (*entity.Role, error)
 }
 
 type RoleRepositoryImpl struct {
@@ -57,96 +61,134 @@ func NewRoleRepository() RoleRepository {
 	return RoleRepositoryImpl{Connection: connection}
 }
 
-func (rri RoleRepositoryImpl) FindAll() ([]*entity.Role, error) {
+func (rri RoleRepositoryImpl) FindAll(ctx context.Context) ([]*entity.Role, error) {
+	db, cancel := withReadContext(ctx, rri.Connection)
+	defer cancel()
+
 	var roles []*entity.Role
-	if err := rri.Connection.Find(&roles).Error; err != nil {
-		return nil, err
+	if err := db.Find(&roles).Error; err != nil {
+		return nil, translateError(err)
 	}
 
 	return roles, nil
 }
 
-func (rri RoleRepositoryImpl) FindOffSetAndLimit(offset int, limit int) ([]*entity.Role, error) {
+func (rri RoleRepositoryImpl) FindOffSetAndLimit(ctx context.Context, offset int, limit int) ([]*entity.Role, error) {
+	db, cancel := withReadContext(ctx, rri.Connection)
+	defer cancel()
+
 	var roles []*entity.Role
-	if err := rri.Connection.Limit(limit).Offset(offset).Find(&roles).Error; err != nil {
-		return nil, err
+	if err := db.Limit(limit).Offset(offset).Find(&roles).Error; err != nil {
+		return nil, translateError(err)
 	}
 
 	return roles, nil
 }
 
-func (rri RoleRepositoryImpl) FindByUuid(uuid string) (*entity.Role, error) {
+func (rri RoleRepositoryImpl) FindByUuid(ctx context.Context, uuid string) (*entity.Role, error) {
+	db, cancel := withReadContext(ctx, rri.Connection)
+	defer cancel()
+
 	var role entity.Role
-	if err := rri.Connection.Where("uuid = ?", uuid).Find(&role).Error; err != nil {
-		return nil, err
+	if err := db.Where("uuid = ?", uuid).Find(&role).Error; err != nil {
+		return nil, translateError(err)
 	}
 
 	return &role, nil
 }
 
-func (rri RoleRepositoryImpl) FindByName(name string) (*entity.Role, error) {
+func (rri RoleRepositoryImpl) FindByName(ctx context.Context, name string) (*entity.Role, error) {
+	db, cancel := withReadContext(ctx, rri.Connection)
+	defer cancel()
+
 	var role entity.Role
-	if err := rri.Connection.Where("name = ?", name).Find(&role).Error; err != nil {
-		return nil, err
+	if err := db.Where("name = ?", name).Find(&role).Error; err != nil {
+		return nil, translateError(err)
 	}
 
 	return &role, nil
 }
 
-func (rri RoleRepositoryImpl) FindByNames(names []string) ([]entity.Role, error) {
+func (rri RoleRepositoryImpl) FindByNames(ctx context.Context, names []string) ([]entity.Role, error) {
+	db, cancel := withReadContext(ctx, rri.Connection)
+	defer cancel()
+
 	var roles []entity.Role
-	if err := rri.Connection.Where("name IN (?)", names).Find(&roles).Error; err != nil {
-		return nil, err
+	if err := db.Where("name IN (?)", names).Find(&roles).Error; err != nil {
+		return nil, translateError(err)
 	}
 
 	return roles, nil
 }
 
-func (rri RoleRepositoryImpl) FindByGroupUuid(groupUuid string) ([]*entity.Role, error) {
+func (rri RoleRepositoryImpl) FindByGroupUuid(ctx context.Context, groupUuid string, page Page) ([]*entity.Role, string, error) {
+	db, cancel := withReadContext(ctx, rri.Connection)
+	defer cancel()
+
+	db = groupTenant.scope(ctx, db, entity.GroupRoleTable.String()+"."+entity.GroupRoleGroupUuid.String())
+	query, err := page.apply(db, entity.RoleTable.String(), entity.RoleName.String())
+	if err != nil {
+		return nil, "", err
+	}
+
 	var roles []*entity.Role
 
-	if err := rri.Connection.Table(entity.GroupRoleTable.String()).
-		Select("*").
+	if err := query.Table(entity.GroupRoleTable.String()).
+		Select(entity.RoleTable.String()+".*").
 		Joins(fmt.Sprintf("LEFT JOIN %s ON %s.%s = %s.%s",
 			entity.RoleTable.String(),
 			entity.GroupRoleTable.String(),
 			entity.GroupRoleRoleUuid.String(),
 			entity.RoleTable.String(),
 			entity.RoleUuid.String())).
+		Where(notDeleted(entity.GroupRoleTable.String(), entity.RoleTable.String())).
 		Where(fmt.Sprintf("%s.%s = ?",
 			entity.GroupRoleTable.String(),
 			entity.GroupRoleGroupUuid.String()), groupUuid).
 		Scan(&roles).Error; err != nil {
 
-		return nil, err
+		return nil, "", translateError(err)
 	}
 
-	return roles, nil
+	size, next := page.nextCursor(len(roles), func(i int) (int, time.Time, string) {
+		return roles[i].Id, roles[i].CreatedAt, roles[i].Name
+	})
+	return roles[:size], next, nil
 }
 
-func (rri RoleRepositoryImpl) FindNameByUuid(uuid string) *string {
-	role, err := rri.FindByUuid(uuid)
+func (rri RoleRepositoryImpl) FindNameByUuid(ctx context.Context, uuid string) *string {
+	role, err := rri.FindByUuid(ctx, uuid)
 	if err != nil {
 		return nil
 	}
 	return &role.Name
 }
 
-func (rri RoleRepositoryImpl) Save(role entity.Role) (*entity.Role, error) {
-	if err := rri.Connection.Create(&role).Error; err != nil {
-		return nil, err
+func (rri RoleRepositoryImpl) Save(ctx context.Context, role entity.Role) (*entity.Role, error) {
+	db, cancel := withContext(ctx, rri.Connection)
+	defer cancel()
+
+	if err := db.Create(&role).Error; err != nil {
+		return nil, translateError(err)
 	}
 
 	return &role, nil
 }
 
-func (rri RoleRepositoryImpl) SaveWithRelationalData(gUuid string, role entity.Role) (*entity.Role, error) {
-	tx := rri.Connection.Begin()
+func (rri RoleRepositoryImpl) SaveWithRelationalData(ctx context.Context, gUuid string, role entity.Role) (*entity.Role, error) {
+	db, cancel := withContext(ctx, rri.Connection)
+	defer cancel()
+
+	if err := groupTenant.check(ctx, db, entity.GroupTable.String(), entity.GroupUuid.String(), gUuid); err != nil {
+		return nil, err
+	}
+
+	tx := db.Begin()
 
 	// Save role
 	if err := tx.Create(&role).Error; err != nil {
 		tx.Rollback()
-		return nil, err
+		return nil, translateError(err)
 	}
 
 	// Save group_roles
@@ -157,9 +199,11 @@ func (rri RoleRepositoryImpl) SaveWithRelationalData(gUuid string, role entity.R
 	}
 	if err := tx.Create(&groupRole).Error; err != nil {
 		tx.Rollback()
-		return nil, err
+		return nil, translateError(err)
 	}
 
-	tx.Commit()
+	if err := tx.Commit().Error; err != nil {
+		return nil, translateError(err)
+	}
 	return &role, nil
 }
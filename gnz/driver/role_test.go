@@ -1,9 +1,9 @@
 package driver
 
 import (
+	"context"
 	"testing"
 
-	"github.com/jinzhu/gorm"
 	"github.com/tomoyane/grant-n-z/gnz/entity"
 	"github.com/tomoyane/grant-n-z/gnz/log"
 )
@@ -14,14 +14,13 @@ var roleRepository RoleRepository
 func init() {
 	log.InitLogger("info")
 
-	stubConnection, _ := gorm.Open("sqlite3", "/tmp/test_grant_nz.db")
-	connection = stubConnection
+	connection = openTestConnection()
 	roleRepository = GetRoleRepositoryInstance()
 }
 
 // FindAll InternalServerError test
 func TestRoleFindAll_Error(t *testing.T) {
-	_, err := roleRepository.FindAll()
+	_, err := roleRepository.FindAll(context.Background())
 	if err == nil {
 		t.Errorf("Incorrect TestRoleFindAll_Error test")
 		t.FailNow()
@@ -30,7 +29,7 @@ func TestRoleFindAll_Error(t *testing.T) {
 
 // FindOffSetAndLimit InternalServerError test
 func TestRoleFindOffSetAndLimit_Error(t *testing.T) {
-	_, err := roleRepository.FindOffSetAndLimit(1, 1)
+	_, err := roleRepository.FindOffSetAndLimit(context.Background(), 1, 1)
 	if err == nil {
 		t.Errorf("Incorrect TestRoleFindOffSetAndLimit_Error test")
 		t.FailNow()
@@ -39,7 +38,7 @@ func TestRoleFindOffSetAndLimit_Error(t *testing.T) {
 
 // FindByUuid InternalServerError test
 func TestRoleFindById_Error(t *testing.T) {
-	_, err := roleRepository.FindByUuid("uuid")
+	_, err := roleRepository.FindByUuid(context.Background(), "uuid")
 	if err == nil {
 		t.Errorf("Incorrect TestRoleFindById_Error test")
 		t.FailNow()
@@ -48,7 +47,7 @@ func TestRoleFindById_Error(t *testing.T) {
 
 // FindByName InternalServerError test
 func TestRoleFindByName_Error(t *testing.T) {
-	_, err := roleRepository.FindByName("test")
+	_, err := roleRepository.FindByName(context.Background(), "test")
 	if err == nil {
 		t.Errorf("Incorrect TestRoleFindByName_Error test")
 		t.FailNow()
@@ -57,7 +56,7 @@ func TestRoleFindByName_Error(t *testing.T) {
 
 // FindByNames InternalServerError test
 func TestRoleFindByNames_Error(t *testing.T) {
-	_, err := roleRepository.FindByNames([]string{"test"})
+	_, err := roleRepository.FindByNames(context.Background(), []string{"test"})
 	if err == nil {
 		t.Errorf("Incorrect TestRoleFindByNames_Error test")
 		t.FailNow()
@@ -66,7 +65,7 @@ func TestRoleFindByNames_Error(t *testing.T) {
 
 // FindByGroupUuid InternalServerError test
 func TestRoleFindByGroupId_Error(t *testing.T) {
-	_, err := roleRepository.FindByGroupUuid("uuid")
+	_, _, err := roleRepository.FindByGroupUuid(context.Background(), "uuid", Page{})
 	if err == nil {
 		t.Errorf("Incorrect TestRoleFindByGroupId_Error test")
 		t.FailNow()
@@ -75,7 +74,7 @@ func TestRoleFindByGroupId_Error(t *testing.T) {
 
 // FindNameByUuid is nil test
 func TestRoleFindNameById_Nil(t *testing.T) {
-	name := roleRepository.FindNameByUuid("uuid")
+	name := roleRepository.FindNameByUuid(context.Background(), "uuid")
 	if name != nil {
 		t.Errorf("Incorrect TestRoleFindNameById_Nil test")
 		t.FailNow()
@@ -84,7 +83,7 @@ func TestRoleFindNameById_Nil(t *testing.T) {
 
 // Save InternalServerError test
 func TestRoleSave_Error(t *testing.T) {
-	_, err := roleRepository.Save(entity.Role{})
+	_, err := roleRepository.Save(context.Background(), entity.Role{})
 	if err == nil {
 		t.Errorf("Incorrect TestRoleSave_Error test")
 		t.FailNow()
@@ -93,7 +92,7 @@ func TestRoleSave_Error(t *testing.T) {
 
 // SaveWithRelationalData InternalServerError test
 func TestRoleSaveWithRelationalData_Error(t *testing.T) {
-	_, err := roleRepository.SaveWithRelationalData("uuid", entity.Role{})
+	_, err := roleRepository.SaveWithRelationalData(context.Background(), "uuid", entity.Role{})
 	if err == nil {
 		t.Errorf("Incorrect TestRoleSaveWithRelationalData_Error test")
 		t.FailNow()
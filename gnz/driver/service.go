@@ -1,9 +1,12 @@
 package driver
 
 import (
+	"context"
 	"crypto/md5"
 	"encoding/hex"
 	"fmt"
+	"time"
+
 	"github.com/google/uuid"
 	"github.com/jinzhu/gorm"
 
@@ -15,36 +18,38 @@ var srInstance ServiceRepository
 
 type ServiceRepository interface {
 	// Find all Service
-	FindAll() ([]*entity.Service, error)
+	// Keyset pagination, filters and sort of page. Next cursor is empty if it is the last page
+	FindAll(ctx context.Context, page Page) ([]*entity.Service, string, error)
 
 	// Find Service for offset and limit
-	FindOffSetAndLimit(offset int, limit int) ([]*entity.Service, error)
+	FindOffSetAndLimit(ctx context.Context, offset int, limit int) ([]*entity.Service, error)
 
 	// Find Service by service uuid
-	FindByUuid(uuid string) (*entity.Service, error)
+	FindByUuid(ctx context.Context, uuid string) (*entity.Service, error)
 
 	// Find Service by service name
-	FindByName(name string) (*entity.Service, error)
+	FindByName(ctx context.Context, name string) (*entity.Service, error)
 
 	// Find Service by service Client-Secret
-	FindBySecret(apiKey string) (*entity.Service, error)
+	FindBySecret(ctx context.Context, apiKey string) (*entity.Service, error)
 
 	// Find Service name by service uuid
-	FindNameByUuid(uuid string) *string
+	FindNameByUuid(ctx context.Context, uuid string) *string
 
 	// Fin Service by user uuid
-	FindServicesByUserUuid(userUuid string) ([]*entity.Service, error)
+	// Keyset pagination, filters and sort of page. Next cursor is empty if it is the last page
+	FindServicesByUserUuid(ctx context.Context, userUuid string, page Page) ([]*entity.Service, string, error)
 
 	// Save Service
-	Save(service entity.Service) (*entity.Service, error)
+	Save(ctx context.Context, service entity.Service) (*entity.Service, error)
 
 	// Generate Service, ServicePermission, ServiceRole
 	// When generate service, insert initialize permission and role data
 	// Transaction mode
-	SaveWithRelationalData(service entity.Service, roles []entity.Role, permissions []entity.Permission) (*entity.Service, error)
+	SaveWithRelationalData(ctx context.Context, service entity.Service, roles []entity.Role, permissions []entity.Permission) (*entity.Service, error)
 
 	// Update Service
-	Update(service entity.Service) (*entity.Service, error)
+	Update(ctx context.Context, service entity.Service) (*entity.Service, error)
 }
 
 // ServiceRepository struct
@@ -67,96 +72,137 @@ func NewServiceRepository() ServiceRepository {
 	return ServiceRepositoryImpl{Connection: connection}
 }
 
-func (sri ServiceRepositoryImpl) FindAll() ([]*entity.Service, error) {
+func (sri ServiceRepositoryImpl) FindAll(ctx context.Context, page Page) ([]*entity.Service, string, error) {
+	db, cancel := withReadContext(ctx, sri.Connection)
+	defer cancel()
+
+	query, err := page.apply(db, entity.ServiceTable.String(), entity.ServiceName.String())
+	if err != nil {
+		return nil, "", err
+	}
+
 	var services []*entity.Service
-	if err := sri.Connection.Find(&services).Error; err != nil {
-		return nil, err
+	if err := query.Find(&services).Error; err != nil {
+		return nil, "", translateError(err)
 	}
 
-	return services, nil
+	size, next := page.nextCursor(len(services), func(i int) (int, time.Time, string) {
+		return services[i].Id, services[i].CreatedAt, services[i].Name
+	})
+	return services[:size], next, nil
 }
 
-func (sri ServiceRepositoryImpl) FindOffSetAndLimit(offset int, limit int) ([]*entity.Service, error) {
+func (sri ServiceRepositoryImpl) FindOffSetAndLimit(ctx context.Context, offset int, limit int) ([]*entity.Service, error) {
+	db, cancel := withReadContext(ctx, sri.Connection)
+	defer cancel()
+
 	var services []*entity.Service
-	if err := sri.Connection.Limit(limit).Offset(offset).Find(&services).Error; err != nil {
-		return nil, err
+	if err := db.Limit(limit).Offset(offset).Find(&services).Error; err != nil {
+		return nil, translateError(err)
 	}
 
 	return services, nil
 }
 
-func (sri ServiceRepositoryImpl) FindByUuid(uuid string) (*entity.Service, error) {
+func (sri ServiceRepositoryImpl) FindByUuid(ctx context.Context, uuid string) (*entity.Service, error) {
+	db, cancel := withReadContext(ctx, sri.Connection)
+	defer cancel()
+
 	var service entity.Service
-	if err := sri.Connection.Where("uuid = ?", uuid).First(&service).Error; err != nil {
-		return nil, err
+	if err := db.Where("uuid = ?", uuid).First(&service).Error; err != nil {
+		return nil, translateError(err)
 	}
 
 	return &service, nil
 }
 
-func (sri ServiceRepositoryImpl) FindByName(name string) (*entity.Service, error) {
+func (sri ServiceRepositoryImpl) FindByName(ctx context.Context, name string) (*entity.Service, error) {
+	db, cancel := withReadContext(ctx, sri.Connection)
+	defer cancel()
+
 	var service entity.Service
-	if err := sri.Connection.Where("name = ?", name).First(&service).Error; err != nil {
-		return nil, err
+	if err := db.Where("name = ?", name).First(&service).Error; err != nil {
+		return nil, translateError(err)
 	}
 
 	return &service, nil
 }
 
-func (sri ServiceRepositoryImpl) FindBySecret(secret string) (*entity.Service, error) {
+func (sri ServiceRepositoryImpl) FindBySecret(ctx context.Context, secret string) (*entity.Service, error) {
+	db, cancel := withReadContext(ctx, sri.Connection)
+	defer cancel()
+
 	var service entity.Service
-	if err := sri.Connection.Where("secret = ?", secret).First(&service).Error; err != nil {
-		return nil, err
+	if err := db.Where("secret = ?", secret).First(&service).Error; err != nil {
+		return nil, translateError(err)
 	}
 
 	return &service, nil
 }
 
-func (sri ServiceRepositoryImpl) FindNameByUuid(uuid string) *string {
-	service, err := sri.FindByUuid(uuid)
+func (sri ServiceRepositoryImpl) FindNameByUuid(ctx context.Context, uuid string) *string {
+	service, err := sri.FindByUuid(ctx, uuid)
 	if err != nil {
 		return nil
 	}
 	return &service.Name
 }
 
-func (sri ServiceRepositoryImpl) FindServicesByUserUuid(userUuid string) ([]*entity.Service, error) {
+func (sri ServiceRepositoryImpl) FindServicesByUserUuid(ctx context.Context, userUuid string, page Page) ([]*entity.Service, string, error) {
+	db, cancel := withReadContext(ctx, sri.Connection)
+	defer cancel()
+
+	query, err := page.apply(db, entity.ServiceTable.String(), entity.ServiceName.String())
+	if err != nil {
+		return nil, "", err
+	}
+
 	var services []*entity.Service
 
-	if err := sri.Connection.Table(entity.ServiceTable.String()).
-		Select("*").
+	if err := query.Table(entity.ServiceTable.String()).
+		Select(entity.ServiceTable.String()+".*").
 		Joins(fmt.Sprintf("LEFT JOIN %s ON %s.%s = %s.%s",
 			entity.UserServiceTable.String(),
 			entity.ServiceTable.String(),
 			entity.ServiceUuid.String(),
 			entity.UserServiceTable.String(),
 			entity.UserServiceServiceUuid.String())).
+		Where(notDeleted(entity.ServiceTable.String(), entity.UserServiceTable.String())).
 		Where(fmt.Sprintf("%s.%s = ?",
 			entity.UserServiceTable.String(),
 			entity.UserServiceUserUuid.String()), userUuid).
 		Scan(&services).Error; err != nil {
 
-		return nil, err
+		return nil, "", translateError(err)
 	}
 
-	return services, nil
+	size, next := page.nextCursor(len(services), func(i int) (int, time.Time, string) {
+		return services[i].Id, services[i].CreatedAt, services[i].Name
+	})
+	return services[:size], next, nil
 }
 
-func (sri ServiceRepositoryImpl) Save(service entity.Service) (*entity.Service, error) {
-	if err := sri.Connection.Create(&service).Error; err != nil {
-		return nil, err
+func (sri ServiceRepositoryImpl) Save(ctx context.Context, service entity.Service) (*entity.Service, error) {
+	db, cancel := withContext(ctx, sri.Connection)
+	defer cancel()
+
+	if err := db.Create(&service).Error; err != nil {
+		return nil, translateError(err)
 	}
 
 	return &service, nil
 }
 
-func (sri ServiceRepositoryImpl) SaveWithRelationalData(service entity.Service, roles []entity.Role, permissions []entity.Permission) (*entity.Service, error) {
-	tx := sri.Connection.Begin()
+func (sri ServiceRepositoryImpl) SaveWithRelationalData(ctx context.Context, service entity.Service, roles []entity.Role, permissions []entity.Permission) (*entity.Service, error) {
+	db, cancel := withContext(ctx, sri.Connection)
+	defer cancel()
+
+	tx := db.Begin()
 
 	// Save service
 	if err := tx.Create(&service).Error; err != nil {
 		tx.Rollback()
-		return nil, err
+		return nil, translateError(err)
 	}
 
 	// Save service_roles
@@ -169,7 +215,7 @@ func (sri ServiceRepositoryImpl) SaveWithRelationalData(service entity.Service,
 		}
 		if err := tx.Create(&serviceRole).Error; err != nil {
 			tx.Rollback()
-			return nil, err
+			return nil, translateError(err)
 		}
 	}
 
@@ -183,17 +229,22 @@ func (sri ServiceRepositoryImpl) SaveWithRelationalData(service entity.Service,
 		}
 		if err := tx.Create(&servicePermission).Error; err != nil {
 			tx.Rollback()
-			return nil, err
+			return nil, translateError(err)
 		}
 	}
 
-	tx.Commit()
+	if err := tx.Commit().Error; err != nil {
+		return nil, translateError(err)
+	}
 	return &service, nil
 }
 
-func (sri ServiceRepositoryImpl) Update(service entity.Service) (*entity.Service, error) {
-	if err := sri.Connection.Save(&service).Error; err != nil {
-		return nil, err
+func (sri ServiceRepositoryImpl) Update(ctx context.Context, service entity.Service) (*entity.Service, error) {
+	db, cancel := withContext(ctx, sri.Connection)
+	defer cancel()
+
+	if err := db.Save(&service).Error; err != nil {
+		return nil, translateError(err)
 	}
 
 	return &service, nil
@@ -1,9 +1,9 @@
 package driver
 
 import (
+	"context"
 	"testing"
 
-	"github.com/jinzhu/gorm"
 	"github.com/tomoyane/grant-n-z/gnz/entity"
 	"github.com/tomoyane/grant-n-z/gnz/log"
 )
@@ -14,14 +14,13 @@ var serviceRepository ServiceRepository
 func init() {
 	log.InitLogger("info")
 
-	stubConnection, _ := gorm.Open("sqlite3", "/tmp/test_grant_nz.db")
-	connection = stubConnection
+	connection = openTestConnection()
 	serviceRepository = GetServiceRepositoryInstance()
 }
 
 // FindAll InternalServerError test
 func TestServiceFindAllError(t *testing.T) {
-	_, err := serviceRepository.FindAll()
+	_, _, err := serviceRepository.FindAll(context.Background(), Page{})
 	if err == nil {
 		t.Errorf("Incorrect TestServiceFindAllError test")
 		t.FailNow()
@@ -30,7 +29,7 @@ func TestServiceFindAllError(t *testing.T) {
 
 // FindOffSetAndLimit InternalServerError test
 func TestServiceFindOffSetAndLimitError(t *testing.T) {
-	_, err := serviceRepository.FindOffSetAndLimit(1, 1)
+	_, err := serviceRepository.FindOffSetAndLimit(context.Background(), 1, 1)
 	if err == nil {
 		t.Errorf("Incorrect TestServiceFindOffSetAndLimitError test")
 		t.FailNow()
@@ -39,7 +38,7 @@ func TestServiceFindOffSetAndLimitError(t *testing.T) {
 
 // FindByUuid InternalServerError test
 func TestServiceFindByIdError(t *testing.T) {
-	_, err := serviceRepository.FindByUuid("uuid")
+	_, err := serviceRepository.FindByUuid(context.Background(), "uuid")
 	if err == nil {
 		t.Errorf("Incorrect TestServiceFindByIdError test")
 		t.FailNow()
@@ -48,7 +47,7 @@ func TestServiceFindByIdError(t *testing.T) {
 
 // FindByName InternalServerError test
 func TestServiceFindByNameError(t *testing.T) {
-	_, err := serviceRepository.FindByName("test")
+	_, err := serviceRepository.FindByName(context.Background(), "test")
 	if err == nil {
 		t.Errorf("Incorrect TestServiceFindByNameError test")
 		t.FailNow()
@@ -57,7 +56,7 @@ func TestServiceFindByNameError(t *testing.T) {
 
 // FindBySecret InternalServerError test
 func TestServiceFindByApiKeyError(t *testing.T) {
-	_, err := serviceRepository.FindBySecret("test_api_key")
+	_, err := serviceRepository.FindBySecret(context.Background(), "test_api_key")
 	if err == nil {
 		t.Errorf("Incorrect TestServiceFindByApiKeyError test")
 		t.FailNow()
@@ -66,7 +65,7 @@ func TestServiceFindByApiKeyError(t *testing.T) {
 
 // FindNameByUuid is nil test
 func TestServiceFindNameById_Nil(t *testing.T) {
-	name := serviceRepository.FindNameByUuid("uuid")
+	name := serviceRepository.FindNameByUuid(context.Background(), "uuid")
 	if name != nil {
 		t.Errorf("Incorrect TestServiceFindNameById_Nil test")
 		t.FailNow()
@@ -75,7 +74,7 @@ func TestServiceFindNameById_Nil(t *testing.T) {
 
 // FindServicesByUserUuid  InternalServerError test
 func TestServiceFindServicesByUserId_Nil(t *testing.T) {
-	_, err := serviceRepository.FindServicesByUserUuid("uuid")
+	_, _, err := serviceRepository.FindServicesByUserUuid(context.Background(), "uuid", Page{})
 	if err == nil {
 		t.Errorf("Incorrect TestServiceFindServicesByUserId_Nil test")
 		t.FailNow()
@@ -84,7 +83,7 @@ func TestServiceFindServicesByUserId_Nil(t *testing.T) {
 
 // Save InternalServerError test
 func TestServiceSaveError(t *testing.T) {
-	_, err := serviceRepository.Save(entity.Service{})
+	_, err := serviceRepository.Save(context.Background(), entity.Service{})
 	if err == nil {
 		t.Errorf("Incorrect TestServiceSaveError test")
 		t.FailNow()
@@ -93,7 +92,7 @@ func TestServiceSaveError(t *testing.T) {
 
 // SaveWithRelationalData InternalServerError test
 func TestServiceSaveWithRelationalDataError(t *testing.T) {
-	_, err := serviceRepository.SaveWithRelationalData(entity.Service{}, []entity.Role{{}}, []entity.Permission{{}})
+	_, err := serviceRepository.SaveWithRelationalData(context.Background(), entity.Service{}, []entity.Role{{}}, []entity.Permission{{}})
 	if err == nil {
 		t.Errorf("Incorrect TestServiceSaveWithRelationalDataError test")
 		t.FailNow()
@@ -102,7 +101,7 @@ func TestServiceSaveWithRelationalDataError(t *testing.T) {
 
 // Update InternalServerError test
 func TestServiceUpdateError(t *testing.T) {
-	_, err := serviceRepository.Update(entity.Service{})
+	_, err := serviceRepository.Update(context.Background(), entity.Service{})
 	if err == nil {
 		t.Errorf("Incorrect TestServiceSaveError test")
 		t.FailNow()
@@ -0,0 +1,265 @@
+package driver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jinzhu/gorm"
+
+	"github.com/tomoyane/grant-n-z/gnz/entity"
+	"github.com/tomoyane/grant-n-z/gnz/log"
+)
+
+// Column of soft delete in all tables
+// Row that has it is hidden from Find methods of repositories, and is purged after retention period
+// Unique keys, such as users.email, include soft deleted rows, so that restore never conflicts with rows created after delete
+const deletedAtColumn = "deleted_at"
+
+var sdrInstance SoftDeleteRepository
+
+// Foreign key of table that refers to uuid of parent table
+type foreignKey struct {
+	table  string
+	column string
+	parent string
+}
+
+// Foreign keys of all tables, that are the same as migrations
+var foreignKeys = []foreignKey{
+	{entity.UserServiceTable.String(), entity.UserServiceUserUuid.String(), entity.UserTable.String()},
+	{entity.UserServiceTable.String(), entity.UserServiceServiceUuid.String(), entity.ServiceTable.String()},
+	{entity.UserGroupTable.String(), entity.UserGroupUserUuid.String(), entity.UserTable.String()},
+	{entity.UserGroupTable.String(), entity.UserGroupGroupUuid.String(), entity.GroupTable.String()},
+	{entity.ServiceGroupTable.String(), entity.ServiceGroupServiceUuid.String(), entity.ServiceTable.String()},
+	{entity.ServiceGroupTable.String(), entity.ServiceGroupGroupUuid.String(), entity.GroupTable.String()},
+	{entity.ServiceRoleTable.String(), entity.ServiceRoleServiceUuid.String(), entity.ServiceTable.String()},
+	{entity.ServiceRoleTable.String(), entity.ServiceRoleRoleUuid.String(), entity.RoleTable.String()},
+	{entity.ServicePermissionTable.String(), entity.ServicePermissionServiceUuid.String(), entity.ServiceTable.String()},
+	{entity.ServicePermissionTable.String(), entity.ServicePermissionPermissionUuid.String(), entity.PermissionTable.String()},
+	{entity.GroupRoleTable.String(), entity.GroupRoleGroupUuid.String(), entity.GroupTable.String()},
+	{entity.GroupRoleTable.String(), entity.GroupRoleRoleUuid.String(), entity.RoleTable.String()},
+	{entity.GroupPermissionTable.String(), entity.GroupPermissionGroupUuid.String(), entity.GroupTable.String()},
+	{entity.GroupPermissionTable.String(), entity.GroupPermissionPermissionUuid.String(), entity.PermissionTable.String()},
+	{entity.OperatorPolicyTable.String(), entity.OperatorPolicyUserUuid.String(), entity.UserTable.String()},
+	{entity.OperatorPolicyTable.String(), entity.OperatorPolicyRoleUuid.String(), entity.RoleTable.String()},
+	{entity.PolicyTable.String(), entity.PolicyRoleUuid.String(), entity.RoleTable.String()},
+	{entity.PolicyTable.String(), entity.PolicyPermissionUuid.String(), entity.PermissionTable.String()},
+	{entity.PolicyTable.String(), entity.PolicyServiceUuid.String(), entity.ServiceTable.String()},
+	{entity.PolicyTable.String(), entity.PolicyUserGroupUuid.String(), entity.UserGroupTable.String()},
+}
+
+// Tables that can be soft deleted by uuid. Other tables are soft deleted with them
+var softDeleteTables = []string{
+	entity.UserTable.String(),
+	entity.GroupTable.String(),
+	entity.ServiceTable.String(),
+	entity.RoleTable.String(),
+	entity.PermissionTable.String(),
+}
+
+// Tables in order of purge, that is children first
+var purgeTables = []string{
+	entity.PolicyTable.String(),
+	entity.OperatorPolicyTable.String(),
+	entity.UserGroupTable.String(),
+	entity.UserServiceTable.String(),
+	entity.ServiceGroupTable.String(),
+	entity.GroupRoleTable.String(),
+	entity.GroupPermissionTable.String(),
+	entity.ServiceRoleTable.String(),
+	entity.ServicePermissionTable.String(),
+	entity.UserTable.String(),
+	entity.GroupTable.String(),
+	entity.ServiceTable.String(),
+	entity.RoleTable.String(),
+	entity.PermissionTable.String(),
+}
+
+// Rows of table that are soft deleted and restored with other row
+type cascade struct {
+	table     string
+	condition string
+}
+
+type SoftDeleteRepository interface {
+	// Soft delete row of uuid, and memberships and policies that refer to it in one transaction
+	// All rows have the same deleted_at, so that restore finds rows that were deleted with it
+	SoftDelete(ctx context.Context, table string, uuid string) error
+
+	// Restore soft deleted row of uuid, and rows that were soft deleted with it in one transaction
+	// Rows that refer to other soft deleted rows are not restored
+	Restore(ctx context.Context, table string, uuid string) error
+
+	// Delete rows that were soft deleted before the time
+	// Row that is referred to by other rows is deleted after them
+	Purge(ctx context.Context, before time.Time) (int64, error)
+}
+
+type SoftDeleteRepositoryImpl struct {
+	Connection *gorm.DB
+}
+
+func GetSoftDeleteRepositoryInstance() SoftDeleteRepository {
+	if sdrInstance == nil {
+		sdrInstance = NewSoftDeleteRepository()
+	}
+	return sdrInstance
+}
+
+func NewSoftDeleteRepository() SoftDeleteRepository {
+	log.Logger.Info("New `SoftDeleteRepository` instance")
+	return SoftDeleteRepositoryImpl{Connection: connection}
+}
+
+func (sdr SoftDeleteRepositoryImpl) SoftDelete(ctx context.Context, table string, uuid string) error {
+	if !isSoftDeleteTable(table) {
+		return errors.New(fmt.Sprintf("Not supported soft delete table. table = %s", table))
+	}
+
+	db, cancel := withContext(ctx, sdr.Connection)
+	defer cancel()
+
+	deletedAt := time.Now().UTC().Truncate(time.Second)
+	tx := db.Begin()
+
+	result := tx.Exec(fmt.Sprintf("UPDATE %s SET %s = ? WHERE uuid = ? AND %s IS NULL", table, deletedAtColumn, deletedAtColumn), deletedAt, uuid)
+	if result.Error != nil {
+		tx.Rollback()
+		return translateError(result.Error)
+	}
+	if result.RowsAffected == 0 {
+		tx.Rollback()
+		return ErrNotFound
+	}
+
+	for _, c := range cascades(table) {
+		query := fmt.Sprintf("UPDATE %s SET %s = ? WHERE %s AND %s IS NULL", c.table, deletedAtColumn, c.condition, deletedAtColumn)
+		if err := tx.Exec(query, deletedAt, uuid).Error; err != nil {
+			tx.Rollback()
+			return translateError(err)
+		}
+	}
+
+	return translateError(tx.Commit().Error)
+}
+
+func (sdr SoftDeleteRepositoryImpl) Restore(ctx context.Context, table string, uuid string) error {
+	if !isSoftDeleteTable(table) {
+		return errors.New(fmt.Sprintf("Not supported soft delete table. table = %s", table))
+	}
+
+	db, cancel := withContext(ctx, sdr.Connection)
+	defer cancel()
+
+	tx := db.Begin()
+
+	var deleted struct {
+		DeletedAt *time.Time
+	}
+	if err := tx.Raw(fmt.Sprintf("SELECT %s FROM %s WHERE uuid = ?", deletedAtColumn, table), uuid).Scan(&deleted).Error; err != nil {
+		tx.Rollback()
+		return translateError(err)
+	}
+	if deleted.DeletedAt == nil {
+		tx.Rollback()
+		return ErrNotFound
+	}
+
+	if err := tx.Exec(fmt.Sprintf("UPDATE %s SET %s = NULL WHERE uuid = ?", table, deletedAtColumn), uuid).Error; err != nil {
+		tx.Rollback()
+		return translateError(err)
+	}
+
+	// Memberships are restored before policies, because policies refer to user_groups
+	for _, c := range cascades(table) {
+		query := fmt.Sprintf("UPDATE %s SET %s = NULL WHERE %s AND %s = ?", c.table, deletedAtColumn, c.condition, deletedAtColumn)
+		if parents := deletedParents(c.table); parents != "" {
+			query += " AND " + parents
+		}
+		if err := tx.Exec(query, uuid, *deleted.DeletedAt).Error; err != nil {
+			tx.Rollback()
+			return translateError(err)
+		}
+	}
+
+	return translateError(tx.Commit().Error)
+}
+
+func (sdr SoftDeleteRepositoryImpl) Purge(ctx context.Context, before time.Time) (int64, error) {
+	db, cancel := withContext(ctx, sdr.Connection)
+	defer cancel()
+
+	var purged int64
+	for _, table := range purgeTables {
+		query := fmt.Sprintf("DELETE FROM %s WHERE %s IS NOT NULL AND %s < ?", table, deletedAtColumn, deletedAtColumn)
+		for _, fk := range foreignKeys {
+			if fk.parent == table {
+				query += fmt.Sprintf(" AND NOT EXISTS (SELECT 1 FROM %s WHERE %s.%s = %s.uuid)", fk.table, fk.table, fk.column, table)
+			}
+		}
+
+		result := db.Exec(query, before.UTC())
+		if result.Error != nil {
+			return purged, translateError(result.Error)
+		}
+		purged += result.RowsAffected
+	}
+	return purged, nil
+}
+
+// Condition of rows that are not soft deleted in tables
+// Find with entity struct has this condition by gorm, but Table and Joins do not have it
+func notDeleted(tables ...string) string {
+	conditions := make([]string, len(tables))
+	for i, table := range tables {
+		conditions[i] = fmt.Sprintf("%s.%s IS NULL", table, deletedAtColumn)
+	}
+	return strings.Join(conditions, " AND ")
+}
+
+// Rows that refer to row of uuid in table directly or through user_groups, parents first
+// Condition of each cascade has one placeholder of uuid
+func cascades(table string) []cascade {
+	var result []cascade
+	var nested []cascade
+	for _, fk := range foreignKeys {
+		if fk.parent != table {
+			continue
+		}
+		condition := fmt.Sprintf("%s = ?", fk.column)
+		result = append(result, cascade{table: fk.table, condition: condition})
+		for _, child := range foreignKeys {
+			if child.parent == fk.table {
+				nested = append(nested, cascade{
+					table:     child.table,
+					condition: fmt.Sprintf("%s IN (SELECT uuid FROM %s WHERE %s)", child.column, fk.table, condition),
+				})
+			}
+		}
+	}
+	return append(result, nested...)
+}
+
+// Condition that no parent of row in table is soft deleted
+func deletedParents(table string) string {
+	var conditions []string
+	for _, fk := range foreignKeys {
+		if fk.table == table {
+			conditions = append(conditions, fmt.Sprintf("NOT EXISTS (SELECT 1 FROM %s WHERE %s.uuid = %s.%s AND %s.%s IS NOT NULL)",
+				fk.parent, fk.parent, table, fk.column, fk.parent, deletedAtColumn))
+		}
+	}
+	return strings.Join(conditions, " AND ")
+}
+
+func isSoftDeleteTable(table string) bool {
+	for _, t := range softDeleteTables {
+		if t == table {
+			return true
+		}
+	}
+	return false
+}
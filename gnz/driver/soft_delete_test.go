@@ -0,0 +1,235 @@
+package driver
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jinzhu/gorm"
+
+	"github.com/tomoyane/grant-n-z/gnz/entity"
+)
+
+// Rows of one user that has policy of one group
+type softDeleteRows struct {
+	user       entity.User
+	group      entity.Group
+	role       entity.Role
+	permission entity.Permission
+	service    entity.Service
+	userGroup  entity.UserGroup
+}
+
+// Setup sqlite that has user, group, and policy of them
+func setUpSoftDelete(t *testing.T) (*gorm.DB, softDeleteRows) {
+	db := openSqliteConnection(t)
+	if _, err := (MigratorImpl{Connection: db, Migrations: Migrations}).Up(context.Background()); err != nil {
+		t.Errorf("Incorrect soft delete test. err = %v", err)
+		t.FailNow()
+	}
+
+	rows := softDeleteRows{
+		user:       entity.User{InternalId: "internal", Uuid: uuid.New(), Username: "test", Email: "test@gmail.com", Password: "password"},
+		group:      entity.Group{InternalId: "internal", Uuid: uuid.New(), Name: "group"},
+		role:       entity.Role{InternalId: "internal", Uuid: uuid.New(), Name: "role"},
+		permission: entity.Permission{InternalId: "internal", Uuid: uuid.New(), Name: "permission"},
+		service:    entity.Service{InternalId: "internal", Uuid: uuid.New(), Name: "service", Secret: "secret"},
+	}
+	rows.userGroup = entity.UserGroup{InternalId: "internal", Uuid: uuid.New(), UserUuid: rows.user.Uuid, GroupUuid: rows.group.Uuid}
+	values := []interface{}{
+		&rows.user, &rows.group, &rows.role, &rows.permission, &rows.service, &rows.userGroup,
+		&entity.GroupRole{InternalId: "internal", GroupUuid: rows.group.Uuid, RoleUuid: rows.role.Uuid},
+		&entity.Policy{InternalId: "internal", Name: "policy", RoleUuid: rows.role.Uuid, PermissionUuid: rows.permission.Uuid,
+			ServiceUuid: rows.service.Uuid, UserGroupUuid: rows.userGroup.Uuid},
+	}
+	for _, value := range values {
+		if err := db.Create(value).Error; err != nil {
+			t.Errorf("Incorrect soft delete test. err = %v", err)
+			t.FailNow()
+		}
+	}
+	return db, rows
+}
+
+// Count rows that are not soft deleted
+func countLive(db *gorm.DB, table string) int {
+	var count int
+	db.Table(table).Where(notDeleted(table)).Count(&count)
+	return count
+}
+
+// Soft delete hides rows and memberships, and restore shows them test
+func TestSoftDelete(t *testing.T) {
+	db, rows := setUpSoftDelete(t)
+	defer db.Close()
+	repository := SoftDeleteRepositoryImpl{Connection: db}
+	ctx := context.Background()
+
+	if err := repository.SoftDelete(ctx, entity.GroupTable.String(), rows.group.Uuid.String()); err != nil {
+		t.Errorf("Incorrect TestSoftDelete test. err = %v", err)
+		t.FailNow()
+	}
+	if _, err := (GroupRepositoryImpl{Connection: db}).FindByUuid(ctx, rows.group.Uuid.String()); err != ErrNotFound {
+		t.Errorf("Incorrect TestSoftDelete test. err = %v", err)
+		t.FailNow()
+	}
+	groups, _, err := GroupRepositoryImpl{Connection: db}.FindByUserUuid(ctx, rows.user.Uuid.String(), Page{})
+	if err != nil || len(groups) != 0 {
+		t.Errorf("Incorrect TestSoftDelete test. groups = %v, err = %v", groups, err)
+		t.FailNow()
+	}
+	if countLive(db, entity.UserGroupTable.String()) != 0 || countLive(db, entity.GroupRoleTable.String()) != 0 || countLive(db, entity.PolicyTable.String()) != 0 {
+		t.Errorf("Incorrect TestSoftDelete test")
+		t.FailNow()
+	}
+	if countLive(db, entity.UserTable.String()) != 1 || countLive(db, entity.RoleTable.String()) != 1 {
+		t.Errorf("Incorrect TestSoftDelete test")
+		t.FailNow()
+	}
+
+	if err := repository.SoftDelete(ctx, entity.GroupTable.String(), rows.group.Uuid.String()); err != ErrNotFound {
+		t.Errorf("Incorrect TestSoftDelete test. err = %v", err)
+		t.FailNow()
+	}
+
+	if err := repository.Restore(ctx, entity.GroupTable.String(), rows.group.Uuid.String()); err != nil {
+		t.Errorf("Incorrect TestSoftDelete test. err = %v", err)
+		t.FailNow()
+	}
+	policies, err := PolicyRepositoryImpl{Connection: db}.FindPolicyOfUserGroupByUserUuids(ctx, []string{rows.user.Uuid.String()})
+	if err != nil || len(policies) != 1 || countLive(db, entity.GroupRoleTable.String()) != 1 {
+		t.Errorf("Incorrect TestSoftDelete test. policies = %v, err = %v", policies, err)
+		t.FailNow()
+	}
+
+	if err := repository.Restore(ctx, entity.GroupTable.String(), rows.group.Uuid.String()); err != ErrNotFound {
+		t.Errorf("Incorrect TestSoftDelete test. err = %v", err)
+		t.FailNow()
+	}
+}
+
+// Restore does not restore rows that refer to other soft deleted rows test
+func TestSoftDeleteRestore_DeletedParent(t *testing.T) {
+	db, rows := setUpSoftDelete(t)
+	defer db.Close()
+	repository := SoftDeleteRepositoryImpl{Connection: db}
+	ctx := context.Background()
+
+	repository.SoftDelete(ctx, entity.UserTable.String(), rows.user.Uuid.String())
+	repository.SoftDelete(ctx, entity.RoleTable.String(), rows.role.Uuid.String())
+
+	if err := repository.Restore(ctx, entity.UserTable.String(), rows.user.Uuid.String()); err != nil {
+		t.Errorf("Incorrect TestSoftDeleteRestore_DeletedParent test. err = %v", err)
+		t.FailNow()
+	}
+	if countLive(db, entity.UserGroupTable.String()) != 1 || countLive(db, entity.PolicyTable.String()) != 0 {
+		t.Errorf("Incorrect TestSoftDeleteRestore_DeletedParent test")
+		t.FailNow()
+	}
+
+	if err := repository.Restore(ctx, entity.RoleTable.String(), rows.role.Uuid.String()); err != nil {
+		t.Errorf("Incorrect TestSoftDeleteRestore_DeletedParent test. err = %v", err)
+		t.FailNow()
+	}
+	if countLive(db, entity.PolicyTable.String()) != 1 {
+		t.Errorf("Incorrect TestSoftDeleteRestore_DeletedParent test")
+		t.FailNow()
+	}
+}
+
+// Purge deletes soft deleted rows after retention period test
+func TestSoftDeletePurge(t *testing.T) {
+	db, rows := setUpSoftDelete(t)
+	defer db.Close()
+	repository := SoftDeleteRepositoryImpl{Connection: db}
+	ctx := context.Background()
+
+	repository.SoftDelete(ctx, entity.UserTable.String(), rows.user.Uuid.String())
+	if purged, err := repository.Purge(ctx, time.Now().Add(-time.Hour)); err != nil || purged != 0 {
+		t.Errorf("Incorrect TestSoftDeletePurge test. purged = %d, err = %v", purged, err)
+		t.FailNow()
+	}
+
+	// user, user_groups and policies are purged, and group that is not soft deleted remains
+	purged, err := repository.Purge(ctx, time.Now().Add(time.Hour))
+	if err != nil || purged != 3 {
+		t.Errorf("Incorrect TestSoftDeletePurge test. purged = %d, err = %v", purged, err)
+		t.FailNow()
+	}
+	if err := repository.Restore(ctx, entity.UserTable.String(), rows.user.Uuid.String()); err != ErrNotFound {
+		t.Errorf("Incorrect TestSoftDeletePurge test. err = %v", err)
+		t.FailNow()
+	}
+	if countLive(db, entity.GroupTable.String()) != 1 || countLive(db, entity.GroupRoleTable.String()) != 1 {
+		t.Errorf("Incorrect TestSoftDeletePurge test")
+		t.FailNow()
+	}
+}
+
+// Email of soft deleted user is reserved until purge, and membership is not test
+func TestSoftDelete_UniqueKey(t *testing.T) {
+	db, rows := setUpSoftDelete(t)
+	defer db.Close()
+	repository := SoftDeleteRepositoryImpl{Connection: db}
+	userRepository := UserRepositoryImpl{Connection: db}
+	ctx := context.Background()
+
+	// Membership of soft deleted user group is hidden, and can be added again
+	db.Exec("UPDATE user_groups SET deleted_at = ? WHERE uuid = ?", time.Now().UTC(), rows.userGroup.Uuid.String())
+	if _, err := userRepository.FindUserGroupByUserUuidAndGroupUuid(ctx, rows.user.Uuid.String(), rows.group.Uuid.String()); err != ErrNotFound {
+		t.Errorf("Incorrect TestSoftDelete_UniqueKey test. err = %v", err)
+		t.FailNow()
+	}
+	userGroup := entity.UserGroup{InternalId: "internal", Uuid: uuid.New(), UserUuid: rows.user.Uuid, GroupUuid: rows.group.Uuid}
+	if _, err := userRepository.SaveUserGroup(ctx, userGroup); err != nil {
+		t.Errorf("Incorrect TestSoftDelete_UniqueKey test. err = %v", err)
+		t.FailNow()
+	}
+
+	repository.SoftDelete(ctx, entity.UserTable.String(), rows.user.Uuid.String())
+	user := entity.User{InternalId: "internal", Uuid: uuid.New(), Username: "test", Email: rows.user.Email, Password: "password"}
+	if _, err := userRepository.FindByEmail(ctx, user.Email); err != ErrNotFound {
+		t.Errorf("Incorrect TestSoftDelete_UniqueKey test. err = %v", err)
+		t.FailNow()
+	}
+	if _, err := userRepository.SaveUser(ctx, user); err != ErrDuplicate {
+		t.Errorf("Incorrect TestSoftDelete_UniqueKey test. err = %v", err)
+		t.FailNow()
+	}
+
+	if _, err := repository.Purge(ctx, time.Now().Add(time.Hour)); err != nil {
+		t.Errorf("Incorrect TestSoftDelete_UniqueKey test. err = %v", err)
+		t.FailNow()
+	}
+	if _, err := userRepository.SaveUser(ctx, user); err != nil {
+		t.Errorf("Incorrect TestSoftDelete_UniqueKey test. err = %v", err)
+		t.FailNow()
+	}
+}
+
+// Not supported table test
+func TestSoftDelete_UnknownTable(t *testing.T) {
+	repository := SoftDeleteRepositoryImpl{}
+	if err := repository.SoftDelete(context.Background(), entity.PolicyTable.String(), "uuid"); err == nil {
+		t.Errorf("Incorrect TestSoftDelete_UnknownTable test")
+		t.FailNow()
+	}
+	if err := repository.Restore(context.Background(), entity.PolicyTable.String(), "uuid"); err == nil {
+		t.Errorf("Incorrect TestSoftDelete_UnknownTable test")
+		t.FailNow()
+	}
+}
+
+// Cascades of user test
+func TestCascades(t *testing.T) {
+	var tables []string
+	for _, c := range cascades(entity.UserTable.String()) {
+		tables = append(tables, c.table)
+	}
+	if strings.Join(tables, ",") != "user_services,user_groups,operator_policies,policies" {
+		t.Errorf("Incorrect TestCascades test. tables = %v", tables)
+		t.FailNow()
+	}
+}
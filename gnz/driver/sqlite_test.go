@@ -0,0 +1,236 @@
+package driver
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jinzhu/gorm"
+
+	"github.com/tomoyane/grant-n-z/gnz/common"
+	"github.com/tomoyane/grant-n-z/gnz/entity"
+)
+
+// In-memory sqlite, the same as Connect of sqlite engine
+func openSqliteConnection(t *testing.T) *gorm.DB {
+	dialect, dsn, err := BuildDataSource(common.DbConfig{Engine: "sqlite", Name: ":memory:"})
+	if err != nil {
+		t.Errorf("Incorrect sqlite test. err = %v", err)
+		t.FailNow()
+	}
+	db, err := gorm.Open(dialect, dsn)
+	if err != nil {
+		t.Errorf("Incorrect sqlite test. err = %v", err)
+		t.FailNow()
+	}
+	setConnectionPool(db, dialect, common.DbConfig{})
+	return db
+}
+
+// Connection of sqlite is not recycled by conn max lifetime test
+func TestSetConnectionPool_Sqlite(t *testing.T) {
+	dialect, dsn, _ := BuildDataSource(common.DbConfig{Engine: "sqlite", Name: ":memory:"})
+	db, err := gorm.Open(dialect, dsn)
+	if err != nil {
+		t.Errorf("Incorrect TestSetConnectionPool_Sqlite test. err = %v", err)
+		t.FailNow()
+	}
+	defer db.Close()
+	setConnectionPool(db, dialect, common.DbConfig{MaxOpenConnection: "5", MaxIdleConnection: "2", ConnMaxLifetime: 1})
+
+	if err := db.Exec("CREATE TABLE lifetime (id INTEGER)").Error; err != nil {
+		t.Errorf("Incorrect TestSetConnectionPool_Sqlite test. err = %v", err)
+		t.FailNow()
+	}
+
+	// Age out the connection, if it has lifetime
+	time.Sleep(10 * time.Millisecond)
+	if err := db.Exec("INSERT INTO lifetime (id) VALUES (1)").Error; err != nil {
+		t.Errorf("Incorrect TestSetConnectionPool_Sqlite test. err = %v", err)
+		t.FailNow()
+	}
+	if stats := db.DB().Stats(); stats.MaxOpenConnections != 1 || stats.MaxLifetimeClosed != 0 {
+		t.Errorf("Incorrect TestSetConnectionPool_Sqlite test. stats = %v", stats)
+		t.FailNow()
+	}
+}
+
+// Repository on sqlite and constraint errors test
+func TestUserRepository_Sqlite(t *testing.T) {
+	db := openSqliteConnection(t)
+	defer db.Close()
+	if _, err := (MigratorImpl{Connection: db, Migrations: Migrations}).Up(context.Background()); err != nil {
+		t.Errorf("Incorrect TestUserRepository_Sqlite test. err = %v", err)
+		t.FailNow()
+	}
+	repository := UserRepositoryImpl{Connection: db}
+
+	user := entity.User{InternalId: "internal", Uuid: uuid.New(), Username: "test", Email: "test@gmail.com", Password: "password"}
+	if _, err := repository.SaveUser(context.Background(), user); err != nil {
+		t.Errorf("Incorrect TestUserRepository_Sqlite test. err = %v", err)
+		t.FailNow()
+	}
+
+	found, err := repository.FindByEmail(context.Background(), "test@gmail.com")
+	if err != nil || found == nil || found.Uuid != user.Uuid {
+		t.Errorf("Incorrect TestUserRepository_Sqlite test. user = %v, err = %v", found, err)
+		t.FailNow()
+	}
+
+	if _, err := repository.FindByEmail(context.Background(), "none@gmail.com"); err != ErrNotFound {
+		t.Errorf("Incorrect TestUserRepository_Sqlite test. err = %v", err)
+		t.FailNow()
+	}
+
+	user.Uuid = uuid.New()
+	if _, err := repository.SaveUser(context.Background(), user); err != ErrDuplicate {
+		t.Errorf("Incorrect TestUserRepository_Sqlite test. err = %v", err)
+		t.FailNow()
+	}
+
+	userService := entity.UserService{InternalId: "internal", UserUuid: found.Uuid, ServiceUuid: uuid.New()}
+	if _, err := repository.SaveUserService(context.Background(), userService); err != ErrForeignKey {
+		t.Errorf("Incorrect TestUserRepository_Sqlite test. err = %v", err)
+		t.FailNow()
+	}
+}
+
+// Update of user by version test
+func TestUserUpdateUser_Version(t *testing.T) {
+	db, rows := setUpSoftDelete(t)
+	defer db.Close()
+	repository := UserRepositoryImpl{Connection: db}
+	ctx := context.Background()
+
+	user := rows.user
+	user.Username = "updated"
+	updatedUser, err := repository.UpdateUser(ctx, user)
+	if err != nil || updatedUser.Version != 2 || updatedUser.Username != "updated" {
+		t.Errorf("Incorrect TestUserUpdateUser_Version test. user = %v, err = %v", updatedUser, err)
+		t.FailNow()
+	}
+
+	if _, err := repository.UpdateUser(ctx, user); err != ErrStaleVersion {
+		t.Errorf("Incorrect TestUserUpdateUser_Version test. err = %v", err)
+		t.FailNow()
+	}
+
+	user.Uuid = uuid.New()
+	if _, err := repository.UpdateUser(ctx, user); err != ErrNotFound {
+		t.Errorf("Incorrect TestUserUpdateUser_Version test. err = %v", err)
+		t.FailNow()
+	}
+}
+
+// Update and create of policy by version test
+func TestPolicyUpdate_Version(t *testing.T) {
+	db, rows := setUpSoftDelete(t)
+	defer db.Close()
+	repository := PolicyRepositoryImpl{Connection: db}
+	ctx := context.Background()
+
+	policy := entity.Policy{InternalId: "internal", Name: "updated", RoleUuid: rows.role.Uuid, PermissionUuid: rows.permission.Uuid,
+		ServiceUuid: rows.service.Uuid, UserGroupUuid: rows.userGroup.Uuid}
+	if _, err := repository.Update(ctx, policy); err != ErrStaleVersion {
+		t.Errorf("Incorrect TestPolicyUpdate_Version test. err = %v", err)
+		t.FailNow()
+	}
+
+	policy.Version = 1
+	updatedPolicy, err := repository.Update(ctx, policy)
+	if err != nil || updatedPolicy.Version != 2 || updatedPolicy.Name != "updated" {
+		t.Errorf("Incorrect TestPolicyUpdate_Version test. policy = %v, err = %v", updatedPolicy, err)
+		t.FailNow()
+	}
+	if _, err := repository.Update(ctx, policy); err != ErrStaleVersion {
+		t.Errorf("Incorrect TestPolicyUpdate_Version test. err = %v", err)
+		t.FailNow()
+	}
+
+	response, err := repository.FindPolicyOfUserGroupByUserUuidAndGroupUuid(ctx, rows.user.Uuid.String(), rows.group.Uuid.String())
+	if err != nil || response.PolicyVersion != 2 {
+		t.Errorf("Incorrect TestPolicyUpdate_Version test. policy = %v, err = %v", response, err)
+		t.FailNow()
+	}
+
+	// Policy is created only if it does not exist
+	db.Exec("DELETE FROM policies")
+	policy.Version = 0
+	createdPolicy, err := repository.Update(ctx, policy)
+	if err != nil || createdPolicy.Version != 1 {
+		t.Errorf("Incorrect TestPolicyUpdate_Version test. policy = %v, err = %v", createdPolicy, err)
+		t.FailNow()
+	}
+}
+
+// Concurrent create of policy of the same user group test
+func TestPolicyUpdate_ConcurrentCreate(t *testing.T) {
+	db, rows := setUpSoftDelete(t)
+	defer db.Close()
+	db.Exec("DELETE FROM policies")
+	repository := PolicyRepositoryImpl{Connection: db}
+
+	policy := entity.Policy{InternalId: "internal", Name: "created", RoleUuid: rows.role.Uuid, PermissionUuid: rows.permission.Uuid,
+		ServiceUuid: rows.service.Uuid, UserGroupUuid: rows.userGroup.Uuid}
+	errs := make(chan error, 10)
+	var wg sync.WaitGroup
+	for i := 0; i < cap(errs); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := repository.Update(context.Background(), policy)
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	created := 0
+	for err := range errs {
+		if err == nil {
+			created++
+		} else if err != ErrStaleVersion {
+			t.Errorf("Incorrect TestPolicyUpdate_ConcurrentCreate test. err = %v", err)
+			t.FailNow()
+		}
+	}
+	if created != 1 || countLive(db, entity.PolicyTable.String()) != 1 {
+		t.Errorf("Incorrect TestPolicyUpdate_ConcurrentCreate test. created = %d", created)
+		t.FailNow()
+	}
+
+	// Other request creates policy between find and create
+	db.Exec("DELETE FROM policies")
+	// Repository opens gorm.DB of each call, that has default callbacks
+	gorm.DefaultCallback.Create().Before("gorm:create").Register("test:concurrent_create", func(scope *gorm.Scope) {
+		scope.SQLDB().Exec("INSERT INTO policies (internal_id, name, role_uuid, permission_uuid, service_uuid, user_group_uuid) VALUES (?, ?, ?, ?, ?, ?)",
+			"internal", "concurrent", rows.role.Uuid.String(), rows.permission.Uuid.String(), rows.service.Uuid.String(), rows.userGroup.Uuid.String())
+	})
+	defer gorm.DefaultCallback.Create().Remove("test:concurrent_create")
+	if _, err := repository.Update(context.Background(), policy); err != ErrStaleVersion {
+		t.Errorf("Incorrect TestPolicyUpdate_ConcurrentCreate test. err = %v", err)
+		t.FailNow()
+	}
+}
+
+// Policy that was soft deleted with its role is replaced by create test
+func TestPolicyUpdate_CreateSoftDeleted(t *testing.T) {
+	db, rows := setUpSoftDelete(t)
+	defer db.Close()
+	if err := (SoftDeleteRepositoryImpl{Connection: db}).SoftDelete(context.Background(), entity.RoleTable.String(), rows.role.Uuid.String()); err != nil {
+		t.Errorf("Incorrect TestPolicyUpdate_CreateSoftDeleted test. err = %v", err)
+		t.FailNow()
+	}
+
+	role := entity.Role{InternalId: "internal", Uuid: uuid.New(), Name: "new role"}
+	db.Create(&role)
+	policy := entity.Policy{InternalId: "internal", Name: "created", RoleUuid: role.Uuid, PermissionUuid: rows.permission.Uuid,
+		ServiceUuid: rows.service.Uuid, UserGroupUuid: rows.userGroup.Uuid}
+	createdPolicy, err := (PolicyRepositoryImpl{Connection: db}).Update(context.Background(), policy)
+	if err != nil || createdPolicy.Version != 1 || createdPolicy.RoleUuid != role.Uuid {
+		t.Errorf("Incorrect TestPolicyUpdate_CreateSoftDeleted test. policy = %v, err = %v", createdPolicy, err)
+		t.FailNow()
+	}
+}
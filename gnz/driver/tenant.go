@@ -0,0 +1,124 @@
+package driver
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/jinzhu/gorm"
+
+	"github.com/tomoyane/grant-n-z/gnz/entity"
+)
+
+type tenantKey struct{}
+
+// Data that belongs to service, that is the tenant of data, through relational table
+// Repositories scope reads of the data to tenant of ctx, and reject writes that refer to data of other tenant
+type tenantScope int
+
+const (
+	// Group of service_groups
+	groupTenant tenantScope = iota
+
+	// User of user_services
+	userTenant
+
+	// Role of service_roles, or group_roles of group of tenant
+	roleTenant
+
+	// Permission of service_permissions, or group_permissions of group of tenant
+	permissionTenant
+)
+
+// Get context whose repository calls are scoped to the service, that is the tenant of Client-Secret
+// Context without tenant, such as operator and cacher, is not scoped
+func WithTenant(ctx context.Context, serviceUuid string) context.Context {
+	return context.WithValue(ctx, tenantKey{}, serviceUuid)
+}
+
+func tenantOf(ctx context.Context) (string, bool) {
+	tenant, ok := ctx.Value(tenantKey{}).(string)
+	return tenant, ok && tenant != ""
+}
+
+// Check that service of data that the caller writes is tenant of ctx. If not, ErrTenant
+func checkTenantService(ctx context.Context, serviceUuid string) error {
+	if tenant, ok := tenantOf(ctx); ok && !strings.EqualFold(tenant, serviceUuid) {
+		return ErrTenant
+	}
+	return nil
+}
+
+// Scope query to rows whose data of column belongs to tenant of ctx
+func (ts tenantScope) scope(ctx context.Context, db *gorm.DB, column string) *gorm.DB {
+	tenant, ok := tenantOf(ctx)
+	if !ok {
+		return db
+	}
+
+	condition := ts.condition(column)
+	args := make([]interface{}, strings.Count(condition, "?"))
+	for i := range args {
+		args[i] = tenant
+	}
+	return db.Where(condition, args...)
+}
+
+// Check that row of table whose uuid is value belongs to tenant of ctx through column. If not, ErrTenant
+func (ts tenantScope) check(ctx context.Context, db *gorm.DB, table string, column string, value string) error {
+	if _, ok := tenantOf(ctx); !ok {
+		return nil
+	}
+
+	var count int
+	query := db.Table(table).Where(fmt.Sprintf("%s.uuid = ?", table), value)
+	if err := ts.scope(ctx, query, fmt.Sprintf("%s.%s", table, column)).Count(&count).Error; err != nil {
+		return translateError(err)
+	}
+	if count == 0 {
+		return ErrTenant
+	}
+	return nil
+}
+
+// Condition that data of column belongs to tenant, whose placeholders are all uuid of tenant
+func (ts tenantScope) condition(column string) string {
+	switch ts {
+	case groupTenant:
+		return existsOfService(entity.ServiceGroupTable.String(), entity.ServiceGroupGroupUuid.String(), column)
+	case userTenant:
+		return existsOfService(entity.UserServiceTable.String(), entity.UserServiceUserUuid.String(), column)
+	case roleTenant:
+		return fmt.Sprintf("(%s OR EXISTS (SELECT 1 FROM %s WHERE %s.%s = %s AND %s AND %s))",
+			existsOfService(entity.ServiceRoleTable.String(), entity.ServiceRoleRoleUuid.String(), column),
+			entity.GroupRoleTable.String(),
+			entity.GroupRoleTable.String(),
+			entity.GroupRoleRoleUuid.String(),
+			column,
+			notDeleted(entity.GroupRoleTable.String()),
+			groupTenant.condition(entity.GroupRoleTable.String()+"."+entity.GroupRoleGroupUuid.String()))
+	case permissionTenant:
+		return fmt.Sprintf("(%s OR EXISTS (SELECT 1 FROM %s WHERE %s.%s = %s AND %s AND %s))",
+			existsOfService(entity.ServicePermissionTable.String(), entity.ServicePermissionPermissionUuid.String(), column),
+			entity.GroupPermissionTable.String(),
+			entity.GroupPermissionTable.String(),
+			entity.GroupPermissionPermissionUuid.String(),
+			column,
+			notDeleted(entity.GroupPermissionTable.String()),
+			groupTenant.condition(entity.GroupPermissionTable.String()+"."+entity.GroupPermissionGroupUuid.String()))
+	}
+	panic("Unknown value")
+}
+
+// Condition that relational table has row of column and tenant
+// Every relational table of service has service_uuid
+func existsOfService(table string, relationColumn string, column string) string {
+	return fmt.Sprintf("EXISTS (SELECT 1 FROM %s WHERE %s.%s = %s AND %s.%s = ? AND %s)",
+		table,
+		table,
+		relationColumn,
+		column,
+		table,
+		entity.ServiceGroupServiceUuid.String(),
+		notDeleted(table))
+}
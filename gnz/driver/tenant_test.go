@@ -0,0 +1,152 @@
+package driver
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jinzhu/gorm"
+
+	"github.com/tomoyane/grant-n-z/gnz/entity"
+)
+
+// Data of one service
+type tenantRows struct {
+	service    entity.Service
+	user       entity.User
+	group      entity.Group
+	role       entity.Role
+	permission entity.Permission
+	userGroup  entity.UserGroup
+}
+
+// Set up sqlite that has data of two services
+func setUpTenant(t *testing.T) (*gorm.DB, tenantRows, tenantRows) {
+	db := openSqliteConnection(t)
+	if _, err := (MigratorImpl{Connection: db, Migrations: Migrations}).Up(context.Background()); err != nil {
+		t.Errorf("Incorrect tenant test. err = %v", err)
+		t.FailNow()
+	}
+
+	var tenants []tenantRows
+	for _, name := range []string{"a", "b"} {
+		rows := tenantRows{
+			service:    entity.Service{InternalId: "internal", Uuid: uuid.New(), Name: name, Secret: name},
+			user:       entity.User{InternalId: "internal", Uuid: uuid.New(), Username: name, Email: name + "@gmail.com", Password: "password"},
+			group:      entity.Group{InternalId: "internal", Uuid: uuid.New(), Name: name},
+			role:       entity.Role{InternalId: "internal", Uuid: uuid.New(), Name: name},
+			permission: entity.Permission{InternalId: "internal", Uuid: uuid.New(), Name: name},
+		}
+		rows.userGroup = entity.UserGroup{InternalId: "internal", Uuid: uuid.New(), UserUuid: rows.user.Uuid, GroupUuid: rows.group.Uuid}
+		values := []interface{}{
+			&rows.service, &rows.user, &rows.group, &rows.role, &rows.permission, &rows.userGroup,
+			&entity.UserService{InternalId: "internal", UserUuid: rows.user.Uuid, ServiceUuid: rows.service.Uuid},
+			&entity.ServiceGroup{InternalId: "internal", GroupUuid: rows.group.Uuid, ServiceUuid: rows.service.Uuid},
+			&entity.ServiceRole{InternalId: "internal", RoleUuid: rows.role.Uuid, ServiceUuid: rows.service.Uuid},
+			&entity.GroupPermission{InternalId: "internal", PermissionUuid: rows.permission.Uuid, GroupUuid: rows.group.Uuid},
+		}
+		for _, value := range values {
+			if err := db.Create(value).Error; err != nil {
+				t.Errorf("Incorrect tenant test. err = %v", err)
+				t.FailNow()
+			}
+		}
+		tenants = append(tenants, rows)
+	}
+	return db, tenants[0], tenants[1]
+}
+
+// Reads of other tenant are not found test
+func TestTenant_Read(t *testing.T) {
+	db, a, b := setUpTenant(t)
+	defer db.Close()
+	ctx := WithTenant(context.Background(), a.service.Uuid.String())
+
+	groupRepository := GroupRepositoryImpl{Connection: db}
+	if _, err := groupRepository.FindByUuid(ctx, a.group.Uuid.String()); err != nil {
+		t.Errorf("Incorrect TestTenant_Read test. err = %v", err)
+		t.FailNow()
+	}
+	if _, err := groupRepository.FindByUuid(ctx, b.group.Uuid.String()); err != ErrNotFound {
+		t.Errorf("Incorrect TestTenant_Read test. err = %v", err)
+		t.FailNow()
+	}
+	if groups, _, err := groupRepository.FindByServiceUuid(ctx, b.service.Uuid.String(), Page{}); err != nil || len(groups) != 0 {
+		t.Errorf("Incorrect TestTenant_Read test. groups = %v, err = %v", groups, err)
+		t.FailNow()
+	}
+
+	userRepository := UserRepositoryImpl{Connection: db}
+	if users, _, err := userRepository.FindByGroupUuid(ctx, a.group.Uuid.String(), Page{}); err != nil || len(users) != 1 {
+		t.Errorf("Incorrect TestTenant_Read test. users = %v, err = %v", users, err)
+		t.FailNow()
+	}
+	if users, _, err := userRepository.FindByGroupUuid(ctx, b.group.Uuid.String(), Page{}); err != nil || len(users) != 0 {
+		t.Errorf("Incorrect TestTenant_Read test. users = %v, err = %v", users, err)
+		t.FailNow()
+	}
+	if _, err := userRepository.FindUserGroupByUserUuidAndGroupUuid(ctx, b.user.Uuid.String(), b.group.Uuid.String()); err != ErrNotFound {
+		t.Errorf("Incorrect TestTenant_Read test. err = %v", err)
+		t.FailNow()
+	}
+
+	if permissions, _, err := (PermissionRepositoryImpl{Connection: db}).FindByGroupUuid(ctx, b.group.Uuid.String(), Page{}); err != nil || len(permissions) != 0 {
+		t.Errorf("Incorrect TestTenant_Read test. permissions = %v, err = %v", permissions, err)
+		t.FailNow()
+	}
+
+	// Context without tenant is not scoped
+	if _, err := groupRepository.FindByUuid(context.Background(), b.group.Uuid.String()); err != nil {
+		t.Errorf("Incorrect TestTenant_Read test. err = %v", err)
+		t.FailNow()
+	}
+}
+
+// Writes that refer to data of other tenant are rejected test
+func TestTenant_Write(t *testing.T) {
+	db, a, b := setUpTenant(t)
+	defer db.Close()
+	ctx := WithTenant(context.Background(), a.service.Uuid.String())
+
+	userRepository := UserRepositoryImpl{Connection: db}
+	userGroups := []entity.UserGroup{
+		{InternalId: "internal", Uuid: uuid.New(), UserUuid: b.user.Uuid, GroupUuid: a.group.Uuid},
+		{InternalId: "internal", Uuid: uuid.New(), UserUuid: a.user.Uuid, GroupUuid: b.group.Uuid},
+	}
+	for _, userGroup := range userGroups {
+		if _, err := userRepository.SaveUserGroup(ctx, userGroup); err != ErrTenant {
+			t.Errorf("Incorrect TestTenant_Write test. err = %v", err)
+			t.FailNow()
+		}
+	}
+
+	role := entity.Role{InternalId: "internal", Uuid: uuid.New(), Name: "role"}
+	if _, err := (RoleRepositoryImpl{Connection: db}).SaveWithRelationalData(ctx, b.group.Uuid.String(), role); err != ErrTenant {
+		t.Errorf("Incorrect TestTenant_Write test. err = %v", err)
+		t.FailNow()
+	}
+
+	policyRepository := PolicyRepositoryImpl{Connection: db}
+	policy := entity.Policy{InternalId: "internal", Name: "policy", RoleUuid: a.role.Uuid, PermissionUuid: a.permission.Uuid,
+		ServiceUuid: a.service.Uuid, UserGroupUuid: a.userGroup.Uuid}
+	policies := []entity.Policy{policy, policy, policy, policy}
+	policies[0].ServiceUuid = b.service.Uuid
+	policies[1].UserGroupUuid = b.userGroup.Uuid
+	policies[2].RoleUuid = b.role.Uuid
+	policies[3].PermissionUuid = b.permission.Uuid
+	for _, p := range policies {
+		if _, err := policyRepository.Update(ctx, p); err != ErrTenant {
+			t.Errorf("Incorrect TestTenant_Write test. policy = %v, err = %v", p, err)
+			t.FailNow()
+		}
+	}
+	if _, err := policyRepository.Update(ctx, policy); err != nil {
+		t.Errorf("Incorrect TestTenant_Write test. err = %v", err)
+		t.FailNow()
+	}
+
+	if countLive(db, entity.UserGroupTable.String()) != 2 || countLive(db, entity.RoleTable.String()) != 2 || countLive(db, entity.PolicyTable.String()) != 1 {
+		t.Errorf("Incorrect TestTenant_Write test")
+		t.FailNow()
+	}
+}
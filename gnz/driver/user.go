@@ -1,7 +1,10 @@
 package driver
 
 import (
+	"context"
 	"fmt"
+	"time"
+
 	"github.com/jinzhu/gorm"
 
 	"github.com/tomoyane/grant-n-z/gnz/entity"
@@ -13,52 +16,70 @@ var urInstance UserRepository
 
 type UserRepository interface {
 	// Find User by user id
-	FindByUuid(uuid string) (*entity.User, error)
+	FindByUuid(ctx context.Context, uuid string) (*entity.User, error)
 
 	// Find User by user email
-	FindByEmail(email string) (*entity.User, error)
+	FindByEmail(ctx context.Context, email string) (*entity.User, error)
 
 	// Find User by group uuid
-	FindByGroupUuid(groupUuid string) ([]*entity.User, error)
+	// Keyset pagination, filters and sort of page. Next cursor is empty if it is the last page
+	FindByGroupUuid(ctx context.Context, groupUuid string, page Page) ([]*entity.User, string, error)
 
 	// Find User and operator policy by user email
-	FindWithOperatorPolicyByEmail(email string) (*model.UserWithOperatorPolicy, error)
+	FindWithOperatorPolicyByEmail(ctx context.Context, email string) (*model.UserWithOperatorPolicy, error)
 
 	// Find User and UserService and service by user email
-	FindWithUserServiceWithServiceByEmail(email string) (*model.UserWithUserServiceWithService, error)
+	FindWithUserServiceWithServiceByEmail(ctx context.Context, email string) (*model.UserWithUserServiceWithService, error)
 
 	// Find UserGroup by user uuid and group uuid
-	FindUserGroupByUserUuidAndGroupUuid(userUuid string, groupUuid string) (*entity.UserGroup, error)
+	FindUserGroupByUserUuidAndGroupUuid(ctx context.Context, userUuid string, groupUuid string) (*entity.UserGroup, error)
 
 	// Find all UserService
-	FindUserServices() ([]*entity.UserService, error)
+	FindUserServices(ctx context.Context) ([]*entity.UserService, error)
 
 	// Find UserService by user uuid
-	FindUserServicesByUserUuid(userUuid string) ([]*entity.UserService, error)
+	FindUserServicesByUserUuid(ctx context.Context, userUuid string) ([]*entity.UserService, error)
 
 	// Find all UserService with offset and limit
-	FindUserServicesOffSetAndLimit(offset int, limit int) ([]*entity.UserService, error)
+	FindUserServicesOffSetAndLimit(ctx context.Context, offset int, limit int) ([]*entity.UserService, error)
 
 	// Find all UserGroup with offset and limit
-	FindUserGroupsOffSetAndLimit(offset int, limit int) ([]*entity.UserGroup, error)
+	FindUserGroupsOffSetAndLimit(ctx context.Context, offset int, limit int) ([]*entity.UserGroup, error)
+
+	// Find distinct user uuid of UserService that is greater than afterUserUuid, ordered by user uuid
+	// Keyset pagination. Pass the last user uuid of the previous page as afterUserUuid, or empty for the first page
+	FindUserUuidsOfUserServicesAfter(ctx context.Context, afterUserUuid string, limit int) ([]string, error)
+
+	// Find distinct user uuid of UserGroup that is greater than afterUserUuid, ordered by user uuid
+	// Keyset pagination. Pass the last user uuid of the previous page as afterUserUuid, or empty for the first page
+	FindUserUuidsOfUserGroupsAfter(ctx context.Context, afterUserUuid string, limit int) ([]string, error)
+
+	// Find UserService and service of all users in userUuids, ordered by user uuid
+	// Join user_services and services
+	FindUserServicesWithServiceByUserUuids(ctx context.Context, userUuids []string) ([]model.UserServiceOnService, error)
+
+	// Find UserGroup and group of all users in userUuids, ordered by user uuid
+	// Join user_groups and groups
+	FindUserGroupsWithGroupByUserUuids(ctx context.Context, userUuids []string) ([]model.UserGroupOnGroup, error)
 
 	// Find UserService by user uuid and service uuid
-	FindUserServiceByUserUuidAndServiceUuid(userUuid string, serviceUuid string) (*entity.UserService, error)
+	FindUserServiceByUserUuidAndServiceUuid(ctx context.Context, userUuid string, serviceUuid string) (*entity.UserService, error)
 
 	// Insert user_group data
-	SaveUserGroup(userGroup entity.UserGroup) (*entity.UserGroup, error)
+	SaveUserGroup(ctx context.Context, userGroup entity.UserGroup) (*entity.UserGroup, error)
 
 	// Save User
-	SaveUser(user entity.User) (*entity.User, error)
+	SaveUser(ctx context.Context, user entity.User) (*entity.User, error)
 
 	// Save User and user service
-	SaveWithUserService(user entity.User, userService entity.UserService) (*entity.User, error)
+	SaveWithUserService(ctx context.Context, user entity.User, userService entity.UserService) (*entity.User, error)
 
 	// Save UserService
-	SaveUserService(userService entity.UserService) (*entity.UserService, error)
+	SaveUserService(ctx context.Context, userService entity.UserService) (*entity.UserService, error)
 
-	// Update User
-	UpdateUser(user entity.User) (*entity.User, error)
+	// Update User of uuid whose version is the version of user, and increment version
+	// If other request updated it after the version, ErrStaleVersion
+	UpdateUser(ctx context.Context, user entity.User) (*entity.User, error)
 }
 
 // UserRepository struct
@@ -81,25 +102,43 @@ func NewUserRepository() UserRepository {
 	return UserRepositoryImpl{Connection: connection}
 }
 
-func (uri UserRepositoryImpl) FindByUuid(uuid string) (*entity.User, error) {
+func (uri UserRepositoryImpl) FindByUuid(ctx context.Context, uuid string) (*entity.User, error) {
+	db, cancel := withReadContext(ctx, uri.Connection)
+	defer cancel()
+
 	var user entity.User
-	if err := uri.Connection.Where("uuid = ?", uuid).Find(&user).Error; err != nil {
-		return nil, err
+	if err := db.Where("uuid = ?", uuid).Find(&user).Error; err != nil {
+		return nil, translateError(err)
 	}
 
 	return &user, nil
 }
 
-func (uri UserRepositoryImpl) FindByEmail(email string) (*entity.User, error) {
+func (uri UserRepositoryImpl) FindByEmail(ctx context.Context, email string) (*entity.User, error) {
+	db, cancel := withReadContext(ctx, uri.Connection)
+	defer cancel()
+
 	var user entity.User
-	if err := uri.Connection.Where("email = ?", email).Find(&user).Error; err != nil {
-		return nil, err
+	if err := db.Where("email = ?", email).Find(&user).Error; err != nil {
+		return nil, translateError(err)
 	}
 
 	return &user, nil
 }
 
-func (uri UserRepositoryImpl) FindByGroupUuid(groupUuid string) ([]*entity.User, error) {
+func (uri UserRepositoryImpl) FindByGroupUuid(ctx context.Context, groupUuid string, page Page) ([]*entity.User, string, error) {
+	db, cancel := withReadContext(ctx, uri.Connection)
+	defer cancel()
+
+	db = groupTenant.scope(ctx, db, entity.UserGroupTable.String()+"."+entity.UserGroupGroupUuid.String())
+	query, err := page.apply(db, entity.UserTable.String(), entity.UserUsername.String())
+	if err != nil {
+		return nil, "", err
+	}
+	if page.Email != "" {
+		query = query.Where(fmt.Sprintf("%s.%s = ?", entity.UserTable.String(), entity.UserEmail.String()), page.Email)
+	}
+
 	var users []*entity.User
 
 	target := entity.UserTable.String() + "." +
@@ -109,9 +148,11 @@ func (uri UserRepositoryImpl) FindByGroupUuid(groupUuid string) ([]*entity.User,
 		entity.UserTable.String() + "." +
 		entity.UserUsername.String() + "," +
 		entity.UserTable.String() + "." +
-		entity.UserEmail.String()
+		entity.UserEmail.String() + "," +
+		entity.UserTable.String() + "." +
+		entity.UserCreatedAt.String()
 
-	if err := uri.Connection.Table(entity.UserGroupTable.String()).
+	if err := query.Table(entity.UserGroupTable.String()).
 		Select(target).
 		Joins(fmt.Sprintf("LEFT JOIN %s ON %s.%s = %s.%s",
 			entity.UserTable.String(),
@@ -119,173 +160,363 @@ func (uri UserRepositoryImpl) FindByGroupUuid(groupUuid string) ([]*entity.User,
 			entity.UserGroupUserUuid.String(),
 			entity.UserTable.String(),
 			entity.UserUuid.String())).
+		Where(notDeleted(entity.UserGroupTable.String(), entity.UserTable.String())).
 		Where(fmt.Sprintf("%s.%s = ?",
 			entity.UserGroupTable.String(),
 			entity.UserGroupGroupUuid.String()), groupUuid).
 		Scan(&users).Error; err != nil {
 
-		return nil, err
+		return nil, "", translateError(err)
 	}
 
-	return users, nil
+	size, next := page.nextCursor(len(users), func(i int) (int, time.Time, string) {
+		return users[i].Id, users[i].CreatedAt, users[i].Username
+	})
+	return users[:size], next, nil
 }
 
-func (uri UserRepositoryImpl) FindWithOperatorPolicyByEmail(email string) (*model.UserWithOperatorPolicy, error) {
+func (uri UserRepositoryImpl) FindWithOperatorPolicyByEmail(ctx context.Context, email string) (*model.UserWithOperatorPolicy, error) {
+	db, cancel := withReadContext(ctx, uri.Connection)
+	defer cancel()
+
 	var uwo model.UserWithOperatorPolicy
 
-	if err := uri.Connection.Table(entity.UserTable.String()).
+	if err := db.Table(entity.UserTable.String()).
 		Select("*").
-		Joins(fmt.Sprintf("LEFT JOIN %s ON %s.%s = %s.%s",
+		Joins(fmt.Sprintf("LEFT JOIN %s ON %s.%s = %s.%s AND %s",
 			entity.OperatorPolicyTable.String(),
 			entity.OperatorPolicyTable.String(),
 			entity.OperatorPolicyUserUuid.String(),
 			entity.UserTable.String(),
-			entity.UserUuid.String())).
-		Joins(fmt.Sprintf("LEFT JOIN %s ON %s.%s = %s.%s",
+			entity.UserUuid.String(),
+			notDeleted(entity.OperatorPolicyTable.String()))).
+		Joins(fmt.Sprintf("LEFT JOIN %s ON %s.%s = %s.%s AND %s",
 			entity.RoleTable.String(),
 			entity.RoleTable.String(),
 			entity.RoleUuid.String(),
 			entity.OperatorPolicyTable.String(),
-			entity.OperatorPolicyRoleUuid.String())).
+			entity.OperatorPolicyRoleUuid.String(),
+			notDeleted(entity.RoleTable.String()))).
+		Where(notDeleted(entity.UserTable.String())).
 		Where(fmt.Sprintf("%s.%s = ?",
 			entity.UserTable.String(),
 			entity.UserEmail.String()), email).
 		Scan(&uwo).Error; err != nil {
 
-		return nil, err
+		return nil, translateError(err)
 	}
 
 	return &uwo, nil
 }
 
-func (uri UserRepositoryImpl) FindWithUserServiceWithServiceByEmail(email string) (*model.UserWithUserServiceWithService, error) {
+func (uri UserRepositoryImpl) FindWithUserServiceWithServiceByEmail(ctx context.Context, email string) (*model.UserWithUserServiceWithService, error) {
+	db, cancel := withReadContext(ctx, uri.Connection)
+	defer cancel()
+
 	var uus model.UserWithUserServiceWithService
 
-	if err := uri.Connection.Table(entity.UserTable.String()).
+	if err := db.Table(entity.UserTable.String()).
 		Select("*").
-		Joins(fmt.Sprintf("LEFT JOIN %s ON %s.%s = %s.%s",
+		Joins(fmt.Sprintf("LEFT JOIN %s ON %s.%s = %s.%s AND %s",
 			entity.UserServiceTable.String(),
 			entity.UserTable.String(),
 			entity.UserUuid.String(),
 			entity.UserServiceTable.String(),
-			entity.UserServiceUserUuid.String())).
-		Joins(fmt.Sprintf("LEFT JOIN %s ON %s.%s = %s.%s",
+			entity.UserServiceUserUuid.String(),
+			notDeleted(entity.UserServiceTable.String()))).
+		Joins(fmt.Sprintf("LEFT JOIN %s ON %s.%s = %s.%s AND %s",
 			entity.ServiceTable.String(),
 			entity.UserServiceTable.String(),
 			entity.UserServiceServiceUuid.String(),
 			entity.ServiceTable.String(),
-			entity.ServiceUuid.String())).
+			entity.ServiceUuid.String(),
+			notDeleted(entity.ServiceTable.String()))).
+		Where(notDeleted(entity.UserTable.String())).
 		Where(fmt.Sprintf("%s.%s = ?",
 			entity.UserTable.String(),
 			entity.UserEmail.String()), email).
 		Scan(&uus).Error; err != nil {
 
-		return nil, err
+		return nil, translateError(err)
 	}
 
 	return &uus, nil
 }
 
-func (uri UserRepositoryImpl) FindUserGroupByUserUuidAndGroupUuid(userUuid string, groupUuid string) (*entity.UserGroup, error) {
+func (uri UserRepositoryImpl) FindUserGroupByUserUuidAndGroupUuid(ctx context.Context, userUuid string, groupUuid string) (*entity.UserGroup, error) {
+	db, cancel := withReadContext(ctx, uri.Connection)
+	defer cancel()
+
 	var userGroup entity.UserGroup
-	if err := uri.Connection.Where("user_uuid = ? AND group_uuid = ?", userUuid, groupUuid).First(&userGroup).Error; err != nil {
-		return nil, err
+	db = groupTenant.scope(ctx, db, entity.UserGroupTable.String()+"."+entity.UserGroupGroupUuid.String())
+	if err := db.Where("user_uuid = ? AND group_uuid = ?", userUuid, groupUuid).First(&userGroup).Error; err != nil {
+		return nil, translateError(err)
 	}
 
 	return &userGroup, nil
 }
 
-func (uri UserRepositoryImpl) FindUserServices() ([]*entity.UserService, error) {
+func (uri UserRepositoryImpl) FindUserServices(ctx context.Context) ([]*entity.UserService, error) {
+	db, cancel := withReadContext(ctx, uri.Connection)
+	defer cancel()
+
 	var userServices []*entity.UserService
-	if err := uri.Connection.Find(&userServices).Error; err != nil {
-		return nil, err
+	if err := db.Find(&userServices).Error; err != nil {
+		return nil, translateError(err)
 	}
 
 	return userServices, nil
 }
 
-func (uri UserRepositoryImpl) FindUserServicesByUserUuid(userUuid string) ([]*entity.UserService, error) {
+func (uri UserRepositoryImpl) FindUserServicesByUserUuid(ctx context.Context, userUuid string) ([]*entity.UserService, error) {
+	db, cancel := withReadContext(ctx, uri.Connection)
+	defer cancel()
+
 	var userServices []*entity.UserService
-	if err := uri.Connection.Where("user_uuid = ?", userUuid).Find(&userServices).Error; err != nil {
-		return nil, err
+	if err := db.Where("user_uuid = ?", userUuid).Find(&userServices).Error; err != nil {
+		return nil, translateError(err)
 	}
 
 	return userServices, nil
 }
 
-func (uri UserRepositoryImpl) FindUserServicesOffSetAndLimit(offset int, limit int) ([]*entity.UserService, error) {
+func (uri UserRepositoryImpl) FindUserServicesOffSetAndLimit(ctx context.Context, offset int, limit int) ([]*entity.UserService, error) {
+	db, cancel := withReadContext(ctx, uri.Connection)
+	defer cancel()
+
 	var userServices []*entity.UserService
-	if err := uri.Connection.Limit(limit).Offset(offset).Find(&userServices).Error; err != nil {
-		return nil, err
+	if err := db.Limit(limit).Offset(offset).Find(&userServices).Error; err != nil {
+		return nil, translateError(err)
 	}
 
 	return userServices, nil
 }
 
-func (uri UserRepositoryImpl) FindUserGroupsOffSetAndLimit(offset int, limit int) ([]*entity.UserGroup, error) {
+func (uri UserRepositoryImpl) FindUserGroupsOffSetAndLimit(ctx context.Context, offset int, limit int) ([]*entity.UserGroup, error) {
+	db, cancel := withReadContext(ctx, uri.Connection)
+	defer cancel()
+
 	var userGroups []*entity.UserGroup
-	if err := uri.Connection.Limit(limit).Offset(offset).Find(&userGroups).Error; err != nil {
-		return nil, err
+	if err := db.Limit(limit).Offset(offset).Find(&userGroups).Error; err != nil {
+		return nil, translateError(err)
 	}
 
 	return userGroups, nil
 }
 
-func (uri UserRepositoryImpl) FindUserServiceByUserUuidAndServiceUuid(userUuid string, serviceUuid string) (*entity.UserService, error) {
+func (uri UserRepositoryImpl) FindUserUuidsOfUserServicesAfter(ctx context.Context, afterUserUuid string, limit int) ([]string, error) {
+	db, cancel := withReadContext(ctx, uri.Connection)
+	defer cancel()
+
+	var userUuids []string
+	if err := db.Table(entity.UserServiceTable.String()).
+		Where(notDeleted(entity.UserServiceTable.String())).
+		Where(fmt.Sprintf("%s > ?", entity.UserServiceUserUuid.String()), afterUserUuid).
+		Order(entity.UserServiceUserUuid.String()).
+		Limit(limit).
+		Pluck("DISTINCT "+entity.UserServiceUserUuid.String(), &userUuids).Error; err != nil {
+
+		return nil, translateError(err)
+	}
+
+	return userUuids, nil
+}
+
+func (uri UserRepositoryImpl) FindUserUuidsOfUserGroupsAfter(ctx context.Context, afterUserUuid string, limit int) ([]string, error) {
+	db, cancel := withReadContext(ctx, uri.Connection)
+	defer cancel()
+
+	var userUuids []string
+	if err := db.Table(entity.UserGroupTable.String()).
+		Where(notDeleted(entity.UserGroupTable.String())).
+		Where(fmt.Sprintf("%s > ?", entity.UserGroupUserUuid.String()), afterUserUuid).
+		Order(entity.UserGroupUserUuid.String()).
+		Limit(limit).
+		Pluck("DISTINCT "+entity.UserGroupUserUuid.String(), &userUuids).Error; err != nil {
+
+		return nil, translateError(err)
+	}
+
+	return userUuids, nil
+}
+
+func (uri UserRepositoryImpl) FindUserServicesWithServiceByUserUuids(ctx context.Context, userUuids []string) ([]model.UserServiceOnService, error) {
+	db, cancel := withReadContext(ctx, uri.Connection)
+	defer cancel()
+
+	var userServices []model.UserServiceOnService
+
+	target := entity.UserServiceTable.String() + "." +
+		entity.UserServiceUserUuid.String() + " AS user_uuid," +
+		entity.ServiceTable.String() + "." +
+		entity.ServiceUuid.String() + " AS service_uuid," +
+		entity.ServiceTable.String() + "." +
+		entity.ServiceName.String() + " AS service_name"
+
+	if err := db.Table(entity.UserServiceTable.String()).
+		Select(target).
+		Joins(fmt.Sprintf("INNER JOIN %s ON %s.%s = %s.%s AND %s",
+			entity.ServiceTable.String(),
+			entity.ServiceTable.String(),
+			entity.ServiceUuid.String(),
+			entity.UserServiceTable.String(),
+			entity.UserServiceServiceUuid.String(),
+			notDeleted(entity.ServiceTable.String()))).
+		Where(notDeleted(entity.UserServiceTable.String())).
+		Where(fmt.Sprintf("%s.%s IN (?)",
+			entity.UserServiceTable.String(),
+			entity.UserServiceUserUuid.String()), userUuids).
+		Order(fmt.Sprintf("%s.%s, %s.%s",
+			entity.UserServiceTable.String(),
+			entity.UserServiceUserUuid.String(),
+			entity.UserServiceTable.String(),
+			entity.UserServiceId.String())).
+		Scan(&userServices).Error; err != nil {
+
+		return nil, translateError(err)
+	}
+
+	return userServices, nil
+}
+
+func (uri UserRepositoryImpl) FindUserGroupsWithGroupByUserUuids(ctx context.Context, userUuids []string) ([]model.UserGroupOnGroup, error) {
+	db, cancel := withReadContext(ctx, uri.Connection)
+	defer cancel()
+
+	var userGroups []model.UserGroupOnGroup
+
+	target := entity.UserGroupTable.String() + "." +
+		entity.UserGroupUserUuid.String() + " AS user_uuid," +
+		entity.GroupTable.String() + "." +
+		entity.GroupUuid.String() + " AS group_uuid," +
+		entity.GroupTable.String() + "." +
+		entity.GroupName.String() + " AS group_name"
+
+	if err := db.Table(entity.UserGroupTable.String()).
+		Select(target).
+		Joins(fmt.Sprintf("INNER JOIN %s ON %s.%s = %s.%s AND %s",
+			entity.GroupTable.String(),
+			entity.GroupTable.String(),
+			entity.GroupUuid.String(),
+			entity.UserGroupTable.String(),
+			entity.UserGroupGroupUuid.String(),
+			notDeleted(entity.GroupTable.String()))).
+		Where(notDeleted(entity.UserGroupTable.String())).
+		Where(fmt.Sprintf("%s.%s IN (?)",
+			entity.UserGroupTable.String(),
+			entity.UserGroupUserUuid.String()), userUuids).
+		Order(fmt.Sprintf("%s.%s, %s.%s",
+			entity.UserGroupTable.String(),
+			entity.UserGroupUserUuid.String(),
+			entity.UserGroupTable.String(),
+			entity.UserGroupId.String())).
+		Scan(&userGroups).Error; err != nil {
+
+		return nil, translateError(err)
+	}
+
+	return userGroups, nil
+}
+
+func (uri UserRepositoryImpl) FindUserServiceByUserUuidAndServiceUuid(ctx context.Context, userUuid string, serviceUuid string) (*entity.UserService, error) {
+	db, cancel := withReadContext(ctx, uri.Connection)
+	defer cancel()
+
 	var userService entity.UserService
-	if err := uri.Connection.Where("user_uuid = ? AND service_uuid = ?", userUuid, serviceUuid).Find(&userService).Error; err != nil {
-		return nil, err
+	if err := db.Where("user_uuid = ? AND service_uuid = ?", userUuid, serviceUuid).Find(&userService).Error; err != nil {
+		return nil, translateError(err)
 	}
 
 	return &userService, nil
 }
 
-func (uri UserRepositoryImpl) SaveUserGroup(userGroup entity.UserGroup) (*entity.UserGroup, error) {
-	if err := uri.Connection.Save(&userGroup).Error; err != nil {
+func (uri UserRepositoryImpl) SaveUserGroup(ctx context.Context, userGroup entity.UserGroup) (*entity.UserGroup, error) {
+	db, cancel := withContext(ctx, uri.Connection)
+	defer cancel()
+
+	if err := groupTenant.check(ctx, db, entity.GroupTable.String(), entity.GroupUuid.String(), userGroup.GroupUuid.String()); err != nil {
+		return nil, err
+	}
+	if err := userTenant.check(ctx, db, entity.UserTable.String(), entity.UserUuid.String(), userGroup.UserUuid.String()); err != nil {
 		return nil, err
 	}
 
+	if err := db.Save(&userGroup).Error; err != nil {
+		return nil, translateError(err)
+	}
+
 	return &userGroup, nil
 }
 
-func (uri UserRepositoryImpl) SaveUser(user entity.User) (*entity.User, error) {
-	if err := uri.Connection.Create(&user).Error; err != nil {
-		return nil, err
+func (uri UserRepositoryImpl) SaveUser(ctx context.Context, user entity.User) (*entity.User, error) {
+	db, cancel := withContext(ctx, uri.Connection)
+	defer cancel()
+
+	if err := db.Create(&user).Error; err != nil {
+		return nil, translateError(err)
 	}
 
 	return &user, nil
 }
 
-func (uri UserRepositoryImpl) SaveWithUserService(user entity.User, userService entity.UserService) (*entity.User, error) {
-	tx := uri.Connection.Begin()
+func (uri UserRepositoryImpl) SaveWithUserService(ctx context.Context, user entity.User, userService entity.UserService) (*entity.User, error) {
+	db, cancel := withContext(ctx, uri.Connection)
+	defer cancel()
+
+	tx := db.Begin()
 
 	if err := tx.Create(&user).Error; err != nil {
 		tx.Rollback()
-		return nil, err
+		return nil, translateError(err)
 	}
 
 	userService.UserUuid = user.Uuid
 	if err := tx.Create(&userService).Error; err != nil {
 		tx.Rollback()
-		return nil, err
+		return nil, translateError(err)
 	}
 
-	tx.Commit()
+	if err := tx.Commit().Error; err != nil {
+		return nil, translateError(err)
+	}
 	return &user, nil
 }
 
-func (uri UserRepositoryImpl) SaveUserService(userService entity.UserService) (*entity.UserService, error) {
-	if err := uri.Connection.Create(&userService).Error; err != nil {
-		return nil, err
+func (uri UserRepositoryImpl) SaveUserService(ctx context.Context, userService entity.UserService) (*entity.UserService, error) {
+	db, cancel := withContext(ctx, uri.Connection)
+	defer cancel()
+
+	if err := db.Create(&userService).Error; err != nil {
+		return nil, translateError(err)
 	}
 
 	return &userService, nil
 }
 
-func (uri UserRepositoryImpl) UpdateUser(user entity.User) (*entity.User, error) {
-	if err := uri.Connection.Save(&user).Error; err != nil {
-		return nil, err
+func (uri UserRepositoryImpl) UpdateUser(ctx context.Context, user entity.User) (*entity.User, error) {
+	db, cancel := withContext(ctx, uri.Connection)
+	defer cancel()
+
+	result := db.Model(&entity.User{}).
+		Where(fmt.Sprintf("%s = ? AND %s = ?", entity.UserUuid.String(), entity.UserVersion.String()), user.Uuid.String(), user.Version).
+		Updates(map[string]interface{}{
+			entity.UserInternalId.String(): user.InternalId,
+			entity.UserUsername.String():   user.Username,
+			entity.UserEmail.String():      user.Email,
+			entity.UserPassword.String():   user.Password,
+			entity.UserVersion.String():    gorm.Expr(entity.UserVersion.String() + " + 1"),
+		})
+	if result.Error != nil {
+		return nil, translateError(result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return nil, versionError(db, &entity.User{}, entity.UserUuid.String(), user.Uuid.String())
 	}
 
-	return &user, nil
+	var updatedUser entity.User
+	if err := db.Where("uuid = ?", user.Uuid.String()).First(&updatedUser).Error; err != nil {
+		return nil, translateError(err)
+	}
+
+	return &updatedUser, nil
 }
@@ -1,9 +1,9 @@
 package driver
 
 import (
+	"context"
 	"testing"
 
-	"github.com/jinzhu/gorm"
 	"github.com/tomoyane/grant-n-z/gnz/entity"
 	"github.com/tomoyane/grant-n-z/gnz/log"
 )
@@ -14,14 +14,13 @@ var userRepository UserRepository
 func init() {
 	log.InitLogger("info")
 
-	stubConnection, _ := gorm.Open("sqlite3", "/tmp/test_grant_nz.db")
-	connection = stubConnection
+	connection = openTestConnection()
 	userRepository = GetUserRepositoryInstance()
 }
 
 // FindByUuid InternalServerError test
 func TestUserFindById_Error(t *testing.T) {
-	_, err := userRepository.FindByUuid("uuid")
+	_, err := userRepository.FindByUuid(context.Background(), "uuid")
 	if err == nil {
 		t.Errorf("Incorrect TestUserFindById_Error test")
 		t.FailNow()
@@ -30,7 +29,7 @@ func TestUserFindById_Error(t *testing.T) {
 
 // FindByEmail InternalServerError test
 func TestUserFindByEmail_Error(t *testing.T) {
-	_, err := userRepository.FindByEmail("test@gmail.com")
+	_, err := userRepository.FindByEmail(context.Background(), "test@gmail.com")
 	if err == nil {
 		t.Errorf("Incorrect TestUserFindByEmail_Error test")
 		t.FailNow()
@@ -39,7 +38,7 @@ func TestUserFindByEmail_Error(t *testing.T) {
 
 // FindByUuid InternalServerError test
 func TestUserFindByGroupId_Error(t *testing.T) {
-	_, err := userRepository.FindByGroupUuid("uuid")
+	_, _, err := userRepository.FindByGroupUuid(context.Background(), "uuid", Page{})
 	if err == nil {
 		t.Errorf("Incorrect TestUserFindByGroupId_Error test")
 		t.FailNow()
@@ -48,7 +47,7 @@ func TestUserFindByGroupId_Error(t *testing.T) {
 
 // FindWithOperatorPolicyByEmail InternalServerError test
 func TestUserFindWithOperatorPolicyByEmail_Error(t *testing.T) {
-	_, err := userRepository.FindWithOperatorPolicyByEmail("test@gmail.com")
+	_, err := userRepository.FindWithOperatorPolicyByEmail(context.Background(), "test@gmail.com")
 	if err == nil {
 		t.Errorf("Incorrect TestUserFindWithOperatorPolicyByEmail_Error test")
 		t.FailNow()
@@ -57,7 +56,7 @@ func TestUserFindWithOperatorPolicyByEmail_Error(t *testing.T) {
 
 // FindUserGroupByUserUuidAndGroupUuid InternalServerError test
 func TestUserFindUserGroupByUserIdAndGroupId_Error(t *testing.T) {
-	_, err := userRepository.FindUserGroupByUserUuidAndGroupUuid("uuid", "uuid")
+	_, err := userRepository.FindUserGroupByUserUuidAndGroupUuid(context.Background(), "uuid", "uuid")
 	if err == nil {
 		t.Errorf("Incorrect TestUserFindUserGroupByUserIdAndGroupId_Error test")
 		t.FailNow()
@@ -66,7 +65,7 @@ func TestUserFindUserGroupByUserIdAndGroupId_Error(t *testing.T) {
 
 // FindUserServices InternalServerError test
 func TestUserFindUserServices_Error(t *testing.T) {
-	_, err := userRepository.FindUserServices()
+	_, err := userRepository.FindUserServices(context.Background())
 	if err == nil {
 		t.Errorf("Incorrect TestUserFindUserServices_Error test")
 		t.FailNow()
@@ -75,7 +74,7 @@ func TestUserFindUserServices_Error(t *testing.T) {
 
 // FindUserServicesByUserUuid InternalServerError test
 func TestFindUserServicesByUserUuid_Error(t *testing.T) {
-	_, err := userRepository.FindUserServicesByUserUuid("")
+	_, err := userRepository.FindUserServicesByUserUuid(context.Background(), "")
 	if err == nil {
 		t.Errorf("Incorrect TestFindUserServicesByUserUuid_Error test")
 		t.FailNow()
@@ -84,7 +83,7 @@ func TestFindUserServicesByUserUuid_Error(t *testing.T) {
 
 // FindUserServicesOffSetAndLimit InternalServerError test
 func TestUserFindUserServicesOffSetAndLimit_Error(t *testing.T) {
-	_, err := userRepository.FindUserServicesOffSetAndLimit(1, 1)
+	_, err := userRepository.FindUserServicesOffSetAndLimit(context.Background(), 1, 1)
 	if err == nil {
 		t.Errorf("Incorrect TestUserFindUserServicesOffSetAndLimit_Error test")
 		t.FailNow()
@@ -93,16 +92,52 @@ func TestUserFindUserServicesOffSetAndLimit_Error(t *testing.T) {
 
 // FindUserGroupsOffSetAndLimit InternalServerError test
 func TestUserFindUserGroupsOffSetAndLimit_Error(t *testing.T) {
-	_, err := userRepository.FindUserGroupsOffSetAndLimit(1, 1)
+	_, err := userRepository.FindUserGroupsOffSetAndLimit(context.Background(), 1, 1)
 	if err == nil {
 		t.Errorf("Incorrect TestUserFindUserGroupsOffSetAndLimit_Error test")
 		t.FailNow()
 	}
 }
 
+// FindUserUuidsOfUserServicesAfter InternalServerError test
+func TestUserFindUserUuidsOfUserServicesAfter_Error(t *testing.T) {
+	_, err := userRepository.FindUserUuidsOfUserServicesAfter(context.Background(), "", 1)
+	if err == nil {
+		t.Errorf("Incorrect TestUserFindUserUuidsOfUserServicesAfter_Error test")
+		t.FailNow()
+	}
+}
+
+// FindUserUuidsOfUserGroupsAfter InternalServerError test
+func TestUserFindUserUuidsOfUserGroupsAfter_Error(t *testing.T) {
+	_, err := userRepository.FindUserUuidsOfUserGroupsAfter(context.Background(), "", 1)
+	if err == nil {
+		t.Errorf("Incorrect TestUserFindUserUuidsOfUserGroupsAfter_Error test")
+		t.FailNow()
+	}
+}
+
+// FindUserServicesWithServiceByUserUuids InternalServerError test
+func TestUserFindUserServicesWithServiceByUserUuids_Error(t *testing.T) {
+	_, err := userRepository.FindUserServicesWithServiceByUserUuids(context.Background(), []string{"uuid"})
+	if err == nil {
+		t.Errorf("Incorrect TestUserFindUserServicesWithServiceByUserUuids_Error test")
+		t.FailNow()
+	}
+}
+
+// FindUserGroupsWithGroupByUserUuids InternalServerError test
+func TestUserFindUserGroupsWithGroupByUserUuids_Error(t *testing.T) {
+	_, err := userRepository.FindUserGroupsWithGroupByUserUuids(context.Background(), []string{"uuid"})
+	if err == nil {
+		t.Errorf("Incorrect TestUserFindUserGroupsWithGroupByUserUuids_Error test")
+		t.FailNow()
+	}
+}
+
 // FindUserServiceByUserUuidAndServiceUuid InternalServerError test
 func TestUserFindUserServiceByUserIdAndServiceId_Error(t *testing.T) {
-	_, err := userRepository.FindUserServiceByUserUuidAndServiceUuid("uuid", "uuid")
+	_, err := userRepository.FindUserServiceByUserUuidAndServiceUuid(context.Background(), "uuid", "uuid")
 	if err == nil {
 		t.Errorf("Incorrect TestUserFindUserServiceByUserIdAndServiceId_Error test")
 		t.FailNow()
@@ -111,7 +146,7 @@ func TestUserFindUserServiceByUserIdAndServiceId_Error(t *testing.T) {
 
 // SaveUserGroup InternalServerError test
 func TestUserSaveUserGroup_Error(t *testing.T) {
-	_, err := userRepository.SaveUserGroup(entity.UserGroup{})
+	_, err := userRepository.SaveUserGroup(context.Background(), entity.UserGroup{})
 	if err == nil {
 		t.Errorf("Incorrect TestUserSaveUserGroup_Error test")
 		t.FailNow()
@@ -120,7 +155,7 @@ func TestUserSaveUserGroup_Error(t *testing.T) {
 
 // SaveUser InternalServerError test
 func TestUserSaveUser_Error(t *testing.T) {
-	_, err := userRepository.SaveUser(entity.User{})
+	_, err := userRepository.SaveUser(context.Background(), entity.User{})
 	if err == nil {
 		t.Errorf("Incorrect TestUserSaveUser_Error test")
 		t.FailNow()
@@ -129,7 +164,7 @@ func TestUserSaveUser_Error(t *testing.T) {
 
 // SaveWithUserService InternalServerError test
 func TestUserSaveWithUserService_Error(t *testing.T) {
-	_, err := userRepository.SaveWithUserService(entity.User{}, entity.UserService{})
+	_, err := userRepository.SaveWithUserService(context.Background(), entity.User{}, entity.UserService{})
 	if err == nil {
 		t.Errorf("Incorrect TestSaveWithUserService_Error test")
 		t.FailNow()
@@ -138,7 +173,7 @@ func TestUserSaveWithUserService_Error(t *testing.T) {
 
 // SaveUserService InternalServerError test
 func TestUserSaveUserService_Error(t *testing.T) {
-	_, err := userRepository.SaveUserService(entity.UserService{})
+	_, err := userRepository.SaveUserService(context.Background(), entity.UserService{})
 	if err == nil {
 		t.Errorf("Incorrect TestUserSaveUserService_Error test")
 		t.FailNow()
@@ -147,7 +182,7 @@ func TestUserSaveUserService_Error(t *testing.T) {
 
 // UpdateUser InternalServerError test
 func TestUserUpdateUser_Error(t *testing.T) {
-	_, err := userRepository.UpdateUser(entity.User{})
+	_, err := userRepository.UpdateUser(context.Background(), entity.User{})
 	if err == nil {
 		t.Errorf("Incorrect TestUserUpdateUser_Error test")
 		t.FailNow()
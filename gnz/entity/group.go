@@ -14,16 +14,18 @@ const (
 	GroupName
 	GroupCreatedAt
 	GroupUpdatedAt
+	GroupDeletedAt
 )
 
 // The table `groups` struct
 type Group struct {
-	Id         int       `json:"id"`
-	InternalId string    `json:"internal_id"`
-	Uuid       uuid.UUID `json:"uuid"`
-	Name       string    `validate:"required"json:"name"`
-	CreatedAt  time.Time `json:"created_at"`
-	UpdatedAt  time.Time `json:"updated_at"`
+	Id         int        `json:"id"`
+	InternalId string     `json:"internal_id"`
+	Uuid       uuid.UUID  `json:"uuid"`
+	Name       string     `validate:"required"json:"name"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+	DeletedAt  *time.Time `json:"deleted_at,omitempty"`
 }
 
 // Group table config struct
@@ -45,6 +47,8 @@ func (gc GroupTableConfig) String() string {
 		return "created_at"
 	case GroupUpdatedAt:
 		return "updated_at"
+	case GroupDeletedAt:
+		return "deleted_at"
 	}
 	panic("Unknown value")
 }
@@ -14,16 +14,18 @@ const (
 	GroupPermissionGroupUuid
 	GroupPermissionCreatedAt
 	GroupPermissionUpdatedAt
+	GroupPermissionDeletedAt
 )
 
 // The table `group_permissions` struct
 type GroupPermission struct {
-	Id             int       `json:"id"`
-	InternalId     string    `json:"internal_id"`
-	PermissionUuid uuid.UUID `validate:"required"json:"permission_uuid"`
-	GroupUuid      uuid.UUID `validate:"required"json:"group_uuid"`
-	CreatedAt      time.Time `json:"created_at"`
-	UpdatedAt      time.Time `json:"updated_at"`
+	Id             int        `json:"id"`
+	InternalId     string     `json:"internal_id"`
+	PermissionUuid uuid.UUID  `validate:"required"json:"permission_uuid"`
+	GroupUuid      uuid.UUID  `validate:"required"json:"group_uuid"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+	DeletedAt      *time.Time `json:"deleted_at,omitempty"`
 }
 
 // GroupPermission table config
@@ -45,6 +47,8 @@ func (gp GroupPermissionTableConfig) String() string {
 		return "created_at"
 	case GroupPermissionUpdatedAt:
 		return "updated_at"
+	case GroupPermissionDeletedAt:
+		return "deleted_at"
 	}
 	panic("Unknown value")
 }
@@ -47,4 +47,10 @@ func TestGroupPermissionString(t *testing.T) {
 		t.Errorf("Incorrect TestString test")
 		t.FailNow()
 	}
+
+	deletedAt := GroupPermissionDeletedAt.String()
+	if !strings.EqualFold(deletedAt, "deleted_at") {
+		t.Errorf("Incorrect TestString test")
+		t.FailNow()
+	}
 }
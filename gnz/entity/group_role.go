@@ -14,16 +14,18 @@ const (
 	GroupRoleGroupUuid
 	GroupRoleCreatedAt
 	GroupRoleUpdatedAt
+	GroupRoleDeletedAt
 )
 
 // The table `group_roles` struct
 type GroupRole struct {
-	Id         int       `json:"id"`
-	InternalId string    `json:"internal_id"`
-	RoleUuid   uuid.UUID `validate:"required"json:"role_uuid"`
-	GroupUuid  uuid.UUID `validate:"required"json:"group_uuid"`
-	CreatedAt  time.Time `json:"created_at"`
-	UpdatedAt  time.Time `json:"updated_at"`
+	Id         int        `json:"id"`
+	InternalId string     `json:"internal_id"`
+	RoleUuid   uuid.UUID  `validate:"required"json:"role_uuid"`
+	GroupUuid  uuid.UUID  `validate:"required"json:"group_uuid"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+	DeletedAt  *time.Time `json:"deleted_at,omitempty"`
 }
 
 // GroupRole table config
@@ -45,6 +47,8 @@ func (gr GroupRoleTableConfig) String() string {
 		return "created_at"
 	case GroupRoleUpdatedAt:
 		return "updated_at"
+	case GroupRoleDeletedAt:
+		return "deleted_at"
 	}
 	panic("Unknown value")
 }
@@ -47,4 +47,10 @@ func TestGroupRoleString(t *testing.T) {
 		t.Errorf("Incorrect TestString test")
 		t.FailNow()
 	}
+
+	deletedAt := GroupRoleDeletedAt.String()
+	if !strings.EqualFold(deletedAt, "deleted_at") {
+		t.Errorf("Incorrect TestString test")
+		t.FailNow()
+	}
 }
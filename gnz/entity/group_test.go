@@ -47,4 +47,10 @@ func TestGroupString(t *testing.T) {
 		t.Errorf("Incorrect Group TestString test")
 		t.FailNow()
 	}
+
+	deletedAt := GroupDeletedAt.String()
+	if !strings.EqualFold(deletedAt, "deleted_at") {
+		t.Errorf("Incorrect Group TestString test")
+		t.FailNow()
+	}
 }
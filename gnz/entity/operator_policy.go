@@ -13,16 +13,18 @@ const (
 	OperatorPolicyUserUuid
 	OperatorPolicyCreatedAt
 	OperatorPolicyUpdatedAt
+	OperatorPolicyDeletedAt
 )
 
 // The table `operator_policies` struct
 type OperatorPolicy struct {
-	Id         int       `json:"id"`
-	InternalId string    `json:"internal_id"`
-	RoleUuid   uuid.UUID `validate:"required"json:"role_uuid"`
-	UserUuid   uuid.UUID `validate:"required"json:"user_uuid"`
-	CreatedAt  time.Time `json:"created_at"`
-	UpdatedAt  time.Time `json:"updated_at"`
+	Id         int        `json:"id"`
+	InternalId string     `json:"internal_id"`
+	RoleUuid   uuid.UUID  `validate:"required"json:"role_uuid"`
+	UserUuid   uuid.UUID  `validate:"required"json:"user_uuid"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+	DeletedAt  *time.Time `json:"deleted_at,omitempty"`
 }
 
 // OperatorPolicy table config struct
@@ -44,6 +46,8 @@ func (opc OperatorPolicyTableConfig) String() string {
 		return "created_at"
 	case OperatorPolicyUpdatedAt:
 		return "updated_at"
+	case OperatorPolicyDeletedAt:
+		return "deleted_at"
 	}
 	panic("Unknown value")
 }
@@ -47,4 +47,10 @@ func TestOperatorPolicyString(t *testing.T) {
 		t.Errorf("Incorrect TestString test")
 		t.FailNow()
 	}
+
+	deletedAt := OperatorPolicyDeletedAt.String()
+	if !strings.EqualFold(deletedAt, "deleted_at") {
+		t.Errorf("Incorrect TestString test")
+		t.FailNow()
+	}
 }
@@ -14,16 +14,18 @@ const (
 	PermissionName
 	PermissionCreatedAt
 	PermissionUpdatedAt
+	PermissionDeletedAt
 )
 
 // The table `permissions` struct
 type Permission struct {
-	Id         int       `json:"id"`
-	InternalId string    `json:"internal_id"`
-	Uuid       uuid.UUID `json:"uuid"`
-	Name       string    `validate:"required"json:"name"`
-	CreatedAt  time.Time `json:"created_at"`
-	UpdatedAt  time.Time `json:"updated_at"`
+	Id         int        `json:"id"`
+	InternalId string     `json:"internal_id"`
+	Uuid       uuid.UUID  `json:"uuid"`
+	Name       string     `validate:"required"json:"name"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+	DeletedAt  *time.Time `json:"deleted_at,omitempty"`
 }
 
 // Permission table config struct
@@ -45,6 +47,8 @@ func (pc PermissionTableConfig) String() string {
 		return "created_at"
 	case PermissionUpdatedAt:
 		return "updated_at"
+	case PermissionDeletedAt:
+		return "deleted_at"
 	}
 	panic("Unknown value")
 }
@@ -47,4 +47,10 @@ func TestPermissionString(t *testing.T) {
 		t.Errorf("Incorrect TestString test")
 		t.FailNow()
 	}
+
+	deletedAt := PermissionDeletedAt.String()
+	if !strings.EqualFold(deletedAt, "deleted_at") {
+		t.Errorf("Incorrect TestString test")
+		t.FailNow()
+	}
 }
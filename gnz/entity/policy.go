@@ -17,19 +17,23 @@ const (
 	PolicyUserGroupUuid
 	PolicyCreatedAt
 	PolicyUpdatedAt
+	PolicyDeletedAt
+	PolicyVersion
 )
 
 // The table `policy` struct
 type Policy struct {
-	Id             int       `json:"id"`
-	InternalId     string    `json:"internal_id"`
-	Name           string    `validate:"required"json:"name"`
-	RoleUuid       uuid.UUID `validate:"required"json:"role_uuid"`
-	PermissionUuid uuid.UUID `validate:"required"json:"permission_uuid"`
-	ServiceUuid    uuid.UUID `validate:"required"json:"service_uuid"`
-	UserGroupUuid  uuid.UUID `validate:"required"json:"user_group_uuid"`
-	CreatedAt      time.Time `json:"created_at"`
-	UpdatedAt      time.Time `json:"updated_at"`
+	Id             int        `json:"id"`
+	InternalId     string     `json:"internal_id"`
+	Name           string     `validate:"required"json:"name"`
+	RoleUuid       uuid.UUID  `validate:"required"json:"role_uuid"`
+	PermissionUuid uuid.UUID  `validate:"required"json:"permission_uuid"`
+	ServiceUuid    uuid.UUID  `validate:"required"json:"service_uuid"`
+	UserGroupUuid  uuid.UUID  `validate:"required"json:"user_group_uuid"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+	DeletedAt      *time.Time `json:"deleted_at,omitempty"`
+	Version        int        `gorm:"default:1" json:"version"`
 }
 
 // Policy table config struct
@@ -57,6 +61,10 @@ func (pc PolicyTableConfig) String() string {
 		return "created_at"
 	case PolicyUpdatedAt:
 		return "updated_at"
+	case PolicyDeletedAt:
+		return "deleted_at"
+	case PolicyVersion:
+		return "version"
 	}
 	panic("Unknown value")
 }
@@ -65,4 +65,16 @@ func TestPolicyString(t *testing.T) {
 		t.Errorf("Incorrect TestString test")
 		t.FailNow()
 	}
+
+	deletedAt := PermissionDeletedAt.String()
+	if !strings.EqualFold(deletedAt, "deleted_at") {
+		t.Errorf("Incorrect TestString test")
+		t.FailNow()
+	}
+
+	version := PolicyVersion.String()
+	if !strings.EqualFold(version, "version") {
+		t.Errorf("Incorrect TestString test")
+		t.FailNow()
+	}
 }
@@ -14,16 +14,18 @@ const (
 	RoleName
 	RoleCreatedAt
 	RoleUpdatedAt
+	RoleDeletedAt
 )
 
 // The table `roles` struct
 type Role struct {
-	Id         int       `json:"id"`
-	InternalId string    `json:"internal_id"`
-	Uuid       uuid.UUID `json:"uuid"`
-	Name       string    `validate:"required"json:"name"`
-	CreatedAt  time.Time `json:"created_at"`
-	UpdatedAt  time.Time `json:"updated_at"`
+	Id         int        `json:"id"`
+	InternalId string     `json:"internal_id"`
+	Uuid       uuid.UUID  `json:"uuid"`
+	Name       string     `validate:"required"json:"name"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+	DeletedAt  *time.Time `json:"deleted_at,omitempty"`
 }
 
 // Role table config struct
@@ -45,6 +47,8 @@ func (rc RoleTableConfig) String() string {
 		return "created_at"
 	case RoleUpdatedAt:
 		return "updated_at"
+	case RoleDeletedAt:
+		return "deleted_at"
 	}
 	panic("Unknown value")
 }
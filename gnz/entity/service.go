@@ -15,17 +15,19 @@ const (
 	ServiceSecret
 	ServiceCreatedAt
 	ServiceUpdatedAt
+	ServiceDeletedAt
 )
 
 // The table `services` struct
 type Service struct {
-	Id         int       `json:"id"`
-	InternalId string    `json:"internal_id"`
-	Uuid       uuid.UUID `json:"uuid"`
-	Name       string    `validate:"required"json:"name"`
-	Secret     string    `json:"secret"`
-	CreatedAt  time.Time `json:"created_at"`
-	UpdatedAt  time.Time `json:"updated_at"`
+	Id         int        `json:"id"`
+	InternalId string     `json:"internal_id"`
+	Uuid       uuid.UUID  `json:"uuid"`
+	Name       string     `validate:"required"json:"name"`
+	Secret     string     `json:"secret"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+	DeletedAt  *time.Time `json:"deleted_at,omitempty"`
 }
 
 // Service table config struct
@@ -49,6 +51,8 @@ func (sc ServiceTableConfig) String() string {
 		return "created_at"
 	case ServiceUpdatedAt:
 		return "updated_at"
+	case ServiceDeletedAt:
+		return "deleted_at"
 	}
 	panic("Unknown value")
 }
@@ -13,16 +13,18 @@ const (
 	ServiceGroupServiceUuid
 	ServiceGroupCreatedAt
 	ServiceGroupUpdatedAt
+	ServiceGroupDeletedAt
 )
 
 // The table `service_groups` struct
 type ServiceGroup struct {
-	Id          int       `json:"id"`
-	InternalId  string    `json:"internal_id"`
-	GroupUuid   uuid.UUID `validate:"required"json:"group_uuid"`
-	ServiceUuid uuid.UUID `validate:"required"json:"service_uuid"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	Id          int        `json:"id"`
+	InternalId  string     `json:"internal_id"`
+	GroupUuid   uuid.UUID  `validate:"required"json:"group_uuid"`
+	ServiceUuid uuid.UUID  `validate:"required"json:"service_uuid"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+	DeletedAt   *time.Time `json:"deleted_at,omitempty"`
 }
 
 // ServiceGroup table config
@@ -44,6 +46,8 @@ func (uc ServiceGroupTableConfig) String() string {
 		return "created_at"
 	case ServiceGroupUpdatedAt:
 		return "updated_at"
+	case ServiceGroupDeletedAt:
+		return "deleted_at"
 	}
 	panic("Unknown value")
 }
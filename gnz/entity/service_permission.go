@@ -13,16 +13,18 @@ const (
 	ServicePermissionServiceUuid
 	ServicePermissionCreatedAt
 	ServicePermissionUpdatedAt
+	ServicePermissionDeletedAt
 )
 
 // The table `service_permissions` struct
 type ServicePermission struct {
-	Id             int       `json:"id"`
-	InternalId     string    `json:"internal_id"`
-	PermissionUuid uuid.UUID `validate:"required"json:"permission_uuid"`
-	ServiceUuid    uuid.UUID `validate:"required"json:"service_uuid"`
-	CreatedAt      time.Time `json:"created_at"`
-	UpdatedAt      time.Time `json:"updated_at"`
+	Id             int        `json:"id"`
+	InternalId     string     `json:"internal_id"`
+	PermissionUuid uuid.UUID  `validate:"required"json:"permission_uuid"`
+	ServiceUuid    uuid.UUID  `validate:"required"json:"service_uuid"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+	DeletedAt      *time.Time `json:"deleted_at,omitempty"`
 }
 
 // ServicePermission table config
@@ -44,6 +46,8 @@ func (ur ServicePermissionTableConfig) String() string {
 		return "created_at"
 	case ServicePermissionUpdatedAt:
 		return "updated_at"
+	case ServicePermissionDeletedAt:
+		return "deleted_at"
 	}
 	panic("Unknown value")
 }
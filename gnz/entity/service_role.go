@@ -13,16 +13,18 @@ const (
 	ServiceRoleServiceUuid
 	ServiceRoleCreatedAt
 	ServiceRoleUpdatedAt
+	ServiceRoleDeletedAt
 )
 
 // The table `service_roles` struct
 type ServiceRole struct {
-	Id          int       `json:"id"`
-	InternalId  string    `json:"internal_id"`
-	RoleUuid    uuid.UUID `validate:"required"json:"role_uuid"`
-	ServiceUuid uuid.UUID `validate:"required"json:"service_uuid"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	Id          int        `json:"id"`
+	InternalId  string     `json:"internal_id"`
+	RoleUuid    uuid.UUID  `validate:"required"json:"role_uuid"`
+	ServiceUuid uuid.UUID  `validate:"required"json:"service_uuid"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+	DeletedAt   *time.Time `json:"deleted_at,omitempty"`
 }
 
 // ServiceRole table config
@@ -44,6 +46,8 @@ func (ur ServiceRoleTableConfig) String() string {
 		return "created_at"
 	case ServiceRoleUpdatedAt:
 		return "updated_at"
+	case ServiceRoleDeletedAt:
+		return "deleted_at"
 	}
 	panic("Unknown value")
 }
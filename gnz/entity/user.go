@@ -16,18 +16,22 @@ const (
 	UserPassword
 	UserCreatedAt
 	UserUpdatedAt
+	UserDeletedAt
+	UserVersion
 )
 
 // The table `users` struct
 type User struct {
-	Id         int       `json:"id"`
-	InternalId string    `json:"internal_id"`
-	Uuid       uuid.UUID `json:"uuid"`
-	Username   string    `validate:"required"json:"username"`
-	Email      string    `validate:"required,email"json:"email"`
-	Password   string    `validate:"min=8,required"json:"password"`
-	CreatedAt  time.Time `json:"created_at"`
-	UpdatedAt  time.Time `json:"updated_at"`
+	Id         int        `json:"id"`
+	InternalId string     `json:"internal_id"`
+	Uuid       uuid.UUID  `json:"uuid"`
+	Username   string     `validate:"required"json:"username"`
+	Email      string     `validate:"required,email"json:"email"`
+	Password   string     `validate:"min=8,required"json:"password"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+	DeletedAt  *time.Time `json:"deleted_at,omitempty"`
+	Version    int        `gorm:"default:1" json:"version"`
 }
 
 // User table config struct
@@ -53,6 +57,10 @@ func (uc UserTableConfig) String() string {
 		return "created_at"
 	case UserUpdatedAt:
 		return "updated_at"
+	case UserDeletedAt:
+		return "deleted_at"
+	case UserVersion:
+		return "version"
 	}
 	panic("Unknown value")
 }
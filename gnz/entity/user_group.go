@@ -14,17 +14,19 @@ const (
 	UserGroupGroupUuid
 	UserGroupCreatedAt
 	UserGroupUpdatedAt
+	UserGroupDeletedAt
 )
 
 // The table `user_groups` struct
 type UserGroup struct {
-	Id         int       `json:"id"`
-	InternalId string    `json:"internal_id"`
-	Uuid       uuid.UUID `validate:"required"json:"uuid"`
-	UserUuid   uuid.UUID `validate:"required"json:"user_uuid"`
-	GroupUuid  uuid.UUID `validate:"required"json:"group_uuid"`
-	CreatedAt  time.Time `json:"created_at"`
-	UpdatedAt  time.Time `json:"updated_at"`
+	Id         int        `json:"id"`
+	InternalId string     `json:"internal_id"`
+	Uuid       uuid.UUID  `validate:"required"json:"uuid"`
+	UserUuid   uuid.UUID  `validate:"required"json:"user_uuid"`
+	GroupUuid  uuid.UUID  `validate:"required"json:"group_uuid"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+	DeletedAt  *time.Time `json:"deleted_at,omitempty"`
 }
 
 // UserGroup table config struct
@@ -48,6 +50,8 @@ func (ugc UserGroupTableConfig) String() string {
 		return "created_at"
 	case UserGroupUpdatedAt:
 		return "updated_at"
+	case UserGroupDeletedAt:
+		return "deleted_at"
 	}
 	panic("Unknown value")
 }
@@ -13,16 +13,18 @@ const (
 	UserServiceServiceUuid
 	UserServiceCreatedAt
 	UserServiceUpdatedAt
+	UserServiceDeletedAt
 )
 
 // The table `user_services` struct
 type UserService struct {
-	Id          int       `json:"id"`
-	InternalId  string    `json:"internal_id"`
-	UserUuid    uuid.UUID `validate:"required"json:"user_uuid"`
-	ServiceUuid uuid.UUID `validate:"required"json:"service_uuid"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	Id          int        `json:"id"`
+	InternalId  string     `json:"internal_id"`
+	UserUuid    uuid.UUID  `validate:"required"json:"user_uuid"`
+	ServiceUuid uuid.UUID  `validate:"required"json:"service_uuid"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+	DeletedAt   *time.Time `json:"deleted_at,omitempty"`
 }
 
 // UserService table config struct
@@ -44,6 +46,8 @@ func (usc UserServiceTableConfig) String() string {
 		return "created_at"
 	case UserServiceUpdatedAt:
 		return "updated_at"
+	case UserServiceDeletedAt:
+		return "deleted_at"
 	}
 	panic("Unknown value")
 }
@@ -59,4 +59,16 @@ func TestUserString(t *testing.T) {
 		t.Errorf("Incorrect TestString test")
 		t.FailNow()
 	}
+
+	deletedAt := UserDeletedAt.String()
+	if !strings.EqualFold(deletedAt, "deleted_at") {
+		t.Errorf("Incorrect TestString test")
+		t.FailNow()
+	}
+
+	version := UserVersion.String()
+	if !strings.EqualFold(version, "version") {
+		t.Errorf("Incorrect TestString test")
+		t.FailNow()
+	}
 }
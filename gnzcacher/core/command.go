@@ -0,0 +1,20 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Exit code of command
+// Command returns exitDifferent when it found difference, same as diff command
+const (
+	exitOk        = 0
+	exitDifferent = 1
+	exitError     = 2
+)
+
+// Print result of command
+func printJson(value interface{}) {
+	res, _ := json.MarshalIndent(value, "", "  ")
+	fmt.Println(string(res))
+}
@@ -1,10 +1,12 @@
 package core
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/tomoyane/grant-n-z/gnz/cache"
 	"github.com/tomoyane/grant-n-z/gnz/common"
@@ -24,8 +26,9 @@ var (
 )
 
 type GrantNZCacher struct {
-	UpdateTimer timer.UpdateTimer
-	Database    driver.Database
+	UpdateTimer     timer.UpdateTimer
+	Database        driver.Database
+	OperationServer OperationServer
 }
 
 func init() {
@@ -36,6 +39,7 @@ func NewGrantNZCacher() GrantNZCacher {
 	common.InitGrantNZCacherConfig(ConfigFilePath)
 	database := driver.NewDatabase()
 	database.Connect()
+	database.CheckSchema()
 	cache.InitEtcd()
 	log.Logger.Info("New GrantNZCacher")
 
@@ -48,7 +52,12 @@ func NewGrantNZCacher() GrantNZCacher {
 		syscall.SIGKILL,
 	)
 
-	return GrantNZCacher{UpdateTimer: timer.NewUpdateTimer()}
+	updateTimer := timer.NewUpdateTimer()
+	return GrantNZCacher{
+		UpdateTimer:     updateTimer,
+		Database:        database,
+		OperationServer: NewOperationServer(updateTimer, database),
+	}
 }
 
 // Start GrantNZ cache
@@ -62,6 +71,8 @@ func (g GrantNZCacher) Run() {
 
 	go g.subscribeSignal(signalCode, exitCode)
 	go g.Database.PingRdbms()
+	go g.Database.CheckReplicas()
+	go g.OperationServer.Run()
 
 	exitCode := g.UpdateTimer.Start(exitCode)
 	g.gracefulShutdown(exitCode)
@@ -100,6 +111,10 @@ func (g GrantNZCacher) subscribeSignal(signalCode chan os.Signal, exitCode chan
 
 // Graceful shutdown
 func (g GrantNZCacher) gracefulShutdown(code int) {
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	g.OperationServer.Shutdown(shutdownCtx)
+	cancel()
+
 	g.Database.Close()
 	cache.Close()
 
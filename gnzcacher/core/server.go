@@ -0,0 +1,377 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+
+	"github.com/tomoyane/grant-n-z/gnz/cache"
+	"github.com/tomoyane/grant-n-z/gnz/common"
+	"github.com/tomoyane/grant-n-z/gnz/log"
+	"github.com/tomoyane/grant-n-z/gnzcacher/service"
+	"github.com/tomoyane/grant-n-z/gnzcacher/timer"
+	"github.com/tomoyane/grant-n-z/gnzserver/model"
+)
+
+// Resync target of all entities
+const resyncAll = "all"
+
+// Dependency that must be reachable to be ready
+type Pinger interface {
+	// Returns error if not reachable
+	Ping(ctx context.Context) error
+}
+
+// File name of GET /snapshot
+const snapshotFileName = "grant_n_z_cache.snapshot.gz"
+
+// Operation endpoint of gnzcacher
+// GET /healthz, GET /readyz, GET /status, POST /resync, GET /snapshot, POST /snapshot and POST /verify
+type OperationServer struct {
+	UpdateTimer     timer.UpdateTimer
+	PrunerService   service.PrunerService
+	VerifierService service.VerifierService
+	Snapshot        cache.Snapshot
+	Database        Pinger
+	Etcd            Pinger
+	Port            string
+	ResyncToken     string
+	MaxBodyBytes    int64
+	server          *http.Server
+}
+
+// Response of /readyz
+type ReadyResponse struct {
+	Status   string `json:"status"`
+	Database string `json:"database"`
+	Etcd     string `json:"etcd"`
+}
+
+// Response of /status
+type StatusResponse struct {
+	timer.Status
+	Prune service.PruneMetrics `json:"prune"`
+}
+
+// Response of /resync
+type ResyncResponse struct {
+	Status   string `json:"status"`
+	Entity   string `json:"entity"`
+	UserUuid string `json:"user_uuid"`
+}
+
+// Response of POST /snapshot
+type SnapshotResponse struct {
+	Status string `json:"status"`
+	cache.SnapshotResult
+}
+
+// Request of /verify
+type VerifyRequest struct {
+	Repair bool `json:"repair"`
+}
+
+// Constructor
+func NewOperationServer(updateTimer timer.UpdateTimer, database Pinger) OperationServer {
+	return OperationServer{
+		UpdateTimer:     updateTimer,
+		PrunerService:   service.GetPrunerServiceInstance(),
+		VerifierService: service.NewVerifierService(),
+		Snapshot:        cache.NewSnapshot(),
+		Database:        database,
+		Etcd:            cache.NewEtcdClient(),
+		Port:            common.GCacher.Port,
+		ResyncToken:     common.GCacher.ResyncToken,
+		MaxBodyBytes:    common.GCacher.MaxBodyBytes,
+		server:          &http.Server{},
+	}
+}
+
+// Start http server
+// If port is empty, operation endpoint is not used
+func (s OperationServer) Run() {
+	if s.Port == "" {
+		log.Logger.Info("Not use operation endpoint")
+		return
+	}
+	if s.ResyncToken == "" {
+		log.Logger.Warn("Resync token is empty. POST /resync, /snapshot and /verify are disabled")
+	}
+
+	s.server.Addr = fmt.Sprintf(":%s", s.Port)
+	s.server.Handler = s.Router()
+	log.Logger.Info(fmt.Sprintf("Start operation endpoint. port = %s", s.Port))
+	if err := s.server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		log.Logger.Error("Error run operation endpoint", err.Error())
+	}
+}
+
+// Stop http server
+func (s OperationServer) Shutdown(ctx context.Context) {
+	if s.server != nil {
+		s.server.Shutdown(ctx)
+	}
+}
+
+// Router of operation endpoint
+func (s OperationServer) Router() *mux.Router {
+	router := mux.NewRouter()
+	router.NotFoundHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		res := model.NotFound("Not found resource path.")
+		model.WriteError(w, res.ToJson(), res.Code)
+	})
+	router.MethodNotAllowedHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		res := model.MethodNotAllowed()
+		model.WriteError(w, res.ToJson(), res.Code)
+	})
+
+	router.HandleFunc("/healthz", s.Healthz).Methods(http.MethodGet)
+	router.HandleFunc("/readyz", s.Readyz).Methods(http.MethodGet)
+	router.HandleFunc("/status", s.Status).Methods(http.MethodGet)
+	router.HandleFunc("/resync", s.Resync).Methods(http.MethodPost)
+	router.HandleFunc("/snapshot", s.ExportSnapshot).Methods(http.MethodGet)
+	router.HandleFunc("/snapshot", s.ImportSnapshot).Methods(http.MethodPost)
+	router.HandleFunc("/verify", s.Verify).Methods(http.MethodPost)
+	return router
+}
+
+// Http GET method
+// The process is alive
+// Endpoint is `/healthz`
+func (s OperationServer) Healthz(w http.ResponseWriter, r *http.Request) {
+	writeJson(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// Http GET method
+// Database and etcd are reachable
+// Endpoint is `/readyz`
+func (s OperationServer) Readyz(w http.ResponseWriter, r *http.Request) {
+	res := ReadyResponse{Status: "ok", Database: "ok", Etcd: "ok"}
+	if err := s.Database.Ping(r.Context()); err != nil {
+		res.Status = "unavailable"
+		res.Database = err.Error()
+	}
+	if err := s.Etcd.Ping(r.Context()); err != nil {
+		res.Status = "unavailable"
+		res.Etcd = err.Error()
+	}
+
+	code := http.StatusOK
+	if res.Status != "ok" {
+		code = http.StatusServiceUnavailable
+	}
+	writeJson(w, code, res)
+}
+
+// Http GET method
+// Status of the last update cache run and pruning
+// Endpoint is `/status`
+func (s OperationServer) Status(w http.ResponseWriter, r *http.Request) {
+	writeJson(w, http.StatusOK, StatusResponse{
+		Status: s.UpdateTimer.GetStatus(),
+		Prune:  s.PrunerService.GetMetrics(),
+	})
+}
+
+// Http POST method
+// Request resync. Required `Authorization: Bearer {resync-token}` header
+// Request body is optional. {"entity":"{all or entity name}"} or {"user_uuid":"{uuid}"}
+// Endpoint is `/resync`
+func (s OperationServer) Resync(w http.ResponseWriter, r *http.Request) {
+	if err := s.authorize(r); err != nil {
+		model.WriteError(w, err.ToJson(), err.Code)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, s.MaxBodyBytes)
+	request, err := bindResyncRequest(r)
+	if err != nil {
+		model.WriteError(w, err.ToJson(), err.Code)
+		return
+	}
+
+	if !s.UpdateTimer.Resync(request) {
+		err := model.Conflict("Resync is already pending.")
+		model.WriteError(w, err.ToJson(), err.Code)
+		return
+	}
+
+	entity := request.Entity
+	if entity == "" && request.UserUuid == "" {
+		entity = resyncAll
+	}
+	writeJson(w, http.StatusAccepted, ResyncResponse{Status: "accepted", Entity: entity, UserUuid: request.UserUuid})
+}
+
+// Http GET method
+// Download snapshot of all cache keys. Required `Authorization: Bearer {resync-token}` header
+// Endpoint is `/snapshot`
+func (s OperationServer) ExportSnapshot(w http.ResponseWriter, r *http.Request) {
+	if err := s.authorize(r); err != nil {
+		model.WriteError(w, err.ToJson(), err.Code)
+		return
+	}
+
+	// Buffer the snapshot, because status code can not be changed after writing body
+	var snapshot bytes.Buffer
+	result, err := s.Snapshot.Export(r.Context(), &snapshot)
+	if err != nil {
+		log.Logger.Error("Failed to export snapshot", err.Error())
+		res := model.InternalServerError("Failed to export snapshot.")
+		model.WriteError(w, res.ToJson(), res.Code)
+		return
+	}
+
+	log.Logger.Info(fmt.Sprintf("Exported snapshot. keys = %d, revision = %d", result.Keys, result.Header.Revision))
+	w.Header().Set("Content-Type", "application/gzip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%s", snapshotFileName))
+	w.WriteHeader(http.StatusOK)
+	w.Write(snapshot.Bytes())
+}
+
+// Http POST method
+// Import snapshot file of request body. Required `Authorization: Bearer {resync-token}` header
+// Endpoint is `/snapshot`
+func (s OperationServer) ImportSnapshot(w http.ResponseWriter, r *http.Request) {
+	if err := s.authorize(r); err != nil {
+		model.WriteError(w, err.ToJson(), err.Code)
+		return
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, s.MaxBodyBytes)
+	defer r.Body.Close()
+
+	result, err := s.Snapshot.Import(r.Context(), r.Body)
+	if err != nil {
+		var res *model.ErrorResBody
+		if isBodyTooLarge(err) {
+			res = model.RequestEntityTooLarge(fmt.Sprintf("Snapshot is larger than %d bytes.", s.MaxBodyBytes))
+		} else if _, ok := err.(cache.InvalidSnapshotError); ok {
+			res = model.BadRequest(err.Error())
+		} else {
+			log.Logger.Error("Failed to import snapshot", err.Error())
+			res = model.InternalServerError(fmt.Sprintf("Failed to import snapshot. Imported keys = %d.", result.Keys))
+		}
+		model.WriteError(w, res.ToJson(), res.Code)
+		return
+	}
+
+	log.Logger.Info(fmt.Sprintf("Imported snapshot. keys = %d, revision = %d", result.Keys, result.Header.Revision))
+	writeJson(w, http.StatusOK, SnapshotResponse{Status: "imported", SnapshotResult: result})
+}
+
+// Http POST method
+// Compare cache with database. Required `Authorization: Bearer {resync-token}` header
+// Request body is optional. {"repair":true} repairs drift
+// Endpoint is `/verify`
+func (s OperationServer) Verify(w http.ResponseWriter, r *http.Request) {
+	if err := s.authorize(r); err != nil {
+		model.WriteError(w, err.ToJson(), err.Code)
+		return
+	}
+
+	var request VerifyRequest
+	r.Body = http.MaxBytesReader(w, r.Body, s.MaxBodyBytes)
+	body, err := readBody(r)
+	if err != nil {
+		model.WriteError(w, err.ToJson(), err.Code)
+		return
+	}
+	if len(body) > 0 {
+		if err := json.Unmarshal(body, &request); err != nil {
+			res := model.BadRequest("Request is not json.")
+			model.WriteError(w, res.ToJson(), res.Code)
+			return
+		}
+	}
+
+	report := s.VerifierService.Verify(r.Context(), request.Repair)
+	log.Logger.Info(fmt.Sprintf("Verified cache. users = %d, repaired = %d", len(report.Users), report.Repaired))
+	writeJson(w, http.StatusOK, report)
+}
+
+// Check resync token
+// It is also used by snapshot and verify
+func (s OperationServer) authorize(r *http.Request) *model.ErrorResBody {
+	if s.ResyncToken == "" {
+		return model.Forbidden("Resync token is not set.")
+	}
+
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if subtle.ConstantTimeCompare([]byte(token), []byte(s.ResyncToken)) != 1 {
+		return model.Unauthorized("Invalid resync token.")
+	}
+	return nil
+}
+
+// Bind and validate resync request
+// Empty body is resync of all entities
+func bindResyncRequest(r *http.Request) (timer.ResyncRequest, *model.ErrorResBody) {
+	var request timer.ResyncRequest
+	body, err := readBody(r)
+	if err != nil {
+		return request, err
+	}
+
+	if len(body) > 0 {
+		if err := json.Unmarshal(body, &request); err != nil {
+			return request, model.BadRequest("Request is not json.")
+		}
+	}
+
+	if strings.EqualFold(request.Entity, resyncAll) {
+		request.Entity = ""
+	}
+
+	if request.UserUuid != "" {
+		if request.Entity != "" {
+			return request, model.BadRequest("Can not set entity with user_uuid.")
+		}
+		if _, err := uuid.Parse(request.UserUuid); err != nil {
+			return request, model.BadRequest("Invalid user_uuid.")
+		}
+		return request, nil
+	}
+
+	if request.Entity != "" {
+		for _, entity := range timer.Entities {
+			if request.Entity == entity {
+				return request, nil
+			}
+		}
+		return request, model.BadRequest(fmt.Sprintf("Invalid entity. Set %s or one of %s.", resyncAll, strings.Join(timer.Entities, ", ")))
+	}
+	return request, nil
+}
+
+// Write json response
+func writeJson(w http.ResponseWriter, code int, body interface{}) {
+	res, _ := json.Marshal(body)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	w.Write(res)
+}
+
+// Read request body that is limited by http.MaxBytesReader
+func readBody(r *http.Request) ([]byte, *model.ErrorResBody) {
+	body, err := ioutil.ReadAll(r.Body)
+	defer r.Body.Close()
+	if isBodyTooLarge(err) {
+		return nil, model.RequestEntityTooLarge("Request body is too large.")
+	} else if err != nil {
+		return nil, model.BadRequest("Failed to read request body.")
+	}
+	return body, nil
+}
+
+// Error of http.MaxBytesReader. It has no error type in go 1.13
+func isBodyTooLarge(err error) bool {
+	return err != nil && strings.Contains(err.Error(), "http: request body too large")
+}
@@ -0,0 +1,300 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/tomoyane/grant-n-z/gnz/cache"
+	"github.com/tomoyane/grant-n-z/gnz/log"
+	"github.com/tomoyane/grant-n-z/gnzcacher/service"
+	"github.com/tomoyane/grant-n-z/gnzcacher/timer"
+)
+
+func init() {
+	log.InitLogger("info")
+}
+
+// Less than stub struct
+// Update timer
+type StubUpdateTimerImpl struct {
+	requests *[]timer.ResyncRequest
+	pending  bool
+}
+
+func (ut StubUpdateTimerImpl) Start(exitCode chan int) int {
+	return 0
+}
+
+func (ut StubUpdateTimerImpl) Stop() {
+}
+
+func (ut StubUpdateTimerImpl) Resync(request timer.ResyncRequest) bool {
+	if ut.pending {
+		return false
+	}
+	*ut.requests = append(*ut.requests, request)
+	return true
+}
+
+func (ut StubUpdateTimerImpl) GetStatus() timer.Status {
+	return timer.Status{
+		Leader:    true,
+		RunResult: timer.RunResult{Rows: map[string]int{timer.EntityRole: 2}, Errors: map[string]string{}},
+	}
+}
+
+// Less than stub struct
+// Pinger
+type StubPingerImpl struct {
+	err error
+}
+
+func (p StubPingerImpl) Ping(ctx context.Context) error {
+	return p.err
+}
+
+// Less than stub struct
+// Verifier service
+type StubVerifierServiceImpl struct {
+	repair *bool
+}
+
+func (vs StubVerifierServiceImpl) Verify(ctx context.Context, repair bool) service.VerifyReport {
+	*vs.repair = repair
+	return service.VerifyReport{Entities: map[string]service.EntityReport{}, Users: []string{}}
+}
+
+// Less than stub struct
+// Snapshot
+type StubSnapshotImpl struct {
+	err error
+}
+
+func (s StubSnapshotImpl) Export(ctx context.Context, w io.Writer) (cache.SnapshotResult, error) {
+	if s.err != nil {
+		return cache.SnapshotResult{}, s.err
+	}
+	w.Write([]byte("snapshot"))
+	return cache.SnapshotResult{Keys: 1}, nil
+}
+
+func (s StubSnapshotImpl) Import(ctx context.Context, r io.Reader) (cache.SnapshotResult, error) {
+	if s.err != nil {
+		return cache.SnapshotResult{}, s.err
+	}
+	if _, err := ioutil.ReadAll(r); err != nil {
+		return cache.SnapshotResult{}, err
+	}
+	return cache.SnapshotResult{Keys: 1}, nil
+}
+
+// Operation server with stub
+func newStubOperationServer(pending bool, databaseErr error) (OperationServer, *[]timer.ResyncRequest) {
+	requests := &[]timer.ResyncRequest{}
+	return OperationServer{
+		UpdateTimer:     StubUpdateTimerImpl{requests: requests, pending: pending},
+		PrunerService:   service.NewPrunerServiceWithMode(nil, service.PruneModeNone),
+		Snapshot:        StubSnapshotImpl{},
+		VerifierService: StubVerifierServiceImpl{repair: new(bool)},
+		Database:        StubPingerImpl{err: databaseErr},
+		Etcd:            StubPingerImpl{},
+		ResyncToken:     "token",
+		MaxBodyBytes:    128,
+	}, requests
+}
+
+// Send request to operation server
+func serve(s OperationServer, method string, path string, token string, body string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, path, strings.NewReader(body))
+	if token != "" {
+		r.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	s.Router().ServeHTTP(w, r)
+	return w
+}
+
+// Test healthz
+func TestHealthz(t *testing.T) {
+	s, _ := newStubOperationServer(false, nil)
+	if w := serve(s, http.MethodGet, "/healthz", "", ""); w.Code != http.StatusOK {
+		t.Errorf("Incorrect TestHealthz test. code = %d", w.Code)
+		t.FailNow()
+	}
+}
+
+// Test readyz
+func TestReadyz(t *testing.T) {
+	s, _ := newStubOperationServer(false, nil)
+	if w := serve(s, http.MethodGet, "/readyz", "", ""); w.Code != http.StatusOK {
+		t.Errorf("Incorrect TestReadyz test. code = %d", w.Code)
+		t.FailNow()
+	}
+
+	s, _ = newStubOperationServer(false, errors.New("failed"))
+	w := serve(s, http.MethodGet, "/readyz", "", "")
+	var res ReadyResponse
+	json.Unmarshal(w.Body.Bytes(), &res)
+	if w.Code != http.StatusServiceUnavailable || res.Database != "failed" || res.Etcd != "ok" {
+		t.Errorf("Incorrect TestReadyz test. code = %d, body = %s", w.Code, w.Body.String())
+		t.FailNow()
+	}
+}
+
+// Test status
+func TestStatus(t *testing.T) {
+	s, _ := newStubOperationServer(false, nil)
+	w := serve(s, http.MethodGet, "/status", "", "")
+
+	var res StatusResponse
+	json.Unmarshal(w.Body.Bytes(), &res)
+	if w.Code != http.StatusOK || !res.Leader || res.Rows[timer.EntityRole] != 2 || res.Prune.Mode != service.PruneModeNone {
+		t.Errorf("Incorrect TestStatus test. code = %d, body = %s", w.Code, w.Body.String())
+		t.FailNow()
+	}
+}
+
+// Test resync
+func TestResync(t *testing.T) {
+	tests := []struct {
+		name     string
+		token    string
+		body     string
+		code     int
+		expected *timer.ResyncRequest
+	}{
+		{"no token", "", "", http.StatusUnauthorized, nil},
+		{"invalid token", "invalid", "", http.StatusUnauthorized, nil},
+		{"all with empty body", "token", "", http.StatusAccepted, &timer.ResyncRequest{}},
+		{"all", "token", `{"entity":"all"}`, http.StatusAccepted, &timer.ResyncRequest{}},
+		{"entity", "token", `{"entity":"role"}`, http.StatusAccepted, &timer.ResyncRequest{Entity: timer.EntityRole}},
+		{"user", "token", `{"user_uuid":"00000000-0000-0000-0000-000000000001"}`, http.StatusAccepted, &timer.ResyncRequest{UserUuid: "00000000-0000-0000-0000-000000000001"}},
+		{"invalid entity", "token", `{"entity":"unknown"}`, http.StatusBadRequest, nil},
+		{"invalid user_uuid", "token", `{"user_uuid":"unknown"}`, http.StatusBadRequest, nil},
+		{"entity with user_uuid", "token", `{"entity":"role","user_uuid":"00000000-0000-0000-0000-000000000001"}`, http.StatusBadRequest, nil},
+		{"not json", "token", `entity`, http.StatusBadRequest, nil},
+		{"too large", "token", `{"entity":"all"}` + strings.Repeat(" ", 128), http.StatusRequestEntityTooLarge, nil},
+	}
+
+	for _, test := range tests {
+		s, requests := newStubOperationServer(false, nil)
+		w := serve(s, http.MethodPost, "/resync", test.token, test.body)
+		if w.Code != test.code {
+			t.Errorf("Incorrect TestResync test. %s: code = %d, body = %s", test.name, w.Code, w.Body.String())
+			continue
+		}
+		if test.expected == nil && len(*requests) != 0 || test.expected != nil && (len(*requests) != 1 || (*requests)[0] != *test.expected) {
+			t.Errorf("Incorrect TestResync test. %s: requests = %v", test.name, *requests)
+		}
+	}
+}
+
+// Test resync when another resync is pending
+func TestResync_Pending(t *testing.T) {
+	s, _ := newStubOperationServer(true, nil)
+	if w := serve(s, http.MethodPost, "/resync", "token", ""); w.Code != http.StatusConflict {
+		t.Errorf("Incorrect TestResync_Pending test. code = %d", w.Code)
+		t.FailNow()
+	}
+}
+
+// Test resync when resync token is not set
+func TestResync_Disabled(t *testing.T) {
+	s, requests := newStubOperationServer(false, nil)
+	s.ResyncToken = ""
+	if w := serve(s, http.MethodPost, "/resync", "", ""); w.Code != http.StatusForbidden || len(*requests) != 0 {
+		t.Errorf("Incorrect TestResync_Disabled test. code = %d", w.Code)
+		t.FailNow()
+	}
+}
+
+// Test method not allowed
+func TestResync_MethodNotAllowed(t *testing.T) {
+	s, _ := newStubOperationServer(false, nil)
+	if w := serve(s, http.MethodGet, "/resync", "token", ""); w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Incorrect TestResync_MethodNotAllowed test. code = %d", w.Code)
+		t.FailNow()
+	}
+}
+
+// Test export snapshot
+func TestExportSnapshot(t *testing.T) {
+	s, _ := newStubOperationServer(false, nil)
+	if w := serve(s, http.MethodGet, "/snapshot", "", ""); w.Code != http.StatusUnauthorized {
+		t.Errorf("Incorrect TestExportSnapshot test. code = %d", w.Code)
+		t.FailNow()
+	}
+
+	w := serve(s, http.MethodGet, "/snapshot", "token", "")
+	if w.Code != http.StatusOK || w.Body.String() != "snapshot" || w.Header().Get("Content-Type") != "application/gzip" {
+		t.Errorf("Incorrect TestExportSnapshot test. code = %d", w.Code)
+		t.FailNow()
+	}
+
+	s.Snapshot = StubSnapshotImpl{err: errors.New("failed")}
+	if w := serve(s, http.MethodGet, "/snapshot", "token", ""); w.Code != http.StatusInternalServerError {
+		t.Errorf("Incorrect TestExportSnapshot test. code = %d", w.Code)
+		t.FailNow()
+	}
+}
+
+// Test import snapshot
+func TestImportSnapshot(t *testing.T) {
+	tests := []struct {
+		name  string
+		token string
+		err   error
+		code  int
+	}{
+		{"no token", "", nil, http.StatusUnauthorized},
+		{"imported", "token", nil, http.StatusOK},
+		{"invalid snapshot", "token", cache.InvalidSnapshotError{Detail: "invalid"}, http.StatusBadRequest},
+		{"failed", "token", errors.New("failed"), http.StatusInternalServerError},
+	}
+
+	for _, test := range tests {
+		s, _ := newStubOperationServer(false, nil)
+		s.Snapshot = StubSnapshotImpl{err: test.err}
+		if w := serve(s, http.MethodPost, "/snapshot", test.token, "snapshot"); w.Code != test.code {
+			t.Errorf("Incorrect TestImportSnapshot test. %s: code = %d, body = %s", test.name, w.Code, w.Body.String())
+		}
+	}
+
+	s, _ := newStubOperationServer(false, nil)
+	if w := serve(s, http.MethodPost, "/snapshot", "token", strings.Repeat("s", 129)); w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("Incorrect TestImportSnapshot test. too large: code = %d, body = %s", w.Code, w.Body.String())
+	}
+}
+
+// Test verify
+func TestVerify(t *testing.T) {
+	tests := []struct {
+		name   string
+		token  string
+		body   string
+		code   int
+		repair bool
+	}{
+		{"no token", "", "", http.StatusUnauthorized, false},
+		{"verify", "token", "", http.StatusOK, false},
+		{"repair", "token", `{"repair":true}`, http.StatusOK, true},
+		{"not json", "token", `repair`, http.StatusBadRequest, false},
+		{"too large", "token", `{"repair":true}` + strings.Repeat(" ", 128), http.StatusRequestEntityTooLarge, false},
+	}
+
+	for _, test := range tests {
+		s, _ := newStubOperationServer(false, nil)
+		repair := new(bool)
+		s.VerifierService = StubVerifierServiceImpl{repair: repair}
+		if w := serve(s, http.MethodPost, "/verify", test.token, test.body); w.Code != test.code || *repair != test.repair {
+			t.Errorf("Incorrect TestVerify test. %s: code = %d, body = %s", test.name, w.Code, w.Body.String())
+		}
+	}
+}
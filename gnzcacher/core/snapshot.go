@@ -0,0 +1,108 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/tomoyane/grant-n-z/gnz/cache"
+	"github.com/tomoyane/grant-n-z/gnz/common"
+	"github.com/tomoyane/grant-n-z/gnz/log"
+)
+
+const snapshotUsage = `Usage:
+  gnzcacher snapshot export {file}
+  gnzcacher snapshot import {file}
+  gnzcacher snapshot diff {source file} {target file}
+`
+
+// Run snapshot command, and return exit code
+// export and import use etcd of grant_n_z_cacher.yaml. diff only reads files
+func RunSnapshotCommand(args []string) int {
+	if len(args) == 2 && args[0] == "export" {
+		return runSnapshot(func(snapshot cache.Snapshot) (interface{}, error) {
+			return exportSnapshot(snapshot, args[1])
+		})
+	}
+	if len(args) == 2 && args[0] == "import" {
+		return runSnapshot(func(snapshot cache.Snapshot) (interface{}, error) {
+			return importSnapshot(snapshot, args[1])
+		})
+	}
+	if len(args) == 3 && args[0] == "diff" {
+		return diffSnapshot(args[1], args[2])
+	}
+
+	fmt.Fprint(os.Stderr, snapshotUsage)
+	return exitError
+}
+
+// Connect etcd, run command and print result
+func runSnapshot(command func(snapshot cache.Snapshot) (interface{}, error)) int {
+	common.InitGrantNZCacherConfig(ConfigFilePath)
+	log.InitLogger(common.App.LogLevel)
+	cache.InitEtcd()
+	defer cache.Close()
+
+	result, err := command(cache.NewSnapshot())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		return exitError
+	}
+	printJson(result)
+	return exitOk
+}
+
+// Export to file
+// The file is removed if export failed, so a truncated snapshot is not left
+func exportSnapshot(snapshot cache.Snapshot, path string) (cache.SnapshotResult, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return cache.SnapshotResult{}, err
+	}
+
+	result, err := snapshot.Export(context.Background(), file)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(path)
+	}
+	return result, err
+}
+
+// Import file
+func importSnapshot(snapshot cache.Snapshot, path string) (cache.SnapshotResult, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return cache.SnapshotResult{}, err
+	}
+	defer file.Close()
+	return snapshot.Import(context.Background(), file)
+}
+
+// Print difference of two files
+func diffSnapshot(sourcePath string, targetPath string) int {
+	var readers []io.Reader
+	for _, path := range []string{sourcePath, targetPath} {
+		file, err := os.Open(path)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err.Error())
+			return exitError
+		}
+		defer file.Close()
+		readers = append(readers, file)
+	}
+
+	diff, err := cache.DiffSnapshot(readers[0], readers[1])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		return exitError
+	}
+	printJson(diff)
+	if len(diff.Added) != 0 || len(diff.Removed) != 0 || len(diff.Changed) != 0 {
+		return exitDifferent
+	}
+	return exitOk
+}
@@ -0,0 +1,62 @@
+package core
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/tomoyane/grant-n-z/gnz/cache"
+)
+
+// Write snapshot file of entries
+func writeTestSnapshot(t *testing.T, dir string, name string, entries ...cache.SnapshotEntry) string {
+	path := filepath.Join(dir, name)
+	file, err := os.Create(path)
+	if err != nil {
+		t.Errorf("Incorrect TestRunSnapshotCommand test. err = %s", err.Error())
+		t.FailNow()
+	}
+	defer file.Close()
+
+	gz := gzip.NewWriter(file)
+	encoder := json.NewEncoder(gz)
+	encoder.Encode(cache.SnapshotHeader{Format: cache.SnapshotFormat, Version: cache.SnapshotFormatVersion, SchemaVersion: cache.SchemaVersion})
+	for _, entry := range entries {
+		encoder.Encode(entry)
+	}
+	gz.Close()
+	return path
+}
+
+// Test snapshot command
+func TestRunSnapshotCommand(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "snapshot")
+	defer os.RemoveAll(dir)
+
+	role := cache.SnapshotEntry{Key: cache.RoleKeyPrefix + "a", Value: json.RawMessage(`{"version":1,"data":{"name":"a"}}`)}
+	source := writeTestSnapshot(t, dir, "source.gz", role)
+	same := writeTestSnapshot(t, dir, "same.gz", role)
+	empty := writeTestSnapshot(t, dir, "empty.gz")
+
+	tests := []struct {
+		name string
+		args []string
+		code int
+	}{
+		{"no command", []string{}, exitError},
+		{"unknown command", []string{"unknown", source}, exitError},
+		{"diff same", []string{"diff", source, same}, exitOk},
+		{"diff different", []string{"diff", source, empty}, exitDifferent},
+		{"diff not found", []string{"diff", source, filepath.Join(dir, "none.gz")}, exitError},
+		{"diff not snapshot", []string{"diff", source, dir}, exitError},
+	}
+
+	for _, test := range tests {
+		if code := RunSnapshotCommand(test.args); code != test.code {
+			t.Errorf("Incorrect TestRunSnapshotCommand test. %s: code = %d", test.name, code)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package core
+
+import (
+	"context"
+	"flag"
+	"os"
+
+	"github.com/tomoyane/grant-n-z/gnz/cache"
+	"github.com/tomoyane/grant-n-z/gnz/common"
+	"github.com/tomoyane/grant-n-z/gnz/driver"
+	"github.com/tomoyane/grant-n-z/gnz/log"
+	"github.com/tomoyane/grant-n-z/gnzcacher/service"
+)
+
+// Run verify command, and return exit code
+// gnzcacher verify [-repair]
+// It returns exitDifferent if drift was found, even if it was repaired
+func RunVerifyCommand(args []string) int {
+	flags := flag.NewFlagSet("verify", flag.ContinueOnError)
+	flags.SetOutput(os.Stderr)
+	repair := flags.Bool("repair", false, "Update missing and mismatched keys, and delete extra keys")
+	if err := flags.Parse(args); err != nil || flags.NArg() != 0 {
+		return exitError
+	}
+
+	common.InitGrantNZCacherConfig(ConfigFilePath)
+	log.InitLogger(common.App.LogLevel)
+	database := driver.NewDatabase()
+	database.Connect()
+	defer database.Close()
+	cache.InitEtcd()
+	defer cache.Close()
+
+	report := service.NewVerifierService().Verify(context.Background(), *repair)
+	printJson(report)
+	return verifyExitCode(report)
+}
+
+// Exit code of verify report
+func verifyExitCode(report service.VerifyReport) int {
+	for _, entity := range report.Entities {
+		if entity.Error != "" {
+			return exitError
+		}
+	}
+	if !report.Consistent() {
+		return exitDifferent
+	}
+	return exitOk
+}
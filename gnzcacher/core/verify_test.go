@@ -0,0 +1,30 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/tomoyane/grant-n-z/gnzcacher/service"
+)
+
+// Test exit code of verify
+func TestVerifyExitCode(t *testing.T) {
+	tests := []struct {
+		name     string
+		entities map[string]service.EntityReport
+		code     int
+	}{
+		{"consistent", map[string]service.EntityReport{"role": {Checked: 1}}, exitOk},
+		{"drift", map[string]service.EntityReport{"role": {Checked: 1, Missing: []string{"role=a"}}}, exitDifferent},
+		{"error", map[string]service.EntityReport{"role": {Missing: []string{"role=a"}}, "service": {Error: "failed"}}, exitError},
+	}
+
+	for _, test := range tests {
+		if code := verifyExitCode(service.VerifyReport{Entities: test.entities}); code != test.code {
+			t.Errorf("Incorrect TestVerifyExitCode test. %s: code = %d", test.name, code)
+		}
+	}
+
+	if code := RunVerifyCommand([]string{"unknown"}); code != exitError {
+		t.Errorf("Incorrect TestVerifyExitCode test. code = %d", code)
+	}
+}
@@ -1,7 +1,19 @@
 package main
 
-import "github.com/tomoyane/grant-n-z/gnzcacher/core"
+import (
+	"os"
+
+	"github.com/tomoyane/grant-n-z/gnzcacher/core"
+)
 
 func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "snapshot":
+			os.Exit(core.RunSnapshotCommand(os.Args[2:]))
+		case "verify":
+			os.Exit(core.RunVerifyCommand(os.Args[2:]))
+		}
+	}
 	core.NewGrantNZCacher().Run()
 }
@@ -1,28 +1,44 @@
 package service
 
 import (
+	"context"
 	"github.com/tomoyane/grant-n-z/gnz/cache/structure"
 	"github.com/tomoyane/grant-n-z/gnz/driver"
 )
 
+// Extract cache data from database
+// The methods for user data page by user with keyset pagination, so that one user's data is always in the same page
+// They return the last user uuid of the page, that is afterUserUuid of the next page
 type ExtractorService interface {
-	// Get policies for offset and limit
-	GetPolicies(offset int, limit int) map[string][]structure.UserPolicy
+	// Get policies of users in user_groups for limit of users after afterUserUuid
+	GetPolicies(ctx context.Context, afterUserUuid string, limit int) (map[string][]structure.UserPolicy, string, error)
 
 	// Get permissions for offset and limit
-	GetPermissions(offset int, limit int) []structure.Permission
+	GetPermissions(ctx context.Context, offset int, limit int) ([]structure.Permission, error)
 
 	// Get roles for offset and limit
-	GetRoles(offset int, limit int) []structure.Role
+	GetRoles(ctx context.Context, offset int, limit int) ([]structure.Role, error)
 
 	// Get services for offset and limit
-	GetServices(offset int, limit int) []structure.Service
+	GetServices(ctx context.Context, offset int, limit int) ([]structure.Service, error)
 
-	// Get user_services for offset and limit
-	GetUserServices(offset int, limit int) map[string][]structure.UserService
+	// Get user_services for limit of users after afterUserUuid
+	GetUserServices(ctx context.Context, afterUserUuid string, limit int) (map[string][]structure.UserService, string, error)
 
-	// Get user_groups for offset and limit
-	GetUserGroups(offset int, limit int) map[string][]structure.UserGroup
+	// Get user_groups for limit of users after afterUserUuid
+	GetUserGroups(ctx context.Context, afterUserUuid string, limit int) (map[string][]structure.UserGroup, string, error)
+
+	// Get policies of users in userUuids
+	// A user that has no policy is in the result with empty policies
+	GetPoliciesByUserUuids(ctx context.Context, userUuids []string) (map[string][]structure.UserPolicy, error)
+
+	// Get user_services of users in userUuids
+	// A user that has no user_service is in the result with empty user_services
+	GetUserServicesByUserUuids(ctx context.Context, userUuids []string) (map[string][]structure.UserService, error)
+
+	// Get user_groups of users in userUuids
+	// A user that has no user_group is in the result with empty user_groups
+	GetUserGroupsByUserUuids(ctx context.Context, userUuids []string) (map[string][]structure.UserGroup, error)
 }
 
 type ExtractorServiceImpl struct {
@@ -31,7 +47,6 @@ type ExtractorServiceImpl struct {
 	RoleRepository       driver.RoleRepository
 	ServiceRepository    driver.ServiceRepository
 	UserRepository       driver.UserRepository
-	GroupRepository      driver.GroupRepository
 }
 
 func NewExtractorService() ExtractorService {
@@ -41,49 +56,30 @@ func NewExtractorService() ExtractorService {
 		RoleRepository:       driver.NewRoleRepository(),
 		ServiceRepository:    driver.NewServiceRepository(),
 		UserRepository:       driver.GetUserRepositoryInstance(),
-		GroupRepository:      driver.GetGroupRepositoryInstance(),
 	}
 }
 
-func (es ExtractorServiceImpl) GetPolicies(offset int, limit int) map[string][]structure.UserPolicy {
-	userServices, err := es.UserRepository.FindUserServicesOffSetAndLimit(offset, limit)
+func (es ExtractorServiceImpl) GetPolicies(ctx context.Context, afterUserUuid string, limit int) (map[string][]structure.UserPolicy, string, error) {
+	userUuids, err := es.UserRepository.FindUserUuidsOfUserGroupsAfter(ctx, afterUserUuid, limit)
 	if err != nil {
-		return nil
+		return nil, "", err
+	}
+	if len(userUuids) == 0 {
+		return map[string][]structure.UserPolicy{}, "", nil
 	}
 
-	userPolicyMap := make(map[string][]structure.UserPolicy)
-	checkedUserUuid := ""
-	var userPolicies []structure.UserPolicy
-	for _, userService := range userServices {
-		if checkedUserUuid == userService.UserUuid.String() {
-			continue
-		}
-
-		policies, err := es.PolicyRepository.FindPolicyOfUserServiceByUserUuidAndServiceUuid(userService.UserUuid.String())
-		if err != nil {
-			return nil
-		}
-
-		for _, policy := range policies {
-			userPolicies = append(userPolicies, structure.UserPolicy{
-				ServiceUuid:    userService.ServiceUuid.String(),
-				GroupUuid:      policy.GroupUuid,
-				RoleName:       policy.RoleName,
-				PermissionName: policy.PermissionName,
-			})
-		}
-
-		userPolicyMap[userService.UserUuid.String()] = userPolicies
-		checkedUserUuid = userService.UserUuid.String()
+	userPolicyMap, err := es.GetPoliciesByUserUuids(ctx, userUuids)
+	if err != nil {
+		return nil, "", err
 	}
 
-	return userPolicyMap
+	return userPolicyMap, userUuids[len(userUuids)-1], nil
 }
 
-func (es ExtractorServiceImpl) GetPermissions(offset int, limit int) []structure.Permission {
-	permissions, err := es.PermissionRepository.FindOffSetAndLimit(offset, limit)
+func (es ExtractorServiceImpl) GetPermissions(ctx context.Context, offset int, limit int) ([]structure.Permission, error) {
+	permissions, err := es.PermissionRepository.FindOffSetAndLimit(ctx, offset, limit)
 	if err != nil {
-		return []structure.Permission{}
+		return nil, err
 	}
 
 	var stPermissions []structure.Permission
@@ -94,13 +90,13 @@ func (es ExtractorServiceImpl) GetPermissions(offset int, limit int) []structure
 		})
 	}
 
-	return stPermissions
+	return stPermissions, nil
 }
 
-func (es ExtractorServiceImpl) GetRoles(offset int, limit int) []structure.Role {
-	roles, err := es.RoleRepository.FindOffSetAndLimit(offset, limit)
+func (es ExtractorServiceImpl) GetRoles(ctx context.Context, offset int, limit int) ([]structure.Role, error) {
+	roles, err := es.RoleRepository.FindOffSetAndLimit(ctx, offset, limit)
 	if err != nil {
-		return []structure.Role{}
+		return nil, err
 	}
 
 	var stRoles []structure.Role
@@ -111,13 +107,13 @@ func (es ExtractorServiceImpl) GetRoles(offset int, limit int) []structure.Role
 		})
 	}
 
-	return stRoles
+	return stRoles, nil
 }
 
-func (es ExtractorServiceImpl) GetServices(offset int, limit int) []structure.Service {
-	services, err := es.ServiceRepository.FindOffSetAndLimit(offset, limit)
+func (es ExtractorServiceImpl) GetServices(ctx context.Context, offset int, limit int) ([]structure.Service, error) {
+	services, err := es.ServiceRepository.FindOffSetAndLimit(ctx, offset, limit)
 	if err != nil {
-		return []structure.Service{}
+		return nil, err
 	}
 
 	var stServices []structure.Service
@@ -128,67 +124,101 @@ func (es ExtractorServiceImpl) GetServices(offset int, limit int) []structure.Se
 		})
 	}
 
-	return stServices
+	return stServices, nil
+}
+
+func (es ExtractorServiceImpl) GetUserServices(ctx context.Context, afterUserUuid string, limit int) (map[string][]structure.UserService, string, error) {
+	userUuids, err := es.UserRepository.FindUserUuidsOfUserServicesAfter(ctx, afterUserUuid, limit)
+	if err != nil {
+		return nil, "", err
+	}
+	if len(userUuids) == 0 {
+		return map[string][]structure.UserService{}, "", nil
+	}
+
+	userServiceMap, err := es.GetUserServicesByUserUuids(ctx, userUuids)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return userServiceMap, userUuids[len(userUuids)-1], nil
 }
 
-func (es ExtractorServiceImpl) GetUserServices(offset int, limit int) map[string][]structure.UserService {
-	userServices, err := es.UserRepository.FindUserServicesOffSetAndLimit(offset, limit)
+func (es ExtractorServiceImpl) GetUserGroups(ctx context.Context, afterUserUuid string, limit int) (map[string][]structure.UserGroup, string, error) {
+	userUuids, err := es.UserRepository.FindUserUuidsOfUserGroupsAfter(ctx, afterUserUuid, limit)
 	if err != nil {
-		return map[string][]structure.UserService{}
+		return nil, "", err
+	}
+	if len(userUuids) == 0 {
+		return map[string][]structure.UserGroup{}, "", nil
 	}
 
-	userServiceMap := make(map[string][]structure.UserService)
-	checkedUserUuid := ""
-	var stUserServices []structure.UserService
-	for _, userService := range userServices {
-		ser, err := es.ServiceRepository.FindByUuid(userService.ServiceUuid.String())
-		if err != nil {
-			return map[string][]structure.UserService{}
-		}
-
-		if checkedUserUuid != userService.UserUuid.String() {
-			stUserServices = stUserServices[:0]
-		}
-
-		stUserServices = append(stUserServices, structure.UserService{
-			ServiceUUid: userService.ServiceUuid.String(),
-			ServiceName: ser.Name,
+	userGroupMap, err := es.GetUserGroupsByUserUuids(ctx, userUuids)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return userGroupMap, userUuids[len(userUuids)-1], nil
+}
+
+func (es ExtractorServiceImpl) GetPoliciesByUserUuids(ctx context.Context, userUuids []string) (map[string][]structure.UserPolicy, error) {
+	policies, err := es.PolicyRepository.FindPolicyOfUserGroupByUserUuids(ctx, userUuids)
+	if err != nil {
+		return nil, err
+	}
+
+	userPolicyMap := make(map[string][]structure.UserPolicy, len(userUuids))
+	for _, userUuid := range userUuids {
+		userPolicyMap[userUuid] = []structure.UserPolicy{}
+	}
+	for _, policy := range policies {
+		userPolicyMap[policy.UserUuid] = append(userPolicyMap[policy.UserUuid], structure.UserPolicy{
+			ServiceUuid:    policy.ServiceUuid,
+			GroupUuid:      policy.GroupUuid,
+			RoleName:       policy.RoleName,
+			PermissionName: policy.PermissionName,
 		})
+	}
 
-		userServiceMap[userService.UserUuid.String()] = stUserServices
-		checkedUserUuid = userService.UserUuid.String()
+	return userPolicyMap, nil
+}
+
+func (es ExtractorServiceImpl) GetUserServicesByUserUuids(ctx context.Context, userUuids []string) (map[string][]structure.UserService, error) {
+	userServices, err := es.UserRepository.FindUserServicesWithServiceByUserUuids(ctx, userUuids)
+	if err != nil {
+		return nil, err
+	}
+
+	userServiceMap := make(map[string][]structure.UserService, len(userUuids))
+	for _, userUuid := range userUuids {
+		userServiceMap[userUuid] = []structure.UserService{}
+	}
+	for _, userService := range userServices {
+		userServiceMap[userService.UserUuid] = append(userServiceMap[userService.UserUuid], structure.UserService{
+			ServiceUUid: userService.ServiceUuid,
+			ServiceName: userService.ServiceName,
+		})
 	}
 
-	return userServiceMap
+	return userServiceMap, nil
 }
 
-func (es ExtractorServiceImpl) GetUserGroups(offset int, limit int) map[string][]structure.UserGroup {
-	userGroups, err := es.UserRepository.FindUserGroupsOffSetAndLimit(offset, limit)
+func (es ExtractorServiceImpl) GetUserGroupsByUserUuids(ctx context.Context, userUuids []string) (map[string][]structure.UserGroup, error) {
+	userGroups, err := es.UserRepository.FindUserGroupsWithGroupByUserUuids(ctx, userUuids)
 	if err != nil {
-		return map[string][]structure.UserGroup{}
+		return nil, err
 	}
 
-	userGroupMap := make(map[string][]structure.UserGroup)
-	checkedUserUuid := ""
-	var stUserGroups []structure.UserGroup
+	userGroupMap := make(map[string][]structure.UserGroup, len(userUuids))
+	for _, userUuid := range userUuids {
+		userGroupMap[userUuid] = []structure.UserGroup{}
+	}
 	for _, userGroup := range userGroups {
-		group, err := es.GroupRepository.FindByUuid(userGroup.GroupUuid.String())
-		if err != nil {
-			return map[string][]structure.UserGroup{}
-		}
-
-		if checkedUserUuid != userGroup.UserUuid.String() {
-			stUserGroups = stUserGroups[:0]
-		}
-
-		stUserGroups = append(stUserGroups, structure.UserGroup{
-			GroupUuid: userGroup.GroupUuid.String(),
-			GroupName: group.Name,
+		userGroupMap[userGroup.UserUuid] = append(userGroupMap[userGroup.UserUuid], structure.UserGroup{
+			GroupUuid: userGroup.GroupUuid,
+			GroupName: userGroup.GroupName,
 		})
-
-		userGroupMap[userGroup.UserUuid.String()] = stUserGroups
-		checkedUserUuid = userGroup.UserUuid.String()
 	}
 
-	return userGroupMap
+	return userGroupMap, nil
 }
@@ -1,10 +1,14 @@
 package service
 
 import (
+	"context"
+	"fmt"
+	"reflect"
 	"testing"
 
 	"github.com/jinzhu/gorm"
 	_ "github.com/mattn/go-sqlite3"
+	"github.com/tomoyane/grant-n-z/gnz/cache/structure"
 	"github.com/tomoyane/grant-n-z/gnz/driver"
 	"github.com/tomoyane/grant-n-z/gnz/log"
 )
@@ -34,8 +38,8 @@ func TestGetPolicies(t *testing.T) {
 		UserRepository:   stubUserRepository,
 	}
 
-	policies := extractorService.GetPolicies(1, 1)
-	if len(policies) > 0 {
+	policies, _, err := extractorService.GetPolicies(context.Background(), "", 1)
+	if err == nil || len(policies) > 0 {
 		t.Errorf("Incorrect TestGetPolicies test")
 		t.FailNow()
 	}
@@ -48,8 +52,8 @@ func TestGetPermissions(t *testing.T) {
 		PermissionRepository: stubPermissionRepository,
 	}
 
-	policies := extractorService.GetPermissions(1, 1)
-	if len(policies) > 0 {
+	policies, err := extractorService.GetPermissions(context.Background(), 1, 1)
+	if err == nil || len(policies) > 0 {
 		t.Errorf("Incorrect TestGetPermissions test")
 		t.FailNow()
 	}
@@ -62,8 +66,8 @@ func TestGetRoles(t *testing.T) {
 		RoleRepository: stubRoleRepository,
 	}
 
-	roles := extractorService.GetRoles(1, 1)
-	if len(roles) > 0 {
+	roles, err := extractorService.GetRoles(context.Background(), 1, 1)
+	if err == nil || len(roles) > 0 {
 		t.Errorf("Incorrect TestGetRoles test")
 		t.FailNow()
 	}
@@ -76,8 +80,8 @@ func TestGetServices(t *testing.T) {
 		ServiceRepository: stubServiceRepository,
 	}
 
-	services := extractorService.GetServices(1, 1)
-	if len(services) > 0 {
+	services, err := extractorService.GetServices(context.Background(), 1, 1)
+	if err == nil || len(services) > 0 {
 		t.Errorf("Incorrect TestGetServices test")
 		t.FailNow()
 	}
@@ -90,8 +94,8 @@ func TestGetUserServices(t *testing.T) {
 		UserRepository: stubUserRepository,
 	}
 
-	userServices := extractorService.GetUserServices(1, 1)
-	if len(userServices) > 0 {
+	userServices, _, err := extractorService.GetUserServices(context.Background(), "", 1)
+	if err == nil || len(userServices) > 0 {
 		t.Errorf("Incorrect TestGetUserServices test")
 		t.FailNow()
 	}
@@ -100,15 +104,359 @@ func TestGetUserServices(t *testing.T) {
 // Test get user groups
 func TestGetUserGroups(t *testing.T) {
 	stubUserRepository := driver.UserRepositoryImpl{Connection: stubConnection}
-	stubUGroupRepository := driver.GroupRepositoryImpl{Connection: stubConnection}
 	extractorService = ExtractorServiceImpl{
-		UserRepository:  stubUserRepository,
-		GroupRepository: stubUGroupRepository,
+		UserRepository: stubUserRepository,
 	}
 
-	userGroups := extractorService.GetUserGroups(1, 1)
-	if len(userGroups) > 0 {
+	userGroups, _, err := extractorService.GetUserGroups(context.Background(), "", 1)
+	if err == nil || len(userGroups) > 0 {
 		t.Errorf("Incorrect TestGetUserGroups test")
 		t.FailNow()
 	}
 }
+
+// Seeded database uuid
+const (
+	seedUser1      = "00000000-0000-0000-0000-000000000001"
+	seedUser2      = "00000000-0000-0000-0000-000000000002"
+	seedUser3      = "00000000-0000-0000-0000-000000000003"
+	seedService1   = "10000000-0000-0000-0000-000000000001"
+	seedService2   = "10000000-0000-0000-0000-000000000002"
+	seedGroup1     = "20000000-0000-0000-0000-000000000001"
+	seedGroup2     = "20000000-0000-0000-0000-000000000002"
+	seedAdmin      = "30000000-0000-0000-0000-000000000001"
+	seedUserRole   = "30000000-0000-0000-0000-000000000002"
+	seedRead       = "40000000-0000-0000-0000-000000000001"
+	seedWrite      = "40000000-0000-0000-0000-000000000002"
+	seedUserGroup1 = "50000000-0000-0000-0000-000000000001"
+	seedUserGroup2 = "50000000-0000-0000-0000-000000000002"
+	seedUserGroup3 = "50000000-0000-0000-0000-000000000003"
+)
+
+// Tables that the extractor reads
+var seedSchema = []string{
+	"CREATE TABLE users (id integer PRIMARY KEY AUTOINCREMENT, internal_id varchar(32), uuid varchar(128) UNIQUE, username varchar(128), email varchar(128), password varchar(128), created_at datetime, updated_at datetime, deleted_at datetime, version integer NOT NULL DEFAULT 1)",
+	"CREATE TABLE services (id integer PRIMARY KEY AUTOINCREMENT, internal_id varchar(32), uuid varchar(128) UNIQUE, name varchar(128), secret varchar(128), created_at datetime, updated_at datetime, deleted_at datetime)",
+	"CREATE TABLE groups (id integer PRIMARY KEY AUTOINCREMENT, internal_id varchar(32), uuid varchar(128) UNIQUE, name varchar(128), created_at datetime, updated_at datetime, deleted_at datetime)",
+	"CREATE TABLE roles (id integer PRIMARY KEY AUTOINCREMENT, internal_id varchar(32), uuid varchar(128) UNIQUE, name varchar(128), created_at datetime, updated_at datetime, deleted_at datetime)",
+	"CREATE TABLE permissions (id integer PRIMARY KEY AUTOINCREMENT, internal_id varchar(32), uuid varchar(128) UNIQUE, name varchar(128), created_at datetime, updated_at datetime, deleted_at datetime)",
+	"CREATE TABLE user_services (id integer PRIMARY KEY AUTOINCREMENT, internal_id varchar(32), user_uuid varchar(128), service_uuid varchar(128), created_at datetime, updated_at datetime, deleted_at datetime)",
+	"CREATE TABLE user_groups (id integer PRIMARY KEY AUTOINCREMENT, internal_id varchar(32), uuid varchar(128) UNIQUE, user_uuid varchar(128), group_uuid varchar(128), created_at datetime, updated_at datetime, deleted_at datetime)",
+	"CREATE TABLE policies (id integer PRIMARY KEY AUTOINCREMENT, internal_id varchar(32), name varchar(128), role_uuid varchar(128), permission_uuid varchar(128), service_uuid varchar(128), user_group_uuid varchar(128), created_at datetime, updated_at datetime, deleted_at datetime, version integer NOT NULL DEFAULT 1)",
+}
+
+// user1 is in service1 and service2, group1 as admin and group2 as user
+// user2 is in service1, group1 as user
+// user3 is in service2 and no group
+var seedData = []string{
+	"INSERT INTO users (uuid, username, email) VALUES ('" + seedUser1 + "', 'user1', 'user1@gmail.com'), ('" + seedUser2 + "', 'user2', 'user2@gmail.com'), ('" + seedUser3 + "', 'user3', 'user3@gmail.com')",
+	"INSERT INTO services (uuid, name, secret) VALUES ('" + seedService1 + "', 'service1', 'secret1'), ('" + seedService2 + "', 'service2', 'secret2')",
+	"INSERT INTO groups (uuid, name) VALUES ('" + seedGroup1 + "', 'group1'), ('" + seedGroup2 + "', 'group2')",
+	"INSERT INTO roles (uuid, name) VALUES ('" + seedAdmin + "', 'admin'), ('" + seedUserRole + "', 'user')",
+	"INSERT INTO permissions (uuid, name) VALUES ('" + seedRead + "', 'read'), ('" + seedWrite + "', 'write')",
+	"INSERT INTO user_services (user_uuid, service_uuid) VALUES ('" + seedUser2 + "', '" + seedService1 + "'), ('" + seedUser1 + "', '" + seedService1 + "'), ('" + seedUser3 + "', '" + seedService2 + "'), ('" + seedUser1 + "', '" + seedService2 + "')",
+	"INSERT INTO user_groups (uuid, user_uuid, group_uuid) VALUES ('" + seedUserGroup1 + "', '" + seedUser1 + "', '" + seedGroup1 + "'), ('" + seedUserGroup2 + "', '" + seedUser2 + "', '" + seedGroup1 + "'), ('" + seedUserGroup3 + "', '" + seedUser1 + "', '" + seedGroup2 + "')",
+	"INSERT INTO policies (name, role_uuid, permission_uuid, service_uuid, user_group_uuid) VALUES ('admin_policy', '" + seedAdmin + "', '" + seedWrite + "', '" + seedService1 + "', '" + seedUserGroup1 + "'), ('user_policy', '" + seedUserRole + "', '" + seedRead + "', '" + seedService1 + "', '" + seedUserGroup2 + "'), ('user_policy', '" + seedUserRole + "', '" + seedRead + "', '" + seedService2 + "', '" + seedUserGroup3 + "')",
+}
+
+// Open in-memory database with seed data
+func newSeededConnection(t *testing.T) *gorm.DB {
+	connection, err := gorm.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("Failed to open sqlite3. %s", err.Error())
+	}
+	connection.DB().SetMaxOpenConns(1)
+
+	for _, query := range append(seedSchema, seedData...) {
+		if err := connection.Exec(query).Error; err != nil {
+			t.Fatalf("Failed to seed sqlite3. %s", err.Error())
+		}
+	}
+	return connection
+}
+
+// Extractor service using seeded database
+func newSeededExtractorService(t *testing.T) ExtractorService {
+	connection := newSeededConnection(t)
+	return ExtractorServiceImpl{
+		PolicyRepository:     driver.PolicyRepositoryImpl{Connection: connection},
+		PermissionRepository: driver.PermissionRepositoryImpl{Connection: connection},
+		RoleRepository:       driver.RoleRepositoryImpl{Connection: connection},
+		ServiceRepository:    driver.ServiceRepositoryImpl{Connection: connection},
+		UserRepository:       driver.UserRepositoryImpl{Connection: connection},
+	}
+}
+
+// Test get policies with seeded database
+func TestGetPolicies_Seeded(t *testing.T) {
+	user1Policies := []structure.UserPolicy{
+		{ServiceUuid: seedService1, GroupUuid: seedGroup1, RoleName: "admin", PermissionName: "write"},
+		{ServiceUuid: seedService2, GroupUuid: seedGroup2, RoleName: "user", PermissionName: "read"},
+	}
+	user2Policies := []structure.UserPolicy{
+		{ServiceUuid: seedService1, GroupUuid: seedGroup1, RoleName: "user", PermissionName: "read"},
+	}
+
+	tests := []struct {
+		name          string
+		afterUserUuid string
+		limit         int
+		expected      map[string][]structure.UserPolicy
+		expectedLast  string
+	}{
+		{"all users", "", 100, map[string][]structure.UserPolicy{seedUser1: user1Policies, seedUser2: user2Policies}, seedUser2},
+		{"first page", "", 1, map[string][]structure.UserPolicy{seedUser1: user1Policies}, seedUser1},
+		{"second page", seedUser1, 1, map[string][]structure.UserPolicy{seedUser2: user2Policies}, seedUser2},
+		{"out of range", seedUser2, 1, map[string][]structure.UserPolicy{}, ""},
+	}
+
+	service := newSeededExtractorService(t)
+	for _, test := range tests {
+		policies, last, err := service.GetPolicies(context.Background(), test.afterUserUuid, test.limit)
+		if err != nil || last != test.expectedLast || !reflect.DeepEqual(policies, test.expected) {
+			t.Errorf("Incorrect TestGetPolicies_Seeded test. %s: policies = %v, last = %s, err = %v", test.name, policies, last, err)
+		}
+	}
+}
+
+// Test get user services with seeded database
+func TestGetUserServices_Seeded(t *testing.T) {
+	service1 := structure.UserService{ServiceUUid: seedService1, ServiceName: "service1"}
+	service2 := structure.UserService{ServiceUUid: seedService2, ServiceName: "service2"}
+
+	tests := []struct {
+		name          string
+		afterUserUuid string
+		limit         int
+		expected      map[string][]structure.UserService
+		expectedLast  string
+	}{
+		{"all users", "", 100, map[string][]structure.UserService{
+			seedUser1: {service1, service2},
+			seedUser2: {service1},
+			seedUser3: {service2},
+		}, seedUser3},
+		{"user rows split across pages", "", 1, map[string][]structure.UserService{seedUser1: {service1, service2}}, seedUser1},
+		{"second page", seedUser1, 2, map[string][]structure.UserService{seedUser2: {service1}, seedUser3: {service2}}, seedUser3},
+		{"out of range", seedUser3, 1, map[string][]structure.UserService{}, ""},
+	}
+
+	service := newSeededExtractorService(t)
+	for _, test := range tests {
+		userServices, last, err := service.GetUserServices(context.Background(), test.afterUserUuid, test.limit)
+		if err != nil || last != test.expectedLast || !reflect.DeepEqual(userServices, test.expected) {
+			t.Errorf("Incorrect TestGetUserServices_Seeded test. %s: user_services = %v, last = %s, err = %v", test.name, userServices, last, err)
+		}
+	}
+}
+
+// Test get user groups with seeded database
+func TestGetUserGroups_Seeded(t *testing.T) {
+	group1 := structure.UserGroup{GroupUuid: seedGroup1, GroupName: "group1"}
+	group2 := structure.UserGroup{GroupUuid: seedGroup2, GroupName: "group2"}
+
+	tests := []struct {
+		name          string
+		afterUserUuid string
+		limit         int
+		expected      map[string][]structure.UserGroup
+		expectedLast  string
+	}{
+		{"all users", "", 100, map[string][]structure.UserGroup{seedUser1: {group1, group2}, seedUser2: {group1}}, seedUser2},
+		{"first page", "", 1, map[string][]structure.UserGroup{seedUser1: {group1, group2}}, seedUser1},
+		{"second page", seedUser1, 1, map[string][]structure.UserGroup{seedUser2: {group1}}, seedUser2},
+		{"out of range", seedUser2, 1, map[string][]structure.UserGroup{}, ""},
+	}
+
+	service := newSeededExtractorService(t)
+	for _, test := range tests {
+		userGroups, last, err := service.GetUserGroups(context.Background(), test.afterUserUuid, test.limit)
+		if err != nil || last != test.expectedLast || !reflect.DeepEqual(userGroups, test.expected) {
+			t.Errorf("Incorrect TestGetUserGroups_Seeded test. %s: user_groups = %v, last = %s, err = %v", test.name, userGroups, last, err)
+		}
+	}
+}
+
+// Test a user in user_groups without policy has empty policies
+func TestGetPolicies_SeededWithoutPolicy(t *testing.T) {
+	connection := newSeededConnection(t)
+	if err := connection.Exec("DELETE FROM policies WHERE user_group_uuid = ?", seedUserGroup2).Error; err != nil {
+		t.Fatalf("Failed to delete policy. %s", err.Error())
+	}
+	service := ExtractorServiceImpl{
+		PolicyRepository: driver.PolicyRepositoryImpl{Connection: connection},
+		UserRepository:   driver.UserRepositoryImpl{Connection: connection},
+	}
+
+	policies, last, err := service.GetPolicies(context.Background(), seedUser1, 100)
+	if err != nil || last != seedUser2 || !reflect.DeepEqual(policies, map[string][]structure.UserPolicy{seedUser2: {}}) {
+		t.Errorf("Incorrect TestGetPolicies_SeededWithoutPolicy test. policies = %v, last = %s, err = %v", policies, last, err)
+		t.FailNow()
+	}
+}
+
+// Benchmark users
+const benchUsers = 2000
+
+// Open in-memory database with benchUsers users
+// Each user is in 2 services and 2 groups, and each user_group has 1 policy
+func newBenchConnection(b *testing.B) *gorm.DB {
+	connection, err := gorm.Open("sqlite3", ":memory:")
+	if err != nil {
+		b.Fatalf("Failed to open sqlite3. %s", err.Error())
+	}
+	connection.DB().SetMaxOpenConns(1)
+
+	for _, query := range seedSchema {
+		if err := connection.Exec(query).Error; err != nil {
+			b.Fatalf("Failed to create table. %s", err.Error())
+		}
+	}
+
+	queries := []string{
+		"INSERT INTO services (uuid, name, secret) VALUES ('" + seedService1 + "', 'service1', 'secret1'), ('" + seedService2 + "', 'service2', 'secret2')",
+		"INSERT INTO groups (uuid, name) VALUES ('" + seedGroup1 + "', 'group1'), ('" + seedGroup2 + "', 'group2')",
+		"INSERT INTO roles (uuid, name) VALUES ('" + seedAdmin + "', 'admin')",
+		"INSERT INTO permissions (uuid, name) VALUES ('" + seedRead + "', 'read')",
+	}
+	for i := 0; i < benchUsers; i++ {
+		userUuid := fmt.Sprintf("60000000-0000-0000-0000-%012d", i)
+		userGroup1 := fmt.Sprintf("70000000-0000-0000-0000-%012d", i)
+		userGroup2 := fmt.Sprintf("80000000-0000-0000-0000-%012d", i)
+		queries = append(queries,
+			"INSERT INTO user_services (user_uuid, service_uuid) VALUES ('"+userUuid+"', '"+seedService1+"'), ('"+userUuid+"', '"+seedService2+"')",
+			"INSERT INTO user_groups (uuid, user_uuid, group_uuid) VALUES ('"+userGroup1+"', '"+userUuid+"', '"+seedGroup1+"'), ('"+userGroup2+"', '"+userUuid+"', '"+seedGroup2+"')",
+			"INSERT INTO policies (name, role_uuid, permission_uuid, service_uuid, user_group_uuid) VALUES ('policy', '"+seedAdmin+"', '"+seedRead+"', '"+seedService1+"', '"+userGroup1+"'), ('policy', '"+seedAdmin+"', '"+seedRead+"', '"+seedService2+"', '"+userGroup2+"')",
+		)
+	}
+
+	tx := connection.Begin()
+	for _, query := range queries {
+		if err := tx.Exec(query).Error; err != nil {
+			tx.Rollback()
+			b.Fatalf("Failed to seed sqlite3. %s", err.Error())
+		}
+	}
+	tx.Commit()
+	return connection
+}
+
+// Benchmark one cycle of policies, user_services and user_groups with bulk queries
+func BenchmarkExtractUserData_Bulk(b *testing.B) {
+	connection := newBenchConnection(b)
+	service := ExtractorServiceImpl{
+		PolicyRepository: driver.PolicyRepositoryImpl{Connection: connection},
+		UserRepository:   driver.UserRepositoryImpl{Connection: connection},
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for after := ""; ; {
+			policies, last, err := service.GetPolicies(context.Background(), after, 100)
+			if err != nil {
+				b.Fatal(err)
+			}
+			if len(policies) == 0 {
+				break
+			}
+			after = last
+		}
+		for after := ""; ; {
+			userServices, last, err := service.GetUserServices(context.Background(), after, 100)
+			if err != nil {
+				b.Fatal(err)
+			}
+			if len(userServices) == 0 {
+				break
+			}
+			after = last
+		}
+		for after := ""; ; {
+			userGroups, last, err := service.GetUserGroups(context.Background(), after, 100)
+			if err != nil {
+				b.Fatal(err)
+			}
+			if len(userGroups) == 0 {
+				break
+			}
+			after = last
+		}
+	}
+}
+
+// Benchmark one cycle of policies, user_services and user_groups with one query per user
+// This is how the extractor read user data before the bulk queries
+func BenchmarkExtractUserData_PerUser(b *testing.B) {
+	connection := newBenchConnection(b)
+	policyRepository := driver.PolicyRepositoryImpl{Connection: connection}
+	userRepository := driver.UserRepositoryImpl{Connection: connection}
+	serviceRepository := driver.ServiceRepositoryImpl{Connection: connection}
+	groupRepository := driver.GroupRepositoryImpl{Connection: connection}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for after := ""; ; {
+			userUuids, err := userRepository.FindUserUuidsOfUserGroupsAfter(context.Background(), after, 100)
+			if err != nil {
+				b.Fatal(err)
+			}
+			if len(userUuids) == 0 {
+				break
+			}
+			for _, userUuid := range userUuids {
+				if _, err := policyRepository.FindPolicyOfUserGroupByUserUuids(context.Background(), []string{userUuid}); err != nil {
+					b.Fatal(err)
+				}
+				if _, _, err := groupRepository.FindByUserUuid(context.Background(), userUuid, driver.Page{}); err != nil {
+					b.Fatal(err)
+				}
+			}
+			after = userUuids[len(userUuids)-1]
+		}
+		for after := ""; ; {
+			userUuids, err := userRepository.FindUserUuidsOfUserServicesAfter(context.Background(), after, 100)
+			if err != nil {
+				b.Fatal(err)
+			}
+			if len(userUuids) == 0 {
+				break
+			}
+			for _, userUuid := range userUuids {
+				if _, _, err := serviceRepository.FindServicesByUserUuid(context.Background(), userUuid, driver.Page{}); err != nil {
+					b.Fatal(err)
+				}
+			}
+			after = userUuids[len(userUuids)-1]
+		}
+	}
+}
+
+// Test get user data of users with seeded database
+func TestGetByUserUuids_Seeded(t *testing.T) {
+	service := newSeededExtractorService(t)
+
+	policies, err := service.GetPoliciesByUserUuids(context.Background(), []string{seedUser2, seedUser3})
+	expectedPolicies := map[string][]structure.UserPolicy{
+		seedUser2: {{ServiceUuid: seedService1, GroupUuid: seedGroup1, RoleName: "user", PermissionName: "read"}},
+		seedUser3: {},
+	}
+	if err != nil || !reflect.DeepEqual(policies, expectedPolicies) {
+		t.Errorf("Incorrect TestGetByUserUuids_Seeded test. policies = %v, err = %v", policies, err)
+		t.FailNow()
+	}
+
+	userServices, err := service.GetUserServicesByUserUuids(context.Background(), []string{seedUser3})
+	expectedUserServices := map[string][]structure.UserService{
+		seedUser3: {{ServiceUUid: seedService2, ServiceName: "service2"}},
+	}
+	if err != nil || !reflect.DeepEqual(userServices, expectedUserServices) {
+		t.Errorf("Incorrect TestGetByUserUuids_Seeded test. user_services = %v, err = %v", userServices, err)
+		t.FailNow()
+	}
+
+	userGroups, err := service.GetUserGroupsByUserUuids(context.Background(), []string{seedUser3})
+	if err != nil || !reflect.DeepEqual(userGroups, map[string][]structure.UserGroup{seedUser3: {}}) {
+		t.Errorf("Incorrect TestGetByUserUuids_Seeded test. user_groups = %v, err = %v", userGroups, err)
+		t.FailNow()
+	}
+}
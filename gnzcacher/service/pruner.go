@@ -0,0 +1,153 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/tomoyane/grant-n-z/gnz/cache"
+	"github.com/tomoyane/grant-n-z/gnz/common"
+	"github.com/tomoyane/grant-n-z/gnz/log"
+)
+
+// Prune mode in grant_n_z_cacher.yaml
+const (
+	PruneModeDelete = "delete"
+	PruneModeDryRun = "dry-run"
+	PruneModeNone   = "none"
+)
+
+var psInstance PrunerService
+
+type PrunerService interface {
+	// Delete keys of prefix that are not in liveIds
+	// liveIds are the ids after prefix, that were extracted from database in this cycle
+	// Returns the number of orphan keys
+	Prune(ctx context.Context, prefix string, liveIds map[string]bool) int
+
+	// Delete keys of prefix in shard that are not in liveIds
+	// Metrics of shard are counted by prefix with shard. ex: `user_policy=a`
+	PruneShard(ctx context.Context, prefix string, shard string, liveIds map[string]bool) int
+
+	// Get pruned key counts
+	GetMetrics() PruneMetrics
+}
+
+// Pruned key counts by key prefix
+type PruneMetrics struct {
+	Mode string `json:"mode"`
+
+	// Total deleted keys since start
+	Pruned map[string]int64 `json:"pruned"`
+
+	// Orphan keys found in the last cycle. In dry-run mode, these keys were not deleted
+	LastOrphans map[string]int `json:"last_orphans"`
+}
+
+type PrunerServiceImpl struct {
+	EtcdClient cache.EtcdClient
+	Mode       string
+	metrics    *pruneMetrics
+}
+
+type pruneMetrics struct {
+	mutex       sync.Mutex
+	pruned      map[string]int64
+	lastOrphans map[string]int
+}
+
+// Get PrunerService instance
+// The instance is shared, so that metrics of pruning can be read from other components
+func GetPrunerServiceInstance() PrunerService {
+	if psInstance == nil {
+		psInstance = NewPrunerService()
+	}
+	return psInstance
+}
+
+func NewPrunerService() PrunerService {
+	return NewPrunerServiceWithMode(cache.NewEtcdClient(), common.GCacher.PruneMode)
+}
+
+// Constructor with prune mode
+// If mode is empty, orphan keys are deleted
+func NewPrunerServiceWithMode(etcdClient cache.EtcdClient, mode string) PrunerService {
+	if mode == "" {
+		mode = PruneModeDelete
+	}
+	return PrunerServiceImpl{
+		EtcdClient: etcdClient,
+		Mode:       mode,
+		metrics: &pruneMetrics{
+			pruned:      make(map[string]int64),
+			lastOrphans: make(map[string]int),
+		},
+	}
+}
+
+func (ps PrunerServiceImpl) Prune(ctx context.Context, prefix string, liveIds map[string]bool) int {
+	return ps.prune(ctx, prefix, prefix, liveIds)
+}
+
+func (ps PrunerServiceImpl) PruneShard(ctx context.Context, prefix string, shard string, liveIds map[string]bool) int {
+	return ps.prune(ctx, prefix, prefix+shard, liveIds)
+}
+
+// Delete keys that start with keyPrefix and are not in liveIds
+// Ids are the keys without prefix
+func (ps PrunerServiceImpl) prune(ctx context.Context, prefix string, keyPrefix string, liveIds map[string]bool) int {
+	if strings.EqualFold(ps.Mode, PruneModeNone) {
+		return 0
+	}
+
+	keys, err := ps.EtcdClient.GetKeys(ctx, keyPrefix)
+	if err != nil {
+		log.Logger.Warn(fmt.Sprintf("Skip pruning. Could not get keys. prefix = %s", keyPrefix))
+		return 0
+	}
+
+	var orphans []string
+	for _, key := range keys {
+		if !liveIds[strings.TrimPrefix(key, prefix)] {
+			orphans = append(orphans, key)
+		}
+	}
+
+	dryRun := strings.EqualFold(ps.Mode, PruneModeDryRun)
+	if dryRun {
+		for _, key := range orphans {
+			log.Logger.Info(fmt.Sprintf("Dry run. Orphan key = %s", key))
+		}
+	} else if len(orphans) > 0 {
+		ps.EtcdClient.DeleteKeys(ctx, orphans)
+	}
+
+	ps.metrics.mutex.Lock()
+	ps.metrics.lastOrphans[keyPrefix] = len(orphans)
+	if !dryRun {
+		ps.metrics.pruned[keyPrefix] += int64(len(orphans))
+	}
+	ps.metrics.mutex.Unlock()
+
+	log.Logger.Info(fmt.Sprintf("Prune %s length = %d. mode = %s", keyPrefix, len(orphans), ps.Mode))
+	return len(orphans)
+}
+
+func (ps PrunerServiceImpl) GetMetrics() PruneMetrics {
+	ps.metrics.mutex.Lock()
+	defer ps.metrics.mutex.Unlock()
+
+	metrics := PruneMetrics{
+		Mode:        ps.Mode,
+		Pruned:      make(map[string]int64),
+		LastOrphans: make(map[string]int),
+	}
+	for prefix, cnt := range ps.metrics.pruned {
+		metrics.Pruned[prefix] = cnt
+	}
+	for prefix, cnt := range ps.metrics.lastOrphans {
+		metrics.LastOrphans[prefix] = cnt
+	}
+	return metrics
+}
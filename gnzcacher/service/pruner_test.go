@@ -0,0 +1,139 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/tomoyane/grant-n-z/gnz/cache"
+)
+
+// Test prune delete mode
+func TestPrune_Delete(t *testing.T) {
+	etcdClient := newStubKeysEtcdClient("user_policy=a", "user_policy=b", "user_policy=c", "user_group=a")
+	prunerService := NewPrunerServiceWithMode(etcdClient, PruneModeDelete)
+
+	cnt := prunerService.Prune(context.Background(), cache.UserPolicyKeyPrefix, map[string]bool{"a": true})
+	if cnt != 2 {
+		t.Errorf("Incorrect TestPrune_Delete test. cnt = %d", cnt)
+		t.FailNow()
+	}
+
+	if !etcdClient.has("user_policy=a") || etcdClient.has("user_policy=b") || etcdClient.has("user_policy=c") || !etcdClient.has("user_group=a") {
+		t.Errorf("Incorrect TestPrune_Delete test. keys = %v", etcdClient.keys)
+		t.FailNow()
+	}
+
+	metrics := prunerService.GetMetrics()
+	if metrics.Pruned[cache.UserPolicyKeyPrefix] != 2 || metrics.LastOrphans[cache.UserPolicyKeyPrefix] != 2 {
+		t.Errorf("Incorrect TestPrune_Delete test. metrics = %v", metrics)
+		t.FailNow()
+	}
+}
+
+// Test prune keys of one shard
+func TestPruneShard(t *testing.T) {
+	etcdClient := newStubKeysEtcdClient("user_policy=a1", "user_policy=a2", "user_policy=b1")
+	prunerService := NewPrunerServiceWithMode(etcdClient, PruneModeDelete)
+
+	cnt := prunerService.PruneShard(context.Background(), cache.UserPolicyKeyPrefix, "a", map[string]bool{"a1": true})
+	if cnt != 1 {
+		t.Errorf("Incorrect TestPruneShard test. cnt = %d", cnt)
+		t.FailNow()
+	}
+
+	// Keys of other shards are not pruned even if they are not live in this shard
+	if !etcdClient.has("user_policy=a1") || etcdClient.has("user_policy=a2") || !etcdClient.has("user_policy=b1") {
+		t.Errorf("Incorrect TestPruneShard test. keys = %v", etcdClient.keys)
+		t.FailNow()
+	}
+
+	metrics := prunerService.GetMetrics()
+	if metrics.Pruned[cache.UserPolicyKeyPrefix+"a"] != 1 {
+		t.Errorf("Incorrect TestPruneShard test. metrics = %v", metrics)
+		t.FailNow()
+	}
+}
+
+// Test prune dry-run mode
+func TestPrune_DryRun(t *testing.T) {
+	etcdClient := newStubKeysEtcdClient("role=a", "role=b")
+	prunerService := NewPrunerServiceWithMode(etcdClient, PruneModeDryRun)
+
+	cnt := prunerService.Prune(context.Background(), cache.RoleKeyPrefix, map[string]bool{"a": true})
+	if cnt != 1 || !etcdClient.has("role=b") {
+		t.Errorf("Incorrect TestPrune_DryRun test. cnt = %d", cnt)
+		t.FailNow()
+	}
+
+	metrics := prunerService.GetMetrics()
+	if metrics.Pruned[cache.RoleKeyPrefix] != 0 || metrics.LastOrphans[cache.RoleKeyPrefix] != 1 {
+		t.Errorf("Incorrect TestPrune_DryRun test. metrics = %v", metrics)
+		t.FailNow()
+	}
+}
+
+// Test prune none mode
+func TestPrune_None(t *testing.T) {
+	etcdClient := newStubKeysEtcdClient("service=a")
+	prunerService := NewPrunerServiceWithMode(etcdClient, PruneModeNone)
+
+	cnt := prunerService.Prune(context.Background(), cache.ServiceKeyPrefix, map[string]bool{})
+	if cnt != 0 || !etcdClient.has("service=a") {
+		t.Errorf("Incorrect TestPrune_None test. cnt = %d", cnt)
+		t.FailNow()
+	}
+}
+
+// Test prune when keys can not be listed
+func TestPrune_GetKeysError(t *testing.T) {
+	etcdClient := newStubKeysEtcdClient("permission=a")
+	etcdClient.err = errors.New("failed")
+	prunerService := NewPrunerServiceWithMode(etcdClient, PruneModeDelete)
+
+	cnt := prunerService.Prune(context.Background(), cache.PermissionKeyPrefix, map[string]bool{})
+	if cnt != 0 || !etcdClient.has("permission=a") {
+		t.Errorf("Incorrect TestPrune_GetKeysError test. cnt = %d", cnt)
+		t.FailNow()
+	}
+}
+
+// Less than stub struct
+// Etcd client that only holds keys
+type stubKeysEtcdClient struct {
+	cache.EtcdClient
+	keys map[string]bool
+	err  error
+}
+
+func newStubKeysEtcdClient(keys ...string) *stubKeysEtcdClient {
+	e := &stubKeysEtcdClient{keys: make(map[string]bool)}
+	for _, key := range keys {
+		e.keys[key] = true
+	}
+	return e
+}
+
+func (e *stubKeysEtcdClient) has(key string) bool {
+	return e.keys[key]
+}
+
+func (e *stubKeysEtcdClient) GetKeys(ctx context.Context, prefix string) ([]string, error) {
+	if e.err != nil {
+		return nil, e.err
+	}
+	var keys []string
+	for key := range e.keys {
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+	return keys, nil
+}
+
+func (e *stubKeysEtcdClient) DeleteKeys(ctx context.Context, keys []string) {
+	for _, key := range keys {
+		delete(e.keys, key)
+	}
+}
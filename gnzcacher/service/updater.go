@@ -1,6 +1,7 @@
 package service
 
 import (
+	"context"
 	"time"
 
 	"github.com/tomoyane/grant-n-z/gnz/cache"
@@ -10,24 +11,30 @@ import (
 // 10 minute cache expires
 const expiresMinutes = 600 * time.Second
 
+// Updates are written with etcd transaction of batch size keys
+// Each method returns the batches that failed
 type UpdaterService interface {
 	// Update policy cache
-	UpdatePolicy(policyMap map[string][]structure.UserPolicy)
+	UpdatePolicy(ctx context.Context, policyMap map[string][]structure.UserPolicy) []cache.FailedBatch
 
 	// Update permission cache
-	UpdatePermission(permissions []structure.Permission)
+	UpdatePermission(ctx context.Context, permissions []structure.Permission) []cache.FailedBatch
 
 	// Update role cache
-	UpdateRole(roles []structure.Role)
+	UpdateRole(ctx context.Context, roles []structure.Role) []cache.FailedBatch
 
 	// Update service cache
-	UpdateService(services []structure.Service)
+	UpdateService(ctx context.Context, services []structure.Service) []cache.FailedBatch
 
 	// Update user_service cache
-	UpdateUserService(serviceMap map[string][]structure.UserService)
+	UpdateUserService(ctx context.Context, serviceMap map[string][]structure.UserService) []cache.FailedBatch
 
 	// Update user_group cache
-	UpdateUserGroup(groupMap map[string][]structure.UserGroup)
+	UpdateUserGroup(ctx context.Context, groupMap map[string][]structure.UserGroup) []cache.FailedBatch
+
+	// Update failed batches again
+	// It returns the batches that failed again
+	Retry(ctx context.Context, failed []cache.FailedBatch) []cache.FailedBatch
 }
 
 type UpdaterServiceImpl struct {
@@ -38,38 +45,54 @@ func NewUpdaterService() UpdaterService {
 	return UpdaterServiceImpl{EtcdClient: cache.NewEtcdClient()}
 }
 
-func (us UpdaterServiceImpl) UpdatePolicy(policyMap map[string][]structure.UserPolicy) {
+func (us UpdaterServiceImpl) UpdatePolicy(ctx context.Context, policyMap map[string][]structure.UserPolicy) []cache.FailedBatch {
+	values := make([]cache.KeyValue, 0, len(policyMap))
 	for key, value := range policyMap {
-		us.EtcdClient.SetUserPolicy(key, value)
+		values = append(values, cache.KeyValue{Key: cache.UserPolicyKeyPrefix + key, Value: value})
 	}
+	return us.EtcdClient.SetBatch(ctx, values)
 }
 
-func (us UpdaterServiceImpl) UpdatePermission(permissions []structure.Permission) {
+func (us UpdaterServiceImpl) UpdatePermission(ctx context.Context, permissions []structure.Permission) []cache.FailedBatch {
+	values := make([]cache.KeyValue, 0, len(permissions))
 	for _, permission := range permissions {
-		us.EtcdClient.SetPermission(permission.Uuid, permission)
+		values = append(values, cache.KeyValue{Key: cache.PermissionKeyPrefix + permission.Uuid, Value: permission})
 	}
+	return us.EtcdClient.SetBatch(ctx, values)
 }
 
-func (us UpdaterServiceImpl) UpdateRole(roles []structure.Role) {
+func (us UpdaterServiceImpl) UpdateRole(ctx context.Context, roles []structure.Role) []cache.FailedBatch {
+	values := make([]cache.KeyValue, 0, len(roles))
 	for _, role := range roles {
-		us.EtcdClient.SetRole(role.Uuid, role)
+		values = append(values, cache.KeyValue{Key: cache.RoleKeyPrefix + role.Uuid, Value: role})
 	}
+	return us.EtcdClient.SetBatch(ctx, values)
 }
 
-func (us UpdaterServiceImpl) UpdateService(services []structure.Service) {
+func (us UpdaterServiceImpl) UpdateService(ctx context.Context, services []structure.Service) []cache.FailedBatch {
+	values := make([]cache.KeyValue, 0, len(services))
 	for _, service := range services {
-		us.EtcdClient.SetService(service.Uuid, service)
+		values = append(values, cache.KeyValue{Key: cache.ServiceKeyPrefix + service.Uuid, Value: service})
 	}
+	return us.EtcdClient.SetBatch(ctx, values)
 }
 
-func (us UpdaterServiceImpl) UpdateUserService(serviceMap map[string][]structure.UserService) {
+func (us UpdaterServiceImpl) UpdateUserService(ctx context.Context, serviceMap map[string][]structure.UserService) []cache.FailedBatch {
+	values := make([]cache.KeyValue, 0, len(serviceMap))
 	for key, value := range serviceMap {
-		us.EtcdClient.SetUserService(key, value)
+		values = append(values, cache.KeyValue{Key: cache.UserServiceKeyPrefix + key, Value: value})
 	}
+	return us.EtcdClient.SetBatch(ctx, values)
 }
 
-func (us UpdaterServiceImpl) UpdateUserGroup(policyMap map[string][]structure.UserGroup) {
-	for key, value := range policyMap {
-		us.EtcdClient.SetUserGroup(key, value)
+func (us UpdaterServiceImpl) UpdateUserGroup(ctx context.Context, groupMap map[string][]structure.UserGroup) []cache.FailedBatch {
+	values := make([]cache.KeyValue, 0, len(groupMap))
+	for key, value := range groupMap {
+		values = append(values, cache.KeyValue{Key: cache.UserGroupKeyPrefix + key, Value: value})
 	}
+	return us.EtcdClient.SetBatch(ctx, values)
+}
+
+func (us UpdaterServiceImpl) Retry(ctx context.Context, failed []cache.FailedBatch) []cache.FailedBatch {
+	return us.EtcdClient.SetBatch(ctx, cache.RetryValues(failed))
 }
@@ -1,6 +1,7 @@
 package service
 
 import (
+	"context"
 	"testing"
 	"time"
 
@@ -40,40 +41,59 @@ func TestNewUpdaterService(t *testing.T) {
 func TestUpdatePolicy(t *testing.T) {
 	policies := make(map[string][]structure.UserPolicy)
 	policies["test"] = []structure.UserPolicy{{GroupUuid: uuid.New().String()}}
-	updaterService.UpdatePolicy(policies)
+	updaterService.UpdatePolicy(context.Background(), policies)
 }
 
 // Test update permission
 func TestUpdatePermission(t *testing.T) {
 	var permissions []structure.Permission
 	permissions = []structure.Permission{{Name: "test"}}
-	updaterService.UpdatePermission(permissions)
+	updaterService.UpdatePermission(context.Background(), permissions)
 }
 
 // Test update role
 func TestUpdateRole(t *testing.T) {
 	var roles []structure.Role
 	roles = []structure.Role{{Name: "test"}}
-	updaterService.UpdateRole(roles)
+	updaterService.UpdateRole(context.Background(), roles)
 }
 
 // Test update service
 func TestUpdateService(t *testing.T) {
 	var services []structure.Service
 	services = []structure.Service{{Name: "test"}}
-	updaterService.UpdateService(services)
+	updaterService.UpdateService(context.Background(), services)
 }
 
 // Test update user service
 func TestUpdateUserService(t *testing.T) {
 	services := make(map[string][]structure.UserService)
 	services["test"] = []structure.UserService{{ServiceName: "service"}}
-	updaterService.UpdateUserService(services)
+	updaterService.UpdateUserService(context.Background(), services)
 }
 
 // Test update user group
 func TestUpdateUserGroup(t *testing.T) {
 	groups := make(map[string][]structure.UserGroup)
 	groups["test"] = []structure.UserGroup{{GroupName: "group"}}
-	updaterService.UpdateUserGroup(groups)
+	updaterService.UpdateUserGroup(context.Background(), groups)
+}
+
+// Test update that etcd can not write
+func TestUpdatePermission_Failed(t *testing.T) {
+	etcdClient := cache.EtcdClientImpl{Connection: stubEtcdConnection, Timeout: 10 * time.Millisecond, BatchSize: 2}
+	updaterService := UpdaterServiceImpl{EtcdClient: etcdClient}
+	permissions := []structure.Permission{{Uuid: "a"}, {Uuid: "b"}, {Uuid: "c"}}
+
+	failed := updaterService.UpdatePermission(context.Background(), permissions)
+	if len(failed) != 2 || len(failed[0].Values) != 2 || len(failed[1].Values) != 1 || failed[0].Err == nil {
+		t.Errorf("Incorrect TestUpdatePermission_Failed test. failed = %v", failed)
+		t.FailNow()
+	}
+
+	failed = updaterService.Retry(context.Background(), failed)
+	if len(cache.RetryValues(failed)) != 3 {
+		t.Errorf("Incorrect TestUpdatePermission_Failed test. failed = %v", failed)
+		t.FailNow()
+	}
 }
@@ -0,0 +1,292 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/tomoyane/grant-n-z/gnz/cache"
+	"github.com/tomoyane/grant-n-z/gnz/driver"
+)
+
+// Page size of extract when verify
+const verifyLimit = 100
+
+// Verify cache by comparing it with values that are extracted from database
+// A key that is updated while verify is running can be reported, so run verify again to confirm drift
+type VerifierService interface {
+	// Compare cache of all prefixes with database
+	// If repair is true, missing and mismatched keys are updated and extra keys are deleted
+	Verify(ctx context.Context, repair bool) VerifyReport
+}
+
+// Report of verify
+type VerifyReport struct {
+	// Report by cache key prefix without `=`. ex: user_policy
+	Entities map[string]EntityReport `json:"entities"`
+
+	// Uuids of users that have missing, extra or mismatched key
+	Users []string `json:"users"`
+
+	// Number of repaired keys. It is 0 if repair is false
+	Repaired int `json:"repaired"`
+}
+
+// No drift and no error
+func (r VerifyReport) Consistent() bool {
+	for _, entity := range r.Entities {
+		if entity.Error != "" || len(entity.Missing) != 0 || len(entity.Extra) != 0 || len(entity.Mismatched) != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Report of one cache key prefix
+type EntityReport struct {
+	// Number of keys that are expected from database
+	Checked int `json:"checked"`
+
+	// Keys that are in database, but not in cache
+	Missing []string `json:"missing"`
+
+	// Keys that are in cache, but not in database
+	Extra []string `json:"extra"`
+
+	// Keys that have different data between cache and database
+	Mismatched []string `json:"mismatched"`
+
+	// Error of extract or etcd. The prefix was not verified if it is not empty
+	Error string `json:"error,omitempty"`
+}
+
+type VerifierServiceImpl struct {
+	ExtractorService ExtractorService
+	EtcdClient       cache.EtcdClient
+}
+
+// Page of extracted values by id. It returns the last user uuid of the page for user data
+type extractPageFunc func(ctx context.Context, afterUserUuid string, offset int) (map[string]interface{}, string, error)
+
+func NewVerifierService() VerifierService {
+	return VerifierServiceImpl{
+		ExtractorService: NewExtractorService(),
+		EtcdClient:       cache.NewEtcdClient(),
+	}
+}
+
+func (vs VerifierServiceImpl) Verify(ctx context.Context, repair bool) VerifyReport {
+	report := VerifyReport{Entities: make(map[string]EntityReport), Users: []string{}}
+	users := make(map[string]bool)
+	pages := vs.extractPages()
+	for _, prefix := range cache.KeyPrefixes {
+		entity := strings.TrimSuffix(prefix, "=")
+		// Replicas may not have applied recent writes, that are already in cache
+		expected, err := vs.extract(driver.WithPrimary(ctx), prefix, pages[prefix])
+		if err != nil {
+			report.Entities[entity] = EntityReport{Error: err.Error()}
+			continue
+		}
+		actual, err := vs.EtcdClient.GetValues(ctx, prefix)
+		if err != nil {
+			report.Entities[entity] = EntityReport{Error: err.Error()}
+			continue
+		}
+
+		entityReport := compareValues(expected, actual)
+		report.Entities[entity] = entityReport
+		if repair {
+			report.Repaired += vs.repair(ctx, expected, entityReport)
+		}
+		if isUserKeyPrefix(prefix) {
+			for _, keys := range [][]string{entityReport.Missing, entityReport.Extra, entityReport.Mismatched} {
+				for _, key := range keys {
+					users[strings.TrimPrefix(key, prefix)] = true
+				}
+			}
+		}
+	}
+
+	for userUuid := range users {
+		report.Users = append(report.Users, userUuid)
+	}
+	sort.Strings(report.Users)
+	return report
+}
+
+// Extract function of each prefix
+func (vs VerifierServiceImpl) extractPages() map[string]extractPageFunc {
+	return map[string]extractPageFunc{
+		cache.UserPolicyKeyPrefix: func(ctx context.Context, afterUserUuid string, offset int) (map[string]interface{}, string, error) {
+			policies, lastUserUuid, err := vs.ExtractorService.GetPolicies(ctx, afterUserUuid, verifyLimit)
+			values := make(map[string]interface{}, len(policies))
+			for userUuid, policy := range policies {
+				values[userUuid] = policy
+			}
+			return values, lastUserUuid, err
+		},
+		cache.UserServiceKeyPrefix: func(ctx context.Context, afterUserUuid string, offset int) (map[string]interface{}, string, error) {
+			userServices, lastUserUuid, err := vs.ExtractorService.GetUserServices(ctx, afterUserUuid, verifyLimit)
+			values := make(map[string]interface{}, len(userServices))
+			for userUuid, userService := range userServices {
+				values[userUuid] = userService
+			}
+			return values, lastUserUuid, err
+		},
+		cache.UserGroupKeyPrefix: func(ctx context.Context, afterUserUuid string, offset int) (map[string]interface{}, string, error) {
+			userGroups, lastUserUuid, err := vs.ExtractorService.GetUserGroups(ctx, afterUserUuid, verifyLimit)
+			values := make(map[string]interface{}, len(userGroups))
+			for userUuid, userGroup := range userGroups {
+				values[userUuid] = userGroup
+			}
+			return values, lastUserUuid, err
+		},
+		cache.PermissionKeyPrefix: func(ctx context.Context, afterUserUuid string, offset int) (map[string]interface{}, string, error) {
+			permissions, err := vs.ExtractorService.GetPermissions(ctx, offset, verifyLimit)
+			values := make(map[string]interface{}, len(permissions))
+			for _, permission := range permissions {
+				values[permission.Uuid] = permission
+			}
+			return values, "", err
+		},
+		cache.RoleKeyPrefix: func(ctx context.Context, afterUserUuid string, offset int) (map[string]interface{}, string, error) {
+			roles, err := vs.ExtractorService.GetRoles(ctx, offset, verifyLimit)
+			values := make(map[string]interface{}, len(roles))
+			for _, role := range roles {
+				values[role.Uuid] = role
+			}
+			return values, "", err
+		},
+		cache.ServiceKeyPrefix: func(ctx context.Context, afterUserUuid string, offset int) (map[string]interface{}, string, error) {
+			services, err := vs.ExtractorService.GetServices(ctx, offset, verifyLimit)
+			values := make(map[string]interface{}, len(services))
+			for _, service := range services {
+				values[service.Uuid] = service
+			}
+			return values, "", err
+		},
+	}
+}
+
+// Extract all expected values of prefix by cache key
+func (vs VerifierServiceImpl) extract(ctx context.Context, prefix string, page extractPageFunc) (map[string]interface{}, error) {
+	expected := make(map[string]interface{})
+	afterUserUuid := ""
+	for offset := 0; ; offset += verifyLimit {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		values, lastUserUuid, err := page(ctx, afterUserUuid, offset)
+		if err != nil {
+			return nil, err
+		}
+		if len(values) == 0 {
+			return expected, nil
+		}
+		for id, value := range values {
+			expected[prefix+id] = value
+		}
+		afterUserUuid = lastUserUuid
+	}
+}
+
+// Update missing and mismatched keys, and delete extra keys
+// It returns the number of repaired keys
+func (vs VerifierServiceImpl) repair(ctx context.Context, expected map[string]interface{}, report EntityReport) int {
+	var values []cache.KeyValue
+	for _, keys := range [][]string{report.Missing, report.Mismatched} {
+		for _, key := range keys {
+			values = append(values, cache.KeyValue{Key: key, Value: expected[key]})
+		}
+	}
+	failed := vs.EtcdClient.SetBatch(ctx, values)
+
+	if len(report.Extra) != 0 {
+		vs.EtcdClient.DeleteKeys(ctx, report.Extra)
+	}
+	return len(values) - len(cache.RetryValues(failed)) + len(report.Extra)
+}
+
+// Compare expected values with cache values
+// A key that has empty data is not extra, because gnzserver caches empty data of the user that has no data
+func compareValues(expected map[string]interface{}, actual map[string][]byte) EntityReport {
+	report := EntityReport{Checked: len(expected), Missing: []string{}, Extra: []string{}, Mismatched: []string{}}
+	for key, value := range expected {
+		cached, ok := actual[key]
+		if !ok {
+			report.Missing = append(report.Missing, key)
+		} else if !equalData(value, cached) {
+			report.Mismatched = append(report.Mismatched, key)
+		}
+	}
+	for key, cached := range actual {
+		if _, ok := expected[key]; !ok && !isEmptyData(cached) {
+			report.Extra = append(report.Extra, key)
+		}
+	}
+
+	sort.Strings(report.Missing)
+	sort.Strings(report.Extra)
+	sort.Strings(report.Mismatched)
+	return report
+}
+
+// Data of cache value equals to expected value
+// Order of list is ignored, because it depends on query of database
+func equalData(expected interface{}, cached []byte) bool {
+	data, err := cache.DecodeData(cached)
+	if err != nil {
+		return false
+	}
+	expectedJson, err := json.Marshal(expected)
+	if err != nil {
+		return false
+	}
+
+	var expectedData, cachedData interface{}
+	if json.Unmarshal(expectedJson, &expectedData) != nil || json.Unmarshal(data, &cachedData) != nil {
+		return false
+	}
+	return reflect.DeepEqual(normalize(expectedData), normalize(cachedData))
+}
+
+// Data of cache value is empty list or null
+func isEmptyData(cached []byte) bool {
+	data, err := cache.DecodeData(cached)
+	if err != nil {
+		return false
+	}
+	var list []interface{}
+	return json.Unmarshal(data, &list) == nil && len(list) == 0
+}
+
+// Sort lists of json data
+func normalize(data interface{}) interface{} {
+	switch value := data.(type) {
+	case []interface{}:
+		sorted := make([]interface{}, len(value))
+		for i, element := range value {
+			sorted[i] = normalize(element)
+		}
+		sort.Slice(sorted, func(i, j int) bool {
+			left, _ := json.Marshal(sorted[i])
+			right, _ := json.Marshal(sorted[j])
+			return string(left) < string(right)
+		})
+		return sorted
+	case map[string]interface{}:
+		for key, element := range value {
+			value[key] = normalize(element)
+		}
+		return value
+	default:
+		return value
+	}
+}
+
+// Prefix of key of user data
+func isUserKeyPrefix(prefix string) bool {
+	return prefix == cache.UserPolicyKeyPrefix || prefix == cache.UserServiceKeyPrefix || prefix == cache.UserGroupKeyPrefix
+}
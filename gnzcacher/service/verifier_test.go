@@ -0,0 +1,159 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/tomoyane/grant-n-z/gnz/cache"
+	"github.com/tomoyane/grant-n-z/gnz/cache/structure"
+	"github.com/tomoyane/grant-n-z/gnz/driver"
+)
+
+// Less than stub struct
+// Extractor service that returns one page
+// Policies of replica are stale, so that reading them fails
+type stubVerifyExtractorService struct {
+	ExtractorService
+	policies map[string][]structure.UserPolicy
+	roles    []structure.Role
+	err      error
+}
+
+func (es stubVerifyExtractorService) GetPolicies(ctx context.Context, afterUserUuid string, limit int) (map[string][]structure.UserPolicy, string, error) {
+	if !driver.ReadsPrimary(ctx) {
+		return nil, "", errors.New("stale replica")
+	}
+	if afterUserUuid != "" {
+		return map[string][]structure.UserPolicy{}, "", nil
+	}
+	return es.policies, "last", nil
+}
+
+func (es stubVerifyExtractorService) GetUserServices(ctx context.Context, afterUserUuid string, limit int) (map[string][]structure.UserService, string, error) {
+	return map[string][]structure.UserService{}, "", nil
+}
+
+func (es stubVerifyExtractorService) GetUserGroups(ctx context.Context, afterUserUuid string, limit int) (map[string][]structure.UserGroup, string, error) {
+	return map[string][]structure.UserGroup{}, "", es.err
+}
+
+func (es stubVerifyExtractorService) GetPermissions(ctx context.Context, offset int, limit int) ([]structure.Permission, error) {
+	return []structure.Permission{}, nil
+}
+
+func (es stubVerifyExtractorService) GetRoles(ctx context.Context, offset int, limit int) ([]structure.Role, error) {
+	if offset != 0 {
+		return []structure.Role{}, nil
+	}
+	return es.roles, nil
+}
+
+func (es stubVerifyExtractorService) GetServices(ctx context.Context, offset int, limit int) ([]structure.Service, error) {
+	return []structure.Service{}, nil
+}
+
+// Less than stub struct
+// Etcd client that holds values
+type stubValuesEtcdClient struct {
+	cache.EtcdClient
+	values  map[string][]byte
+	set     []string
+	deleted []string
+}
+
+func (e *stubValuesEtcdClient) GetValues(ctx context.Context, prefix string) (map[string][]byte, error) {
+	values := make(map[string][]byte)
+	for key, value := range e.values {
+		if len(key) >= len(prefix) && key[:len(prefix)] == prefix {
+			values[key] = value
+		}
+	}
+	return values, nil
+}
+
+func (e *stubValuesEtcdClient) SetBatch(ctx context.Context, values []cache.KeyValue) []cache.FailedBatch {
+	for _, kv := range values {
+		e.set = append(e.set, kv.Key)
+	}
+	return nil
+}
+
+func (e *stubValuesEtcdClient) DeleteKeys(ctx context.Context, keys []string) {
+	e.deleted = append(e.deleted, keys...)
+}
+
+// Versioned cache value of data
+func versioned(data interface{}) []byte {
+	value, _ := json.Marshal(data)
+	versionedValue, _ := json.Marshal(map[string]interface{}{"version": cache.SchemaVersion, "data": json.RawMessage(value)})
+	return versionedValue
+}
+
+// Test verify
+func TestVerify(t *testing.T) {
+	extractorService := stubVerifyExtractorService{
+		policies: map[string][]structure.UserPolicy{
+			"same":     {{RoleName: "a"}, {RoleName: "b"}},
+			"mismatch": {{RoleName: "a"}},
+			"missing":  {{RoleName: "a"}},
+		},
+		roles: []structure.Role{{Uuid: "a", Name: "admin"}},
+	}
+	etcdClient := &stubValuesEtcdClient{values: map[string][]byte{
+		cache.UserPolicyKeyPrefix + "same":     versioned([]structure.UserPolicy{{RoleName: "b"}, {RoleName: "a"}}),
+		cache.UserPolicyKeyPrefix + "mismatch": versioned([]structure.UserPolicy{{RoleName: "x"}}),
+		cache.UserPolicyKeyPrefix + "extra":    versioned([]structure.UserPolicy{{RoleName: "a"}}),
+		cache.UserPolicyKeyPrefix + "empty":    versioned([]structure.UserPolicy{}),
+		cache.RoleKeyPrefix + "a":              []byte(`{"uuid":"a","name":"admin"}`),
+	}}
+	verifierService := VerifierServiceImpl{ExtractorService: extractorService, EtcdClient: etcdClient}
+
+	report := verifierService.Verify(context.Background(), false)
+	policy := report.Entities["user_policy"]
+	if report.Consistent() || policy.Checked != 3 ||
+		len(policy.Missing) != 1 || policy.Missing[0] != cache.UserPolicyKeyPrefix+"missing" ||
+		len(policy.Mismatched) != 1 || policy.Mismatched[0] != cache.UserPolicyKeyPrefix+"mismatch" ||
+		len(policy.Extra) != 1 || policy.Extra[0] != cache.UserPolicyKeyPrefix+"extra" ||
+		len(report.Users) != 3 || report.Repaired != 0 || len(etcdClient.set) != 0 {
+		t.Errorf("Incorrect TestVerify test. report = %v", report)
+		t.FailNow()
+	}
+
+	role := report.Entities["role"]
+	if role.Checked != 1 || len(role.Missing) != 0 || len(role.Mismatched) != 0 || len(role.Extra) != 0 {
+		t.Errorf("Incorrect TestVerify test. role = %v", role)
+		t.FailNow()
+	}
+}
+
+// Test verify with repair
+func TestVerify_Repair(t *testing.T) {
+	extractorService := stubVerifyExtractorService{
+		policies: map[string][]structure.UserPolicy{"missing": {{RoleName: "a"}}},
+	}
+	etcdClient := &stubValuesEtcdClient{values: map[string][]byte{
+		cache.UserPolicyKeyPrefix + "extra": versioned([]structure.UserPolicy{{RoleName: "a"}}),
+	}}
+	verifierService := VerifierServiceImpl{ExtractorService: extractorService, EtcdClient: etcdClient}
+
+	report := verifierService.Verify(context.Background(), true)
+	if report.Repaired != 2 || len(etcdClient.set) != 1 || etcdClient.set[0] != cache.UserPolicyKeyPrefix+"missing" ||
+		len(etcdClient.deleted) != 1 || etcdClient.deleted[0] != cache.UserPolicyKeyPrefix+"extra" {
+		t.Errorf("Incorrect TestVerify_Repair test. report = %v", report)
+		t.FailNow()
+	}
+}
+
+// Test verify when extract failed
+func TestVerify_Error(t *testing.T) {
+	extractorService := stubVerifyExtractorService{err: errors.New("failed")}
+	verifierService := VerifierServiceImpl{ExtractorService: extractorService, EtcdClient: &stubValuesEtcdClient{}}
+
+	report := verifierService.Verify(context.Background(), true)
+	if report.Consistent() || report.Entities["user_group"].Error != "failed" || report.Entities["role"].Error != "" {
+		t.Errorf("Incorrect TestVerify_Error test. report = %v", report)
+		t.FailNow()
+	}
+}
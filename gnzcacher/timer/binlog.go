@@ -0,0 +1,471 @@
+package timer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/tomoyane/grant-n-z/gnz/binlog"
+	"github.com/tomoyane/grant-n-z/gnz/cache"
+	"github.com/tomoyane/grant-n-z/gnz/common"
+	"github.com/tomoyane/grant-n-z/gnz/driver"
+	"github.com/tomoyane/grant-n-z/gnz/log"
+)
+
+// Name of checkpoint of binlog position in etcd, and name of leader that follows binlog
+const (
+	binlogCheckpoint = "binlog"
+	binlogLeader     = "binlog"
+)
+
+const (
+	// Server id derived from host name when cacher.binlog-server-id is not set is not less than this
+	// Smaller ids are left for MySQL servers
+	minBinlogServerId = 1000000
+
+	// Replica that is not leader campaigns in this interval, and leader checks that it still is in this interval
+	// Writes of the old leader are rejected by etcd even before it notices
+	leaderInterval = 3 * time.Second
+
+	binlogHeartbeatPeriod = 30 * time.Second
+	binlogDialTimeout     = 10 * time.Second
+
+	// Checkpoint is saved at least in this interval, even if no transaction changed cache
+	checkpointInterval = 10 * time.Second
+
+	// Wait before following binlog again after failure. It doubles up to maxBinlogBackoff
+	minBinlogBackoff = 1 * time.Second
+	maxBinlogBackoff = 1 * time.Minute
+)
+
+// Stream of binlog events
+type EventStream interface {
+	// Read next event. It blocks until an event arrives
+	Next() (binlog.Event, error)
+
+	// Close stream. Next that is waiting returns error
+	Close() error
+}
+
+// Open stream of binlog events from position
+type StreamOpener func(position binlog.Position) (EventStream, error)
+
+// UpdateTimer that follows binlog of MySQL
+// Cache is updated for each transaction that changed the tables of ChangeTables, and the position is saved as checkpoint in etcd
+// When there is no checkpoint or the binlog of checkpoint was purged, all entities are updated from the current position
+// Only the leader of replicas follows binlog and saves checkpoint. The others wait until the leader stopped
+// MySQL needs binlog_format = ROW and binlog_row_image = FULL
+type BinlogUpdateTimerImpl struct {
+	UpdateTimerImpl
+	BinlogRepository driver.BinlogRepository
+	EtcdClient       cache.EtcdClient
+	Membership       cache.Membership
+	Open             StreamOpener
+
+	// Database name of GrantNZ tables
+	Schema string
+}
+
+// State of binlog while following it
+type binlogState struct {
+	// Position after the last transaction
+	position binlog.Position
+
+	// Changes of the current transaction
+	changes *Changes
+
+	// Column names by table
+	columns map[string][]string
+
+	savedAt time.Time
+	applied bool
+}
+
+// Result of following binlog
+type followResult struct {
+	stopped bool
+	code    int
+	applied bool
+	err     error
+}
+
+// Event or error of stream
+type streamEvent struct {
+	event binlog.Event
+	err   error
+}
+
+// Runner that reads primary
+// Checkpoint is the position of primary, and replicas may not have applied the transactions before it yet
+type primaryRunner struct {
+	Runner
+}
+
+// Error of following binlog after another replica became the leader
+var errNotLeader = errors.New("Not leader of binlog follower")
+
+// Constructor
+// It connects to the first host of db config as replica
+func NewBinlogUpdateTimer() UpdateTimer {
+	config := binlog.Config{
+		Addr:            net.JoinHostPort(strings.Split(common.Db.Hosts, ",")[0], common.Db.Port),
+		User:            common.Db.User,
+		Password:        common.Db.Password,
+		ServerId:        binlogServerId(common.GCacher.BinlogServerId),
+		HeartbeatPeriod: binlogHeartbeatPeriod,
+		DialTimeout:     binlogDialTimeout,
+	}
+	return NewBinlogUpdateTimerWithConfig(common.GCacher, NewClock(), NewRunner(), OpenBinlogStream(config, common.Db.Name), common.Db.Name)
+}
+
+// Constructor with cacher config
+// Timeout of each transaction and full sync is the same as poll mode
+func NewBinlogUpdateTimerWithConfig(cacherConfig common.CacherConfig, clock Clock, runner Runner, open StreamOpener, schema string) BinlogUpdateTimerImpl {
+	updateTimer := NewUpdateTimerWithConfig(cacherConfig, clock, primaryRunner{runner})
+	updateTimer.status = newStatusHolder(ModeBinlog)
+	updateTimer.status.setLeader(false)
+	return BinlogUpdateTimerImpl{
+		UpdateTimerImpl:  updateTimer,
+		BinlogRepository: driver.GetBinlogRepositoryInstance(),
+		EtcdClient:       cache.NewEtcdClient(),
+		Membership:       cache.GetMembershipInstance(),
+		Open:             open,
+		Schema:           schema,
+	}
+}
+
+// Server id of this replica
+// If cacher.binlog-server-id is not set, it is derived from host name, so that replicas do not disconnect each other
+func binlogServerId(serverId int) uint32 {
+	if serverId > 0 {
+		return uint32(serverId)
+	}
+	hostname, _ := os.Hostname()
+	hash := fnv.New32a()
+	hash.Write([]byte(hostname))
+	return minBinlogServerId + hash.Sum32()%(math.MaxUint32-minBinlogServerId)
+}
+
+func (r primaryRunner) Run(ctx context.Context) RunResult {
+	return r.Runner.Run(driver.WithPrimary(ctx))
+}
+
+func (r primaryRunner) RunEntity(ctx context.Context, entity string) RunResult {
+	return r.Runner.RunEntity(driver.WithPrimary(ctx), entity)
+}
+
+func (r primaryRunner) RunUser(ctx context.Context, userUuid string) RunResult {
+	return r.Runner.RunUser(driver.WithPrimary(ctx), userUuid)
+}
+
+func (r primaryRunner) RunChanges(ctx context.Context, changes *Changes) RunResult {
+	return r.Runner.RunChanges(driver.WithPrimary(ctx), changes)
+}
+
+// Open binlog stream of MySQL that parses events of ChangeTables in schema
+func OpenBinlogStream(config binlog.Config, schema string) StreamOpener {
+	return func(position binlog.Position) (EventStream, error) {
+		conn, err := binlog.Dial(config)
+		if err != nil {
+			return nil, err
+		}
+		if err := conn.StartDump(position); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		parser := binlog.NewParser(schema, ChangeTables)
+		parser.DumpChecksum = conn.Checksum()
+		return binlogStream{conn: conn, parser: parser}, nil
+	}
+}
+
+// Stream of binlog dump connection
+type binlogStream struct {
+	conn   *binlog.Conn
+	parser *binlog.Parser
+}
+
+func (s binlogStream) Next() (binlog.Event, error) {
+	data, err := s.conn.ReadEvent()
+	if err != nil {
+		return binlog.Event{}, err
+	}
+	return s.parser.Parse(data)
+}
+
+func (s binlogStream) Close() error {
+	return s.conn.Close()
+}
+
+func (ut BinlogUpdateTimerImpl) Start(exitCode chan int) int {
+	log.Logger.Info(fmt.Sprintf("Start update cache by binlog. schema = %s, timeout = %v", ut.Schema, ut.Timeout))
+
+	backoff := minBinlogBackoff
+	for {
+		if !ut.campaign() {
+			if stopped, code := ut.wait(leaderInterval, exitCode); stopped {
+				return code
+			}
+			continue
+		}
+
+		// MySQL returns error 1236 also when it can not read binlog for other reasons than purge,
+		// so that full sync runs only when startPosition does not find binlog of checkpoint
+		position, err := ut.startPosition()
+		if err == nil {
+			result := ut.follow(position, exitCode)
+			if result.stopped {
+				log.Logger.Info("Stopped update cache process")
+				return result.code
+			}
+			if result.applied {
+				backoff = minBinlogBackoff
+			}
+			if result.err == errNotLeader {
+				continue
+			}
+			err = result.err
+		}
+
+		log.Logger.Error(fmt.Sprintf("Failed to follow binlog. Retry after %v. err = %s", backoff, err.Error()))
+		if stopped, code := ut.wait(backoff, exitCode); stopped {
+			return code
+		}
+		backoff *= 2
+		if backoff > maxBinlogBackoff {
+			backoff = maxBinlogBackoff
+		}
+	}
+}
+
+// Wait for d while running resync requests
+// It returns true with exit code if timer stopped
+func (ut BinlogUpdateTimerImpl) wait(d time.Duration, exitCode chan int) (bool, int) {
+	timeout := ut.Clock.After(d)
+	for {
+		select {
+		case <-timeout:
+			return false, 0
+		case request := <-ut.resync:
+			ut.runResync(request)
+		case <-ut.stop:
+			log.Logger.Info("Stop update cache loop")
+			return true, 0
+		case c := <-exitCode:
+			log.Logger.Info("Break update cache loop")
+			return true, c
+		}
+	}
+}
+
+// Campaign for leader of binlog follower, and set the result to status
+func (ut BinlogUpdateTimerImpl) campaign() bool {
+	ctx, cancel := context.WithTimeout(context.Background(), ut.Timeout)
+	defer cancel()
+
+	err := ut.Membership.Join(ctx)
+	leader := false
+	if err == nil {
+		leader, err = ut.Membership.Campaign(ctx, binlogLeader)
+	}
+	if err != nil {
+		log.Logger.Error(fmt.Sprintf("Failed to campaign for leader of binlog follower. err = %s", err.Error()))
+	}
+	if ut.status.setLeader(leader) {
+		log.Logger.Info(fmt.Sprintf("Leader of binlog follower changed. leader = %v, member_id = %s", leader, ut.Membership.Id()))
+	}
+	return leader
+}
+
+// Position to follow binlog from
+// It is the checkpoint if its binlog still exists. Otherwise all entities are updated, and the position before it is used
+func (ut BinlogUpdateTimerImpl) startPosition() (binlog.Position, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), ut.Timeout)
+	defer cancel()
+
+	var position binlog.Position
+	found, err := ut.EtcdClient.GetCheckpoint(ctx, binlogCheckpoint, &position)
+	if err != nil {
+		return position, err
+	}
+	if found {
+		names, err := ut.BinlogRepository.FindBinaryLogs(ctx)
+		if err != nil {
+			return position, err
+		}
+		for _, name := range names {
+			if name == position.Name {
+				return position, nil
+			}
+		}
+		log.Logger.Warn(fmt.Sprintf("Binlog of checkpoint was purged. Run full sync. checkpoint = %s", position.String()))
+	}
+
+	// Changes while full sync are applied again from the position
+	position, err = ut.BinlogRepository.FindMasterStatus(ctx)
+	if err != nil {
+		return position, err
+	}
+	result := ut.runCycle("all", func(ctx context.Context) RunResult {
+		return ut.Runner.Run(ut.Membership.Lead(ctx, binlogLeader))
+	})
+	if len(result.Errors) != 0 {
+		return position, errors.New(fmt.Sprintf("Failed to run full sync. errors = %v", result.Errors))
+	}
+
+	// Full sync may take the timeout, so that checkpoint is saved with new ctx
+	saveCtx, saveCancel := context.WithTimeout(context.Background(), ut.Timeout)
+	defer saveCancel()
+	if err := ut.EtcdClient.SetCheckpoint(ut.Membership.Lead(saveCtx, binlogLeader), binlogCheckpoint, position); err != nil {
+		return position, err
+	}
+	ut.status.setBinlogPosition(position.String())
+	return position, nil
+}
+
+// Follow binlog from position until stream failed, another replica became the leader or timer stopped
+// Resync requests run between events
+func (ut BinlogUpdateTimerImpl) follow(position binlog.Position, exitCode chan int) followResult {
+	stream, err := ut.Open(position)
+	if err != nil {
+		return followResult{err: err}
+	}
+	defer stream.Close()
+	log.Logger.Info(fmt.Sprintf("Follow binlog from %s", position.String()))
+
+	events := make(chan streamEvent)
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		for {
+			event, err := stream.Next()
+			select {
+			case events <- streamEvent{event: event, err: err}:
+			case <-done:
+				return
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+
+	state := &binlogState{
+		position: position,
+		changes:  NewChanges(),
+		columns:  make(map[string][]string),
+		savedAt:  ut.Clock.Now(),
+	}
+	check := ut.Clock.After(leaderInterval)
+	for {
+		select {
+		case <-check:
+			if !ut.campaign() {
+				log.Logger.Warn(fmt.Sprintf("Stop following binlog of non leader. position = %s", state.position.String()))
+				return followResult{applied: state.applied, err: errNotLeader}
+			}
+			check = ut.Clock.After(leaderInterval)
+		case e := <-events:
+			if e.err == nil {
+				e.err = ut.handle(state, e.event)
+			}
+			if e.err != nil {
+				return followResult{applied: state.applied, err: e.err}
+			}
+		case request := <-ut.resync:
+			ut.runResync(request)
+		case <-ut.stop:
+			log.Logger.Info("Stop update cache loop")
+			return followResult{stopped: true}
+		case c := <-exitCode:
+			log.Logger.Info("Break update cache loop")
+			return followResult{stopped: true, code: c}
+		}
+	}
+}
+
+// Handle one event
+// Changes of rows are applied at commit of the transaction
+func (ut BinlogUpdateTimerImpl) handle(state *binlogState, event binlog.Event) error {
+	switch body := event.Body.(type) {
+	case *binlog.Rotate:
+		state.position = body.Next
+		if event.Header.LogPos != 0 {
+			// Rotate at the end of binlog file
+			return ut.saveCheckpoint(state, true)
+		}
+
+	case *binlog.Rows:
+		columns, err := ut.columnNames(state, body.Table)
+		if err != nil {
+			return err
+		}
+		state.changes.Add(body, columns)
+
+	case *binlog.Xid:
+		state.position.Pos = event.Header.LogPos
+		state.applied = true
+		if state.changes.Empty() {
+			return ut.saveCheckpoint(state, false)
+		}
+
+		changes := state.changes
+		state.changes = NewChanges()
+		result := ut.runCycle("binlog="+state.position.String(), func(ctx context.Context) RunResult {
+			return ut.Runner.RunChanges(ut.Membership.Lead(ctx, binlogLeader), changes)
+		})
+		if len(result.Errors) != 0 {
+			// The transaction is applied again from the last checkpoint
+			return errors.New(fmt.Sprintf("Failed to update cache by binlog. position = %s, errors = %v", state.position.String(), result.Errors))
+		}
+		return ut.saveCheckpoint(state, true)
+	}
+	return nil
+}
+
+// Save position as checkpoint
+// If not force, it is saved only when checkpointInterval passed since the last save
+func (ut BinlogUpdateTimerImpl) saveCheckpoint(state *binlogState, force bool) error {
+	ut.status.setBinlogPosition(state.position.String())
+	now := ut.Clock.Now()
+	if !force && now.Sub(state.savedAt) < checkpointInterval {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), ut.Timeout)
+	defer cancel()
+	if err := ut.EtcdClient.SetCheckpoint(ut.Membership.Lead(ctx, binlogLeader), binlogCheckpoint, state.position); err != nil {
+		return err
+	}
+	state.savedAt = now
+	return nil
+}
+
+// Column names of table
+// Table map has them if binlog_row_metadata is FULL. Otherwise they are read from database, and read again when the number of columns changed
+func (ut BinlogUpdateTimerImpl) columnNames(state *binlogState, table *binlog.TableMap) ([]string, error) {
+	if len(table.ColumnNames) == len(table.ColumnTypes) {
+		return table.ColumnNames, nil
+	}
+	if columns, ok := state.columns[table.Table]; ok && len(columns) == len(table.ColumnTypes) {
+		return columns, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), ut.Timeout)
+	defer cancel()
+	columns, err := ut.BinlogRepository.FindColumnNames(ctx, table.Schema, table.Table)
+	if err != nil {
+		return nil, err
+	}
+	if len(columns) != len(table.ColumnTypes) {
+		return nil, errors.New(fmt.Sprintf("Columns of %s do not match binlog. columns = %v", table.Table, columns))
+	}
+	state.columns[table.Table] = columns
+	return columns, nil
+}
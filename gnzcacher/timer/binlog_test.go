@@ -0,0 +1,493 @@
+package timer
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/tomoyane/grant-n-z/gnz/binlog"
+	"github.com/tomoyane/grant-n-z/gnz/cache"
+	"github.com/tomoyane/grant-n-z/gnz/common"
+	"github.com/tomoyane/grant-n-z/gnz/driver"
+)
+
+// Test binlog mode runs full sync without checkpoint, and applies transactions
+func TestBinlogStart_FullSync(t *testing.T) {
+	stub := newStubRunner(false)
+	etcdClient := newStubCheckpointEtcdClient(nil)
+	opener := newStubStreamOpener()
+	updateTimer := newBinlogUpdateTimer(stub, etcdClient, opener, newFakeClock())
+
+	exitCode := make(chan int)
+	result := make(chan int)
+	go func() { result <- updateTimer.Start(exitCode) }()
+
+	waitRun(t, stub)
+	if position := waitCheckpoint(t, etcdClient); position != (binlog.Position{Name: "mysql-bin.000003", Pos: 154}) {
+		t.Errorf("Incorrect TestBinlogStart_FullSync test. checkpoint = %v", position)
+		t.FailNow()
+	}
+
+	stream := waitOpen(t, opener, binlog.Position{Name: "mysql-bin.000003", Pos: 154})
+	stream.events <- streamEvent{event: binlog.Event{Body: &binlog.Rotate{Next: binlog.Position{Name: "mysql-bin.000003", Pos: 154}}}}
+	stream.events <- streamEvent{event: roleRowsEvent()}
+	stream.events <- streamEvent{event: binlog.Event{Header: binlog.EventHeader{Type: binlog.XidEvent, LogPos: 500}, Body: &binlog.Xid{Xid: 1}}}
+
+	if target := <-stub.targets; target != "changes" {
+		t.Errorf("Incorrect TestBinlogStart_FullSync test. target = %s", target)
+		t.FailNow()
+	}
+	if position := waitCheckpoint(t, etcdClient); position.Pos != 500 {
+		t.Errorf("Incorrect TestBinlogStart_FullSync test. checkpoint = %v", position)
+		t.FailNow()
+	}
+	if status := updateTimer.GetStatus(); status.Mode != ModeBinlog || status.BinlogPosition != "mysql-bin.000003:500" || status.LastTarget != "binlog=mysql-bin.000003:500" {
+		t.Errorf("Incorrect TestBinlogStart_FullSync test. status = %v", status)
+		t.FailNow()
+	}
+
+	exitCode <- 1
+	if code := <-result; code != 1 {
+		t.Errorf("Incorrect TestBinlogStart_FullSync test. code = %d", code)
+		t.FailNow()
+	}
+}
+
+// Test binlog mode follows from checkpoint
+func TestBinlogStart_Checkpoint(t *testing.T) {
+	stub := newStubRunner(false)
+	checkpoint := binlog.Position{Name: "mysql-bin.000002", Pos: 1000}
+	etcdClient := newStubCheckpointEtcdClient(&checkpoint)
+	opener := newStubStreamOpener()
+	updateTimer := newBinlogUpdateTimer(stub, etcdClient, opener, newFakeClock())
+
+	go updateTimer.Start(make(chan int))
+	waitOpen(t, opener, checkpoint)
+
+	select {
+	case <-stub.runs:
+		t.Errorf("Incorrect TestBinlogStart_Checkpoint test. Full sync was run")
+		t.FailNow()
+	default:
+	}
+	updateTimer.Stop()
+}
+
+// Test binlog mode runs full sync when binlog of checkpoint was purged
+func TestBinlogStart_Purged(t *testing.T) {
+	stub := newStubRunner(false)
+	checkpoint := binlog.Position{Name: "mysql-bin.000001", Pos: 1000}
+	etcdClient := newStubCheckpointEtcdClient(&checkpoint)
+	opener := newStubStreamOpener()
+	updateTimer := newBinlogUpdateTimer(stub, etcdClient, opener, newFakeClock())
+
+	go updateTimer.Start(make(chan int))
+	waitRun(t, stub)
+	waitOpen(t, opener, binlog.Position{Name: "mysql-bin.000003", Pos: 154})
+	updateTimer.Stop()
+}
+
+// Test binlog mode runs full sync when MySQL can not read binlog of checkpoint that was purged while following
+func TestBinlogStart_Expired(t *testing.T) {
+	stub := newStubRunner(false)
+	checkpoint := binlog.Position{Name: "mysql-bin.000002", Pos: 1000}
+	etcdClient := newStubCheckpointEtcdClient(&checkpoint)
+	opener := newStubStreamOpener()
+	clock := newFakeClock()
+	binlogRepository := &stubPurgeBinlogRepository{}
+	updateTimer := newBinlogUpdateTimer(stub, etcdClient, opener, clock)
+	updateTimer.BinlogRepository = binlogRepository
+
+	go updateTimer.Start(make(chan int))
+	stream := waitOpen(t, opener, checkpoint)
+	binlogRepository.purge()
+	stream.events <- streamEvent{err: binlog.MySQLError{Code: binlog.ErrCodeMasterFatalReadingBinlog, Message: "purged"}}
+
+	clock.waitWaiter(t, minBinlogBackoff)
+	clock.Advance(minBinlogBackoff)
+	waitRun(t, stub)
+	waitOpen(t, opener, binlog.Position{Name: "mysql-bin.000003", Pos: 154})
+	updateTimer.Stop()
+}
+
+// Test binlog mode follows again from checkpoint when MySQL can not read binlog that was not purged
+func TestBinlogStart_ReadError(t *testing.T) {
+	stub := newStubRunner(false)
+	checkpoint := binlog.Position{Name: "mysql-bin.000002", Pos: 1000}
+	etcdClient := newStubCheckpointEtcdClient(&checkpoint)
+	opener := newStubStreamOpener()
+	clock := newFakeClock()
+	updateTimer := newBinlogUpdateTimer(stub, etcdClient, opener, clock)
+
+	go updateTimer.Start(make(chan int))
+	stream := waitOpen(t, opener, checkpoint)
+	stream.events <- streamEvent{err: binlog.MySQLError{Code: binlog.ErrCodeMasterFatalReadingBinlog, Message: "slave with the same server_uuid/server_id"}}
+
+	clock.waitWaiter(t, minBinlogBackoff)
+	clock.Advance(minBinlogBackoff)
+	waitOpen(t, opener, checkpoint)
+	select {
+	case <-stub.runs:
+		t.Errorf("Incorrect TestBinlogStart_ReadError test. Full sync was run")
+		t.FailNow()
+	default:
+	}
+	updateTimer.Stop()
+}
+
+// Test binlog mode does not follow binlog until this replica becomes the leader
+func TestBinlogStart_NotLeader(t *testing.T) {
+	checkpoint := binlog.Position{Name: "mysql-bin.000002", Pos: 1000}
+	opener := newStubStreamOpener()
+	clock := newFakeClock()
+	membership := newStubLeaderMembership("member-a", "member-b")
+	updateTimer := newBinlogUpdateTimer(newStubRunner(false), newStubCheckpointEtcdClient(&checkpoint), opener, clock)
+	updateTimer.Membership = membership
+
+	go updateTimer.Start(make(chan int))
+	clock.waitWaiter(t, leaderInterval)
+	if status := updateTimer.GetStatus(); status.Leader {
+		t.Errorf("Incorrect TestBinlogStart_NotLeader test. status = %v", status)
+		t.FailNow()
+	}
+	select {
+	case <-opener.streams:
+		t.Errorf("Incorrect TestBinlogStart_NotLeader test. Stream was opened")
+		t.FailNow()
+	default:
+	}
+
+	// Leader stopped
+	membership.setLeader("")
+	clock.Advance(leaderInterval)
+	waitOpen(t, opener, checkpoint)
+	if status := updateTimer.GetStatus(); !status.Leader {
+		t.Errorf("Incorrect TestBinlogStart_NotLeader test. status = %v", status)
+		t.FailNow()
+	}
+	updateTimer.Stop()
+}
+
+// Test binlog mode stops following binlog when another replica became the leader
+func TestBinlogStart_LostLeader(t *testing.T) {
+	checkpoint := binlog.Position{Name: "mysql-bin.000002", Pos: 1000}
+	opener := newStubStreamOpener()
+	clock := newFakeClock()
+	membership := newStubLeaderMembership("member-a", "")
+	updateTimer := newBinlogUpdateTimer(newStubRunner(false), newStubCheckpointEtcdClient(&checkpoint), opener, clock)
+	updateTimer.Membership = membership
+
+	go updateTimer.Start(make(chan int))
+	stream := waitOpen(t, opener, checkpoint)
+
+	membership.setLeader("member-b")
+	clock.Advance(leaderInterval)
+	select {
+	case <-stream.closed:
+	case <-time.After(time.Second):
+		t.Errorf("Incorrect TestBinlogStart_LostLeader test. Stream was not closed")
+		t.FailNow()
+	}
+	clock.waitWaiter(t, leaderInterval)
+	if status := updateTimer.GetStatus(); status.Leader {
+		t.Errorf("Incorrect TestBinlogStart_LostLeader test. status = %v", status)
+		t.FailNow()
+	}
+	updateTimer.Stop()
+}
+
+// Test binlog mode reads primary in full sync, resync and changes
+func TestPrimaryRunner(t *testing.T) {
+	updateTimer := newBinlogUpdateTimer(newStubRunner(false), newStubCheckpointEtcdClient(nil), newStubStreamOpener(), newFakeClock())
+	if _, ok := updateTimer.Runner.(primaryRunner); !ok {
+		t.Errorf("Incorrect TestPrimaryRunner test. runner = %v", updateTimer.Runner)
+		t.FailNow()
+	}
+
+	stub := &readsPrimaryRunner{}
+	runner := primaryRunner{stub}
+	ctx := context.Background()
+	runner.Run(ctx)
+	runner.RunEntity(ctx, EntityRole)
+	runner.RunUser(ctx, "00000000-0000-0000-0000-000000000001")
+	runner.RunChanges(ctx, NewChanges())
+	if stub.primary != 4 {
+		t.Errorf("Incorrect TestPrimaryRunner test. primary = %d", stub.primary)
+		t.FailNow()
+	}
+}
+
+// Test server id is derived from host name when it is not set
+func TestBinlogServerId(t *testing.T) {
+	if serverId := binlogServerId(1001); serverId != 1001 {
+		t.Errorf("Incorrect TestBinlogServerId test. server_id = %d", serverId)
+		t.FailNow()
+	}
+	if serverId := binlogServerId(0); serverId < minBinlogServerId || serverId != binlogServerId(0) {
+		t.Errorf("Incorrect TestBinlogServerId test. server_id = %d", serverId)
+		t.FailNow()
+	}
+}
+
+// Test binlog mode follows again from checkpoint when cache update failed
+func TestBinlogStart_ApplyError(t *testing.T) {
+	checkpoint := binlog.Position{Name: "mysql-bin.000002", Pos: 1000}
+	etcdClient := newStubCheckpointEtcdClient(&checkpoint)
+	opener := newStubStreamOpener()
+	clock := newFakeClock()
+	updateTimer := newBinlogUpdateTimer(failedChangesRunner{newStubRunner(false)}, etcdClient, opener, clock)
+
+	go updateTimer.Start(make(chan int))
+	stream := waitOpen(t, opener, checkpoint)
+	stream.events <- streamEvent{event: roleRowsEvent()}
+	stream.events <- streamEvent{event: binlog.Event{Header: binlog.EventHeader{Type: binlog.XidEvent, LogPos: 1500}, Body: &binlog.Xid{Xid: 1}}}
+
+	clock.waitWaiter(t, minBinlogBackoff)
+	clock.Advance(minBinlogBackoff)
+	waitOpen(t, opener, checkpoint)
+	updateTimer.Stop()
+}
+
+func newBinlogUpdateTimer(runner Runner, etcdClient cache.EtcdClient, opener *stubStreamOpener, clock Clock) BinlogUpdateTimerImpl {
+	updateTimer := NewBinlogUpdateTimerWithConfig(common.CacherConfig{TimeoutMillis: 10000}, clock, runner, opener.open, "grant_n_z")
+	updateTimer.BinlogRepository = stubStatusBinlogRepository{}
+	updateTimer.EtcdClient = etcdClient
+	updateTimer.Membership = newStubLeaderMembership("member-a", "")
+	return updateTimer
+}
+
+// Update rows event of roles whose column names are in table map
+func roleRowsEvent() binlog.Event {
+	return binlog.Event{
+		Header: binlog.EventHeader{Type: binlog.UpdateRowsEventV2},
+		Body: &binlog.Rows{
+			Kind:  binlog.RowsUpdate,
+			Table: &binlog.TableMap{Schema: "grant_n_z", Table: "roles", ColumnTypes: make([]byte, 6), ColumnNames: roleColumns},
+			Rows: [][]interface{}{
+				{int64(1), "internal", "role-a", "user", nil, nil},
+				{int64(1), "internal", "role-a", "admin", nil, nil},
+			},
+		},
+	}
+}
+
+func waitCheckpoint(t *testing.T, etcdClient *stubCheckpointEtcdClient) binlog.Position {
+	select {
+	case position := <-etcdClient.saved:
+		return position
+	case <-time.After(time.Second):
+		t.Errorf("Checkpoint was not saved")
+		t.FailNow()
+	}
+	return binlog.Position{}
+}
+
+func waitOpen(t *testing.T, opener *stubStreamOpener, expected binlog.Position) *stubStream {
+	select {
+	case stream := <-opener.streams:
+		if stream.position != expected {
+			t.Errorf("Incorrect position of stream. position = %v", stream.position)
+			t.FailNow()
+		}
+		return stream
+	case <-time.After(time.Second):
+		t.Errorf("Stream was not opened")
+		t.FailNow()
+	}
+	return nil
+}
+
+// Less than stub struct
+// Binlog repository of MySQL that has mysql-bin.000002 and mysql-bin.000003
+type stubStatusBinlogRepository struct {
+	driver.BinlogRepository
+}
+
+func (br stubStatusBinlogRepository) FindMasterStatus(ctx context.Context) (binlog.Position, error) {
+	return binlog.Position{Name: "mysql-bin.000003", Pos: 154}, nil
+}
+
+func (br stubStatusBinlogRepository) FindBinaryLogs(ctx context.Context) ([]string, error) {
+	return []string{"mysql-bin.000002", "mysql-bin.000003"}, nil
+}
+
+// Less than stub struct
+// Binlog repository whose mysql-bin.000002 is purged by purge
+type stubPurgeBinlogRepository struct {
+	stubStatusBinlogRepository
+	mutex  sync.Mutex
+	purged bool
+}
+
+func (br *stubPurgeBinlogRepository) purge() {
+	br.mutex.Lock()
+	defer br.mutex.Unlock()
+	br.purged = true
+}
+
+func (br *stubPurgeBinlogRepository) FindBinaryLogs(ctx context.Context) ([]string, error) {
+	br.mutex.Lock()
+	defer br.mutex.Unlock()
+	if br.purged {
+		return []string{"mysql-bin.000003"}, nil
+	}
+	return br.stubStatusBinlogRepository.FindBinaryLogs(ctx)
+}
+
+// Less than stub struct
+// Membership with the leader in memory
+type stubLeaderMembership struct {
+	cache.Membership
+	id     string
+	mutex  *sync.Mutex
+	leader string
+}
+
+type stubLeaderKey struct{}
+
+func newStubLeaderMembership(id string, leader string) *stubLeaderMembership {
+	return &stubLeaderMembership{id: id, mutex: &sync.Mutex{}, leader: leader}
+}
+
+func (m *stubLeaderMembership) setLeader(leader string) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.leader = leader
+}
+
+func (m *stubLeaderMembership) Join(ctx context.Context) error {
+	return nil
+}
+
+func (m *stubLeaderMembership) Id() string {
+	return m.id
+}
+
+func (m *stubLeaderMembership) Campaign(ctx context.Context, name string) (bool, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if m.leader == "" {
+		m.leader = m.id
+	}
+	return m.leader == m.id, nil
+}
+
+func (m *stubLeaderMembership) Lead(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, stubLeaderKey{}, name)
+}
+
+// Less than stub struct
+// Etcd client that holds checkpoint, and sends saved checkpoints to channel
+type stubCheckpointEtcdClient struct {
+	cache.EtcdClient
+	mutex      *sync.Mutex
+	checkpoint *binlog.Position
+	saved      chan binlog.Position
+}
+
+func newStubCheckpointEtcdClient(checkpoint *binlog.Position) *stubCheckpointEtcdClient {
+	return &stubCheckpointEtcdClient{mutex: &sync.Mutex{}, checkpoint: checkpoint, saved: make(chan binlog.Position, 10)}
+}
+
+func (e *stubCheckpointEtcdClient) GetCheckpoint(ctx context.Context, name string, checkpoint interface{}) (bool, error) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	if e.checkpoint == nil {
+		return false, nil
+	}
+	*checkpoint.(*binlog.Position) = *e.checkpoint
+	return true, nil
+}
+
+// Checkpoint is saved only by the leader
+func (e *stubCheckpointEtcdClient) SetCheckpoint(ctx context.Context, name string, checkpoint interface{}) error {
+	if ctx.Value(stubLeaderKey{}) != binlogLeader {
+		return cache.ErrShardNotOwned
+	}
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	position := checkpoint.(binlog.Position)
+	e.checkpoint = &position
+	e.saved <- position
+	return nil
+}
+
+// Less than stub struct
+// Stream opener that sends opened streams to channel
+type stubStreamOpener struct {
+	streams chan *stubStream
+}
+
+func newStubStreamOpener() *stubStreamOpener {
+	return &stubStreamOpener{streams: make(chan *stubStream, 10)}
+}
+
+func (o *stubStreamOpener) open(position binlog.Position) (EventStream, error) {
+	stream := &stubStream{position: position, events: make(chan streamEvent, 10), closed: make(chan struct{})}
+	o.streams <- stream
+	return stream, nil
+}
+
+// Less than stub struct
+// Stream that returns events sent to channel
+type stubStream struct {
+	position binlog.Position
+	events   chan streamEvent
+	closed   chan struct{}
+	once     sync.Once
+}
+
+func (s *stubStream) Next() (binlog.Event, error) {
+	select {
+	case e := <-s.events:
+		return e.event, e.err
+	case <-s.closed:
+		return binlog.Event{}, errors.New("closed")
+	}
+}
+
+func (s *stubStream) Close() error {
+	s.once.Do(func() { close(s.closed) })
+	return nil
+}
+
+// Less than stub struct
+// Runner that fails to update cache by changes
+type failedChangesRunner struct {
+	stubRunner
+}
+
+func (r failedChangesRunner) RunChanges(ctx context.Context, changes *Changes) RunResult {
+	return RunResult{Rows: map[string]int{}, Errors: map[string]string{EntityRole: "failed"}}
+}
+
+// Less than stub struct
+// Runner that counts runs whose reads are routed to primary
+type readsPrimaryRunner struct {
+	primary int
+}
+
+func (r *readsPrimaryRunner) count(ctx context.Context) RunResult {
+	if driver.ReadsPrimary(ctx) {
+		r.primary++
+	}
+	return RunResult{Rows: map[string]int{}, Errors: map[string]string{}}
+}
+
+func (r *readsPrimaryRunner) Run(ctx context.Context) RunResult {
+	return r.count(ctx)
+}
+
+func (r *readsPrimaryRunner) RunEntity(ctx context.Context, entity string) RunResult {
+	return r.count(ctx)
+}
+
+func (r *readsPrimaryRunner) RunUser(ctx context.Context, userUuid string) RunResult {
+	return r.count(ctx)
+}
+
+func (r *readsPrimaryRunner) RunChanges(ctx context.Context, changes *Changes) RunResult {
+	return r.count(ctx)
+}
@@ -0,0 +1,165 @@
+package timer
+
+import (
+	"github.com/tomoyane/grant-n-z/gnz/binlog"
+	"github.com/tomoyane/grant-n-z/gnz/cache/structure"
+	"github.com/tomoyane/grant-n-z/gnz/entity"
+)
+
+// Tables that binlog mode follows
+var ChangeTables = []string{
+	entity.PolicyTable.String(),
+	entity.UserGroupTable.String(),
+	entity.UserServiceTable.String(),
+	entity.RoleTable.String(),
+	entity.PermissionTable.String(),
+	entity.ServiceTable.String(),
+	entity.GroupTable.String(),
+}
+
+// Changes of cache by rows of one transaction
+// Roles, permissions and services are set from rows. A nil value means the key is deleted, that is also set for soft deleted rows
+// Other changes are the uuids to find users whose cache is recomputed from database
+type Changes struct {
+	Roles       map[string]*structure.Role
+	Permissions map[string]*structure.Permission
+	Services    map[string]*structure.Service
+
+	// Users of changed user_groups and user_services
+	UserUuids map[string]bool
+
+	// user_groups of changed policies
+	UserGroupUuids map[string]bool
+
+	// Renamed groups, roles, permissions and services. Their names are in cache of users
+	GroupUuids      map[string]bool
+	RoleUuids       map[string]bool
+	PermissionUuids map[string]bool
+	ServiceUuids    map[string]bool
+}
+
+func NewChanges() *Changes {
+	return &Changes{
+		Roles:           make(map[string]*structure.Role),
+		Permissions:     make(map[string]*structure.Permission),
+		Services:        make(map[string]*structure.Service),
+		UserUuids:       make(map[string]bool),
+		UserGroupUuids:  make(map[string]bool),
+		GroupUuids:      make(map[string]bool),
+		RoleUuids:       make(map[string]bool),
+		PermissionUuids: make(map[string]bool),
+		ServiceUuids:    make(map[string]bool),
+	}
+}
+
+// No change
+func (c *Changes) Empty() bool {
+	return len(c.Roles) == 0 && len(c.Permissions) == 0 && len(c.Services) == 0 &&
+		len(c.UserUuids) == 0 && len(c.UserGroupUuids) == 0 && len(c.GroupUuids) == 0 &&
+		len(c.RoleUuids) == 0 && len(c.PermissionUuids) == 0 && len(c.ServiceUuids) == 0
+}
+
+// Add rows event
+// columns are the names of columns of the table in ordinal order
+func (c *Changes) Add(rows *binlog.Rows, columns []string) {
+	images := make([]map[string]string, len(rows.Rows))
+	for i, row := range rows.Rows {
+		images[i] = rowImage(row, columns)
+	}
+
+	switch rows.Table.Table {
+	case entity.PolicyTable.String():
+		for _, image := range images {
+			addUuid(c.UserGroupUuids, image[entity.PolicyUserGroupUuid.String()])
+		}
+
+	case entity.UserGroupTable.String():
+		for _, image := range images {
+			addUuid(c.UserUuids, image[entity.UserGroupUserUuid.String()])
+		}
+
+	case entity.UserServiceTable.String():
+		for _, image := range images {
+			addUuid(c.UserUuids, image[entity.UserServiceUserUuid.String()])
+		}
+
+	case entity.GroupTable.String():
+		if rows.Kind == binlog.RowsUpdate {
+			for _, image := range images {
+				addUuid(c.GroupUuids, image[entity.GroupUuid.String()])
+			}
+		}
+
+	case entity.RoleTable.String():
+		c.addEntity(rows.Kind, images, entity.RoleUuid.String(), c.RoleUuids, func(uuid string, image map[string]string) {
+			if image == nil || image[entity.RoleDeletedAt.String()] != "" {
+				c.Roles[uuid] = nil
+				return
+			}
+			c.Roles[uuid] = &structure.Role{Name: image[entity.RoleName.String()], Uuid: uuid}
+		})
+
+	case entity.PermissionTable.String():
+		c.addEntity(rows.Kind, images, entity.PermissionUuid.String(), c.PermissionUuids, func(uuid string, image map[string]string) {
+			if image == nil || image[entity.PermissionDeletedAt.String()] != "" {
+				c.Permissions[uuid] = nil
+				return
+			}
+			c.Permissions[uuid] = &structure.Permission{Name: image[entity.PermissionName.String()], Uuid: uuid}
+		})
+
+	case entity.ServiceTable.String():
+		c.addEntity(rows.Kind, images, entity.ServiceUuid.String(), c.ServiceUuids, func(uuid string, image map[string]string) {
+			if image == nil || image[entity.ServiceDeletedAt.String()] != "" {
+				c.Services[uuid] = nil
+				return
+			}
+			c.Services[uuid] = &structure.Service{Name: image[entity.ServiceName.String()], Uuid: uuid}
+		})
+	}
+}
+
+// Set or delete the entity of each row
+// For update, the key of before image is deleted if uuid was changed, and users of the entity are recomputed
+func (c *Changes) addEntity(kind string, images []map[string]string, uuidColumn string, renamed map[string]bool, set func(uuid string, image map[string]string)) {
+	switch kind {
+	case binlog.RowsInsert:
+		for _, image := range images {
+			set(image[uuidColumn], image)
+		}
+	case binlog.RowsDelete:
+		for _, image := range images {
+			set(image[uuidColumn], nil)
+		}
+	case binlog.RowsUpdate:
+		for i := 0; i+1 < len(images); i += 2 {
+			before, after := images[i][uuidColumn], images[i+1][uuidColumn]
+			if before != after {
+				set(before, nil)
+			}
+			set(after, images[i+1])
+			addUuid(renamed, after)
+		}
+	}
+}
+
+// Values of row by column name
+// Columns that are not string are not needed for cache
+func rowImage(row []interface{}, columns []string) map[string]string {
+	image := make(map[string]string, len(columns))
+	for i, value := range row {
+		if i >= len(columns) {
+			break
+		}
+		if str, ok := value.(string); ok {
+			image[columns[i]] = str
+		}
+	}
+	return image
+}
+
+func addUuid(uuids map[string]bool, uuid string) {
+	if uuid != "" {
+		uuids[uuid] = true
+	}
+}
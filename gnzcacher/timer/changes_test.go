@@ -0,0 +1,117 @@
+package timer
+
+import (
+	"testing"
+
+	"github.com/tomoyane/grant-n-z/gnz/binlog"
+)
+
+var (
+	roleColumns      = []string{"id", "internal_id", "uuid", "name", "created_at", "updated_at"}
+	policyColumns    = []string{"id", "internal_id", "name", "role_uuid", "permission_uuid", "service_uuid", "user_group_uuid", "created_at", "updated_at"}
+	userGroupColumns = []string{"id", "internal_id", "uuid", "user_uuid", "group_uuid", "created_at", "updated_at"}
+)
+
+// Test add rows of roles
+func TestChangesAdd_Role(t *testing.T) {
+	changes := NewChanges()
+	changes.Add(&binlog.Rows{
+		Kind:  binlog.RowsInsert,
+		Table: &binlog.TableMap{Table: "roles"},
+		Rows:  [][]interface{}{{int64(1), "internal", "role-a", "admin", "2020-04-01 12:30:15", "2020-04-01 12:30:15"}},
+	}, roleColumns)
+	changes.Add(&binlog.Rows{
+		Kind:  binlog.RowsUpdate,
+		Table: &binlog.TableMap{Table: "roles"},
+		Rows: [][]interface{}{
+			{int64(2), "internal", "role-b", "user", nil, nil},
+			{int64(2), "internal", "role-c", "member", nil, nil},
+		},
+	}, roleColumns)
+
+	if role := changes.Roles["role-a"]; role == nil || role.Name != "admin" || role.Uuid != "role-a" {
+		t.Errorf("Incorrect TestChangesAdd_Role test. role = %v", role)
+		t.FailNow()
+	}
+	if role, ok := changes.Roles["role-b"]; !ok || role != nil {
+		t.Errorf("Incorrect TestChangesAdd_Role test. before = %v", role)
+		t.FailNow()
+	}
+	if role := changes.Roles["role-c"]; role == nil || role.Name != "member" || !changes.RoleUuids["role-c"] || changes.RoleUuids["role-a"] {
+		t.Errorf("Incorrect TestChangesAdd_Role test. after = %v, renamed = %v", role, changes.RoleUuids)
+		t.FailNow()
+	}
+
+	changes.Add(&binlog.Rows{
+		Kind:  binlog.RowsDelete,
+		Table: &binlog.TableMap{Table: "roles"},
+		Rows:  [][]interface{}{{int64(1), "internal", "role-a", "admin", nil, nil}},
+	}, roleColumns)
+	if role, ok := changes.Roles["role-a"]; !ok || role != nil {
+		t.Errorf("Incorrect TestChangesAdd_Role test. deleted = %v", role)
+		t.FailNow()
+	}
+}
+
+// Test soft deleted and restored role
+func TestChangesAdd_SoftDeletedRole(t *testing.T) {
+	columns := append(roleColumns, "deleted_at")
+	changes := NewChanges()
+	changes.Add(&binlog.Rows{
+		Kind:  binlog.RowsUpdate,
+		Table: &binlog.TableMap{Table: "roles"},
+		Rows: [][]interface{}{
+			{int64(1), "internal", "role-a", "admin", nil, nil, nil},
+			{int64(1), "internal", "role-a", "admin", nil, nil, "2020-04-01 12:30:15"},
+		},
+	}, columns)
+	if role, ok := changes.Roles["role-a"]; !ok || role != nil || !changes.RoleUuids["role-a"] {
+		t.Errorf("Incorrect TestChangesAdd_SoftDeletedRole test. deleted = %v", role)
+		t.FailNow()
+	}
+
+	changes.Add(&binlog.Rows{
+		Kind:  binlog.RowsUpdate,
+		Table: &binlog.TableMap{Table: "roles"},
+		Rows: [][]interface{}{
+			{int64(1), "internal", "role-a", "admin", nil, nil, "2020-04-01 12:30:15"},
+			{int64(1), "internal", "role-a", "admin", nil, nil, nil},
+		},
+	}, columns)
+	if role := changes.Roles["role-a"]; role == nil || role.Name != "admin" {
+		t.Errorf("Incorrect TestChangesAdd_SoftDeletedRole test. restored = %v", role)
+		t.FailNow()
+	}
+}
+
+// Test add rows of policies and user_groups
+func TestChangesAdd_User(t *testing.T) {
+	changes := NewChanges()
+	if !changes.Empty() {
+		t.Errorf("Incorrect TestChangesAdd_User test")
+		t.FailNow()
+	}
+
+	changes.Add(&binlog.Rows{
+		Kind:  binlog.RowsDelete,
+		Table: &binlog.TableMap{Table: "policies"},
+		Rows:  [][]interface{}{{int64(1), "internal", "policy", "role", "permission", "service", "user-group-a", nil, nil}},
+	}, policyColumns)
+	changes.Add(&binlog.Rows{
+		Kind:  binlog.RowsUpdate,
+		Table: &binlog.TableMap{Table: "user_groups"},
+		Rows: [][]interface{}{
+			{int64(1), "internal", "user-group-b", "user-a", "group", nil, nil},
+			{int64(1), "internal", "user-group-b", "user-b", "group", nil, nil},
+		},
+	}, userGroupColumns)
+
+	if changes.Empty() || !changes.UserGroupUuids["user-group-a"] || len(changes.UserGroupUuids) != 1 {
+		t.Errorf("Incorrect TestChangesAdd_User test. user_groups = %v", changes.UserGroupUuids)
+		t.FailNow()
+	}
+	if !changes.UserUuids["user-a"] || !changes.UserUuids["user-b"] || len(changes.Roles) != 0 {
+		t.Errorf("Incorrect TestChangesAdd_User test. users = %v", changes.UserUuids)
+		t.FailNow()
+	}
+}
@@ -0,0 +1,30 @@
+package timer
+
+import "time"
+
+// Clock interface
+// The scheduler waits through this, so that tests can drive it with a fake clock
+type Clock interface {
+	// Current time
+	Now() time.Time
+
+	// Receive current time after duration
+	After(d time.Duration) <-chan time.Time
+}
+
+// Clock struct using the time package
+type ClockImpl struct {
+}
+
+// Constructor
+func NewClock() Clock {
+	return ClockImpl{}
+}
+
+func (c ClockImpl) Now() time.Time {
+	return time.Now()
+}
+
+func (c ClockImpl) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
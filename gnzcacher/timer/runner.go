@@ -1,108 +1,483 @@
 package timer
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"sort"
+	"sync"
 
+	"github.com/tomoyane/grant-n-z/gnz/cache"
+	"github.com/tomoyane/grant-n-z/gnz/cache/structure"
+	"github.com/tomoyane/grant-n-z/gnz/driver"
 	"github.com/tomoyane/grant-n-z/gnz/log"
 	"github.com/tomoyane/grant-n-z/gnzcacher/service"
 )
 
 const limit = 100
 
+// Retry count of failed batches
+const batchRetryCnt = 3
+
+// Entity name of cache data
+const (
+	EntityPolicy      = "policy"
+	EntityPermission  = "permission"
+	EntityRole        = "role"
+	EntityService     = "service"
+	EntityUserService = "user_service"
+	EntityUserGroup   = "user_group"
+)
+
+// All entity names
+var Entities = []string{
+	EntityPolicy,
+	EntityPermission,
+	EntityRole,
+	EntityService,
+	EntityUserService,
+	EntityUserGroup,
+}
+
 type Runner interface {
 	// Run main process
-	Run()
+	// It returns when every entity has been updated or ctx is done
+	Run(ctx context.Context) RunResult
+
+	// Update one entity
+	RunEntity(ctx context.Context, entity string) RunResult
+
+	// Update policy, user_service and user_group of one user
+	// If the user was deleted, the keys of the user are updated with empty data
+	RunUser(ctx context.Context, userUuid string) RunResult
+
+	// Update cache by changes of binlog
+	// Roles, permissions and services are set or deleted, and users of the changes are updated as RunUser
+	RunChanges(ctx context.Context, changes *Changes) RunResult
+}
+
+// Result of run
+type RunResult struct {
+	// Updated keys by entity
+	Rows map[string]int `json:"rows"`
+
+	// Error by entity. The entity that has error was not fully updated, and its keys were not pruned
+	Errors map[string]string `json:"errors"`
 }
 
 type RunnerImpl struct {
 	UpdaterService   service.UpdaterService
 	ExtractorService service.ExtractorService
+	PrunerService    service.PrunerService
+	BinlogRepository driver.BinlogRepository
+	EtcdClient       cache.EtcdClient
 }
 
+// Update function of entity. It returns the number of updated keys
+type executeFunc func(ctx context.Context) (int, error)
+
 func NewRunner() Runner {
+	return newRunnerImpl()
+}
+
+func newRunnerImpl() RunnerImpl {
 	return RunnerImpl{
 		UpdaterService:   service.NewUpdaterService(),
 		ExtractorService: service.NewExtractorService(),
+		PrunerService:    service.GetPrunerServiceInstance(),
+		BinlogRepository: driver.GetBinlogRepositoryInstance(),
+		EtcdClient:       cache.NewEtcdClient(),
+	}
+}
+
+func (r RunnerImpl) Run(ctx context.Context) RunResult {
+	return r.run(ctx, r.executes())
+}
+
+func (r RunnerImpl) RunEntity(ctx context.Context, entity string) RunResult {
+	execute, ok := r.executes()[entity]
+	if !ok {
+		return RunResult{
+			Rows:   map[string]int{},
+			Errors: map[string]string{entity: "Unknown entity"},
+		}
+	}
+	return r.run(ctx, map[string]executeFunc{entity: execute})
+}
+
+func (r RunnerImpl) RunUser(ctx context.Context, userUuid string) RunResult {
+	return r.runUsers(ctx, []string{userUuid})
+}
+
+func (r RunnerImpl) RunChanges(ctx context.Context, changes *Changes) RunResult {
+	executes := make(map[string]executeFunc)
+	if len(changes.Roles) != 0 {
+		executes[EntityRole] = func(ctx context.Context) (int, error) {
+			var roles []structure.Role
+			var deleted []string
+			for uuid, role := range changes.Roles {
+				if role == nil {
+					deleted = append(deleted, cache.RoleKeyPrefix+uuid)
+					continue
+				}
+				roles = append(roles, *role)
+			}
+			return len(changes.Roles), r.applyEntity(ctx, EntityRole, deleted, r.UpdaterService.UpdateRole(ctx, roles))
+		}
+	}
+	if len(changes.Permissions) != 0 {
+		executes[EntityPermission] = func(ctx context.Context) (int, error) {
+			var permissions []structure.Permission
+			var deleted []string
+			for uuid, permission := range changes.Permissions {
+				if permission == nil {
+					deleted = append(deleted, cache.PermissionKeyPrefix+uuid)
+					continue
+				}
+				permissions = append(permissions, *permission)
+			}
+			return len(changes.Permissions), r.applyEntity(ctx, EntityPermission, deleted, r.UpdaterService.UpdatePermission(ctx, permissions))
+		}
+	}
+	if len(changes.Services) != 0 {
+		executes[EntityService] = func(ctx context.Context) (int, error) {
+			var services []structure.Service
+			var deleted []string
+			for uuid, service := range changes.Services {
+				if service == nil {
+					deleted = append(deleted, cache.ServiceKeyPrefix+uuid)
+					continue
+				}
+				services = append(services, *service)
+			}
+			return len(changes.Services), r.applyEntity(ctx, EntityService, deleted, r.UpdaterService.UpdateService(ctx, services))
+		}
+	}
+	result := r.run(ctx, executes)
+
+	userUuids, err := r.findChangedUsers(ctx, changes)
+	if err != nil {
+		// Users were not updated
+		log.Logger.Error(fmt.Sprintf("Failed to find users of changes. err = %s", err.Error()))
+		for _, entity := range []string{EntityPolicy, EntityUserService, EntityUserGroup} {
+			result.Errors[entity] = err.Error()
+		}
+		return result
+	}
+	if len(userUuids) != 0 {
+		userResult := r.runUsers(ctx, userUuids)
+		for entity, rows := range userResult.Rows {
+			result.Rows[entity] += rows
+		}
+		for entity, err := range userResult.Errors {
+			result.Errors[entity] = err
+		}
+	}
+	return result
+}
+
+// Update policy, user_service and user_group of users
+func (r RunnerImpl) runUsers(ctx context.Context, userUuids []string) RunResult {
+	return r.run(ctx, map[string]executeFunc{
+		EntityPolicy: func(ctx context.Context) (int, error) {
+			policies, err := r.ExtractorService.GetPoliciesByUserUuids(ctx, userUuids)
+			if err != nil {
+				return 0, err
+			}
+			if err := r.retry(ctx, EntityPolicy, r.UpdaterService.UpdatePolicy(ctx, policies)); err != nil {
+				return 0, err
+			}
+			r.deleteMissing(ctx, cache.UserPolicyKeyPrefix, userUuids, func(userUuid string) bool {
+				_, ok := policies[userUuid]
+				return ok
+			})
+			return len(policies), nil
+		},
+		EntityUserService: func(ctx context.Context) (int, error) {
+			userServices, err := r.ExtractorService.GetUserServicesByUserUuids(ctx, userUuids)
+			if err != nil {
+				return 0, err
+			}
+			if err := r.retry(ctx, EntityUserService, r.UpdaterService.UpdateUserService(ctx, userServices)); err != nil {
+				return 0, err
+			}
+			r.deleteMissing(ctx, cache.UserServiceKeyPrefix, userUuids, func(userUuid string) bool {
+				_, ok := userServices[userUuid]
+				return ok
+			})
+			return len(userServices), nil
+		},
+		EntityUserGroup: func(ctx context.Context) (int, error) {
+			userGroups, err := r.ExtractorService.GetUserGroupsByUserUuids(ctx, userUuids)
+			if err != nil {
+				return 0, err
+			}
+			if err := r.retry(ctx, EntityUserGroup, r.UpdaterService.UpdateUserGroup(ctx, userGroups)); err != nil {
+				return 0, err
+			}
+			r.deleteMissing(ctx, cache.UserGroupKeyPrefix, userUuids, func(userUuid string) bool {
+				_, ok := userGroups[userUuid]
+				return ok
+			})
+			return len(userGroups), nil
+		},
+	})
+}
+
+func (r RunnerImpl) executes() map[string]executeFunc {
+	return map[string]executeFunc{
+		EntityPolicy:      r.executePolicy,
+		EntityPermission:  r.executePermission,
+		EntityRole:        r.executeRole,
+		EntityService:     r.executeService,
+		EntityUserService: r.executeUserService,
+		EntityUserGroup:   r.executeUserGroup,
 	}
 }
 
-func (r RunnerImpl) Run() {
-	go r.executePolicy()
-	go r.executePermission()
-	go r.executeRole()
-	go r.executeService()
-	go r.executeUserService()
-	go r.executeUserGroup()
+// Run executes in parallel, and wait for all of them
+func (r RunnerImpl) run(ctx context.Context, executes map[string]executeFunc) RunResult {
+	result := RunResult{
+		Rows:   make(map[string]int),
+		Errors: make(map[string]string),
+	}
+
+	var mutex sync.Mutex
+	var wg sync.WaitGroup
+	for entity, execute := range executes {
+		wg.Add(1)
+		go func(entity string, execute executeFunc) {
+			defer wg.Done()
+			rows, err := execute(ctx)
+
+			mutex.Lock()
+			defer mutex.Unlock()
+			result.Rows[entity] = rows
+			if err != nil {
+				log.Logger.Error(fmt.Sprintf("Failed to update %s. err = %s", entity, err.Error()))
+				result.Errors[entity] = err.Error()
+			}
+		}(entity, execute)
+	}
+	wg.Wait()
+	return result
 }
 
-func (r RunnerImpl) executePolicy() {
+func (r RunnerImpl) executePolicy(ctx context.Context) (int, error) {
+	liveIds := make(map[string]bool)
 	dataLength := 1
-	offset := 0
-	for dataLength != 0 {
-		policies := r.ExtractorService.GetPolicies(offset, limit)
-		r.UpdaterService.UpdatePolicy(policies)
+	afterUserUuid := ""
+	for dataLength != 0 && ctx.Err() == nil {
+		policies, lastUserUuid, err := r.ExtractorService.GetPolicies(ctx, afterUserUuid, limit)
+		if err != nil {
+			return len(liveIds), err
+		}
+		if err := r.retry(ctx, EntityPolicy, r.UpdaterService.UpdatePolicy(ctx, policies)); err != nil {
+			return len(liveIds), err
+		}
+		for userUuid := range policies {
+			liveIds[userUuid] = true
+		}
 		dataLength = len(policies)
-		offset += limit
+		afterUserUuid = lastUserUuid
 		log.Logger.Info(fmt.Sprintf("Update policy length = %d", dataLength))
 	}
+	return r.prune(ctx, cache.UserPolicyKeyPrefix, liveIds)
 }
 
-func (r RunnerImpl) executePermission() {
+func (r RunnerImpl) executePermission(ctx context.Context) (int, error) {
+	liveIds := make(map[string]bool)
 	dataLength := 1
 	offset := 0
-	for dataLength != 0 {
-		permissions := r.ExtractorService.GetPermissions(offset, limit)
-		r.UpdaterService.UpdatePermission(permissions)
+	for dataLength != 0 && ctx.Err() == nil {
+		permissions, err := r.ExtractorService.GetPermissions(ctx, offset, limit)
+		if err != nil {
+			return len(liveIds), err
+		}
+		if err := r.retry(ctx, EntityPermission, r.UpdaterService.UpdatePermission(ctx, permissions)); err != nil {
+			return len(liveIds), err
+		}
+		for _, permission := range permissions {
+			liveIds[permission.Uuid] = true
+		}
 		dataLength = len(permissions)
 		offset += limit
 		log.Logger.Info(fmt.Sprintf("Update permission length = %d", dataLength))
 	}
+	return r.prune(ctx, cache.PermissionKeyPrefix, liveIds)
 }
 
-func (r RunnerImpl) executeRole() {
+func (r RunnerImpl) executeRole(ctx context.Context) (int, error) {
+	liveIds := make(map[string]bool)
 	dataLength := 1
 	offset := 0
-	for dataLength != 0 {
-		roles := r.ExtractorService.GetRoles(offset, limit)
-		r.UpdaterService.UpdateRole(roles)
+	for dataLength != 0 && ctx.Err() == nil {
+		roles, err := r.ExtractorService.GetRoles(ctx, offset, limit)
+		if err != nil {
+			return len(liveIds), err
+		}
+		if err := r.retry(ctx, EntityRole, r.UpdaterService.UpdateRole(ctx, roles)); err != nil {
+			return len(liveIds), err
+		}
+		for _, role := range roles {
+			liveIds[role.Uuid] = true
+		}
 		dataLength = len(roles)
 		offset += limit
 		log.Logger.Info(fmt.Sprintf("Update role length = %d", dataLength))
 	}
+	return r.prune(ctx, cache.RoleKeyPrefix, liveIds)
 }
 
-func (r RunnerImpl) executeService() {
+func (r RunnerImpl) executeService(ctx context.Context) (int, error) {
+	liveIds := make(map[string]bool)
 	dataLength := 1
 	offset := 0
-	for dataLength != 0 {
-		services := r.ExtractorService.GetServices(offset, limit)
-		r.UpdaterService.UpdateService(services)
+	for dataLength != 0 && ctx.Err() == nil {
+		services, err := r.ExtractorService.GetServices(ctx, offset, limit)
+		if err != nil {
+			return len(liveIds), err
+		}
+		if err := r.retry(ctx, EntityService, r.UpdaterService.UpdateService(ctx, services)); err != nil {
+			return len(liveIds), err
+		}
+		for _, service := range services {
+			liveIds[service.Uuid] = true
+		}
 		dataLength = len(services)
 		offset += limit
 		log.Logger.Info(fmt.Sprintf("Update service length = %d", dataLength))
 	}
+	return r.prune(ctx, cache.ServiceKeyPrefix, liveIds)
 }
 
-func (r RunnerImpl) executeUserService() {
+func (r RunnerImpl) executeUserService(ctx context.Context) (int, error) {
+	liveIds := make(map[string]bool)
 	dataLength := 1
-	offset := 0
-	for dataLength != 0 {
-		userServices := r.ExtractorService.GetUserServices(offset, limit)
-		r.UpdaterService.UpdateUserService(userServices)
+	afterUserUuid := ""
+	for dataLength != 0 && ctx.Err() == nil {
+		userServices, lastUserUuid, err := r.ExtractorService.GetUserServices(ctx, afterUserUuid, limit)
+		if err != nil {
+			return len(liveIds), err
+		}
+		if err := r.retry(ctx, EntityUserService, r.UpdaterService.UpdateUserService(ctx, userServices)); err != nil {
+			return len(liveIds), err
+		}
+		for userUuid := range userServices {
+			liveIds[userUuid] = true
+		}
 		dataLength = len(userServices)
-		offset += limit
+		afterUserUuid = lastUserUuid
 		log.Logger.Info(fmt.Sprintf("Update user_service length = %d", dataLength))
 	}
+	return r.prune(ctx, cache.UserServiceKeyPrefix, liveIds)
 }
 
-func (r RunnerImpl) executeUserGroup() {
+func (r RunnerImpl) executeUserGroup(ctx context.Context) (int, error) {
+	liveIds := make(map[string]bool)
 	dataLength := 1
-	offset := 0
-	for dataLength != 0 {
-		userGroups := r.ExtractorService.GetUserGroups(offset, limit)
-		r.UpdaterService.UpdateUserGroup(userGroups)
+	afterUserUuid := ""
+	for dataLength != 0 && ctx.Err() == nil {
+		userGroups, lastUserUuid, err := r.ExtractorService.GetUserGroups(ctx, afterUserUuid, limit)
+		if err != nil {
+			return len(liveIds), err
+		}
+		if err := r.retry(ctx, EntityUserGroup, r.UpdaterService.UpdateUserGroup(ctx, userGroups)); err != nil {
+			return len(liveIds), err
+		}
+		for userUuid := range userGroups {
+			liveIds[userUuid] = true
+		}
 		dataLength = len(userGroups)
-		offset += limit
+		afterUserUuid = lastUserUuid
 		log.Logger.Info(fmt.Sprintf("Update user_group length = %d", dataLength))
 	}
+	return r.prune(ctx, cache.UserGroupKeyPrefix, liveIds)
+}
+
+// Delete keys of deleted rows, and retry failed batches of updated rows
+func (r RunnerImpl) applyEntity(ctx context.Context, entity string, deleted []string, failed []cache.FailedBatch) error {
+	if len(deleted) != 0 {
+		r.EtcdClient.DeleteKeys(ctx, deleted)
+	}
+	return r.retry(ctx, entity, failed)
+}
+
+// Delete keys of users that have no rows, such as users whose memberships were deleted or soft deleted
+func (r RunnerImpl) deleteMissing(ctx context.Context, prefix string, userUuids []string, found func(userUuid string) bool) {
+	var keys []string
+	for _, userUuid := range userUuids {
+		if !found(userUuid) {
+			keys = append(keys, prefix+userUuid)
+		}
+	}
+	if len(keys) != 0 {
+		r.EtcdClient.DeleteKeys(ctx, keys)
+	}
+}
+
+// Users whose cache depends on changes
+func (r RunnerImpl) findChangedUsers(ctx context.Context, changes *Changes) ([]string, error) {
+	userUuids := make(map[string]bool)
+	for userUuid := range changes.UserUuids {
+		userUuids[userUuid] = true
+	}
+
+	finds := []struct {
+		uuids map[string]bool
+		find  func(ctx context.Context, uuids []string) ([]string, error)
+	}{
+		{changes.UserGroupUuids, r.BinlogRepository.FindUserUuidsByUserGroupUuids},
+		{changes.GroupUuids, r.BinlogRepository.FindUserUuidsByGroupUuids},
+		{changes.RoleUuids, r.BinlogRepository.FindUserUuidsByRoleUuids},
+		{changes.PermissionUuids, r.BinlogRepository.FindUserUuidsByPermissionUuids},
+		{changes.ServiceUuids, r.BinlogRepository.FindUserUuidsByServiceUuids},
+	}
+	for _, find := range finds {
+		if len(find.uuids) == 0 {
+			continue
+		}
+		uuids := make([]string, 0, len(find.uuids))
+		for uuid := range find.uuids {
+			uuids = append(uuids, uuid)
+		}
+		found, err := find.find(ctx, uuids)
+		if err != nil {
+			return nil, err
+		}
+		for _, userUuid := range found {
+			userUuids[userUuid] = true
+		}
+	}
+
+	result := make([]string, 0, len(userUuids))
+	for userUuid := range userUuids {
+		result = append(result, userUuid)
+	}
+	sort.Strings(result)
+	return result, nil
+}
+
+// Retry failed batches of update
+// If some batches still failed, the entity was not fully updated, so it returns error and its keys are not pruned
+func (r RunnerImpl) retry(ctx context.Context, entity string, failed []cache.FailedBatch) error {
+	for i := 0; i < batchRetryCnt && len(failed) != 0 && ctx.Err() == nil; i++ {
+		log.Logger.Warn(fmt.Sprintf("Retry %d failed batches of %s", len(failed), entity))
+		failed = r.UpdaterService.Retry(ctx, failed)
+	}
+	if len(failed) != 0 {
+		return errors.New(fmt.Sprintf("Failed to update %d batches of %s. err = %s", len(failed), entity, failed[0].Err.Error()))
+	}
+	return nil
+}
+
+// Delete orphan keys after all data of the prefix was extracted
+// If the cycle was cancelled, the extracted ids are not complete, so pruning is skipped
+func (r RunnerImpl) prune(ctx context.Context, prefix string, liveIds map[string]bool) (int, error) {
+	if ctx.Err() != nil {
+		log.Logger.Warn(fmt.Sprintf("Skip pruning %s. Update cache cycle was cancelled", prefix))
+		return len(liveIds), ctx.Err()
+	}
+	r.PrunerService.Prune(ctx, prefix, liveIds)
+	return len(liveIds), nil
 }
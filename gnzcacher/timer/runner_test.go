@@ -1,6 +1,10 @@
 package timer
 
 import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
 	"testing"
 	"time"
 
@@ -9,6 +13,7 @@ import (
 	"github.com/jinzhu/gorm"
 	_ "github.com/mattn/go-sqlite3"
 	"github.com/tomoyane/grant-n-z/gnz/cache"
+	"github.com/tomoyane/grant-n-z/gnz/cache/structure"
 	"github.com/tomoyane/grant-n-z/gnz/driver"
 	"github.com/tomoyane/grant-n-z/gnz/log"
 	"github.com/tomoyane/grant-n-z/gnzcacher/service"
@@ -17,6 +22,7 @@ import (
 var (
 	extractorService service.ExtractorService
 	updaterService   service.UpdaterService
+	prunerService    service.PrunerService
 )
 
 func init() {
@@ -45,6 +51,7 @@ func init() {
 	}
 
 	updaterService = service.UpdaterServiceImpl{EtcdClient: etcdClient}
+	prunerService = service.NewPrunerServiceWithMode(etcdClient, service.PruneModeDelete)
 }
 
 // Test run
@@ -52,6 +59,229 @@ func TestRun(t *testing.T) {
 	runner := RunnerImpl{
 		UpdaterService:   updaterService,
 		ExtractorService: extractorService,
+		PrunerService:    prunerService,
 	}
-	runner.Run()
+	result := runner.Run(context.Background())
+
+	// Tables do not exist in test database, so every entity fails
+	if len(result.Errors) != len(Entities) {
+		t.Errorf("Incorrect TestRun test. errors = %v", result.Errors)
+		t.FailNow()
+	}
+}
+
+// Test run entity
+func TestRunEntity(t *testing.T) {
+	runner := RunnerImpl{
+		UpdaterService:   updaterService,
+		ExtractorService: extractorService,
+		PrunerService:    prunerService,
+	}
+
+	result := runner.RunEntity(context.Background(), EntityRole)
+	if len(result.Errors) != 1 || result.Errors[EntityRole] == "" {
+		t.Errorf("Incorrect TestRunEntity test. errors = %v", result.Errors)
+		t.FailNow()
+	}
+
+	result = runner.RunEntity(context.Background(), "unknown")
+	if result.Errors["unknown"] == "" {
+		t.Errorf("Incorrect TestRunEntity test. errors = %v", result.Errors)
+		t.FailNow()
+	}
+}
+
+// Test run user
+func TestRunUser(t *testing.T) {
+	runner := RunnerImpl{
+		UpdaterService:   updaterService,
+		ExtractorService: extractorService,
+		PrunerService:    prunerService,
+	}
+
+	result := runner.RunUser(context.Background(), "uuid")
+	if len(result.Errors) != 3 || result.Errors[EntityPolicy] == "" || result.Errors[EntityUserService] == "" || result.Errors[EntityUserGroup] == "" {
+		t.Errorf("Incorrect TestRunUser test. errors = %v", result.Errors)
+		t.FailNow()
+	}
+}
+
+// Test keys of user that has no rows are deleted
+func TestRunUser_Deleted(t *testing.T) {
+	etcdClient := &stubDeleteEtcdClient{}
+	runner := RunnerImpl{
+		UpdaterService:   &stubChangeUpdaterService{},
+		ExtractorService: stubEmptyExtractorService{},
+		EtcdClient:       etcdClient,
+	}
+
+	result := runner.RunUser(context.Background(), "uuid")
+	sort.Strings(etcdClient.deleted)
+	if len(result.Errors) != 0 || len(etcdClient.deleted) != 3 || etcdClient.deleted[0] != cache.UserGroupKeyPrefix+"uuid" {
+		t.Errorf("Incorrect TestRunUser_Deleted test. errors = %v, deleted = %v", result.Errors, etcdClient.deleted)
+		t.FailNow()
+	}
+}
+
+// Test run changes
+func TestRunChanges(t *testing.T) {
+	updaterService := &stubChangeUpdaterService{}
+	etcdClient := &stubDeleteEtcdClient{}
+	binlogRepository := &stubBinlogRepository{userUuids: []string{"user-b", "user-a"}}
+	runner := RunnerImpl{
+		UpdaterService:   updaterService,
+		ExtractorService: extractorService,
+		BinlogRepository: binlogRepository,
+		EtcdClient:       etcdClient,
+	}
+
+	changes := NewChanges()
+	changes.Roles["role-a"] = &structure.Role{Name: "admin", Uuid: "role-a"}
+	changes.Roles["role-b"] = nil
+	changes.RoleUuids["role-a"] = true
+	result := runner.RunChanges(context.Background(), changes)
+
+	if len(updaterService.roles) != 1 || updaterService.roles[0].Name != "admin" || len(etcdClient.deleted) != 1 || etcdClient.deleted[0] != "role=role-b" {
+		t.Errorf("Incorrect TestRunChanges test. roles = %v, deleted = %v", updaterService.roles, etcdClient.deleted)
+		t.FailNow()
+	}
+	if len(binlogRepository.found) != 1 || binlogRepository.found[0] != "role-a" || result.Rows[EntityRole] != 2 {
+		t.Errorf("Incorrect TestRunChanges test. found = %v, rows = %v", binlogRepository.found, result.Rows)
+		t.FailNow()
+	}
+
+	// Tables do not exist in test database, so users of changes are not updated
+	if result.Errors[EntityRole] != "" || result.Errors[EntityPolicy] == "" || result.Errors[EntityUserGroup] == "" {
+		t.Errorf("Incorrect TestRunChanges test. errors = %v", result.Errors)
+		t.FailNow()
+	}
+}
+
+// Test run changes when users of changes can not be found
+func TestRunChanges_FindUsersError(t *testing.T) {
+	runner := RunnerImpl{
+		UpdaterService:   &stubChangeUpdaterService{},
+		ExtractorService: extractorService,
+		BinlogRepository: &stubBinlogRepository{err: errors.New("failed")},
+		EtcdClient:       &stubDeleteEtcdClient{},
+	}
+
+	changes := NewChanges()
+	changes.UserUuids["user-a"] = true
+	changes.UserGroupUuids["user-group-a"] = true
+	result := runner.RunChanges(context.Background(), changes)
+	if len(result.Errors) != 3 || result.Errors[EntityUserService] != "failed" {
+		t.Errorf("Incorrect TestRunChanges_FindUsersError test. errors = %v", result.Errors)
+		t.FailNow()
+	}
+}
+
+// Test retry of failed batches
+func TestRetry(t *testing.T) {
+	failed := []cache.FailedBatch{{Values: []cache.KeyValue{{Key: "role=a"}}, Err: errors.New("failed")}}
+
+	updaterService := &stubRetryUpdaterService{failCnt: 2}
+	runner := RunnerImpl{UpdaterService: updaterService}
+	if err := runner.retry(context.Background(), EntityRole, failed); err != nil || updaterService.retryCnt != 2 {
+		t.Errorf("Incorrect TestRetry test. retry = %d", updaterService.retryCnt)
+		t.FailNow()
+	}
+
+	updaterService = &stubRetryUpdaterService{failCnt: batchRetryCnt + 1}
+	runner = RunnerImpl{UpdaterService: updaterService}
+	if err := runner.retry(context.Background(), EntityRole, failed); err == nil || updaterService.retryCnt != batchRetryCnt {
+		t.Errorf("Incorrect TestRetry test. retry = %d", updaterService.retryCnt)
+		t.FailNow()
+	}
+}
+
+// Less than stub struct
+// Updater service that fails until retry is called failCnt times
+type stubRetryUpdaterService struct {
+	service.UpdaterService
+	failCnt  int
+	retryCnt int
+}
+
+func (us *stubRetryUpdaterService) Retry(ctx context.Context, failed []cache.FailedBatch) []cache.FailedBatch {
+	us.retryCnt++
+	if us.retryCnt < us.failCnt {
+		return failed
+	}
+	return nil
+}
+
+// Less than stub struct
+// Updater service that records roles
+type stubChangeUpdaterService struct {
+	service.UpdaterService
+	roles []structure.Role
+}
+
+func (us *stubChangeUpdaterService) UpdateRole(ctx context.Context, roles []structure.Role) []cache.FailedBatch {
+	us.roles = append(us.roles, roles...)
+	return nil
+}
+
+func (us *stubChangeUpdaterService) UpdatePolicy(ctx context.Context, policyMap map[string][]structure.UserPolicy) []cache.FailedBatch {
+	return nil
+}
+
+func (us *stubChangeUpdaterService) UpdateUserService(ctx context.Context, serviceMap map[string][]structure.UserService) []cache.FailedBatch {
+	return nil
+}
+
+func (us *stubChangeUpdaterService) UpdateUserGroup(ctx context.Context, groupMap map[string][]structure.UserGroup) []cache.FailedBatch {
+	return nil
+}
+
+// Less than stub struct
+// Extractor service that finds no rows of users
+type stubEmptyExtractorService struct {
+	service.ExtractorService
+}
+
+func (es stubEmptyExtractorService) GetPoliciesByUserUuids(ctx context.Context, userUuids []string) (map[string][]structure.UserPolicy, error) {
+	return map[string][]structure.UserPolicy{}, nil
+}
+
+func (es stubEmptyExtractorService) GetUserServicesByUserUuids(ctx context.Context, userUuids []string) (map[string][]structure.UserService, error) {
+	return map[string][]structure.UserService{}, nil
+}
+
+func (es stubEmptyExtractorService) GetUserGroupsByUserUuids(ctx context.Context, userUuids []string) (map[string][]structure.UserGroup, error) {
+	return map[string][]structure.UserGroup{}, nil
+}
+
+// Less than stub struct
+// Etcd client that records deleted keys
+type stubDeleteEtcdClient struct {
+	cache.EtcdClient
+	mutex   sync.Mutex
+	deleted []string
+}
+
+func (e *stubDeleteEtcdClient) DeleteKeys(ctx context.Context, keys []string) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	e.deleted = append(e.deleted, keys...)
+}
+
+// Less than stub struct
+// Binlog repository that returns users of changes
+type stubBinlogRepository struct {
+	driver.BinlogRepository
+	userUuids []string
+	found     []string
+	err       error
+}
+
+func (br *stubBinlogRepository) FindUserUuidsByUserGroupUuids(ctx context.Context, userGroupUuids []string) ([]string, error) {
+	br.found = append(br.found, userGroupUuids...)
+	return br.userUuids, br.err
+}
+
+func (br *stubBinlogRepository) FindUserUuidsByRoleUuids(ctx context.Context, roleUuids []string) ([]string, error) {
+	br.found = append(br.found, roleUuids...)
+	return br.userUuids, br.err
 }
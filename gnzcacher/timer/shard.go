@@ -0,0 +1,276 @@
+package timer
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/tomoyane/grant-n-z/gnz/cache"
+	"github.com/tomoyane/grant-n-z/gnz/log"
+)
+
+// Runner that owns shards of user uuid space
+type ShardedRunner interface {
+	// Shards that this replica owns
+	OwnedShards() []string
+}
+
+// Runner that updates only the shards this replica owns
+// Shards are assigned to members by cache.AssignShards, and claimed or released at the start of each Run
+// A shard is synced by its previous owner until the owner releases it, so that a new member claims it at a later cycle
+// Roles, permissions and services are not split, and the owner of the first shard updates them
+type ShardRunnerImpl struct {
+	RunnerImpl
+	Membership cache.Membership
+	owned      *ownedShards
+}
+
+// Shards owned in the last rebalance
+type ownedShards struct {
+	mutex  sync.Mutex
+	shards []string
+}
+
+// Update one page of a user entity
+// It returns the user uuids of the page in shard, and the last user uuid of the page
+type userPageFunc func(ctx context.Context, shard string, afterUserUuid string) ([]string, string, error)
+
+func NewShardRunner() Runner {
+	return NewShardRunnerWithMembership(newRunnerImpl(), cache.GetMembershipInstance())
+}
+
+// Constructor with membership
+func NewShardRunnerWithMembership(runner RunnerImpl, membership cache.Membership) ShardRunnerImpl {
+	return ShardRunnerImpl{
+		RunnerImpl: runner,
+		Membership: membership,
+		owned:      &ownedShards{},
+	}
+}
+
+func (r ShardRunnerImpl) Run(ctx context.Context) RunResult {
+	shards, err := r.rebalance(ctx)
+	if err != nil {
+		log.Logger.Error(fmt.Sprintf("Failed to rebalance shards. err = %s", err.Error()))
+		return errorResult(Entities, err.Error())
+	}
+	return r.run(ctx, r.executes(shards))
+}
+
+func (r ShardRunnerImpl) RunEntity(ctx context.Context, entity string) RunResult {
+	if _, ok := r.RunnerImpl.executes()[entity]; !ok {
+		return errorResult([]string{entity}, "Unknown entity")
+	}
+	execute, ok := r.executes(r.OwnedShards())[entity]
+	if !ok {
+		return errorResult([]string{entity}, fmt.Sprintf("%s is updated by the owner of shard %s", entity, cache.Shards[0]))
+	}
+	return r.run(ctx, map[string]executeFunc{entity: execute})
+}
+
+func (r ShardRunnerImpl) RunUser(ctx context.Context, userUuid string) RunResult {
+	shard := cache.ShardOf(userUuid)
+	if !containsShard(r.OwnedShards(), shard) {
+		return errorResult([]string{EntityPolicy, EntityUserService, EntityUserGroup}, fmt.Sprintf("Shard %s of user is owned by another replica", shard))
+	}
+	return r.runUsers(r.Membership.Guard(ctx, shard), []string{userUuid})
+}
+
+// Binlog mode follows all changes in one replica, so that it is not sharded
+func (r ShardRunnerImpl) RunChanges(ctx context.Context, changes *Changes) RunResult {
+	return errorResult(Entities, "Binlog changes are not supported in shard mode")
+}
+
+func (r ShardRunnerImpl) OwnedShards() []string {
+	r.owned.mutex.Lock()
+	defer r.owned.mutex.Unlock()
+	shards := make([]string, len(r.owned.shards))
+	copy(shards, r.owned.shards)
+	return shards
+}
+
+// Claim shards assigned to this replica, and release the others
+// A shard that another replica still owns is claimed at the next cycle
+func (r ShardRunnerImpl) rebalance(ctx context.Context) ([]string, error) {
+	shards, err := r.claimShards(ctx)
+	r.owned.mutex.Lock()
+	r.owned.shards = shards
+	r.owned.mutex.Unlock()
+	return shards, err
+}
+
+func (r ShardRunnerImpl) claimShards(ctx context.Context) ([]string, error) {
+	if err := r.Membership.Join(ctx); err != nil {
+		return nil, err
+	}
+	members, err := r.Membership.Members(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	assigned := cache.AssignShards(members, r.Membership.Id())
+	var shards []string
+	for _, shard := range cache.Shards {
+		if !containsShard(assigned, shard) {
+			if err := r.Membership.Release(ctx, shard); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		claimed, err := r.Membership.Claim(ctx, shard)
+		if err != nil {
+			return nil, err
+		}
+		if !claimed {
+			log.Logger.Info(fmt.Sprintf("Shard %s is still owned by another replica", shard))
+			continue
+		}
+		shards = append(shards, shard)
+	}
+	log.Logger.Info(fmt.Sprintf("Owned shards = %v, members = %d", shards, len(members)))
+	return shards, nil
+}
+
+// Update functions of owned shards
+func (r ShardRunnerImpl) executes(shards []string) map[string]executeFunc {
+	executes := map[string]executeFunc{
+		EntityPolicy:      r.executeShards(shards, cache.UserPolicyKeyPrefix, r.updatePolicyPage),
+		EntityUserService: r.executeShards(shards, cache.UserServiceKeyPrefix, r.updateUserServicePage),
+		EntityUserGroup:   r.executeShards(shards, cache.UserGroupKeyPrefix, r.updateUserGroupPage),
+	}
+	if containsShard(shards, cache.Shards[0]) {
+		executes[EntityPermission] = r.guard(cache.Shards[0], r.executePermission)
+		executes[EntityRole] = r.guard(cache.Shards[0], r.executeRole)
+		executes[EntityService] = r.guard(cache.Shards[0], r.executeService)
+	}
+	return executes
+}
+
+// Execute with the context that writes only while this replica owns shard
+func (r ShardRunnerImpl) guard(shard string, execute executeFunc) executeFunc {
+	return func(ctx context.Context) (int, error) {
+		return execute(r.Membership.Guard(ctx, shard))
+	}
+}
+
+// Update a user entity shard by shard
+func (r ShardRunnerImpl) executeShards(shards []string, prefix string, update userPageFunc) executeFunc {
+	return func(ctx context.Context) (int, error) {
+		rows := 0
+		for _, shard := range shards {
+			cnt, err := r.executeShard(r.Membership.Guard(ctx, shard), shard, prefix, update)
+			rows += cnt
+			if err != nil {
+				return rows, err
+			}
+		}
+		return rows, nil
+	}
+}
+
+// Update a user entity of one shard, and prune orphan keys of the shard
+// Uuids of the shard are greater than the shard itself, so that pages start from it and end at a user of the next shard
+func (r ShardRunnerImpl) executeShard(ctx context.Context, shard string, prefix string, update userPageFunc) (int, error) {
+	liveIds := make(map[string]bool)
+	afterUserUuid := shard
+	for ctx.Err() == nil {
+		userUuids, lastUserUuid, err := update(ctx, shard, afterUserUuid)
+		if err != nil {
+			return len(liveIds), err
+		}
+		for _, userUuid := range userUuids {
+			liveIds[userUuid] = true
+		}
+		log.Logger.Info(fmt.Sprintf("Update %s of shard %s length = %d", prefix, shard, len(userUuids)))
+		if lastUserUuid == "" || cache.ShardOf(lastUserUuid) != shard {
+			break
+		}
+		afterUserUuid = lastUserUuid
+	}
+
+	if ctx.Err() != nil {
+		log.Logger.Warn(fmt.Sprintf("Skip pruning %s of shard %s. Update cache cycle was cancelled", prefix, shard))
+		return len(liveIds), ctx.Err()
+	}
+	r.PrunerService.PruneShard(ctx, prefix, shard, liveIds)
+	return len(liveIds), nil
+}
+
+func (r ShardRunnerImpl) updatePolicyPage(ctx context.Context, shard string, afterUserUuid string) ([]string, string, error) {
+	policies, lastUserUuid, err := r.ExtractorService.GetPolicies(ctx, afterUserUuid, limit)
+	if err != nil {
+		return nil, "", err
+	}
+	var userUuids []string
+	for userUuid := range policies {
+		if cache.ShardOf(userUuid) != shard {
+			delete(policies, userUuid)
+			continue
+		}
+		userUuids = append(userUuids, userUuid)
+	}
+	if err := r.retry(ctx, EntityPolicy, r.UpdaterService.UpdatePolicy(ctx, policies)); err != nil {
+		return nil, "", err
+	}
+	return userUuids, lastUserUuid, nil
+}
+
+func (r ShardRunnerImpl) updateUserServicePage(ctx context.Context, shard string, afterUserUuid string) ([]string, string, error) {
+	userServices, lastUserUuid, err := r.ExtractorService.GetUserServices(ctx, afterUserUuid, limit)
+	if err != nil {
+		return nil, "", err
+	}
+	var userUuids []string
+	for userUuid := range userServices {
+		if cache.ShardOf(userUuid) != shard {
+			delete(userServices, userUuid)
+			continue
+		}
+		userUuids = append(userUuids, userUuid)
+	}
+	if err := r.retry(ctx, EntityUserService, r.UpdaterService.UpdateUserService(ctx, userServices)); err != nil {
+		return nil, "", err
+	}
+	return userUuids, lastUserUuid, nil
+}
+
+func (r ShardRunnerImpl) updateUserGroupPage(ctx context.Context, shard string, afterUserUuid string) ([]string, string, error) {
+	userGroups, lastUserUuid, err := r.ExtractorService.GetUserGroups(ctx, afterUserUuid, limit)
+	if err != nil {
+		return nil, "", err
+	}
+	var userUuids []string
+	for userUuid := range userGroups {
+		if cache.ShardOf(userUuid) != shard {
+			delete(userGroups, userUuid)
+			continue
+		}
+		userUuids = append(userUuids, userUuid)
+	}
+	if err := r.retry(ctx, EntityUserGroup, r.UpdaterService.UpdateUserGroup(ctx, userGroups)); err != nil {
+		return nil, "", err
+	}
+	return userUuids, lastUserUuid, nil
+}
+
+// Result that every entity failed with err
+func errorResult(entities []string, err string) RunResult {
+	result := RunResult{
+		Rows:   make(map[string]int),
+		Errors: make(map[string]string),
+	}
+	for _, entity := range entities {
+		result.Errors[entity] = err
+	}
+	return result
+}
+
+func containsShard(shards []string, shard string) bool {
+	for _, s := range shards {
+		if s == shard {
+			return true
+		}
+	}
+	return false
+}
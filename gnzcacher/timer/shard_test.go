@@ -0,0 +1,290 @@
+package timer
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/tomoyane/grant-n-z/gnz/cache"
+	"github.com/tomoyane/grant-n-z/gnz/cache/structure"
+	"github.com/tomoyane/grant-n-z/gnz/common"
+	"github.com/tomoyane/grant-n-z/gnzcacher/service"
+)
+
+// Test run only owned shards
+func TestShardRun(t *testing.T) {
+	membership := newStubMembership("member-a", "member-a", "member-b")
+	membership.owners["2"] = "member-b"
+	updaterService := newStubShardUpdaterService()
+	prunerService := newStubShardPrunerService()
+	runner := NewShardRunnerWithMembership(RunnerImpl{
+		UpdaterService:   updaterService,
+		ExtractorService: &stubShardExtractorService{userUuids: []string{"0a", "1a", "2a", "4a", "4b"}},
+		PrunerService:    prunerService,
+	}, membership)
+
+	result := runner.Run(context.Background())
+	if len(result.Errors) != 0 || result.Rows[EntityPolicy] != 3 {
+		t.Errorf("Incorrect TestShardRun test. rows = %v, errors = %v", result.Rows, result.Errors)
+		t.FailNow()
+	}
+
+	// Shard 2 is assigned, but another replica has not released it yet
+	shards := runner.OwnedShards()
+	if len(shards) != 7 || shards[0] != "0" || shards[1] != "4" || membership.owners["2"] != "member-b" {
+		t.Errorf("Incorrect TestShardRun test. shards = %v", shards)
+		t.FailNow()
+	}
+
+	// Users of other shards are not written, and writes have the guard of their shard
+	if len(updaterService.policies) != 3 || updaterService.policies["0a"] != "0" || updaterService.policies["4b"] != "4" {
+		t.Errorf("Incorrect TestShardRun test. policies = %v", updaterService.policies)
+		t.FailNow()
+	}
+	if updaterService.roles != "0" {
+		t.Errorf("Incorrect TestShardRun test. roles = %s", updaterService.roles)
+		t.FailNow()
+	}
+
+	pruned := prunerService.pruned[cache.UserPolicyKeyPrefix+"4"]
+	if len(pruned) != 2 || !pruned["4a"] || prunerService.pruned[cache.UserPolicyKeyPrefix+"6"] == nil || prunerService.pruned[cache.UserPolicyKeyPrefix+"2"] != nil {
+		t.Errorf("Incorrect TestShardRun test. pruned = %v", prunerService.pruned)
+		t.FailNow()
+	}
+
+	updateTimer := NewUpdateTimerWithConfig(common.CacherConfig{}, newFakeClock(), runner)
+	if status := updateTimer.GetStatus(); len(status.Shards) != 7 {
+		t.Errorf("Incorrect TestShardRun test. status shards = %v", status.Shards)
+		t.FailNow()
+	}
+}
+
+// Test replica that does not own the first shard does not update roles, permissions and services
+func TestShardRun_NotFirstShard(t *testing.T) {
+	membership := newStubMembership("member-b", "member-a", "member-b")
+	membership.owners["0"] = "member-b"
+	updaterService := newStubShardUpdaterService()
+	runner := NewShardRunnerWithMembership(RunnerImpl{
+		UpdaterService:   updaterService,
+		ExtractorService: &stubShardExtractorService{userUuids: []string{"0a", "1a"}},
+		PrunerService:    newStubShardPrunerService(),
+	}, membership)
+
+	result := runner.Run(context.Background())
+	if _, ok := result.Rows[EntityRole]; ok || len(result.Errors) != 0 || updaterService.roles != "" {
+		t.Errorf("Incorrect TestShardRun_NotFirstShard test. rows = %v, errors = %v", result.Rows, result.Errors)
+		t.FailNow()
+	}
+
+	// Shard 0 that was assigned to another member is released
+	if _, ok := membership.owners["0"]; ok || len(updaterService.policies) != 1 || updaterService.policies["1a"] != "1" {
+		t.Errorf("Incorrect TestShardRun_NotFirstShard test. owners = %v, policies = %v", membership.owners, updaterService.policies)
+		t.FailNow()
+	}
+
+	result = runner.RunEntity(context.Background(), EntityRole)
+	if result.Errors[EntityRole] == "" {
+		t.Errorf("Incorrect TestShardRun_NotFirstShard test. errors = %v", result.Errors)
+		t.FailNow()
+	}
+
+	result = runner.RunUser(context.Background(), "0a")
+	if len(result.Errors) != 3 || result.Errors[EntityPolicy] == "" {
+		t.Errorf("Incorrect TestShardRun_NotFirstShard test. errors = %v", result.Errors)
+		t.FailNow()
+	}
+}
+
+// Test run when membership failed
+func TestShardRun_JoinError(t *testing.T) {
+	membership := newStubMembership("member-a", "member-a")
+	membership.err = errors.New("failed")
+	runner := NewShardRunnerWithMembership(RunnerImpl{}, membership)
+
+	result := runner.Run(context.Background())
+	if len(result.Errors) != len(Entities) || result.Errors[EntityPolicy] != "failed" || len(runner.OwnedShards()) != 0 {
+		t.Errorf("Incorrect TestShardRun_JoinError test. errors = %v", result.Errors)
+		t.FailNow()
+	}
+}
+
+// Key of shard of stub guard
+type stubShardKey struct{}
+
+func shardOfContext(ctx context.Context) string {
+	shard, _ := ctx.Value(stubShardKey{}).(string)
+	return shard
+}
+
+// Less than stub struct
+// Membership with owners of shards in memory
+type stubMembership struct {
+	cache.Membership
+	id      string
+	members []string
+	owners  map[string]string
+	err     error
+}
+
+func newStubMembership(id string, members ...string) *stubMembership {
+	return &stubMembership{id: id, members: members, owners: make(map[string]string)}
+}
+
+func (m *stubMembership) Join(ctx context.Context) error {
+	return m.err
+}
+
+func (m *stubMembership) Id() string {
+	return m.id
+}
+
+func (m *stubMembership) Members(ctx context.Context) ([]string, error) {
+	return m.members, nil
+}
+
+func (m *stubMembership) Claim(ctx context.Context, shard string) (bool, error) {
+	if owner, ok := m.owners[shard]; ok && owner != m.id {
+		return false, nil
+	}
+	m.owners[shard] = m.id
+	return true, nil
+}
+
+func (m *stubMembership) Release(ctx context.Context, shard string) error {
+	if m.owners[shard] == m.id {
+		delete(m.owners, shard)
+	}
+	return nil
+}
+
+func (m *stubMembership) Guard(ctx context.Context, shard string) context.Context {
+	return context.WithValue(ctx, stubShardKey{}, shard)
+}
+
+// Less than stub struct
+// Extractor service that returns pages of sorted user uuids
+type stubShardExtractorService struct {
+	service.ExtractorService
+	userUuids []string
+}
+
+func (es *stubShardExtractorService) page(afterUserUuid string) ([]string, string) {
+	index := sort.SearchStrings(es.userUuids, afterUserUuid)
+	if index < len(es.userUuids) && es.userUuids[index] == afterUserUuid {
+		index++
+	}
+	page := es.userUuids[index:]
+	if len(page) == 0 {
+		return nil, ""
+	}
+	return page, page[len(page)-1]
+}
+
+func (es *stubShardExtractorService) GetPolicies(ctx context.Context, afterUserUuid string, limit int) (map[string][]structure.UserPolicy, string, error) {
+	page, last := es.page(afterUserUuid)
+	policies := make(map[string][]structure.UserPolicy)
+	for _, userUuid := range page {
+		policies[userUuid] = []structure.UserPolicy{}
+	}
+	return policies, last, nil
+}
+
+func (es *stubShardExtractorService) GetUserServices(ctx context.Context, afterUserUuid string, limit int) (map[string][]structure.UserService, string, error) {
+	page, last := es.page(afterUserUuid)
+	userServices := make(map[string][]structure.UserService)
+	for _, userUuid := range page {
+		userServices[userUuid] = []structure.UserService{}
+	}
+	return userServices, last, nil
+}
+
+func (es *stubShardExtractorService) GetUserGroups(ctx context.Context, afterUserUuid string, limit int) (map[string][]structure.UserGroup, string, error) {
+	page, last := es.page(afterUserUuid)
+	userGroups := make(map[string][]structure.UserGroup)
+	for _, userUuid := range page {
+		userGroups[userUuid] = []structure.UserGroup{}
+	}
+	return userGroups, last, nil
+}
+
+func (es *stubShardExtractorService) GetPermissions(ctx context.Context, offset int, limit int) ([]structure.Permission, error) {
+	return nil, nil
+}
+
+func (es *stubShardExtractorService) GetRoles(ctx context.Context, offset int, limit int) ([]structure.Role, error) {
+	return nil, nil
+}
+
+func (es *stubShardExtractorService) GetServices(ctx context.Context, offset int, limit int) ([]structure.Service, error) {
+	return nil, nil
+}
+
+// Less than stub struct
+// Updater service that records shard of guard of updated policies and roles
+type stubShardUpdaterService struct {
+	service.UpdaterService
+	mutex    sync.Mutex
+	policies map[string]string
+	roles    string
+}
+
+func newStubShardUpdaterService() *stubShardUpdaterService {
+	return &stubShardUpdaterService{policies: make(map[string]string)}
+}
+
+func (us *stubShardUpdaterService) UpdatePolicy(ctx context.Context, policyMap map[string][]structure.UserPolicy) []cache.FailedBatch {
+	us.mutex.Lock()
+	defer us.mutex.Unlock()
+	for userUuid := range policyMap {
+		us.policies[userUuid] = shardOfContext(ctx)
+	}
+	return nil
+}
+
+func (us *stubShardUpdaterService) UpdateUserService(ctx context.Context, serviceMap map[string][]structure.UserService) []cache.FailedBatch {
+	return nil
+}
+
+func (us *stubShardUpdaterService) UpdateUserGroup(ctx context.Context, groupMap map[string][]structure.UserGroup) []cache.FailedBatch {
+	return nil
+}
+
+func (us *stubShardUpdaterService) UpdatePermission(ctx context.Context, permissions []structure.Permission) []cache.FailedBatch {
+	return nil
+}
+
+func (us *stubShardUpdaterService) UpdateRole(ctx context.Context, roles []structure.Role) []cache.FailedBatch {
+	us.mutex.Lock()
+	defer us.mutex.Unlock()
+	us.roles = shardOfContext(ctx)
+	return nil
+}
+
+func (us *stubShardUpdaterService) UpdateService(ctx context.Context, services []structure.Service) []cache.FailedBatch {
+	return nil
+}
+
+// Less than stub struct
+// Pruner service that records live ids of each shard
+type stubShardPrunerService struct {
+	service.PrunerService
+	mutex  sync.Mutex
+	pruned map[string]map[string]bool
+}
+
+func newStubShardPrunerService() *stubShardPrunerService {
+	return &stubShardPrunerService{pruned: make(map[string]map[string]bool)}
+}
+
+func (ps *stubShardPrunerService) Prune(ctx context.Context, prefix string, liveIds map[string]bool) int {
+	return 0
+}
+
+func (ps *stubShardPrunerService) PruneShard(ctx context.Context, prefix string, shard string, liveIds map[string]bool) int {
+	ps.mutex.Lock()
+	defer ps.mutex.Unlock()
+	ps.pruned[prefix+shard] = liveIds
+	return 0
+}
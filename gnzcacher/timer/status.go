@@ -0,0 +1,106 @@
+package timer
+
+import (
+	"sync"
+	"time"
+)
+
+// Status of update cache cycles
+type Status struct {
+	// This replica follows binlog in binlog mode. Every replica updates cache as leader in the other modes
+	Leader bool `json:"leader"`
+
+	// Update mode. `poll`, `binlog` or `shard`
+	Mode string `json:"mode"`
+
+	// Shards that this replica owns. It is empty except in shard mode
+	Shards []string `json:"shards,omitempty"`
+
+	// Binlog position that cache has been updated to. It is empty in poll mode
+	BinlogPosition string `json:"binlog_position,omitempty"`
+
+	// A cycle or a resync is running
+	Running bool `json:"running"`
+
+	// A resync is waiting for the running cycle
+	ResyncPending bool `json:"resync_pending"`
+
+	// Target of the last run. `all`, `entity={name}` or `user={uuid}`
+	LastTarget string `json:"last_target"`
+
+	// Start time of the last run. It is nil until the first run
+	LastRunAt *time.Time `json:"last_run_at"`
+
+	// Duration of the last finished run
+	LastDurationMillis int64 `json:"last_duration_millis"`
+
+	// Updated keys and errors of the last finished run
+	RunResult
+}
+
+// Status shared between the timer loop and readers
+type statusHolder struct {
+	mutex  sync.Mutex
+	status Status
+}
+
+func newStatusHolder(mode string) *statusHolder {
+	return &statusHolder{
+		status: Status{
+			Leader: true,
+			Mode:   mode,
+			RunResult: RunResult{
+				Rows:   map[string]int{},
+				Errors: map[string]string{},
+			},
+		},
+	}
+}
+
+func (s *statusHolder) start(target string, now time.Time) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.status.Running = true
+	s.status.LastTarget = target
+	s.status.LastRunAt = &now
+}
+
+func (s *statusHolder) finish(result RunResult, duration time.Duration) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.status.Running = false
+	s.status.LastDurationMillis = int64(duration / time.Millisecond)
+	s.status.RunResult = result
+}
+
+// Set leader. It returns true if it changed
+func (s *statusHolder) setLeader(leader bool) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	changed := s.status.Leader != leader
+	s.status.Leader = leader
+	return changed
+}
+
+func (s *statusHolder) setBinlogPosition(position string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.status.BinlogPosition = position
+}
+
+// Copy of current status
+func (s *statusHolder) get() Status {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	status := s.status
+	status.Rows = make(map[string]int, len(s.status.Rows))
+	for entity, rows := range s.status.Rows {
+		status.Rows[entity] = rows
+	}
+	status.Errors = make(map[string]string, len(s.status.Errors))
+	for entity, err := range s.status.Errors {
+		status.Errors[entity] = err
+	}
+	return status
+}
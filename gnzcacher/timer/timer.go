@@ -1,44 +1,125 @@
 package timer
 
 import (
+	"context"
+	"fmt"
+	"math/rand"
 	"time"
 
+	"github.com/tomoyane/grant-n-z/gnz/common"
 	"github.com/tomoyane/grant-n-z/gnz/log"
 )
 
+// Default interval when cacher.time-millis is not set
+const defaultInterval = 5 * time.Minute
+
+// Update modes of cacher.mode
+const (
+	ModePoll   = "poll"
+	ModeBinlog = "binlog"
+	ModeShard  = "shard"
+)
+
 // UpdateTimer interface
 type UpdateTimer interface {
 	// Start update cache timer
+	// The first cycle runs immediately, and the next one is scheduled after it finished
 	Start(exitCode chan int) int
 
 	// Stop update cache timer
 	Stop()
+
+	// Request resync
+	// It runs between the scheduled cycles, so that it never overlaps with them
+	// Returns false if another resync is pending
+	Resync(request ResyncRequest) bool
+
+	// Get status of the last run
+	GetStatus() Status
+}
+
+// Target of resync
+// If UserUuid is set, data of the user is updated. Else if Entity is set, the entity is updated. Otherwise all entities are updated
+type ResyncRequest struct {
+	Entity   string `json:"entity"`
+	UserUuid string `json:"user_uuid"`
 }
 
 // UpdateTimer struct
 type UpdateTimerImpl struct {
-	Ticker *time.Ticker
-	Runner Runner
+	Clock    Clock
+	Runner   Runner
+	Interval time.Duration
+	Jitter   time.Duration
+	Timeout  time.Duration
+	stop     chan struct{}
+	resync   chan ResyncRequest
+	status   *statusHolder
 }
 
 // Constructor
+// In binlog mode, cache is updated by binlog of MySQL instead of polling
+// In shard mode, cache is updated by polling, and replicas split users between them
 func NewUpdateTimer() UpdateTimer {
+	switch common.GCacher.Mode {
+	case ModeBinlog:
+		return NewBinlogUpdateTimer()
+	case ModeShard:
+		updateTimer := NewUpdateTimerWithConfig(common.GCacher, NewClock(), NewShardRunner())
+		updateTimer.status = newStatusHolder(ModeShard)
+		return updateTimer
+	}
+	return NewUpdateTimerWithConfig(common.GCacher, NewClock(), NewRunner())
+}
+
+// Constructor with cacher config
+// If time-millis is empty, interval is 5 minutes. If timeout-millis is empty, timeout is the interval
+func NewUpdateTimerWithConfig(cacherConfig common.CacherConfig, clock Clock, runner Runner) UpdateTimerImpl {
+	interval := time.Duration(cacherConfig.TimeMillis) * time.Millisecond
+	if interval <= 0 {
+		interval = defaultInterval
+	}
+
+	jitter := time.Duration(cacherConfig.JitterMillis) * time.Millisecond
+	if jitter < 0 {
+		jitter = 0
+	}
+
+	timeout := time.Duration(cacherConfig.TimeoutMillis) * time.Millisecond
+	if timeout <= 0 {
+		timeout = interval
+	}
+
 	return UpdateTimerImpl{
-		Ticker: time.NewTicker(5 * time.Minute),
-		Runner: NewRunner(),
+		Clock:    clock,
+		Runner:   runner,
+		Interval: interval,
+		Jitter:   jitter,
+		Timeout:  timeout,
+		stop:     make(chan struct{}, 1),
+		resync:   make(chan ResyncRequest, 1),
+		status:   newStatusHolder(ModePoll),
 	}
 }
 
 func (ut UpdateTimerImpl) Start(exitCode chan int) int {
+	log.Logger.Info(fmt.Sprintf("Start update cache timer. interval = %v, jitter = %v, timeout = %v", ut.Interval, ut.Jitter, ut.Timeout))
+
 	code := 0
+	next := ut.Clock.After(0)
 loop:
 	for {
 		select {
-		case <-ut.Ticker.C:
-			ut.Runner.Run()
+		case <-next:
+			ut.runCycle("all", ut.Runner.Run)
+			next = ut.Clock.After(ut.nextDelay())
+		case request := <-ut.resync:
+			ut.runResync(request)
+		case <-ut.stop:
+			log.Logger.Info("Stop update cache loop")
+			break loop
 		case c := <-exitCode:
 			log.Logger.Info("Break update cache loop")
-			ut.Ticker.Stop()
 			code = c
 			break loop
 		}
@@ -49,5 +130,80 @@ loop:
 }
 
 func (ut UpdateTimerImpl) Stop() {
-	ut.Ticker.Stop()
+	select {
+	case ut.stop <- struct{}{}:
+	default:
+	}
+}
+
+func (ut UpdateTimerImpl) Resync(request ResyncRequest) bool {
+	select {
+	case ut.resync <- request:
+		return true
+	default:
+		return false
+	}
+}
+
+func (ut UpdateTimerImpl) GetStatus() Status {
+	status := ut.status.get()
+	status.ResyncPending = len(ut.resync) > 0
+	if runner, ok := ut.Runner.(ShardedRunner); ok {
+		status.Shards = runner.OwnedShards()
+	}
+	return status
+}
+
+// Run resync for the target of request
+func (ut UpdateTimerImpl) runResync(request ResyncRequest) {
+	log.Logger.Info(fmt.Sprintf("Resync. entity = %s, user_uuid = %s", request.Entity, request.UserUuid))
+	switch {
+	case request.UserUuid != "":
+		ut.runCycle("user="+request.UserUuid, func(ctx context.Context) RunResult {
+			return ut.Runner.RunUser(ctx, request.UserUuid)
+		})
+	case request.Entity != "":
+		ut.runCycle("entity="+request.Entity, func(ctx context.Context) RunResult {
+			return ut.Runner.RunEntity(ctx, request.Entity)
+		})
+	default:
+		ut.runCycle("all", ut.Runner.Run)
+	}
+}
+
+// Run one cycle and wait for it
+// When the cycle exceeds timeout, it is cancelled and the timer waits until runner returns
+func (ut UpdateTimerImpl) runCycle(target string, run func(ctx context.Context) RunResult) RunResult {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	startedAt := ut.Clock.Now()
+	ut.status.start(target, startedAt)
+
+	done := make(chan RunResult, 1)
+	go func() {
+		done <- run(ctx)
+	}()
+
+	var result RunResult
+	select {
+	case result = <-done:
+	case <-ut.Clock.After(ut.Timeout):
+		log.Logger.Warn(fmt.Sprintf("Update cache cycle exceeded timeout %v. Cancel it", ut.Timeout))
+		cancel()
+		result = <-done
+	}
+
+	duration := ut.Clock.Now().Sub(startedAt)
+	ut.status.finish(result, duration)
+	log.Logger.Info(fmt.Sprintf("Finished update cache cycle. target = %s, duration = %v", target, duration))
+	return result
+}
+
+// Interval with random jitter
+func (ut UpdateTimerImpl) nextDelay() time.Duration {
+	if ut.Jitter <= 0 {
+		return ut.Interval
+	}
+	return ut.Interval + time.Duration(rand.Int63n(int64(ut.Jitter)))
 }
@@ -1,6 +1,9 @@
 package timer
 
 import (
+	"context"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -9,6 +12,7 @@ import (
 	"github.com/jinzhu/gorm"
 	_ "github.com/mattn/go-sqlite3"
 	"github.com/tomoyane/grant-n-z/gnz/cache"
+	"github.com/tomoyane/grant-n-z/gnz/common"
 	"github.com/tomoyane/grant-n-z/gnz/driver"
 	"github.com/tomoyane/grant-n-z/gnz/log"
 	"github.com/tomoyane/grant-n-z/gnzcacher/service"
@@ -44,25 +48,371 @@ func init() {
 	}
 
 	updaterService = service.UpdaterServiceImpl{EtcdClient: etcdClient}
+	prunerService = service.NewPrunerServiceWithMode(etcdClient, service.PruneModeDelete)
 	runner = RunnerImpl{
 		UpdaterService:   updaterService,
 		ExtractorService: extractorService,
+		PrunerService:    prunerService,
+	}
+}
+
+// Fake clock that only moves when Advance is called
+type fakeClock struct {
+	mu      sync.Mutex
+	now     time.Time
+	waiters []fakeWaiter
+}
+
+type fakeWaiter struct {
+	at time.Time
+	c  chan time.Time
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)}
+}
+
+func (f *fakeClock) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.now
+}
+
+func (f *fakeClock) After(d time.Duration) <-chan time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	c := make(chan time.Time, 1)
+	if d <= 0 {
+		c <- f.now
+		return c
+	}
+	f.waiters = append(f.waiters, fakeWaiter{at: f.now.Add(d), c: c})
+	return c
+}
+
+// Move the clock forward and fire the expired waiters
+func (f *fakeClock) Advance(d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.now = f.now.Add(d)
+	var waiters []fakeWaiter
+	for _, w := range f.waiters {
+		if !w.at.After(f.now) {
+			w.c <- f.now
+		} else {
+			waiters = append(waiters, w)
+		}
+	}
+	f.waiters = waiters
+}
+
+// Block until a waiter fires after d from now
+func (f *fakeClock) waitWaiter(t *testing.T, d time.Duration) {
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		f.mu.Lock()
+		for _, w := range f.waiters {
+			if w.at.Equal(f.now.Add(d)) {
+				f.mu.Unlock()
+				return
+			}
+		}
+		f.mu.Unlock()
+		time.Sleep(time.Millisecond)
+	}
+	t.Errorf("Timed out waiting for clock waiter after %v", d)
+	t.FailNow()
+}
+
+// Runner that records how it was called
+type stubRunner struct {
+	runs      chan struct{}
+	targets   chan string
+	release   chan struct{}
+	cancelled chan struct{}
+	running   *int32
+	overlap   *int32
+}
+
+func newStubRunner(blocking bool) stubRunner {
+	r := stubRunner{
+		runs:      make(chan struct{}, 10),
+		targets:   make(chan string, 10),
+		cancelled: make(chan struct{}, 10),
+		running:   new(int32),
+		overlap:   new(int32),
+	}
+	if blocking {
+		r.release = make(chan struct{})
+	}
+	return r
+}
+
+func (r stubRunner) Run(ctx context.Context) RunResult {
+	if atomic.AddInt32(r.running, 1) > 1 {
+		atomic.StoreInt32(r.overlap, 1)
+	}
+	defer atomic.AddInt32(r.running, -1)
+
+	r.runs <- struct{}{}
+	if r.release == nil {
+		return RunResult{Rows: map[string]int{EntityRole: 1}, Errors: map[string]string{}}
+	}
+	select {
+	case <-r.release:
+		return RunResult{Rows: map[string]int{EntityRole: 1}, Errors: map[string]string{}}
+	case <-ctx.Done():
+		r.cancelled <- struct{}{}
+		return RunResult{Rows: map[string]int{}, Errors: map[string]string{EntityRole: ctx.Err().Error()}}
+	}
+}
+
+func (r stubRunner) RunEntity(ctx context.Context, entity string) RunResult {
+	r.targets <- "entity=" + entity
+	return RunResult{Rows: map[string]int{entity: 1}, Errors: map[string]string{}}
+}
+
+func (r stubRunner) RunUser(ctx context.Context, userUuid string) RunResult {
+	r.targets <- "user=" + userUuid
+	return RunResult{Rows: map[string]int{EntityPolicy: 1}, Errors: map[string]string{}}
+}
+
+func (r stubRunner) RunChanges(ctx context.Context, changes *Changes) RunResult {
+	r.targets <- "changes"
+	return RunResult{Rows: map[string]int{EntityRole: len(changes.Roles)}, Errors: map[string]string{}}
+}
+
+// Wait one run of stub runner
+func waitRun(t *testing.T, r stubRunner) {
+	select {
+	case <-r.runs:
+	case <-time.After(time.Second):
+		t.Errorf("Runner was not called")
+		t.FailNow()
+	}
+}
+
+// Test constructor
+func TestNewUpdateTimerWithConfig(t *testing.T) {
+	updateTimer := NewUpdateTimerWithConfig(common.CacherConfig{}, newFakeClock(), runner)
+	if updateTimer.Interval != 5*time.Minute || updateTimer.Timeout != 5*time.Minute || updateTimer.Jitter != 0 {
+		t.Errorf("Incorrect TestNewUpdateTimerWithConfig test. default = %v, %v, %v", updateTimer.Interval, updateTimer.Timeout, updateTimer.Jitter)
+		t.FailNow()
+	}
+
+	cacherConfig := common.CacherConfig{TimeMillis: 1000, JitterMillis: 100, TimeoutMillis: 500}
+	updateTimer = NewUpdateTimerWithConfig(cacherConfig, newFakeClock(), runner)
+	if updateTimer.Interval != time.Second || updateTimer.Timeout != 500*time.Millisecond || updateTimer.Jitter != 100*time.Millisecond {
+		t.Errorf("Incorrect TestNewUpdateTimerWithConfig test. config = %v, %v, %v", updateTimer.Interval, updateTimer.Timeout, updateTimer.Jitter)
+		t.FailNow()
 	}
 }
 
 // Test start
 func TestStart(t *testing.T) {
-	updateTimer := UpdateTimerImpl{
-		Runner: runner,
-		Ticker: time.NewTicker(1 * time.Second),
+	clock := newFakeClock()
+	updateTimer := NewUpdateTimerWithConfig(common.CacherConfig{TimeMillis: 1000, TimeoutMillis: 10000}, clock, runner)
+
+	exitCode := make(chan int)
+	result := make(chan int)
+	go func() { result <- updateTimer.Start(exitCode) }()
+
+	clock.waitWaiter(t, time.Second)
+	exitCode <- 1
+
+	if code := <-result; code != 1 {
+		t.Errorf("Incorrect TestStart test. code = %d", code)
+		t.FailNow()
 	}
+}
+
+// Test start runs immediately and then every interval
+func TestStart_RunImmediately(t *testing.T) {
+	clock := newFakeClock()
+	stub := newStubRunner(false)
+	updateTimer := NewUpdateTimerWithConfig(common.CacherConfig{TimeMillis: 1000, TimeoutMillis: 10000}, clock, stub)
 
 	exitCode := make(chan int)
 	go updateTimer.Start(exitCode)
 
-	time.Sleep(2 * time.Second)
+	waitRun(t, stub)
+	clock.waitWaiter(t, time.Second)
 
-	exitCode <- 1
+	clock.Advance(999 * time.Millisecond)
+	select {
+	case <-stub.runs:
+		t.Errorf("Incorrect TestStart_RunImmediately test. Runner was called before interval")
+		t.FailNow()
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	clock.Advance(time.Millisecond)
+	waitRun(t, stub)
 
 	updateTimer.Stop()
 }
+
+// Test a slow cycle delays the next one instead of overlapping
+func TestStart_NoOverlap(t *testing.T) {
+	clock := newFakeClock()
+	stub := newStubRunner(true)
+	updateTimer := NewUpdateTimerWithConfig(common.CacherConfig{TimeMillis: 1000, TimeoutMillis: 10000}, clock, stub)
+
+	exitCode := make(chan int)
+	go updateTimer.Start(exitCode)
+
+	waitRun(t, stub)
+	clock.waitWaiter(t, 10*time.Second)
+
+	// Several intervals pass while the first cycle is still running
+	clock.Advance(3 * time.Second)
+	select {
+	case <-stub.runs:
+		t.Errorf("Incorrect TestStart_NoOverlap test. Runner overlapped")
+		t.FailNow()
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	stub.release <- struct{}{}
+	clock.waitWaiter(t, time.Second)
+	clock.Advance(time.Second)
+	waitRun(t, stub)
+	stub.release <- struct{}{}
+
+	if atomic.LoadInt32(stub.overlap) != 0 {
+		t.Errorf("Incorrect TestStart_NoOverlap test. Runner overlapped")
+		t.FailNow()
+	}
+
+	updateTimer.Stop()
+}
+
+// Test a cycle is cancelled after timeout
+func TestStart_Timeout(t *testing.T) {
+	clock := newFakeClock()
+	stub := newStubRunner(true)
+	updateTimer := NewUpdateTimerWithConfig(common.CacherConfig{TimeMillis: 1000, TimeoutMillis: 500}, clock, stub)
+
+	exitCode := make(chan int)
+	go updateTimer.Start(exitCode)
+
+	waitRun(t, stub)
+	clock.waitWaiter(t, 500*time.Millisecond)
+	clock.Advance(500 * time.Millisecond)
+
+	select {
+	case <-stub.cancelled:
+	case <-time.After(time.Second):
+		t.Errorf("Incorrect TestStart_Timeout test. Cycle was not cancelled")
+		t.FailNow()
+	}
+
+	// Next cycle is scheduled after the cancelled one returned
+	clock.waitWaiter(t, time.Second)
+	clock.Advance(time.Second)
+	waitRun(t, stub)
+	stub.release <- struct{}{}
+
+	updateTimer.Stop()
+}
+
+// Test next delay with jitter
+func TestNextDelay(t *testing.T) {
+	updateTimer := NewUpdateTimerWithConfig(common.CacherConfig{TimeMillis: 1000, JitterMillis: 100}, newFakeClock(), runner)
+	for i := 0; i < 100; i++ {
+		delay := updateTimer.nextDelay()
+		if delay < time.Second || delay >= 1100*time.Millisecond {
+			t.Errorf("Incorrect TestNextDelay test. delay = %v", delay)
+			t.FailNow()
+		}
+	}
+}
+
+// Wait one resync of stub runner
+func waitTarget(t *testing.T, r stubRunner) string {
+	select {
+	case target := <-r.targets:
+		return target
+	case <-time.After(time.Second):
+		t.Errorf("Runner was not called for resync")
+		t.FailNow()
+	}
+	return ""
+}
+
+// Test resync runs for the target of request
+func TestResync(t *testing.T) {
+	clock := newFakeClock()
+	stub := newStubRunner(false)
+	updateTimer := NewUpdateTimerWithConfig(common.CacherConfig{TimeMillis: 1000}, clock, stub)
+
+	exitCode := make(chan int)
+	go updateTimer.Start(exitCode)
+	waitRun(t, stub)
+
+	updateTimer.Resync(ResyncRequest{UserUuid: "uuid"})
+	if target := waitTarget(t, stub); target != "user=uuid" {
+		t.Errorf("Incorrect TestResync test. target = %s", target)
+		t.FailNow()
+	}
+
+	updateTimer.Resync(ResyncRequest{Entity: EntityRole})
+	if target := waitTarget(t, stub); target != "entity=role" {
+		t.Errorf("Incorrect TestResync test. target = %s", target)
+		t.FailNow()
+	}
+
+	updateTimer.Resync(ResyncRequest{})
+	waitRun(t, stub)
+
+	updateTimer.Stop()
+}
+
+// Test resync waits for the running cycle, and only one resync can be pending
+func TestResync_Pending(t *testing.T) {
+	clock := newFakeClock()
+	stub := newStubRunner(true)
+	updateTimer := NewUpdateTimerWithConfig(common.CacherConfig{TimeMillis: 1000}, clock, stub)
+
+	exitCode := make(chan int)
+	go updateTimer.Start(exitCode)
+	waitRun(t, stub)
+
+	if !updateTimer.Resync(ResyncRequest{Entity: EntityRole}) || updateTimer.Resync(ResyncRequest{Entity: EntityRole}) {
+		t.Errorf("Incorrect TestResync_Pending test. Second resync was accepted")
+		t.FailNow()
+	}
+	if status := updateTimer.GetStatus(); !status.Running || !status.ResyncPending {
+		t.Errorf("Incorrect TestResync_Pending test. status = %v", status)
+		t.FailNow()
+	}
+
+	stub.release <- struct{}{}
+	if target := waitTarget(t, stub); target != "entity=role" {
+		t.Errorf("Incorrect TestResync_Pending test. target = %s", target)
+		t.FailNow()
+	}
+
+	updateTimer.Stop()
+}
+
+// Test status of the last run
+func TestGetStatus(t *testing.T) {
+	clock := newFakeClock()
+	stub := newStubRunner(false)
+	updateTimer := NewUpdateTimerWithConfig(common.CacherConfig{TimeMillis: 1000}, clock, stub)
+
+	status := updateTimer.GetStatus()
+	if !status.Leader || status.Running || status.LastRunAt != nil {
+		t.Errorf("Incorrect TestGetStatus test. initial status = %v", status)
+		t.FailNow()
+	}
+
+	updateTimer.runCycle("all", stub.Run)
+	<-stub.runs
+	status = updateTimer.GetStatus()
+	if status.Running || status.LastTarget != "all" || status.LastRunAt == nil || !status.LastRunAt.Equal(clock.Now()) || status.Rows[EntityRole] != 1 {
+		t.Errorf("Incorrect TestGetStatus test. status = %v", status)
+		t.FailNow()
+	}
+}
@@ -65,7 +65,7 @@ func (rmrhi OperatorPolicyImpl) Api(w http.ResponseWriter, r *http.Request) {
 func (rmrhi OperatorPolicyImpl) get(w http.ResponseWriter, r *http.Request) {
 	id := r.URL.Query().Get(entity.OperatorPolicyUserUuid.String())
 
-	roleMemberEntities, err := rmrhi.OperatorPolicyService.Get(id)
+	roleMemberEntities, err := rmrhi.OperatorPolicyService.Get(r.Context(), id)
 	if err != nil {
 		model.WriteError(w, err.ToJson(), err.Code)
 		return
@@ -86,7 +86,7 @@ func (rmrhi OperatorPolicyImpl) post(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	roleMember, err := rmrhi.OperatorPolicyService.Insert(roleMemberEntity)
+	roleMember, err := rmrhi.OperatorPolicyService.Insert(r.Context(), roleMemberEntity)
 	if err != nil {
 		model.WriteError(w, err.ToJson(), err.Code)
 		return
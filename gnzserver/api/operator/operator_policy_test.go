@@ -2,6 +2,7 @@ package operator
 
 import (
 	"bytes"
+	"context"
 	"testing"
 
 	"io/ioutil"
@@ -126,22 +127,22 @@ func (w StubResponseWriter) WriteHeader(code int) {
 type StubOperatorPolicyService struct {
 }
 
-func (ops StubOperatorPolicyService) Get(queryParam string) ([]*entity.OperatorPolicy, *model.ErrorResBody) {
+func (ops StubOperatorPolicyService) Get(ctx context.Context, queryParam string) ([]*entity.OperatorPolicy, *model.ErrorResBody) {
 	return []*entity.OperatorPolicy{}, nil
 }
 
-func (ops StubOperatorPolicyService) GetAll() ([]*entity.OperatorPolicy, *model.ErrorResBody) {
+func (ops StubOperatorPolicyService) GetAll(ctx context.Context) ([]*entity.OperatorPolicy, *model.ErrorResBody) {
 	return []*entity.OperatorPolicy{}, nil
 }
 
-func (ops StubOperatorPolicyService) GetByUserUuid(userUuid string) ([]*entity.OperatorPolicy, *model.ErrorResBody) {
+func (ops StubOperatorPolicyService) GetByUserUuid(ctx context.Context, userUuid string) ([]*entity.OperatorPolicy, *model.ErrorResBody) {
 	return []*entity.OperatorPolicy{}, nil
 }
 
-func (ops StubOperatorPolicyService) GetByUserUuidAndRoleUuid(userUuid string, roleUuid string) (*entity.OperatorPolicy, *model.ErrorResBody) {
+func (ops StubOperatorPolicyService) GetByUserUuidAndRoleUuid(ctx context.Context, userUuid string, roleUuid string) (*entity.OperatorPolicy, *model.ErrorResBody) {
 	return &entity.OperatorPolicy{}, nil
 }
 
-func (ops StubOperatorPolicyService) Insert(policy *entity.OperatorPolicy) (*entity.OperatorPolicy, *model.ErrorResBody) {
+func (ops StubOperatorPolicyService) Insert(ctx context.Context, policy *entity.OperatorPolicy) (*entity.OperatorPolicy, *model.ErrorResBody) {
 	return &entity.OperatorPolicy{}, nil
 }
@@ -63,13 +63,18 @@ func (sh OperatorServiceImpl) Api(w http.ResponseWriter, r *http.Request) {
 }
 
 func (sh OperatorServiceImpl) get(w http.ResponseWriter, r *http.Request) {
-	result, err := sh.Service.GetServices()
+	page, err := middleware.BindPage(w, r)
+	if err != nil {
+		return
+	}
+
+	result, next, err := sh.Service.GetServices(r.Context(), page)
 	if err != nil {
 		model.WriteError(w, err.ToJson(), err.Code)
 		return
 	}
 
-	res, _ := json.Marshal(result)
+	res, _ := json.Marshal(model.PageResponse{Items: result, NextCursor: next})
 	w.WriteHeader(http.StatusOK)
 	w.Write(res)
 }
@@ -85,7 +90,7 @@ func (sh OperatorServiceImpl) post(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	serviceData, err := sh.Service.InsertServiceWithRelationalData(serviceEntity)
+	serviceData, err := sh.Service.InsertServiceWithRelationalData(r.Context(), serviceEntity)
 	if err != nil {
 		model.WriteError(w, err.ToJson(), err.Code)
 		return
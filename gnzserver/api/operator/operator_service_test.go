@@ -2,11 +2,14 @@ package operator
 
 import (
 	"bytes"
+	"context"
 	"net/http"
+	"net/url"
 	"testing"
 
 	"io/ioutil"
 
+	"github.com/tomoyane/grant-n-z/gnz/driver"
 	"github.com/tomoyane/grant-n-z/gnz/entity"
 	"github.com/tomoyane/grant-n-z/gnz/log"
 	"github.com/tomoyane/grant-n-z/gnzserver/model"
@@ -42,7 +45,7 @@ func TestOperatorService_MethodNotAllowed(t *testing.T) {
 // Test get
 func TestOperatorService_Get(t *testing.T) {
 	response := StubResponseWriter{}
-	request := http.Request{Header: http.Header{}, Method: http.MethodGet}
+	request := http.Request{Header: http.Header{}, URL: &url.URL{}, Method: http.MethodGet}
 	operatorService.Api(response, &request)
 
 	if statusCode != http.StatusOK {
@@ -107,31 +110,31 @@ func TestOperatorService_Delete(t *testing.T) {
 type StubService struct {
 }
 
-func (ss StubService) GetServices() ([]*entity.Service, *model.ErrorResBody) {
-	return []*entity.Service{}, nil
+func (ss StubService) GetServices(ctx context.Context, page driver.Page) ([]*entity.Service, string, *model.ErrorResBody) {
+	return []*entity.Service{}, "", nil
 }
 
-func (ss StubService) GetServiceByUuid(uuid string) (*entity.Service, *model.ErrorResBody) {
+func (ss StubService) GetServiceByUuid(ctx context.Context, uuid string) (*entity.Service, *model.ErrorResBody) {
 	return &entity.Service{}, nil
 }
 
-func (ss StubService) GetServiceByName(name string) (*entity.Service, *model.ErrorResBody) {
+func (ss StubService) GetServiceByName(ctx context.Context, name string) (*entity.Service, *model.ErrorResBody) {
 	return &entity.Service{}, nil
 }
 
-func (ss StubService) GetServiceBySecret(secret string) (*entity.Service, *model.ErrorResBody) {
+func (ss StubService) GetServiceBySecret(ctx context.Context, secret string) (*entity.Service, *model.ErrorResBody) {
 	return &entity.Service{}, nil
 }
 
-func (ss StubService) GetServiceByUser(userUuid string) ([]*entity.Service, *model.ErrorResBody) {
-	return []*entity.Service{}, nil
+func (ss StubService) GetServiceByUser(ctx context.Context, userUuid string, page driver.Page) ([]*entity.Service, string, *model.ErrorResBody) {
+	return []*entity.Service{}, "", nil
 }
 
-func (ss StubService) InsertService(service entity.Service) (*entity.Service, *model.ErrorResBody) {
+func (ss StubService) InsertService(ctx context.Context, service entity.Service) (*entity.Service, *model.ErrorResBody) {
 	return &entity.Service{}, nil
 }
 
-func (ss StubService) InsertServiceWithRelationalData(service *entity.Service) (*entity.Service, *model.ErrorResBody) {
+func (ss StubService) InsertServiceWithRelationalData(ctx context.Context, service *entity.Service) (*entity.Service, *model.ErrorResBody) {
 	return &entity.Service{}, nil
 }
 
@@ -0,0 +1,56 @@
+package operator
+
+import (
+	"net/http"
+
+	"github.com/tomoyane/grant-n-z/gnz/log"
+	"github.com/tomoyane/grant-n-z/gnzserver/middleware"
+	"github.com/tomoyane/grant-n-z/gnzserver/model"
+	"github.com/tomoyane/grant-n-z/gnzserver/service"
+)
+
+var osdhInstance OperatorSoftDelete
+
+type OperatorSoftDelete interface {
+	// Http DELETE method
+	// Endpoint is `/api/operators/{entity}/{uuid}`
+	Delete(w http.ResponseWriter, r *http.Request)
+
+	// Http POST method
+	// Endpoint is `/api/operators/{entity}/{uuid}/restore`
+	Restore(w http.ResponseWriter, r *http.Request)
+}
+
+type OperatorSoftDeleteImpl struct {
+	SoftDeleteService service.SoftDeleteService
+}
+
+func GetOperatorSoftDeleteInstance() OperatorSoftDelete {
+	if osdhInstance == nil {
+		osdhInstance = NewOperatorSoftDelete()
+	}
+	return osdhInstance
+}
+
+func NewOperatorSoftDelete() OperatorSoftDelete {
+	log.Logger.Info("New `OperatorSoftDelete` instance")
+	return OperatorSoftDeleteImpl{SoftDeleteService: service.GetSoftDeleteServiceInstance()}
+}
+
+func (sdh OperatorSoftDeleteImpl) Delete(w http.ResponseWriter, r *http.Request) {
+	if err := sdh.SoftDeleteService.Delete(r.Context(), middleware.ParamEntity(r), middleware.ParamUuid(r)); err != nil {
+		model.WriteError(w, err.ToJson(), err.Code)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (sdh OperatorSoftDeleteImpl) Restore(w http.ResponseWriter, r *http.Request) {
+	if err := sdh.SoftDeleteService.Restore(r.Context(), middleware.ParamEntity(r), middleware.ParamUuid(r)); err != nil {
+		model.WriteError(w, err.ToJson(), err.Code)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,57 @@
+package operator
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/gorilla/mux"
+
+	"github.com/tomoyane/grant-n-z/gnz/log"
+	"github.com/tomoyane/grant-n-z/gnzserver/model"
+)
+
+func init() {
+	log.InitLogger("info")
+}
+
+// Test constructor
+func TestGetOperatorSoftDeleteInstance(t *testing.T) {
+	GetOperatorSoftDeleteInstance()
+}
+
+// Test delete
+func TestOperatorSoftDelete_Delete(t *testing.T) {
+	request := mux.SetURLVars(&http.Request{Header: http.Header{}, Method: http.MethodDelete}, map[string]string{"entity": "users", "uuid": "uuid"})
+	OperatorSoftDeleteImpl{SoftDeleteService: StubSoftDeleteService{}}.Delete(StubResponseWriter{}, request)
+
+	if statusCode != http.StatusNoContent {
+		t.Errorf("Incorrect TestOperatorSoftDelete_Delete test.")
+		t.FailNow()
+	}
+}
+
+// Test restore not found
+func TestOperatorSoftDelete_Restore_NotFound(t *testing.T) {
+	request := mux.SetURLVars(&http.Request{Header: http.Header{}, Method: http.MethodPost}, map[string]string{"entity": "users", "uuid": "uuid"})
+	OperatorSoftDeleteImpl{SoftDeleteService: StubSoftDeleteService{err: model.NotFound()}}.Restore(StubResponseWriter{}, request)
+
+	if statusCode != http.StatusNotFound {
+		t.Errorf("Incorrect TestOperatorSoftDelete_Restore_NotFound test.")
+		t.FailNow()
+	}
+}
+
+// Less than stub struct
+// SoftDeleteService
+type StubSoftDeleteService struct {
+	err *model.ErrorResBody
+}
+
+func (sds StubSoftDeleteService) Delete(ctx context.Context, entityName string, uuid string) *model.ErrorResBody {
+	return sds.err
+}
+
+func (sds StubSoftDeleteService) Restore(ctx context.Context, entityName string, uuid string) *model.ErrorResBody {
+	return sds.err
+}
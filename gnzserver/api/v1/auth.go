@@ -56,7 +56,7 @@ func (ah AuthImpl) get(w http.ResponseWriter, r *http.Request) {
 	roleNames := r.URL.Query().Get("role")
 	permissionNames := r.URL.Query().Get("permission")
 
-	_, err := ah.tokenProcessor.VerifyUserToken(token, roleNames, permissionNames, groupUuids)
+	_, err := ah.tokenProcessor.VerifyUserToken(r.Context(), token, roleNames, permissionNames, groupUuids)
 	if err != nil {
 		model.WriteError(w, err.ToJson(), err.Code)
 		return
@@ -37,7 +37,7 @@ func NewGroup() Group {
 }
 
 func (gh GroupImpl) Get(w http.ResponseWriter, r *http.Request) {
-	group, err := gh.GroupService.GetGroupByUuid(middleware.ParamGroupUuid(r))
+	group, err := gh.GroupService.GetGroupByUuid(r.Context(), middleware.ParamGroupUuid(r))
 	if err != nil {
 		model.WriteError(w, err.ToJson(), err.Code)
 		return
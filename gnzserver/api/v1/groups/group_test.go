@@ -1,11 +1,13 @@
 package groups
 
 import (
+	"context"
 	"testing"
 
 	"net/http"
 	"net/url"
 
+	"github.com/tomoyane/grant-n-z/gnz/driver"
 	"github.com/tomoyane/grant-n-z/gnz/entity"
 	"github.com/tomoyane/grant-n-z/gnz/log"
 	"github.com/tomoyane/grant-n-z/gnzserver/model"
@@ -45,22 +47,22 @@ func TestGroup_Get_Success(t *testing.T) {
 type StubGroupService struct {
 }
 
-func (gs StubGroupService) GetGroups() ([]*entity.Group, *model.ErrorResBody) {
+func (gs StubGroupService) GetGroups(ctx context.Context) ([]*entity.Group, *model.ErrorResBody) {
 	return []*entity.Group{}, nil
 }
 
-func (gs StubGroupService) GetGroupByUuid(uuid string) (*entity.Group, *model.ErrorResBody) {
+func (gs StubGroupService) GetGroupByUuid(ctx context.Context, uuid string) (*entity.Group, *model.ErrorResBody) {
 	return &entity.Group{}, nil
 }
 
-func (gs StubGroupService) GetGroupByUser(userUuid string) ([]*entity.Group, *model.ErrorResBody) {
-	return []*entity.Group{}, nil
+func (gs StubGroupService) GetGroupByUser(ctx context.Context, userUuid string, page driver.Page) ([]*entity.Group, string, *model.ErrorResBody) {
+	return []*entity.Group{}, "", nil
 }
 
-func (gs StubGroupService) GetGroupByServices(serviceUuid string) ([]*entity.Group, *model.ErrorResBody) {
-	return []*entity.Group{}, nil
+func (gs StubGroupService) GetGroupByServices(ctx context.Context, serviceUuid string, page driver.Page) ([]*entity.Group, string, *model.ErrorResBody) {
+	return []*entity.Group{}, "", nil
 }
 
-func (gs StubGroupService) InsertGroupWithRelationalData(group entity.Group, userUuid string, serviceUuid string) (*entity.Group, *model.ErrorResBody) {
+func (gs StubGroupService) InsertGroupWithRelationalData(ctx context.Context, group entity.Group, userUuid string, serviceUuid string) (*entity.Group, *model.ErrorResBody) {
 	return &entity.Group{}, nil
 }
@@ -46,14 +46,19 @@ func NewPermission() Permission {
 }
 
 func (ph PermissionImpl) Get(w http.ResponseWriter, r *http.Request) {
+	page, err := middleware.BindPage(w, r)
+	if err != nil {
+		return
+	}
+
 	groupUuid := middleware.ParamGroupUuid(r)
-	permissions, err := ph.PermissionService.GetPermissionsByGroupUuid(groupUuid)
+	permissions, next, err := ph.PermissionService.GetPermissionsByGroupUuid(r.Context(), groupUuid, page)
 	if err != nil {
 		model.WriteError(w, err.ToJson(), err.Code)
 		return
 	}
 
-	res, _ := json.Marshal(permissions)
+	res, _ := json.Marshal(model.PageResponse{Items: permissions, NextCursor: next})
 	w.WriteHeader(http.StatusOK)
 	w.Write(res)
 }
@@ -69,7 +74,7 @@ func (ph PermissionImpl) Post(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	permission, err := ph.PermissionService.InsertWithRelationalData(middleware.ParamGroupUuid(r), *permissionEntity)
+	permission, err := ph.PermissionService.InsertWithRelationalData(r.Context(), middleware.ParamGroupUuid(r), *permissionEntity)
 	if err != nil {
 		model.WriteError(w, err.ToJson(), err.Code)
 		return
@@ -2,12 +2,14 @@ package groups
 
 import (
 	"bytes"
+	"context"
 	"io/ioutil"
 	"net/url"
 	"testing"
 
 	"net/http"
 
+	"github.com/tomoyane/grant-n-z/gnz/driver"
 	"github.com/tomoyane/grant-n-z/gnz/entity"
 	"github.com/tomoyane/grant-n-z/gnz/log"
 	"github.com/tomoyane/grant-n-z/gnzserver/model"
@@ -76,26 +78,26 @@ func (w StubResponseWriter) WriteHeader(code int) {
 type StubPermissionService struct {
 }
 
-func (ps StubPermissionService) GetPermissions() ([]*entity.Permission, *model.ErrorResBody) {
+func (ps StubPermissionService) GetPermissions(ctx context.Context) ([]*entity.Permission, *model.ErrorResBody) {
 	return []*entity.Permission{}, nil
 }
 
-func (ps StubPermissionService) GetPermissionByUuid(uuid string) (*entity.Permission, *model.ErrorResBody) {
+func (ps StubPermissionService) GetPermissionByUuid(ctx context.Context, uuid string) (*entity.Permission, *model.ErrorResBody) {
 	return &entity.Permission{}, nil
 }
 
-func (ps StubPermissionService) GetPermissionByName(name string) (*entity.Permission, *model.ErrorResBody) {
+func (ps StubPermissionService) GetPermissionByName(ctx context.Context, name string) (*entity.Permission, *model.ErrorResBody) {
 	return &entity.Permission{}, nil
 }
 
-func (ps StubPermissionService) GetPermissionsByGroupUuid(groupUuid string) ([]*entity.Permission, *model.ErrorResBody) {
-	return []*entity.Permission{}, nil
+func (ps StubPermissionService) GetPermissionsByGroupUuid(ctx context.Context, groupUuid string, page driver.Page) ([]*entity.Permission, string, *model.ErrorResBody) {
+	return []*entity.Permission{}, "", nil
 }
 
-func (ps StubPermissionService) InsertPermission(permission *entity.Permission) (*entity.Permission, *model.ErrorResBody) {
+func (ps StubPermissionService) InsertPermission(ctx context.Context, permission *entity.Permission) (*entity.Permission, *model.ErrorResBody) {
 	return &entity.Permission{}, nil
 }
 
-func (ps StubPermissionService) InsertWithRelationalData(groupUuid string, permission entity.Permission) (*entity.Permission, *model.ErrorResBody) {
+func (ps StubPermissionService) InsertWithRelationalData(ctx context.Context, groupUuid string, permission entity.Permission) (*entity.Permission, *model.ErrorResBody) {
 	return &entity.Permission{}, nil
 }
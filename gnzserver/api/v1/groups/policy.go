@@ -18,7 +18,8 @@ type Policy interface {
 	Api(w http.ResponseWriter, r *http.Request)
 
 	// Http PUT method
-	// Update user's policy
+	// Update user's policy of If-Match, that is policy_version of GET
+	// If user does not have policy, insert it with If-None-Match `*`
 	put(w http.ResponseWriter, r *http.Request)
 
 	// Http GET method
@@ -72,26 +73,41 @@ func (p PolicyImpl) put(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	var version int
+	if r.Header.Get(middleware.IfNoneMatch) != "*" {
+		ifMatch, err := middleware.BindIfMatch(w, r)
+		if err != nil {
+			return
+		}
+		version = ifMatch
+	}
+
 	secret := r.Context().Value(middleware.ScopeSecret).(string)
-	insertedPolicy, errPolicy := p.PolicyService.UpdatePolicy(*policyRequest, secret, middleware.ParamGroupUuid(r))
+	insertedPolicy, errPolicy := p.PolicyService.UpdatePolicy(r.Context(), *policyRequest, secret, middleware.ParamGroupUuid(r), version)
 	if errPolicy != nil {
 		model.WriteError(w, errPolicy.ToJson(), errPolicy.Code)
 		return
 	}
 
 	res, _ := json.Marshal(insertedPolicy)
+	middleware.SetETag(w, insertedPolicy.Version)
 	w.WriteHeader(http.StatusOK)
 	w.Write(res)
 }
 
 func (p PolicyImpl) get(w http.ResponseWriter, r *http.Request) {
-	policies, err := p.PolicyService.GetPoliciesByUserGroup(middleware.ParamGroupUuid(r))
+	page, err := middleware.BindPage(w, r)
+	if err != nil {
+		return
+	}
+
+	policies, next, err := p.PolicyService.GetPoliciesByUserGroup(r.Context(), middleware.ParamGroupUuid(r), page)
 	if err != nil {
 		model.WriteError(w, err.ToJson(), err.Code)
 		return
 	}
 
-	res, _ := json.Marshal(policies)
+	res, _ := json.Marshal(model.PageResponse{Items: policies, NextCursor: next})
 	w.WriteHeader(http.StatusOK)
 	w.Write(res)
 }
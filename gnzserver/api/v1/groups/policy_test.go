@@ -2,13 +2,17 @@ package groups
 
 import (
 	"bytes"
+	"context"
 	"testing"
 
 	"io/ioutil"
 	"net/http"
+	"net/url"
 
+	"github.com/tomoyane/grant-n-z/gnz/driver"
 	"github.com/tomoyane/grant-n-z/gnz/entity"
 	"github.com/tomoyane/grant-n-z/gnz/log"
+	"github.com/tomoyane/grant-n-z/gnzserver/middleware"
 	"github.com/tomoyane/grant-n-z/gnzserver/model"
 )
 
@@ -70,10 +74,37 @@ func TestPolicy_Put_BadRequest_QueryParam(t *testing.T) {
 	}
 }
 
+// Test put without If-Match
+func TestPolicy_Put_PreconditionRequired(t *testing.T) {
+	response := StubResponseWriter{}
+	body := ioutil.NopCloser(bytes.NewReader([]byte("{\"name\":\"test\",\"to_user_email\":\"test@gmail.com\",\"role_uuid\":\"role\",\"permission_uuid\":\"permission\"}")))
+	request := http.Request{Header: http.Header{}, Method: http.MethodPut, Body: body}
+	policy.Api(response, &request)
+
+	if statusCode != http.StatusPreconditionRequired {
+		t.Errorf("Incorrect TestPolicy_Put_PreconditionRequired test. %d", statusCode)
+		t.FailNow()
+	}
+}
+
+// Test put that inserts policy
+func TestPolicy_Put_IfNoneMatch(t *testing.T) {
+	response := StubResponseWriter{}
+	body := ioutil.NopCloser(bytes.NewReader([]byte("{\"name\":\"test\",\"to_user_email\":\"test@gmail.com\",\"role_uuid\":\"role\",\"permission_uuid\":\"permission\"}")))
+	request := http.Request{Header: http.Header{}, Method: http.MethodPut, Body: body}
+	request.Header.Set(middleware.IfNoneMatch, "*")
+	policy.Api(response, request.WithContext(context.WithValue(request.Context(), middleware.ScopeSecret, "secret")))
+
+	if statusCode != http.StatusOK {
+		t.Errorf("Incorrect TestPolicy_Put_IfNoneMatch test. %d", statusCode)
+		t.FailNow()
+	}
+}
+
 // Test get bad request
 func TestPolicy_Get_Success(t *testing.T) {
 	response := StubResponseWriter{}
-	request := http.Request{Header: http.Header{}, Method: http.MethodGet}
+	request := http.Request{Header: http.Header{}, URL: &url.URL{}, Method: http.MethodGet}
 	policy.Api(response, &request)
 
 	if statusCode != http.StatusOK {
@@ -87,30 +118,30 @@ func TestPolicy_Get_Success(t *testing.T) {
 type StubPolicyService struct {
 }
 
-func (ps StubPolicyService) GetPolicies() ([]*entity.Policy, *model.ErrorResBody) {
+func (ps StubPolicyService) GetPolicies(ctx context.Context) ([]*entity.Policy, *model.ErrorResBody) {
 	return []*entity.Policy{}, nil
 }
 
-func (ps StubPolicyService) GetPoliciesByRoleUuid(roleUuid string) ([]*entity.Policy, *model.ErrorResBody) {
+func (ps StubPolicyService) GetPoliciesByRoleUuid(ctx context.Context, roleUuid string) ([]*entity.Policy, *model.ErrorResBody) {
 	return []*entity.Policy{}, nil
 }
 
-func (ps StubPolicyService) GetPoliciesByUser(userUuid string) ([]model.PolicyResponse, *model.ErrorResBody) {
+func (ps StubPolicyService) GetPoliciesByUser(ctx context.Context, userUuid string) ([]model.PolicyResponse, *model.ErrorResBody) {
 	return []model.PolicyResponse{}, nil
 }
 
-func (ps StubPolicyService) GetPolicyByUserGroup(userUuid string, groupUuid string) (*entity.Policy, *model.ErrorResBody) {
+func (ps StubPolicyService) GetPolicyByUserGroup(ctx context.Context, userUuid string, groupUuid string) (*entity.Policy, *model.ErrorResBody) {
 	return &entity.Policy{}, nil
 }
 
-func (ps StubPolicyService) GetPoliciesByUserGroup(groupUuid string) ([]model.UserPolicyOnGroupResponse, *model.ErrorResBody) {
-	return []model.UserPolicyOnGroupResponse{}, nil
+func (ps StubPolicyService) GetPoliciesByUserGroup(ctx context.Context, groupUuid string, page driver.Page) ([]model.UserPolicyOnGroupResponse, string, *model.ErrorResBody) {
+	return []model.UserPolicyOnGroupResponse{}, "", nil
 }
 
-func (ps StubPolicyService) GetPolicyByUuid(uuid string) (entity.Policy, *model.ErrorResBody) {
+func (ps StubPolicyService) GetPolicyByUuid(ctx context.Context, uuid string) (entity.Policy, *model.ErrorResBody) {
 	return entity.Policy{}, nil
 }
 
-func (ps StubPolicyService) UpdatePolicy(policyRequest model.PolicyRequest, secret string, groupUuid string) (*entity.Policy, *model.ErrorResBody) {
+func (ps StubPolicyService) UpdatePolicy(ctx context.Context, policyRequest model.PolicyRequest, secret string, groupUuid string, version int) (*entity.Policy, *model.ErrorResBody) {
 	return &entity.Policy{}, nil
 }
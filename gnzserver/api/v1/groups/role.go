@@ -44,13 +44,18 @@ func NewRole() Role {
 }
 
 func (rh RoleImpl) Get(w http.ResponseWriter, r *http.Request) {
-	roles, err := rh.RoleService.GetRolesByGroupUuid(middleware.ParamGroupUuid(r))
+	page, err := middleware.BindPage(w, r)
+	if err != nil {
+		return
+	}
+
+	roles, next, err := rh.RoleService.GetRolesByGroupUuid(r.Context(), middleware.ParamGroupUuid(r), page)
 	if err != nil {
 		model.WriteError(w, err.ToJson(), err.Code)
 		return
 	}
 
-	res, _ := json.Marshal(roles)
+	res, _ := json.Marshal(model.PageResponse{Items: roles, NextCursor: next})
 	w.WriteHeader(http.StatusOK)
 	w.Write(res)
 }
@@ -66,7 +71,7 @@ func (rh RoleImpl) Post(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	role, err := rh.RoleService.InsertWithRelationalData(middleware.ParamGroupUuid(r), *roleEntity)
+	role, err := rh.RoleService.InsertWithRelationalData(r.Context(), middleware.ParamGroupUuid(r), *roleEntity)
 	if err != nil {
 		model.WriteError(w, err.ToJson(), err.Code)
 		return
@@ -2,11 +2,14 @@ package groups
 
 import (
 	"bytes"
+	"context"
 	"io/ioutil"
 	"testing"
 
 	"net/http"
+	"net/url"
 
+	"github.com/tomoyane/grant-n-z/gnz/driver"
 	"github.com/tomoyane/grant-n-z/gnz/entity"
 	"github.com/tomoyane/grant-n-z/gnz/log"
 	"github.com/tomoyane/grant-n-z/gnzserver/model"
@@ -30,7 +33,7 @@ func TestGetRoleInstance(t *testing.T) {
 // Test get
 func TestRole_Get_Success(t *testing.T) {
 	response := StubResponseWriter{}
-	request := http.Request{Header: http.Header{}, Method: http.MethodGet}
+	request := http.Request{Header: http.Header{}, URL: &url.URL{}, Method: http.MethodGet}
 	role.Get(response, &request)
 
 	if statusCode != http.StatusOK {
@@ -70,30 +73,30 @@ func TestRole_Post_BadRequest_Success(t *testing.T) {
 type StubRoleService struct {
 }
 
-func (rs StubRoleService) GetRoles() ([]*entity.Role, *model.ErrorResBody) {
+func (rs StubRoleService) GetRoles(ctx context.Context) ([]*entity.Role, *model.ErrorResBody) {
 	return []*entity.Role{}, nil
 }
 
-func (rs StubRoleService) GetRoleByUuid(uuid string) (*entity.Role, *model.ErrorResBody) {
+func (rs StubRoleService) GetRoleByUuid(ctx context.Context, uuid string) (*entity.Role, *model.ErrorResBody) {
 	return &entity.Role{}, nil
 }
 
-func (rs StubRoleService) GetRoleByName(name string) (*entity.Role, *model.ErrorResBody) {
+func (rs StubRoleService) GetRoleByName(ctx context.Context, name string) (*entity.Role, *model.ErrorResBody) {
 	return &entity.Role{}, nil
 }
 
-func (rs StubRoleService) GetRoleByNames(names []string) ([]entity.Role, *model.ErrorResBody) {
+func (rs StubRoleService) GetRoleByNames(ctx context.Context, names []string) ([]entity.Role, *model.ErrorResBody) {
 	return []entity.Role{}, nil
 }
 
-func (rs StubRoleService) GetRolesByGroupUuid(groupUuid string) ([]*entity.Role, *model.ErrorResBody) {
-	return []*entity.Role{}, nil
+func (rs StubRoleService) GetRolesByGroupUuid(ctx context.Context, groupUuid string, page driver.Page) ([]*entity.Role, string, *model.ErrorResBody) {
+	return []*entity.Role{}, "", nil
 }
 
-func (rs StubRoleService) InsertRole(role *entity.Role) (*entity.Role, *model.ErrorResBody) {
+func (rs StubRoleService) InsertRole(ctx context.Context, role *entity.Role) (*entity.Role, *model.ErrorResBody) {
 	return &entity.Role{}, nil
 }
 
-func (rs StubRoleService) InsertWithRelationalData(groupUuid string, role entity.Role) (*entity.Role, *model.ErrorResBody) {
+func (rs StubRoleService) InsertWithRelationalData(ctx context.Context, groupUuid string, role entity.Role) (*entity.Role, *model.ErrorResBody) {
 	return &entity.Role{}, nil
 }
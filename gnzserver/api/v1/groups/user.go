@@ -69,13 +69,13 @@ func (u UserImpl) put(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	group, errGroup := u.GroupService.GetGroupByUuid(middleware.ParamGroupUuid(r))
+	group, errGroup := u.GroupService.GetGroupByUuid(r.Context(), middleware.ParamGroupUuid(r))
 	if errGroup != nil {
 		model.WriteError(w, errGroup.ToJson(), errGroup.Code)
 		return
 	}
 
-	user, errUser := u.UserService.GetUserWithUserServiceWithServiceByEmail(addUserEntity.UserEmail)
+	user, errUser := u.UserService.GetUserWithUserServiceWithServiceByEmail(r.Context(), addUserEntity.UserEmail)
 	if errUser != nil {
 		model.WriteError(w, errUser.ToJson(), errUser.Code)
 		return
@@ -85,7 +85,7 @@ func (u UserImpl) put(w http.ResponseWriter, r *http.Request) {
 		UserUuid:  user.User.Uuid,
 		GroupUuid: group.Uuid,
 	}
-	userGroup, errUserGroup := u.UserService.InsertUserGroup(userGroupEntity)
+	userGroup, errUserGroup := u.UserService.InsertUserGroup(r.Context(), userGroupEntity)
 	if errUserGroup != nil {
 		model.WriteError(w, errUserGroup.ToJson(), errUserGroup.Code)
 		return
@@ -97,13 +97,18 @@ func (u UserImpl) put(w http.ResponseWriter, r *http.Request) {
 }
 
 func (u UserImpl) get(w http.ResponseWriter, r *http.Request) {
-	userResponse, errUser := u.UserService.GetUserByGroupUuid(middleware.ParamGroupUuid(r))
+	page, err := middleware.BindUserPage(w, r)
+	if err != nil {
+		return
+	}
+
+	userResponse, next, errUser := u.UserService.GetUserByGroupUuid(r.Context(), middleware.ParamGroupUuid(r), page)
 	if errUser != nil {
 		model.WriteError(w, errUser.ToJson(), errUser.Code)
 		return
 	}
 
-	res, _ := json.Marshal(userResponse)
+	res, _ := json.Marshal(model.PageResponse{Items: userResponse, NextCursor: next})
 	w.WriteHeader(http.StatusOK)
 	w.Write(res)
 }
@@ -1,15 +1,18 @@
 package groups
 
 import (
+	"context"
 	"bytes"
 	"github.com/tomoyane/grant-n-z/gnz/cache/structure"
 	"testing"
 
 	"io/ioutil"
 	"net/http"
+	"net/url"
 
 	"golang.org/x/crypto/bcrypt"
 
+	"github.com/tomoyane/grant-n-z/gnz/driver"
 	"github.com/tomoyane/grant-n-z/gnz/entity"
 	"github.com/tomoyane/grant-n-z/gnz/log"
 	"github.com/tomoyane/grant-n-z/gnzserver/model"
@@ -74,7 +77,7 @@ func TestUser_Put_Ok(t *testing.T) {
 // Test get
 func TestUser_Get_Ok(t *testing.T) {
 	response := StubResponseWriter{}
-	request := http.Request{Header: http.Header{}, Method: http.MethodGet}
+	request := http.Request{Header: http.Header{}, URL: &url.URL{}, Method: http.MethodGet}
 	user.Api(response, &request)
 
 	if statusCode != http.StatusOK {
@@ -106,67 +109,67 @@ func (us StubUserService) ComparePw(passwordHash string, password string) bool {
 	return true
 }
 
-func (us StubUserService) GetUserByUuid(uuid string) (*entity.User, *model.ErrorResBody) {
+func (us StubUserService) GetUserByUuid(ctx context.Context, uuid string) (*entity.User, *model.ErrorResBody) {
 	return &entity.User{}, nil
 }
 
-func (us StubUserService) GetUserByEmail(email string) (*entity.User, *model.ErrorResBody) {
+func (us StubUserService) GetUserByEmail(ctx context.Context, email string) (*entity.User, *model.ErrorResBody) {
 	return &entity.User{}, nil
 }
 
 
-func (us StubUserService) GetUserWithOperatorPolicyByEmail(email string) (*model.UserWithOperatorPolicy, *model.ErrorResBody) {
+func (us StubUserService) GetUserWithOperatorPolicyByEmail(ctx context.Context, email string) (*model.UserWithOperatorPolicy, *model.ErrorResBody) {
 	return &model.UserWithOperatorPolicy{}, nil
 }
 
-func (us StubUserService) GetUserWithUserServiceWithServiceByEmail(email string) (*model.UserWithUserServiceWithService, *model.ErrorResBody) {
+func (us StubUserService) GetUserWithUserServiceWithServiceByEmail(ctx context.Context, email string) (*model.UserWithUserServiceWithService, *model.ErrorResBody) {
 	return &model.UserWithUserServiceWithService{}, nil
 }
 
-func (us StubUserService) GetUserGroupByUserUuidAndGroupUuid(userUuid string, groupUuid string) (*entity.UserGroup, *model.ErrorResBody) {
+func (us StubUserService) GetUserGroupByUserUuidAndGroupUuid(ctx context.Context, userUuid string, groupUuid string) (*entity.UserGroup, *model.ErrorResBody) {
 	return &entity.UserGroup{}, nil
 }
 
-func (us StubUserService) GetUserServices() ([]*entity.UserService, *model.ErrorResBody) {
+func (us StubUserService) GetUserServices(ctx context.Context) ([]*entity.UserService, *model.ErrorResBody) {
 	return []*entity.UserService{}, nil
 }
 
-func (us StubUserService) GetUserServicesByUserUuid(userUuid string) ([]*entity.UserService, *model.ErrorResBody) {
+func (us StubUserService) GetUserServicesByUserUuid(ctx context.Context, userUuid string) ([]*entity.UserService, *model.ErrorResBody) {
 	return []*entity.UserService{}, nil
 }
 
-func (us StubUserService) GetUserServiceByUserUuidAndServiceUuid(userUuid string, serviceUuid string) (*entity.UserService, *model.ErrorResBody) {
+func (us StubUserService) GetUserServiceByUserUuidAndServiceUuid(ctx context.Context, userUuid string, serviceUuid string) (*entity.UserService, *model.ErrorResBody) {
 	return &entity.UserService{}, nil
 }
 
-func (us StubUserService) GetUserByGroupUuid(groupUuid string) ([]*model.UserResponse, *model.ErrorResBody) {
-	return []*model.UserResponse{}, nil
+func (us StubUserService) GetUserByGroupUuid(ctx context.Context, groupUuid string, page driver.Page) ([]*model.UserResponse, string, *model.ErrorResBody) {
+	return []*model.UserResponse{}, "", nil
 }
 
-func (us StubUserService) GetUserPoliciesByUserUuid(userUuid string) []structure.UserPolicy {
+func (us StubUserService) GetUserPoliciesByUserUuid(ctx context.Context, userUuid string) []structure.UserPolicy {
 	return []structure.UserPolicy{}
 }
 
-func (us StubUserService) GetUserGroupsByUserUuid(userUuid string) []structure.UserGroup {
+func (us StubUserService) GetUserGroupsByUserUuid(ctx context.Context, userUuid string) []structure.UserGroup {
 	return []structure.UserGroup{}
 }
 
-func (us StubUserService) InsertUserGroup(userGroupEntity entity.UserGroup) (*entity.UserGroup, *model.ErrorResBody) {
+func (us StubUserService) InsertUserGroup(ctx context.Context, userGroupEntity entity.UserGroup) (*entity.UserGroup, *model.ErrorResBody) {
 	return &entity.UserGroup{}, nil
 }
 
-func (us StubUserService) InsertUser(user entity.User) (*entity.User, *model.ErrorResBody) {
+func (us StubUserService) InsertUser(ctx context.Context, user entity.User) (*entity.User, *model.ErrorResBody) {
 	return &entity.User{}, nil
 }
 
-func (us StubUserService) InsertUserWithUserService(user entity.User, userService entity.UserService) (*entity.User, *model.ErrorResBody) {
+func (us StubUserService) InsertUserWithUserService(ctx context.Context, user entity.User, userService entity.UserService) (*entity.User, *model.ErrorResBody) {
 	return &entity.User{}, nil
 }
 
-func (us StubUserService) InsertUserService(userServiceEntity entity.UserService) (*entity.UserService, *model.ErrorResBody) {
+func (us StubUserService) InsertUserService(ctx context.Context, userServiceEntity entity.UserService) (*entity.UserService, *model.ErrorResBody) {
 	return &entity.UserService{}, nil
 }
 
-func (us StubUserService) UpdateUser(user entity.User) (*entity.User, *model.ErrorResBody) {
+func (us StubUserService) UpdateUser(ctx context.Context, user entity.User) (*entity.User, *model.ErrorResBody) {
 	return &entity.User{}, nil
 }
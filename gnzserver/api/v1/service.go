@@ -50,12 +50,18 @@ func NewService() Service {
 }
 
 func (s ServiceImpl) Get(w http.ResponseWriter, r *http.Request) {
-	services, err := s.ServiceService.GetServices()
+	page, err := middleware.BindPage(w, r)
+	if err != nil {
+		return
+	}
+
+	services, next, err := s.ServiceService.GetServices(r.Context(), page)
 	if err != nil {
 		model.WriteError(w, err.ToJson(), err.Code)
+		return
 	}
 
-	res, _ := json.Marshal(services)
+	res, _ := json.Marshal(model.PageResponse{Items: services, NextCursor: next})
 	w.WriteHeader(http.StatusOK)
 	w.Write(res)
 }
@@ -70,7 +76,7 @@ func (s ServiceImpl) Post(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Authentication user
-	token, err := s.TokenProcessor.Generate(common.AuthUser, *tokenRequest)
+	token, err := s.TokenProcessor.Generate(r.Context(), common.AuthUser, *tokenRequest)
 	if err != nil {
 		model.WriteError(w, err.ToJson(), err.Code)
 		return
@@ -83,7 +89,7 @@ func (s ServiceImpl) Post(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Check exist service
-	serviceEntity, err := s.ServiceService.GetServiceBySecret(r.Context().Value(middleware.ScopeSecret).(string))
+	serviceEntity, err := s.ServiceService.GetServiceBySecret(r.Context(), r.Context().Value(middleware.ScopeSecret).(string))
 	if err != nil {
 		model.WriteError(w, err.ToJson(), err.Code)
 		return
@@ -95,7 +101,7 @@ func (s ServiceImpl) Post(w http.ResponseWriter, r *http.Request) {
 		UserUuid:    userUuid,
 		ServiceUuid: serviceEntity.Uuid,
 	}
-	userService, err := s.UserService.InsertUserService(*userServiceEntity)
+	userService, err := s.UserService.InsertUserService(r.Context(), *userServiceEntity)
 	if err != nil {
 		model.WriteError(w, err.ToJson(), err.Code)
 		return
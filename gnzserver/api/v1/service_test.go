@@ -9,10 +9,12 @@ import (
 
 	"io/ioutil"
 	"net/http"
+	"net/url"
 
 	"golang.org/x/crypto/bcrypt"
 
 	"github.com/google/uuid"
+	"github.com/tomoyane/grant-n-z/gnz/driver"
 	"github.com/tomoyane/grant-n-z/gnz/entity"
 	"github.com/tomoyane/grant-n-z/gnz/log"
 	"github.com/tomoyane/grant-n-z/gnzserver/model"
@@ -40,7 +42,7 @@ func TestGetServiceInstance(t *testing.T) {
 // Test get
 func TestService_Get(t *testing.T) {
 	response := StubResponseWriter{}
-	request := http.Request{Header: http.Header{}, Method: http.MethodGet}
+	request := http.Request{Header: http.Header{}, URL: &url.URL{}, Method: http.MethodGet}
 	ser.Get(response, &request)
 
 	if statusCode != http.StatusOK {
@@ -49,6 +51,18 @@ func TestService_Get(t *testing.T) {
 	}
 }
 
+// Test get with invalid page
+func TestService_Get_BadRequest(t *testing.T) {
+	response := StubResponseWriter{}
+	request := http.Request{Header: http.Header{}, URL: &url.URL{RawQuery: "limit=1001"}, Method: http.MethodGet}
+	ser.Get(response, &request)
+
+	if statusCode != http.StatusBadRequest {
+		t.Errorf("Incorrect TestService_Get_BadRequest test.")
+		t.FailNow()
+	}
+}
+
 // Test post bad request
 func TestService_Post_BadRequest(t *testing.T) {
 	response := StubResponseWriter{}
@@ -83,31 +97,31 @@ func TestService_Post(t *testing.T) {
 type StubService struct {
 }
 
-func (ss StubService) GetServices() ([]*entity.Service, *model.ErrorResBody) {
-	return []*entity.Service{}, nil
+func (ss StubService) GetServices(ctx context.Context, page driver.Page) ([]*entity.Service, string, *model.ErrorResBody) {
+	return []*entity.Service{}, "", nil
 }
 
-func (ss StubService) GetServiceByUuid(uuid string) (*entity.Service, *model.ErrorResBody) {
+func (ss StubService) GetServiceByUuid(ctx context.Context, uuid string) (*entity.Service, *model.ErrorResBody) {
 	return &entity.Service{}, nil
 }
 
-func (ss StubService) GetServiceByName(name string) (*entity.Service, *model.ErrorResBody) {
+func (ss StubService) GetServiceByName(ctx context.Context, name string) (*entity.Service, *model.ErrorResBody) {
 	return &entity.Service{}, nil
 }
 
-func (ss StubService) GetServiceBySecret(secret string) (*entity.Service, *model.ErrorResBody) {
+func (ss StubService) GetServiceBySecret(ctx context.Context, secret string) (*entity.Service, *model.ErrorResBody) {
 	return &entity.Service{}, nil
 }
 
-func (ss StubService) GetServiceByUser(userUuid string) ([]*entity.Service, *model.ErrorResBody) {
-	return []*entity.Service{}, nil
+func (ss StubService) GetServiceByUser(ctx context.Context, userUuid string, page driver.Page) ([]*entity.Service, string, *model.ErrorResBody) {
+	return []*entity.Service{}, "", nil
 }
 
-func (ss StubService) InsertService(service entity.Service) (*entity.Service, *model.ErrorResBody) {
+func (ss StubService) InsertService(ctx context.Context, service entity.Service) (*entity.Service, *model.ErrorResBody) {
 	return &entity.Service{}, nil
 }
 
-func (ss StubService) InsertServiceWithRelationalData(service *entity.Service) (*entity.Service, *model.ErrorResBody) {
+func (ss StubService) InsertServiceWithRelationalData(ctx context.Context, service *entity.Service) (*entity.Service, *model.ErrorResBody) {
 	return &entity.Service{}, nil
 }
 
@@ -138,66 +152,66 @@ func (us StubUserService) ComparePw(passwordHash string, password string) bool {
 	return true
 }
 
-func (us StubUserService) GetUserByUuid(uuid string) (*entity.User, *model.ErrorResBody) {
+func (us StubUserService) GetUserByUuid(ctx context.Context, uuid string) (*entity.User, *model.ErrorResBody) {
 	return &entity.User{}, nil
 }
 
-func (us StubUserService) GetUserByEmail(email string) (*entity.User, *model.ErrorResBody) {
+func (us StubUserService) GetUserByEmail(ctx context.Context, email string) (*entity.User, *model.ErrorResBody) {
 	return &entity.User{}, nil
 }
 
-func (us StubUserService) GetUserWithOperatorPolicyByEmail(email string) (*model.UserWithOperatorPolicy, *model.ErrorResBody) {
+func (us StubUserService) GetUserWithOperatorPolicyByEmail(ctx context.Context, email string) (*model.UserWithOperatorPolicy, *model.ErrorResBody) {
 	return &model.UserWithOperatorPolicy{}, nil
 }
 
-func (us StubUserService) GetUserWithUserServiceWithServiceByEmail(email string) (*model.UserWithUserServiceWithService, *model.ErrorResBody) {
+func (us StubUserService) GetUserWithUserServiceWithServiceByEmail(ctx context.Context, email string) (*model.UserWithUserServiceWithService, *model.ErrorResBody) {
 	return &model.UserWithUserServiceWithService{}, nil
 }
 
-func (us StubUserService) GetUserGroupByUserUuidAndGroupUuid(userUuid string, groupUuid string) (*entity.UserGroup, *model.ErrorResBody) {
+func (us StubUserService) GetUserGroupByUserUuidAndGroupUuid(ctx context.Context, userUuid string, groupUuid string) (*entity.UserGroup, *model.ErrorResBody) {
 	return &entity.UserGroup{}, nil
 }
 
-func (us StubUserService) GetUserServices() ([]*entity.UserService, *model.ErrorResBody) {
+func (us StubUserService) GetUserServices(ctx context.Context) ([]*entity.UserService, *model.ErrorResBody) {
 	return []*entity.UserService{}, nil
 }
 
-func (us StubUserService) GetUserServicesByUserUuid(userUuid string) ([]*entity.UserService, *model.ErrorResBody) {
+func (us StubUserService) GetUserServicesByUserUuid(ctx context.Context, userUuid string) ([]*entity.UserService, *model.ErrorResBody) {
 	return []*entity.UserService{}, nil
 }
 
-func (us StubUserService) GetUserServiceByUserUuidAndServiceUuid(userUuid string, serviceUuid string) (*entity.UserService, *model.ErrorResBody) {
+func (us StubUserService) GetUserServiceByUserUuidAndServiceUuid(ctx context.Context, userUuid string, serviceUuid string) (*entity.UserService, *model.ErrorResBody) {
 	return &entity.UserService{}, nil
 }
 
-func (us StubUserService) GetUserByGroupUuid(groupUuid string) ([]*model.UserResponse, *model.ErrorResBody) {
-	return []*model.UserResponse{}, nil
+func (us StubUserService) GetUserByGroupUuid(ctx context.Context, groupUuid string, page driver.Page) ([]*model.UserResponse, string, *model.ErrorResBody) {
+	return []*model.UserResponse{}, "", nil
 }
 
-func (us StubUserService) GetUserPoliciesByUserUuid(userUuid string) []structure.UserPolicy {
+func (us StubUserService) GetUserPoliciesByUserUuid(ctx context.Context, userUuid string) []structure.UserPolicy {
 	return []structure.UserPolicy{}
 }
 
-func (us StubUserService) GetUserGroupsByUserUuid(userUuid string) []structure.UserGroup {
+func (us StubUserService) GetUserGroupsByUserUuid(ctx context.Context, userUuid string) []structure.UserGroup {
 	return []structure.UserGroup{}
 }
 
-func (us StubUserService) InsertUserGroup(userGroupEntity entity.UserGroup) (*entity.UserGroup, *model.ErrorResBody) {
+func (us StubUserService) InsertUserGroup(ctx context.Context, userGroupEntity entity.UserGroup) (*entity.UserGroup, *model.ErrorResBody) {
 	return &entity.UserGroup{}, nil
 }
 
-func (us StubUserService) InsertUser(user entity.User) (*entity.User, *model.ErrorResBody) {
+func (us StubUserService) InsertUser(ctx context.Context, user entity.User) (*entity.User, *model.ErrorResBody) {
 	return &entity.User{}, nil
 }
 
-func (us StubUserService) InsertUserWithUserService(user entity.User, userService entity.UserService) (*entity.User, *model.ErrorResBody) {
+func (us StubUserService) InsertUserWithUserService(ctx context.Context, user entity.User, userService entity.UserService) (*entity.User, *model.ErrorResBody) {
 	return &entity.User{}, nil
 }
 
-func (us StubUserService) InsertUserService(userServiceEntity entity.UserService) (*entity.UserService, *model.ErrorResBody) {
+func (us StubUserService) InsertUserService(ctx context.Context, userServiceEntity entity.UserService) (*entity.UserService, *model.ErrorResBody) {
 	return &entity.UserService{}, nil
 }
 
-func (us StubUserService) UpdateUser(user entity.User) (*entity.User, *model.ErrorResBody) {
+func (us StubUserService) UpdateUser(ctx context.Context, user entity.User) (*entity.User, *model.ErrorResBody) {
 	return &entity.User{}, nil
 }
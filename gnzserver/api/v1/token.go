@@ -6,6 +6,7 @@ import (
 	"github.com/tomoyane/grant-n-z/gnzserver/service"
 	"net/http"
 
+	"github.com/tomoyane/grant-n-z/gnz/driver"
 	"github.com/tomoyane/grant-n-z/gnz/log"
 	"github.com/tomoyane/grant-n-z/gnzserver/middleware"
 	"github.com/tomoyane/grant-n-z/gnzserver/model"
@@ -70,7 +71,7 @@ func (th TokenImpl) post(w http.ResponseWriter, r *http.Request) {
 	if userType == "" {
 		userType = common.AuthUser
 	}
-	token, err := th.TokenProcessor.Generate(userType, *tokenRequest)
+	token, err := th.TokenProcessor.Generate(r.Context(), userType, *tokenRequest)
 	if err != nil {
 		model.WriteError(w, err.ToJson(), err.Code)
 		return
@@ -78,7 +79,7 @@ func (th TokenImpl) post(w http.ResponseWriter, r *http.Request) {
 
 	secret := r.Context().Value(middleware.ScopeSecret)
 	if secret != nil {
-		_, err := th.Service.GetServiceByUser(secret.(string))
+		_, _, err := th.Service.GetServiceByUser(r.Context(), secret.(string), driver.Page{})
 		if err != nil {
 			err = model.Unauthorized("You don't join this service")
 			model.WriteError(w, err.ToJson(), err.Code)
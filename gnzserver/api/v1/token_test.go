@@ -2,6 +2,7 @@ package v1
 
 import (
 	"bytes"
+	"context"
 	"testing"
 
 	"io/ioutil"
@@ -72,15 +73,15 @@ func TestToken_Post(t *testing.T) {
 type StubTokenProcessor struct {
 }
 
-func (tp StubTokenProcessor) Generate(userType string, tokenRequest model.TokenRequest) (*model.TokenResponse, *model.ErrorResBody) {
+func (tp StubTokenProcessor) Generate(ctx context.Context, userType string, tokenRequest model.TokenRequest) (*model.TokenResponse, *model.ErrorResBody) {
 	return &model.TokenResponse{}, nil
 }
 
-func (tp StubTokenProcessor) VerifyOperatorToken(token string) (*model.JwtPayload, *model.ErrorResBody) {
+func (tp StubTokenProcessor) VerifyOperatorToken(ctx context.Context, token string) (*model.JwtPayload, *model.ErrorResBody) {
 	return &model.JwtPayload{}, nil
 }
 
-func (tp StubTokenProcessor) VerifyUserToken(token string, roleNames string, permissionNames string, groupUuid string) (*model.JwtPayload, *model.ErrorResBody) {
+func (tp StubTokenProcessor) VerifyUserToken(ctx context.Context, token string, roleNames string, permissionNames string, groupUuid string) (*model.JwtPayload, *model.ErrorResBody) {
 	return &model.JwtPayload{}, nil
 }
 
@@ -67,29 +67,35 @@ func (gh GroupImpl) Api(w http.ResponseWriter, r *http.Request) {
 func (gh GroupImpl) get(w http.ResponseWriter, r *http.Request) {
 	jwt := r.Context().Value(middleware.ScopeJwt).(model.JwtPayload)
 	secret := r.Context().Value(middleware.ScopeSecret)
+	page, err := middleware.BindPage(w, r)
+	if err != nil {
+		return
+	}
+
 	var groups []*entity.Group
+	var next string
 	if secret == nil {
-		data, err := gh.groupService.GetGroupByUser(jwt.UserUuid)
+		data, cursor, err := gh.groupService.GetGroupByUser(r.Context(), jwt.UserUuid, page)
 		if err != nil {
 			model.WriteError(w, err.ToJson(), err.Code)
 			return
 		}
-		groups = data
+		groups, next = data, cursor
 	} else {
-		ser, err := gh.service.GetServiceBySecret(secret.(string))
+		ser, err := gh.service.GetServiceBySecret(r.Context(), secret.(string))
 		if err != nil {
 			model.WriteError(w, err.ToJson(), err.Code)
 			return
 		}
-		data, err := gh.groupService.GetGroupByServices(ser.Uuid.String())
+		data, cursor, err := gh.groupService.GetGroupByServices(r.Context(), ser.Uuid.String(), page)
 		if err != nil {
 			model.WriteError(w, err.ToJson(), err.Code)
 			return
 		}
-		groups = data
+		groups, next = data, cursor
 	}
 
-	res, _ := json.Marshal(groups)
+	res, _ := json.Marshal(model.PageResponse{Items: groups, NextCursor: next})
 	w.WriteHeader(http.StatusOK)
 	w.Write(res)
 }
@@ -111,7 +117,7 @@ func (gh GroupImpl) post(w http.ResponseWriter, r *http.Request) {
 	}
 
 	jwt := r.Context().Value(middleware.ScopeJwt).(model.JwtPayload)
-	group, err := gh.groupService.InsertGroupWithRelationalData(*groupEntity, jwt.UserUuid, secret.(string))
+	group, err := gh.groupService.InsertGroupWithRelationalData(r.Context(), *groupEntity, jwt.UserUuid, secret.(string))
 	if err != nil {
 		model.WriteError(w, err.ToJson(), err.Code)
 		return
@@ -9,7 +9,9 @@ import (
 
 	"io/ioutil"
 	"net/http"
+	"net/url"
 
+	"github.com/tomoyane/grant-n-z/gnz/driver"
 	"github.com/tomoyane/grant-n-z/gnz/entity"
 	"github.com/tomoyane/grant-n-z/gnz/log"
 	"github.com/tomoyane/grant-n-z/gnzserver/model"
@@ -48,7 +50,7 @@ func TestGroup_MethodNotAllowed(t *testing.T) {
 // Test get
 func TestGroup_Get(t *testing.T) {
 	response := StubResponseWriter{}
-	request := http.Request{Header: http.Header{}, Method: http.MethodGet}
+	request := http.Request{Header: http.Header{}, URL: &url.URL{}, Method: http.MethodGet}
 
 	jwt := model.JwtPayload{
 		UserUuid: uuid.New().String(),
@@ -100,23 +102,23 @@ func TestGroup_Post(t *testing.T) {
 type StubGroupService struct {
 }
 
-func (gs StubGroupService) GetGroups() ([]*entity.Group, *model.ErrorResBody) {
+func (gs StubGroupService) GetGroups(ctx context.Context) ([]*entity.Group, *model.ErrorResBody) {
 	return []*entity.Group{}, nil
 }
 
-func (gs StubGroupService) GetGroupByUuid(uuid string) (*entity.Group, *model.ErrorResBody) {
+func (gs StubGroupService) GetGroupByUuid(ctx context.Context, uuid string) (*entity.Group, *model.ErrorResBody) {
 	return &entity.Group{}, nil
 }
 
-func (gs StubGroupService) GetGroupByUser(userUuid string) ([]*entity.Group, *model.ErrorResBody) {
-	return []*entity.Group{}, nil
+func (gs StubGroupService) GetGroupByUser(ctx context.Context, userUuid string, page driver.Page) ([]*entity.Group, string, *model.ErrorResBody) {
+	return []*entity.Group{}, "", nil
 }
 
-func (gs StubGroupService) GetGroupByServices(serviceUuid string) ([]*entity.Group, *model.ErrorResBody) {
-	return []*entity.Group{}, nil
+func (gs StubGroupService) GetGroupByServices(ctx context.Context, serviceUuid string, page driver.Page) ([]*entity.Group, string, *model.ErrorResBody) {
+	return []*entity.Group{}, "", nil
 }
 
-func (gs StubGroupService) InsertGroupWithRelationalData(group entity.Group, userUuid string, serviceUuid string) (*entity.Group, *model.ErrorResBody) {
+func (gs StubGroupService) InsertGroupWithRelationalData(ctx context.Context, group entity.Group, userUuid string, serviceUuid string) (*entity.Group, *model.ErrorResBody) {
 	return &entity.Group{}, nil
 }
 
@@ -7,6 +7,7 @@ import (
 	"net/http"
 	"testing"
 
+	"github.com/tomoyane/grant-n-z/gnz/driver"
 	"github.com/tomoyane/grant-n-z/gnz/entity"
 	"github.com/tomoyane/grant-n-z/gnz/log"
 	"github.com/tomoyane/grant-n-z/gnzserver/model"
@@ -63,30 +64,30 @@ func TestPolicy_Get(t *testing.T) {
 type StubPolicyService struct {
 }
 
-func (ps StubPolicyService) GetPolicies() ([]*entity.Policy, *model.ErrorResBody) {
+func (ps StubPolicyService) GetPolicies(ctx context.Context) ([]*entity.Policy, *model.ErrorResBody) {
 	return []*entity.Policy{}, nil
 }
 
-func (ps StubPolicyService) GetPoliciesByRoleUuid(roleUuid string) ([]*entity.Policy, *model.ErrorResBody) {
+func (ps StubPolicyService) GetPoliciesByRoleUuid(ctx context.Context, roleUuid string) ([]*entity.Policy, *model.ErrorResBody) {
 	return []*entity.Policy{}, nil
 }
 
-func (ps StubPolicyService) GetPoliciesByUser(userUuid string) ([]model.PolicyResponse, *model.ErrorResBody) {
+func (ps StubPolicyService) GetPoliciesByUser(ctx context.Context, userUuid string) ([]model.PolicyResponse, *model.ErrorResBody) {
 	return []model.PolicyResponse{}, nil
 }
 
-func (ps StubPolicyService) GetPolicyByUserGroup(userUuid string, groupUuid string) (*entity.Policy, *model.ErrorResBody) {
+func (ps StubPolicyService) GetPolicyByUserGroup(ctx context.Context, userUuid string, groupUuid string) (*entity.Policy, *model.ErrorResBody) {
 	return &entity.Policy{}, nil
 }
 
-func (ps StubPolicyService) GetPoliciesByUserGroup(groupUuid string) ([]model.UserPolicyOnGroupResponse, *model.ErrorResBody) {
-	return []model.UserPolicyOnGroupResponse{}, nil
+func (ps StubPolicyService) GetPoliciesByUserGroup(ctx context.Context, groupUuid string, page driver.Page) ([]model.UserPolicyOnGroupResponse, string, *model.ErrorResBody) {
+	return []model.UserPolicyOnGroupResponse{}, "", nil
 }
 
-func (ps StubPolicyService) GetPolicyByUuid(uuid string) (entity.Policy, *model.ErrorResBody) {
+func (ps StubPolicyService) GetPolicyByUuid(ctx context.Context, uuid string) (entity.Policy, *model.ErrorResBody) {
 	return entity.Policy{}, nil
 }
 
-func (ps StubPolicyService) UpdatePolicy(policyRequest model.PolicyRequest, secret string, groupUuid string) (*entity.Policy, *model.ErrorResBody) {
+func (ps StubPolicyService) UpdatePolicy(ctx context.Context, policyRequest model.PolicyRequest, secret string, groupUuid string, version int) (*entity.Policy, *model.ErrorResBody) {
 	return &entity.Policy{}, nil
 }
@@ -53,13 +53,18 @@ func (sh ServiceImpl) Api(w http.ResponseWriter, r *http.Request) {
 
 func (sh ServiceImpl) get(w http.ResponseWriter, r *http.Request) {
 	jwt := r.Context().Value(middleware.ScopeJwt).(model.JwtPayload)
-	result, err := sh.Service.GetServiceByUser(jwt.UserUuid)
+	page, err := middleware.BindPage(w, r)
+	if err != nil {
+		return
+	}
+
+	result, next, err := sh.Service.GetServiceByUser(r.Context(), jwt.UserUuid, page)
 	if err != nil {
 		model.WriteError(w, err.ToJson(), err.Code)
 		return
 	}
 
-	res, _ := json.Marshal(result)
+	res, _ := json.Marshal(model.PageResponse{Items: result, NextCursor: next})
 	w.WriteHeader(http.StatusOK)
 	w.Write(res)
 }
@@ -7,7 +7,9 @@ import (
 	"testing"
 
 	"net/http"
+	"net/url"
 
+	"github.com/tomoyane/grant-n-z/gnz/driver"
 	"github.com/tomoyane/grant-n-z/gnz/entity"
 	"github.com/tomoyane/grant-n-z/gnz/log"
 	"github.com/tomoyane/grant-n-z/gnzserver/model"
@@ -45,7 +47,7 @@ func TestService_MethodNotAllowed(t *testing.T) {
 // Test get
 func TestService_Get(t *testing.T) {
 	response := StubResponseWriter{}
-	request := http.Request{Header: http.Header{}, Method: http.MethodGet}
+	request := http.Request{Header: http.Header{}, URL: &url.URL{}, Method: http.MethodGet}
 
 	jwt := model.JwtPayload{
 		UserUuid: uuid.New().String(),
@@ -64,31 +66,31 @@ func TestService_Get(t *testing.T) {
 type StubService struct {
 }
 
-func (ss StubService) GetServices() ([]*entity.Service, *model.ErrorResBody) {
-	return []*entity.Service{}, nil
+func (ss StubService) GetServices(ctx context.Context, page driver.Page) ([]*entity.Service, string, *model.ErrorResBody) {
+	return []*entity.Service{}, "", nil
 }
 
-func (ss StubService) GetServiceByUuid(uuid string) (*entity.Service, *model.ErrorResBody) {
+func (ss StubService) GetServiceByUuid(ctx context.Context, uuid string) (*entity.Service, *model.ErrorResBody) {
 	return &entity.Service{}, nil
 }
 
-func (ss StubService) GetServiceByName(name string) (*entity.Service, *model.ErrorResBody) {
+func (ss StubService) GetServiceByName(ctx context.Context, name string) (*entity.Service, *model.ErrorResBody) {
 	return &entity.Service{}, nil
 }
 
-func (ss StubService) GetServiceBySecret(secret string) (*entity.Service, *model.ErrorResBody) {
+func (ss StubService) GetServiceBySecret(ctx context.Context, secret string) (*entity.Service, *model.ErrorResBody) {
 	return &entity.Service{}, nil
 }
 
-func (ss StubService) GetServiceByUser(userUuid string) ([]*entity.Service, *model.ErrorResBody) {
-	return []*entity.Service{}, nil
+func (ss StubService) GetServiceByUser(ctx context.Context, userUuid string, page driver.Page) ([]*entity.Service, string, *model.ErrorResBody) {
+	return []*entity.Service{}, "", nil
 }
 
-func (ss StubService) InsertService(service entity.Service) (*entity.Service, *model.ErrorResBody) {
+func (ss StubService) InsertService(ctx context.Context, service entity.Service) (*entity.Service, *model.ErrorResBody) {
 	return &entity.Service{}, nil
 }
 
-func (ss StubService) InsertServiceWithRelationalData(service *entity.Service) (*entity.Service, *model.ErrorResBody) {
+func (ss StubService) InsertServiceWithRelationalData(ctx context.Context, service *entity.Service) (*entity.Service, *model.ErrorResBody) {
 	return &entity.Service{}, nil
 }
 
@@ -19,6 +19,11 @@ type User interface {
 	// Endpoint is `/api/v1/users`
 	Post(w http.ResponseWriter, r *http.Request)
 
+	// Http GET method.
+	// Endpoint is `/api/v1/users`
+	// Version of user is ETag, that is If-Match of PUT
+	Get(w http.ResponseWriter, r *http.Request)
+
 	// Http PUT method.
 	// Endpoint is `/api/v1/users`
 	Put(w http.ResponseWriter, r *http.Request)
@@ -59,7 +64,7 @@ func (uh UserImpl) Post(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	serviceEntity, err := uh.Service.GetServiceBySecret(r.Context().Value(middleware.ScopeSecret).(string))
+	serviceEntity, err := uh.Service.GetServiceBySecret(r.Context(), r.Context().Value(middleware.ScopeSecret).(string))
 	if err != nil {
 		model.WriteError(w, err.ToJson(), err.Code)
 		return
@@ -70,7 +75,7 @@ func (uh UserImpl) Post(w http.ResponseWriter, r *http.Request) {
 		ServiceUuid: serviceEntity.Uuid,
 	}
 
-	if _, err = uh.UserService.InsertUserWithUserService(*userEntity, *userServiceEntity); err != nil {
+	if _, err = uh.UserService.InsertUserWithUserService(r.Context(), *userEntity, *userServiceEntity); err != nil {
 		model.WriteError(w, err.ToJson(), err.Code)
 		return
 	}
@@ -80,6 +85,20 @@ func (uh UserImpl) Post(w http.ResponseWriter, r *http.Request) {
 	w.Write(res)
 }
 
+func (uh UserImpl) Get(w http.ResponseWriter, r *http.Request) {
+	jwt := r.Context().Value(middleware.ScopeJwt).(model.JwtPayload)
+	user, err := uh.UserService.GetUserByUuid(r.Context(), jwt.UserUuid)
+	if err != nil {
+		model.WriteError(w, err.ToJson(), err.Code)
+		return
+	}
+
+	res, _ := json.Marshal(model.UserResponse{Uuid: user.Uuid.String(), Username: user.Username, Email: user.Email})
+	middleware.SetETag(w, user.Version)
+	w.WriteHeader(http.StatusOK)
+	w.Write(res)
+}
+
 func (uh UserImpl) Put(w http.ResponseWriter, r *http.Request) {
 	var userEntity *entity.User
 	if err := middleware.BindBody(w, r, &userEntity); err != nil {
@@ -90,15 +109,23 @@ func (uh UserImpl) Put(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	version, errVersion := middleware.BindIfMatch(w, r)
+	if errVersion != nil {
+		return
+	}
+
 	jwt := r.Context().Value(middleware.ScopeJwt).(model.JwtPayload)
 	userUuid := uuid.MustParse(jwt.UserUuid)
 	userEntity.Uuid = userUuid
-	if _, err := uh.UserService.UpdateUser(*userEntity); err != nil {
+	userEntity.Version = version
+	updatedUser, err := uh.UserService.UpdateUser(r.Context(), *userEntity)
+	if err != nil {
 		model.WriteError(w, err.ToJson(), err.Code)
 		return
 	}
 
 	res, _ := json.Marshal(map[string]string{"message": "User update succeeded."})
+	middleware.SetETag(w, updatedUser.Version)
 	w.WriteHeader(http.StatusOK)
 	w.Write(res)
 }
@@ -13,6 +13,7 @@ import (
 	"golang.org/x/crypto/bcrypt"
 
 	"github.com/tomoyane/grant-n-z/gnz/cache/structure"
+	"github.com/tomoyane/grant-n-z/gnz/driver"
 	"github.com/tomoyane/grant-n-z/gnz/entity"
 	"github.com/tomoyane/grant-n-z/gnz/log"
 	"github.com/tomoyane/grant-n-z/gnzserver/model"
@@ -80,6 +81,7 @@ func TestUser_Put(t *testing.T) {
 	response := StubResponseWriter{}
 	body := ioutil.NopCloser(bytes.NewReader([]byte("{\"username\":\"test\",\"email\":\"test@gmail.com\",\"password\":\"testtest\"}")))
 	request := http.Request{Header: http.Header{}, Method: http.MethodPut, Body: body}
+	request.Header.Set(middleware.IfMatch, `"1"`)
 
 	jwt := model.JwtPayload{
 		UserUuid: uuid.New().String(),
@@ -93,6 +95,36 @@ func TestUser_Put(t *testing.T) {
 	}
 }
 
+// Test put without If-Match
+func TestUser_Put_PreconditionRequired(t *testing.T) {
+	response := StubResponseWriter{}
+	body := ioutil.NopCloser(bytes.NewReader([]byte("{\"username\":\"test\",\"email\":\"test@gmail.com\",\"password\":\"testtest\"}")))
+	request := http.Request{Header: http.Header{}, Method: http.MethodPut, Body: body}
+	user.Put(response, &request)
+
+	if statusCode != http.StatusPreconditionRequired {
+		t.Errorf("Incorrect TestUser_Put_PreconditionRequired test.")
+		t.FailNow()
+	}
+}
+
+// Test get
+func TestUser_Get(t *testing.T) {
+	response := StubResponseWriter{}
+	request := http.Request{Header: http.Header{}, Method: http.MethodGet}
+
+	jwt := model.JwtPayload{
+		UserUuid: uuid.New().String(),
+		Username: "user",
+	}
+	user.Get(response, request.WithContext(context.WithValue(request.Context(), middleware.ScopeJwt, jwt)))
+
+	if statusCode != http.StatusOK {
+		t.Errorf("Incorrect TestUser_Get test.")
+		t.FailNow()
+	}
+}
+
 // Less than stub struct
 // UserService
 type StubUserService struct {
@@ -116,66 +148,66 @@ func (us StubUserService) ComparePw(passwordHash string, password string) bool {
 	return true
 }
 
-func (us StubUserService) GetUserByUuid(uuid string) (*entity.User, *model.ErrorResBody) {
+func (us StubUserService) GetUserByUuid(ctx context.Context, uuid string) (*entity.User, *model.ErrorResBody) {
 	return &entity.User{}, nil
 }
 
-func (us StubUserService) GetUserByEmail(email string) (*entity.User, *model.ErrorResBody) {
+func (us StubUserService) GetUserByEmail(ctx context.Context, email string) (*entity.User, *model.ErrorResBody) {
 	return &entity.User{}, nil
 }
 
-func (us StubUserService) GetUserWithOperatorPolicyByEmail(email string) (*model.UserWithOperatorPolicy, *model.ErrorResBody) {
+func (us StubUserService) GetUserWithOperatorPolicyByEmail(ctx context.Context, email string) (*model.UserWithOperatorPolicy, *model.ErrorResBody) {
 	return &model.UserWithOperatorPolicy{}, nil
 }
 
-func (us StubUserService) GetUserWithUserServiceWithServiceByEmail(email string) (*model.UserWithUserServiceWithService, *model.ErrorResBody) {
+func (us StubUserService) GetUserWithUserServiceWithServiceByEmail(ctx context.Context, email string) (*model.UserWithUserServiceWithService, *model.ErrorResBody) {
 	return &model.UserWithUserServiceWithService{}, nil
 }
 
-func (us StubUserService) GetUserGroupByUserUuidAndGroupUuid(userUuid string, groupUuid string) (*entity.UserGroup, *model.ErrorResBody) {
+func (us StubUserService) GetUserGroupByUserUuidAndGroupUuid(ctx context.Context, userUuid string, groupUuid string) (*entity.UserGroup, *model.ErrorResBody) {
 	return &entity.UserGroup{}, nil
 }
 
-func (us StubUserService) GetUserServices() ([]*entity.UserService, *model.ErrorResBody) {
+func (us StubUserService) GetUserServices(ctx context.Context) ([]*entity.UserService, *model.ErrorResBody) {
 	return []*entity.UserService{}, nil
 }
 
-func (us StubUserService) GetUserServicesByUserUuid(userUuid string) ([]*entity.UserService, *model.ErrorResBody) {
+func (us StubUserService) GetUserServicesByUserUuid(ctx context.Context, userUuid string) ([]*entity.UserService, *model.ErrorResBody) {
 	return []*entity.UserService{}, nil
 }
 
-func (us StubUserService) GetUserServiceByUserUuidAndServiceUuid(userUuid string, serviceUuid string) (*entity.UserService, *model.ErrorResBody) {
+func (us StubUserService) GetUserServiceByUserUuidAndServiceUuid(ctx context.Context, userUuid string, serviceUuid string) (*entity.UserService, *model.ErrorResBody) {
 	return &entity.UserService{}, nil
 }
 
-func (us StubUserService) GetUserByGroupUuid(groupUuid string) ([]*model.UserResponse, *model.ErrorResBody) {
-	return []*model.UserResponse{}, nil
+func (us StubUserService) GetUserByGroupUuid(ctx context.Context, groupUuid string, page driver.Page) ([]*model.UserResponse, string, *model.ErrorResBody) {
+	return []*model.UserResponse{}, "", nil
 }
 
-func (us StubUserService) GetUserPoliciesByUserUuid(userUuid string) []structure.UserPolicy {
+func (us StubUserService) GetUserPoliciesByUserUuid(ctx context.Context, userUuid string) []structure.UserPolicy {
 	return []structure.UserPolicy{}
 }
 
-func (us StubUserService) GetUserGroupsByUserUuid(userUuid string) []structure.UserGroup {
+func (us StubUserService) GetUserGroupsByUserUuid(ctx context.Context, userUuid string) []structure.UserGroup {
 	return []structure.UserGroup{}
 }
 
-func (us StubUserService) InsertUserGroup(userGroupEntity entity.UserGroup) (*entity.UserGroup, *model.ErrorResBody) {
+func (us StubUserService) InsertUserGroup(ctx context.Context, userGroupEntity entity.UserGroup) (*entity.UserGroup, *model.ErrorResBody) {
 	return &entity.UserGroup{}, nil
 }
 
-func (us StubUserService) InsertUser(user entity.User) (*entity.User, *model.ErrorResBody) {
+func (us StubUserService) InsertUser(ctx context.Context, user entity.User) (*entity.User, *model.ErrorResBody) {
 	return &entity.User{}, nil
 }
 
-func (us StubUserService) InsertUserWithUserService(user entity.User, userService entity.UserService) (*entity.User, *model.ErrorResBody) {
+func (us StubUserService) InsertUserWithUserService(ctx context.Context, user entity.User, userService entity.UserService) (*entity.User, *model.ErrorResBody) {
 	return &entity.User{}, nil
 }
 
-func (us StubUserService) InsertUserService(userServiceEntity entity.UserService) (*entity.UserService, *model.ErrorResBody) {
+func (us StubUserService) InsertUserService(ctx context.Context, userServiceEntity entity.UserService) (*entity.UserService, *model.ErrorResBody) {
 	return &entity.UserService{}, nil
 }
 
-func (us StubUserService) UpdateUser(user entity.User) (*entity.User, *model.ErrorResBody) {
+func (us StubUserService) UpdateUser(ctx context.Context, user entity.User) (*entity.User, *model.ErrorResBody) {
 	return &entity.User{}, nil
 }
@@ -0,0 +1,18 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Exit code of command
+const (
+	exitOk    = 0
+	exitError = 1
+)
+
+// Print result of command
+func printJson(value interface{}) {
+	res, _ := json.MarshalIndent(value, "", "  ")
+	fmt.Println(string(res))
+}
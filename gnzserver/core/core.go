@@ -40,7 +40,9 @@ func NewGrantNZServer() GrantNZServer {
 	log.InitLogger(common.App.LogLevel)
 	database := driver.NewDatabase()
 	database.Connect()
+	database.CheckSchema()
 	cache.InitEtcd()
+	cache.InitLocalCache(common.GServer.LocalCacheSize)
 	log.Logger.Info("New GrantNZServer")
 
 	signal.Notify(
@@ -69,6 +71,9 @@ func (g GrantNZServer) Run() {
 	go g.subscribeSignal(signalCode, exitCode)
 	go g.gracefulShutdown(shutdownCtx, exitCode, server)
 	go g.database.PingRdbms()
+	go g.database.CheckReplicas()
+	go g.database.PurgeSoftDeleted()
+	go cache.ReportLocalCacheMetrics()
 
 	g.runServer(g.runRouter())
 }
@@ -0,0 +1,58 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/tomoyane/grant-n-z/gnz/driver"
+)
+
+// Timeout of ping of /readyz
+const readyTimeout = 2 * time.Second
+
+// Response of /readyz
+type ReadyResponse struct {
+	Status   string `json:"status"`
+	Database string `json:"database"`
+	Breaker  string `json:"breaker"`
+}
+
+// Http GET method
+// The process is alive. It does not depend on database, so that database down does not restart the process
+// Endpoint is `/healthz`
+func Healthz(w http.ResponseWriter, r *http.Request) {
+	writeJson(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// Http GET method
+// Database is reachable, and api calls are not failed fast by circuit breaker
+// Endpoint is `/readyz`
+func Readyz(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), readyTimeout)
+	defer cancel()
+
+	res := ReadyResponse{Status: "ok", Database: "ok"}
+	if err := driver.NewDatabase().Ping(ctx); err != nil {
+		res.Status = "unavailable"
+		res.Database = err.Error()
+	}
+	res.Breaker = driver.BreakerState()
+	if res.Breaker == driver.BreakerOpen {
+		res.Status = "unavailable"
+	}
+
+	code := http.StatusOK
+	if res.Status != "ok" {
+		code = http.StatusServiceUnavailable
+	}
+	writeJson(w, code, res)
+}
+
+func writeJson(w http.ResponseWriter, code int, body interface{}) {
+	res, _ := json.Marshal(body)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	w.Write(res)
+}
@@ -0,0 +1,66 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/tomoyane/grant-n-z/gnz/common"
+	"github.com/tomoyane/grant-n-z/gnz/driver"
+	"github.com/tomoyane/grant-n-z/gnz/log"
+)
+
+const migrateUsage = `Usage:
+  gnzserver migrate up
+  gnzserver migrate down [steps]
+  gnzserver migrate status
+`
+
+// Run migrate command, and return exit code
+// It uses db of grant_n_z_server.yaml, and prints status of migrations
+func RunMigrateCommand(args []string) int {
+	if len(args) == 1 && args[0] == "up" {
+		return runMigrate(func(migrator driver.Migrator) ([]driver.MigrationStatus, error) {
+			return migrator.Up(context.Background())
+		})
+	}
+	if (len(args) == 1 || len(args) == 2) && args[0] == "down" {
+		steps := 1
+		if len(args) == 2 {
+			var err error
+			if steps, err = strconv.Atoi(args[1]); err != nil || steps <= 0 {
+				fmt.Fprint(os.Stderr, migrateUsage)
+				return exitError
+			}
+		}
+		return runMigrate(func(migrator driver.Migrator) ([]driver.MigrationStatus, error) {
+			return migrator.Down(context.Background(), steps)
+		})
+	}
+	if len(args) == 1 && args[0] == "status" {
+		return runMigrate(func(migrator driver.Migrator) ([]driver.MigrationStatus, error) {
+			return migrator.Status(context.Background())
+		})
+	}
+
+	fmt.Fprint(os.Stderr, migrateUsage)
+	return exitError
+}
+
+// Connect db, run command and print statuses
+func runMigrate(command func(migrator driver.Migrator) ([]driver.MigrationStatus, error)) int {
+	common.InitGrantNZServerConfig(ConfigFilePath)
+	log.InitLogger(common.App.LogLevel)
+	database := driver.NewDatabase()
+	database.Connect()
+	defer database.Close()
+
+	statuses, err := command(driver.NewMigrator())
+	printJson(statuses)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		return exitError
+	}
+	return exitOk
+}
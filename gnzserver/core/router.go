@@ -53,6 +53,7 @@ type GroupsRouter struct {
 type OperatorsRouter struct {
 	OperatorPolicy operator.OperatorPolicy
 	Service        operator.OperatorService
+	SoftDelete     operator.OperatorSoftDelete
 }
 
 func NewRouter() Router {
@@ -74,6 +75,7 @@ func NewRouter() Router {
 	operatorsRouter := OperatorsRouter{
 		OperatorPolicy: operator.GetOperatorPolicyInstance(),
 		Service:        operator.GetOperatorServiceInstance(),
+		SoftDelete:     operator.GetOperatorSoftDeleteInstance(),
 	}
 
 	return Router{
@@ -98,6 +100,8 @@ func (r Router) Run() *mux.Router {
 		w.Write([]byte(res.ToJson()))
 	})
 
+	r.mux.Use(middleware.ReadYourWrites, middleware.CircuitBreaker)
+	r.health()
 	r.v1()
 	r.operators()
 	return r.mux
@@ -117,6 +121,7 @@ func (r Router) v1() {
 	// Not required Client-Secret header
 	user := func() {
 		r.mux.HandleFunc("/api/v1/users", r.interceptor.Intercept(r.UsersRouter.User.Post)).Methods(http.MethodPost, http.MethodOptions)
+		r.mux.HandleFunc("/api/v1/users", r.interceptor.InterceptAuthenticateUser(r.UsersRouter.User.Get)).Methods(http.MethodGet, http.MethodOptions)
 		r.mux.HandleFunc("/api/v1/users", r.interceptor.InterceptAuthenticateUser(r.UsersRouter.User.Put)).Methods(http.MethodPut, http.MethodOptions)
 		r.mux.HandleFunc("/api/v1/users/group", r.interceptor.InterceptAuthenticateUser(r.UsersRouter.Group.Api))
 		r.mux.HandleFunc("/api/v1/users/service", r.interceptor.InterceptAuthenticateUser(r.UsersRouter.Service.Api))
@@ -140,8 +145,18 @@ func (r Router) v1() {
 	group()
 }
 
+func (r Router) health() {
+	r.mux.HandleFunc("/healthz", Healthz).Methods(http.MethodGet)
+	r.mux.HandleFunc("/readyz", Readyz).Methods(http.MethodGet)
+}
+
 func (r Router) operators() {
 	r.mux.HandleFunc("/api/operators/service", r.interceptor.InterceptAuthenticateOperator(r.OperatorsRouter.Service.Api))
+
+	// Soft delete and restore of users, groups, services, roles and permissions
+	softDelete := "/api/operators/{entity:users|groups|services|roles|permissions}/{uuid}"
+	r.mux.HandleFunc(softDelete, r.interceptor.InterceptAuthenticateOperator(r.OperatorsRouter.SoftDelete.Delete)).Methods(http.MethodDelete, http.MethodOptions)
+	r.mux.HandleFunc(softDelete+"/restore", r.interceptor.InterceptAuthenticateOperator(r.OperatorsRouter.SoftDelete.Restore)).Methods(http.MethodPost, http.MethodOptions)
 	//r.mux.HandleFunc("/api/operators/role", r.OperatorsRouter.OperatorService.Api)
 	//r.mux.HandleFunc("/api/operators/permission", r.OperatorsRouter.OperatorService.Api)
 	//r.mux.HandleFunc("/api/operators/policy", r.OperatorsRouter.OperatorService.Api)
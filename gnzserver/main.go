@@ -1,7 +1,14 @@
 package main
 
-import "github.com/tomoyane/grant-n-z/gnzserver/core"
+import (
+	"os"
+
+	"github.com/tomoyane/grant-n-z/gnzserver/core"
+)
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		os.Exit(core.RunMigrateCommand(os.Args[2:]))
+	}
 	core.NewGrantNZServer().Run()
 }
@@ -4,7 +4,9 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strconv"
 	"strings"
+	"time"
 
 	"encoding/json"
 	"io/ioutil"
@@ -14,19 +16,36 @@ import (
 
 	"github.com/gorilla/mux"
 	"github.com/tomoyane/grant-n-z/gnz/common"
+	"github.com/tomoyane/grant-n-z/gnz/driver"
 	"github.com/tomoyane/grant-n-z/gnz/log"
 	"github.com/tomoyane/grant-n-z/gnzserver/model"
+	"github.com/tomoyane/grant-n-z/gnzserver/service"
 )
 
 // Http Header, Request scope const
 const (
-	Authorization             = "Authorization"
-	ClientSecret              = "Client-Secret"
-	ContentType               = "Content-Type"
-	AccessControlAllowOrigin  = "Access-Control-Allow-Origin"
-	AccessControlAllowHeaders = "Access-Control-Allow-Headers"
-	ScopeSecret               = "secret"
-	ScopeJwt                  = "jwt"
+	Authorization              = "Authorization"
+	ClientSecret               = "Client-Secret"
+	ContentType                = "Content-Type"
+	AccessControlAllowOrigin   = "Access-Control-Allow-Origin"
+	AccessControlAllowHeaders  = "Access-Control-Allow-Headers"
+	AccessControlExposeHeaders = "Access-Control-Expose-Headers"
+	ETag                       = "ETag"
+	IfMatch                    = "If-Match"
+	IfNoneMatch                = "If-None-Match"
+	ScopeSecret                = "secret"
+	ScopeJwt                   = "jwt"
+)
+
+// Query parameter of list request const
+const (
+	QueryLimit       = "limit"
+	QueryCursor      = "cursor"
+	QueryName        = "name"
+	QueryEmail       = "email"
+	QueryCreatedFrom = "created_from"
+	QueryCreatedTo   = "created_to"
+	QuerySort        = "sort"
 )
 
 var iInstance Interceptor
@@ -53,6 +72,7 @@ type Interceptor interface {
 
 type InterceptorImpl struct {
 	tokenProcessor TokenProcessor
+	service        service.Service
 }
 
 func GetInterceptorInstance() Interceptor {
@@ -66,6 +86,7 @@ func NewInterceptor() Interceptor {
 	log.Logger.Info("New `Interceptor` instance")
 	return InterceptorImpl{
 		tokenProcessor: GetTokenProcessorInstance(),
+		service:        service.GetServiceInstance(),
 	}
 }
 
@@ -86,6 +107,9 @@ func (i InterceptorImpl) Intercept(next http.HandlerFunc) http.HandlerFunc {
 		secret, secretErr := interceptClientSecret(r)
 		if secretErr == nil {
 			r = r.WithContext(context.WithValue(r.Context(), ScopeSecret, *secret))
+			if r = i.interceptTenant(w, r, *secret); r == nil {
+				return
+			}
 		} else {
 			r = r.WithContext(context.WithValue(r.Context(), ScopeSecret, nil))
 		}
@@ -116,6 +140,9 @@ func (i InterceptorImpl) InterceptSecret(next http.HandlerFunc) http.HandlerFunc
 		}
 
 		r = r.WithContext(context.WithValue(r.Context(), ScopeSecret, *secret))
+		if r = i.interceptTenant(w, r, *secret); r == nil {
+			return
+		}
 		next.ServeHTTP(w, r)
 	}
 }
@@ -137,12 +164,15 @@ func (i InterceptorImpl) InterceptAuthenticateUser(next http.HandlerFunc) http.H
 		secret, secretErr := interceptClientSecret(r)
 		if secretErr == nil {
 			r = r.WithContext(context.WithValue(r.Context(), ScopeSecret, *secret))
+			if r = i.interceptTenant(w, r, *secret); r == nil {
+				return
+			}
 		} else {
 			r = r.WithContext(context.WithValue(r.Context(), ScopeSecret, nil))
 		}
 
 		token := r.Header.Get(Authorization)
-		jwtPayload, err := i.tokenProcessor.VerifyUserToken(token, "", "", "")
+		jwtPayload, err := i.tokenProcessor.VerifyUserToken(r.Context(), token, "", "", "")
 		if err != nil {
 			model.WriteError(w, err.ToJson(), err.Code)
 			return
@@ -176,7 +206,7 @@ func (i InterceptorImpl) InterceptAuthenticateGroupAdmin(next http.HandlerFunc)
 
 		token := r.Header.Get(Authorization)
 		groupId := ParamGroupUuid(r)
-		jwtPayload, err := i.tokenProcessor.VerifyUserToken(token, common.AdminRole, "", groupId)
+		jwtPayload, err := i.tokenProcessor.VerifyUserToken(r.Context(), token, common.AdminRole, "", groupId)
 		if err != nil {
 			model.WriteError(w, err.ToJson(), err.Code)
 			return
@@ -184,6 +214,9 @@ func (i InterceptorImpl) InterceptAuthenticateGroupAdmin(next http.HandlerFunc)
 
 		r = r.WithContext(context.WithValue(r.Context(), ScopeSecret, *secret))
 		r = r.WithContext(context.WithValue(r.Context(), ScopeJwt, *jwtPayload))
+		if r = i.interceptTenant(w, r, *secret); r == nil {
+			return
+		}
 		next.ServeHTTP(w, r)
 	}
 }
@@ -211,7 +244,7 @@ func (i InterceptorImpl) InterceptAuthenticateGroupUser(next http.HandlerFunc) h
 
 		token := r.Header.Get(Authorization)
 		groupId := ParamGroupUuid(r)
-		jwtPayload, err := i.tokenProcessor.VerifyUserToken(token, fmt.Sprintf("%s,%s", common.AdminRole, common.UserRole), "", groupId)
+		jwtPayload, err := i.tokenProcessor.VerifyUserToken(r.Context(), token, fmt.Sprintf("%s,%s", common.AdminRole, common.UserRole), "", groupId)
 		if err != nil {
 			model.WriteError(w, err.ToJson(), err.Code)
 			return
@@ -219,6 +252,9 @@ func (i InterceptorImpl) InterceptAuthenticateGroupUser(next http.HandlerFunc) h
 
 		r = r.WithContext(context.WithValue(r.Context(), ScopeSecret, *secret))
 		r = r.WithContext(context.WithValue(r.Context(), ScopeJwt, *jwtPayload))
+		if r = i.interceptTenant(w, r, *secret); r == nil {
+			return
+		}
 		next.ServeHTTP(w, r)
 	}
 }
@@ -238,7 +274,7 @@ func (i InterceptorImpl) InterceptAuthenticateOperator(next http.HandlerFunc) ht
 		}
 
 		token := r.Header.Get(Authorization)
-		jwtPayload, err := i.tokenProcessor.VerifyOperatorToken(token)
+		jwtPayload, err := i.tokenProcessor.VerifyOperatorToken(r.Context(), token)
 		if err != nil {
 			model.WriteError(w, err.ToJson(), err.Code)
 			return
@@ -249,11 +285,23 @@ func (i InterceptorImpl) InterceptAuthenticateOperator(next http.HandlerFunc) ht
 	}
 }
 
+// Scope repository calls of request to the service of Client-Secret, that is the tenant of request
+// Client-Secret that is not of any service is an error and nil, so that request is not unscoped
+func (i InterceptorImpl) interceptTenant(w http.ResponseWriter, r *http.Request, secret string) *http.Request {
+	ser, err := i.service.GetServiceBySecret(r.Context(), secret)
+	if err != nil {
+		model.WriteError(w, err.ToJson(), err.Code)
+		return nil
+	}
+	return r.WithContext(driver.WithTenant(r.Context(), ser.Uuid.String()))
+}
+
 // Intercept http request header
 func interceptHeader(w http.ResponseWriter, r *http.Request) *model.ErrorResBody {
 	w.Header().Set(ContentType, "application/json")
 	w.Header().Set(AccessControlAllowOrigin, "*")
 	w.Header().Set(AccessControlAllowHeaders, "*")
+	w.Header().Set(AccessControlExposeHeaders, ETag)
 	if err := validateHeader(r); err != nil {
 		model.WriteError(w, err.ToJson(), err.Code)
 		return err
@@ -261,6 +309,30 @@ func interceptHeader(w http.ResponseWriter, r *http.Request) *model.ErrorResBody
 	return nil
 }
 
+// Route reads of request that mutates data to primary after its write, so that it reads own writes
+// Reads of other requests are routed to replicas
+func ReadYourWrites(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodOptions {
+			r = r.WithContext(driver.WithReadYourWrites(r.Context()))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Fail api calls fast with 503 while circuit breaker of rdbms is open
+// Endpoints other than api, such as /healthz, are not affected
+func CircuitBreaker(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/api/") && !driver.Available() {
+			err := model.ServiceUnavailable("Database is unavailable. Retry later.")
+			model.WriteError(w, err.ToJson(), err.Code)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
 // Intercept Client-Secret header
 func interceptClientSecret(r *http.Request) (*string, error) {
 	clientSecret := r.Header.Get(ClientSecret)
@@ -302,6 +374,80 @@ func BindBody(w http.ResponseWriter, r *http.Request, i interface{}) *model.Erro
 	return nil
 }
 
+// Set version of data to ETag header
+func SetETag(w http.ResponseWriter, version int) {
+	w.Header().Set(ETag, fmt.Sprintf(`"%d"`, version))
+}
+
+// Bind version of If-Match header, that is ETag of GET response
+// If it is missing, 428. If it is not ETag of version, 412 because it never matches
+func BindIfMatch(w http.ResponseWriter, r *http.Request) (int, *model.ErrorResBody) {
+	ifMatch := r.Header.Get(IfMatch)
+	if ifMatch == "" {
+		err := model.PreconditionRequired("Required If-Match header.")
+		model.WriteError(w, err.ToJson(), err.Code)
+		return 0, err
+	}
+
+	version, parseErr := strconv.Atoi(strings.Trim(ifMatch, `"`))
+	if parseErr != nil || version <= 0 || !strings.HasPrefix(ifMatch, `"`) || !strings.HasSuffix(ifMatch, `"`) {
+		err := model.PreconditionFailed("If-Match does not match.")
+		model.WriteError(w, err.ToJson(), err.Code)
+		return 0, err
+	}
+	return version, nil
+}
+
+// Bind pagination, filters and sort of query parameters
+// Time of created_from and created_to is RFC3339
+// Email filter is only for users, and it is bad request
+func BindPage(w http.ResponseWriter, r *http.Request) (driver.Page, *model.ErrorResBody) {
+	if _, ok := r.URL.Query()[QueryEmail]; ok {
+		err := model.BadRequest(fmt.Sprintf("Query %s is not supported.", QueryEmail))
+		model.WriteError(w, err.ToJson(), err.Code)
+		return driver.Page{}, err
+	}
+	return BindUserPage(w, r)
+}
+
+// Bind pagination, filters and sort of query parameters of users
+// It is BindPage with email filter
+func BindUserPage(w http.ResponseWriter, r *http.Request) (driver.Page, *model.ErrorResBody) {
+	query := r.URL.Query()
+	page := driver.Page{
+		Cursor:     query.Get(QueryCursor),
+		NamePrefix: query.Get(QueryName),
+		Email:      query.Get(QueryEmail),
+		Sort:       query.Get(QuerySort),
+	}
+
+	var parseErr error
+	if limit := query.Get(QueryLimit); limit != "" {
+		if page.Limit, parseErr = strconv.Atoi(limit); parseErr == nil && page.Limit == 0 {
+			parseErr = errors.New(fmt.Sprintf("Limit must be 1 to %d.", driver.MaxPageLimit))
+		}
+	}
+	if createdFrom := query.Get(QueryCreatedFrom); createdFrom != "" && parseErr == nil {
+		page.CreatedFrom = new(time.Time)
+		*page.CreatedFrom, parseErr = time.Parse(time.RFC3339, createdFrom)
+	}
+	if createdTo := query.Get(QueryCreatedTo); createdTo != "" && parseErr == nil {
+		page.CreatedTo = new(time.Time)
+		*page.CreatedTo, parseErr = time.Parse(time.RFC3339, createdTo)
+	}
+	if parseErr == nil {
+		parseErr = page.Validate()
+	}
+
+	if parseErr != nil {
+		log.Logger.Info(parseErr.Error())
+		err := model.BadRequest(parseErr.Error())
+		model.WriteError(w, err.ToJson(), err.Code)
+		return page, err
+	}
+	return page, nil
+}
+
 // Validate request body
 func ValidateBody(w http.ResponseWriter, i interface{}) *model.ErrorResBody {
 	if err := validator.New().Struct(i); err != nil {
@@ -344,3 +490,13 @@ func ValidateTokenRequest(w http.ResponseWriter, tokenRequest *model.TokenReques
 func ParamGroupUuid(r *http.Request) string {
 	return mux.Vars(r)["group_uuid"]
 }
+
+// Parse request entity of path parameter
+func ParamEntity(r *http.Request) string {
+	return mux.Vars(r)["entity"]
+}
+
+// Parse request uuid of path parameter
+func ParamUuid(r *http.Request) string {
+	return mux.Vars(r)["uuid"]
+}
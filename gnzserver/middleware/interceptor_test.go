@@ -38,8 +38,9 @@ func init() {
 	})
 
 	userService := service.UserServiceImpl{
-		UserRepository: StubUserRepositoryImpl{Connection: stubConnection},
-		EtcdClient:     cache.EtcdClientImpl{Connection: stubEtcdConnection},
+		UserRepository:   StubUserRepositoryImpl{Connection: stubConnection},
+		PolicyRepository: StubPolicyRepositoryImpl{Connection: stubConnection},
+		EtcdClient:       cache.EtcdClientImpl{Connection: stubEtcdConnection},
 	}
 
 	operatorPolicyService := service.OperatorPolicyServiceImpl{
@@ -88,7 +89,7 @@ func init() {
 		ServerConfig:          serviceConfig,
 	}
 
-	interceptor = InterceptorImpl{tokenProcessor: tokenProcessor}
+	interceptor = InterceptorImpl{tokenProcessor: tokenProcessor, service: ser}
 }
 
 // Test constructor
@@ -198,6 +199,78 @@ func TestBindBody_Success(t *testing.T) {
 	}
 }
 
+// Test bind If-Match header
+func TestBindIfMatch(t *testing.T) {
+	cases := []struct {
+		ifMatch string
+		version int
+		code    int
+	}{
+		{`"3"`, 3, 0},
+		{"", 0, http.StatusPreconditionRequired},
+		{"3", 0, http.StatusPreconditionFailed},
+		{`W/"3"`, 0, http.StatusPreconditionFailed},
+		{`"0"`, 0, http.StatusPreconditionFailed},
+		{"*", 0, http.StatusPreconditionFailed},
+	}
+
+	for _, c := range cases {
+		request := http.Request{Header: http.Header{}}
+		request.Header.Set(IfMatch, c.ifMatch)
+		version, err := BindIfMatch(StubResponseWriter{}, &request)
+		if version != c.version || (err == nil) != (c.code == 0) || (err != nil && err.Code != c.code) {
+			t.Errorf("Incorrect TestBindIfMatch test. If-Match = %s", c.ifMatch)
+			t.FailNow()
+		}
+	}
+}
+
+// Test bind page of query parameters
+func TestBindPage(t *testing.T) {
+	cases := []struct {
+		query string
+		limit int
+		code  int
+	}{
+		{"", 0, 0},
+		{"limit=10&name=gr&sort=-name", 10, 0},
+		{"email=test@gmail.com", 0, http.StatusBadRequest},
+		{"email=", 0, http.StatusBadRequest},
+		{"created_from=2020-01-01T00:00:00Z&created_to=2020-02-01T00:00:00%2B09:00", 0, 0},
+		{"limit=0", 0, http.StatusBadRequest},
+		{"limit=1001", 0, http.StatusBadRequest},
+		{"limit=a", 0, http.StatusBadRequest},
+		{"sort=email", 0, http.StatusBadRequest},
+		{"cursor=invalid", 0, http.StatusBadRequest},
+		{"created_from=2020-01-01", 0, http.StatusBadRequest},
+	}
+
+	for _, c := range cases {
+		request := http.Request{URL: &url.URL{RawQuery: c.query}}
+		page, err := BindPage(StubResponseWriter{}, &request)
+		if (err == nil) != (c.code == 0) || (err != nil && err.Code != c.code) || (err == nil && page.Limit != c.limit) {
+			t.Errorf("Incorrect TestBindPage test. query = %s", c.query)
+			t.FailNow()
+		}
+	}
+}
+
+// Test bind page of users
+func TestBindUserPage(t *testing.T) {
+	request := http.Request{URL: &url.URL{RawQuery: "limit=10&email=test@gmail.com"}}
+	page, err := BindUserPage(StubResponseWriter{}, &request)
+	if err != nil || page.Limit != 10 || page.Email != "test@gmail.com" {
+		t.Errorf("Incorrect TestBindUserPage test. page = %v", page)
+		t.FailNow()
+	}
+
+	request = http.Request{URL: &url.URL{RawQuery: "limit=0&email=test@gmail.com"}}
+	if _, err := BindUserPage(StubResponseWriter{}, &request); err == nil || err.Code != http.StatusBadRequest {
+		t.Errorf("Incorrect TestBindUserPage test")
+		t.FailNow()
+	}
+}
+
 // Test bind request body
 func TestValidateBody_Error(t *testing.T) {
 	writer := StubResponseWriter{}
@@ -313,6 +386,20 @@ func TestParamGroupId(t *testing.T) {
 	}
 }
 
+// Test api call is served while circuit breaker is closed
+func TestCircuitBreaker(t *testing.T) {
+	served := false
+	handler := CircuitBreaker(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		served = true
+	}))
+	request := http.Request{Method: http.MethodGet, Header: http.Header{}, URL: &url.URL{Path: "/api/v1/users"}}
+	handler.ServeHTTP(StubResponseWriter{}, &request)
+	if !served {
+		t.Errorf("Incorrect TestCircuitBreaker test.")
+		t.FailNow()
+	}
+}
+
 type StubResponseWriter struct {
 }
 
@@ -1,6 +1,7 @@
 package middleware
 
 import (
+	"context"
 	"crypto/md5"
 	"encoding/hex"
 	"github.com/google/uuid"
@@ -33,7 +34,8 @@ func NewMigration() Migration {
 }
 
 func (m Migration) V1() {
-	if !m.checkV1Migration() {
+	ctx := context.Background()
+	if !m.checkV1Migration(ctx) {
 		return
 	}
 
@@ -48,7 +50,7 @@ func (m Migration) V1() {
 		Email:      "operator@gmail.com",
 		Password:   "grant_n_z_operator",
 	}
-	savedOperatorUser, userErr := m.userService.InsertUser(operatorUser)
+	savedOperatorUser, userErr := m.userService.InsertUser(ctx, operatorUser)
 	if userErr != nil {
 		if userErr.Code != http.StatusConflict {
 			panic("Failed to generate user for migration")
@@ -64,7 +66,7 @@ func (m Migration) V1() {
 		Uuid:       roleUidMd5,
 		Name:       common.OperatorRole,
 	}
-	_, roleErr1 := m.roleService.InsertRole(&operatorRole)
+	_, roleErr1 := m.roleService.InsertRole(ctx, &operatorRole)
 	if roleErr1 != nil {
 		if roleErr1.Code != http.StatusConflict {
 			panic("Failed to generate operator role for migration")
@@ -79,7 +81,7 @@ func (m Migration) V1() {
 		Uuid:       roleUidMd5,
 		Name:       common.AdminRole,
 	}
-	_, roleErr2 := m.roleService.InsertRole(&adminRole)
+	_, roleErr2 := m.roleService.InsertRole(ctx, &adminRole)
 	if roleErr2 != nil {
 		if roleErr2.Code != http.StatusConflict {
 			panic("Failed to generate admin role for migration")
@@ -94,7 +96,7 @@ func (m Migration) V1() {
 		Uuid:       roleUidMd5,
 		Name:       common.UserRole,
 	}
-	_, roleErr3 := m.roleService.InsertRole(&userRole)
+	_, roleErr3 := m.roleService.InsertRole(ctx, &userRole)
 	if roleErr3 != nil {
 		if roleErr3.Code != http.StatusConflict {
 			panic("Failed to generate user role for migration")
@@ -110,7 +112,7 @@ func (m Migration) V1() {
 		Uuid:       permissionUid,
 		Name:       common.AdminPermission,
 	}
-	_, permissionErr01 := m.permissionService.InsertPermission(&adminPermission)
+	_, permissionErr01 := m.permissionService.InsertPermission(ctx, &adminPermission)
 	if permissionErr01 != nil {
 		if permissionErr01.Code != http.StatusConflict {
 			panic("Failed to generate admin permission for migration")
@@ -125,7 +127,7 @@ func (m Migration) V1() {
 		Uuid:       permissionUid,
 		Name:       common.ReadPermission,
 	}
-	_, permissionErr02 := m.permissionService.InsertPermission(&readPermission)
+	_, permissionErr02 := m.permissionService.InsertPermission(ctx, &readPermission)
 	if permissionErr02 != nil {
 		if permissionErr02.Code != http.StatusConflict {
 			panic("Failed to generate read permission for migration")
@@ -140,7 +142,7 @@ func (m Migration) V1() {
 		Uuid:       permissionUid,
 		Name:       common.WritePermission,
 	}
-	_, permissionErr03 := m.permissionService.InsertPermission(&writePermission)
+	_, permissionErr03 := m.permissionService.InsertPermission(ctx, &writePermission)
 	if permissionErr03 != nil {
 		if permissionErr03.Code != http.StatusConflict {
 			panic("Failed to generate write permission for migration")
@@ -155,7 +157,7 @@ func (m Migration) V1() {
 		UserUuid:   savedOperatorUser.Uuid,
 		RoleUuid:   operatorRole.Uuid,
 	}
-	_, operatorRoleMemberErr := m.operatorPolicyService.Insert(&operatorMemberRole)
+	_, operatorRoleMemberErr := m.operatorPolicyService.Insert(ctx, &operatorMemberRole)
 	if operatorRoleMemberErr != nil {
 		if operatorRoleMemberErr.Code != http.StatusConflict {
 			panic("Error generate operator policies for migration")
@@ -164,26 +166,26 @@ func (m Migration) V1() {
 	log.Logger.Info("Generate to operator_policies for migration")
 }
 
-func (m Migration) checkV1Migration() bool {
-	operatorAdminRole, err := m.roleService.GetRoleByName(common.OperatorRole)
+func (m Migration) checkV1Migration(ctx context.Context) bool {
+	operatorAdminRole, err := m.roleService.GetRoleByName(ctx, common.OperatorRole)
 	if err != nil && err.Code != http.StatusNotFound {
 		log.Logger.Info("Not found operator role")
 		panic(failedMigrationMsg)
 	}
 
-	adminRole, err := m.roleService.GetRoleByName(common.AdminRole)
+	adminRole, err := m.roleService.GetRoleByName(ctx, common.AdminRole)
 	if err != nil && err.Code != http.StatusNotFound {
 		log.Logger.Info("Not found admin role")
 		panic(failedMigrationMsg)
 	}
 
-	adminPermission, err := m.permissionService.GetPermissionByName(common.AdminPermission)
+	adminPermission, err := m.permissionService.GetPermissionByName(ctx, common.AdminPermission)
 	if err != nil && err.Code != http.StatusNotFound {
 		log.Logger.Info("Not found admin permission")
 		panic(failedMigrationMsg)
 	}
 	var operatorPolicy []*entity.OperatorPolicy
-	operatorPolicy, err = m.operatorPolicyService.GetAll()
+	operatorPolicy, err = m.operatorPolicyService.GetAll(ctx)
 	if err != nil && err.Code != http.StatusNotFound {
 		panic(failedMigrationMsg)
 	}
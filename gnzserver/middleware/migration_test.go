@@ -25,8 +25,9 @@ func init() {
 	})
 
 	userService := service.UserServiceImpl{
-		UserRepository: StubUserRepositoryImpl{Connection: stubConnection},
-		EtcdClient:     cache.EtcdClientImpl{Connection: stubEtcdConnection},
+		UserRepository:   StubUserRepositoryImpl{Connection: stubConnection},
+		PolicyRepository: StubPolicyRepositoryImpl{Connection: stubConnection},
+		EtcdClient:       cache.EtcdClientImpl{Connection: stubEtcdConnection},
 	}
 
 	operatorPolicyService := service.OperatorPolicyServiceImpl{
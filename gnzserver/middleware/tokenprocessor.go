@@ -1,6 +1,7 @@
 package middleware
 
 import (
+	"context"
 	"errors"
 	"github.com/google/uuid"
 	"strconv"
@@ -22,13 +23,13 @@ var tpInstance TokenProcessor
 
 type TokenProcessor interface {
 	// Generate jwt token
-	Generate(userType string, tokenRequest model.TokenRequest) (*model.TokenResponse, *model.ErrorResBody)
+	Generate(ctx context.Context, userType string, tokenRequest model.TokenRequest) (*model.TokenResponse, *model.ErrorResBody)
 
 	// Verify operator token
-	VerifyOperatorToken(token string) (*model.JwtPayload, *model.ErrorResBody)
+	VerifyOperatorToken(ctx context.Context, token string) (*model.JwtPayload, *model.ErrorResBody)
 
 	// Verify user token
-	VerifyUserToken(token string, roleNames string, permissionNames string, groupUuid string) (*model.JwtPayload, *model.ErrorResBody)
+	VerifyUserToken(ctx context.Context, token string, roleNames string, permissionNames string, groupUuid string) (*model.JwtPayload, *model.ErrorResBody)
 
 	// Get auth user data in token
 	// If invalid token, return 401
@@ -72,22 +73,22 @@ func NewTokenProcessor() TokenProcessor {
 	}
 }
 
-func (tp TokenProcessorImpl) Generate(userType string, tokenRequest model.TokenRequest) (*model.TokenResponse, *model.ErrorResBody) {
+func (tp TokenProcessorImpl) Generate(ctx context.Context, userType string, tokenRequest model.TokenRequest) (*model.TokenResponse, *model.ErrorResBody) {
 	if strings.EqualFold(tokenRequest.GrantType, model.GrantPassword.String()) {
 		switch userType {
 		case common.AuthOperator:
-			return tp.generateOperatorToken(tokenRequest)
+			return tp.generateOperatorToken(ctx, tokenRequest)
 		case common.AuthUser:
-			return tp.generateUserToken(tokenRequest)
+			return tp.generateUserToken(ctx, tokenRequest)
 		default:
 			return nil, model.BadRequest("Not support type of query parameter")
 		}
 	} else {
-		return tp.generateTokenByRefreshToken(tokenRequest.RefreshToken)
+		return tp.generateTokenByRefreshToken(ctx, tokenRequest.RefreshToken)
 	}
 }
 
-func (tp TokenProcessorImpl) VerifyOperatorToken(token string) (*model.JwtPayload, *model.ErrorResBody) {
+func (tp TokenProcessorImpl) VerifyOperatorToken(ctx context.Context, token string) (*model.JwtPayload, *model.ErrorResBody) {
 	jwtPayload, err := tp.GetJwtPayload(token, false)
 	if err != nil || jwtPayload.IsRefresh {
 		return nil, err
@@ -106,7 +107,7 @@ func (tp TokenProcessorImpl) VerifyOperatorToken(token string) (*model.JwtPayloa
 	return jwtPayload, nil
 }
 
-func (tp TokenProcessorImpl) VerifyUserToken(token string, roleNames string, permissionNames string, groupUuid string) (*model.JwtPayload, *model.ErrorResBody) {
+func (tp TokenProcessorImpl) VerifyUserToken(ctx context.Context, token string, roleNames string, permissionNames string, groupUuid string) (*model.JwtPayload, *model.ErrorResBody) {
 	jwtPayload, err := tp.GetJwtPayload(token, false)
 	if err != nil || jwtPayload.IsRefresh {
 		return nil, err
@@ -114,7 +115,7 @@ func (tp TokenProcessorImpl) VerifyUserToken(token string, roleNames string, per
 
 	if groupUuid != "" {
 		hasGroup := false
-		userGroups := tp.UserService.GetUserGroupsByUserUuid(jwtPayload.UserUuid)
+		userGroups := tp.UserService.GetUserGroupsByUserUuid(ctx, jwtPayload.UserUuid)
 		for _, group := range userGroups {
 			if strings.EqualFold(groupUuid, group.GroupUuid) {
 				hasGroup = true
@@ -128,7 +129,7 @@ func (tp TokenProcessorImpl) VerifyUserToken(token string, roleNames string, per
 	hasRole := false
 	hasPermission := false
 	if roleNames != "" || permissionNames != "" {
-		userPolicies := tp.UserService.GetUserPoliciesByUserUuid(jwtPayload.UserUuid)
+		userPolicies := tp.UserService.GetUserPoliciesByUserUuid(ctx, jwtPayload.UserUuid)
 		if userPolicies == nil {
 			return nil, model.Forbidden("Forbidden the user has not policy")
 		}
@@ -171,8 +172,8 @@ func (tp TokenProcessorImpl) GetJwtPayload(token string, isRefresh bool) (*model
 	return &payload, nil
 }
 
-func (tp TokenProcessorImpl) generateOperatorToken(tokenRequest model.TokenRequest) (*model.TokenResponse, *model.ErrorResBody) {
-	targetUser, err := tp.UserService.GetUserWithOperatorPolicyByEmail(tokenRequest.Email)
+func (tp TokenProcessorImpl) generateOperatorToken(ctx context.Context, tokenRequest model.TokenRequest) (*model.TokenResponse, *model.ErrorResBody) {
+	targetUser, err := tp.UserService.GetUserWithOperatorPolicyByEmail(ctx, tokenRequest.Email)
 	if err != nil || targetUser == nil {
 		return nil, model.Unauthorized("Failed to email or password")
 	}
@@ -196,8 +197,8 @@ func (tp TokenProcessorImpl) generateOperatorToken(tokenRequest model.TokenReque
 	return token, nil
 }
 
-func (tp TokenProcessorImpl) generateUserToken(tokenRequest model.TokenRequest) (*model.TokenResponse, *model.ErrorResBody) {
-	user, err := tp.UserService.GetUserByEmail(tokenRequest.Email)
+func (tp TokenProcessorImpl) generateUserToken(ctx context.Context, tokenRequest model.TokenRequest) (*model.TokenResponse, *model.ErrorResBody) {
+	user, err := tp.UserService.GetUserByEmail(ctx, tokenRequest.Email)
 	if err != nil {
 		return nil, model.Unauthorized("Failed to email or password")
 	}
@@ -206,7 +207,7 @@ func (tp TokenProcessorImpl) generateUserToken(tokenRequest model.TokenRequest)
 		return nil, model.Unauthorized("Failed to email or password")
 	}
 
-	policies, err := tp.PolicyService.GetPoliciesByUser(user.Uuid.String())
+	policies, err := tp.PolicyService.GetPoliciesByUser(ctx, user.Uuid.String())
 	if err != nil {
 		return nil, model.Forbidden("Can't issue token for group")
 	}
@@ -227,7 +228,7 @@ func (tp TokenProcessorImpl) generateUserToken(tokenRequest model.TokenRequest)
 	return tp.generateTokenResponse(exp, rExp, userPolicies, user.Uuid.String(), user.Username), nil
 }
 
-func (tp TokenProcessorImpl) generateTokenByRefreshToken(refreshToken string) (*model.TokenResponse, *model.ErrorResBody) {
+func (tp TokenProcessorImpl) generateTokenByRefreshToken(ctx context.Context, refreshToken string) (*model.TokenResponse, *model.ErrorResBody) {
 	jwtPayload, err := tp.GetJwtPayload(refreshToken, true)
 	if err != nil {
 		return nil, err
@@ -236,7 +237,7 @@ func (tp TokenProcessorImpl) generateTokenByRefreshToken(refreshToken string) (*
 	exp := time.Now().Add(time.Hour * time.Duration(tp.ServerConfig.TokenExpireHour))
 	rExp := time.Now().Add(time.Hour * time.Duration(tp.ServerConfig.TokenExpireHour) * 200)
 
-	policies := tp.UserService.GetUserPoliciesByUserUuid(jwtPayload.UserUuid)
+	policies := tp.UserService.GetUserPoliciesByUserUuid(ctx, jwtPayload.UserUuid)
 	if policies == nil {
 		policies = []structure.UserPolicy{}
 	}
@@ -1,6 +1,7 @@
 package middleware
 
 import (
+	"context"
 	"fmt"
 	"testing"
 
@@ -9,8 +10,10 @@ import (
 	"github.com/dgrijalva/jwt-go"
 	"github.com/google/uuid"
 	"github.com/jinzhu/gorm"
+	"github.com/tomoyane/grant-n-z/gnz/cache"
 	"github.com/tomoyane/grant-n-z/gnz/cache/structure"
 	"github.com/tomoyane/grant-n-z/gnz/common"
+	"github.com/tomoyane/grant-n-z/gnz/driver"
 	"github.com/tomoyane/grant-n-z/gnz/entity"
 	"github.com/tomoyane/grant-n-z/gnz/log"
 	"github.com/tomoyane/grant-n-z/gnzserver/model"
@@ -27,8 +30,9 @@ func init() {
 	stubConnection, _ := gorm.Open("sqlite3", "/tmp/test_grant_nz.db")
 
 	userService := service.UserServiceImpl{
-		UserRepository: StubUserRepositoryImpl{Connection: stubConnection},
-		EtcdClient:     StubEtcdlClient{},
+		UserRepository:   StubUserRepositoryImpl{Connection: stubConnection},
+		PolicyRepository: StubPolicyRepositoryImpl{Connection: stubConnection},
+		EtcdClient:       StubEtcdlClient{},
 	}
 
 	operatorPolicyService := service.OperatorPolicyServiceImpl{
@@ -95,6 +99,7 @@ func TestGetTokenProcessorInstance(t *testing.T) {
 // Test Generate token error
 func TestGenerate_Error(t *testing.T) {
 	_, err := tokenProcessor.Generate(
+		context.Background(),
 		common.AuthOperator,
 		model.TokenRequest{
 			GrantType: "password",
@@ -108,6 +113,7 @@ func TestGenerate_Error(t *testing.T) {
 	}
 
 	_, err = tokenProcessor.Generate(
+		context.Background(),
 		common.AuthOperator,
 		model.TokenRequest{
 			GrantType: "password",
@@ -121,6 +127,7 @@ func TestGenerate_Error(t *testing.T) {
 	}
 
 	_, err = tokenProcessor.Generate(
+		context.Background(),
 		common.AuthUser,
 		model.TokenRequest{
 			Password: "testa",
@@ -132,6 +139,7 @@ func TestGenerate_Error(t *testing.T) {
 	}
 
 	_, err = tokenProcessor.Generate(
+		context.Background(),
 		"",
 		model.TokenRequest{
 			GrantType: "password",
@@ -145,6 +153,7 @@ func TestGenerate_Error(t *testing.T) {
 	}
 
 	_, err = tokenProcessor.Generate(
+		context.Background(),
 		"none",
 		model.TokenRequest{
 			GrantType: "password",
@@ -161,6 +170,7 @@ func TestGenerate_Error(t *testing.T) {
 // Test Generate token success
 func TestGenerate_Success(t *testing.T) {
 	_, err := tokenProcessor.Generate(
+		context.Background(),
 		common.AuthUser,
 		model.TokenRequest{
 			GrantType: "password",
@@ -177,6 +187,7 @@ func TestGenerate_Success(t *testing.T) {
 // Test Generate refresh token success
 func TestGenerateRefreshToken_Success(t *testing.T) {
 	to, _ := tokenProcessor.Generate(
+		context.Background(),
 		common.AuthUser,
 		model.TokenRequest{
 			GrantType: "password",
@@ -186,6 +197,7 @@ func TestGenerateRefreshToken_Success(t *testing.T) {
 	)
 
 	_, err := tokenProcessor.Generate(
+		context.Background(),
 		common.AuthUser,
 		model.TokenRequest{
 			GrantType:    model.GrantRefreshToken.String(),
@@ -202,6 +214,7 @@ func TestGenerateRefreshToken_Success(t *testing.T) {
 // Test verify operator token
 func TestVerifyOperatorToken_Error(t *testing.T) {
 	token, _ := tokenProcessor.Generate(
+		context.Background(),
 		common.AuthUser,
 		model.TokenRequest{
 			GrantType: "password",
@@ -210,7 +223,7 @@ func TestVerifyOperatorToken_Error(t *testing.T) {
 		},
 	)
 
-	_, err := tokenProcessor.VerifyOperatorToken(token.Token)
+	_, err := tokenProcessor.VerifyOperatorToken(context.Background(), token.Token)
 	if err == nil {
 		t.Errorf("Incorrect TestVerifyOperatorToken_Error test.")
 		t.FailNow()
@@ -220,6 +233,7 @@ func TestVerifyOperatorToken_Error(t *testing.T) {
 // Test verify operator token
 func TestVerifyOperatorToken_Success(t *testing.T) {
 	token, _ := tokenProcessor.Generate(
+		context.Background(),
 		common.AuthOperator,
 		model.TokenRequest{
 			GrantType: "password",
@@ -227,7 +241,7 @@ func TestVerifyOperatorToken_Success(t *testing.T) {
 			Password:  "test",
 		},
 	)
-	_, err := tokenProcessor.VerifyOperatorToken(fmt.Sprintf("Bearer %s", token.Token))
+	_, err := tokenProcessor.VerifyOperatorToken(context.Background(), fmt.Sprintf("Bearer %s", token.Token))
 	if err != nil {
 		t.Errorf("Incorrect TestVerifyOperatorToken_Success test." + err.ToJson())
 		t.FailNow()
@@ -236,7 +250,7 @@ func TestVerifyOperatorToken_Success(t *testing.T) {
 
 // Test verify user token
 func TestVerifyUserToken_Error(t *testing.T) {
-	_, err := tokenProcessor.VerifyUserToken("test_token", "test_role", "test_permission", "")
+	_, err := tokenProcessor.VerifyUserToken(context.Background(), "test_token", "test_role", "test_permission", "")
 	if err == nil {
 		t.Errorf("Incorrect TestVerifyUserToken_Error test.")
 		t.FailNow()
@@ -246,6 +260,7 @@ func TestVerifyUserToken_Error(t *testing.T) {
 // Test verify user token
 func TestVerifyUserToken_Success(t *testing.T) {
 	token, _ := tokenProcessor.Generate(
+		context.Background(),
 		common.AuthUser,
 		model.TokenRequest{
 			GrantType: "password",
@@ -253,7 +268,7 @@ func TestVerifyUserToken_Success(t *testing.T) {
 			Password:  "test",
 		},
 	)
-	_, err := tokenProcessor.VerifyUserToken("Bearer "+token.Token, "test_role", "test_permission", "")
+	_, err := tokenProcessor.VerifyUserToken(context.Background(), "Bearer "+token.Token, "test_role", "test_permission", "")
 	if err != nil {
 		t.Errorf("Incorrect TestVerifyUserToken_Success test." + err.ToJson())
 		t.FailNow()
@@ -266,12 +281,12 @@ type StubUserRepositoryImpl struct {
 	Connection *gorm.DB
 }
 
-func (uri StubUserRepositoryImpl) FindByUuid(uuid string) (*entity.User, error) {
+func (uri StubUserRepositoryImpl) FindByUuid(ctx context.Context, uuid string) (*entity.User, error) {
 	var user entity.User
 	return &user, nil
 }
 
-func (uri StubUserRepositoryImpl) FindByEmail(email string) (*entity.User, error) {
+func (uri StubUserRepositoryImpl) FindByEmail(ctx context.Context, email string) (*entity.User, error) {
 	user := entity.User{
 		Username: "test",
 		Email:    email,
@@ -280,12 +295,12 @@ func (uri StubUserRepositoryImpl) FindByEmail(email string) (*entity.User, error
 	return &user, nil
 }
 
-func (uri StubUserRepositoryImpl) FindByGroupUuid(groupUuid string) ([]*entity.User, error) {
+func (uri StubUserRepositoryImpl) FindByGroupUuid(ctx context.Context, groupUuid string, page driver.Page) ([]*entity.User, string, error) {
 	var users []*entity.User
-	return users, nil
+	return users, "", nil
 }
 
-func (uri StubUserRepositoryImpl) FindWithOperatorPolicyByEmail(email string) (*model.UserWithOperatorPolicy, error) {
+func (uri StubUserRepositoryImpl) FindWithOperatorPolicyByEmail(ctx context.Context, email string) (*model.UserWithOperatorPolicy, error) {
 	user := entity.User{
 		InternalId: "",
 		Username:   "test",
@@ -304,58 +319,78 @@ func (uri StubUserRepositoryImpl) FindWithOperatorPolicyByEmail(email string) (*
 	return &uwo, nil
 }
 
-func (uri StubUserRepositoryImpl) FindWithUserServiceWithServiceByEmail(email string) (*model.UserWithUserServiceWithService, error) {
+func (uri StubUserRepositoryImpl) FindWithUserServiceWithServiceByEmail(ctx context.Context, email string) (*model.UserWithUserServiceWithService, error) {
 	var uus model.UserWithUserServiceWithService
 	return &uus, nil
 }
 
-func (uri StubUserRepositoryImpl) FindUserGroupByUserUuidAndGroupUuid(userUuid string, groupUuid string) (*entity.UserGroup, error) {
+func (uri StubUserRepositoryImpl) FindUserGroupByUserUuidAndGroupUuid(ctx context.Context, userUuid string, groupUuid string) (*entity.UserGroup, error) {
 	var userGroup entity.UserGroup
 	return &userGroup, nil
 }
 
-func (uri StubUserRepositoryImpl) FindUserServices() ([]*entity.UserService, error) {
+func (uri StubUserRepositoryImpl) FindUserServices(ctx context.Context) ([]*entity.UserService, error) {
 	var userServices []*entity.UserService
 	return userServices, nil
 }
 
-func (uri StubUserRepositoryImpl) FindUserServicesByUserUuid(userUuid string) ([]*entity.UserService, error) {
+func (uri StubUserRepositoryImpl) FindUserServicesByUserUuid(ctx context.Context, userUuid string) ([]*entity.UserService, error) {
 	var userServices []*entity.UserService
 	return userServices, nil
 }
 
-func (uri StubUserRepositoryImpl) FindUserServicesOffSetAndLimit(offset int, limit int) ([]*entity.UserService, error) {
+func (uri StubUserRepositoryImpl) FindUserServicesOffSetAndLimit(ctx context.Context, offset int, limit int) ([]*entity.UserService, error) {
 	var userServices []*entity.UserService
 	return userServices, nil
 }
 
-func (uri StubUserRepositoryImpl) FindUserGroupsOffSetAndLimit(offset int, limit int) ([]*entity.UserGroup, error) {
+func (uri StubUserRepositoryImpl) FindUserGroupsOffSetAndLimit(ctx context.Context, offset int, limit int) ([]*entity.UserGroup, error) {
 	var userGroups []*entity.UserGroup
 	return userGroups, nil
 }
 
-func (uri StubUserRepositoryImpl) FindUserServiceByUserUuidAndServiceUuid(userUuid string, serviceUuid string) (*entity.UserService, error) {
+func (uri StubUserRepositoryImpl) FindUserUuidsOfUserServicesAfter(ctx context.Context, afterUserUuid string, limit int) ([]string, error) {
+	var userUuids []string
+	return userUuids, nil
+}
+
+func (uri StubUserRepositoryImpl) FindUserUuidsOfUserGroupsAfter(ctx context.Context, afterUserUuid string, limit int) ([]string, error) {
+	var userUuids []string
+	return userUuids, nil
+}
+
+func (uri StubUserRepositoryImpl) FindUserServicesWithServiceByUserUuids(ctx context.Context, userUuids []string) ([]model.UserServiceOnService, error) {
+	var userServices []model.UserServiceOnService
+	return userServices, nil
+}
+
+func (uri StubUserRepositoryImpl) FindUserGroupsWithGroupByUserUuids(ctx context.Context, userUuids []string) ([]model.UserGroupOnGroup, error) {
+	var userGroups []model.UserGroupOnGroup
+	return userGroups, nil
+}
+
+func (uri StubUserRepositoryImpl) FindUserServiceByUserUuidAndServiceUuid(ctx context.Context, userUuid string, serviceUuid string) (*entity.UserService, error) {
 	var userService entity.UserService
 	return &userService, nil
 }
 
-func (uri StubUserRepositoryImpl) SaveUserGroup(userGroup entity.UserGroup) (*entity.UserGroup, error) {
+func (uri StubUserRepositoryImpl) SaveUserGroup(ctx context.Context, userGroup entity.UserGroup) (*entity.UserGroup, error) {
 	return &userGroup, nil
 }
 
-func (uri StubUserRepositoryImpl) SaveUser(user entity.User) (*entity.User, error) {
+func (uri StubUserRepositoryImpl) SaveUser(ctx context.Context, user entity.User) (*entity.User, error) {
 	return &user, nil
 }
 
-func (uri StubUserRepositoryImpl) SaveWithUserService(user entity.User, userService entity.UserService) (*entity.User, error) {
+func (uri StubUserRepositoryImpl) SaveWithUserService(ctx context.Context, user entity.User, userService entity.UserService) (*entity.User, error) {
 	return &user, nil
 }
 
-func (uri StubUserRepositoryImpl) SaveUserService(userService entity.UserService) (*entity.UserService, error) {
+func (uri StubUserRepositoryImpl) SaveUserService(ctx context.Context, userService entity.UserService) (*entity.UserService, error) {
 	return &userService, nil
 }
 
-func (uri StubUserRepositoryImpl) UpdateUser(user entity.User) (*entity.User, error) {
+func (uri StubUserRepositoryImpl) UpdateUser(ctx context.Context, user entity.User) (*entity.User, error) {
 	return &user, nil
 }
 
@@ -365,16 +400,16 @@ type StubOperatorPolicyRepositoryImpl struct {
 	Connection *gorm.DB
 }
 
-func (opr StubOperatorPolicyRepositoryImpl) FindAll() ([]*entity.OperatorPolicy, error) {
+func (opr StubOperatorPolicyRepositoryImpl) FindAll(ctx context.Context) ([]*entity.OperatorPolicy, error) {
 	var entities []*entity.OperatorPolicy
 	return entities, nil
 }
 
-func (opr StubOperatorPolicyRepositoryImpl) FindByUserUuid(userUuid string) ([]*entity.OperatorPolicy, error) {
+func (opr StubOperatorPolicyRepositoryImpl) FindByUserUuid(ctx context.Context, userUuid string) ([]*entity.OperatorPolicy, error) {
 	return []*entity.OperatorPolicy{{RoleUuid: uuid.New(), UserUuid: uuid.New()}}, nil
 }
 
-func (opr StubOperatorPolicyRepositoryImpl) FindByUserUuidAndRoleUuid(userUuid string, roleUuid string) (*entity.OperatorPolicy, error) {
+func (opr StubOperatorPolicyRepositoryImpl) FindByUserUuidAndRoleUuid(ctx context.Context, userUuid string, roleUuid string) (*entity.OperatorPolicy, error) {
 	operatorMemberRole := entity.OperatorPolicy{
 		RoleUuid: uuid.New(),
 		UserUuid: uuid.New(),
@@ -382,11 +417,11 @@ func (opr StubOperatorPolicyRepositoryImpl) FindByUserUuidAndRoleUuid(userUuid s
 	return &operatorMemberRole, nil
 }
 
-func (opr StubOperatorPolicyRepositoryImpl) FindRoleNameByUserUuid(userUuid string) ([]string, error) {
+func (opr StubOperatorPolicyRepositoryImpl) FindRoleNameByUserUuid(ctx context.Context, userUuid string) ([]string, error) {
 	return []string{""}, nil
 }
 
-func (opr StubOperatorPolicyRepositoryImpl) Save(entity entity.OperatorPolicy) (*entity.OperatorPolicy, error) {
+func (opr StubOperatorPolicyRepositoryImpl) Save(ctx context.Context, entity entity.OperatorPolicy) (*entity.OperatorPolicy, error) {
 	return &entity, nil
 }
 
@@ -396,51 +431,51 @@ type StubRoleRepositoryImpl struct {
 	Connection *gorm.DB
 }
 
-func (rri StubRoleRepositoryImpl) FindAll() ([]*entity.Role, error) {
+func (rri StubRoleRepositoryImpl) FindAll(ctx context.Context) ([]*entity.Role, error) {
 	var roles []*entity.Role
 	return roles, nil
 }
 
-func (rri StubRoleRepositoryImpl) FindOffSetAndLimit(offset int, limit int) ([]*entity.Role, error) {
+func (rri StubRoleRepositoryImpl) FindOffSetAndLimit(ctx context.Context, offset int, limit int) ([]*entity.Role, error) {
 	var roles []*entity.Role
 	return roles, nil
 }
 
-func (rri StubRoleRepositoryImpl) FindByUuid(uuid string) (*entity.Role, error) {
+func (rri StubRoleRepositoryImpl) FindByUuid(ctx context.Context, uuid string) (*entity.Role, error) {
 	var role entity.Role
 	return &role, nil
 }
 
-func (rri StubRoleRepositoryImpl) FindByName(name string) (*entity.Role, error) {
+func (rri StubRoleRepositoryImpl) FindByName(ctx context.Context, name string) (*entity.Role, error) {
 	var role entity.Role
 	return &role, nil
 }
 
-func (rri StubRoleRepositoryImpl) FindByNames(names []string) ([]entity.Role, error) {
+func (rri StubRoleRepositoryImpl) FindByNames(ctx context.Context, names []string) ([]entity.Role, error) {
 	var roles []entity.Role
 	roles = append(roles, entity.Role{InternalId: "", Name: "test_role"})
 	roles = append(roles, entity.Role{InternalId: "", Name: "test_role"})
 	return roles, nil
 }
 
-func (rri StubRoleRepositoryImpl) FindByGroupUuid(groupUuid string) ([]*entity.Role, error) {
+func (rri StubRoleRepositoryImpl) FindByGroupUuid(ctx context.Context, groupUuid string, page driver.Page) ([]*entity.Role, string, error) {
 	var roles []*entity.Role
-	return roles, nil
+	return roles, "", nil
 }
 
-func (rri StubRoleRepositoryImpl) FindNameByUuid(uuid string) *string {
-	role, err := rri.FindByUuid(uuid)
+func (rri StubRoleRepositoryImpl) FindNameByUuid(ctx context.Context, uuid string) *string {
+	role, err := rri.FindByUuid(ctx, uuid)
 	if err != nil {
 		return nil
 	}
 	return &role.Name
 }
 
-func (rri StubRoleRepositoryImpl) Save(role entity.Role) (*entity.Role, error) {
+func (rri StubRoleRepositoryImpl) Save(ctx context.Context, role entity.Role) (*entity.Role, error) {
 	return &role, nil
 }
 
-func (rri StubRoleRepositoryImpl) SaveWithRelationalData(groupUuid string, role entity.Role) (*entity.Role, error) {
+func (rri StubRoleRepositoryImpl) SaveWithRelationalData(ctx context.Context, groupUuid string, role entity.Role) (*entity.Role, error) {
 	return &role, nil
 }
 
@@ -450,53 +485,53 @@ type StubServiceRepositoryImpl struct {
 	Connection *gorm.DB
 }
 
-func (sri StubServiceRepositoryImpl) FindAll() ([]*entity.Service, error) {
+func (sri StubServiceRepositoryImpl) FindAll(ctx context.Context, page driver.Page) ([]*entity.Service, string, error) {
 	var services []*entity.Service
-	return services, nil
+	return services, "", nil
 }
 
-func (sri StubServiceRepositoryImpl) FindOffSetAndLimit(offset int, limit int) ([]*entity.Service, error) {
+func (sri StubServiceRepositoryImpl) FindOffSetAndLimit(ctx context.Context, offset int, limit int) ([]*entity.Service, error) {
 	var services []*entity.Service
 	return services, nil
 }
 
-func (sri StubServiceRepositoryImpl) FindByUuid(uuid string) (*entity.Service, error) {
+func (sri StubServiceRepositoryImpl) FindByUuid(ctx context.Context, uuid string) (*entity.Service, error) {
 	var service entity.Service
 	return &service, nil
 }
 
-func (sri StubServiceRepositoryImpl) FindByName(name string) (*entity.Service, error) {
+func (sri StubServiceRepositoryImpl) FindByName(ctx context.Context, name string) (*entity.Service, error) {
 	var service entity.Service
 	return &service, nil
 }
 
-func (sri StubServiceRepositoryImpl) FindBySecret(secret string) (*entity.Service, error) {
+func (sri StubServiceRepositoryImpl) FindBySecret(ctx context.Context, secret string) (*entity.Service, error) {
 	service := entity.Service{Name: "test"}
 	return &service, nil
 }
 
-func (sri StubServiceRepositoryImpl) FindNameByUuid(uuid string) *string {
-	service, err := sri.FindByUuid(uuid)
+func (sri StubServiceRepositoryImpl) FindNameByUuid(ctx context.Context, uuid string) *string {
+	service, err := sri.FindByUuid(ctx, uuid)
 	if err != nil {
 		return nil
 	}
 	return &service.Name
 }
 
-func (sri StubServiceRepositoryImpl) FindServicesByUserUuid(userUuid string) ([]*entity.Service, error) {
+func (sri StubServiceRepositoryImpl) FindServicesByUserUuid(ctx context.Context, userUuid string, page driver.Page) ([]*entity.Service, string, error) {
 	var services []*entity.Service
-	return services, nil
+	return services, "", nil
 }
 
-func (sri StubServiceRepositoryImpl) Save(service entity.Service) (*entity.Service, error) {
+func (sri StubServiceRepositoryImpl) Save(ctx context.Context, service entity.Service) (*entity.Service, error) {
 	return &service, nil
 }
 
-func (sri StubServiceRepositoryImpl) SaveWithRelationalData(service entity.Service, roles []entity.Role, permissions []entity.Permission) (*entity.Service, error) {
+func (sri StubServiceRepositoryImpl) SaveWithRelationalData(ctx context.Context, service entity.Service, roles []entity.Role, permissions []entity.Permission) (*entity.Service, error) {
 	return &service, nil
 }
 
-func (sri StubServiceRepositoryImpl) Update(service entity.Service) (*entity.Service, error) {
+func (sri StubServiceRepositoryImpl) Update(ctx context.Context, service entity.Service) (*entity.Service, error) {
 	return &service, nil
 }
 
@@ -506,46 +541,46 @@ type StubPermissionRepositoryImpl struct {
 	Connection *gorm.DB
 }
 
-func (pri StubPermissionRepositoryImpl) FindAll() ([]*entity.Permission, error) {
+func (pri StubPermissionRepositoryImpl) FindAll(ctx context.Context) ([]*entity.Permission, error) {
 	permissions := []*entity.Permission{{InternalId: "", Name: "test_permission"}}
 	return permissions, nil
 }
 
-func (pri StubPermissionRepositoryImpl) FindOffSetAndLimit(offsetCnt int, limitCnt int) ([]*entity.Permission, error) {
+func (pri StubPermissionRepositoryImpl) FindOffSetAndLimit(ctx context.Context, offsetCnt int, limitCnt int) ([]*entity.Permission, error) {
 	var permissions []*entity.Permission
 	return permissions, nil
 }
 
-func (pri StubPermissionRepositoryImpl) FindByUuid(uuid string) (*entity.Permission, error) {
+func (pri StubPermissionRepositoryImpl) FindByUuid(ctx context.Context, uuid string) (*entity.Permission, error) {
 	var permission entity.Permission
 	return &permission, nil
 }
 
-func (pri StubPermissionRepositoryImpl) FindByName(name string) (*entity.Permission, error) {
+func (pri StubPermissionRepositoryImpl) FindByName(ctx context.Context, name string) (*entity.Permission, error) {
 	permission := entity.Permission{InternalId: "", Name: "test_permission"}
 	return &permission, nil
 }
 
-func (pri StubPermissionRepositoryImpl) FindByNames(names []string) ([]entity.Permission, error) {
+func (pri StubPermissionRepositoryImpl) FindByNames(ctx context.Context, names []string) ([]entity.Permission, error) {
 	var permissions []entity.Permission
 	permissions = append(permissions, entity.Permission{InternalId: "", Name: "test_permission"})
 	return permissions, nil
 }
 
-func (pri StubPermissionRepositoryImpl) FindByGroupUuid(groupUuid string) ([]*entity.Permission, error) {
+func (pri StubPermissionRepositoryImpl) FindByGroupUuid(ctx context.Context, groupUuid string, page driver.Page) ([]*entity.Permission, string, error) {
 	var permissions []*entity.Permission
-	return permissions, nil
+	return permissions, "", nil
 }
 
-func (pri StubPermissionRepositoryImpl) FindNameByUuid(uuid string) *string {
+func (pri StubPermissionRepositoryImpl) FindNameByUuid(ctx context.Context, uuid string) *string {
 	return nil
 }
 
-func (pri StubPermissionRepositoryImpl) Save(permission entity.Permission) (*entity.Permission, error) {
+func (pri StubPermissionRepositoryImpl) Save(ctx context.Context, permission entity.Permission) (*entity.Permission, error) {
 	return &permission, nil
 }
 
-func (pri StubPermissionRepositoryImpl) SaveWithRelationalData(groupUuid string, permission entity.Permission) (*entity.Permission, error) {
+func (pri StubPermissionRepositoryImpl) SaveWithRelationalData(ctx context.Context, groupUuid string, permission entity.Permission) (*entity.Permission, error) {
 	return &permission, nil
 }
 
@@ -555,43 +590,44 @@ type StubGroupRepositoryImpl struct {
 	Connection *gorm.DB
 }
 
-func (gr StubGroupRepositoryImpl) FindAll() ([]*entity.Group, error) {
+func (gr StubGroupRepositoryImpl) FindAll(ctx context.Context) ([]*entity.Group, error) {
 	return []*entity.Group{}, nil
 }
 
-func (gr StubGroupRepositoryImpl) FindByUuid(uuid string) (*entity.Group, error) {
+func (gr StubGroupRepositoryImpl) FindByUuid(ctx context.Context, uuid string) (*entity.Group, error) {
 	var group entity.Group
 	return &group, nil
 }
 
-func (gr StubGroupRepositoryImpl) FindByName(name string) (*entity.Group, error) {
+func (gr StubGroupRepositoryImpl) FindByName(ctx context.Context, name string) (*entity.Group, error) {
 	var group *entity.Group
 	return group, nil
 }
 
-func (gr StubGroupRepositoryImpl) FindByUserUuid(userUuid string) ([]*entity.Group, error) {
+func (gr StubGroupRepositoryImpl) FindByUserUuid(ctx context.Context, userUuid string, page driver.Page) ([]*entity.Group, string, error) {
 	var groups []*entity.Group
-	return groups, nil
+	return groups, "", nil
 }
 
-func (gr StubGroupRepositoryImpl) FindByServiceUuid(serviceUuid string) ([]*entity.Group, error) {
+func (gr StubGroupRepositoryImpl) FindByServiceUuid(ctx context.Context, serviceUuid string, page driver.Page) ([]*entity.Group, string, error) {
 	var groups []*entity.Group
-	return groups, nil
+	return groups, "", nil
 }
 
-func (gr StubGroupRepositoryImpl) FindGroupWithUserWithPolicyGroupsByUserUuid(userUuid string) ([]*model.GroupWithUserGroupWithPolicy, error) {
+func (gr StubGroupRepositoryImpl) FindGroupWithUserWithPolicyGroupsByUserUuid(ctx context.Context, userUuid string) ([]*model.GroupWithUserGroupWithPolicy, error) {
 	uid := uuid.MustParse("20aabf03-7f3f-479c-91d2-909c844de26c")
 	var policies []*model.GroupWithUserGroupWithPolicy
 	policies = append(policies, &model.GroupWithUserGroupWithPolicy{Policy: entity.Policy{UserGroupUuid: uid, RoleUuid: uid, PermissionUuid: uid, ServiceUuid: uid}})
 	return policies, nil
 }
 
-func (gr StubGroupRepositoryImpl) FindGroupWithPolicyByUserUuidAndGroupUuid(userUuid string, groupUuid string) (*model.GroupWithUserGroupWithPolicy, error) {
+func (gr StubGroupRepositoryImpl) FindGroupWithPolicyByUserUuidAndGroupUuid(ctx context.Context, userUuid string, groupUuid string) (*model.GroupWithUserGroupWithPolicy, error) {
 	var groupWithUserGroupWithPolicy model.GroupWithUserGroupWithPolicy
 	return &groupWithUserGroupWithPolicy, nil
 }
 
 func (gr StubGroupRepositoryImpl) SaveWithRelationalData(
+	ctx context.Context,
 	group entity.Group,
 	serviceGroup entity.ServiceGroup,
 	userGroup entity.UserGroup,
@@ -608,37 +644,37 @@ type StubPolicyRepositoryImpl struct {
 	Connection *gorm.DB
 }
 
-func (pri StubPolicyRepositoryImpl) FindAll() ([]*entity.Policy, error) {
+func (pri StubPolicyRepositoryImpl) FindAll(ctx context.Context) ([]*entity.Policy, error) {
 	var policies []*entity.Policy
 	return policies, nil
 }
 
-func (pri StubPolicyRepositoryImpl) FindOffSetAndLimit(offsetCnt int, limitCnt int) ([]*entity.Policy, error) {
+func (pri StubPolicyRepositoryImpl) FindOffSetAndLimit(ctx context.Context, offsetCnt int, limitCnt int) ([]*entity.Policy, error) {
 	var policies []*entity.Policy
 	return policies, nil
 }
 
-func (pri StubPolicyRepositoryImpl) FindByRoleUuid(roleUuid string) ([]*entity.Policy, error) {
+func (pri StubPolicyRepositoryImpl) FindByRoleUuid(ctx context.Context, roleUuid string) ([]*entity.Policy, error) {
 	var policies []*entity.Policy
 	return policies, nil
 }
 
-func (pri StubPolicyRepositoryImpl) FindByUuid(uuid string) (entity.Policy, error) {
+func (pri StubPolicyRepositoryImpl) FindByUuid(ctx context.Context, uuid string) (entity.Policy, error) {
 	var policy entity.Policy
 	return policy, nil
 }
 
-func (pri StubPolicyRepositoryImpl) FindPolicyOfUserGroupByUserUuidAndGroupUuid(userUuid string, groupUuid string) (model.UserPolicyOnGroupResponse, error) {
+func (pri StubPolicyRepositoryImpl) FindPolicyOfUserGroupByUserUuidAndGroupUuid(ctx context.Context, userUuid string, groupUuid string) (model.UserPolicyOnGroupResponse, error) {
 	var policy model.UserPolicyOnGroupResponse
 	return policy, nil
 }
 
-func (pri StubPolicyRepositoryImpl) FindPolicyOfUserServiceByUserUuidAndServiceUuid(userUuid string) ([]model.UserPolicyOnServiceResponse, error) {
-	var policy []model.UserPolicyOnServiceResponse
-	return policy, nil
+func (pri StubPolicyRepositoryImpl) FindPolicyOfUserGroupByUserUuids(ctx context.Context, userUuids []string) ([]model.UserPolicyOnUserGroup, error) {
+	var policies []model.UserPolicyOnUserGroup
+	return policies, nil
 }
 
-func (pri StubPolicyRepositoryImpl) Update(policy entity.Policy) (*entity.Policy, error) {
+func (pri StubPolicyRepositoryImpl) Update(ctx context.Context, policy entity.Policy) (*entity.Policy, error) {
 	return &policy, nil
 }
 
@@ -647,53 +683,80 @@ func (pri StubPolicyRepositoryImpl) Update(policy entity.Policy) (*entity.Policy
 type StubEtcdlClient struct {
 }
 
-func (e StubEtcdlClient) SetUserPolicy(userUuid string, policy []structure.UserPolicy) {
+func (e StubEtcdlClient) SetUserPolicy(ctx context.Context, userUuid string, policy []structure.UserPolicy) {
 }
 
-func (e StubEtcdlClient) SetPermission(permissionUuid string, permission structure.Permission) {
+func (e StubEtcdlClient) SetPermission(ctx context.Context, permissionUuid string, permission structure.Permission) {
 }
 
-func (e StubEtcdlClient) SetRole(roleUuid string, role structure.Role) {
+func (e StubEtcdlClient) SetRole(ctx context.Context, roleUuid string, role structure.Role) {
 }
 
-func (e StubEtcdlClient) SetService(serviceUuid string, service structure.Service) {
+func (e StubEtcdlClient) SetService(ctx context.Context, serviceUuid string, service structure.Service) {
 }
 
-func (e StubEtcdlClient) SetUserService(userUuid string, userServices []structure.UserService) {
+func (e StubEtcdlClient) SetUserService(ctx context.Context, userUuid string, userServices []structure.UserService) {
 }
 
-func (e StubEtcdlClient) SetUserGroup(userUuid string, userGroups []structure.UserGroup) {
+func (e StubEtcdlClient) SetUserGroup(ctx context.Context, userUuid string, userGroups []structure.UserGroup) {
 }
 
-func (e StubEtcdlClient) GetUserPolicy(userUuid string) []structure.UserPolicy {
+func (e StubEtcdlClient) GetUserPolicy(ctx context.Context, userUuid string) []structure.UserPolicy {
 	uid := "6311b383-4587-469d-8aa1-8103142d33e8"
 	return []structure.UserPolicy{{ServiceUuid: uid, GroupUuid: uid, RoleName: "test_role", PermissionName: "test_permission"}}
 }
 
-func (e StubEtcdlClient) GetPermission(permissionUuid string) *structure.Permission {
+func (e StubEtcdlClient) GetPermission(ctx context.Context, permissionUuid string) *structure.Permission {
 	var permission structure.Permission
 	return &permission
 }
 
-func (e StubEtcdlClient) GetRole(roleUuid string) *structure.Role {
+func (e StubEtcdlClient) GetRole(ctx context.Context, roleUuid string) *structure.Role {
 	var role structure.Role
 	return &role
 }
 
-func (e StubEtcdlClient) GetService(serviceUuid string) *structure.Service {
+func (e StubEtcdlClient) GetService(ctx context.Context, serviceUuid string) *structure.Service {
 	var service structure.Service
 	return &service
 }
 
-func (e StubEtcdlClient) GetUserService(userUuid string) []structure.UserService {
+func (e StubEtcdlClient) GetUserService(ctx context.Context, userUuid string) []structure.UserService {
 	var userServices []structure.UserService
 	return userServices
 }
 
-func (e StubEtcdlClient) GetUserGroup(userUuid string) []structure.UserGroup {
+func (e StubEtcdlClient) GetUserGroup(ctx context.Context, userUuid string) []structure.UserGroup {
 	var userGroups []structure.UserGroup
 	return userGroups
 }
 
-func (e StubEtcdlClient) DeleteUserPolicy(userUuid string) {
+func (e StubEtcdlClient) DeleteUserPolicy(ctx context.Context, userUuid string) {
+}
+
+func (e StubEtcdlClient) GetKeys(ctx context.Context, prefix string) ([]string, error) {
+	return []string{}, nil
+}
+
+func (e StubEtcdlClient) GetValues(ctx context.Context, prefix string) (map[string][]byte, error) {
+	return map[string][]byte{}, nil
+}
+
+func (e StubEtcdlClient) DeleteKeys(ctx context.Context, keys []string) {
+}
+
+func (e StubEtcdlClient) SetBatch(ctx context.Context, values []cache.KeyValue) []cache.FailedBatch {
+	return nil
+}
+
+func (e StubEtcdlClient) GetCheckpoint(ctx context.Context, name string, checkpoint interface{}) (bool, error) {
+	return false, nil
+}
+
+func (e StubEtcdlClient) SetCheckpoint(ctx context.Context, name string, checkpoint interface{}) error {
+	return nil
+}
+
+func (e StubEtcdlClient) Ping(ctx context.Context) error {
+	return nil
 }
@@ -113,6 +113,45 @@ func MethodNotAllowed(err ...string) *ErrorResBody {
 	}
 }
 
+// PreconditionFailed
+func PreconditionFailed(err ...string) *ErrorResBody {
+	var detail string
+	if err != nil {
+		detail = err[0]
+	}
+	return &ErrorResBody{
+		Code:    http.StatusPreconditionFailed,
+		Title:   "Precondition failed.",
+		Message: detail,
+	}
+}
+
+// PreconditionRequired
+func PreconditionRequired(err ...string) *ErrorResBody {
+	var detail string
+	if err != nil {
+		detail = err[0]
+	}
+	return &ErrorResBody{
+		Code:    http.StatusPreconditionRequired,
+		Title:   "Precondition required.",
+		Message: detail,
+	}
+}
+
+// RequestEntityTooLarge
+func RequestEntityTooLarge(err ...string) *ErrorResBody {
+	var detail string
+	if err != nil {
+		detail = err[0]
+	}
+	return &ErrorResBody{
+		Code:    http.StatusRequestEntityTooLarge,
+		Title:   "Request entity too large.",
+		Message: detail,
+	}
+}
+
 // UnProcessableEntity
 func UnProcessableEntity(err ...string) *ErrorResBody {
 	var detail string
@@ -141,3 +180,16 @@ func InternalServerError(err ...string) *ErrorResBody {
 	log.Logger.Error(body.ToJson())
 	return &body
 }
+
+// ServiceUnavailable
+func ServiceUnavailable(err ...string) *ErrorResBody {
+	var detail string
+	if err != nil {
+		detail = err[0]
+	}
+	return &ErrorResBody{
+		Code:    http.StatusServiceUnavailable,
+		Title:   "Service unavailable.",
+		Message: detail,
+	}
+}
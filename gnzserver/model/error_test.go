@@ -105,6 +105,42 @@ func TestInternalServerError(t *testing.T) {
 	}
 }
 
+// Test precondition failed
+func TestPreconditionFailed(t *testing.T) {
+	preconditionFailed := PreconditionFailed("test")
+	if preconditionFailed == nil || preconditionFailed.Code != http.StatusPreconditionFailed {
+		t.Errorf("Incorrect TestPreconditionFailed test")
+		t.FailNow()
+	}
+}
+
+// Test precondition required
+func TestPreconditionRequired(t *testing.T) {
+	preconditionRequired := PreconditionRequired("test")
+	if preconditionRequired == nil || preconditionRequired.Code != http.StatusPreconditionRequired {
+		t.Errorf("Incorrect TestPreconditionRequired test")
+		t.FailNow()
+	}
+}
+
+// Test request entity too large
+func TestRequestEntityTooLarge(t *testing.T) {
+	requestEntityTooLarge := RequestEntityTooLarge("test")
+	if requestEntityTooLarge == nil || requestEntityTooLarge.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("Incorrect TestRequestEntityTooLarge test")
+		t.FailNow()
+	}
+}
+
+// Test service unavailable
+func TestServiceUnavailable(t *testing.T) {
+	serviceUnavailable := ServiceUnavailable("test")
+	if serviceUnavailable == nil || serviceUnavailable.Code != http.StatusServiceUnavailable {
+		t.Errorf("Incorrect TestServiceUnavailable test")
+		t.FailNow()
+	}
+}
+
 // Less than stub struct
 // ResponseWriter
 type StubResponseWriter struct {
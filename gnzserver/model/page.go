@@ -0,0 +1,8 @@
+package model
+
+// The api list response struct
+// NextCursor is the cursor query of the next page. If empty, it is the last page
+type PageResponse struct {
+	Items      interface{} `json:"items"`
+	NextCursor string      `json:"next_cursor"`
+}
@@ -45,26 +45,24 @@ type PolicyResponse struct {
 }
 
 // The user policy response struct
+// PolicyVersion is If-Match of PUT policy. If user does not have policy, it is 0
 type UserPolicyOnGroupResponse struct {
 	Username       string `json:"username"`
 	Email          string `json:"email"`
 	ServiceName    string `json:"service_name"`
 	PolicyName     string `json:"policy_name"`
+	PolicyVersion  int    `json:"policy_version"`
 	RoleName       string `json:"role_name"`
 	PermissionName string `json:"permission_name"`
 }
 
-// The user policy response struct
-type UserPolicyOnServiceResponse struct {
-	Username       string `json:"username"`
-	Email          string `json:"email"`
-	PolicyName     string `json:"policy_name"`
-	GroupName      string `json:"group_name"`
+// The user policy struct on user_groups
+type UserPolicyOnUserGroup struct {
+	UserUuid       string `json:"user_uuid"`
+	ServiceUuid    string `json:"service_uuid"`
 	GroupUuid      string `json:"group_uuid"`
 	RoleName       string `json:"role_name"`
-	RoleUuid       string `json:"role_uuid"`
 	PermissionName string `json:"permission_name"`
-	PermissionUuid string `json:"permission_uuid"`
 }
 
 // PolicyResponse constructor
@@ -31,6 +31,20 @@ type UserWithUserServiceWithService struct {
 	entity.Service
 }
 
+// The table `user_services` and `services` struct of user
+type UserServiceOnService struct {
+	UserUuid    string `json:"user_uuid"`
+	ServiceUuid string `json:"service_uuid"`
+	ServiceName string `json:"service_name"`
+}
+
+// The table `user_groups` and `groups` struct of user
+type UserGroupOnGroup struct {
+	UserUuid  string `json:"user_uuid"`
+	GroupUuid string `json:"group_uuid"`
+	GroupName string `json:"group_name"`
+}
+
 // Add user id
 type AddUser struct {
 	UserEmail string `validate:"required"json:"user_email"`
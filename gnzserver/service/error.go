@@ -0,0 +1,36 @@
+package service
+
+import (
+	"github.com/tomoyane/grant-n-z/gnz/driver"
+	"github.com/tomoyane/grant-n-z/gnzserver/model"
+)
+
+// Message of error response when data refers to data that does not exist
+const relationalErrorMessage = "Not register relational id."
+
+// Convert error of repository to error response, so that status code of the same error is the same in all services
+// message is detail of not found and duplicate errors, such as "Not found user". Other errors have the fixed detail
+func repositoryError(err error, message ...string) *model.ErrorResBody {
+	if len(message) == 0 {
+		message = []string{err.Error()}
+	}
+
+	switch err {
+	case driver.ErrNotFound:
+		return model.NotFound(message...)
+	case driver.ErrDuplicate:
+		return model.Conflict(message...)
+	case driver.ErrForeignKey:
+		return model.BadRequest(relationalErrorMessage)
+	case driver.ErrConflict:
+		return model.Conflict(err.Error())
+	case driver.ErrStaleVersion:
+		return model.PreconditionFailed(err.Error())
+	case driver.ErrTenant:
+		return model.Forbidden(err.Error())
+	case driver.ErrUnavailable:
+		return model.ServiceUnavailable(err.Error())
+	default:
+		return model.InternalServerError(err.Error())
+	}
+}
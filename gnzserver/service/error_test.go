@@ -0,0 +1,37 @@
+package service
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/tomoyane/grant-n-z/gnz/driver"
+)
+
+// Test repository error to error response
+func TestRepositoryError(t *testing.T) {
+	cases := []struct {
+		err     error
+		message []string
+		code    int
+		detail  string
+	}{
+		{driver.ErrNotFound, []string{"Not found user"}, http.StatusNotFound, "Not found user"},
+		{driver.ErrNotFound, nil, http.StatusNotFound, driver.ErrNotFound.Error()},
+		{driver.ErrDuplicate, []string{"Already exit data."}, http.StatusConflict, "Already exit data."},
+		{driver.ErrForeignKey, []string{"Already exit data."}, http.StatusBadRequest, relationalErrorMessage},
+		{driver.ErrConflict, []string{"Already exit data."}, http.StatusConflict, driver.ErrConflict.Error()},
+		{driver.ErrStaleVersion, []string{"Already exit data."}, http.StatusPreconditionFailed, driver.ErrStaleVersion.Error()},
+		{driver.ErrTenant, []string{"Already exit data."}, http.StatusForbidden, driver.ErrTenant.Error()},
+		{driver.ErrUnavailable, []string{"Not found user"}, http.StatusServiceUnavailable, driver.ErrUnavailable.Error()},
+		{errors.New("failed"), []string{"Not found user"}, http.StatusInternalServerError, "failed"},
+	}
+
+	for _, c := range cases {
+		errRes := repositoryError(c.err, c.message...)
+		if errRes.Code != c.code || errRes.Message != c.detail {
+			t.Errorf("Incorrect TestRepositoryError test. err = %s", c.err.Error())
+			t.FailNow()
+		}
+	}
+}
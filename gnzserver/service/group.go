@@ -1,9 +1,9 @@
 package service
 
 import (
+	"context"
 	"crypto/md5"
 	"encoding/hex"
-	"strings"
 
 	"github.com/google/uuid"
 
@@ -19,19 +19,21 @@ var gsInstance GroupService
 
 type GroupService interface {
 	// Get all groups
-	GetGroups() ([]*entity.Group, *model.ErrorResBody)
+	GetGroups(ctx context.Context) ([]*entity.Group, *model.ErrorResBody)
 
 	// Get group by uuid
-	GetGroupByUuid(uuid string) (*entity.Group, *model.ErrorResBody)
+	GetGroupByUuid(ctx context.Context, uuid string) (*entity.Group, *model.ErrorResBody)
 
 	// Get group that has the user
-	GetGroupByUser(userUuid string) ([]*entity.Group, *model.ErrorResBody)
+	// Next cursor is empty if it is the last page
+	GetGroupByUser(ctx context.Context, userUuid string, page driver.Page) ([]*entity.Group, string, *model.ErrorResBody)
 
 	// Get group that has the service
-	GetGroupByServices(serviceUuid string) ([]*entity.Group, *model.ErrorResBody)
+	// Next cursor is empty if it is the last page
+	GetGroupByServices(ctx context.Context, serviceUuid string, page driver.Page) ([]*entity.Group, string, *model.ErrorResBody)
 
 	// Insert group
-	InsertGroupWithRelationalData(group entity.Group, userUuid string, secret string) (*entity.Group, *model.ErrorResBody)
+	InsertGroupWithRelationalData(ctx context.Context, group entity.Group, userUuid string, secret string) (*entity.Group, *model.ErrorResBody)
 }
 
 // GroupService struct
@@ -64,82 +66,73 @@ func NewGroupService() GroupService {
 	}
 }
 
-func (gs GroupServiceImpl) GetGroups() ([]*entity.Group, *model.ErrorResBody) {
-	groups, err := gs.GroupRepository.FindAll()
+func (gs GroupServiceImpl) GetGroups(ctx context.Context) ([]*entity.Group, *model.ErrorResBody) {
+	groups, err := gs.GroupRepository.FindAll(ctx)
 	if err != nil {
-		if strings.Contains(err.Error(), "record not found") {
+		if err == driver.ErrNotFound {
 			return []*entity.Group{}, nil
 		}
-		return nil, model.InternalServerError(err.Error())
+		return nil, repositoryError(err)
 	}
 
 	return groups, nil
 }
 
-func (gs GroupServiceImpl) GetGroupByUuid(uuid string) (*entity.Group, *model.ErrorResBody) {
-	group, err := gs.GroupRepository.FindByUuid(uuid)
+func (gs GroupServiceImpl) GetGroupByUuid(ctx context.Context, uuid string) (*entity.Group, *model.ErrorResBody) {
+	group, err := gs.GroupRepository.FindByUuid(ctx, uuid)
 	if err != nil {
-		if strings.Contains(err.Error(), "record not found") {
-			return nil, model.NotFound("Not found group")
-		}
-		return nil, model.InternalServerError(err.Error())
+		return nil, repositoryError(err, "Not found group")
 	}
 
 	return group, nil
 }
 
-func (gs GroupServiceImpl) GetGroupByUser(userUuid string) ([]*entity.Group, *model.ErrorResBody) {
-	groups, err := gs.GroupRepository.FindByUserUuid(userUuid)
+func (gs GroupServiceImpl) GetGroupByUser(ctx context.Context, userUuid string, page driver.Page) ([]*entity.Group, string, *model.ErrorResBody) {
+	groups, next, err := gs.GroupRepository.FindByUserUuid(ctx, userUuid, page)
 	if err != nil {
-		if strings.Contains(err.Error(), "record not found") {
-			return []*entity.Group{}, nil
+		if err == driver.ErrNotFound {
+			return []*entity.Group{}, "", nil
 		}
-		return nil, model.InternalServerError(err.Error())
+		return nil, "", repositoryError(err)
 	}
 
-	return groups, nil
+	return groups, next, nil
 }
 
-func (gs GroupServiceImpl) GetGroupByServices(serviceUuid string) ([]*entity.Group, *model.ErrorResBody) {
-	groups, err := gs.GroupRepository.FindByServiceUuid(serviceUuid)
+func (gs GroupServiceImpl) GetGroupByServices(ctx context.Context, serviceUuid string, page driver.Page) ([]*entity.Group, string, *model.ErrorResBody) {
+	groups, next, err := gs.GroupRepository.FindByServiceUuid(ctx, serviceUuid, page)
 	if err != nil {
-		if strings.Contains(err.Error(), "record not found") {
-			return []*entity.Group{}, nil
+		if err == driver.ErrNotFound {
+			return []*entity.Group{}, "", nil
 		}
-		return nil, model.InternalServerError(err.Error())
+		return nil, "", repositoryError(err)
 	}
 
-	return groups, nil
+	return groups, next, nil
 }
 
-func (gs GroupServiceImpl) InsertGroupWithRelationalData(group entity.Group, uUuid string, secret string) (*entity.Group, *model.ErrorResBody) {
+func (gs GroupServiceImpl) InsertGroupWithRelationalData(ctx context.Context, group entity.Group, uUuid string, secret string) (*entity.Group, *model.ErrorResBody) {
 	gid := uuid.New()
 	gidMd5 := md5.Sum(gid.NodeID())
 	group.Uuid = gid
 	group.InternalId = hex.EncodeToString(gidMd5[:])
 
-	role, err := gs.RoleRepository.FindByName(common.AdminRole)
+	role, err := gs.RoleRepository.FindByName(ctx, common.AdminRole)
 	if err != nil {
-		if strings.Contains(err.Error(), "record not found") {
-			return nil, model.NotFound("Not found relation role")
-		}
-		return nil, model.InternalServerError(err.Error())
+		return nil, repositoryError(err, "Not found relation role")
 	}
 
-	permission, err := gs.PermissionRepository.FindByName(common.AdminPermission)
+	permission, err := gs.PermissionRepository.FindByName(ctx, common.AdminPermission)
 	if err != nil {
-		if strings.Contains(err.Error(), "record not found") {
-			return nil, model.NotFound("Not found relation permission")
-		}
-		return nil, model.InternalServerError(err.Error())
+		return nil, repositoryError(err, "Not found relation permission")
 	}
 
-	ser, err := gs.ServiceRepository.FindBySecret(secret)
+	ser, err := gs.ServiceRepository.FindBySecret(ctx, secret)
 	if err != nil {
-		if strings.Contains(err.Error(), "record not found") {
+		if err == driver.ErrNotFound {
 			return nil, model.BadRequest("Invalid secret")
 		}
-		return nil, model.InternalServerError(err.Error())
+		return nil, repositoryError(err)
 	}
 
 	userUuid := uuid.MustParse(uUuid)
@@ -189,12 +182,9 @@ func (gs GroupServiceImpl) InsertGroupWithRelationalData(group entity.Group, uUu
 		UserGroupUuid:  userGroup.Uuid,
 	}
 
-	savedData, err := gs.GroupRepository.SaveWithRelationalData(group, serviceGroup, userGroup, groupPermission, groupRole, policy)
+	savedData, err := gs.GroupRepository.SaveWithRelationalData(ctx, group, serviceGroup, userGroup, groupPermission, groupRole, policy)
 	if err != nil {
-		if strings.Contains(err.Error(), "1062") {
-			return nil, model.Conflict("Already exit service group data.")
-		}
-		return nil, model.InternalServerError("Failed to save transaction")
+		return nil, repositoryError(err, "Already exit service group data.")
 	}
 
 	return savedData, nil
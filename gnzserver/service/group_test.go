@@ -1,6 +1,7 @@
 package service
 
 import (
+	"context"
 	"testing"
 	"time"
 
@@ -10,6 +11,7 @@ import (
 	"github.com/jinzhu/gorm"
 	_ "github.com/mattn/go-sqlite3"
 	"github.com/tomoyane/grant-n-z/gnz/cache"
+	"github.com/tomoyane/grant-n-z/gnz/driver"
 	"github.com/tomoyane/grant-n-z/gnz/entity"
 	"github.com/tomoyane/grant-n-z/gnz/log"
 	"github.com/tomoyane/grant-n-z/gnzserver/model"
@@ -51,7 +53,7 @@ func TestGetGroupServiceInstance(t *testing.T) {
 
 // Test get groups
 func TestGetGroups_Success(t *testing.T) {
-	_, err := groupService.GetGroups()
+	_, err := groupService.GetGroups(context.Background())
 	if err != nil {
 		t.Errorf("Incorrect TestGetGroups_Success test")
 		t.FailNow()
@@ -60,7 +62,7 @@ func TestGetGroups_Success(t *testing.T) {
 
 // Test get group by id
 func TestGetGroupById_Success(t *testing.T) {
-	_, err := groupService.GetGroupByUuid(uuid.New().String())
+	_, err := groupService.GetGroupByUuid(context.Background(), uuid.New().String())
 	if err != nil {
 		t.Errorf("Incorrect TestGetGroupById_Success test")
 		t.FailNow()
@@ -69,7 +71,7 @@ func TestGetGroupById_Success(t *testing.T) {
 
 // Test get group of login user
 func TestGetGroupByUser_Success(t *testing.T) {
-	_, err := groupService.GetGroupByUser(uuid.New().String())
+	_, _, err := groupService.GetGroupByUser(context.Background(), uuid.New().String(), driver.Page{})
 	if err != nil {
 		t.Errorf("Incorrect TestGetGroupOfUser_Success test")
 		t.FailNow()
@@ -78,7 +80,7 @@ func TestGetGroupByUser_Success(t *testing.T) {
 
 // Test get group of service for login user
 func TestGetGroupByServices_Success(t *testing.T) {
-	_, err := groupService.GetGroupByServices(uuid.New().String())
+	_, _, err := groupService.GetGroupByServices(context.Background(), uuid.New().String(), driver.Page{})
 	if err != nil {
 		t.Errorf("Incorrect TestGetGroupByServices_Success test")
 		t.FailNow()
@@ -88,6 +90,7 @@ func TestGetGroupByServices_Success(t *testing.T) {
 // Test insert group with relational data
 func TestInsertGroupWithRelationalData_Success(t *testing.T) {
 	_, err := groupService.InsertGroupWithRelationalData(
+		context.Background(),
 		entity.Group{InternalId: "", Name: "test", Uuid: uuid.New()},
 		uuid.New().String(),
 		uuid.New().String(),
@@ -104,41 +107,42 @@ type StubGroupRepositoryImpl struct {
 	Connection *gorm.DB
 }
 
-func (gr StubGroupRepositoryImpl) FindAll() ([]*entity.Group, error) {
+func (gr StubGroupRepositoryImpl) FindAll(ctx context.Context) ([]*entity.Group, error) {
 	return []*entity.Group{}, nil
 }
 
-func (gr StubGroupRepositoryImpl) FindByUuid(uuid string) (*entity.Group, error) {
+func (gr StubGroupRepositoryImpl) FindByUuid(ctx context.Context, uuid string) (*entity.Group, error) {
 	var group entity.Group
 	return &group, nil
 }
 
-func (gr StubGroupRepositoryImpl) FindByName(name string) (*entity.Group, error) {
+func (gr StubGroupRepositoryImpl) FindByName(ctx context.Context, name string) (*entity.Group, error) {
 	var group *entity.Group
 	return group, nil
 }
 
-func (gr StubGroupRepositoryImpl) FindByUserUuid(userUuid string) ([]*entity.Group, error) {
+func (gr StubGroupRepositoryImpl) FindByUserUuid(ctx context.Context, userUuid string, page driver.Page) ([]*entity.Group, string, error) {
 	var groups []*entity.Group
-	return groups, nil
+	return groups, "", nil
 }
 
-func (gr StubGroupRepositoryImpl) FindByServiceUuid(serviceUuid string) ([]*entity.Group, error) {
+func (gr StubGroupRepositoryImpl) FindByServiceUuid(ctx context.Context, serviceUuid string, page driver.Page) ([]*entity.Group, string, error) {
 	var groups []*entity.Group
-	return groups, nil
+	return groups, "", nil
 }
 
-func (gr StubGroupRepositoryImpl) FindGroupWithUserWithPolicyGroupsByUserUuid(userUuid string) ([]*model.GroupWithUserGroupWithPolicy, error) {
+func (gr StubGroupRepositoryImpl) FindGroupWithUserWithPolicyGroupsByUserUuid(ctx context.Context, userUuid string) ([]*model.GroupWithUserGroupWithPolicy, error) {
 	var groupWithUserGroupWithPolicies []*model.GroupWithUserGroupWithPolicy
 	return groupWithUserGroupWithPolicies, nil
 }
 
-func (gr StubGroupRepositoryImpl) FindGroupWithPolicyByUserUuidAndGroupUuid(userUuid string, groupUuid string) (*model.GroupWithUserGroupWithPolicy, error) {
+func (gr StubGroupRepositoryImpl) FindGroupWithPolicyByUserUuidAndGroupUuid(ctx context.Context, userUuid string, groupUuid string) (*model.GroupWithUserGroupWithPolicy, error) {
 	var groupWithUserGroupWithPolicy model.GroupWithUserGroupWithPolicy
 	return &groupWithUserGroupWithPolicy, nil
 }
 
 func (gr StubGroupRepositoryImpl) SaveWithRelationalData(
+	ctx context.Context,
 	group entity.Group,
 	serviceGroup entity.ServiceGroup,
 	userGroup entity.UserGroup,
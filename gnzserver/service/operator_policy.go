@@ -1,6 +1,7 @@
 package service
 
 import (
+	"context"
 	"crypto/md5"
 	"encoding/hex"
 	"github.com/google/uuid"
@@ -16,19 +17,19 @@ var opsInstance OperatorPolicyService
 
 type OperatorPolicyService interface {
 	// Get
-	Get(queryParam string) ([]*entity.OperatorPolicy, *model.ErrorResBody)
+	Get(ctx context.Context, queryParam string) ([]*entity.OperatorPolicy, *model.ErrorResBody)
 
 	// Get all operator policy
-	GetAll() ([]*entity.OperatorPolicy, *model.ErrorResBody)
+	GetAll(ctx context.Context) ([]*entity.OperatorPolicy, *model.ErrorResBody)
 
 	// Get by user uuid
-	GetByUserUuid(userUuid string) ([]*entity.OperatorPolicy, *model.ErrorResBody)
+	GetByUserUuid(ctx context.Context, userUuid string) ([]*entity.OperatorPolicy, *model.ErrorResBody)
 
 	// Get user uuid and role uuid
-	GetByUserUuidAndRoleUuid(userUuid string, roleUuid string) (*entity.OperatorPolicy, *model.ErrorResBody)
+	GetByUserUuidAndRoleUuid(ctx context.Context, userUuid string, roleUuid string) (*entity.OperatorPolicy, *model.ErrorResBody)
 
 	// Insert policy
-	Insert(operatorPolicy *entity.OperatorPolicy) (*entity.OperatorPolicy, *model.ErrorResBody)
+	Insert(ctx context.Context, operatorPolicy *entity.OperatorPolicy) (*entity.OperatorPolicy, *model.ErrorResBody)
 }
 
 type OperatorPolicyServiceImpl struct {
@@ -53,12 +54,12 @@ func NewOperatorPolicyServiceService() OperatorPolicyService {
 	}
 }
 
-func (ops OperatorPolicyServiceImpl) Get(queryParam string) ([]*entity.OperatorPolicy, *model.ErrorResBody) {
+func (ops OperatorPolicyServiceImpl) Get(ctx context.Context, queryParam string) ([]*entity.OperatorPolicy, *model.ErrorResBody) {
 	if strings.EqualFold(queryParam, "") {
-		return ops.GetAll()
+		return ops.GetAll(ctx)
 	}
 
-	entities, err := ops.GetByUserUuid(queryParam)
+	entities, err := ops.GetByUserUuid(ctx, queryParam)
 	if err != nil {
 		return nil, err
 	}
@@ -70,66 +71,60 @@ func (ops OperatorPolicyServiceImpl) Get(queryParam string) ([]*entity.OperatorP
 	return entities, nil
 }
 
-func (ops OperatorPolicyServiceImpl) GetAll() ([]*entity.OperatorPolicy, *model.ErrorResBody) {
-	operatorPolicies, err := ops.OperatorPolicyRepository.FindAll()
+func (ops OperatorPolicyServiceImpl) GetAll(ctx context.Context) ([]*entity.OperatorPolicy, *model.ErrorResBody) {
+	operatorPolicies, err := ops.OperatorPolicyRepository.FindAll(ctx)
 	if err != nil {
-		if strings.Contains(err.Error(), "record not found") {
+		if err == driver.ErrNotFound {
 			return []*entity.OperatorPolicy{}, nil
 		}
-		return nil, model.InternalServerError(err.Error())
+		return nil, repositoryError(err)
 	}
 
 	return operatorPolicies, nil
 }
 
-func (ops OperatorPolicyServiceImpl) GetByUserUuid(userUuid string) ([]*entity.OperatorPolicy, *model.ErrorResBody) {
-	operatorPolicies, err := ops.OperatorPolicyRepository.FindByUserUuid(userUuid)
+func (ops OperatorPolicyServiceImpl) GetByUserUuid(ctx context.Context, userUuid string) ([]*entity.OperatorPolicy, *model.ErrorResBody) {
+	operatorPolicies, err := ops.OperatorPolicyRepository.FindByUserUuid(ctx, userUuid)
 	if err != nil {
-		if strings.Contains(err.Error(), "record not found") {
+		if err == driver.ErrNotFound {
 			return []*entity.OperatorPolicy{}, nil
 		}
-		return nil, model.InternalServerError(err.Error())
+		return nil, repositoryError(err)
 	}
 
 	return operatorPolicies, nil
 }
 
-func (ops OperatorPolicyServiceImpl) GetByUserUuidAndRoleUuid(userUuid string, roleUuid string) (*entity.OperatorPolicy, *model.ErrorResBody) {
-	operatorPolicy, err := ops.OperatorPolicyRepository.FindByUserUuidAndRoleUuid(userUuid, roleUuid)
+func (ops OperatorPolicyServiceImpl) GetByUserUuidAndRoleUuid(ctx context.Context, userUuid string, roleUuid string) (*entity.OperatorPolicy, *model.ErrorResBody) {
+	operatorPolicy, err := ops.OperatorPolicyRepository.FindByUserUuidAndRoleUuid(ctx, userUuid, roleUuid)
 	if err != nil {
-		if strings.Contains(err.Error(), "record not found") {
+		if err == driver.ErrNotFound {
 			return &entity.OperatorPolicy{}, nil
 		}
-		return nil, model.InternalServerError(err.Error())
+		return nil, repositoryError(err)
 	}
 
 	return operatorPolicy, nil
 }
 
-func (ops OperatorPolicyServiceImpl) Insert(entity *entity.OperatorPolicy) (*entity.OperatorPolicy, *model.ErrorResBody) {
+func (ops OperatorPolicyServiceImpl) Insert(ctx context.Context, entity *entity.OperatorPolicy) (*entity.OperatorPolicy, *model.ErrorResBody) {
 	operatorIdMd5 := md5.Sum(uuid.New().NodeID())
 	entity.InternalId = hex.EncodeToString(operatorIdMd5[:])
-	if _, err := ops.UserRepository.FindByUuid(entity.UserUuid.String()); err != nil {
-		if !strings.Contains(err.Error(), "record not found") {
-			return nil, model.InternalServerError(err.Error())
+	if _, err := ops.UserRepository.FindByUuid(ctx, entity.UserUuid.String()); err != nil {
+		if err != driver.ErrNotFound {
+			return nil, repositoryError(err)
 		}
 	}
 
-	if _, err := ops.RoleRepository.FindByUuid(entity.RoleUuid.String()); err != nil {
-		if !strings.Contains(err.Error(), "record not found") {
-			return nil, model.InternalServerError(err.Error())
+	if _, err := ops.RoleRepository.FindByUuid(ctx, entity.RoleUuid.String()); err != nil {
+		if err != driver.ErrNotFound {
+			return nil, repositoryError(err)
 		}
 	}
 
-	savedOperatorPolicy, err := ops.OperatorPolicyRepository.Save(*entity)
+	savedOperatorPolicy, err := ops.OperatorPolicyRepository.Save(ctx, *entity)
 	if err != nil {
-		if strings.Contains(err.Error(), "1062") {
-			return nil, model.Conflict("Already exit data.")
-		} else if strings.Contains(err.Error(), "1452") {
-			return nil, model.BadRequest("Not register relational id.")
-		} else {
-			return nil, model.InternalServerError(err.Error())
-		}
+		return nil, repositoryError(err, "Already exit data.")
 	}
 
 	return savedOperatorPolicy, nil
@@ -1,6 +1,7 @@
 package service
 
 import (
+	"context"
 	"testing"
 
 	"github.com/google/uuid"
@@ -41,7 +42,7 @@ func TestOperatorPolicy_Get_Error(t *testing.T) {
 		RoleRepository:           StubRoleRepositoryImpl{Connection: stubConnection},
 	}
 
-	_, err := operatorPolicyService.Get("test")
+	_, err := operatorPolicyService.Get(context.Background(), "test")
 	if err == nil {
 		t.Errorf("Incorrect TestOperatorPolicy_Get_Error test")
 		t.FailNow()
@@ -56,13 +57,13 @@ func TestOperatorPolicy_Get_Success(t *testing.T) {
 		RoleRepository:           StubRoleRepositoryImpl{Connection: stubConnection},
 	}
 
-	_, err := operatorPolicyService.Get("1")
+	_, err := operatorPolicyService.Get(context.Background(), "1")
 	if err != nil {
 		t.Errorf("Incorrect TestOperatorPolicy_Get_Success test")
 		t.FailNow()
 	}
 
-	_, err = operatorPolicyService.Get("")
+	_, err = operatorPolicyService.Get(context.Background(), "")
 	if err != nil {
 		t.Errorf("Incorrect TestOperatorPolicy_Get_Success test")
 		t.FailNow()
@@ -77,7 +78,7 @@ func TestOperatorPolicy_GetByUserIdAndRoleId_Success(t *testing.T) {
 		RoleRepository:           StubRoleRepositoryImpl{Connection: stubConnection},
 	}
 
-	_, err := operatorPolicyService.GetByUserUuidAndRoleUuid(uuid.New().String(), uuid.New().String())
+	_, err := operatorPolicyService.GetByUserUuidAndRoleUuid(context.Background(), uuid.New().String(), uuid.New().String())
 	if err != nil {
 		t.Errorf("Incorrect TestOperatorPolicy_GetByUserIdAndRoleId test")
 		t.FailNow()
@@ -92,7 +93,7 @@ func TestOperatorPolicy_Insert_Success(t *testing.T) {
 		RoleRepository:           StubRoleRepositoryImpl{Connection: stubConnection},
 	}
 
-	_, err := operatorPolicyService.Insert(&entity.OperatorPolicy{InternalId: "", UserUuid: uuid.New(), RoleUuid: uuid.New()})
+	_, err := operatorPolicyService.Insert(context.Background(), &entity.OperatorPolicy{InternalId: "", UserUuid: uuid.New(), RoleUuid: uuid.New()})
 	if err != nil {
 		t.Errorf("Incorrect TestOperatorPolicy_GetByUserIdAndRoleId test")
 		t.FailNow()
@@ -105,25 +106,25 @@ type StubOperatorPolicyRepositoryImpl struct {
 	Connection *gorm.DB
 }
 
-func (opr StubOperatorPolicyRepositoryImpl) FindAll() ([]*entity.OperatorPolicy, error) {
+func (opr StubOperatorPolicyRepositoryImpl) FindAll(ctx context.Context) ([]*entity.OperatorPolicy, error) {
 	var entities []*entity.OperatorPolicy
 	return entities, nil
 }
 
-func (opr StubOperatorPolicyRepositoryImpl) FindByUserUuid(userUuid string) ([]*entity.OperatorPolicy, error) {
+func (opr StubOperatorPolicyRepositoryImpl) FindByUserUuid(ctx context.Context, userUuid string) ([]*entity.OperatorPolicy, error) {
 	var entities []*entity.OperatorPolicy
 	return entities, nil
 }
 
-func (opr StubOperatorPolicyRepositoryImpl) FindByUserUuidAndRoleUuid(userUuid string, roleUuid string) (*entity.OperatorPolicy, error) {
+func (opr StubOperatorPolicyRepositoryImpl) FindByUserUuidAndRoleUuid(ctx context.Context, userUuid string, roleUuid string) (*entity.OperatorPolicy, error) {
 	var operatorMemberRole entity.OperatorPolicy
 	return &operatorMemberRole, nil
 }
 
-func (opr StubOperatorPolicyRepositoryImpl) FindRoleNameByUserUuid(userUuid string) ([]string, error) {
+func (opr StubOperatorPolicyRepositoryImpl) FindRoleNameByUserUuid(ctx context.Context, userUuid string) ([]string, error) {
 	return []string{""}, nil
 }
 
-func (opr StubOperatorPolicyRepositoryImpl) Save(entity entity.OperatorPolicy) (*entity.OperatorPolicy, error) {
+func (opr StubOperatorPolicyRepositoryImpl) Save(ctx context.Context, entity entity.OperatorPolicy) (*entity.OperatorPolicy, error) {
 	return &entity, nil
 }
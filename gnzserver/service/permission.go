@@ -1,9 +1,9 @@
 package service
 
 import (
+	"context"
 	"crypto/md5"
 	"encoding/hex"
-	"strings"
 
 	"github.com/google/uuid"
 
@@ -18,23 +18,24 @@ var psInstance PermissionService
 
 type PermissionService interface {
 	// Get all permissions
-	GetPermissions() ([]*entity.Permission, *model.ErrorResBody)
+	GetPermissions(ctx context.Context) ([]*entity.Permission, *model.ErrorResBody)
 
 	// Get permission by uuid
-	GetPermissionByUuid(uuid string) (*entity.Permission, *model.ErrorResBody)
+	GetPermissionByUuid(ctx context.Context, uuid string) (*entity.Permission, *model.ErrorResBody)
 
 	// Get permission by name
-	GetPermissionByName(name string) (*entity.Permission, *model.ErrorResBody)
+	GetPermissionByName(ctx context.Context, name string) (*entity.Permission, *model.ErrorResBody)
 
 	// Get permissions by group uuid
 	// Join group_permission and permission
-	GetPermissionsByGroupUuid(groupUuid string) ([]*entity.Permission, *model.ErrorResBody)
+	// Next cursor is empty if it is the last page
+	GetPermissionsByGroupUuid(ctx context.Context, groupUuid string, page driver.Page) ([]*entity.Permission, string, *model.ErrorResBody)
 
 	// Inert permission
-	InsertPermission(permission *entity.Permission) (*entity.Permission, *model.ErrorResBody)
+	InsertPermission(ctx context.Context, permission *entity.Permission) (*entity.Permission, *model.ErrorResBody)
 
 	// Insert permission with relational data
-	InsertWithRelationalData(groupUuid string, permission entity.Permission) (*entity.Permission, *model.ErrorResBody)
+	InsertWithRelationalData(ctx context.Context, groupUuid string, permission entity.Permission) (*entity.Permission, *model.ErrorResBody)
 }
 
 type PermissionServiceImpl struct {
@@ -57,84 +58,72 @@ func NewPermissionService() PermissionService {
 	}
 }
 
-func (ps PermissionServiceImpl) GetPermissions() ([]*entity.Permission, *model.ErrorResBody) {
-	permissions, err := ps.PermissionRepository.FindAll()
+func (ps PermissionServiceImpl) GetPermissions(ctx context.Context) ([]*entity.Permission, *model.ErrorResBody) {
+	permissions, err := ps.PermissionRepository.FindAll(ctx)
 	if err != nil {
-		if strings.Contains(err.Error(), "record not found") {
+		if err == driver.ErrNotFound {
 			return []*entity.Permission{}, nil
 		}
-		return nil, model.InternalServerError(err.Error())
+		return nil, repositoryError(err)
 	}
 
 	return permissions, nil
 }
 
-func (ps PermissionServiceImpl) GetPermissionByUuid(uuid string) (*entity.Permission, *model.ErrorResBody) {
-	permission, err := ps.PermissionRepository.FindByUuid(uuid)
+func (ps PermissionServiceImpl) GetPermissionByUuid(ctx context.Context, uuid string) (*entity.Permission, *model.ErrorResBody) {
+	permission, err := ps.PermissionRepository.FindByUuid(ctx, uuid)
 	if err != nil {
-		if strings.Contains(err.Error(), "record not found") {
+		if err == driver.ErrNotFound {
 			return &entity.Permission{}, nil
 		}
-		return nil, model.InternalServerError(err.Error())
+		return nil, repositoryError(err)
 	}
 
 	return permission, nil
 }
 
-func (ps PermissionServiceImpl) GetPermissionByName(name string) (*entity.Permission, *model.ErrorResBody) {
-	permission, err := ps.PermissionRepository.FindByName(name)
+func (ps PermissionServiceImpl) GetPermissionByName(ctx context.Context, name string) (*entity.Permission, *model.ErrorResBody) {
+	permission, err := ps.PermissionRepository.FindByName(ctx, name)
 	if err != nil {
-		if strings.Contains(err.Error(), "record not found") {
-			return nil, model.NotFound("Not found permission")
-		}
-		return nil, model.InternalServerError(err.Error())
+		return nil, repositoryError(err, "Not found permission")
 	}
 
 	return permission, nil
 }
 
-func (ps PermissionServiceImpl) GetPermissionsByGroupUuid(groupUuid string) ([]*entity.Permission, *model.ErrorResBody) {
-	permissions, err := ps.PermissionRepository.FindByGroupUuid(groupUuid)
+func (ps PermissionServiceImpl) GetPermissionsByGroupUuid(ctx context.Context, groupUuid string, page driver.Page) ([]*entity.Permission, string, *model.ErrorResBody) {
+	permissions, next, err := ps.PermissionRepository.FindByGroupUuid(ctx, groupUuid, page)
 	if err != nil {
-		if strings.Contains(err.Error(), "record not found") {
-			return nil, model.NotFound("Not found permissions")
-		}
-		return nil, model.InternalServerError()
+		return nil, "", repositoryError(err, "Not found permissions")
 	}
 
-	return permissions, nil
+	return permissions, next, nil
 }
 
-func (ps PermissionServiceImpl) InsertPermission(permission *entity.Permission) (*entity.Permission, *model.ErrorResBody) {
+func (ps PermissionServiceImpl) InsertPermission(ctx context.Context, permission *entity.Permission) (*entity.Permission, *model.ErrorResBody) {
 	permissionId := uuid.New()
 	permissionMd5 := md5.Sum(permissionId.NodeID())
 	permission.Uuid = permissionId
 	permission.InternalId = hex.EncodeToString(permissionMd5[:])
 
-	savedPermission, err := ps.PermissionRepository.Save(*permission)
+	savedPermission, err := ps.PermissionRepository.Save(ctx, *permission)
 	if err != nil {
 		log.Logger.Warn(err.Error())
-		if strings.Contains(err.Error(), "1062") {
-			return nil, model.Conflict("Already exit data.")
-		}
-		return nil, model.InternalServerError(err.Error())
+		return nil, repositoryError(err, "Already exit data.")
 	}
 
 	return savedPermission, nil
 }
 
-func (ps PermissionServiceImpl) InsertWithRelationalData(groupUuid string, permission entity.Permission) (*entity.Permission, *model.ErrorResBody) {
+func (ps PermissionServiceImpl) InsertWithRelationalData(ctx context.Context, groupUuid string, permission entity.Permission) (*entity.Permission, *model.ErrorResBody) {
 	permissionId := uuid.New()
 	permissionMd5 := md5.Sum(permissionId.NodeID())
 	permission.Uuid = permissionId
 	permission.InternalId = hex.EncodeToString(permissionMd5[:])
 
-	savedData, err := ps.PermissionRepository.SaveWithRelationalData(groupUuid, permission)
+	savedData, err := ps.PermissionRepository.SaveWithRelationalData(ctx, groupUuid, permission)
 	if err != nil {
-		if strings.Contains(err.Error(), "1062") {
-			return nil, model.Conflict("Already exit group permission data.")
-		}
-		return nil, model.InternalServerError()
+		return nil, repositoryError(err, "Already exit group permission data.")
 	}
 
 	return savedData, nil
@@ -1,6 +1,7 @@
 package service
 
 import (
+	"context"
 	"testing"
 	"time"
 
@@ -9,6 +10,7 @@ import (
 	"github.com/google/uuid"
 	"github.com/jinzhu/gorm"
 	"github.com/tomoyane/grant-n-z/gnz/cache"
+	"github.com/tomoyane/grant-n-z/gnz/driver"
 	"github.com/tomoyane/grant-n-z/gnz/entity"
 	"github.com/tomoyane/grant-n-z/gnz/log"
 )
@@ -43,7 +45,7 @@ func TestGetPermissionServiceInstance(t *testing.T) {
 
 // Test get permissions
 func TestGetPermissions_Success(t *testing.T) {
-	_, err := permissionService.GetPermissions()
+	_, err := permissionService.GetPermissions(context.Background())
 	if err != nil {
 		t.Errorf("Incorrect TestGetPermissions_Success test")
 		t.FailNow()
@@ -52,7 +54,7 @@ func TestGetPermissions_Success(t *testing.T) {
 
 // Test get by id
 func TestGetPermissionById_Success(t *testing.T) {
-	_, err := permissionService.GetPermissionByUuid(uuid.New().String())
+	_, err := permissionService.GetPermissionByUuid(context.Background(), uuid.New().String())
 	if err != nil {
 		t.Errorf("Incorrect TestGetPermissionById_Success test")
 		t.FailNow()
@@ -61,7 +63,7 @@ func TestGetPermissionById_Success(t *testing.T) {
 
 // Test get by name
 func TestGetPermissionByName_Success(t *testing.T) {
-	_, err := permissionService.GetPermissionByName("name")
+	_, err := permissionService.GetPermissionByName(context.Background(), "name")
 	if err != nil {
 		t.Errorf("Incorrect TestGetPermissionByName_Success test")
 		t.FailNow()
@@ -70,7 +72,7 @@ func TestGetPermissionByName_Success(t *testing.T) {
 
 // Test get by group id
 func TestGetPermissionsByGroupId_Success(t *testing.T) {
-	_, err := permissionService.GetPermissionsByGroupUuid(uuid.New().String())
+	_, _, err := permissionService.GetPermissionsByGroupUuid(context.Background(), uuid.New().String(), driver.Page{})
 	if err != nil {
 		t.Errorf("Incorrect TestGetPermissionsByGroupId_Success test")
 		t.FailNow()
@@ -79,7 +81,7 @@ func TestGetPermissionsByGroupId_Success(t *testing.T) {
 
 // Test insert
 func TestInsertPermission_Success(t *testing.T) {
-	_, err := permissionService.InsertPermission(&entity.Permission{})
+	_, err := permissionService.InsertPermission(context.Background(), &entity.Permission{})
 	if err != nil {
 		t.Errorf("Incorrect TestInsertPermission_Success test")
 		t.FailNow()
@@ -88,7 +90,7 @@ func TestInsertPermission_Success(t *testing.T) {
 
 // Test insert with relational data
 func TestInsertWithRelationalData_Success(t *testing.T) {
-	_, err := permissionService.InsertWithRelationalData(uuid.New().String(), entity.Permission{})
+	_, err := permissionService.InsertWithRelationalData(context.Background(), uuid.New().String(), entity.Permission{})
 	if err != nil {
 		t.Errorf("Incorrect TestInsertWithRelationalData_Success test")
 		t.FailNow()
@@ -101,45 +103,45 @@ type StubPermissionRepositoryImpl struct {
 	Connection *gorm.DB
 }
 
-func (pri StubPermissionRepositoryImpl) FindAll() ([]*entity.Permission, error) {
+func (pri StubPermissionRepositoryImpl) FindAll(ctx context.Context) ([]*entity.Permission, error) {
 	permissions := []*entity.Permission{{InternalId: "", Name: "test"}}
 	return permissions, nil
 }
 
-func (pri StubPermissionRepositoryImpl) FindOffSetAndLimit(offsetCnt int, limitCnt int) ([]*entity.Permission, error) {
+func (pri StubPermissionRepositoryImpl) FindOffSetAndLimit(ctx context.Context, offsetCnt int, limitCnt int) ([]*entity.Permission, error) {
 	var permissions []*entity.Permission
 	return permissions, nil
 }
 
-func (pri StubPermissionRepositoryImpl) FindByUuid(uuid string) (*entity.Permission, error) {
+func (pri StubPermissionRepositoryImpl) FindByUuid(ctx context.Context, uuid string) (*entity.Permission, error) {
 	var permission entity.Permission
 	return &permission, nil
 }
 
-func (pri StubPermissionRepositoryImpl) FindByName(name string) (*entity.Permission, error) {
+func (pri StubPermissionRepositoryImpl) FindByName(ctx context.Context, name string) (*entity.Permission, error) {
 	var permission entity.Permission
 	return &permission, nil
 }
 
-func (pri StubPermissionRepositoryImpl) FindByNames(names []string) ([]entity.Permission, error) {
+func (pri StubPermissionRepositoryImpl) FindByNames(ctx context.Context, names []string) ([]entity.Permission, error) {
 	var permissions []entity.Permission
 	permissions = append(permissions, entity.Permission{Name: "test"})
 	return permissions, nil
 }
 
-func (pri StubPermissionRepositoryImpl) FindByGroupUuid(groupUuid string) ([]*entity.Permission, error) {
+func (pri StubPermissionRepositoryImpl) FindByGroupUuid(ctx context.Context, groupUuid string, page driver.Page) ([]*entity.Permission, string, error) {
 	var permissions []*entity.Permission
-	return permissions, nil
+	return permissions, "", nil
 }
 
-func (pri StubPermissionRepositoryImpl) FindNameByUuid(uuid string) *string {
+func (pri StubPermissionRepositoryImpl) FindNameByUuid(ctx context.Context, uuid string) *string {
 	return nil
 }
 
-func (pri StubPermissionRepositoryImpl) Save(permission entity.Permission) (*entity.Permission, error) {
+func (pri StubPermissionRepositoryImpl) Save(ctx context.Context, permission entity.Permission) (*entity.Permission, error) {
 	return &permission, nil
 }
 
-func (pri StubPermissionRepositoryImpl) SaveWithRelationalData(groupUuid string, permission entity.Permission) (*entity.Permission, error) {
+func (pri StubPermissionRepositoryImpl) SaveWithRelationalData(ctx context.Context, groupUuid string, permission entity.Permission) (*entity.Permission, error) {
 	return &permission, nil
 }
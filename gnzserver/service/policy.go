@@ -1,10 +1,10 @@
 package service
 
 import (
+	"context"
 	"crypto/md5"
 	"encoding/hex"
 	"github.com/google/uuid"
-	"strings"
 
 	"github.com/tomoyane/grant-n-z/gnz/cache"
 	"github.com/tomoyane/grant-n-z/gnz/cache/structure"
@@ -20,26 +20,28 @@ var (
 
 type PolicyService interface {
 	// Get all policy
-	GetPolicies() ([]*entity.Policy, *model.ErrorResBody)
+	GetPolicies(ctx context.Context) ([]*entity.Policy, *model.ErrorResBody)
 
 	// Get policy by role uuid
-	GetPoliciesByRoleUuid(roleUuid string) ([]*entity.Policy, *model.ErrorResBody)
+	GetPoliciesByRoleUuid(ctx context.Context, roleUuid string) ([]*entity.Policy, *model.ErrorResBody)
 
 	// Get policies of user
 	// The method uses request scope user id
-	GetPoliciesByUser(userUuid string) ([]model.PolicyResponse, *model.ErrorResBody)
+	GetPoliciesByUser(ctx context.Context, userUuid string) ([]model.PolicyResponse, *model.ErrorResBody)
 
 	// Get policy by user_groups data
-	GetPolicyByUserGroup(userUuid string, groupUuid string) (*entity.Policy, *model.ErrorResBody)
+	GetPolicyByUserGroup(ctx context.Context, userUuid string, groupUuid string) (*entity.Policy, *model.ErrorResBody)
 
 	// Get policies by group uuid
-	GetPoliciesByUserGroup(groupUuid string) ([]model.UserPolicyOnGroupResponse, *model.ErrorResBody)
+	// Users of group are paginated by page. Next cursor is empty if it is the last page
+	GetPoliciesByUserGroup(ctx context.Context, groupUuid string, page driver.Page) ([]model.UserPolicyOnGroupResponse, string, *model.ErrorResBody)
 
 	// Get policy by uuid
-	GetPolicyByUuid(uuid string) (entity.Policy, *model.ErrorResBody)
+	GetPolicyByUuid(ctx context.Context, uuid string) (entity.Policy, *model.ErrorResBody)
 
-	// Insert or update policy
-	UpdatePolicy(policyRequest model.PolicyRequest, secret string, groupUuid string) (*entity.Policy, *model.ErrorResBody)
+	// Insert or update policy whose version is the version
+	// If the version is 0, insert policy only if user does not have it. If other request changed it, 412
+	UpdatePolicy(ctx context.Context, policyRequest model.PolicyRequest, secret string, groupUuid string, version int) (*entity.Policy, *model.ErrorResBody)
 }
 
 // PolicyService struct
@@ -76,63 +78,51 @@ func NewPolicyService() PolicyService {
 	}
 }
 
-func (ps PolicyServiceImpl) GetPolicies() ([]*entity.Policy, *model.ErrorResBody) {
-	policies, err := ps.PolicyRepository.FindAll()
+func (ps PolicyServiceImpl) GetPolicies(ctx context.Context) ([]*entity.Policy, *model.ErrorResBody) {
+	policies, err := ps.PolicyRepository.FindAll(ctx)
 	if err != nil {
-		if strings.Contains(err.Error(), "record not found") {
+		if err == driver.ErrNotFound {
 			return []*entity.Policy{}, nil
 		}
-		return nil, model.InternalServerError(err.Error())
+		return nil, repositoryError(err)
 	}
 
 	return policies, nil
 }
 
-func (ps PolicyServiceImpl) GetPoliciesByRoleUuid(roleUuid string) ([]*entity.Policy, *model.ErrorResBody) {
-	policies, err := ps.PolicyRepository.FindByRoleUuid(roleUuid)
+func (ps PolicyServiceImpl) GetPoliciesByRoleUuid(ctx context.Context, roleUuid string) ([]*entity.Policy, *model.ErrorResBody) {
+	policies, err := ps.PolicyRepository.FindByRoleUuid(ctx, roleUuid)
 	if err != nil {
-		if strings.Contains(err.Error(), "record not found") {
-			return nil, model.NotFound("Not found policies")
-		}
-		return nil, model.InternalServerError(err.Error())
+		return nil, repositoryError(err, "Not found policies")
 	}
 
 	return policies, nil
 }
 
-func (ps PolicyServiceImpl) GetPoliciesByUser(userUuid string) ([]model.PolicyResponse, *model.ErrorResBody) {
-	userGroupPolicies, err := ps.GroupRepository.FindGroupWithUserWithPolicyGroupsByUserUuid(userUuid)
+func (ps PolicyServiceImpl) GetPoliciesByUser(ctx context.Context, userUuid string) ([]model.PolicyResponse, *model.ErrorResBody) {
+	userGroupPolicies, err := ps.GroupRepository.FindGroupWithUserWithPolicyGroupsByUserUuid(ctx, userUuid)
 	if err != nil {
-		if strings.Contains(err.Error(), "record not found") {
+		if err == driver.ErrNotFound {
 			return []model.PolicyResponse{}, nil
 		}
-		return nil, model.InternalServerError(err.Error())
+		return nil, repositoryError(err)
 	}
 
 	policyResponses := []model.PolicyResponse{}
 	for _, ugp := range userGroupPolicies {
-		role, err := ps.RoleRepository.FindByUuid(ugp.Policy.RoleUuid.String())
+		role, err := ps.RoleRepository.FindByUuid(ctx, ugp.Policy.RoleUuid.String())
 		if err != nil {
-			if strings.Contains(err.Error(), "record not found") {
-				return nil, model.NotFound("Not found role that have policy")
-			}
-			return nil, model.InternalServerError(err.Error())
+			return nil, repositoryError(err, "Not found role that have policy")
 		}
 
-		permission, err := ps.PermissionRepository.FindByUuid(ugp.Policy.PermissionUuid.String())
+		permission, err := ps.PermissionRepository.FindByUuid(ctx, ugp.Policy.PermissionUuid.String())
 		if err != nil {
-			if strings.Contains(err.Error(), "record not found") {
-				return nil, model.NotFound("Not found permission that have policy")
-			}
-			return nil, model.InternalServerError(err.Error())
+			return nil, repositoryError(err, "Not found permission that have policy")
 		}
 
-		service, err := ps.ServiceRepository.FindByUuid(ugp.Policy.ServiceUuid.String())
+		service, err := ps.ServiceRepository.FindByUuid(ctx, ugp.Policy.ServiceUuid.String())
 		if err != nil {
-			if strings.Contains(err.Error(), "record not found") {
-				return nil, model.NotFound("Not found service that have policy")
-			}
-			return nil, model.InternalServerError(err.Error())
+			return nil, repositoryError(err, "Not found service that have policy")
 		}
 
 		policyResponse := model.NewPolicyResponse().
@@ -149,88 +139,85 @@ func (ps PolicyServiceImpl) GetPoliciesByUser(userUuid string) ([]model.PolicyRe
 	return policyResponses, nil
 }
 
-func (ps PolicyServiceImpl) GetPolicyByUserGroup(userUuid string, groupUuid string) (*entity.Policy, *model.ErrorResBody) {
-	groupWithPolicy, err := ps.GroupRepository.FindGroupWithPolicyByUserUuidAndGroupUuid(userUuid, groupUuid)
+func (ps PolicyServiceImpl) GetPolicyByUserGroup(ctx context.Context, userUuid string, groupUuid string) (*entity.Policy, *model.ErrorResBody) {
+	groupWithPolicy, err := ps.GroupRepository.FindGroupWithPolicyByUserUuidAndGroupUuid(ctx, userUuid, groupUuid)
 	if err != nil {
-		if strings.Contains(err.Error(), "record not found") {
-			return nil, model.NotFound("Not found policy")
-		}
-		return nil, model.InternalServerError(err.Error())
+		return nil, repositoryError(err, "Not found policy")
 	}
 
 	return &groupWithPolicy.Policy, nil
 }
 
-func (ps PolicyServiceImpl) GetPoliciesByUserGroup(groupUuid string) ([]model.UserPolicyOnGroupResponse, *model.ErrorResBody) {
-	users, err := ps.UserRepository.FindByGroupUuid(groupUuid)
+func (ps PolicyServiceImpl) GetPoliciesByUserGroup(ctx context.Context, groupUuid string, page driver.Page) ([]model.UserPolicyOnGroupResponse, string, *model.ErrorResBody) {
+	users, next, err := ps.UserRepository.FindByGroupUuid(ctx, groupUuid, page)
 	if err != nil {
-		return nil, model.InternalServerError(err.Error())
+		return nil, "", repositoryError(err)
 	}
 
 	var userPolicies []model.UserPolicyOnGroupResponse
 	for _, user := range users {
-		policyResponse, err := ps.PolicyRepository.FindPolicyOfUserGroupByUserUuidAndGroupUuid(user.Uuid.String(), groupUuid)
+		policyResponse, err := ps.PolicyRepository.FindPolicyOfUserGroupByUserUuidAndGroupUuid(ctx, user.Uuid.String(), groupUuid)
 		if err != nil {
-			return nil, model.InternalServerError()
+			return nil, "", model.InternalServerError()
 		}
 		userPolicies = append(userPolicies, policyResponse)
 	}
 
-	return userPolicies, nil
+	return userPolicies, next, nil
 }
 
-func (ps PolicyServiceImpl) GetPolicyByUuid(uuid string) (entity.Policy, *model.ErrorResBody) {
-	policy, err := ps.PolicyRepository.FindByUuid(uuid)
+func (ps PolicyServiceImpl) GetPolicyByUuid(ctx context.Context, uuid string) (entity.Policy, *model.ErrorResBody) {
+	policy, err := ps.PolicyRepository.FindByUuid(ctx, uuid)
 	if err != nil {
-		if strings.Contains(err.Error(), "record not found") {
+		if err == driver.ErrNotFound {
 			return entity.Policy{}, nil
 		}
 
-		return policy, model.InternalServerError(err.Error())
+		return policy, repositoryError(err)
 	}
 
 	return policy, nil
 }
 
-func (ps PolicyServiceImpl) UpdatePolicy(policyRequest model.PolicyRequest, secret string, groupUuid string) (*entity.Policy, *model.ErrorResBody) {
-	user, errUser := ps.UserRepository.FindByEmail(policyRequest.ToUserEmail)
+func (ps PolicyServiceImpl) UpdatePolicy(ctx context.Context, policyRequest model.PolicyRequest, secret string, groupUuid string, version int) (*entity.Policy, *model.ErrorResBody) {
+	user, errUser := ps.UserRepository.FindByEmail(ctx, policyRequest.ToUserEmail)
 	if errUser != nil {
-		if strings.Contains(errUser.Error(), "record not found") {
+		if errUser == driver.ErrNotFound {
 			return nil, model.BadRequest("Not exist this user")
 		}
-		return nil, model.InternalServerError(errUser.Error())
+		return nil, repositoryError(errUser)
 	}
 
-	userGroup, errGroup := ps.UserRepository.FindUserGroupByUserUuidAndGroupUuid(user.Uuid.String(), groupUuid)
+	userGroup, errGroup := ps.UserRepository.FindUserGroupByUserUuidAndGroupUuid(ctx, user.Uuid.String(), groupUuid)
 	if errGroup != nil {
-		if strings.Contains(errGroup.Error(), "record not found") {
+		if errGroup == driver.ErrNotFound {
 			return nil, model.BadRequest("Not exist this user in group")
 		}
-		return nil, model.InternalServerError(errGroup.Error())
+		return nil, repositoryError(errGroup)
 	}
 
-	role, errRole := ps.RoleRepository.FindByUuid(policyRequest.RoleUuid)
+	role, errRole := ps.RoleRepository.FindByUuid(ctx, policyRequest.RoleUuid)
 	if errRole != nil {
-		if strings.Contains(errRole.Error(), "record not found") {
+		if errRole == driver.ErrNotFound {
 			return nil, model.BadRequest("Not exist role")
 		}
-		return nil, model.InternalServerError(errRole.Error())
+		return nil, repositoryError(errRole)
 	}
 
-	permission, errPermission := ps.PermissionRepository.FindByUuid(policyRequest.PermissionUuid)
+	permission, errPermission := ps.PermissionRepository.FindByUuid(ctx, policyRequest.PermissionUuid)
 	if errPermission != nil {
-		if strings.Contains(errPermission.Error(), "record not found") {
+		if errPermission == driver.ErrNotFound {
 			return nil, model.BadRequest("Not exist permission")
 		}
-		return nil, model.InternalServerError(errPermission.Error())
+		return nil, repositoryError(errPermission)
 	}
 
-	ser, errSer := ps.ServiceRepository.FindBySecret(secret)
+	ser, errSer := ps.ServiceRepository.FindBySecret(ctx, secret)
 	if errSer != nil {
-		if strings.Contains(errSer.Error(), "record not found") {
+		if errSer == driver.ErrNotFound {
 			return nil, model.BadRequest("Not exist service")
 		}
-		return nil, model.InternalServerError(errSer.Error())
+		return nil, repositoryError(errSer)
 	}
 
 	policyMd5 := md5.Sum(uuid.New().NodeID())
@@ -241,22 +228,17 @@ func (ps PolicyServiceImpl) UpdatePolicy(policyRequest model.PolicyRequest, secr
 		PermissionUuid: permission.Uuid,
 		ServiceUuid:    ser.Uuid,
 		UserGroupUuid:  userGroup.Uuid,
+		Version:        version,
 	}
 
 	// Update RDBMS
-	updatedPolicy, err := ps.PolicyRepository.Update(policy)
+	updatedPolicy, err := ps.PolicyRepository.Update(ctx, policy)
 	if err != nil {
-		if strings.Contains(err.Error(), "1062") {
-			return nil, model.Conflict("Already exit data.")
-		} else if strings.Contains(err.Error(), "1452") {
-			return nil, model.BadRequest("Not register relational id.")
-		} else {
-			return nil, model.InternalServerError()
-		}
+		return nil, repositoryError(err, "Already exit data.")
 	}
 
 	// Update etcd
-	userPolicies := ps.EtcdClient.GetUserPolicy(user.Uuid.String())
+	userPolicies := ps.EtcdClient.GetUserPolicy(ctx, user.Uuid.String())
 	var updatePolicies []structure.UserPolicy
 	for _, userPolicy := range userPolicies {
 		if updatedPolicy.ServiceUuid.String() == userPolicy.ServiceUuid {
@@ -272,6 +254,6 @@ func (ps PolicyServiceImpl) UpdatePolicy(policyRequest model.PolicyRequest, secr
 		}
 	}
 
-	ps.EtcdClient.SetUserPolicy(user.Uuid.String(), updatePolicies)
+	ps.EtcdClient.SetUserPolicy(ctx, user.Uuid.String(), updatePolicies)
 	return updatedPolicy, nil
 }
@@ -1,13 +1,16 @@
 package service
 
 import (
+	"context"
 	"github.com/google/uuid"
 	"github.com/jinzhu/gorm"
 	"github.com/tomoyane/grant-n-z/gnz/cache"
+	"github.com/tomoyane/grant-n-z/gnz/driver"
 	"github.com/tomoyane/grant-n-z/gnz/entity"
 	"github.com/tomoyane/grant-n-z/gnz/log"
 	"github.com/tomoyane/grant-n-z/gnzserver/model"
 	"go.etcd.io/etcd/clientv3"
+	"net/http"
 	"testing"
 	"time"
 )
@@ -45,7 +48,7 @@ func TestGetPolicyServiceInstance(t *testing.T) {
 
 // Test get policies
 func TestGetPolicies_Success(t *testing.T) {
-	_, err := policyService.GetPolicies()
+	_, err := policyService.GetPolicies(context.Background())
 	if err != nil {
 		t.Errorf("Incorrect TestGetPolicies_Success test")
 		t.FailNow()
@@ -54,7 +57,7 @@ func TestGetPolicies_Success(t *testing.T) {
 
 // Test get policies by role id
 func TestGetPoliciesByRoleId_Success(t *testing.T) {
-	_, err := policyService.GetPoliciesByRoleUuid(uuid.New().String())
+	_, err := policyService.GetPoliciesByRoleUuid(context.Background(), uuid.New().String())
 	if err != nil {
 		t.Errorf("Incorrect TestGetPoliciesByRoleId_Success test")
 		t.FailNow()
@@ -63,7 +66,7 @@ func TestGetPoliciesByRoleId_Success(t *testing.T) {
 
 // Test get policies of request user
 func TestGetPoliciesOfUser_Success(t *testing.T) {
-	_, err := policyService.GetPoliciesByUser(uuid.New().String())
+	_, err := policyService.GetPoliciesByUser(context.Background(), uuid.New().String())
 	if err != nil {
 		t.Errorf("Incorrect TestGetPoliciesOfUser_Success test")
 		t.FailNow()
@@ -72,7 +75,7 @@ func TestGetPoliciesOfUser_Success(t *testing.T) {
 
 // Test get policy by user_group
 func TestGetPolicyByUserGroup_Success(t *testing.T) {
-	_, err := policyService.GetPolicyByUserGroup(uuid.New().String(), uuid.New().String())
+	_, err := policyService.GetPolicyByUserGroup(context.Background(), uuid.New().String(), uuid.New().String())
 	if err != nil {
 		t.Errorf("Incorrect TestGetPolicyByUserGroup_Success test")
 		t.FailNow()
@@ -81,7 +84,7 @@ func TestGetPolicyByUserGroup_Success(t *testing.T) {
 
 // Test get policies by user_group
 func TestGetPoliciesOfUserGroup_Success(t *testing.T) {
-	_, err := policyService.GetPoliciesByUserGroup(uuid.New().String())
+	_, _, err := policyService.GetPoliciesByUserGroup(context.Background(), uuid.New().String(), driver.Page{})
 	if err != nil {
 		t.Errorf("Incorrect TestGetPoliciesOfUserGroup_Success test")
 		t.FailNow()
@@ -90,7 +93,7 @@ func TestGetPoliciesOfUserGroup_Success(t *testing.T) {
 
 // Test get policies by id
 func TestGetPolicyById_Success(t *testing.T) {
-	_, err := policyService.GetPolicyByUuid(uuid.New().String())
+	_, err := policyService.GetPolicyByUuid(context.Background(), uuid.New().String())
 	if err != nil {
 		t.Errorf("Incorrect TestGetPolicyById_Success test")
 		t.FailNow()
@@ -99,49 +102,70 @@ func TestGetPolicyById_Success(t *testing.T) {
 
 // Test update
 func TestUpdatePolicy_Success(t *testing.T) {
-	_, err := policyService.UpdatePolicy(model.PolicyRequest{}, "", "")
+	_, err := policyService.UpdatePolicy(context.Background(), model.PolicyRequest{}, "", "", 1)
 	if err != nil {
 		t.Errorf("Incorrect TestUpdatePolicy_Success test")
 		t.FailNow()
 	}
 }
 
+// Test create that lost to concurrent create of the same user group
+func TestUpdatePolicy_ConcurrentCreate(t *testing.T) {
+	stalePolicyService := policyService.(PolicyServiceImpl)
+	stalePolicyService.PolicyRepository = StubStalePolicyRepositoryImpl{StubPolicyRepositoryImpl{Connection: stubConnection}}
+	_, err := stalePolicyService.UpdatePolicy(context.Background(), model.PolicyRequest{}, "", "", 0)
+	if err == nil || err.Code != http.StatusPreconditionFailed {
+		t.Errorf("Incorrect TestUpdatePolicy_ConcurrentCreate test. err = %v", err)
+		t.FailNow()
+	}
+}
+
 // Less than stub struct
 // Policy repository
 type StubPolicyRepositoryImpl struct {
 	Connection *gorm.DB
 }
 
-func (pri StubPolicyRepositoryImpl) FindAll() ([]*entity.Policy, error) {
+func (pri StubPolicyRepositoryImpl) FindAll(ctx context.Context) ([]*entity.Policy, error) {
 	var policies []*entity.Policy
 	return policies, nil
 }
 
-func (pri StubPolicyRepositoryImpl) FindOffSetAndLimit(offsetCnt int, limitCnt int) ([]*entity.Policy, error) {
+func (pri StubPolicyRepositoryImpl) FindOffSetAndLimit(ctx context.Context, offsetCnt int, limitCnt int) ([]*entity.Policy, error) {
 	var policies []*entity.Policy
 	return policies, nil
 }
 
-func (pri StubPolicyRepositoryImpl) FindByRoleUuid(roleUuid string) ([]*entity.Policy, error) {
+func (pri StubPolicyRepositoryImpl) FindByRoleUuid(ctx context.Context, roleUuid string) ([]*entity.Policy, error) {
 	var policies []*entity.Policy
 	return policies, nil
 }
 
-func (pri StubPolicyRepositoryImpl) FindByUuid(uuid string) (entity.Policy, error) {
+func (pri StubPolicyRepositoryImpl) FindByUuid(ctx context.Context, uuid string) (entity.Policy, error) {
 	var policy entity.Policy
 	return policy, nil
 }
 
-func (pri StubPolicyRepositoryImpl) FindPolicyOfUserGroupByUserUuidAndGroupUuid(userUuid string, groupUuid string) (model.UserPolicyOnGroupResponse, error) {
+func (pri StubPolicyRepositoryImpl) FindPolicyOfUserGroupByUserUuidAndGroupUuid(ctx context.Context, userUuid string, groupUuid string) (model.UserPolicyOnGroupResponse, error) {
 	var policy model.UserPolicyOnGroupResponse
 	return policy, nil
 }
 
-func (pri StubPolicyRepositoryImpl) FindPolicyOfUserServiceByUserUuidAndServiceUuid(userUuid string) ([]model.UserPolicyOnServiceResponse, error) {
-	var policy []model.UserPolicyOnServiceResponse
-	return policy, nil
+func (pri StubPolicyRepositoryImpl) FindPolicyOfUserGroupByUserUuids(ctx context.Context, userUuids []string) ([]model.UserPolicyOnUserGroup, error) {
+	var policies []model.UserPolicyOnUserGroup
+	return policies, nil
 }
 
-func (pri StubPolicyRepositoryImpl) Update(policy entity.Policy) (*entity.Policy, error) {
+func (pri StubPolicyRepositoryImpl) Update(ctx context.Context, policy entity.Policy) (*entity.Policy, error) {
 	return &policy, nil
 }
+
+// Less than stub struct
+// Policy repository whose policy was created by concurrent request
+type StubStalePolicyRepositoryImpl struct {
+	StubPolicyRepositoryImpl
+}
+
+func (pri StubStalePolicyRepositoryImpl) Update(ctx context.Context, policy entity.Policy) (*entity.Policy, error) {
+	return nil, driver.ErrStaleVersion
+}
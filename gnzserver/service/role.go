@@ -1,9 +1,9 @@
 package service
 
 import (
+	"context"
 	"crypto/md5"
 	"encoding/hex"
-	"strings"
 
 	"github.com/google/uuid"
 	"github.com/tomoyane/grant-n-z/gnz/cache"
@@ -17,26 +17,27 @@ var rsInstance RoleService
 
 type RoleService interface {
 	// Get all roles
-	GetRoles() ([]*entity.Role, *model.ErrorResBody)
+	GetRoles(ctx context.Context) ([]*entity.Role, *model.ErrorResBody)
 
 	// Get role by role uuid
-	GetRoleByUuid(uuid string) (*entity.Role, *model.ErrorResBody)
+	GetRoleByUuid(ctx context.Context, uuid string) (*entity.Role, *model.ErrorResBody)
 
 	// Get role by name
-	GetRoleByName(name string) (*entity.Role, *model.ErrorResBody)
+	GetRoleByName(ctx context.Context, name string) (*entity.Role, *model.ErrorResBody)
 
 	// Get role by name array
-	GetRoleByNames(name []string) ([]entity.Role, *model.ErrorResBody)
+	GetRoleByNames(ctx context.Context, name []string) ([]entity.Role, *model.ErrorResBody)
 
 	// Get role by group uuid
 	// Join group_roles and roles
-	GetRolesByGroupUuid(groupUuid string) ([]*entity.Role, *model.ErrorResBody)
+	// Next cursor is empty if it is the last page
+	GetRolesByGroupUuid(ctx context.Context, groupUuid string, page driver.Page) ([]*entity.Role, string, *model.ErrorResBody)
 
 	// Insert role
-	InsertRole(role *entity.Role) (*entity.Role, *model.ErrorResBody)
+	InsertRole(ctx context.Context, role *entity.Role) (*entity.Role, *model.ErrorResBody)
 
 	// Insert role with relational data
-	InsertWithRelationalData(groupUuid string, role entity.Role) (*entity.Role, *model.ErrorResBody)
+	InsertWithRelationalData(ctx context.Context, groupUuid string, role entity.Role) (*entity.Role, *model.ErrorResBody)
 }
 
 type RoleServiceImpl struct {
@@ -59,97 +60,79 @@ func NewRoleService() RoleService {
 	}
 }
 
-func (rs RoleServiceImpl) GetRoles() ([]*entity.Role, *model.ErrorResBody) {
-	roles, err := rs.RoleRepository.FindAll()
+func (rs RoleServiceImpl) GetRoles(ctx context.Context) ([]*entity.Role, *model.ErrorResBody) {
+	roles, err := rs.RoleRepository.FindAll(ctx)
 	if err != nil {
-		if strings.Contains(err.Error(), "record not found") {
+		if err == driver.ErrNotFound {
 			return []*entity.Role{}, nil
 		}
-		return nil, model.InternalServerError(err.Error())
+		return nil, repositoryError(err)
 	}
 
 	return roles, nil
 }
 
-func (rs RoleServiceImpl) GetRoleByUuid(uuid string) (*entity.Role, *model.ErrorResBody) {
-	role, err := rs.RoleRepository.FindByUuid(uuid)
+func (rs RoleServiceImpl) GetRoleByUuid(ctx context.Context, uuid string) (*entity.Role, *model.ErrorResBody) {
+	role, err := rs.RoleRepository.FindByUuid(ctx, uuid)
 	if err != nil {
-		if strings.Contains(err.Error(), "record not found") {
-			return nil, model.NotFound("Not found role")
-		}
-		return nil, model.InternalServerError(err.Error())
+		return nil, repositoryError(err, "Not found role")
 	}
 
 	return role, nil
 }
 
-func (rs RoleServiceImpl) GetRoleByName(name string) (*entity.Role, *model.ErrorResBody) {
-	role, err := rs.RoleRepository.FindByName(name)
+func (rs RoleServiceImpl) GetRoleByName(ctx context.Context, name string) (*entity.Role, *model.ErrorResBody) {
+	role, err := rs.RoleRepository.FindByName(ctx, name)
 	if err != nil {
-		if strings.Contains(err.Error(), "record not found") {
-			return nil, model.NotFound("Not found role")
-		}
-		return nil, model.InternalServerError(err.Error())
+		return nil, repositoryError(err, "Not found role")
 	}
 
 	return role, nil
 }
 
-func (rs RoleServiceImpl) GetRoleByNames(names []string) ([]entity.Role, *model.ErrorResBody) {
+func (rs RoleServiceImpl) GetRoleByNames(ctx context.Context, names []string) ([]entity.Role, *model.ErrorResBody) {
 
-	roles, err := rs.RoleRepository.FindByNames(names)
+	roles, err := rs.RoleRepository.FindByNames(ctx, names)
 	if err != nil {
-		if strings.Contains(err.Error(), "record not found") {
-			return nil, model.NotFound("Not found roles")
-		}
-		return nil, model.InternalServerError(err.Error())
+		return nil, repositoryError(err, "Not found roles")
 	}
 
 	return roles, nil
 }
 
-func (rs RoleServiceImpl) GetRolesByGroupUuid(groupUuid string) ([]*entity.Role, *model.ErrorResBody) {
-	roles, err := rs.RoleRepository.FindByGroupUuid(groupUuid)
+func (rs RoleServiceImpl) GetRolesByGroupUuid(ctx context.Context, groupUuid string, page driver.Page) ([]*entity.Role, string, *model.ErrorResBody) {
+	roles, next, err := rs.RoleRepository.FindByGroupUuid(ctx, groupUuid, page)
 	if err != nil {
-		if strings.Contains(err.Error(), "record not found") {
-			return nil, model.NotFound("Not found roles")
-		}
-		return nil, model.InternalServerError()
+		return nil, "", repositoryError(err, "Not found roles")
 	}
 
-	return roles, nil
+	return roles, next, nil
 }
 
-func (rs RoleServiceImpl) InsertRole(role *entity.Role) (*entity.Role, *model.ErrorResBody) {
+func (rs RoleServiceImpl) InsertRole(ctx context.Context, role *entity.Role) (*entity.Role, *model.ErrorResBody) {
 	roleId := uuid.New()
 	roleIdMd5 := md5.Sum(roleId.NodeID())
 	role.Uuid = roleId
 	role.InternalId = hex.EncodeToString(roleIdMd5[:])
 
-	savedRole, err := rs.RoleRepository.Save(*role)
+	savedRole, err := rs.RoleRepository.Save(ctx, *role)
 	if err != nil {
 		log.Logger.Warn(err.Error())
-		if strings.Contains(err.Error(), "1062") {
-			return nil, model.Conflict("Already exit data.")
-		}
-		return nil, model.InternalServerError(err.Error())
+		return nil, repositoryError(err, "Already exit data.")
 	}
 
 	return savedRole, nil
 }
 
-func (rs RoleServiceImpl) InsertWithRelationalData(groupUuid string, role entity.Role) (*entity.Role, *model.ErrorResBody) {
+func (rs RoleServiceImpl) InsertWithRelationalData(ctx context.Context, groupUuid string, role entity.Role) (*entity.Role, *model.ErrorResBody) {
 	roleId := uuid.New()
 	roleIdMd5 := md5.Sum(roleId.NodeID())
 	role.Uuid = roleId
 	role.InternalId = hex.EncodeToString(roleIdMd5[:])
 
-	savedRole, err := rs.RoleRepository.SaveWithRelationalData(groupUuid, role)
+	savedRole, err := rs.RoleRepository.SaveWithRelationalData(ctx, groupUuid, role)
 	if err != nil {
-		if strings.Contains(err.Error(), "1062") {
-			return nil, model.Conflict("Already exit roles data.")
-		}
-		return nil, model.InternalServerError()
+		return nil, repositoryError(err, "Already exit roles data.")
 	}
 
 	return savedRole, nil
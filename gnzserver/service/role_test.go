@@ -1,6 +1,7 @@
 package service
 
 import (
+	"context"
 	"testing"
 	"time"
 
@@ -9,6 +10,7 @@ import (
 	"github.com/google/uuid"
 	"github.com/jinzhu/gorm"
 	"github.com/tomoyane/grant-n-z/gnz/cache"
+	"github.com/tomoyane/grant-n-z/gnz/driver"
 	"github.com/tomoyane/grant-n-z/gnz/entity"
 	"github.com/tomoyane/grant-n-z/gnz/log"
 )
@@ -41,7 +43,7 @@ func TestGetRoleServiceInstance(t *testing.T) {
 
 // Test get roles
 func TestGetRoles_Success(t *testing.T) {
-	_, err := roleService.GetRoles()
+	_, err := roleService.GetRoles(context.Background())
 	if err != nil {
 		t.Errorf("Incorrect TestGetRoles_Success test")
 		t.FailNow()
@@ -50,7 +52,7 @@ func TestGetRoles_Success(t *testing.T) {
 
 // Test get role by uuid
 func TestGetRoleByUuid_Success(t *testing.T) {
-	_, err := roleService.GetRoleByUuid(uuid.New().String())
+	_, err := roleService.GetRoleByUuid(context.Background(), uuid.New().String())
 	if err != nil {
 		t.Errorf("Incorrect TestGetRoleById_Success test")
 		t.FailNow()
@@ -59,7 +61,7 @@ func TestGetRoleByUuid_Success(t *testing.T) {
 
 // Test get role by name
 func TestGetRoleByName_Success(t *testing.T) {
-	_, err := roleService.GetRoleByName("name")
+	_, err := roleService.GetRoleByName(context.Background(), "name")
 	if err != nil {
 		t.Errorf("Incorrect TestGetRoleByName_Success test")
 		t.FailNow()
@@ -68,7 +70,7 @@ func TestGetRoleByName_Success(t *testing.T) {
 
 // Test get role by names
 func TestGetRoleByNames_Success(t *testing.T) {
-	_, err := roleService.GetRoleByNames([]string{"name"})
+	_, err := roleService.GetRoleByNames(context.Background(), []string{"name"})
 	if err != nil {
 		t.Errorf("Incorrect TestGetRoleByNames_Success test")
 		t.FailNow()
@@ -77,7 +79,7 @@ func TestGetRoleByNames_Success(t *testing.T) {
 
 // Test get role by group uuid
 func TestGetRolesByGroupUuid_Success(t *testing.T) {
-	_, err := roleService.GetRolesByGroupUuid(uuid.New().String())
+	_, _, err := roleService.GetRolesByGroupUuid(context.Background(), uuid.New().String(), driver.Page{})
 	if err != nil {
 		t.Errorf("Incorrect TestGetRolesByGroupId_Success test")
 		t.FailNow()
@@ -86,7 +88,7 @@ func TestGetRolesByGroupUuid_Success(t *testing.T) {
 
 // Test insert
 func TestInsertRoleInsertRole_Success(t *testing.T) {
-	_, err := roleService.InsertRole(&entity.Role{})
+	_, err := roleService.InsertRole(context.Background(), &entity.Role{})
 	if err != nil {
 		t.Errorf("Incorrect v test")
 		t.FailNow()
@@ -95,7 +97,7 @@ func TestInsertRoleInsertRole_Success(t *testing.T) {
 
 // Test insert with relational data
 func TestRoleInsertWithRelationalData_Success(t *testing.T) {
-	_, err := roleService.InsertWithRelationalData(uuid.New().String(), entity.Role{})
+	_, err := roleService.InsertWithRelationalData(context.Background(), uuid.New().String(), entity.Role{})
 	if err != nil {
 		t.Errorf("Incorrect TestRoleInsertWithRelationalData_Success test")
 		t.FailNow()
@@ -108,49 +110,49 @@ type StubRoleRepositoryImpl struct {
 	Connection *gorm.DB
 }
 
-func (rri StubRoleRepositoryImpl) FindAll() ([]*entity.Role, error) {
+func (rri StubRoleRepositoryImpl) FindAll(ctx context.Context) ([]*entity.Role, error) {
 	var roles []*entity.Role
 	return roles, nil
 }
 
-func (rri StubRoleRepositoryImpl) FindOffSetAndLimit(offset int, limit int) ([]*entity.Role, error) {
+func (rri StubRoleRepositoryImpl) FindOffSetAndLimit(ctx context.Context, offset int, limit int) ([]*entity.Role, error) {
 	var roles []*entity.Role
 	return roles, nil
 }
 
-func (rri StubRoleRepositoryImpl) FindByUuid(uuid string) (*entity.Role, error) {
+func (rri StubRoleRepositoryImpl) FindByUuid(ctx context.Context, uuid string) (*entity.Role, error) {
 	var role entity.Role
 	return &role, nil
 }
 
-func (rri StubRoleRepositoryImpl) FindByName(name string) (*entity.Role, error) {
+func (rri StubRoleRepositoryImpl) FindByName(ctx context.Context, name string) (*entity.Role, error) {
 	var role entity.Role
 	return &role, nil
 }
 
-func (rri StubRoleRepositoryImpl) FindByNames(names []string) ([]entity.Role, error) {
+func (rri StubRoleRepositoryImpl) FindByNames(ctx context.Context, names []string) ([]entity.Role, error) {
 	var roles []entity.Role
 	roles = append(roles, entity.Role{Name: "test"})
 	return roles, nil
 }
 
-func (rri StubRoleRepositoryImpl) FindByGroupUuid(groupUuid string) ([]*entity.Role, error) {
+func (rri StubRoleRepositoryImpl) FindByGroupUuid(ctx context.Context, groupUuid string, page driver.Page) ([]*entity.Role, string, error) {
 	var roles []*entity.Role
-	return roles, nil
+	return roles, "", nil
 }
 
-func (rri StubRoleRepositoryImpl) FindNameByUuid(uuid string) *string {
-	role, err := rri.FindByUuid(uuid)
+func (rri StubRoleRepositoryImpl) FindNameByUuid(ctx context.Context, uuid string) *string {
+	role, err := rri.FindByUuid(ctx, uuid)
 	if err != nil {
 		return nil
 	}
 	return &role.Name
 }
 
-func (rri StubRoleRepositoryImpl) Save(role entity.Role) (*entity.Role, error) {
+func (rri StubRoleRepositoryImpl) Save(ctx context.Context, role entity.Role) (*entity.Role, error) {
 	return &role, nil
 }
 
-func (rri StubRoleRepositoryImpl) SaveWithRelationalData(groupUuid string, role entity.Role) (*entity.Role, error) {
+func (rri StubRoleRepositoryImpl) SaveWithRelationalData(ctx context.Context, groupUuid string, role entity.Role) (*entity.Role, error) {
 	return &role, nil
 }
@@ -1,6 +1,7 @@
 package service
 
 import (
+	"context"
 	"crypto/md5"
 	"encoding/hex"
 	"strings"
@@ -26,25 +27,27 @@ type ServiceImpl struct {
 
 type Service interface {
 	// Get service
-	GetServices() ([]*entity.Service, *model.ErrorResBody)
+	// Next cursor is empty if it is the last page
+	GetServices(ctx context.Context, page driver.Page) ([]*entity.Service, string, *model.ErrorResBody)
 
 	// Get service by service uuid
-	GetServiceByUuid(uuid string) (*entity.Service, *model.ErrorResBody)
+	GetServiceByUuid(ctx context.Context, uuid string) (*entity.Service, *model.ErrorResBody)
 
 	// Get service by service name
-	GetServiceByName(name string) (*entity.Service, *model.ErrorResBody)
+	GetServiceByName(ctx context.Context, name string) (*entity.Service, *model.ErrorResBody)
 
 	// Get service by service secret
-	GetServiceBySecret(secret string) (*entity.Service, *model.ErrorResBody)
+	GetServiceBySecret(ctx context.Context, secret string) (*entity.Service, *model.ErrorResBody)
 
 	// Get service of user
-	GetServiceByUser(userUuid string) ([]*entity.Service, *model.ErrorResBody)
+	// Next cursor is empty if it is the last page
+	GetServiceByUser(ctx context.Context, userUuid string, page driver.Page) ([]*entity.Service, string, *model.ErrorResBody)
 
 	// Insert service
-	InsertService(service entity.Service) (*entity.Service, *model.ErrorResBody)
+	InsertService(ctx context.Context, service entity.Service) (*entity.Service, *model.ErrorResBody)
 
 	// Insert service
-	InsertServiceWithRelationalData(service *entity.Service) (*entity.Service, *model.ErrorResBody)
+	InsertServiceWithRelationalData(ctx context.Context, service *entity.Service) (*entity.Service, *model.ErrorResBody)
 
 	// Generate secret
 	GenerateSecret() string
@@ -70,114 +73,93 @@ func NewServiceService() Service {
 	}
 }
 
-func (ss ServiceImpl) GetServices() ([]*entity.Service, *model.ErrorResBody) {
-	services, err := ss.ServiceRepository.FindAll()
+func (ss ServiceImpl) GetServices(ctx context.Context, page driver.Page) ([]*entity.Service, string, *model.ErrorResBody) {
+	services, next, err := ss.ServiceRepository.FindAll(ctx, page)
 	if err != nil {
-		if strings.Contains(err.Error(), "record not found") {
-			return []*entity.Service{}, nil
+		if err == driver.ErrNotFound {
+			return []*entity.Service{}, "", nil
 		}
-		return nil, model.InternalServerError(err.Error())
+		return nil, "", repositoryError(err)
 	}
 
-	return services, nil
+	return services, next, nil
 }
 
-func (ss ServiceImpl) GetServiceByUuid(uuid string) (*entity.Service, *model.ErrorResBody) {
-	service, err := ss.ServiceRepository.FindByUuid(uuid)
+func (ss ServiceImpl) GetServiceByUuid(ctx context.Context, uuid string) (*entity.Service, *model.ErrorResBody) {
+	service, err := ss.ServiceRepository.FindByUuid(ctx, uuid)
 	if err != nil {
-		if strings.Contains(err.Error(), "record not found") {
-			return nil, model.NotFound("Not found service")
-		}
-		return nil, model.InternalServerError(err.Error())
+		return nil, repositoryError(err, "Not found service")
 	}
 
 	return service, nil
 }
 
-func (ss ServiceImpl) GetServiceByName(name string) (*entity.Service, *model.ErrorResBody) {
-	service, err := ss.ServiceRepository.FindByName(name)
+func (ss ServiceImpl) GetServiceByName(ctx context.Context, name string) (*entity.Service, *model.ErrorResBody) {
+	service, err := ss.ServiceRepository.FindByName(ctx, name)
 	if err != nil {
-		if strings.Contains(err.Error(), "record not found") {
-			return nil, model.NotFound("Not found service")
-		}
-		return nil, model.InternalServerError(err.Error())
+		return nil, repositoryError(err, "Not found service")
 	}
 
 	return service, nil
 }
 
-func (ss ServiceImpl) GetServiceBySecret(secret string) (*entity.Service, *model.ErrorResBody) {
-	service, err := ss.ServiceRepository.FindBySecret(secret)
+func (ss ServiceImpl) GetServiceBySecret(ctx context.Context, secret string) (*entity.Service, *model.ErrorResBody) {
+	service, err := ss.ServiceRepository.FindBySecret(ctx, secret)
 	if err != nil {
-		if strings.Contains(err.Error(), "record not found") {
+		if err == driver.ErrNotFound {
 			return nil, model.BadRequest("Invalid secret")
 		}
-		return nil, model.InternalServerError(err.Error())
+		return nil, repositoryError(err)
 	}
 
 	return service, nil
 }
 
-func (ss ServiceImpl) GetServiceByUser(userUuid string) ([]*entity.Service, *model.ErrorResBody) {
-	services, err := ss.ServiceRepository.FindServicesByUserUuid(userUuid)
+func (ss ServiceImpl) GetServiceByUser(ctx context.Context, userUuid string, page driver.Page) ([]*entity.Service, string, *model.ErrorResBody) {
+	services, next, err := ss.ServiceRepository.FindServicesByUserUuid(ctx, userUuid, page)
 	if err != nil {
-		if strings.Contains(err.Error(), "record not found") {
-			return nil, model.NotFound("Not found services")
-		}
-		return nil, model.InternalServerError()
+		return nil, "", repositoryError(err, "Not found services")
 	}
 
-	return services, nil
+	return services, next, nil
 }
 
-func (ss ServiceImpl) InsertService(service entity.Service) (*entity.Service, *model.ErrorResBody) {
+func (ss ServiceImpl) InsertService(ctx context.Context, service entity.Service) (*entity.Service, *model.ErrorResBody) {
 	serviceId := uuid.New()
 	serviceIdMd5 := md5.Sum(serviceId.NodeID())
 	service.Uuid = serviceId
 	service.InternalId = hex.EncodeToString(serviceIdMd5[:])
 	service.Secret = ss.GenerateSecret()
 
-	savedService, err := ss.ServiceRepository.Save(service)
+	savedService, err := ss.ServiceRepository.Save(ctx, service)
 	if err != nil {
 		log.Logger.Warn(err.Error())
-		if strings.Contains(err.Error(), "1062") {
-			return nil, model.Conflict("Already exit data.")
-		}
-		return nil, model.InternalServerError(err.Error())
+		return nil, repositoryError(err, "Already exit data.")
 	}
 
 	return savedService, nil
 }
 
-func (ss ServiceImpl) InsertServiceWithRelationalData(service *entity.Service) (*entity.Service, *model.ErrorResBody) {
+func (ss ServiceImpl) InsertServiceWithRelationalData(ctx context.Context, service *entity.Service) (*entity.Service, *model.ErrorResBody) {
 	serviceId := uuid.New()
 	serviceIdMd5 := md5.Sum(serviceId.NodeID())
 	service.Uuid = serviceId
 	service.InternalId = hex.EncodeToString(serviceIdMd5[:])
 	service.Secret = ss.GenerateSecret()
 
-	roles, err := ss.RoleRepository.FindByNames([]string{common.AdminRole, common.UserRole})
+	roles, err := ss.RoleRepository.FindByNames(ctx, []string{common.AdminRole, common.UserRole})
 	if err != nil {
-		if strings.Contains(err.Error(), "record not found") {
-			return nil, model.NotFound("Not found role")
-		}
-		return nil, model.InternalServerError()
+		return nil, repositoryError(err, "Not found role")
 	}
 
-	permissions, err := ss.PermissionRepository.FindByNames([]string{common.AdminPermission, common.ReadPermission, common.WritePermission})
+	permissions, err := ss.PermissionRepository.FindByNames(ctx, []string{common.AdminPermission, common.ReadPermission, common.WritePermission})
 	if err != nil {
-		if !strings.Contains(err.Error(), "record not found") {
-			return nil, model.NotFound("Not found permission")
-		}
-		return nil, model.InternalServerError(err.Error())
+		return nil, repositoryError(err, "Not found permission")
 	}
 
-	saveWithRelationalData, err := ss.ServiceRepository.SaveWithRelationalData(*service, roles, permissions)
+	saveWithRelationalData, err := ss.ServiceRepository.SaveWithRelationalData(ctx, *service, roles, permissions)
 	if err != nil {
-		if strings.Contains(err.Error(), "1062") {
-			return nil, model.Conflict("Already exit services data.")
-		}
-		return nil, model.InternalServerError()
+		return nil, repositoryError(err, "Already exit services data.")
 	}
 
 	return saveWithRelationalData, nil
@@ -1,6 +1,7 @@
 package service
 
 import (
+	"context"
 	"strings"
 	"testing"
 	"time"
@@ -10,6 +11,7 @@ import (
 	"github.com/google/uuid"
 	"github.com/jinzhu/gorm"
 	"github.com/tomoyane/grant-n-z/gnz/cache"
+	"github.com/tomoyane/grant-n-z/gnz/driver"
 	"github.com/tomoyane/grant-n-z/gnz/entity"
 	"github.com/tomoyane/grant-n-z/gnz/log"
 )
@@ -44,7 +46,7 @@ func TestGetServiceInstance(t *testing.T) {
 
 // Test get services
 func TestGetServices_Success(t *testing.T) {
-	_, err := service.GetServices()
+	_, _, err := service.GetServices(context.Background(), driver.Page{})
 	if err != nil {
 		t.Errorf("Incorrect TestGetServices_Success test")
 		t.FailNow()
@@ -53,7 +55,7 @@ func TestGetServices_Success(t *testing.T) {
 
 // Test get service by id
 func TestGetServiceById_Success(t *testing.T) {
-	_, err := service.GetServiceByUuid(uuid.New().String())
+	_, err := service.GetServiceByUuid(context.Background(), uuid.New().String())
 	if err != nil {
 		t.Errorf("Incorrect TestGetServiceById_Success test")
 		t.FailNow()
@@ -62,7 +64,7 @@ func TestGetServiceById_Success(t *testing.T) {
 
 // Test get service by name
 func TestGetServiceByName_Success(t *testing.T) {
-	_, err := service.GetServiceByName("name")
+	_, err := service.GetServiceByName(context.Background(), "name")
 	if err != nil {
 		t.Errorf("Incorrect TestGetServiceByName_Success test")
 		t.FailNow()
@@ -71,7 +73,7 @@ func TestGetServiceByName_Success(t *testing.T) {
 
 // Test get service of user
 func TestGetServiceOfApiKey_Success(t *testing.T) {
-	_, err := service.GetServiceBySecret("secret")
+	_, err := service.GetServiceBySecret(context.Background(), "secret")
 	if err != nil {
 		t.Errorf("Incorrect TestGetServiceOfApiKey_Success test")
 		t.FailNow()
@@ -80,7 +82,7 @@ func TestGetServiceOfApiKey_Success(t *testing.T) {
 
 // Test get service of user
 func TestGetServiceOfUser_Success(t *testing.T) {
-	_, err := service.GetServiceByUser(uuid.New().String())
+	_, _, err := service.GetServiceByUser(context.Background(), uuid.New().String(), driver.Page{})
 	if err != nil {
 		t.Errorf("Incorrect TestGetServiceOfUser_Success test")
 		t.FailNow()
@@ -89,7 +91,7 @@ func TestGetServiceOfUser_Success(t *testing.T) {
 
 // Test insert service
 func TestInsertService_Success(t *testing.T) {
-	_, err := service.InsertService(entity.Service{Name: "name"})
+	_, err := service.InsertService(context.Background(), entity.Service{Name: "name"})
 	if err != nil {
 		t.Errorf("Incorrect TestInsertService_Success test")
 		t.FailNow()
@@ -98,7 +100,7 @@ func TestInsertService_Success(t *testing.T) {
 
 // Test insert service with relational data
 func TestInsertServiceWithRelationalData_Success(t *testing.T) {
-	_, err := service.InsertServiceWithRelationalData(&entity.Service{Name: "name"})
+	_, err := service.InsertServiceWithRelationalData(context.Background(), &entity.Service{Name: "name"})
 	if err != nil {
 		t.Errorf("Incorrect TestInsertServiceWithRelationalData_Success test")
 		t.FailNow()
@@ -120,52 +122,52 @@ type StubServiceRepositoryImpl struct {
 	Connection *gorm.DB
 }
 
-func (sri StubServiceRepositoryImpl) FindAll() ([]*entity.Service, error) {
+func (sri StubServiceRepositoryImpl) FindAll(ctx context.Context, page driver.Page) ([]*entity.Service, string, error) {
 	var services []*entity.Service
-	return services, nil
+	return services, "", nil
 }
 
-func (sri StubServiceRepositoryImpl) FindOffSetAndLimit(offset int, limit int) ([]*entity.Service, error) {
+func (sri StubServiceRepositoryImpl) FindOffSetAndLimit(ctx context.Context, offset int, limit int) ([]*entity.Service, error) {
 	var services []*entity.Service
 	return services, nil
 }
 
-func (sri StubServiceRepositoryImpl) FindByUuid(uuid string) (*entity.Service, error) {
+func (sri StubServiceRepositoryImpl) FindByUuid(ctx context.Context, uuid string) (*entity.Service, error) {
 	var service entity.Service
 	return &service, nil
 }
 
-func (sri StubServiceRepositoryImpl) FindByName(name string) (*entity.Service, error) {
+func (sri StubServiceRepositoryImpl) FindByName(ctx context.Context, name string) (*entity.Service, error) {
 	var service entity.Service
 	return &service, nil
 }
 
-func (sri StubServiceRepositoryImpl) FindBySecret(secret string) (*entity.Service, error) {
+func (sri StubServiceRepositoryImpl) FindBySecret(ctx context.Context, secret string) (*entity.Service, error) {
 	service := entity.Service{Name: "test"}
 	return &service, nil
 }
 
-func (sri StubServiceRepositoryImpl) FindNameByUuid(uuid string) *string {
-	service, err := sri.FindByUuid(uuid)
+func (sri StubServiceRepositoryImpl) FindNameByUuid(ctx context.Context, uuid string) *string {
+	service, err := sri.FindByUuid(ctx, uuid)
 	if err != nil {
 		return nil
 	}
 	return &service.Name
 }
 
-func (sri StubServiceRepositoryImpl) FindServicesByUserUuid(userUuid string) ([]*entity.Service, error) {
+func (sri StubServiceRepositoryImpl) FindServicesByUserUuid(ctx context.Context, userUuid string, page driver.Page) ([]*entity.Service, string, error) {
 	var services []*entity.Service
-	return services, nil
+	return services, "", nil
 }
 
-func (sri StubServiceRepositoryImpl) Save(service entity.Service) (*entity.Service, error) {
+func (sri StubServiceRepositoryImpl) Save(ctx context.Context, service entity.Service) (*entity.Service, error) {
 	return &service, nil
 }
 
-func (sri StubServiceRepositoryImpl) SaveWithRelationalData(service entity.Service, roles []entity.Role, permissions []entity.Permission) (*entity.Service, error) {
+func (sri StubServiceRepositoryImpl) SaveWithRelationalData(ctx context.Context, service entity.Service, roles []entity.Role, permissions []entity.Permission) (*entity.Service, error) {
 	return &service, nil
 }
 
-func (sri StubServiceRepositoryImpl) Update(service entity.Service) (*entity.Service, error) {
+func (sri StubServiceRepositoryImpl) Update(ctx context.Context, service entity.Service) (*entity.Service, error) {
 	return &service, nil
 }
@@ -0,0 +1,11 @@
+package service
+
+import (
+	"golang.org/x/sync/singleflight"
+)
+
+// Loader of cache miss
+// Concurrent misses of the same user are collapsed into one database query
+// Loader runs with background ctx, because cancel of the first caller must not fail the other callers.
+// It is still bounded by the repository timeout
+var cacheLoader singleflight.Group
@@ -0,0 +1,68 @@
+package service
+
+import (
+	"context"
+
+	"github.com/tomoyane/grant-n-z/gnz/driver"
+	"github.com/tomoyane/grant-n-z/gnz/entity"
+	"github.com/tomoyane/grant-n-z/gnz/log"
+	"github.com/tomoyane/grant-n-z/gnzserver/model"
+)
+
+var sdsInstance SoftDeleteService
+
+// Entities that operator can soft delete and restore. Their names are the same as tables
+var softDeleteEntities = map[string]bool{
+	entity.UserTable.String():       true,
+	entity.GroupTable.String():      true,
+	entity.ServiceTable.String():    true,
+	entity.RoleTable.String():       true,
+	entity.PermissionTable.String(): true,
+}
+
+type SoftDeleteService interface {
+	// Soft delete entity of uuid, and its memberships and policies
+	// Cache of them is updated by gnzcacher
+	Delete(ctx context.Context, entityName string, uuid string) *model.ErrorResBody
+
+	// Restore soft deleted entity of uuid, and memberships and policies that were deleted with it
+	Restore(ctx context.Context, entityName string, uuid string) *model.ErrorResBody
+}
+
+type SoftDeleteServiceImpl struct {
+	SoftDeleteRepository driver.SoftDeleteRepository
+}
+
+func GetSoftDeleteServiceInstance() SoftDeleteService {
+	if sdsInstance == nil {
+		sdsInstance = NewSoftDeleteService()
+	}
+	return sdsInstance
+}
+
+func NewSoftDeleteService() SoftDeleteService {
+	log.Logger.Info("New `SoftDeleteService` instance")
+	return SoftDeleteServiceImpl{SoftDeleteRepository: driver.GetSoftDeleteRepositoryInstance()}
+}
+
+func (sds SoftDeleteServiceImpl) Delete(ctx context.Context, entityName string, uuid string) *model.ErrorResBody {
+	if !softDeleteEntities[entityName] {
+		return model.BadRequest("Not support entity.")
+	}
+
+	if err := sds.SoftDeleteRepository.SoftDelete(ctx, entityName, uuid); err != nil {
+		return repositoryError(err)
+	}
+	return nil
+}
+
+func (sds SoftDeleteServiceImpl) Restore(ctx context.Context, entityName string, uuid string) *model.ErrorResBody {
+	if !softDeleteEntities[entityName] {
+		return model.BadRequest("Not support entity.")
+	}
+
+	if err := sds.SoftDeleteRepository.Restore(ctx, entityName, uuid); err != nil {
+		return repositoryError(err, "Not found soft deleted data.")
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/tomoyane/grant-n-z/gnz/driver"
+	"github.com/tomoyane/grant-n-z/gnz/log"
+)
+
+// Set up
+func init() {
+	log.InitLogger("info")
+}
+
+// Test instance
+func TestGetSoftDeleteServiceInstance(t *testing.T) {
+	GetSoftDeleteServiceInstance()
+}
+
+// Test delete success
+func TestSoftDelete_Delete_Success(t *testing.T) {
+	softDeleteService := SoftDeleteServiceImpl{SoftDeleteRepository: StubSoftDeleteRepositoryImpl{}}
+	if err := softDeleteService.Delete(context.Background(), "users", "uuid"); err != nil {
+		t.Errorf("Incorrect TestSoftDelete_Delete_Success test")
+		t.FailNow()
+	}
+}
+
+// Test delete of not supported entity and not found
+func TestSoftDelete_Delete_Error(t *testing.T) {
+	softDeleteService := SoftDeleteServiceImpl{SoftDeleteRepository: StubSoftDeleteRepositoryImpl{err: driver.ErrNotFound}}
+	if err := softDeleteService.Delete(context.Background(), "policies", "uuid"); err == nil || err.Code != http.StatusBadRequest {
+		t.Errorf("Incorrect TestSoftDelete_Delete_Error test")
+		t.FailNow()
+	}
+	if err := softDeleteService.Delete(context.Background(), "groups", "uuid"); err == nil || err.Code != http.StatusNotFound {
+		t.Errorf("Incorrect TestSoftDelete_Delete_Error test")
+		t.FailNow()
+	}
+}
+
+// Test restore success
+func TestSoftDelete_Restore_Success(t *testing.T) {
+	softDeleteService := SoftDeleteServiceImpl{SoftDeleteRepository: StubSoftDeleteRepositoryImpl{}}
+	if err := softDeleteService.Restore(context.Background(), "roles", "uuid"); err != nil {
+		t.Errorf("Incorrect TestSoftDelete_Restore_Success test")
+		t.FailNow()
+	}
+}
+
+// Test restore of not soft deleted entity
+func TestSoftDelete_Restore_Error(t *testing.T) {
+	softDeleteService := SoftDeleteServiceImpl{SoftDeleteRepository: StubSoftDeleteRepositoryImpl{err: driver.ErrNotFound}}
+	if err := softDeleteService.Restore(context.Background(), "permissions", "uuid"); err == nil || err.Code != http.StatusNotFound {
+		t.Errorf("Incorrect TestSoftDelete_Restore_Error test")
+		t.FailNow()
+	}
+}
+
+// Less than stub struct
+// SoftDelete repository
+type StubSoftDeleteRepositoryImpl struct {
+	err error
+}
+
+func (sdr StubSoftDeleteRepositoryImpl) SoftDelete(ctx context.Context, table string, uuid string) error {
+	return sdr.err
+}
+
+func (sdr StubSoftDeleteRepositoryImpl) Restore(ctx context.Context, table string, uuid string) error {
+	return sdr.err
+}
+
+func (sdr StubSoftDeleteRepositoryImpl) Purge(ctx context.Context, before time.Time) (int64, error) {
+	return 0, sdr.err
+}
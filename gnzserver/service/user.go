@@ -1,7 +1,8 @@
 package service
 
 import (
-	"strings"
+	"context"
+	"fmt"
 
 	"crypto/md5"
 	"encoding/hex"
@@ -30,58 +31,63 @@ type UserService interface {
 	ComparePw(passwordHash string, password string) bool
 
 	// Get User by user uuid
-	GetUserByUuid(uuid string) (*entity.User, *model.ErrorResBody)
+	GetUserByUuid(ctx context.Context, uuid string) (*entity.User, *model.ErrorResBody)
 
 	// Get User by user email
-	GetUserByEmail(email string) (*entity.User, *model.ErrorResBody)
+	GetUserByEmail(ctx context.Context, email string) (*entity.User, *model.ErrorResBody)
 
 	// Get User and operator policy by user email
-	GetUserWithOperatorPolicyByEmail(email string) (*model.UserWithOperatorPolicy, *model.ErrorResBody)
+	GetUserWithOperatorPolicyByEmail(ctx context.Context, email string) (*model.UserWithOperatorPolicy, *model.ErrorResBody)
 
 	// Get User and user service and service by user email
-	GetUserWithUserServiceWithServiceByEmail(email string) (*model.UserWithUserServiceWithService, *model.ErrorResBody)
+	GetUserWithUserServiceWithServiceByEmail(ctx context.Context, email string) (*model.UserWithUserServiceWithService, *model.ErrorResBody)
 
 	// Get UserGroup by user uuid and group uuid
-	GetUserGroupByUserUuidAndGroupUuid(userUuid string, groupUuid string) (*entity.UserGroup, *model.ErrorResBody)
+	GetUserGroupByUserUuidAndGroupUuid(ctx context.Context, userUuid string, groupUuid string) (*entity.UserGroup, *model.ErrorResBody)
 
 	// Get Users by group uuid
-	GetUserByGroupUuid(groupUuid string) ([]*model.UserResponse, *model.ErrorResBody)
+	// Next cursor is empty if it is the last page
+	GetUserByGroupUuid(ctx context.Context, groupUuid string, page driver.Page) ([]*model.UserResponse, string, *model.ErrorResBody)
 
 	// Get all UserService
-	GetUserServices() ([]*entity.UserService, *model.ErrorResBody)
+	GetUserServices(ctx context.Context) ([]*entity.UserService, *model.ErrorResBody)
 
 	// Get UserService by user uuid
-	GetUserServicesByUserUuid(userUuid string) ([]*entity.UserService, *model.ErrorResBody)
+	GetUserServicesByUserUuid(ctx context.Context, userUuid string) ([]*entity.UserService, *model.ErrorResBody)
 
 	// Get UserService by user uuid and service uuid
-	GetUserServiceByUserUuidAndServiceUuid(userUuid string, serviceUuid string) (*entity.UserService, *model.ErrorResBody)
+	GetUserServiceByUserUuidAndServiceUuid(ctx context.Context, userUuid string, serviceUuid string) (*entity.UserService, *model.ErrorResBody)
 
 	// Get UserPolicy in etcd by user uuid
-	GetUserPoliciesByUserUuid(userUuid string) []structure.UserPolicy
+	// If etcd does not have it, load it from rdbms and write it back to etcd
+	GetUserPoliciesByUserUuid(ctx context.Context, userUuid string) []structure.UserPolicy
 
-	// Get UserPolicy in etcd by user uuid
-	GetUserGroupsByUserUuid(userUuid string) []structure.UserGroup
+	// Get UserGroup in etcd by user uuid
+	// If etcd does not have it, load it from rdbms and write it back to etcd
+	GetUserGroupsByUserUuid(ctx context.Context, userUuid string) []structure.UserGroup
 
 	// Insert UserGroup
-	InsertUserGroup(userGroup entity.UserGroup) (*entity.UserGroup, *model.ErrorResBody)
+	InsertUserGroup(ctx context.Context, userGroup entity.UserGroup) (*entity.UserGroup, *model.ErrorResBody)
 
 	// Insert User
-	InsertUser(user entity.User) (*entity.User, *model.ErrorResBody)
+	InsertUser(ctx context.Context, user entity.User) (*entity.User, *model.ErrorResBody)
 
 	// Insert User and UserService
-	InsertUserWithUserService(user entity.User, userService entity.UserService) (*entity.User, *model.ErrorResBody)
+	InsertUserWithUserService(ctx context.Context, user entity.User, userService entity.UserService) (*entity.User, *model.ErrorResBody)
 
 	// Insert UserService
-	InsertUserService(userServiceEntity entity.UserService) (*entity.UserService, *model.ErrorResBody)
+	InsertUserService(ctx context.Context, userServiceEntity entity.UserService) (*entity.UserService, *model.ErrorResBody)
 
-	// Update User
-	UpdateUser(user entity.User) (*entity.User, *model.ErrorResBody)
+	// Update User whose version is the version of user
+	// If other request updated it after the version, 412
+	UpdateUser(ctx context.Context, user entity.User) (*entity.User, *model.ErrorResBody)
 }
 
 // UserService struct
 type UserServiceImpl struct {
-	UserRepository driver.UserRepository
-	EtcdClient     cache.EtcdClient
+	UserRepository   driver.UserRepository
+	PolicyRepository driver.PolicyRepository
+	EtcdClient       cache.EtcdClient
 }
 
 // Get Policy instance.
@@ -97,8 +103,9 @@ func GetUserServiceInstance() UserService {
 func NewUserService() UserService {
 	log.Logger.Info("New `UserService` instance")
 	return UserServiceImpl{
-		UserRepository: driver.GetUserRepositoryInstance(),
-		EtcdClient:     cache.GetEtcdClientInstance(),
+		UserRepository:   driver.GetUserRepositoryInstance(),
+		PolicyRepository: driver.GetPolicyRepositoryInstance(),
+		EtcdClient:       cache.GetEtcdClientInstance(),
 	}
 }
 
@@ -121,32 +128,26 @@ func (us UserServiceImpl) ComparePw(passwordHash string, password string) bool {
 	return true
 }
 
-func (us UserServiceImpl) GetUserByUuid(uuid string) (*entity.User, *model.ErrorResBody) {
-	user, err := us.UserRepository.FindByUuid(uuid)
+func (us UserServiceImpl) GetUserByUuid(ctx context.Context, uuid string) (*entity.User, *model.ErrorResBody) {
+	user, err := us.UserRepository.FindByUuid(ctx, uuid)
 	if err != nil {
-		if strings.Contains(err.Error(), "record not found") {
-			return nil, model.NotFound("Not found user")
-		}
-		return nil, model.InternalServerError(err.Error())
+		return nil, repositoryError(err, "Not found user")
 	}
 
 	return user, nil
 }
 
-func (us UserServiceImpl) GetUserByEmail(email string) (*entity.User, *model.ErrorResBody) {
-	user, err := us.UserRepository.FindByEmail(email)
+func (us UserServiceImpl) GetUserByEmail(ctx context.Context, email string) (*entity.User, *model.ErrorResBody) {
+	user, err := us.UserRepository.FindByEmail(ctx, email)
 	if err != nil {
-		if strings.Contains(err.Error(), "record not found") {
-			return nil, model.NotFound("Not found user")
-		}
-		return nil, model.InternalServerError()
+		return nil, repositoryError(err, "Not found user")
 	}
 
 	return user, nil
 }
 
-func (us UserServiceImpl) GetUserWithOperatorPolicyByEmail(email string) (*model.UserWithOperatorPolicy, *model.ErrorResBody) {
-	userWithOperatorPolicy, err := us.UserRepository.FindWithOperatorPolicyByEmail(email)
+func (us UserServiceImpl) GetUserWithOperatorPolicyByEmail(ctx context.Context, email string) (*model.UserWithOperatorPolicy, *model.ErrorResBody) {
+	userWithOperatorPolicy, err := us.UserRepository.FindWithOperatorPolicyByEmail(ctx, email)
 	if err != nil {
 		return nil, model.InternalServerError(err.Error())
 	}
@@ -154,70 +155,55 @@ func (us UserServiceImpl) GetUserWithOperatorPolicyByEmail(email string) (*model
 	return userWithOperatorPolicy, nil
 }
 
-func (us UserServiceImpl) GetUserWithUserServiceWithServiceByEmail(email string) (*model.UserWithUserServiceWithService, *model.ErrorResBody) {
-	userWithService, err := us.UserRepository.FindWithUserServiceWithServiceByEmail(email)
+func (us UserServiceImpl) GetUserWithUserServiceWithServiceByEmail(ctx context.Context, email string) (*model.UserWithUserServiceWithService, *model.ErrorResBody) {
+	userWithService, err := us.UserRepository.FindWithUserServiceWithServiceByEmail(ctx, email)
 	if err != nil {
-		if strings.Contains(err.Error(), "record not found") {
-			return nil, model.NotFound("Not found service of user")
-		}
-		return nil, model.InternalServerError()
+		return nil, repositoryError(err, "Not found service of user")
 	}
 
 	return userWithService, nil
 }
 
-func (us UserServiceImpl) GetUserGroupByUserUuidAndGroupUuid(userUuid string, groupUuid string) (*entity.UserGroup, *model.ErrorResBody) {
-	userGroup, err := us.UserRepository.FindUserGroupByUserUuidAndGroupUuid(userUuid, groupUuid)
+func (us UserServiceImpl) GetUserGroupByUserUuidAndGroupUuid(ctx context.Context, userUuid string, groupUuid string) (*entity.UserGroup, *model.ErrorResBody) {
+	userGroup, err := us.UserRepository.FindUserGroupByUserUuidAndGroupUuid(ctx, userUuid, groupUuid)
 	if err != nil {
-		if strings.Contains(err.Error(), "record not found") {
-			return nil, model.NotFound("Not found group of user")
-		}
-		return nil, model.InternalServerError(err.Error())
+		return nil, repositoryError(err, "Not found group of user")
 	}
 
 	return userGroup, nil
 }
 
-func (us UserServiceImpl) GetUserServices() ([]*entity.UserService, *model.ErrorResBody) {
-	userServices, err := us.UserRepository.FindUserServices()
+func (us UserServiceImpl) GetUserServices(ctx context.Context) ([]*entity.UserService, *model.ErrorResBody) {
+	userServices, err := us.UserRepository.FindUserServices(ctx)
 	if err != nil {
-		if strings.Contains(err.Error(), "record not found") {
-			return nil, model.NotFound("Not found services")
-		}
-		return nil, model.InternalServerError(err.Error())
+		return nil, repositoryError(err, "Not found services")
 	}
 
 	return userServices, nil
 }
 
-func (us UserServiceImpl) GetUserServicesByUserUuid(userUuid string) ([]*entity.UserService, *model.ErrorResBody) {
-	userServices, err := us.UserRepository.FindUserServicesByUserUuid(userUuid)
+func (us UserServiceImpl) GetUserServicesByUserUuid(ctx context.Context, userUuid string) ([]*entity.UserService, *model.ErrorResBody) {
+	userServices, err := us.UserRepository.FindUserServicesByUserUuid(ctx, userUuid)
 	if err != nil {
-		if strings.Contains(err.Error(), "record not found") {
-			return nil, model.NotFound("Not found services of user")
-		}
-		return nil, model.InternalServerError(err.Error())
+		return nil, repositoryError(err, "Not found services of user")
 	}
 
 	return userServices, nil
 }
 
-func (us UserServiceImpl) GetUserServiceByUserUuidAndServiceUuid(userUuid string, serviceUuid string) (*entity.UserService, *model.ErrorResBody) {
-	userServices, err := us.UserRepository.FindUserServiceByUserUuidAndServiceUuid(userUuid, serviceUuid)
+func (us UserServiceImpl) GetUserServiceByUserUuidAndServiceUuid(ctx context.Context, userUuid string, serviceUuid string) (*entity.UserService, *model.ErrorResBody) {
+	userServices, err := us.UserRepository.FindUserServiceByUserUuidAndServiceUuid(ctx, userUuid, serviceUuid)
 	if err != nil {
-		if strings.Contains(err.Error(), "record not found") {
-			return nil, model.NotFound("Not found service of user")
-		}
-		return nil, model.InternalServerError(err.Error())
+		return nil, repositoryError(err, "Not found service of user")
 	}
 
 	return userServices, nil
 }
 
-func (us UserServiceImpl) GetUserByGroupUuid(groupUuid string) ([]*model.UserResponse, *model.ErrorResBody) {
-	users, err := us.UserRepository.FindByGroupUuid(groupUuid)
+func (us UserServiceImpl) GetUserByGroupUuid(ctx context.Context, groupUuid string, page driver.Page) ([]*model.UserResponse, string, *model.ErrorResBody) {
+	users, next, err := us.UserRepository.FindByGroupUuid(ctx, groupUuid, page)
 	if err != nil {
-		return nil, model.InternalServerError(err.Error())
+		return nil, "", repositoryError(err)
 	}
 
 	var userResponse []*model.UserResponse
@@ -225,57 +211,104 @@ func (us UserServiceImpl) GetUserByGroupUuid(groupUuid string) ([]*model.UserRes
 		userResponse = append(userResponse, &model.UserResponse{Uuid: user.Uuid.String(), Username: user.Username, Email: user.Email})
 	}
 
-	return userResponse, nil
+	return userResponse, next, nil
 }
 
-func (us UserServiceImpl) GetUserPoliciesByUserUuid(userUuid string) []structure.UserPolicy {
-	return us.EtcdClient.GetUserPolicy(userUuid)
+func (us UserServiceImpl) GetUserPoliciesByUserUuid(ctx context.Context, userUuid string) []structure.UserPolicy {
+	if userPolicies := us.EtcdClient.GetUserPolicy(ctx, userUuid); userPolicies != nil {
+		return userPolicies
+	}
+
+	value, err, _ := cacheLoader.Do(cache.UserPolicyKeyPrefix+userUuid, func() (interface{}, error) {
+		ctx := context.Background()
+		policies, err := us.PolicyRepository.FindPolicyOfUserGroupByUserUuids(ctx, []string{userUuid})
+		if err != nil {
+			return nil, err
+		}
+
+		userPolicies := []structure.UserPolicy{}
+		for _, policy := range policies {
+			userPolicies = append(userPolicies, structure.UserPolicy{
+				ServiceUuid:    policy.ServiceUuid,
+				GroupUuid:      policy.GroupUuid,
+				RoleName:       policy.RoleName,
+				PermissionName: policy.PermissionName,
+			})
+		}
+		us.EtcdClient.SetUserPolicy(ctx, userUuid, userPolicies)
+		return userPolicies, nil
+	})
+	if err != nil {
+		log.Logger.Warn(fmt.Sprintf("Failed to load user policies. user_uuid = %s. err = %s", userUuid, err.Error()))
+		return nil
+	}
+	return value.([]structure.UserPolicy)
 }
 
-func (us UserServiceImpl) GetUserGroupsByUserUuid(userUuid string) []structure.UserGroup {
-	return us.EtcdClient.GetUserGroup(userUuid)
+func (us UserServiceImpl) GetUserGroupsByUserUuid(ctx context.Context, userUuid string) []structure.UserGroup {
+	if userGroups := us.EtcdClient.GetUserGroup(ctx, userUuid); userGroups != nil {
+		return userGroups
+	}
+
+	value, err, _ := cacheLoader.Do(cache.UserGroupKeyPrefix+userUuid, func() (interface{}, error) {
+		ctx := context.Background()
+		groups, err := us.UserRepository.FindUserGroupsWithGroupByUserUuids(ctx, []string{userUuid})
+		if err != nil {
+			return nil, err
+		}
+
+		userGroups := []structure.UserGroup{}
+		for _, group := range groups {
+			userGroups = append(userGroups, structure.UserGroup{
+				GroupName: group.GroupName,
+				GroupUuid: group.GroupUuid,
+			})
+		}
+		us.EtcdClient.SetUserGroup(ctx, userUuid, userGroups)
+		return userGroups, nil
+	})
+	if err != nil {
+		log.Logger.Warn(fmt.Sprintf("Failed to load user groups. user_uuid = %s. err = %s", userUuid, err.Error()))
+		return nil
+	}
+	return value.([]structure.UserGroup)
 }
 
-func (us UserServiceImpl) InsertUserGroup(userGroupEntity entity.UserGroup) (*entity.UserGroup, *model.ErrorResBody) {
+func (us UserServiceImpl) InsertUserGroup(ctx context.Context, userGroupEntity entity.UserGroup) (*entity.UserGroup, *model.ErrorResBody) {
 	userGroupMd5 := md5.Sum(uuid.New().NodeID())
 	userGroupEntity.InternalId = hex.EncodeToString(userGroupMd5[:])
 
-	_, err := us.UserRepository.FindUserGroupByUserUuidAndGroupUuid(userGroupEntity.UserUuid.String(), userGroupEntity.GroupUuid.String())
-	if err != nil {
-		conflictErr := model.Conflict("This user already joins group")
-		return nil, conflictErr
+	_, err := us.UserRepository.FindUserGroupByUserUuidAndGroupUuid(ctx, userGroupEntity.UserUuid.String(), userGroupEntity.GroupUuid.String())
+	if err == nil {
+		return nil, model.Conflict("This user already joins group")
+	} else if err != driver.ErrNotFound {
+		return nil, repositoryError(err)
 	}
 
-	savedUserGroup, err := us.UserRepository.SaveUserGroup(userGroupEntity)
+	savedUserGroup, err := us.UserRepository.SaveUserGroup(ctx, userGroupEntity)
 	if err != nil {
-		if strings.Contains(err.Error(), "1062") {
-			return nil, model.Conflict("Already exit data.")
-		}
-		return nil, model.InternalServerError(err.Error())
+		return nil, repositoryError(err, "Already exit data.")
 	}
 
 	return savedUserGroup, nil
 }
 
-func (us UserServiceImpl) InsertUser(user entity.User) (*entity.User, *model.ErrorResBody) {
+func (us UserServiceImpl) InsertUser(ctx context.Context, user entity.User) (*entity.User, *model.ErrorResBody) {
 	uid := uuid.New()
 	uidMd5 := md5.Sum(uid.NodeID())
 	user.InternalId = hex.EncodeToString(uidMd5[:])
 	user.Uuid = uid
 	user.Password = us.EncryptPw(user.Password)
 
-	savedUser, err := us.UserRepository.SaveUser(user)
+	savedUser, err := us.UserRepository.SaveUser(ctx, user)
 	if err != nil {
-		if strings.Contains(err.Error(), "1062") {
-			return nil, model.Conflict("Already exit data.")
-		}
-		return nil, model.InternalServerError(err.Error())
+		return nil, repositoryError(err, "Already exit data.")
 	}
 
 	return savedUser, nil
 }
 
-func (us UserServiceImpl) InsertUserWithUserService(user entity.User, userService entity.UserService) (*entity.User, *model.ErrorResBody) {
+func (us UserServiceImpl) InsertUserWithUserService(ctx context.Context, user entity.User, userService entity.UserService) (*entity.User, *model.ErrorResBody) {
 	uid := uuid.New()
 	uidMd5 := md5.Sum(uid.NodeID())
 	user.InternalId = hex.EncodeToString(uidMd5[:])
@@ -284,48 +317,41 @@ func (us UserServiceImpl) InsertUserWithUserService(user entity.User, userServic
 
 	userServiceIdMd5 := md5.Sum(uuid.New().NodeID())
 	userService.InternalId = hex.EncodeToString(userServiceIdMd5[:])
-	savedUser, err := us.UserRepository.SaveWithUserService(user, userService)
+	savedUser, err := us.UserRepository.SaveWithUserService(ctx, user, userService)
 	if err != nil {
-		if strings.Contains(err.Error(), "1062") {
-			return nil, model.Conflict("Already exit service data.")
-		}
-		return nil, model.InternalServerError(err.Error())
+		return nil, repositoryError(err, "Already exit service data.")
 	}
 
 	return savedUser, nil
 }
 
-func (us UserServiceImpl) InsertUserService(userServiceEntity entity.UserService) (*entity.UserService, *model.ErrorResBody) {
+func (us UserServiceImpl) InsertUserService(ctx context.Context, userServiceEntity entity.UserService) (*entity.UserService, *model.ErrorResBody) {
 	userServiceMd5 := md5.Sum(uuid.New().NodeID())
 	userServiceEntity.InternalId = hex.EncodeToString(userServiceMd5[:])
 
-	_, err := us.UserRepository.FindUserServiceByUserUuidAndServiceUuid(userServiceEntity.UserUuid.String(), userServiceEntity.ServiceUuid.String())
-	if err != nil {
+	_, err := us.UserRepository.FindUserServiceByUserUuidAndServiceUuid(ctx, userServiceEntity.UserUuid.String(), userServiceEntity.ServiceUuid.String())
+	if err == nil {
 		return nil, model.Conflict("Already the user has this service account")
+	} else if err != driver.ErrNotFound {
+		return nil, repositoryError(err)
 	}
 
-	savedUserService, err := us.UserRepository.SaveUserService(userServiceEntity)
+	savedUserService, err := us.UserRepository.SaveUserService(ctx, userServiceEntity)
 	if err != nil {
-		if strings.Contains(err.Error(), "1062") {
-			return nil, model.Conflict("Already exit data.")
-		} else if strings.Contains(err.Error(), "1452") {
-			return nil, model.BadRequest("Not register relational id.")
-		} else {
-			return nil, model.InternalServerError(err.Error())
-		}
+		return nil, repositoryError(err, "Already exit data.")
 	}
 
 	return savedUserService, nil
 }
 
-func (us UserServiceImpl) UpdateUser(user entity.User) (*entity.User, *model.ErrorResBody) {
+func (us UserServiceImpl) UpdateUser(ctx context.Context, user entity.User) (*entity.User, *model.ErrorResBody) {
 	uidMd5 := md5.Sum(user.Uuid.NodeID())
 	user.InternalId = hex.EncodeToString(uidMd5[:])
 	user.Password = us.EncryptPw(user.Password)
 
-	updatedUser, err := us.UserRepository.UpdateUser(user)
+	updatedUser, err := us.UserRepository.UpdateUser(ctx, user)
 	if err != nil {
-		return nil, model.InternalServerError(err.Error())
+		return nil, repositoryError(err)
 	}
 
 	return updatedUser, nil
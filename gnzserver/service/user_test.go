@@ -1,7 +1,12 @@
 package service
 
 import (
+	"context"
+	"errors"
+	"net/http"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -10,6 +15,8 @@ import (
 	"github.com/google/uuid"
 	"github.com/jinzhu/gorm"
 	"github.com/tomoyane/grant-n-z/gnz/cache"
+	"github.com/tomoyane/grant-n-z/gnz/cache/structure"
+	"github.com/tomoyane/grant-n-z/gnz/driver"
 	"github.com/tomoyane/grant-n-z/gnz/entity"
 	"github.com/tomoyane/grant-n-z/gnz/log"
 	"github.com/tomoyane/grant-n-z/gnzserver/model"
@@ -31,8 +38,9 @@ func init() {
 	})
 
 	userService = UserServiceImpl{
-		UserRepository: StubUserRepositoryImpl{Connection: stubConnection},
-		EtcdClient:     cache.EtcdClientImpl{Connection: stubEtcdConnection},
+		UserRepository:   StubUserRepositoryImpl{Connection: stubConnection},
+		PolicyRepository: StubPolicyRepositoryImpl{Connection: stubConnection},
+		EtcdClient:       cache.EtcdClientImpl{Connection: stubEtcdConnection},
 	}
 }
 
@@ -87,7 +95,7 @@ func TestComparePw_Failed(t *testing.T) {
 
 // Test get user by id
 func TestGetUserById_Success(t *testing.T) {
-	_, err := userService.GetUserByUuid(uuid.New().String())
+	_, err := userService.GetUserByUuid(context.Background(), uuid.New().String())
 	if err != nil {
 		t.Errorf("Incorrect TestGetUserById_Success test")
 		t.FailNow()
@@ -96,7 +104,7 @@ func TestGetUserById_Success(t *testing.T) {
 
 // Test get user by email
 func TestGetUserByEmail_Success(t *testing.T) {
-	_, err := userService.GetUserByEmail("test@gmail.com")
+	_, err := userService.GetUserByEmail(context.Background(), "test@gmail.com")
 	if err != nil {
 		t.Errorf("Incorrect TestGetUserByEmail_Success test")
 		t.FailNow()
@@ -105,7 +113,7 @@ func TestGetUserByEmail_Success(t *testing.T) {
 
 // Test get user with operator policy by email
 func TestGetUserWithOperatorPolicyByEmail_Success(t *testing.T) {
-	_, err := userService.GetUserWithOperatorPolicyByEmail("test@gmail.com")
+	_, err := userService.GetUserWithOperatorPolicyByEmail(context.Background(), "test@gmail.com")
 	if err != nil {
 		t.Errorf("Incorrect TestGetUserWithOperatorPolicyByEmail_Success test")
 		t.FailNow()
@@ -114,7 +122,7 @@ func TestGetUserWithOperatorPolicyByEmail_Success(t *testing.T) {
 
 // Test user with user service with service by email
 func TestGetUserWithUserServiceWithServiceByEmail_Success(t *testing.T) {
-	_, err := userService.GetUserWithUserServiceWithServiceByEmail("test@gmail.com")
+	_, err := userService.GetUserWithUserServiceWithServiceByEmail(context.Background(), "test@gmail.com")
 	if err != nil {
 		t.Errorf("Incorrect TestGetUserWithUserServiceWithServiceByEmail_Success test")
 		t.FailNow()
@@ -123,7 +131,7 @@ func TestGetUserWithUserServiceWithServiceByEmail_Success(t *testing.T) {
 
 // Test user group by user id and group id
 func TestGetUserGroupByUserIdAndGroupId_Success(t *testing.T) {
-	_, err := userService.GetUserGroupByUserUuidAndGroupUuid(uuid.New().String(), uuid.New().String())
+	_, err := userService.GetUserGroupByUserUuidAndGroupUuid(context.Background(), uuid.New().String(), uuid.New().String())
 	if err != nil {
 		t.Errorf("Incorrect TestGetUserGroupByUserIdAndGroupId_Success test")
 		t.FailNow()
@@ -132,7 +140,7 @@ func TestGetUserGroupByUserIdAndGroupId_Success(t *testing.T) {
 
 // Test get user by group id
 func TestGetUserByGroupId_Success(t *testing.T) {
-	_, err := userService.GetUserByGroupUuid(uuid.New().String())
+	_, _, err := userService.GetUserByGroupUuid(context.Background(), uuid.New().String(), driver.Page{})
 	if err != nil {
 		t.Errorf("Incorrect TestGetUserByGroupId_Success test")
 		t.FailNow()
@@ -141,7 +149,7 @@ func TestGetUserByGroupId_Success(t *testing.T) {
 
 // Test user services
 func TestGetUserServices_Success(t *testing.T) {
-	_, err := userService.GetUserServices()
+	_, err := userService.GetUserServices(context.Background())
 	if err != nil {
 		t.Errorf("Incorrect TestGetUserServices_Success test")
 		t.FailNow()
@@ -150,7 +158,7 @@ func TestGetUserServices_Success(t *testing.T) {
 
 // Test user services by user uuid
 func TestGetUserServicesByUserUuid_Success(t *testing.T) {
-	_, err := userService.GetUserServicesByUserUuid("uuid")
+	_, err := userService.GetUserServicesByUserUuid(context.Background(), "uuid")
 	if err != nil {
 		t.Errorf("Incorrect TestGetUserServicesByUserUuid_Success test")
 		t.FailNow()
@@ -159,26 +167,73 @@ func TestGetUserServicesByUserUuid_Success(t *testing.T) {
 
 // Test get user service by user id and service id
 func TestGetUserServiceByUserIdAndServiceId_Success(t *testing.T) {
-	_, err := userService.GetUserServiceByUserUuidAndServiceUuid(uuid.New().String(), uuid.New().String())
+	_, err := userService.GetUserServiceByUserUuidAndServiceUuid(context.Background(), uuid.New().String(), uuid.New().String())
 	if err != nil {
 		t.Errorf("Incorrect TestGetUserServiceByUserIdAndServiceId_Success test")
 		t.FailNow()
 	}
 }
 
-// Test get user policy in etcd by user uuid
+// Test get user policy by user uuid that is not in etcd
 func TestGetUserPoliciesByUserUuid_Success(t *testing.T) {
-	policies := userService.GetUserPoliciesByUserUuid(uuid.New().String())
-	if policies != nil {
+	policies := userService.GetUserPoliciesByUserUuid(context.Background(), uuid.New().String())
+	if policies == nil || len(policies) != 0 {
 		t.Errorf("Incorrect TestGetUserPoliciesByUserUuid_Success test")
 		t.FailNow()
 	}
 }
 
-// Test get user service in etcd by user uuid
+// Test get user policy loads rdbms once for concurrent misses
+func TestGetUserPoliciesByUserUuid_Load(t *testing.T) {
+	policyRepository := &StubLoadPolicyRepositoryImpl{}
+	us := UserServiceImpl{
+		UserRepository:   StubUserRepositoryImpl{Connection: stubConnection},
+		PolicyRepository: policyRepository,
+		EtcdClient:       cache.EtcdClientImpl{},
+	}
+
+	userUuid := uuid.New().String()
+	var wg sync.WaitGroup
+	results := make([][]structure.UserPolicy, 10)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i] = us.GetUserPoliciesByUserUuid(context.Background(), userUuid)
+		}(i)
+	}
+	wg.Wait()
+
+	if count := atomic.LoadInt32(&policyRepository.count); count != 1 {
+		t.Errorf("Incorrect TestGetUserPoliciesByUserUuid_Load test. count = %d", count)
+		t.FailNow()
+	}
+	for _, policies := range results {
+		if len(policies) != 1 || policies[0].RoleName != "admin" || policies[0].ServiceUuid != userUuid {
+			t.Errorf("Incorrect TestGetUserPoliciesByUserUuid_Load test. policies = %v", policies)
+			t.FailNow()
+		}
+	}
+}
+
+// Test get user policy failed to load rdbms
+func TestGetUserPoliciesByUserUuid_LoadError(t *testing.T) {
+	us := UserServiceImpl{
+		UserRepository:   StubUserRepositoryImpl{Connection: stubConnection},
+		PolicyRepository: &StubLoadPolicyRepositoryImpl{err: errors.New("failed")},
+		EtcdClient:       cache.EtcdClientImpl{},
+	}
+
+	if policies := us.GetUserPoliciesByUserUuid(context.Background(), uuid.New().String()); policies != nil {
+		t.Errorf("Incorrect TestGetUserPoliciesByUserUuid_LoadError test")
+		t.FailNow()
+	}
+}
+
+// Test get user group by user uuid that is not in etcd
 func TestGetUserGroupsByUserUuid_Success(t *testing.T) {
-	policies := userService.GetUserGroupsByUserUuid(uuid.New().String())
-	if policies != nil {
+	groups := userService.GetUserGroupsByUserUuid(context.Background(), uuid.New().String())
+	if groups == nil || len(groups) != 0 {
 		t.Errorf("Incorrect TestGetUserGroupsByUserUuid_Success test")
 		t.FailNow()
 	}
@@ -186,16 +241,36 @@ func TestGetUserGroupsByUserUuid_Success(t *testing.T) {
 
 // Test insert user group
 func TestInsertUserGroup_Success(t *testing.T) {
-	_, err := userService.InsertUserGroup(entity.UserGroup{UserUuid: uuid.New(), GroupUuid: uuid.New()})
+	us := UserServiceImpl{UserRepository: StubUserRelationRepositoryImpl{err: driver.ErrNotFound}}
+	_, err := us.InsertUserGroup(context.Background(), entity.UserGroup{UserUuid: uuid.New(), GroupUuid: uuid.New()})
 	if err != nil {
 		t.Errorf("Incorrect TestInsertUserGroup_Success test")
 		t.FailNow()
 	}
 }
 
+// Test insert user group that the user already joins
+func TestInsertUserGroup_Conflict(t *testing.T) {
+	_, err := userService.InsertUserGroup(context.Background(), entity.UserGroup{UserUuid: uuid.New(), GroupUuid: uuid.New()})
+	if err == nil || err.Code != http.StatusConflict {
+		t.Errorf("Incorrect TestInsertUserGroup_Conflict test")
+		t.FailNow()
+	}
+}
+
+// Test insert user group when finding user group fails
+func TestInsertUserGroup_Error(t *testing.T) {
+	us := UserServiceImpl{UserRepository: StubUserRelationRepositoryImpl{err: errors.New("failed")}}
+	_, err := us.InsertUserGroup(context.Background(), entity.UserGroup{UserUuid: uuid.New(), GroupUuid: uuid.New()})
+	if err == nil || err.Code != http.StatusInternalServerError {
+		t.Errorf("Incorrect TestInsertUserGroup_Error test")
+		t.FailNow()
+	}
+}
+
 // Test insert user
 func TestInsertUser_Success(t *testing.T) {
-	_, err := userService.InsertUser(entity.User{InternalId: ""})
+	_, err := userService.InsertUser(context.Background(), entity.User{InternalId: ""})
 	if err != nil {
 		t.Errorf("Incorrect TestInsertUser_Success test")
 		t.FailNow()
@@ -204,7 +279,7 @@ func TestInsertUser_Success(t *testing.T) {
 
 // Test insert user group with user service
 func TestInsertUserWithUserService_Success(t *testing.T) {
-	_, err := userService.InsertUserWithUserService(entity.User{}, entity.UserService{})
+	_, err := userService.InsertUserWithUserService(context.Background(), entity.User{}, entity.UserService{})
 	if err != nil {
 		t.Errorf("Incorrect TestInsertUserWithUserService_Success test")
 		t.FailNow()
@@ -213,17 +288,27 @@ func TestInsertUserWithUserService_Success(t *testing.T) {
 
 // Test insert user service
 func TestInsertUserService_Success(t *testing.T) {
-	_, err := userService.InsertUserService(entity.UserService{})
+	us := UserServiceImpl{UserRepository: StubUserRelationRepositoryImpl{err: driver.ErrNotFound}}
+	_, err := us.InsertUserService(context.Background(), entity.UserService{})
 	if err != nil {
 		t.Errorf("Incorrect TestInsertUserService_Success test")
 		t.FailNow()
 	}
 }
 
+// Test insert user service that the user already has
+func TestInsertUserService_Conflict(t *testing.T) {
+	_, err := userService.InsertUserService(context.Background(), entity.UserService{})
+	if err == nil || err.Code != http.StatusConflict {
+		t.Errorf("Incorrect TestInsertUserService_Conflict test")
+		t.FailNow()
+	}
+}
+
 // Test update user
 func TestUpdateUser_Success(t *testing.T) {
 	var user entity.User
-	_, err := userService.UpdateUser(user)
+	_, err := userService.UpdateUser(context.Background(), user)
 	if err != nil {
 		t.Errorf("Incorrect TestUpdateUser_Success test")
 		t.FailNow()
@@ -236,77 +321,127 @@ type StubUserRepositoryImpl struct {
 	Connection *gorm.DB
 }
 
-func (uri StubUserRepositoryImpl) FindByUuid(uuid string) (*entity.User, error) {
+func (uri StubUserRepositoryImpl) FindByUuid(ctx context.Context, uuid string) (*entity.User, error) {
 	var user entity.User
 	return &user, nil
 }
 
-func (uri StubUserRepositoryImpl) FindByEmail(email string) (*entity.User, error) {
+func (uri StubUserRepositoryImpl) FindByEmail(ctx context.Context, email string) (*entity.User, error) {
 	var user entity.User
 	return &user, nil
 }
 
-func (uri StubUserRepositoryImpl) FindByGroupUuid(groupUuid string) ([]*entity.User, error) {
+func (uri StubUserRepositoryImpl) FindByGroupUuid(ctx context.Context, groupUuid string, page driver.Page) ([]*entity.User, string, error) {
 	var users []*entity.User
-	return users, nil
+	return users, "", nil
 }
 
-func (uri StubUserRepositoryImpl) FindWithOperatorPolicyByEmail(email string) (*model.UserWithOperatorPolicy, error) {
+func (uri StubUserRepositoryImpl) FindWithOperatorPolicyByEmail(ctx context.Context, email string) (*model.UserWithOperatorPolicy, error) {
 	var uwo model.UserWithOperatorPolicy
 	return &uwo, nil
 }
 
-func (uri StubUserRepositoryImpl) FindWithUserServiceWithServiceByEmail(email string) (*model.UserWithUserServiceWithService, error) {
+func (uri StubUserRepositoryImpl) FindWithUserServiceWithServiceByEmail(ctx context.Context, email string) (*model.UserWithUserServiceWithService, error) {
 	var uus model.UserWithUserServiceWithService
 	return &uus, nil
 }
 
-func (uri StubUserRepositoryImpl) FindUserGroupByUserUuidAndGroupUuid(userUuid string, groupUuid string) (*entity.UserGroup, error) {
+func (uri StubUserRepositoryImpl) FindUserGroupByUserUuidAndGroupUuid(ctx context.Context, userUuid string, groupUuid string) (*entity.UserGroup, error) {
 	var userGroup entity.UserGroup
 	return &userGroup, nil
 }
 
-func (uri StubUserRepositoryImpl) FindUserServices() ([]*entity.UserService, error) {
+func (uri StubUserRepositoryImpl) FindUserServices(ctx context.Context) ([]*entity.UserService, error) {
 	var userServices []*entity.UserService
 	return userServices, nil
 }
 
-func (uri StubUserRepositoryImpl) FindUserServicesByUserUuid(userUuid string) ([]*entity.UserService, error) {
+func (uri StubUserRepositoryImpl) FindUserServicesByUserUuid(ctx context.Context, userUuid string) ([]*entity.UserService, error) {
 	var userServices []*entity.UserService
 	return userServices, nil
 }
 
-func (uri StubUserRepositoryImpl) FindUserServicesOffSetAndLimit(offset int, limit int) ([]*entity.UserService, error) {
+func (uri StubUserRepositoryImpl) FindUserServicesOffSetAndLimit(ctx context.Context, offset int, limit int) ([]*entity.UserService, error) {
 	var userServices []*entity.UserService
 	return userServices, nil
 }
 
-func (uri StubUserRepositoryImpl) FindUserGroupsOffSetAndLimit(offset int, limit int) ([]*entity.UserGroup, error) {
+func (uri StubUserRepositoryImpl) FindUserGroupsOffSetAndLimit(ctx context.Context, offset int, limit int) ([]*entity.UserGroup, error) {
 	var userGroups []*entity.UserGroup
 	return userGroups, nil
 }
 
-func (uri StubUserRepositoryImpl) FindUserServiceByUserUuidAndServiceUuid(userUuid string, serviceUuid string) (*entity.UserService, error) {
+func (uri StubUserRepositoryImpl) FindUserUuidsOfUserServicesAfter(ctx context.Context, afterUserUuid string, limit int) ([]string, error) {
+	var userUuids []string
+	return userUuids, nil
+}
+
+func (uri StubUserRepositoryImpl) FindUserUuidsOfUserGroupsAfter(ctx context.Context, afterUserUuid string, limit int) ([]string, error) {
+	var userUuids []string
+	return userUuids, nil
+}
+
+func (uri StubUserRepositoryImpl) FindUserServicesWithServiceByUserUuids(ctx context.Context, userUuids []string) ([]model.UserServiceOnService, error) {
+	var userServices []model.UserServiceOnService
+	return userServices, nil
+}
+
+func (uri StubUserRepositoryImpl) FindUserGroupsWithGroupByUserUuids(ctx context.Context, userUuids []string) ([]model.UserGroupOnGroup, error) {
+	var userGroups []model.UserGroupOnGroup
+	return userGroups, nil
+}
+
+func (uri StubUserRepositoryImpl) FindUserServiceByUserUuidAndServiceUuid(ctx context.Context, userUuid string, serviceUuid string) (*entity.UserService, error) {
 	var userService entity.UserService
 	return &userService, nil
 }
 
-func (uri StubUserRepositoryImpl) SaveUserGroup(userGroup entity.UserGroup) (*entity.UserGroup, error) {
+func (uri StubUserRepositoryImpl) SaveUserGroup(ctx context.Context, userGroup entity.UserGroup) (*entity.UserGroup, error) {
 	return &userGroup, nil
 }
 
-func (uri StubUserRepositoryImpl) SaveUser(user entity.User) (*entity.User, error) {
+func (uri StubUserRepositoryImpl) SaveUser(ctx context.Context, user entity.User) (*entity.User, error) {
 	return &user, nil
 }
 
-func (uri StubUserRepositoryImpl) SaveWithUserService(user entity.User, userService entity.UserService) (*entity.User, error) {
+func (uri StubUserRepositoryImpl) SaveWithUserService(ctx context.Context, user entity.User, userService entity.UserService) (*entity.User, error) {
 	return &user, nil
 }
 
-func (uri StubUserRepositoryImpl) SaveUserService(userService entity.UserService) (*entity.UserService, error) {
+func (uri StubUserRepositoryImpl) SaveUserService(ctx context.Context, userService entity.UserService) (*entity.UserService, error) {
 	return &userService, nil
 }
 
-func (uri StubUserRepositoryImpl) UpdateUser(user entity.User) (*entity.User, error) {
+func (uri StubUserRepositoryImpl) UpdateUser(ctx context.Context, user entity.User) (*entity.User, error) {
 	return &user, nil
 }
+
+// Policy repository that counts loads of user policies
+type StubLoadPolicyRepositoryImpl struct {
+	StubPolicyRepositoryImpl
+	count int32
+	err   error
+}
+
+func (pri *StubLoadPolicyRepositoryImpl) FindPolicyOfUserGroupByUserUuids(ctx context.Context, userUuids []string) ([]model.UserPolicyOnUserGroup, error) {
+	atomic.AddInt32(&pri.count, 1)
+	time.Sleep(50 * time.Millisecond)
+	if pri.err != nil {
+		return nil, pri.err
+	}
+	return []model.UserPolicyOnUserGroup{{UserUuid: userUuids[0], ServiceUuid: userUuids[0], RoleName: "admin"}}, nil
+}
+
+// User repository that fails to find user group and user service
+type StubUserRelationRepositoryImpl struct {
+	StubUserRepositoryImpl
+	err error
+}
+
+func (uri StubUserRelationRepositoryImpl) FindUserGroupByUserUuidAndGroupUuid(ctx context.Context, userUuid string, groupUuid string) (*entity.UserGroup, error) {
+	return nil, uri.err
+}
+
+func (uri StubUserRelationRepositoryImpl) FindUserServiceByUserUuidAndServiceUuid(ctx context.Context, userUuid string, serviceUuid string) (*entity.UserService, error) {
+	return nil, uri.err
+}
@@ -0,0 +1,289 @@
+-- grant-n-z for PostgreSQL
+-- Run in the grant_n_z database. e.g. psql -d grant_n_z -f postgresql.sql
+
+-- Tables are dropped in reverse order of foreign keys
+DROP TABLE IF EXISTS policies;
+DROP TABLE IF EXISTS operator_policies;
+DROP TABLE IF EXISTS group_permissions;
+DROP TABLE IF EXISTS group_roles;
+DROP TABLE IF EXISTS service_permissions;
+DROP TABLE IF EXISTS service_roles;
+DROP TABLE IF EXISTS service_groups;
+DROP TABLE IF EXISTS user_groups;
+DROP TABLE IF EXISTS user_services;
+DROP TABLE IF EXISTS roles;
+DROP TABLE IF EXISTS groups;
+DROP TABLE IF EXISTS permissions;
+DROP TABLE IF EXISTS users;
+DROP TABLE IF EXISTS services;
+
+-- `services`
+CREATE TABLE services (
+  id SERIAL NOT NULL,
+  internal_id varchar(32) NOT NULL,
+  uuid varchar(128) NOT NULL,
+  name varchar(128) NOT NULL,
+  secret varchar(128) NOT NULL,
+  created_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (id),
+  UNIQUE (name),
+  UNIQUE (uuid)
+);
+
+-- `users`
+CREATE TABLE users (
+  id SERIAL NOT NULL,
+  internal_id varchar(32) NOT NULL,
+  uuid varchar(128) NOT NULL,
+  username varchar(128) NOT NULL,
+  email varchar(128) NOT NULL,
+  password varchar(128) NOT NULL,
+  created_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (id),
+  UNIQUE (email),
+  UNIQUE (uuid)
+);
+
+-- `permissions`
+CREATE TABLE permissions (
+  id SERIAL NOT NULL,
+  internal_id varchar(32) NOT NULL,
+  uuid varchar(128) NOT NULL,
+  name varchar(128) NOT NULL,
+  created_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (id),
+  UNIQUE (name),
+  UNIQUE (uuid)
+);
+
+-- `groups`
+CREATE TABLE groups (
+  id SERIAL NOT NULL,
+  internal_id varchar(32) NOT NULL,
+  uuid varchar(128) NOT NULL,
+  name varchar(128) NOT NULL,
+  created_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (id),
+  UNIQUE (uuid)
+);
+
+-- `roles`
+CREATE TABLE roles (
+  id SERIAL NOT NULL,
+  internal_id varchar(32) NOT NULL,
+  uuid varchar(128) NOT NULL,
+  name varchar(128) NOT NULL,
+  created_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (id),
+  UNIQUE (name),
+  UNIQUE (uuid)
+);
+
+-- `user_services`
+CREATE TABLE user_services (
+  id SERIAL NOT NULL,
+  internal_id varchar(32) NOT NULL,
+  user_uuid varchar(128) NOT NULL,
+  service_uuid varchar(128) NOT NULL,
+  created_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (id),
+  CONSTRAINT fk_user_services_user_uuid
+  FOREIGN KEY (user_uuid)
+  REFERENCES users (uuid)
+  ON DELETE RESTRICT ON UPDATE RESTRICT,
+  CONSTRAINT fk_user_services_service_uuid
+  FOREIGN KEY (service_uuid)
+  REFERENCES services (uuid)
+  ON DELETE RESTRICT ON UPDATE RESTRICT
+);
+CREATE INDEX idx_user_services_user_uuid ON user_services (user_uuid);
+CREATE INDEX idx_user_services_service_uuid ON user_services (service_uuid);
+
+-- `user_groups`
+CREATE TABLE user_groups (
+  id SERIAL NOT NULL,
+  internal_id varchar(32) NOT NULL,
+  uuid varchar(128) NOT NULL,
+  user_uuid varchar(128) NOT NULL,
+  group_uuid varchar(128) NOT NULL,
+  created_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (id),
+  UNIQUE (uuid),
+  CONSTRAINT fk_user_groups_user_uuid
+  FOREIGN KEY (user_uuid)
+  REFERENCES users (uuid)
+  ON DELETE RESTRICT ON UPDATE RESTRICT,
+  CONSTRAINT fk_user_groups_group_uuid
+  FOREIGN KEY (group_uuid)
+  REFERENCES groups (uuid)
+  ON DELETE RESTRICT ON UPDATE RESTRICT
+);
+CREATE INDEX idx_user_groups_user_uuid ON user_groups (user_uuid);
+CREATE INDEX idx_user_groups_group_uuid ON user_groups (group_uuid);
+
+-- `service_groups`
+CREATE TABLE service_groups (
+  id SERIAL NOT NULL,
+  internal_id varchar(32) NOT NULL,
+  group_uuid varchar(128) NOT NULL,
+  service_uuid varchar(128) NOT NULL,
+  created_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (id),
+  CONSTRAINT fk_service_groups_group_uuid
+  FOREIGN KEY (group_uuid)
+  REFERENCES groups (uuid)
+  ON DELETE RESTRICT ON UPDATE RESTRICT,
+  CONSTRAINT fk_service_groups_serviceuuid
+  FOREIGN KEY (service_uuid)
+  REFERENCES services (uuid)
+  ON DELETE RESTRICT ON UPDATE RESTRICT
+);
+CREATE INDEX idx_service_groups_group_uuid ON service_groups (group_uuid);
+CREATE INDEX idx_service_groups_service_uuid ON service_groups (service_uuid);
+
+-- `service_roles`
+CREATE TABLE service_roles (
+  id SERIAL NOT NULL,
+  internal_id varchar(32) NOT NULL,
+  role_uuid varchar(128) NOT NULL,
+  service_uuid varchar(128) NOT NULL,
+  created_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (id),
+  CONSTRAINT fk_service_roles_role_uuid
+  FOREIGN KEY (role_uuid)
+  REFERENCES roles (uuid)
+  ON DELETE RESTRICT ON UPDATE RESTRICT,
+  CONSTRAINT fk_service_roles_service_uuid
+  FOREIGN KEY (service_uuid)
+  REFERENCES services (uuid)
+  ON DELETE RESTRICT ON UPDATE RESTRICT
+);
+CREATE INDEX idx_service_roles_role_uuid ON service_roles (role_uuid);
+CREATE INDEX idx_service_roles_service_uuid ON service_roles (service_uuid);
+
+-- `service_permissions`
+CREATE TABLE service_permissions (
+  id SERIAL NOT NULL,
+  internal_id varchar(32) NOT NULL,
+  permission_uuid varchar(128) NOT NULL,
+  service_uuid varchar(128) NOT NULL,
+  created_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (id),
+  CONSTRAINT fk_service_permissions_permission_uuid
+  FOREIGN KEY (permission_uuid)
+  REFERENCES permissions (uuid)
+  ON DELETE RESTRICT ON UPDATE RESTRICT,
+  CONSTRAINT fk_service_permissions_service_uuid
+  FOREIGN KEY (service_uuid)
+  REFERENCES services (uuid)
+  ON DELETE RESTRICT ON UPDATE RESTRICT
+);
+CREATE INDEX idx_service_permissions_permission_uuid ON service_permissions (permission_uuid);
+CREATE INDEX idx_service_permissions_service_uuid ON service_permissions (service_uuid);
+
+-- `group_roles`
+CREATE TABLE group_roles (
+  id SERIAL NOT NULL,
+  internal_id varchar(32) NOT NULL,
+  role_uuid varchar(128) NOT NULL,
+  group_uuid varchar(128) NOT NULL,
+  created_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (id),
+  CONSTRAINT fk_group_roles_role_uuid
+  FOREIGN KEY (role_uuid)
+  REFERENCES roles (uuid)
+  ON DELETE RESTRICT ON UPDATE RESTRICT,
+  CONSTRAINT fk_group_roles_group_uuid
+  FOREIGN KEY (group_uuid)
+  REFERENCES groups (uuid)
+  ON DELETE RESTRICT ON UPDATE RESTRICT
+);
+CREATE INDEX idx_group_roles_role_uuid ON group_roles (role_uuid);
+CREATE INDEX idx_group_roles_group_uuid ON group_roles (group_uuid);
+
+-- `group_permissions`
+CREATE TABLE group_permissions (
+  id SERIAL NOT NULL,
+  internal_id varchar(32) NOT NULL,
+  permission_uuid varchar(128) NOT NULL,
+  group_uuid varchar(128) NOT NULL,
+  created_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (id),
+  CONSTRAINT fk_group_permissions_permission_uuid
+  FOREIGN KEY (permission_uuid)
+  REFERENCES permissions (uuid)
+  ON DELETE RESTRICT ON UPDATE RESTRICT,
+  CONSTRAINT fk_group_permissions_group_uuid
+  FOREIGN KEY (group_uuid)
+  REFERENCES groups (uuid)
+  ON DELETE RESTRICT ON UPDATE RESTRICT
+);
+CREATE INDEX idx_group_permissions_permission_uuid ON group_permissions (permission_uuid);
+CREATE INDEX idx_group_permissions_group_uuid ON group_permissions (group_uuid);
+
+-- `operator_policies`
+CREATE TABLE operator_policies (
+  id SERIAL NOT NULL,
+  internal_id varchar(32) NOT NULL,
+  role_uuid varchar(128) NOT NULL,
+  user_uuid varchar(128) NOT NULL,
+  created_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (id),
+  CONSTRAINT fk_operator_policies_role_uuid
+  FOREIGN KEY (role_uuid)
+  REFERENCES roles (uuid)
+  ON DELETE RESTRICT ON UPDATE RESTRICT,
+  CONSTRAINT fk_operator_policies_user_uuid
+  FOREIGN KEY (user_uuid)
+  REFERENCES users (uuid)
+  ON DELETE RESTRICT ON UPDATE RESTRICT
+);
+CREATE INDEX idx_operator_policies_user_uuid ON operator_policies (user_uuid);
+CREATE INDEX idx_operator_policies_role_uuid ON operator_policies (role_uuid);
+
+-- `policies`
+CREATE TABLE policies (
+  id SERIAL NOT NULL,
+  internal_id varchar(32) NOT NULL,
+  name varchar(128) NOT NULL,
+  role_uuid varchar(128) NOT NULL,
+  permission_uuid varchar(128) NOT NULL,
+  service_uuid varchar(128) NOT NULL,
+  user_group_uuid varchar(128) NOT NULL,
+  created_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (id),
+  CONSTRAINT fk_policies_role_uuid
+  FOREIGN KEY (role_uuid)
+  REFERENCES roles (uuid)
+  ON DELETE RESTRICT ON UPDATE RESTRICT,
+  CONSTRAINT fk_policies_permission_uuid
+  FOREIGN KEY (permission_uuid)
+  REFERENCES permissions (uuid)
+  ON DELETE RESTRICT ON UPDATE RESTRICT,
+  CONSTRAINT fk_policies_service_uuid
+  FOREIGN KEY (service_uuid)
+  REFERENCES services (uuid)
+  ON DELETE RESTRICT ON UPDATE RESTRICT,
+  CONSTRAINT fk_policies_user_group_uuid
+  FOREIGN KEY (user_group_uuid)
+  REFERENCES user_groups (uuid)
+  ON DELETE RESTRICT ON UPDATE RESTRICT
+);
+CREATE INDEX idx_policies_role_uuid ON policies (role_uuid);
+CREATE INDEX idx_policies_permission_uuid ON policies (permission_uuid);
+CREATE INDEX idx_policies_service_uuid ON policies (service_uuid);
+CREATE INDEX idx_policies_user_group_uuid ON policies (user_group_uuid);